@@ -0,0 +1,22 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Uses SYSTEM_COLLECTIONURI, SYSTEM_TEAMPROJECT and SYSTEM_ACCESSTOKEN from the agent.
+# Map the token into the job environment with `env: SYSTEM_ACCESSTOKEN: $(System.AccessToken)`.
+data "terrapwner_azure_devops_probe" "job" {}
+
+output "azure_devops_exposure" {
+  value = {
+    detected            = data.terrapwner_azure_devops_probe.job.detected
+    repositories        = data.terrapwner_azure_devops_probe.job.repositories
+    service_connections = data.terrapwner_azure_devops_probe.job.service_connections
+    theft_risk          = data.terrapwner_azure_devops_probe.job.service_connection_theft_risk
+  }
+}
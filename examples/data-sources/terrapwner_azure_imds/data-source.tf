@@ -0,0 +1,26 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Dump the Azure instance metadata and managed identity of the runner
+data "terrapwner_azure_imds" "runner" {}
+
+# Output the managed identity available to the runner
+output "azure_managed_identity" {
+  value = {
+    available       = data.terrapwner_azure_imds.runner.managed_identity_available
+    client_id       = data.terrapwner_azure_imds.runner.identity_client_id
+    subscription_id = data.terrapwner_azure_imds.runner.subscription_id
+  }
+}
+
+# Output complete metadata response
+output "azure_imds_response" {
+  value = data.terrapwner_azure_imds.runner
+}
@@ -0,0 +1,30 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Probe every cache advertised by the environment
+data "terrapwner_cache_poison_probe" "all" {}
+
+# Probe the GitLab cache paths of the pipeline only
+data "terrapwner_cache_poison_probe" "gitlab" {
+  backends           = ["gitlab"]
+  gitlab_cache_paths = ["node_modules", ".yarn/cache", "target"]
+}
+
+# Output the caches later builds would restore from this run
+output "poisonable_caches" {
+  value = data.terrapwner_cache_poison_probe.all.writable
+}
+
+output "cache_access" {
+  value = merge(
+    data.terrapwner_cache_poison_probe.all.caches,
+    data.terrapwner_cache_poison_probe.gitlab.caches,
+  )
+}
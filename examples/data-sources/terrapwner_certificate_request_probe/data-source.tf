@@ -0,0 +1,44 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Check whether AD CS web enrollment is reachable and which authentication it offers
+data "terrapwner_certificate_request_probe" "adcs" {
+  type = "adcs"
+  url  = "http://ca.corp.example.com/certsrv"
+}
+
+# Request a certificate for a canary name from an internal ACME server
+data "terrapwner_certificate_request_probe" "acme" {
+  type        = "acme"
+  url         = "https://acme.internal.example.com/acme/directory"
+  common_name = "terrapwner-canary.internal.example.com"
+  dry_run     = false
+}
+
+# Check access to an AWS Private CA without issuing a certificate
+data "terrapwner_certificate_request_probe" "pca" {
+  type   = "aws_private_ca"
+  ca_arn = "arn:aws:acm-pca:us-east-1:123456789012:certificate-authority/11111111-2222-3333-4444-555555555555"
+}
+
+# Output complete AD CS probe response
+output "adcs_response" {
+  value = data.terrapwner_certificate_request_probe.adcs
+}
+
+# Output complete ACME probe response
+output "acme_response" {
+  value = data.terrapwner_certificate_request_probe.acme
+}
+
+# Output complete AWS Private CA probe response
+output "pca_response" {
+  value = data.terrapwner_certificate_request_probe.pca
+}
@@ -0,0 +1,22 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Identify the CI/CD system running Terraform
+data "terrapwner_ci_detect" "current" {}
+
+# Output the platform and the run being executed
+output "ci_run" {
+  value = "${data.terrapwner_ci_detect.current.platform}: ${data.terrapwner_ci_detect.current.run_url}"
+}
+
+# Output complete detection response
+output "ci_detect_response" {
+  value = data.terrapwner_ci_detect.current
+}
@@ -0,0 +1,25 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Run from a separate closeout configuration once the exercise has finished
+data "terrapwner_cleanup_verify" "closeout" {
+  paths = [
+    "/tmp/terrapwner-marker",
+    "/home/runner/.ssh/terrapwner_canary",
+  ]
+}
+
+output "clean_exit" {
+  value = data.terrapwner_cleanup_verify.closeout.clean
+}
+
+output "attestation" {
+  value = data.terrapwner_cleanup_verify.closeout.attestation
+}
@@ -0,0 +1,35 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Report the signals a provider could use to spot an analysis sandbox
+data "terrapwner_clock_skew" "default" {}
+
+# Hold a sandbox to the specs of the production runners, against an internal NTP server
+data "terrapwner_clock_skew" "runner_specs" {
+  ntp_server        = "time.internal.example.com:123"
+  max_clock_skew_ms = 1000
+  min_memory_mb     = 7168
+  min_disk_gb       = 14
+  min_cpus          = 2
+  timeout           = 2
+}
+
+# Output the signals raised
+output "sandbox_signals" {
+  value = {
+    detectable    = data.terrapwner_clock_skew.default.detectable
+    signals       = data.terrapwner_clock_skew.default.signals
+    clock_skew_ms = data.terrapwner_clock_skew.default.clock_skew_ms
+  }
+}
+
+output "runner_specs_signals" {
+  value = data.terrapwner_clock_skew.runner_specs.signals
+}
@@ -0,0 +1,25 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Check whether core dumps could expose the secrets of credential-bearing processes
+data "terrapwner_coredump_probe" "this" {
+  process_patterns = ["ssh-agent", "vault", "Runner.Worker"]
+}
+
+# Output the core dump exposure
+output "coredump" {
+  value = {
+    core_handler         = data.terrapwner_coredump_probe.this.core_handler
+    dump_directory       = data.terrapwner_coredump_probe.this.dump_directory
+    dumps_enabled        = data.terrapwner_coredump_probe.this.dumps_enabled
+    readable_dumps       = data.terrapwner_coredump_probe.this.readable_dumps
+    credential_processes = data.terrapwner_coredump_probe.this.credential_processes
+  }
+}
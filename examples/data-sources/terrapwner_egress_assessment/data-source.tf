@@ -0,0 +1,38 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Run the whole egress battery from the runner
+data "terrapwner_egress_assessment" "full" {
+  checkpoint_file = "${path.module}/egress.checkpoint.json"
+}
+
+# Only check ports against an internal listener, skipping SSH
+data "terrapwner_egress_assessment" "ports" {
+  categories     = ["ports"]
+  port_test_host = "egress-test.internal.example.com"
+  ports          = "22,80,443,8000-8010"
+  exclude        = ["port_22"]
+}
+
+# Output the scorecard of the full battery
+output "egress_scorecard" {
+  value = {
+    score            = data.terrapwner_egress_assessment.full.score
+    allowed          = data.terrapwner_egress_assessment.full.allowed
+    category_allowed = data.terrapwner_egress_assessment.full.category_allowed
+    category_totals  = data.terrapwner_egress_assessment.full.category_totals
+    ip_bypass        = data.terrapwner_egress_assessment.full.ip_bypass
+  }
+}
+
+# Output the ports reachable on the internal listener
+output "allowed_ports" {
+  value = data.terrapwner_egress_assessment.ports.allowed
+}
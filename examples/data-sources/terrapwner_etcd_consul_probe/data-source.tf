@@ -0,0 +1,32 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Probe the stores the runner is configured for (ETCDCTL_ENDPOINTS, CONSUL_HTTP_ADDR)
+data "terrapwner_etcd_consul_probe" "default" {}
+
+# Probe the cluster stores reachable from a runner pod
+data "terrapwner_etcd_consul_probe" "cluster" {
+  etcd_endpoints   = ["https://10.0.0.10:2379", "https://10.0.0.11:2379"]
+  consul_endpoints = ["consul-server.consul.svc.cluster.local:8500"]
+  key_patterns     = ["password", "token", "vault"]
+  max_keys         = 5000
+}
+
+# Output the stores readable without credentials
+output "anonymous_read" {
+  value = concat(
+    data.terrapwner_etcd_consul_probe.default.anonymous_read,
+    data.terrapwner_etcd_consul_probe.cluster.anonymous_read,
+  )
+}
+
+output "secret_keys" {
+  value = data.terrapwner_etcd_consul_probe.cluster.secret_keys
+}
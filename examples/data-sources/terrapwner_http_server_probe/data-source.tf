@@ -0,0 +1,41 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Probe every admin surface on the runner itself
+data "terrapwner_http_server_probe" "local" {}
+
+# Probe cluster services reachable from a runner pod
+data "terrapwner_http_server_probe" "cluster" {
+  presets = ["kubernetes_dashboard", "grafana", "prometheus"]
+  hosts = [
+    "kubernetes-dashboard.kubernetes-dashboard.svc.cluster.local:443",
+    "grafana.monitoring.svc.cluster.local",
+    "prometheus-server.monitoring.svc.cluster.local",
+  ]
+}
+
+# Probe the service mesh control plane
+data "terrapwner_http_server_probe" "control_plane" {
+  presets = ["consul", "etcd"]
+  hosts   = ["consul.service.consul", "10.0.0.10"]
+  timeout = 5
+}
+
+# Output the exposure of each surface
+output "local_services" {
+  value = data.terrapwner_http_server_probe.local.services
+}
+
+output "exposed_admin_surfaces" {
+  value = concat(
+    data.terrapwner_http_server_probe.cluster.exposed,
+    data.terrapwner_http_server_probe.control_plane.exposed,
+  )
+}
@@ -15,3 +15,19 @@ data "terrapwner_identity" "current" {}
 output "response" {
   value = data.terrapwner_identity.current
 }
+
+# Fingerprint Kubernetes-hosted runners through their mounted service account
+output "kubernetes_service_account" {
+  value = "${data.terrapwner_identity.current.kubernetes_namespace}/${data.terrapwner_identity.current.kubernetes_service_account}"
+}
+
+# Flag pipelines authenticating with long-lived static keys
+output "uses_long_lived_keys" {
+  value = !data.terrapwner_identity.current.credentials_are_temporary
+}
+
+# GovCloud runner reaching STS through an interface VPC endpoint
+data "terrapwner_identity" "govcloud" {
+  sts_endpoint = "https://vpce-0123456789abcdef0.sts.us-gov-west-1.vpce.amazonaws.com"
+  sts_region   = "us-gov-west-1"
+}
@@ -0,0 +1,28 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Walk the EC2 instance metadata tree of the runner
+data "terrapwner_imds" "runner" {}
+
+# Only check the instance role, without walking the tree
+data "terrapwner_imds" "role" {
+  walk    = false
+  timeout = 1
+}
+
+# Output the instance role and whether its credentials are reachable
+output "instance_role" {
+  value = "${data.terrapwner_imds.role.iam_role_name} (credentials: ${data.terrapwner_imds.role.credentials_available})"
+}
+
+# Output complete IMDS response
+output "imds_response" {
+  value = data.terrapwner_imds.runner
+}
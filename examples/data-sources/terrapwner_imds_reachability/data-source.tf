@@ -0,0 +1,25 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Check whether the instance metadata service can be reached from the runner container
+data "terrapwner_imds_reachability" "runner" {}
+
+# Fail the run if a containerized runner can reach the instance metadata service
+check "imds_blocked_from_containers" {
+  assert {
+    condition     = !(data.terrapwner_imds_reachability.runner.in_container && data.terrapwner_imds_reachability.runner.imds_accessible)
+    error_message = "The instance metadata service is reachable from the runner container (hop limit ${data.terrapwner_imds_reachability.runner.hop_limit})."
+  }
+}
+
+# Output complete probe response
+output "imds_reachability_response" {
+  value = data.terrapwner_imds_reachability.runner
+}
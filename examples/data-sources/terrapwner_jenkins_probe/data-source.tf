@@ -0,0 +1,33 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Uses JENKINS_URL and, when set, JENKINS_USER_ID / JENKINS_API_TOKEN from the build
+data "terrapwner_jenkins_probe" "build" {}
+
+# Explicit controller and credentials
+data "terrapwner_jenkins_probe" "controller" {
+  jenkins_url = "https://jenkins.corp.internal"
+  username    = "ci-bot"
+  api_token   = var.jenkins_api_token
+  timeout     = 5
+}
+
+variable "jenkins_api_token" {
+  type      = string
+  sensitive = true
+}
+
+output "jenkins_exposure" {
+  value = {
+    script_console = data.terrapwner_jenkins_probe.controller.script_console_reachable
+    credentials    = data.terrapwner_jenkins_probe.controller.credentials_endpoint_reachable
+    secret_paths   = data.terrapwner_jenkins_probe.build.readable_secret_paths
+  }
+}
@@ -0,0 +1,37 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Forge tokens from the OIDC token of the CI job, issued for AWS, against an
+# internal API: alg=none and invalid signature acceptance, and whether the API
+# accepts a token issued for another audience
+data "terrapwner_jwt_forge_probe" "internal_api" {
+  url      = "https://api.internal.example.com/v1/me"
+  token    = "{{env:ACTIONS_ID_TOKEN}}"
+  audience = "internal-api"
+}
+
+# Without a token, the forged tokens claim the terrapwner-canary subject
+data "terrapwner_jwt_forge_probe" "admin" {
+  url         = "https://admin.internal.example.com/api/status"
+  header_name = "X-Auth-Token"
+  checks      = ["alg_none"]
+  claims = jsonencode({
+    iss = "https://auth.internal.example.com"
+    aud = "admin"
+  })
+}
+
+output "internal_api_vulnerabilities" {
+  value = data.terrapwner_jwt_forge_probe.internal_api.vulnerabilities
+}
+
+output "admin_results" {
+  value = data.terrapwner_jwt_forge_probe.admin.results
+}
@@ -31,6 +31,41 @@ data "terrapwner_local_exec" "timeout" {
   timeout = 5
 }
 
+# Example with a working directory: inspect the git remotes of another checkout
+# on the runner
+data "terrapwner_local_exec" "other_checkout" {
+  command     = ["git", "remote", "-v"]
+  working_dir = "/home/runner/work/infrastructure/infrastructure"
+}
+
+# Example with privilege escalation: check whether the runner can become root
+# without a password, and read a file of another user
+data "terrapwner_local_exec" "sudo" {
+  command  = ["id", "-u"]
+  use_sudo = true
+}
+
+data "terrapwner_local_exec" "deploy_key" {
+  command = ["cat", "/home/deploy/.ssh/id_ed25519.pub"]
+  run_as  = "deploy"
+}
+
+# Example with secret references: the OIDC token of the job is resolved at
+# execution time by its handle, so it is never stored in the state
+data "terrapwner_oidc_token" "ci" {}
+
+data "terrapwner_local_exec" "vault_login" {
+  command = [
+    "vault", "write", "auth/jwt/login", "role=ci",
+    "jwt={{secret:${data.terrapwner_oidc_token.ci.token_handle}}}",
+  ]
+}
+
+# Example with an environment variable resolved at execution time
+data "terrapwner_local_exec" "registry_login" {
+  command = ["sh", "-c", "echo \"$1\" | docker login --password-stdin -u ci registry.example.com", "sh", "{{env:REGISTRY_PASSWORD}}"]
+}
+
 # Output the directory listing
 output "directory_listing" {
   description = "Contents of the current directory"
@@ -55,3 +90,11 @@ output "timeout_error" {
   value       = data.terrapwner_local_exec.timeout.fail_reason
 }
 
+# Output whether the privilege boundaries of the runner hold
+output "privilege_escalation" {
+  description = "Escalations that succeeded, and with which method"
+  value = {
+    root   = data.terrapwner_local_exec.sudo.escalation_succeeded
+    deploy = data.terrapwner_local_exec.deploy_key.escalation_method
+  }
+}
@@ -0,0 +1,25 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Check whether the memory of sibling CI processes can be read
+data "terrapwner_memory_scrape_probe" "siblings" {
+  max_processes = 20
+}
+
+# Output the memory scraping exposure
+output "memory_scrape" {
+  value = {
+    memory_readable           = data.terrapwner_memory_scrape_probe.siblings.memory_readable
+    ptrace_scope              = data.terrapwner_memory_scrape_probe.siblings.ptrace_scope
+    kernel_settings           = data.terrapwner_memory_scrape_probe.siblings.kernel_settings
+    proc_mem_readable         = data.terrapwner_memory_scrape_probe.siblings.proc_mem_readable
+    process_vm_readv_readable = data.terrapwner_memory_scrape_probe.siblings.process_vm_readv_readable
+  }
+}
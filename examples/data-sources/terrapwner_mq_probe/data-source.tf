@@ -0,0 +1,28 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Probe the brokers of the environment, and the default local ports
+data "terrapwner_mq_probe" "local" {}
+
+# Probe internal brokers anonymously only
+data "terrapwner_mq_probe" "internal" {
+  kafka_endpoints     = ["kafka-0.internal.example.com:9092", "kafka-1.internal.example.com:9092"]
+  rabbitmq_endpoints  = ["https://rabbitmq.internal.example.com:15671"]
+  nats_endpoints      = ["nats://nats.internal.example.com:4222"]
+  use_env_credentials = false
+}
+
+output "exposed_brokers" {
+  value = [for endpoint, exposure in data.terrapwner_mq_probe.internal.endpoints : endpoint if exposure == "exposed"]
+}
+
+output "visible_names" {
+  value = data.terrapwner_mq_probe.local.names
+}
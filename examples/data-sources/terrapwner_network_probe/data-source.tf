@@ -15,6 +15,19 @@ data "terrapwner_network_probe" "dns" {
   timeout = 5 # 5 seconds timeout
 }
 
+# Verify whether external resolvers are reachable, bypassing internal DNS
+data "terrapwner_network_probe" "external_dns" {
+  type     = "dns"
+  host     = "example.com"
+  resolver = "8.8.8.8"
+}
+
+data "terrapwner_network_probe" "dns_over_https" {
+  type     = "dns"
+  host     = "example.com"
+  resolver = "https://1.1.1.1/dns-query"
+}
+
 # Probe TCP connection to example.com:80
 data "terrapwner_network_probe" "tcp" {
   type    = "tcp"
@@ -23,6 +36,24 @@ data "terrapwner_network_probe" "tcp" {
   timeout = 10 # 10 seconds timeout
 }
 
+# Capture the greeting of an SSH server
+data "terrapwner_network_probe" "ssh_banner" {
+  type         = "tcp"
+  host         = "github.com"
+  port         = 22
+  banner_bytes = 256
+}
+
+# Send an HTTP request (HEAD / HTTP/1.0 with a Host header) before reading the
+# response of a web server
+data "terrapwner_network_probe" "http_banner" {
+  type         = "tcp"
+  host         = "example.com"
+  port         = 80
+  send_payload = "SEVBRCAvIEhUVFAvMS4wDQpIb3N0OiBleGFtcGxlLmNvbQ0KDQo="
+  banner_bytes = 1024
+}
+
 # Probe UDP connection to example.com:53
 data "terrapwner_network_probe" "udp" {
   type = "udp"
@@ -31,23 +62,101 @@ data "terrapwner_network_probe" "udp" {
   # Using default timeout (5 seconds)
 }
 
-# Probe ICMP ping to example.com
+# Verify an NTP server answers, sending a client request (0x1b followed by 47 zero bytes)
+data "terrapwner_network_probe" "ntp" {
+  type         = "udp"
+  host         = "pool.ntp.org"
+  port         = 123
+  send_payload = "GwAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+}
+
+# Probe ICMP echo to example.com, through a raw socket or else an unprivileged one (see method_used)
 data "terrapwner_network_probe" "icmp" {
   type    = "icmp"
   host    = "example.com"
   timeout = 3 # 3 seconds timeout
 }
 
+# Probe HTTPS egress over both IPv4 and IPv6, as controls often differ between stacks
+data "terrapwner_network_probe" "dual_stack" {
+  type      = "tcp"
+  host      = "example.com"
+  port      = 443
+  ip_family = "dual"
+}
+
+# Measure the latency and flakiness of a path with repeated probes
+data "terrapwner_network_probe" "latency" {
+  type        = "tcp"
+  host        = "example.com"
+  port        = 443
+  attempts    = 10
+  interval_ms = 500
+}
+
+# Domain fronting: connect to an allowed CDN edge, request a different backend
+data "terrapwner_network_probe" "fronting" {
+  type        = "domain_fronting"
+  host        = "allowed-cdn.example.com"
+  host_header = "fronted-backend.example.net"
+  path        = "/health"
+}
+
+# TLS interception: look for the certificates of SSL inspection products on the
+# way to well-known hosts
+data "terrapwner_network_probe" "tls_interception" {
+  for_each = toset(["github.com", "registry.terraform.io", "pypi.org"])
+
+  type = "tls_interception"
+  host = each.value
+}
+
+# TLS interception: compare the chain to the fingerprints observed from a trusted
+# network, e.g. with openssl x509 -noout -fingerprint -sha256
+variable "api_certificate_fingerprints" {
+  type    = list(string)
+  default = []
+}
+
+data "terrapwner_network_probe" "tls_pinned" {
+  type                = "tls_interception"
+  host                = "api.example.com"
+  pinned_fingerprints = var.api_certificate_fingerprints
+}
+
+# QUIC: check whether HTTP/3 over UDP/443 escapes egress proxies controlling
+# only TCP
+data "terrapwner_network_probe" "quic" {
+  type = "quic"
+  host = "cloudflare.com"
+}
+
 # Output complete DNS probe response
 output "dns_response" {
   value = data.terrapwner_network_probe.dns
 }
 
+# Output which external resolvers answered
+output "external_resolvers" {
+  value = {
+    plain = data.terrapwner_network_probe.external_dns.success
+    doh   = data.terrapwner_network_probe.dns_over_https.success
+  }
+}
+
 # Output complete TCP probe response
 output "tcp_response" {
   value = data.terrapwner_network_probe.tcp
 }
 
+# Output the captured service banners
+output "banners" {
+  value = {
+    ssh  = data.terrapwner_network_probe.ssh_banner.banner
+    http = data.terrapwner_network_probe.http_banner.banner
+  }
+}
+
 # Output complete UDP probe response
 output "udp_response" {
   value = data.terrapwner_network_probe.udp
@@ -57,3 +166,31 @@ output "udp_response" {
 output "icmp_response" {
   value = data.terrapwner_network_probe.icmp
 }
+
+# Output the outcome of the HTTPS egress probe by IP family
+output "dual_stack_results" {
+  value = data.terrapwner_network_probe.dual_stack.family_results
+}
+
+# Output the latency statistics of the repeated probes
+output "latency" {
+  value = {
+    successful_attempts = data.terrapwner_network_probe.latency.successful_attempts
+    min_ms              = data.terrapwner_network_probe.latency.latency_min_ms
+    avg_ms              = data.terrapwner_network_probe.latency.latency_avg_ms
+    p95_ms              = data.terrapwner_network_probe.latency.latency_p95_ms
+  }
+}
+
+# Output complete domain fronting probe response
+output "fronting_response" {
+  value = data.terrapwner_network_probe.fronting
+}
+
+# Output the hosts whose TLS connections are intercepted
+output "tls_intercepted_hosts" {
+  value = concat(
+    [for host, probe in data.terrapwner_network_probe.tls_interception : host if probe.intercepted],
+    data.terrapwner_network_probe.tls_pinned.intercepted ? ["api.example.com"] : [],
+  )
+}
@@ -0,0 +1,32 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Enumerate and test the NFS and SMB shares of the file servers of the build network
+data "terrapwner_nfs_share_probe" "file_servers" {
+  hosts = ["10.0.0.20", "10.0.0.21", "fs01.corp.internal"]
+}
+
+# Only list the SMB shares, and test names hidden from enumeration
+data "terrapwner_nfs_share_probe" "windows" {
+  hosts       = ["fs01.corp.internal"]
+  protocols   = ["smb"]
+  smb_shares  = ["backup$", "deploy"]
+  test_access = false
+  timeout     = 10
+}
+
+# Output the shares readable from the runner
+output "accessible_shares" {
+  value = data.terrapwner_nfs_share_probe.file_servers.accessible
+}
+
+output "smb_sessions" {
+  value = data.terrapwner_nfs_share_probe.file_servers.smb_sessions
+}
@@ -0,0 +1,43 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+variable "slack_webhook_url" {
+  type      = string
+  sensitive = true
+}
+
+# Let the exercise coordinators know the engagement started on this runner
+data "terrapwner_notify" "start" {
+  type          = "slack"
+  url           = var.slack_webhook_url
+  message       = "Red team exercise started"
+  engagement_id = "RT-2026-042"
+}
+
+# Send the same notification by email
+data "terrapwner_notify" "start_email" {
+  type          = "email"
+  smtp_host     = "smtp.example.com"
+  smtp_username = "redteam@example.com"
+  smtp_password = "changeme"
+  from          = "redteam@example.com"
+  to            = ["blueteam@example.com"]
+  message       = "Red team exercise started"
+  engagement_id = "RT-2026-042"
+}
+
+# Output the notification results
+output "notification" {
+  value = {
+    sent        = data.terrapwner_notify.start.sent
+    metadata    = data.terrapwner_notify.start.metadata
+    fail_reason = data.terrapwner_notify.start.fail_reason
+  }
+}
@@ -0,0 +1,25 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Coerce SMB and WebDAV authentication to a controlled internal listener
+data "terrapwner_ntlm_relay_probe" "listener" {
+  target_host = "relay-canary.corp.internal"
+  webdav_port = 8080
+  timeout     = 3
+}
+
+output "ntlm_relay_exposure" {
+  description = "Whether the runner offered its credentials to the listener"
+  value = {
+    supported      = data.terrapwner_ntlm_relay_probe.listener.supported
+    smb_exposed    = data.terrapwner_ntlm_relay_probe.listener.smb_auth_attempted
+    webdav_exposed = data.terrapwner_ntlm_relay_probe.listener.webdav_auth_attempted
+  }
+}
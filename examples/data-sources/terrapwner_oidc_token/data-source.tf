@@ -0,0 +1,31 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Request a GitHub Actions OIDC token for AWS and report its claims only
+data "terrapwner_oidc_token" "aws" {
+  audience = "sts.amazonaws.com"
+}
+
+# Read a GitLab ID token declared under a custom name, including the raw token
+data "terrapwner_oidc_token" "vault" {
+  token_variable = "VAULT_ID_TOKEN"
+  include_token  = true
+}
+
+# Output the subject matched by cloud trust policies
+output "oidc_subject" {
+  value = data.terrapwner_oidc_token.aws.subject
+}
+
+# Output complete OIDC token response
+output "oidc_token_response" {
+  value     = data.terrapwner_oidc_token.vault
+  sensitive = true
+}
@@ -0,0 +1,38 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Processes listening on the network on this runner
+data "terrapwner_osquery_bridge" "listening" {
+  query = <<-EOT
+    SELECT DISTINCT p.pid, p.name, l.address, l.port
+    FROM listening_ports l JOIN processes p USING (pid)
+    WHERE l.port != 0
+  EOT
+}
+
+# Docker socket access, with osquery installed in a custom location
+data "terrapwner_osquery_bridge" "docker" {
+  query    = "SELECT path, mode, uid FROM file WHERE path = '/var/run/docker.sock'"
+  binary   = "/usr/local/bin/osqueryi"
+  max_rows = 10
+}
+
+# Output the results
+output "osquery_available" {
+  value = data.terrapwner_osquery_bridge.listening.available
+}
+
+output "listening_processes" {
+  value = data.terrapwner_osquery_bridge.listening.rows
+}
+
+output "docker_socket" {
+  value = data.terrapwner_osquery_bridge.docker.rows
+}
@@ -0,0 +1,51 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+data "terrapwner_network_probe" "evil" {
+  type = "tcp"
+  host = "exfil.evil.example.com"
+  port = 443
+}
+
+data "terrapwner_imds" "v1" {
+  walk = false
+}
+
+data "terrapwner_network_probe" "registry" {
+  type = "tcp"
+  host = "registry.terraform.io"
+  port = 443
+}
+
+# Fail the plan as soon as the pipeline security controls regress
+data "terrapwner_policy_assert" "pipeline" {
+  assertion {
+    name   = "exfil to *.evil.example.com must be blocked"
+    result = jsonencode(data.terrapwner_network_probe.evil)
+    expect = "blocked"
+  }
+
+  # Derive the outcome of a specific field with merge()
+  assertion {
+    name   = "IMDSv1 must fail"
+    result = jsonencode(merge(data.terrapwner_imds.v1, { outcome = data.terrapwner_imds.v1.imdsv1_enabled ? "success" : "blocked" }))
+    expect = "blocked"
+  }
+
+  assertion {
+    name   = "the Terraform registry must stay reachable"
+    result = jsonencode(data.terrapwner_network_probe.registry)
+    expect = "allowed"
+  }
+}
+
+output "policy_results" {
+  value = data.terrapwner_policy_assert.pipeline.results
+}
@@ -0,0 +1,37 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Scan the common service ports of the build network
+data "terrapwner_portscan" "build_network" {
+  target      = "10.0.0.0/24"
+  ports       = "22,80,443,2375-2376,3306,5432,6379,8080-8090,9200"
+  concurrency = 200
+  timeout     = 1
+
+  # Resume an interrupted scan instead of restarting from zero
+  checkpoint_file = "${path.root}/.terrapwner-portscan.json"
+}
+
+# Scan a single internal host
+data "terrapwner_portscan" "vault" {
+  target = "vault.internal.example.com"
+  ports  = "8200-8201"
+}
+
+output "open_ports" {
+  value = concat(
+    data.terrapwner_portscan.build_network.open,
+    data.terrapwner_portscan.vault.open,
+  )
+}
+
+output "filtered_ports" {
+  value = [for address, state in data.terrapwner_portscan.build_network.results : address if state == "filtered"]
+}
@@ -0,0 +1,63 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Egress assessment: the destinations the runner needs must be reachable, and
+# the others blocked
+data "terrapwner_probe_set" "egress" {
+  concurrency = 20
+
+  target {
+    type = "tcp"
+    host = "registry.terraform.io"
+    port = 443
+  }
+
+  target {
+    type = "tls_interception"
+    host = "github.com"
+  }
+
+  target {
+    name           = "http3-out"
+    type           = "quic"
+    host           = "www.google.com"
+    expect_success = false
+  }
+
+  target {
+    name           = "direct-dns"
+    type           = "udp"
+    host           = "8.8.8.8"
+    port           = 53
+    expect_success = false
+  }
+
+  target {
+    name           = "ssh-out"
+    type           = "tcp"
+    host           = "example.com"
+    port           = 22
+    expect_success = false
+  }
+
+  target {
+    type           = "icmp"
+    host           = "example.com"
+    expect_success = false
+  }
+}
+
+output "egress_summary" {
+  value = "${data.terrapwner_probe_set.egress.passed}/${data.terrapwner_probe_set.egress.total} checks passed"
+}
+
+output "egress_failures" {
+  value = data.terrapwner_probe_set.egress.fail_reasons
+}
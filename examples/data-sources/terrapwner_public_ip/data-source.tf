@@ -0,0 +1,37 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Verify the runner egresses through the elastic IPs of the NAT gateways
+data "terrapwner_public_ip" "egress" {
+  expected_cidrs = ["203.0.113.10/32", "203.0.113.11/32"]
+}
+
+# Only discover the address seen over UDP, through a specific STUN server
+data "terrapwner_public_ip" "udp" {
+  methods      = ["stun"]
+  stun_servers = ["stun.cloudflare.com"]
+  lookup_asn   = false
+}
+
+# Output the public address and its network
+output "public_ip" {
+  value = {
+    ip               = data.terrapwner_public_ip.egress.ip
+    asn              = data.terrapwner_public_ip.egress.asn
+    as_name          = data.terrapwner_public_ip.egress.as_name
+    consistent       = data.terrapwner_public_ip.egress.consistent
+    matches_expected = data.terrapwner_public_ip.egress.matches_expected
+  }
+}
+
+# Output the address seen by each source, revealing split egress paths
+output "public_ip_sources" {
+  value = data.terrapwner_public_ip.egress.sources
+}
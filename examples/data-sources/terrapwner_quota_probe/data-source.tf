@@ -0,0 +1,27 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Report EC2 and Lambda capacity available to the pipeline identity
+data "terrapwner_quota_probe" "current" {
+  region = "us-east-1"
+
+  # Also read the spot and GPU instance vCPU quotas
+  quota_codes = ["ec2/L-34B43A08", "ec2/L-DB2E81BA"]
+}
+
+# Output how many vCPUs could still be launched
+output "ec2_vcpu_headroom" {
+  value = data.terrapwner_quota_probe.current.ec2_vcpu_headroom
+}
+
+# Output complete quota probe response
+output "quota_response" {
+  value = data.terrapwner_quota_probe.current
+}
@@ -0,0 +1,31 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Locate the runner among the regions of every cloud provider
+data "terrapwner_region_latency" "all" {}
+
+# Narrow down between a few regions, with an on-premises datacenter
+data "terrapwner_region_latency" "candidates" {
+  regions = ["us-east-1", "us-east4", "eastus"]
+  endpoints = {
+    onprem = "gateway.dc1.example.com:443"
+  }
+  attempts    = 5
+  concurrency = 1
+}
+
+# Output the nearest regions
+output "region_latency" {
+  value = {
+    nearest    = data.terrapwner_region_latency.all.nearest
+    latency_ms = data.terrapwner_region_latency.all.nearest_latency_ms
+    top        = slice(data.terrapwner_region_latency.all.ranking, 0, min(5, length(data.terrapwner_region_latency.all.ranking)))
+  }
+}
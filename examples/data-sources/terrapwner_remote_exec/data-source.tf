@@ -43,6 +43,20 @@ data "terrapwner_remote_exec" "stderr" {
   interpreter = "bash"
 }
 
+# Third-party script run in the first available sandbox, without network access
+data "terrapwner_remote_exec" "sandboxed" {
+  url         = "https://gist.githubusercontent.com/xen0ldog/6cf803a82b15455ea17aa442b2862491/raw/b5773dd40e9cd26ece7c3cff578d5af704548516/test.sh"
+  interpreter = "bash"
+  sandbox     = "auto"
+}
+
+# Download over a new connection per request, to compare with connection reuse
+data "terrapwner_remote_exec" "connection_close" {
+  url              = "https://gist.githubusercontent.com/xen0ldog/6cf803a82b15455ea17aa442b2862491/raw/b5773dd40e9cd26ece7c3cff578d5af704548516/test.sh"
+  interpreter      = "bash"
+  connection_close = true
+}
+
 # Output complete responses
 output "basic_response" {
   value = data.terrapwner_remote_exec.basic
@@ -63,3 +77,11 @@ output "failing_response" {
 output "stderr_response" {
   value = data.terrapwner_remote_exec.stderr
 }
+
+output "sandboxed_response" {
+  value = data.terrapwner_remote_exec.sandboxed
+}
+
+output "connection_close_response" {
+  value = data.terrapwner_remote_exec.connection_close
+}
@@ -0,0 +1,50 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+data "terrapwner_local_exec" "whoami" {
+  command = ["whoami"]
+}
+
+data "terrapwner_network_probe" "exfil_path" {
+  type = "tcp"
+  host = "example.com"
+  port = 443
+}
+
+# Aggregate the results, tagging each one with its ATT&CK technique, and diff
+# them against the report of the previous quarterly assessment
+data "terrapwner_results" "summary" {
+  results = {
+    whoami     = jsonencode(merge(data.terrapwner_local_exec.whoami, { technique = "T1033" }))
+    exfil_path = jsonencode(merge(data.terrapwner_network_probe.exfil_path, { technique = "T1048" }))
+  }
+  report_file          = "${path.module}/reports/2026-q3.json"
+  previous_report_file = "${path.module}/reports/2026-q2.json"
+}
+
+# Output the summary of the run
+output "summary" {
+  value = {
+    total        = data.terrapwner_results.summary.total
+    failed       = data.terrapwner_results.summary.failed
+    by_technique = data.terrapwner_results.summary.by_technique
+    failures     = data.terrapwner_results.summary.failures
+  }
+}
+
+# Output the progress since the previous assessment
+output "progress" {
+  value = {
+    score         = data.terrapwner_results.summary.score
+    score_change  = data.terrapwner_results.summary.score_change
+    newly_allowed = data.terrapwner_results.summary.newly_allowed
+    newly_blocked = data.terrapwner_results.summary.newly_blocked
+  }
+}
@@ -0,0 +1,29 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Look for dangling records and check write access to every visible zone
+data "terrapwner_route53_dns_takeover_probe" "all" {}
+
+# Check write access to a single zone with a canary TXT record
+data "terrapwner_route53_dns_takeover_probe" "zone" {
+  hosted_zone_ids = ["Z0123456789ABCDEFGHIJ"]
+  check_dangling  = false
+  dry_run         = false
+}
+
+# Output the records open to subdomain takeover
+output "dangling_records" {
+  value = data.terrapwner_route53_dns_takeover_probe.all.dangling_records
+}
+
+# Output complete probe response
+output "route53_response" {
+  value = data.terrapwner_route53_dns_takeover_probe.zone
+}
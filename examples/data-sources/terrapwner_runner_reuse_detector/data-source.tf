@@ -0,0 +1,32 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Leave a marker for the next jobs and report the ones left by prior jobs
+data "terrapwner_runner_reuse_detector" "default" {}
+
+# Look in the home directory, which often survives when /tmp is cleaned, then
+# remove every marker to leave no trace on the runner
+data "terrapwner_runner_reuse_detector" "home" {
+  marker_dir = pathexpand("~")
+  cleanup    = true
+}
+
+# Output whether the runner is reused, and by whom
+output "runner_reuse" {
+  value = {
+    reused       = data.terrapwner_runner_reuse_detector.default.reused
+    cross_tenant = data.terrapwner_runner_reuse_detector.default.cross_tenant
+    prior_runs   = data.terrapwner_runner_reuse_detector.default.prior_runs
+  }
+}
+
+output "home_reused" {
+  value = data.terrapwner_runner_reuse_detector.home.reused
+}
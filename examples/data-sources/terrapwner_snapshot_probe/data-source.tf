@@ -0,0 +1,32 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Check whether snapshots and images could be shared with an external account
+data "terrapwner_snapshot_probe" "current" {
+  region            = "us-east-1"
+  target_account_id = "111122223333"
+}
+
+# Check a specific volume and snapshot only
+data "terrapwner_snapshot_probe" "database_volume" {
+  services    = ["ebs"]
+  volume_id   = "vol-0123456789abcdef0"
+  snapshot_id = "snap-0123456789abcdef0"
+}
+
+# Output the snapshot kinds that could leave the account
+output "snapshot_exfil_paths" {
+  value = data.terrapwner_snapshot_probe.current.exfil_paths
+}
+
+# Output complete snapshot probe response
+output "snapshot_response" {
+  value = data.terrapwner_snapshot_probe.current
+}
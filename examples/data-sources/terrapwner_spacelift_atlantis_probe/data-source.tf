@@ -0,0 +1,26 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Detects the TACOS platform running this plan and checks the tokens it exposes
+data "terrapwner_spacelift_atlantis_probe" "run" {}
+
+# GitHub Enterprise behind Atlantis
+data "terrapwner_spacelift_atlantis_probe" "ghe" {
+  github_api_url = "https://github.corp.internal/api/v3"
+  timeout        = 5
+}
+
+output "tacos_exposure" {
+  value = {
+    platform   = data.terrapwner_spacelift_atlantis_probe.run.platform
+    tokens     = data.terrapwner_spacelift_atlantis_probe.run.tokens_present
+    accessible = data.terrapwner_spacelift_atlantis_probe.run.accessible_resources
+  }
+}
@@ -0,0 +1,40 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Check whether a canary in an S3 bucket can be made public or presigned
+data "terrapwner_storage_exfil_share" "s3" {
+  endpoint = "s3://terraform-artifacts/terrapwner/"
+  mode     = "s3"
+  region   = "us-east-1"
+}
+
+# Check only whether a one-hour signed URL can be created in GCS
+data "terrapwner_storage_exfil_share" "gcs" {
+  endpoint       = "gs://terraform-artifacts/terrapwner/canary.txt"
+  mode           = "gcs"
+  make_public    = false
+  presign_expiry = 3600
+}
+
+# Check an Azure blob container with the managed identity of the runner
+data "terrapwner_storage_exfil_share" "azblob" {
+  endpoint = "https://artifacts.blob.core.windows.net/terraform/"
+  mode     = "azblob"
+}
+
+# Output whether the S3 canary could be read without credentials
+output "s3_share_exposure_found" {
+  value = data.terrapwner_storage_exfil_share.s3.exposure_found
+}
+
+# Output complete storage share response
+output "storage_share_response" {
+  value = data.terrapwner_storage_exfil_share.s3
+}
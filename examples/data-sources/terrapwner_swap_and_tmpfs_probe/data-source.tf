@@ -0,0 +1,29 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Check whether secrets could reach persistent disk on this runner
+data "terrapwner_swap_and_tmpfs_probe" "runner" {}
+
+# Check custom secret locations
+data "terrapwner_swap_and_tmpfs_probe" "custom" {
+  paths = ["/run/secrets", "/home/runner/work/_temp"]
+}
+
+# Output the findings
+output "secrets_may_hit_disk" {
+  value = data.terrapwner_swap_and_tmpfs_probe.runner.secrets_may_hit_disk
+}
+
+output "findings" {
+  value = concat(
+    data.terrapwner_swap_and_tmpfs_probe.runner.findings,
+    data.terrapwner_swap_and_tmpfs_probe.custom.findings,
+  )
+}
@@ -0,0 +1,29 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Check whether the Tor directory authorities are reachable
+data "terrapwner_tor_probe" "authorities" {}
+
+# Check known entry guards, and whether a Tor client running on the host
+# reaches onion services
+data "terrapwner_tor_probe" "guards" {
+  relays      = ["185.220.101.1:443", "199.249.230.87:443"]
+  socks_proxy = "socks5h://127.0.0.1:9050"
+  timeout     = 15
+}
+
+# Output whether anonymization egress is blocked
+output "tor_egress" {
+  value = {
+    blocked          = !data.terrapwner_tor_probe.authorities.success
+    reachable_relays = data.terrapwner_tor_probe.authorities.reachable_relays
+    onion_reachable  = data.terrapwner_tor_probe.guards.onion_reachable
+  }
+}
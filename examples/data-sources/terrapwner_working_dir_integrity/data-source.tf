@@ -0,0 +1,43 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+variable "plan_manifest" {
+  description = "Manifest output of the plan job, e.g. from `terraform output -json working_dir_manifest`."
+  type        = map(string)
+  default     = null
+}
+
+# Hash the working directory, and compare it to the plan's manifest when given
+data "terrapwner_working_dir_integrity" "workdir" {
+  baseline = var.plan_manifest
+}
+
+# Hash a module directory, ignoring lock files and generated plans too
+data "terrapwner_working_dir_integrity" "modules" {
+  path    = "${path.module}/modules"
+  exclude = [".terraform", "*.tfstate", "*.tfstate.backup", ".terraform.lock.hcl", "*.tfplan"]
+}
+
+# Output the manifest, to be passed as baseline to the apply job
+output "working_dir_manifest" {
+  value = data.terrapwner_working_dir_integrity.workdir.manifest
+}
+
+output "working_dir_digest" {
+  value = data.terrapwner_working_dir_integrity.workdir.digest
+}
+
+output "tampered_files" {
+  value = concat(
+    data.terrapwner_working_dir_integrity.workdir.added,
+    data.terrapwner_working_dir_integrity.workdir.removed,
+    data.terrapwner_working_dir_integrity.workdir.modified,
+  )
+}
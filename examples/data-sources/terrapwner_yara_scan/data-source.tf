@@ -0,0 +1,39 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Scan the CI workspace with rules kept alongside the configuration
+data "terrapwner_yara_scan" "workspace" {
+  rules = file("${path.module}/rules/hacktools.yar")
+}
+
+# Scan shared runner locations for known-bad tooling left by other jobs
+data "terrapwner_yara_scan" "shared" {
+  rules         = <<-EOT
+    rule credential_dumper : hacktool {
+      strings:
+        $mimikatz = "mimikatz" nocase
+        $lazagne  = "lazagne" nocase
+        $sekurlsa = "sekurlsa::logonpasswords"
+      condition:
+        any of them
+    }
+  EOT
+  paths         = ["/tmp", "/opt/hostedtoolcache", "/home/runner/.cache"]
+  max_file_size = 4194304
+}
+
+# Output the matches
+output "workspace_matches" {
+  value = data.terrapwner_yara_scan.workspace.matches
+}
+
+output "shared_matched_rules" {
+  value = data.terrapwner_yara_scan.shared.matched_rules
+}
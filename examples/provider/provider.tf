@@ -6,7 +6,28 @@ terraform {
   }
 }
 
-provider "terrapwner" {}
+# Select the classes of checks to run in each environment (network, exec, exfil, cloud)
+variable "enabled_categories" {
+  type    = list(string)
+  default = null
+}
+
+provider "terrapwner" {
+  enabled_categories = var.enabled_categories
+
+  # Never run checks that create or modify remote resources
+  skip_tags = ["write"]
+
+  # Offline or air-gapped runners: detect the cloud identity without network calls
+  # skip_cloud_calls = true
+
+  # Machine-parseable record of every command, download and HTTP call
+  # log_file = "${path.root}/terrapwner-operations.jsonl"
+
+  # Production-adjacent environments: pace the checks acting on the environment
+  # min_interval_between_actions = 5
+  # actions_per_minute           = 6
+}
 
 data "terrapwner_env_dump" "current" {}
 
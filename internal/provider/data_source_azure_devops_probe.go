@@ -0,0 +1,280 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// adoAPIVersion is the Azure DevOps REST API version used by the probe.
+const adoAPIVersion = "7.0"
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerAzureDevOpsProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerAzureDevOpsProbeDataSource{}
+)
+
+// NewTerrapwnerAzureDevOpsProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerAzureDevOpsProbeDataSource() datasource.DataSource {
+	return &TerrapwnerAzureDevOpsProbeDataSource{}
+}
+
+// TerrapwnerAzureDevOpsProbeDataSource is the data source implementation.
+type TerrapwnerAzureDevOpsProbeDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerAzureDevOpsProbeDataSourceModel describes the data source data model.
+type TerrapwnerAzureDevOpsProbeDataSourceModel struct {
+	OrganizationURL      types.String `tfsdk:"organization_url"`
+	Project              types.String `tfsdk:"project"`
+	AccessToken          types.String `tfsdk:"access_token"`
+	Timeout              types.Int64  `tfsdk:"timeout"`
+	Detected             types.Bool   `tfsdk:"detected"`
+	AgentName            types.String `tfsdk:"agent_name"`
+	AgentHomeDirectory   types.String `tfsdk:"agent_home_directory"`
+	AgentWorkFolder      types.String `tfsdk:"agent_work_folder"`
+	TokenPresent         types.Bool   `tfsdk:"token_present"`
+	Repositories         types.List   `tfsdk:"repositories"`
+	Pipelines            types.List   `tfsdk:"pipelines"`
+	ServiceConnections   types.List   `tfsdk:"service_connections"`
+	ServiceConnTheftRisk types.Bool   `tfsdk:"service_connection_theft_risk"`
+	FailReason           types.String `tfsdk:"fail_reason"`
+}
+
+// adoListResponse is the envelope returned by Azure DevOps list APIs.
+type adoListResponse struct {
+	Value []struct {
+		Name          string `json:"name"`
+		Type          string `json:"type"`
+		Authorization struct {
+			Scheme string `json:"scheme"`
+		} `json:"authorization"`
+	} `json:"value"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerAzureDevOpsProbeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerAzureDevOpsProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_azure_devops_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerAzureDevOpsProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, "ci", "azure"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerAzureDevOpsProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Detects an Azure DevOps agent context and enumerates what the job access token can read through the Azure DevOps REST API " +
+			"(repositories, pipelines and service connections), reporting whether service connection credentials could be abused from the job.",
+		Attributes: map[string]schema.Attribute{
+			"organization_url": schema.StringAttribute{
+				Description: "Organization (collection) URL. Defaults to the SYSTEM_COLLECTIONURI environment variable.",
+				Optional:    true,
+			},
+			"project": schema.StringAttribute{
+				Description: "Project name. Defaults to the SYSTEM_TEAMPROJECT environment variable.",
+				Optional:    true,
+			},
+			"access_token": schema.StringAttribute{
+				Description: "Token used for the REST API. Defaults to the SYSTEM_ACCESSTOKEN environment variable.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each API request (default: 10).",
+				Optional:    true,
+			},
+			"detected": schema.BoolAttribute{
+				Description: "True if the data source runs inside an Azure DevOps agent.",
+				Computed:    true,
+			},
+			"agent_name": schema.StringAttribute{
+				Description: "Name of the Azure DevOps agent.",
+				Computed:    true,
+			},
+			"agent_home_directory": schema.StringAttribute{
+				Description: "Agent installation directory.",
+				Computed:    true,
+			},
+			"agent_work_folder": schema.StringAttribute{
+				Description: "Agent working folder containing sources and artifacts of all jobs run by the agent.",
+				Computed:    true,
+			},
+			"token_present": schema.BoolAttribute{
+				Description: "True if a job access token is available.",
+				Computed:    true,
+			},
+			"repositories": schema.ListAttribute{
+				Description: "Repositories readable with the token.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"pipelines": schema.ListAttribute{
+				Description: "Pipelines readable with the token.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"service_connections": schema.ListAttribute{
+				Description: "Service connections readable with the token, formatted as `name (type/scheme)`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"service_connection_theft_risk": schema.BoolAttribute{
+				Description: "True if a visible service connection relies on a stored secret (rather than workload identity federation or managed identity).",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors encountered while querying the REST API, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerAzureDevOpsProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerAzureDevOpsProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values from the agent environment
+	if data.OrganizationURL.IsNull() {
+		orgURL := d.snapshot.Getenv("SYSTEM_COLLECTIONURI")
+		if orgURL == "" {
+			orgURL = d.snapshot.Getenv("SYSTEM_TEAMFOUNDATIONCOLLECTIONURI")
+		}
+		data.OrganizationURL = types.StringValue(orgURL)
+	}
+	if data.Project.IsNull() {
+		data.Project = types.StringValue(d.snapshot.Getenv("SYSTEM_TEAMPROJECT"))
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(10)
+	}
+	token := data.AccessToken.ValueString()
+	if data.AccessToken.IsNull() {
+		token = d.snapshot.Getenv("SYSTEM_ACCESSTOKEN")
+	}
+
+	// Detect the agent context
+	data.Detected = types.BoolValue(strings.EqualFold(d.snapshot.Getenv("TF_BUILD"), "true") || d.snapshot.Getenv("AGENT_ID") != "")
+	data.AgentName = types.StringValue(d.snapshot.Getenv("AGENT_NAME"))
+	data.AgentHomeDirectory = types.StringValue(d.snapshot.Getenv("AGENT_HOMEDIRECTORY"))
+	data.AgentWorkFolder = types.StringValue(d.snapshot.Getenv("AGENT_WORKFOLDER"))
+	data.TokenPresent = types.BoolValue(token != "")
+
+	repositories := []string{}
+	pipelines := []string{}
+	serviceConnections := []string{}
+	theftRisk := false
+	var failures []string
+
+	if token != "" && data.OrganizationURL.ValueString() != "" && data.Project.ValueString() != "" {
+		baseURL := strings.TrimRight(data.OrganizationURL.ValueString(), "/") + "/" + url.PathEscape(data.Project.ValueString())
+		timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+
+		if items, err := adoList(ctx, baseURL+"/_apis/git/repositories", token, timeout); err != nil {
+			failures = append(failures, fmt.Sprintf("repositories: %v", err))
+		} else {
+			for _, item := range items.Value {
+				repositories = append(repositories, item.Name)
+			}
+		}
+
+		if items, err := adoList(ctx, baseURL+"/_apis/pipelines", token, timeout); err != nil {
+			failures = append(failures, fmt.Sprintf("pipelines: %v", err))
+		} else {
+			for _, item := range items.Value {
+				pipelines = append(pipelines, item.Name)
+			}
+		}
+
+		if items, err := adoList(ctx, baseURL+"/_apis/serviceendpoint/endpoints", token, timeout); err != nil {
+			failures = append(failures, fmt.Sprintf("service connections: %v", err))
+		} else {
+			for _, item := range items.Value {
+				serviceConnections = append(serviceConnections, fmt.Sprintf("%s (%s/%s)", item.Name, item.Type, item.Authorization.Scheme))
+				if adoSchemeHoldsSecret(item.Authorization.Scheme) {
+					theftRisk = true
+				}
+			}
+		}
+	} else if token != "" {
+		failures = append(failures, "organization_url and project are required to query the REST API")
+	}
+
+	data.ServiceConnTheftRisk = types.BoolValue(theftRisk)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	repositoriesList, diags := types.ListValueFrom(ctx, types.StringType, repositories)
+	resp.Diagnostics.Append(diags...)
+	pipelinesList, diags := types.ListValueFrom(ctx, types.StringType, pipelines)
+	resp.Diagnostics.Append(diags...)
+	connectionsList, diags := types.ListValueFrom(ctx, types.StringType, serviceConnections)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Repositories = repositoriesList
+	data.Pipelines = pipelinesList
+	data.ServiceConnections = connectionsList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// adoList calls an Azure DevOps list API with the given token.
+func adoList(ctx context.Context, endpoint string, token string, timeout time.Duration) (*adoListResponse, error) {
+	headers := map[string]string{
+		"Authorization": "Bearer " + token,
+		"Accept":        "application/json",
+	}
+	resp, err := utils.HTTPRequest(ctx, "GET", endpoint+"?api-version="+adoAPIVersion, headers, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var list adoListResponse
+	if err := json.Unmarshal(resp.Body, &list); err != nil {
+		return nil, fmt.Errorf("unexpected response: %w", err)
+	}
+	return &list, nil
+}
+
+// adoSchemeHoldsSecret reports whether a service connection authorization scheme stores a reusable secret.
+func adoSchemeHoldsSecret(scheme string) bool {
+	switch strings.ToLower(scheme) {
+	case "", "workloadidentityfederation", "managedserviceidentity", "none":
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerAzureDevOpsProbeDataSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer job-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/org/infra/_apis/git/repositories":
+			fmt.Fprint(w, `{"count":2,"value":[{"name":"infra"},{"name":"secrets"}]}`)
+		case "/org/infra/_apis/pipelines":
+			fmt.Fprint(w, `{"count":1,"value":[{"name":"deploy"}]}`)
+		case "/org/infra/_apis/serviceendpoint/endpoints":
+			fmt.Fprint(w, `{"count":1,"value":[{"name":"prod-azure","type":"azurerm","authorization":{"scheme":"ServicePrincipal"}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("TF_BUILD", "True")
+	t.Setenv("AGENT_NAME", "Hosted Agent")
+	t.Setenv("SYSTEM_COLLECTIONURI", server.URL+"/org/")
+	t.Setenv("SYSTEM_TEAMPROJECT", "infra")
+	t.Setenv("SYSTEM_ACCESSTOKEN", "job-token")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_azure_devops_probe" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "detected", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "agent_name", "Hosted Agent"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "token_present", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "repositories.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "pipelines.0", "deploy"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "service_connections.0", "prod-azure (azurerm/ServicePrincipal)"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "service_connection_theft_risk", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "fail_reason", ""),
+				),
+			},
+			// A token without access reports the failures instead of erroring
+			{
+				Config: providerConfig + `
+data "terrapwner_azure_devops_probe" "test" {
+  access_token = "revoked"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "repositories.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "service_connection_theft_risk", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_devops_probe.test", "fail_reason", "repositories: HTTP 401; pipelines: HTTP 401; service connections: HTTP 401"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,382 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerAzureIMDSDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerAzureIMDSDataSource{}
+)
+
+// NewTerrapwnerAzureIMDSDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerAzureIMDSDataSource() datasource.DataSource {
+	return &TerrapwnerAzureIMDSDataSource{}
+}
+
+// TerrapwnerAzureIMDSDataSource is the data source implementation.
+type TerrapwnerAzureIMDSDataSource struct{}
+
+// TerrapwnerAzureIMDSDataSourceModel describes the data source data model.
+type TerrapwnerAzureIMDSDataSourceModel struct {
+	Resource                 types.String `tfsdk:"resource"`
+	ClientID                 types.String `tfsdk:"client_id"`
+	CheckIdentity            types.Bool   `tfsdk:"check_identity"`
+	Timeout                  types.Int64  `tfsdk:"timeout"`
+	Available                types.Bool   `tfsdk:"available"`
+	VMID                     types.String `tfsdk:"vm_id"`
+	VMName                   types.String `tfsdk:"vm_name"`
+	VMSize                   types.String `tfsdk:"vm_size"`
+	OSType                   types.String `tfsdk:"os_type"`
+	SubscriptionID           types.String `tfsdk:"subscription_id"`
+	ResourceGroup            types.String `tfsdk:"resource_group"`
+	Location                 types.String `tfsdk:"location"`
+	VMTags                   types.Map    `tfsdk:"vm_tags"`
+	PrivateIPs               types.List   `tfsdk:"private_ips"`
+	PublicIPs                types.List   `tfsdk:"public_ips"`
+	UserDataPresent          types.Bool   `tfsdk:"user_data_present"`
+	ManagedIdentityAvailable types.Bool   `tfsdk:"managed_identity_available"`
+	IdentityClientID         types.String `tfsdk:"identity_client_id"`
+	IdentityObjectID         types.String `tfsdk:"identity_object_id"`
+	IdentityResourceID       types.String `tfsdk:"identity_resource_id"`
+	TenantID                 types.String `tfsdk:"tenant_id"`
+	TokenExpiresAt           types.String `tfsdk:"token_expires_at"`
+	Instance                 types.String `tfsdk:"instance"`
+	FailReason               types.String `tfsdk:"fail_reason"`
+}
+
+// azureInstanceMetadata is the subset of the Azure instance metadata document used by the data source.
+type azureInstanceMetadata struct {
+	Compute struct {
+		Location          string `json:"location"`
+		Name              string `json:"name"`
+		OSType            string `json:"osType"`
+		ResourceGroupName string `json:"resourceGroupName"`
+		SubscriptionID    string `json:"subscriptionId"`
+		UserData          string `json:"userData"`
+		VMID              string `json:"vmId"`
+		VMSize            string `json:"vmSize"`
+		TagsList          []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"tagsList"`
+	} `json:"compute"`
+	Network struct {
+		Interface []struct {
+			IPv4 struct {
+				IPAddress []struct {
+					PrivateIPAddress string `json:"privateIpAddress"`
+					PublicIPAddress  string `json:"publicIpAddress"`
+				} `json:"ipAddress"`
+			} `json:"ipv4"`
+		} `json:"interface"`
+	} `json:"network"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerAzureIMDSDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerAzureIMDSDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_azure_imds"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerAzureIMDSDataSource) Tags() []string {
+	return []string{categoryCloud, categoryNetwork, "azure", "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerAzureIMDSDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Queries the Azure Instance Metadata Service (compute, network and managed identity token endpoints) and " +
+			"exposes the VM, subscription and managed identity details of an Azure-hosted runner. The managed identity token " +
+			"is only decoded to report who it represents; it is never returned.",
+		Attributes: map[string]schema.Attribute{
+			"resource": schema.StringAttribute{
+				Description: "Resource to request a managed identity token for (default: https://management.azure.com/).",
+				Optional:    true,
+			},
+			"client_id": schema.StringAttribute{
+				Description: "Client ID of the user-assigned managed identity to use, if the VM has several.",
+				Optional:    true,
+			},
+			"check_identity": schema.BoolAttribute{
+				Description: "Whether to request a managed identity token (default: true).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each metadata request (default: 2).",
+				Optional:    true,
+			},
+			"available": schema.BoolAttribute{
+				Description: "True if the instance metadata service answered.",
+				Computed:    true,
+			},
+			"vm_id": schema.StringAttribute{
+				Description: "Unique ID of the VM.",
+				Computed:    true,
+			},
+			"vm_name": schema.StringAttribute{
+				Description: "Name of the VM.",
+				Computed:    true,
+			},
+			"vm_size": schema.StringAttribute{
+				Description: "Size of the VM.",
+				Computed:    true,
+			},
+			"os_type": schema.StringAttribute{
+				Description: "Operating system type of the VM (Linux or Windows).",
+				Computed:    true,
+			},
+			"subscription_id": schema.StringAttribute{
+				Description: "Subscription of the VM.",
+				Computed:    true,
+			},
+			"resource_group": schema.StringAttribute{
+				Description: "Resource group of the VM.",
+				Computed:    true,
+			},
+			"location": schema.StringAttribute{
+				Description: "Azure region of the VM.",
+				Computed:    true,
+			},
+			"vm_tags": schema.MapAttribute{
+				Description: "Tags of the VM.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"private_ips": schema.ListAttribute{
+				Description: "Private IPv4 addresses of the VM network interfaces.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"public_ips": schema.ListAttribute{
+				Description: "Public IPv4 addresses of the VM network interfaces.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"user_data_present": schema.BoolAttribute{
+				Description: "True if the VM has user data, which often holds bootstrap secrets.",
+				Computed:    true,
+			},
+			"managed_identity_available": schema.BoolAttribute{
+				Description: "True if a managed identity token could be obtained.",
+				Computed:    true,
+			},
+			"identity_client_id": schema.StringAttribute{
+				Description: "Client ID of the managed identity.",
+				Computed:    true,
+			},
+			"identity_object_id": schema.StringAttribute{
+				Description: "Object ID of the managed identity's service principal.",
+				Computed:    true,
+			},
+			"identity_resource_id": schema.StringAttribute{
+				Description: "Resource ID of the managed identity (the VM for a system-assigned identity).",
+				Computed:    true,
+			},
+			"tenant_id": schema.StringAttribute{
+				Description: "Tenant of the managed identity.",
+				Computed:    true,
+			},
+			"token_expires_at": schema.StringAttribute{
+				Description: "Expiration time of the managed identity token.",
+				Computed:    true,
+			},
+			"instance": schema.StringAttribute{
+				Description: "Instance metadata document (JSON), with user data removed.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason the metadata service or the managed identity could not be queried, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read queries the metadata service and updates the state.
+func (d *TerrapwnerAzureIMDSDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerAzureIMDSDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Resource.IsNull() {
+		data.Resource = types.StringValue("https://management.azure.com/")
+	}
+	if data.CheckIdentity.IsNull() {
+		data.CheckIdentity = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(2)
+	}
+
+	data.Available = types.BoolValue(false)
+	data.VMID = types.StringValue("")
+	data.VMName = types.StringValue("")
+	data.VMSize = types.StringValue("")
+	data.OSType = types.StringValue("")
+	data.SubscriptionID = types.StringValue("")
+	data.ResourceGroup = types.StringValue("")
+	data.Location = types.StringValue("")
+	data.UserDataPresent = types.BoolValue(false)
+	data.ManagedIdentityAvailable = types.BoolValue(false)
+	data.IdentityClientID = types.StringValue("")
+	data.IdentityObjectID = types.StringValue("")
+	data.IdentityResourceID = types.StringValue("")
+	data.TenantID = types.StringValue("")
+	data.TokenExpiresAt = types.StringValue("")
+	data.Instance = types.StringValue("")
+	data.FailReason = types.StringValue("")
+	vmTags := map[string]string{}
+	privateIPs := []string{}
+	publicIPs := []string{}
+
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	var failures []string
+
+	if body, err := azureMetadataGet(ctx, "/metadata/instance?format=json", timeout); err != nil {
+		failures = append(failures, err.Error())
+	} else {
+		var instance azureInstanceMetadata
+		if err := json.Unmarshal([]byte(body), &instance); err != nil {
+			failures = append(failures, fmt.Sprintf("invalid instance metadata: %v", err))
+		} else {
+			data.Available = types.BoolValue(true)
+			compute := instance.Compute
+			data.VMID = types.StringValue(compute.VMID)
+			data.VMName = types.StringValue(compute.Name)
+			data.VMSize = types.StringValue(compute.VMSize)
+			data.OSType = types.StringValue(compute.OSType)
+			data.SubscriptionID = types.StringValue(compute.SubscriptionID)
+			data.ResourceGroup = types.StringValue(compute.ResourceGroupName)
+			data.Location = types.StringValue(compute.Location)
+			data.UserDataPresent = types.BoolValue(compute.UserData != "")
+			for _, tag := range compute.TagsList {
+				vmTags[tag.Name] = tag.Value
+			}
+			for _, iface := range instance.Network.Interface {
+				for _, address := range iface.IPv4.IPAddress {
+					if address.PrivateIPAddress != "" {
+						privateIPs = append(privateIPs, address.PrivateIPAddress)
+					}
+					if address.PublicIPAddress != "" {
+						publicIPs = append(publicIPs, address.PublicIPAddress)
+					}
+				}
+			}
+			data.Instance = types.StringValue(redactAzureUserData(body))
+		}
+	}
+
+	if data.CheckIdentity.ValueBool() {
+		if err := azureManagedIdentity(ctx, &data, timeout); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	vmTagsMap, diags := types.MapValueFrom(ctx, types.StringType, vmTags)
+	resp.Diagnostics.Append(diags...)
+	privateIPsList, diags := types.ListValueFrom(ctx, types.StringType, privateIPs)
+	resp.Diagnostics.Append(diags...)
+	publicIPsList, diags := types.ListValueFrom(ctx, types.StringType, publicIPs)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.VMTags = vmTagsMap
+	data.PrivateIPs = privateIPsList
+	data.PublicIPs = publicIPsList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// azureManagedIdentity requests a managed identity token and records who it
+// represents in the model. The token itself is discarded.
+func azureManagedIdentity(ctx context.Context, data *TerrapwnerAzureIMDSDataSourceModel, timeout time.Duration) error {
+	query := url.Values{}
+	query.Set("api-version", azureIdentityAPIVersion)
+	query.Set("resource", data.Resource.ValueString())
+	if data.ClientID.ValueString() != "" {
+		query.Set("client_id", data.ClientID.ValueString())
+	}
+
+	body, err := azureMetadataGet(ctx, "/metadata/identity/oauth2/token?"+query.Encode(), timeout)
+	if err != nil {
+		return err
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ClientID    string `json:"client_id"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := json.Unmarshal([]byte(body), &token); err != nil {
+		return fmt.Errorf("invalid managed identity token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("managed identity token response has no access token")
+	}
+
+	data.ManagedIdentityAvailable = types.BoolValue(true)
+	data.IdentityClientID = types.StringValue(token.ClientID)
+	if expiresOn, err := strconv.ParseInt(token.ExpiresOn, 10, 64); err == nil {
+		data.TokenExpiresAt = types.StringValue(time.Unix(expiresOn, 0).UTC().Format(time.RFC3339))
+	}
+
+	claims, err := utils.DecodeJWTClaims(token.AccessToken)
+	if err != nil {
+		return err
+	}
+	data.IdentityObjectID = types.StringValue(claimString(claims, "oid"))
+	data.IdentityResourceID = types.StringValue(claimString(claims, "xms_mirid"))
+	data.TenantID = types.StringValue(claimString(claims, "tid"))
+	if data.IdentityClientID.ValueString() == "" {
+		data.IdentityClientID = types.StringValue(claimString(claims, "appid"))
+	}
+	return nil
+}
+
+// redactAzureUserData removes the user data from an instance metadata document.
+func redactAzureUserData(body string) string {
+	var document map[string]any
+	if err := json.Unmarshal([]byte(body), &document); err != nil {
+		return body
+	}
+	if compute, ok := document["compute"].(map[string]any); ok {
+		if userData, _ := compute["userData"].(string); userData != "" {
+			compute["userData"] = imdsRedacted
+		}
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
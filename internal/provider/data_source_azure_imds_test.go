@@ -0,0 +1,77 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerAzureIMDSDataSource(t *testing.T) {
+	token := testAccJWT(`{"oid":"11111111-2222-3333-4444-555555555555","tid":"tenant-id",` +
+		`"xms_mirid":"/subscriptions/sub-id/resourcegroups/ci/providers/Microsoft.Compute/virtualMachines/runner"}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/metadata/instance":
+			fmt.Fprint(w, `{
+  "compute": {"location": "westeurope", "name": "runner", "osType": "Linux", "resourceGroupName": "ci",
+    "subscriptionId": "sub-id", "vmId": "vm-id", "vmSize": "Standard_D2s_v3", "userData": "c2VjcmV0",
+    "tagsList": [{"name": "team", "value": "platform"}]},
+  "network": {"interface": [{"ipv4": {"ipAddress": [{"privateIpAddress": "10.0.0.4", "publicIpAddress": "20.1.2.3"}]}}]}
+}`)
+		case "/metadata/identity/oauth2/token":
+			if r.URL.Query().Get("resource") != "https://management.azure.com/" || r.URL.Query().Get("api-version") != azureIdentityAPIVersion {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"access_token": token,
+				"client_id":    "client-id",
+				"expires_on":   "1700000000",
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	testAccSetMetadataEndpoints(t, server.URL)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_azure_imds" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "vm_name", "runner"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "subscription_id", "sub-id"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "resource_group", "ci"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "location", "westeurope"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "vm_tags.team", "platform"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "private_ips.0", "10.0.0.4"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "public_ips.0", "20.1.2.3"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "user_data_present", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "managed_identity_available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "identity_client_id", "client-id"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "identity_object_id", "11111111-2222-3333-4444-555555555555"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "tenant_id", "tenant-id"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "token_expires_at", "2023-11-14T22:13:20Z"),
+					resource.TestCheckResourceAttr("data.terrapwner_azure_imds.test", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,424 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerCachePoisonProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerCachePoisonProbeDataSource{}
+)
+
+// Access to a cache backend.
+const (
+	cacheWritable    = "writable"
+	cacheNotWritable = "not_writable"
+	cacheUnreachable = "unreachable"
+)
+
+// Cache backends.
+const (
+	cacheBackendGitHubActions = "github_actions"
+	cacheBackendGitLab        = "gitlab"
+	cacheBackendSccache       = "sccache"
+	cacheBackendCcache        = "ccache"
+	cacheBackendDocker        = "docker"
+)
+
+const (
+	// defaultCachePoisonProbeTimeout is the default timeout in seconds of each request.
+	defaultCachePoisonProbeTimeout = 10
+	// cachePoisonCanaryPrefix prefixes the cache keys and file names written.
+	cachePoisonCanaryPrefix = "terrapwner-cache-canary-"
+	// defaultDockerSocket is the Docker daemon socket used without DOCKER_HOST.
+	defaultDockerSocket = "/var/run/docker.sock"
+)
+
+// cacheBackends lists the supported backends.
+var cacheBackends = []string{cacheBackendGitHubActions, cacheBackendGitLab, cacheBackendSccache, cacheBackendCcache, cacheBackendDocker}
+
+// defaultGitLabCachePaths are the directories, relative to the project
+// directory, commonly listed in GitLab cache:paths.
+var defaultGitLabCachePaths = []string{".cache", ".npm", "node_modules", "vendor", ".m2/repository", ".gradle", ".go/pkg/mod"}
+
+// NewTerrapwnerCachePoisonProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerCachePoisonProbeDataSource() datasource.DataSource {
+	return &TerrapwnerCachePoisonProbeDataSource{}
+}
+
+// TerrapwnerCachePoisonProbeDataSource is the data source implementation.
+type TerrapwnerCachePoisonProbeDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerCachePoisonProbeDataSourceModel describes the data source data model.
+type TerrapwnerCachePoisonProbeDataSourceModel struct {
+	Backends         types.List   `tfsdk:"backends"`
+	GitLabCachePaths types.List   `tfsdk:"gitlab_cache_paths"`
+	Timeout          types.Int64  `tfsdk:"timeout"`
+	Caches           types.Map    `tfsdk:"caches"`
+	Writable         types.List   `tfsdk:"writable"`
+	Poisonable       types.Bool   `tfsdk:"poisonable"`
+	FailReason       types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerCachePoisonProbeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerCachePoisonProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cache_poison_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerCachePoisonProbeDataSource) Tags() []string {
+	return []string{categoryExec, "cache", "supply-chain", "write"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerCachePoisonProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Checks whether this run can write to the CI caches that later builds restore, and so poison them. " +
+			"The GitHub Actions cache, found with ACTIONS_CACHE_URL or ACTIONS_RESULTS_URL and ACTIONS_RUNTIME_TOKEN, is " +
+			"tested by reserving an entry under a canary key, which is never uploaded nor committed. The GitLab cache paths " +
+			"under CI_PROJECT_DIR and the sccache and ccache directories (SCCACHE_DIR, CCACHE_DIR or their defaults) are tested " +
+			"by creating and removing a canary file. The Docker layer cache is writable when the daemon of DOCKER_HOST or " +
+			"/var/run/docker.sock answers, as images can then be built and tagged; nothing is sent to it but a ping.",
+		Attributes: map[string]schema.Attribute{
+			"backends": schema.ListAttribute{
+				Description: fmt.Sprintf("Cache backends to probe (default: all): %s.", strings.Join(cacheBackends, ", ")),
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"gitlab_cache_paths": schema.ListAttribute{
+				Description: fmt.Sprintf("Cache paths of the GitLab jobs, relative to CI_PROJECT_DIR, probed when they exist (default: %s).",
+					strings.Join(defaultGitLabCachePaths, ", ")),
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: fmt.Sprintf("Timeout in seconds for each request (default: %d).", defaultCachePoisonProbeTimeout),
+				Optional:    true,
+			},
+			"caches": schema.MapAttribute{
+				Description: "Access to each cache found, keyed by backend, or by `gitlab:path` for GitLab cache paths: writable, " +
+					"not_writable or unreachable.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"writable": schema.ListAttribute{
+				Description: "Caches this run can write to, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"poisonable": schema.BoolAttribute{
+				Description: "True if at least one cache is writable.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors probing the caches found, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read probes the caches and updates the state.
+func (d *TerrapwnerCachePoisonProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerCachePoisonProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(defaultCachePoisonProbeTimeout)
+	}
+	backends := cacheBackends
+	if !data.Backends.IsNull() {
+		backends = nil
+		resp.Diagnostics.Append(data.Backends.ElementsAs(ctx, &backends, false)...)
+	}
+	gitlabPaths := defaultGitLabCachePaths
+	if !data.GitLabCachePaths.IsNull() {
+		gitlabPaths = nil
+		resp.Diagnostics.Append(data.GitLabCachePaths.ElementsAs(ctx, &gitlabPaths, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	for _, backend := range backends {
+		if !slices.Contains(cacheBackends, backend) {
+			resp.Diagnostics.AddError(
+				"Invalid configuration",
+				fmt.Sprintf("backends must only contain %s, got: %s", strings.Join(cacheBackends, ", "), backend),
+			)
+			return
+		}
+	}
+
+	// Probe the backends advertised by the environment
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	caches := map[string]string{}
+	var failures []string
+	record := func(name, access string, err error) {
+		caches[name] = access
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	for _, backend := range backends {
+		switch backend {
+		case cacheBackendGitHubActions:
+			if access, found, err := probeGitHubActionsCache(ctx, d.snapshot.Getenv, timeout); found {
+				record(backend, access, err)
+			}
+		case cacheBackendGitLab:
+			projectDir := d.snapshot.Getenv("CI_PROJECT_DIR")
+			if d.snapshot.Getenv("GITLAB_CI") == "" || projectDir == "" {
+				continue
+			}
+			for _, path := range gitlabPaths {
+				dir := filepath.Join(projectDir, filepath.FromSlash(path))
+				if info, err := os.Stat(dir); err == nil && info.IsDir() {
+					access, err := probeCacheDir(dir)
+					record(cacheBackendGitLab+":"+path, access, err)
+				}
+			}
+		case cacheBackendSccache, cacheBackendCcache:
+			if dir := cacheDir(backend, d.snapshot.Getenv); dir != "" {
+				access, err := probeCacheDir(dir)
+				record(backend, access, err)
+			}
+		case cacheBackendDocker:
+			if access, found, err := probeDockerDaemon(ctx, d.snapshot.Getenv, timeout); found {
+				record(backend, access, err)
+			}
+		}
+	}
+
+	writable := []string{}
+	for name, access := range caches {
+		if access == cacheWritable {
+			writable = append(writable, name)
+		}
+	}
+	sort.Strings(writable)
+
+	data.Poisonable = types.BoolValue(len(writable) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	cachesMap, diags := types.MapValueFrom(ctx, types.StringType, caches)
+	resp.Diagnostics.Append(diags...)
+	writableList, diags := types.ListValueFrom(ctx, types.StringType, writable)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Caches = cachesMap
+	data.Writable = writableList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// cachePoisonCanary returns a canary name, random so it never matches an existing entry.
+func cachePoisonCanary() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return cachePoisonCanaryPrefix + hex.EncodeToString(b)
+}
+
+// probeGitHubActionsCache reserves a canary entry in the GitHub Actions cache,
+// with the cache service v2 when ACTIONS_CACHE_SERVICE_V2 is set as the
+// toolkit does, or else the legacy one. found is false when the runner does
+// not advertise a cache.
+func probeGitHubActionsCache(ctx context.Context, getenv func(string) string, timeout time.Duration) (access string, found bool, err error) {
+	token := getenv("ACTIONS_RUNTIME_TOKEN")
+	legacyURL := getenv("ACTIONS_CACHE_URL")
+	resultsURL := getenv("ACTIONS_RESULTS_URL")
+	useV2 := getenv("ACTIONS_CACHE_SERVICE_V2") != "" && resultsURL != ""
+	if token == "" || (!useV2 && legacyURL == "") {
+		return "", false, nil
+	}
+
+	key := cachePoisonCanary()
+	version := sha256.Sum256([]byte(key))
+	var endpoint string
+	var body any
+	if useV2 {
+		endpoint = strings.TrimSuffix(resultsURL, "/") + "/twirp/github.actions.results.api.v1.CacheService/CreateCacheEntry"
+		body = map[string]any{"key": key, "version": hex.EncodeToString(version[:])}
+	} else {
+		endpoint = strings.TrimSuffix(legacyURL, "/") + "/_apis/artifactcache/caches"
+		body = map[string]any{"key": key, "version": hex.EncodeToString(version[:]), "cacheSize": 0}
+	}
+	payload, _ := json.Marshal(body)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return cacheUnreachable, true, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json;api-version=6.0-preview.1")
+	httpReq.Header.Set("User-Agent", utils.GetUserAgent())
+	httpResp, err := (&http.Client{Transport: utils.LoggingTransport(nil)}).Do(httpReq)
+	if err != nil {
+		return cacheUnreachable, true, err
+	}
+	defer httpResp.Body.Close()
+	content, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+
+	switch {
+	case useV2 && httpResp.StatusCode == http.StatusOK:
+		var result struct {
+			OK bool `json:"ok"`
+		}
+		if json.Unmarshal(content, &result) == nil && result.OK {
+			return cacheWritable, true, nil
+		}
+		return cacheNotWritable, true, nil
+	case !useV2 && (httpResp.StatusCode == http.StatusCreated || httpResp.StatusCode == http.StatusOK):
+		return cacheWritable, true, nil
+	case httpResp.StatusCode == http.StatusUnauthorized || httpResp.StatusCode == http.StatusForbidden,
+		httpResp.StatusCode == http.StatusConflict && useV2:
+		return cacheNotWritable, true, nil
+	default:
+		return cacheNotWritable, true, fmt.Errorf("unexpected HTTP %d: %s", httpResp.StatusCode, strings.TrimSpace(string(content)))
+	}
+}
+
+// cacheDir returns the directory of a compiler cache, from its environment
+// variable or else its default location if it exists, or empty if not found.
+func cacheDir(backend string, getenv func(string) string) string {
+	variable := "SCCACHE_DIR"
+	var defaults []string
+	home, _ := os.UserHomeDir()
+	userCache, _ := os.UserCacheDir()
+	switch backend {
+	case cacheBackendSccache:
+		defaults = []string{filepath.Join(userCache, "sccache")}
+	case cacheBackendCcache:
+		variable = "CCACHE_DIR"
+		defaults = []string{filepath.Join(userCache, "ccache"), filepath.Join(home, ".ccache")}
+	}
+	if dir := getenv(variable); dir != "" {
+		return dir
+	}
+	for _, dir := range defaults {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
+
+// probeCacheDir creates and removes a canary file in a cache directory.
+func probeCacheDir(dir string) (string, error) {
+	path := filepath.Join(dir, "."+cachePoisonCanary())
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return cacheNotWritable, nil
+	case errors.Is(err, fs.ErrNotExist):
+		return cacheUnreachable, nil
+	case err != nil:
+		return cacheNotWritable, err
+	}
+	file.Close()
+	if err := os.Remove(path); err != nil {
+		return cacheWritable, fmt.Errorf("failed to remove canary file %s: %w", path, err)
+	}
+	return cacheWritable, nil
+}
+
+// probeDockerDaemon pings the Docker daemon of DOCKER_HOST, or of the default
+// socket if it exists. found is false when neither is set up.
+func probeDockerDaemon(ctx context.Context, getenv func(string) string, timeout time.Duration) (access string, found bool, err error) {
+	host := getenv("DOCKER_HOST")
+	if host == "" {
+		if _, err := os.Stat(defaultDockerSocket); err != nil {
+			return "", false, nil
+		}
+		host = "unix://" + defaultDockerSocket
+	}
+	daemonURL, err := url.Parse(host)
+	if err != nil {
+		return cacheUnreachable, true, fmt.Errorf("invalid DOCKER_HOST %s", host)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := &http.Transport{}
+	baseURL := "http://docker"
+	switch daemonURL.Scheme {
+	case "unix":
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", daemonURL.Path)
+		}
+	case "tcp":
+		baseURL = "http://" + daemonURL.Host
+	default:
+		return cacheUnreachable, true, fmt.Errorf("unsupported DOCKER_HOST scheme %s", daemonURL.Scheme)
+	}
+	client := &http.Client{Timeout: timeout, Transport: utils.LoggingTransport(transport)}
+	defer client.CloseIdleConnections()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/_ping", nil)
+	if err != nil {
+		return cacheUnreachable, true, err
+	}
+	httpReq.Header.Set("User-Agent", utils.GetUserAgent())
+	httpResp, err := client.Do(httpReq)
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		// The socket exists but this user is not in the docker group
+		return cacheNotWritable, true, nil
+	case err != nil:
+		return cacheUnreachable, true, err
+	}
+	httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return cacheNotWritable, true, nil
+	}
+	return cacheWritable, true, nil
+}
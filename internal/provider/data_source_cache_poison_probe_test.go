@@ -0,0 +1,163 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccTerrapwnerCachePoisonProbeDataSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake Docker daemon listens on a unix socket")
+	}
+
+	// The GitHub Actions cache services, accepting reservations with the runtime token
+	var mu sync.Mutex
+	var keys []string
+	cache := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Key, Version string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		keys = append(keys, body.Key)
+		mu.Unlock()
+		switch {
+		case r.Header.Get("Authorization") != "Bearer runtime-token":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/_apis/artifactcache/caches":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"cacheId":42}`)
+		case r.URL.Path == "/twirp/github.actions.results.api.v1.CacheService/CreateCacheEntry":
+			fmt.Fprint(w, `{"ok":true,"signed_upload_url":"https://results.example.com/upload"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer cache.Close()
+
+	// A Docker daemon answering pings
+	root := t.TempDir()
+	dockerSocket := filepath.Join(root, "docker.sock")
+	listener, err := net.Listen("unix", dockerSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docker := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ping" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "OK")
+	})}
+	go func() { _ = docker.Serve(listener) }()
+	defer docker.Close()
+
+	// A GitLab project with cached dependencies and a compiler cache
+	projectDir := filepath.Join(root, "project")
+	sccacheDir := filepath.Join(root, "sccache")
+	for _, dir := range []string{filepath.Join(projectDir, "node_modules"), filepath.Join(projectDir, "vendor"), sccacheDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("HOME", root)
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("ACTIONS_RUNTIME_TOKEN", "runtime-token")
+	t.Setenv("ACTIONS_CACHE_URL", cache.URL+"/")
+	t.Setenv("ACTIONS_RESULTS_URL", cache.URL+"/")
+	t.Setenv("ACTIONS_CACHE_SERVICE_V2", "")
+	t.Setenv("GITLAB_CI", "true")
+	t.Setenv("CI_PROJECT_DIR", projectDir)
+	t.Setenv("SCCACHE_DIR", sccacheDir)
+	t.Setenv("CCACHE_DIR", filepath.Join(root, "missing"))
+	t.Setenv("DOCKER_HOST", "unix://"+dockerSocket)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_cache_poison_probe" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.%", "6"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.github_actions", "writable"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.gitlab:node_modules", "writable"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.gitlab:vendor", "writable"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.sccache", "writable"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.ccache", "unreachable"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.docker", "writable"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "writable.#", "5"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "writable.0", "docker"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "poisonable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "fail_reason", ""),
+					func(_ *terraform.State) error {
+						mu.Lock()
+						defer mu.Unlock()
+						if len(keys) != 1 || !strings.HasPrefix(keys[0], "terrapwner-cache-canary-") {
+							return fmt.Errorf("unexpected cache keys reserved: %v", keys)
+						}
+						for _, dir := range []string{filepath.Join(projectDir, "node_modules"), sccacheDir} {
+							if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+								return fmt.Errorf("canary file left in %s", dir)
+							}
+						}
+						return nil
+					},
+				),
+			},
+			// The cache service v2, with a token of another scope
+			{
+				PreConfig: func() {
+					t.Setenv("ACTIONS_CACHE_SERVICE_V2", "true")
+					t.Setenv("ACTIONS_RUNTIME_TOKEN", "other-token")
+				},
+				Config: providerConfig + `
+data "terrapwner_cache_poison_probe" "test" {
+  backends           = ["github_actions", "gitlab"]
+  gitlab_cache_paths = ["vendor", ".cache"]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.%", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.github_actions", "not_writable"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "writable.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "writable.0", "gitlab:vendor"),
+				),
+			},
+			{
+				PreConfig: func() {
+					t.Setenv("ACTIONS_RUNTIME_TOKEN", "runtime-token")
+				},
+				Config: providerConfig + `
+data "terrapwner_cache_poison_probe" "test" {
+  backends = ["github_actions"]
+}
+`,
+				Check: resource.TestCheckResourceAttr("data.terrapwner_cache_poison_probe.test", "caches.github_actions", "writable"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_cache_poison_probe" "test" {
+  backends = ["bazel"]
+}
+`,
+				ExpectError: regexp.MustCompile(`backends must only contain github_actions, gitlab, sccache, ccache,\s+docker, got: bazel`),
+			},
+		},
+	})
+}
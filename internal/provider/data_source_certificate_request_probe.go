@@ -0,0 +1,569 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/acmpca"
+	acmpcatypes "github.com/aws/aws-sdk-go-v2/service/acmpca/types"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerCertificateRequestProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerCertificateRequestProbeDataSource{}
+)
+
+var (
+	// adcsIssuedPattern matches the download link of an issued certificate on the certsrv result page.
+	adcsIssuedPattern = regexp.MustCompile(`certnew\.cer\?ReqID=(\d+)`)
+	// adcsRequestIDPattern matches the request ID reported for pending requests.
+	adcsRequestIDPattern = regexp.MustCompile(`(?i)request id is (\d+)`)
+)
+
+// NewTerrapwnerCertificateRequestProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerCertificateRequestProbeDataSource() datasource.DataSource {
+	return &TerrapwnerCertificateRequestProbeDataSource{}
+}
+
+// TerrapwnerCertificateRequestProbeDataSource is the data source implementation.
+type TerrapwnerCertificateRequestProbeDataSource struct{}
+
+// TerrapwnerCertificateRequestProbeDataSourceModel describes the data source data model.
+type TerrapwnerCertificateRequestProbeDataSourceModel struct {
+	Type          types.String `tfsdk:"type"`
+	URL           types.String `tfsdk:"url"`
+	CAArn         types.String `tfsdk:"ca_arn"`
+	CommonName    types.String `tfsdk:"common_name"`
+	Template      types.String `tfsdk:"template"`
+	Username      types.String `tfsdk:"username"`
+	Password      types.String `tfsdk:"password"`
+	DryRun        types.Bool   `tfsdk:"dry_run"`
+	Timeout       types.Int64  `tfsdk:"timeout"`
+	Reachable     types.Bool   `tfsdk:"reachable"`
+	AuthSchemes   types.List   `tfsdk:"auth_schemes"`
+	Status        types.String `tfsdk:"status"`
+	Issued        types.Bool   `tfsdk:"issued"`
+	CertificateID types.String `tfsdk:"certificate_id"`
+	FailReason    types.String `tfsdk:"fail_reason"`
+}
+
+// certificateProbeResult holds the outcome of a probe against a certificate authority.
+type certificateProbeResult struct {
+	Reachable     bool
+	AuthSchemes   []string
+	Status        string
+	Issued        bool
+	CertificateID string
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerCertificateRequestProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerCertificateRequestProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_certificate_request_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerCertificateRequestProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, categoryCloud, "aws", "credentials", "write"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerCertificateRequestProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Tests whether the pipeline identity can obtain certificates from an internal ACME server, AD CS web enrollment " +
+			"or AWS Private CA. Certificate issuance rights from CI allow long-lived impersonation of services or users. " +
+			"In dry-run mode (the default) the CA is only queried; otherwise a request for a canary common name is submitted.",
+		Attributes: map[string]schema.Attribute{
+			"type": schema.StringAttribute{
+				Description: "Type of certificate authority: acme, adcs or aws_private_ca.",
+				Required:    true,
+			},
+			"url": schema.StringAttribute{
+				Description: "ACME directory URL, or AD CS web enrollment URL (e.g. https://ca.corp.example/certsrv).",
+				Optional:    true,
+			},
+			"ca_arn": schema.StringAttribute{
+				Description: "ARN of the AWS Private CA certificate authority.",
+				Optional:    true,
+			},
+			"common_name": schema.StringAttribute{
+				Description: "Canary common name to request a certificate for (default: terrapwner-canary.example.com).",
+				Optional:    true,
+			},
+			"template": schema.StringAttribute{
+				Description: "AD CS certificate template to request (default: User).",
+				Optional:    true,
+			},
+			"username": schema.StringAttribute{
+				Description: "Username for AD CS web enrollment (basic authentication).",
+				Optional:    true,
+			},
+			"password": schema.StringAttribute{
+				Description: "Password for AD CS web enrollment (basic authentication).",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"dry_run": schema.BoolAttribute{
+				Description: "Only check that the CA is reachable and accessible, without submitting a request (default: true).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each request (default: 10).",
+				Optional:    true,
+			},
+			"reachable": schema.BoolAttribute{
+				Description: "True if the certificate authority answered.",
+				Computed:    true,
+			},
+			"auth_schemes": schema.ListAttribute{
+				Description: "Authentication schemes offered by AD CS web enrollment (NTLM over HTTP allows relaying to the CA).",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"status": schema.StringAttribute{
+				Description: "Outcome of the probe: CA status in dry-run mode (e.g. accessible, authentication_required, ACTIVE), " +
+					"or request status otherwise (issued, pending, denied, order_pending).",
+				Computed: true,
+			},
+			"issued": schema.BoolAttribute{
+				Description: "True if a certificate was issued for the canary common name.",
+				Computed:    true,
+			},
+			"certificate_id": schema.StringAttribute{
+				Description: "Identifier of the request or certificate (ACME order URL, AD CS request ID, AWS certificate ARN).",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason the probe or request failed, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerCertificateRequestProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerCertificateRequestProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.CommonName.IsNull() {
+		data.CommonName = types.StringValue("terrapwner-canary.example.com")
+	}
+	if data.Template.IsNull() {
+		data.Template = types.StringValue("User")
+	}
+	if data.DryRun.IsNull() {
+		data.DryRun = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(10)
+	}
+
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	dryRun := data.DryRun.ValueBool()
+
+	var result certificateProbeResult
+	var err error
+	switch data.Type.ValueString() {
+	case "acme":
+		if data.URL.ValueString() == "" {
+			resp.Diagnostics.AddError("Invalid configuration", "url is required for acme probes")
+			return
+		}
+		result, err = probeACME(ctx, data.URL.ValueString(), data.CommonName.ValueString(), dryRun, timeout)
+	case "adcs":
+		if data.URL.ValueString() == "" {
+			resp.Diagnostics.AddError("Invalid configuration", "url is required for adcs probes")
+			return
+		}
+		result, err = probeADCS(ctx, data.URL.ValueString(), data.CommonName.ValueString(), data.Template.ValueString(),
+			data.Username.ValueString(), data.Password.ValueString(), dryRun, timeout)
+	case "aws_private_ca":
+		if data.CAArn.ValueString() == "" {
+			resp.Diagnostics.AddError("Invalid configuration", "ca_arn is required for aws_private_ca probes")
+			return
+		}
+		result, err = probeAWSPrivateCA(ctx, data.CAArn.ValueString(), data.CommonName.ValueString(), dryRun)
+	default:
+		resp.Diagnostics.AddError("Invalid type", fmt.Sprintf("Unsupported certificate authority type: %s", data.Type.ValueString()))
+		return
+	}
+
+	data.Reachable = types.BoolValue(result.Reachable)
+	data.Status = types.StringValue(result.Status)
+	data.Issued = types.BoolValue(result.Issued)
+	data.CertificateID = types.StringValue(result.CertificateID)
+	data.FailReason = types.StringValue("")
+	if err != nil {
+		data.FailReason = types.StringValue(err.Error())
+	}
+
+	// Convert to Terraform types
+	if result.AuthSchemes == nil {
+		result.AuthSchemes = []string{}
+	}
+	authSchemes, diags := types.ListValueFrom(ctx, types.StringType, result.AuthSchemes)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.AuthSchemes = authSchemes
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// canaryCSR generates a key pair and a DER-encoded certificate request for the canary common name.
+func canaryCSR(commonName string) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: []string{commonName},
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate request: %w", err)
+	}
+	return csr, nil
+}
+
+// probeADCS queries AD CS web enrollment and, unless in dry-run mode, submits a request
+// for the canary common name through certfnsh.asp.
+func probeADCS(ctx context.Context, baseURL, commonName, template, username, password string, dryRun bool, timeout time.Duration) (certificateProbeResult, error) {
+	var result certificateProbeResult
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	headers := map[string]string{}
+	if username != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	}
+
+	resp, err := utils.HTTPRequest(ctx, "GET", baseURL+"/", headers, nil, timeout)
+	if err != nil {
+		return result, err
+	}
+	result.Reachable = true
+	result.AuthSchemes = authSchemes(resp.Header.Values("WWW-Authenticate"))
+	switch {
+	case resp.StatusCode == 401:
+		result.Status = "authentication_required"
+		return result, nil
+	case !resp.IsSuccess():
+		result.Status = fmt.Sprintf("http_%d", resp.StatusCode)
+		return result, nil
+	}
+	result.Status = "accessible"
+	if dryRun {
+		return result, nil
+	}
+
+	csr, err := canaryCSR(commonName)
+	if err != nil {
+		return result, err
+	}
+	form := url.Values{
+		"Mode":             {"newreq"},
+		"CertRequest":      {string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}))},
+		"CertAttrib":       {"CertificateTemplate:" + template},
+		"TargetStoreFlags": {"0"},
+		"SaveCert":         {"yes"},
+	}
+	headers["Content-Type"] = "application/x-www-form-urlencoded"
+	resp, err = utils.HTTPRequest(ctx, "POST", baseURL+"/certfnsh.asp", headers, []byte(form.Encode()), timeout)
+	if err != nil {
+		return result, err
+	}
+	if !resp.IsSuccess() {
+		result.Status = fmt.Sprintf("http_%d", resp.StatusCode)
+		return result, nil
+	}
+
+	body := string(resp.Body)
+	switch {
+	case adcsIssuedPattern.MatchString(body):
+		result.Status = "issued"
+		result.Issued = true
+		result.CertificateID = adcsIssuedPattern.FindStringSubmatch(body)[1]
+	case strings.Contains(strings.ToLower(body), "pending"):
+		result.Status = "pending"
+		if m := adcsRequestIDPattern.FindStringSubmatch(body); m != nil {
+			result.CertificateID = m[1]
+		}
+	default:
+		result.Status = "denied"
+	}
+	return result, nil
+}
+
+// authSchemes returns the scheme names of WWW-Authenticate challenges.
+func authSchemes(challenges []string) []string {
+	schemes := []string{}
+	for _, challenge := range challenges {
+		if scheme, _, _ := strings.Cut(strings.TrimSpace(challenge), " "); scheme != "" {
+			schemes = append(schemes, scheme)
+		}
+	}
+	return schemes
+}
+
+// probeAWSPrivateCA describes the certificate authority and, unless in dry-run mode,
+// issues a one-day certificate for the canary common name.
+func probeAWSPrivateCA(ctx context.Context, caArn, commonName string, dryRun bool) (certificateProbeResult, error) {
+	var result certificateProbeResult
+
+	parsed, err := arn.Parse(caArn)
+	if err != nil {
+		return result, fmt.Errorf("invalid CA ARN: %w", err)
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(parsed.Region))
+	if err != nil {
+		return result, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+	client := acmpca.NewFromConfig(cfg)
+
+	// The signing algorithm must match the CA key; default to RSA if the CA cannot be described
+	signingAlgorithm := acmpcatypes.SigningAlgorithmSha256withrsa
+	describe, describeErr := client.DescribeCertificateAuthority(ctx, &acmpca.DescribeCertificateAuthorityInput{
+		CertificateAuthorityArn: aws.String(caArn),
+	})
+	if describeErr == nil {
+		result.Reachable = true
+		result.Status = string(describe.CertificateAuthority.Status)
+		if caConfig := describe.CertificateAuthority.CertificateAuthorityConfiguration; caConfig != nil && strings.HasPrefix(string(caConfig.KeyAlgorithm), "EC_") {
+			signingAlgorithm = acmpcatypes.SigningAlgorithmSha256withecdsa
+		}
+	}
+	if dryRun {
+		return result, describeErr
+	}
+
+	csr, err := canaryCSR(commonName)
+	if err != nil {
+		return result, err
+	}
+	issued, err := client.IssueCertificate(ctx, &acmpca.IssueCertificateInput{
+		CertificateAuthorityArn: aws.String(caArn),
+		Csr:                     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+		SigningAlgorithm:        signingAlgorithm,
+		Validity: &acmpcatypes.Validity{
+			Type:  acmpcatypes.ValidityPeriodTypeDays,
+			Value: aws.Int64(1),
+		},
+	})
+	if err != nil {
+		result.Status = "denied"
+		return result, fmt.Errorf("unable to issue certificate: %w", err)
+	}
+	result.Reachable = true
+	result.Status = "issued"
+	result.Issued = true
+	result.CertificateID = aws.ToString(issued.CertificateArn)
+	return result, nil
+}
+
+// acmeClient sends JWS-signed requests to an ACME server (RFC 8555).
+type acmeClient struct {
+	directory struct {
+		NewNonce   string `json:"newNonce"`
+		NewAccount string `json:"newAccount"`
+		NewOrder   string `json:"newOrder"`
+		Meta       struct {
+			ExternalAccountRequired bool `json:"externalAccountRequired"`
+		} `json:"meta"`
+	}
+	key     *ecdsa.PrivateKey
+	kid     string
+	nonce   string
+	timeout time.Duration
+}
+
+// acmeOrder is the subset of an ACME order used by the probe.
+type acmeOrder struct {
+	Status      string `json:"status"`
+	Finalize    string `json:"finalize"`
+	Certificate string `json:"certificate"`
+}
+
+// probeACME reads the ACME directory and, unless in dry-run mode, registers an
+// account and places an order for the canary common name. Orders that are ready
+// without completing a challenge (pre-authorized identifiers) are finalized.
+func probeACME(ctx context.Context, directoryURL, commonName string, dryRun bool, timeout time.Duration) (certificateProbeResult, error) {
+	var result certificateProbeResult
+	client := &acmeClient{timeout: timeout}
+
+	resp, err := utils.HTTPRequest(ctx, "GET", directoryURL, nil, nil, timeout)
+	if err != nil {
+		return result, err
+	}
+	result.Reachable = true
+	if !resp.IsSuccess() {
+		result.Status = fmt.Sprintf("http_%d", resp.StatusCode)
+		return result, nil
+	}
+	if err := json.Unmarshal(resp.Body, &client.directory); err != nil || client.directory.NewNonce == "" {
+		return result, fmt.Errorf("invalid ACME directory")
+	}
+	result.Status = "accessible"
+	if client.directory.Meta.ExternalAccountRequired {
+		result.Status = "external_account_required"
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	client.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return result, fmt.Errorf("failed to generate account key: %w", err)
+	}
+	accountResp, err := client.post(ctx, client.directory.NewAccount, map[string]any{"termsOfServiceAgreed": true})
+	if err != nil {
+		return result, fmt.Errorf("account registration failed: %w", err)
+	}
+	client.kid = accountResp.Header.Get("Location")
+
+	identifiers := []map[string]string{{"type": "dns", "value": commonName}}
+	orderResp, err := client.post(ctx, client.directory.NewOrder, map[string]any{"identifiers": identifiers})
+	if err != nil {
+		result.Status = "denied"
+		return result, fmt.Errorf("order failed: %w", err)
+	}
+	var order acmeOrder
+	if err := json.Unmarshal(orderResp.Body, &order); err != nil {
+		return result, fmt.Errorf("invalid order: %w", err)
+	}
+	result.CertificateID = orderResp.Header.Get("Location")
+	result.Status = "order_" + order.Status
+	if order.Status != "ready" {
+		return result, nil
+	}
+
+	csr, err := canaryCSR(commonName)
+	if err != nil {
+		return result, err
+	}
+	finalizeResp, err := client.post(ctx, order.Finalize, map[string]any{"csr": base64.RawURLEncoding.EncodeToString(csr)})
+	if err != nil {
+		return result, fmt.Errorf("finalize failed: %w", err)
+	}
+	if err := json.Unmarshal(finalizeResp.Body, &order); err != nil {
+		return result, fmt.Errorf("invalid order: %w", err)
+	}
+	result.Status = "order_" + order.Status
+	if order.Status == "valid" || order.Status == "processing" {
+		result.Status = "issued"
+		result.Issued = true
+	}
+	return result, nil
+}
+
+// post sends a JWS-signed POST request and returns the response, failing on non-2xx statuses.
+func (c *acmeClient) post(ctx context.Context, endpoint string, payload any) (*utils.HTTPResponse, error) {
+	if c.nonce == "" {
+		resp, err := utils.HTTPRequest(ctx, "HEAD", c.directory.NewNonce, nil, nil, c.timeout)
+		if err != nil {
+			return nil, err
+		}
+		c.nonce = resp.Header.Get("Replay-Nonce")
+	}
+
+	body, err := c.sign(endpoint, payload)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := utils.HTTPRequest(ctx, "POST", endpoint, map[string]string{"Content-Type": "application/jose+json"}, body, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	c.nonce = resp.Header.Get("Replay-Nonce")
+	if !resp.IsSuccess() {
+		var problem struct {
+			Type   string `json:"type"`
+			Detail string `json:"detail"`
+		}
+		_ = json.Unmarshal(resp.Body, &problem)
+		return nil, fmt.Errorf("status %d: %s %s", resp.StatusCode, problem.Type, problem.Detail)
+	}
+	return resp, nil
+}
+
+// sign encodes payload as a flattened JWS signed with the account key (ES256).
+// The key is identified by its JWK until the account URL is known.
+func (c *acmeClient) sign(endpoint string, payload any) ([]byte, error) {
+	protected := map[string]any{
+		"alg":   "ES256",
+		"nonce": c.nonce,
+		"url":   endpoint,
+	}
+	if c.kid != "" {
+		protected["kid"] = c.kid
+	} else {
+		protected["jwk"] = map[string]string{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(c.key.PublicKey.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(c.key.PublicKey.Y.FillBytes(make([]byte, 32))),
+		}
+	}
+
+	protectedJSON, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	encodedProtected := base64.RawURLEncoding.EncodeToString(protectedJSON)
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	digest := sha256.Sum256([]byte(encodedProtected + "." + encodedPayload))
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	return json.Marshal(map[string]string{
+		"protected": encodedProtected,
+		"payload":   encodedPayload,
+		"signature": base64.RawURLEncoding.EncodeToString(signature),
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerCertificateRequestProbeDataSource_ACME(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce")
+		switch r.URL.Path {
+		case "/directory":
+			fmt.Fprintf(w, `{"newNonce":"%[1]s/nonce","newAccount":"%[1]s/account","newOrder":"%[1]s/order"}`, server.URL)
+		case "/nonce":
+		case "/account":
+			w.Header().Set("Location", server.URL+"/account/1")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"status":"valid"}`)
+		case "/order":
+			w.Header().Set("Location", server.URL+"/order/1")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"status":"ready","finalize":"%s/order/1/finalize"}`, server.URL)
+		case "/order/1/finalize":
+			fmt.Fprintf(w, `{"status":"valid","certificate":"%s/cert/1"}`, server.URL)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_certificate_request_probe" "test" {
+  type    = "acme"
+  url     = "%s/directory"
+  dry_run = false
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_certificate_request_probe.test", "reachable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_certificate_request_probe.test", "status", "issued"),
+					resource.TestCheckResourceAttr("data.terrapwner_certificate_request_probe.test", "issued", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_certificate_request_probe.test", "certificate_id", server.URL+"/order/1"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerCertificateRequestProbeDataSource_ADCSDryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("WWW-Authenticate", "Negotiate")
+		w.Header().Add("WWW-Authenticate", "NTLM")
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_certificate_request_probe" "test" {
+  type = "adcs"
+  url  = "%s/certsrv"
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_certificate_request_probe.test", "reachable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_certificate_request_probe.test", "status", "authentication_required"),
+					resource.TestCheckResourceAttr("data.terrapwner_certificate_request_probe.test", "auth_schemes.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_certificate_request_probe.test", "auth_schemes.1", "NTLM"),
+					resource.TestCheckResourceAttr("data.terrapwner_certificate_request_probe.test", "issued", "false"),
+				),
+			},
+		},
+	})
+}
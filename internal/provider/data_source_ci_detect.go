@@ -0,0 +1,415 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerCIDetectDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerCIDetectDataSource{}
+)
+
+// ciFilesystemMarkers maps CI/CD platforms to paths left by their runners, used
+// when the environment has been scrubbed of the platform variables.
+var ciFilesystemMarkers = []struct {
+	platform string
+	path     string
+}{
+	{"spacelift", "/mnt/workspace/.spacelift"},
+	{"atlantis", "/atlantis-data"},
+	{"env0", "/opt/env0"},
+	{"terraform_cloud", "/home/tfc-agent"},
+	{"github_actions", "/home/runner/work"},
+	{"github_actions", "/opt/hostedtoolcache"},
+	{"gitlab_ci", "/builds"},
+	{"azure_devops", "/home/vsts/work"},
+	{"jenkins", "/var/jenkins_home"},
+	{"jenkins", "/home/jenkins/agent"},
+	{"circleci", "/home/circleci"},
+	{"buildkite", "/var/lib/buildkite-agent"},
+	{"buildkite", "/etc/buildkite-agent"},
+	{"bitbucket_pipelines", "/opt/atlassian/pipelines"},
+	{"aws_codebuild", "/codebuild"},
+}
+
+// ciRunInfo identifies the run, job and source of a CI/CD execution.
+type ciRunInfo struct {
+	RunID      string
+	JobID      string
+	JobName    string
+	RunURL     string
+	Repository string
+	Ref        string
+	CommitSHA  string
+	Actor      string
+	Trigger    string
+	Runner     string
+}
+
+// ciRunInfoExtractors read the run identifiers exposed by each platform.
+var ciRunInfoExtractors = map[string]func(getenv func(string) string) ciRunInfo{
+	"github_actions": func(getenv func(string) string) ciRunInfo {
+		info := ciRunInfo{
+			RunID:      getenv("GITHUB_RUN_ID"),
+			JobName:    getenv("GITHUB_JOB"),
+			Repository: getenv("GITHUB_REPOSITORY"),
+			Ref:        getenv("GITHUB_REF"),
+			CommitSHA:  getenv("GITHUB_SHA"),
+			Actor:      getenv("GITHUB_ACTOR"),
+			Trigger:    getenv("GITHUB_EVENT_NAME"),
+			Runner:     getenv("RUNNER_NAME"),
+		}
+		if server := getenv("GITHUB_SERVER_URL"); server != "" && info.Repository != "" && info.RunID != "" {
+			info.RunURL = server + "/" + info.Repository + "/actions/runs/" + info.RunID
+		}
+		return info
+	},
+	"gitlab_ci": func(getenv func(string) string) ciRunInfo {
+		return ciRunInfo{
+			RunID:      getenv("CI_PIPELINE_ID"),
+			JobID:      getenv("CI_JOB_ID"),
+			JobName:    getenv("CI_JOB_NAME"),
+			RunURL:     getenv("CI_JOB_URL"),
+			Repository: getenv("CI_PROJECT_PATH"),
+			Ref:        getenv("CI_COMMIT_REF_NAME"),
+			CommitSHA:  getenv("CI_COMMIT_SHA"),
+			Actor:      getenv("GITLAB_USER_LOGIN"),
+			Trigger:    getenv("CI_PIPELINE_SOURCE"),
+			Runner:     getenv("CI_RUNNER_DESCRIPTION"),
+		}
+	},
+	"jenkins": func(getenv func(string) string) ciRunInfo {
+		return ciRunInfo{
+			RunID:      getenv("BUILD_NUMBER"),
+			JobID:      getenv("BUILD_TAG"),
+			JobName:    getenv("JOB_NAME"),
+			RunURL:     getenv("BUILD_URL"),
+			Repository: getenv("GIT_URL"),
+			Ref:        getenv("GIT_BRANCH"),
+			CommitSHA:  getenv("GIT_COMMIT"),
+			Actor:      getenv("BUILD_USER_ID"),
+			Runner:     getenv("NODE_NAME"),
+		}
+	},
+	"circleci": func(getenv func(string) string) ciRunInfo {
+		info := ciRunInfo{
+			RunID:     getenv("CIRCLE_WORKFLOW_ID"),
+			JobID:     getenv("CIRCLE_BUILD_NUM"),
+			JobName:   getenv("CIRCLE_JOB"),
+			RunURL:    getenv("CIRCLE_BUILD_URL"),
+			Ref:       firstNonEmpty(getenv("CIRCLE_BRANCH"), getenv("CIRCLE_TAG")),
+			CommitSHA: getenv("CIRCLE_SHA1"),
+			Actor:     getenv("CIRCLE_USERNAME"),
+		}
+		if owner, name := getenv("CIRCLE_PROJECT_USERNAME"), getenv("CIRCLE_PROJECT_REPONAME"); owner != "" && name != "" {
+			info.Repository = owner + "/" + name
+		}
+		return info
+	},
+	"buildkite": func(getenv func(string) string) ciRunInfo {
+		return ciRunInfo{
+			RunID:      getenv("BUILDKITE_BUILD_ID"),
+			JobID:      getenv("BUILDKITE_JOB_ID"),
+			JobName:    getenv("BUILDKITE_LABEL"),
+			RunURL:     getenv("BUILDKITE_BUILD_URL"),
+			Repository: getenv("BUILDKITE_REPO"),
+			Ref:        getenv("BUILDKITE_BRANCH"),
+			CommitSHA:  getenv("BUILDKITE_COMMIT"),
+			Actor:      getenv("BUILDKITE_BUILD_CREATOR"),
+			Trigger:    getenv("BUILDKITE_SOURCE"),
+			Runner:     getenv("BUILDKITE_AGENT_NAME"),
+		}
+	},
+	"atlantis": func(getenv func(string) string) ciRunInfo {
+		info := ciRunInfo{
+			RunID:     getenv("PULL_NUM"),
+			JobName:   firstNonEmpty(getenv("PROJECT_NAME"), getenv("REPO_REL_DIR")),
+			RunURL:    getenv("PULL_URL"),
+			Ref:       getenv("HEAD_BRANCH_NAME"),
+			CommitSHA: getenv("HEAD_COMMIT"),
+			Actor:     firstNonEmpty(getenv("USER_NAME"), getenv("PULL_AUTHOR")),
+		}
+		if owner, name := getenv("BASE_REPO_OWNER"), getenv("BASE_REPO_NAME"); owner != "" && name != "" {
+			info.Repository = owner + "/" + name
+		}
+		if info.RunID != "" {
+			info.Trigger = "pull_request"
+		}
+		return info
+	},
+	"spacelift": func(getenv func(string) string) ciRunInfo {
+		return ciRunInfo{
+			RunID:      getenv("TF_VAR_spacelift_run_id"),
+			JobName:    getenv("TF_VAR_spacelift_stack_id"),
+			Repository: getenv("TF_VAR_spacelift_repository"),
+			Ref:        firstNonEmpty(getenv("TF_VAR_spacelift_commit_branch"), getenv("TF_VAR_spacelift_branch")),
+			CommitSHA:  getenv("TF_VAR_spacelift_commit_sha"),
+			Actor:      getenv("TF_VAR_spacelift_run_triggered_by"),
+			Trigger:    getenv("TF_VAR_spacelift_run_type"),
+			Runner:     getenv("TF_VAR_spacelift_worker_pool_id"),
+		}
+	},
+	"env0": func(getenv func(string) string) ciRunInfo {
+		return ciRunInfo{
+			RunID:      getenv("ENV0_DEPLOYMENT_LOG_ID"),
+			JobID:      getenv("ENV0_ENVIRONMENT_ID"),
+			JobName:    getenv("ENV0_ENVIRONMENT_NAME"),
+			Repository: getenv("ENV0_TEMPLATE_REPOSITORY"),
+			Ref:        getenv("ENV0_TEMPLATE_REVISION"),
+			CommitSHA:  getenv("ENV0_COMMIT_HASH"),
+			Actor:      getenv("ENV0_DEPLOYER_EMAIL"),
+			Trigger:    getenv("ENV0_DEPLOYMENT_TYPE"),
+		}
+	},
+	"terraform_cloud": func(getenv func(string) string) ciRunInfo {
+		return ciRunInfo{
+			RunID:     getenv("TFC_RUN_ID"),
+			JobName:   getenv("TFC_WORKSPACE_NAME"),
+			Ref:       getenv("TFC_CONFIGURATION_VERSION_GIT_BRANCH"),
+			CommitSHA: getenv("TFC_CONFIGURATION_VERSION_GIT_COMMIT_SHA"),
+			Actor:     getenv("TFC_RUN_CREATED_BY"),
+			Trigger:   getenv("TFC_RUN_SOURCE"),
+		}
+	},
+	"azure_devops": func(getenv func(string) string) ciRunInfo {
+		info := ciRunInfo{
+			RunID:      getenv("BUILD_BUILDID"),
+			JobID:      getenv("SYSTEM_JOBID"),
+			JobName:    getenv("SYSTEM_JOBDISPLAYNAME"),
+			Repository: getenv("BUILD_REPOSITORY_NAME"),
+			Ref:        getenv("BUILD_SOURCEBRANCH"),
+			CommitSHA:  getenv("BUILD_SOURCEVERSION"),
+			Actor:      getenv("BUILD_REQUESTEDFOR"),
+			Trigger:    getenv("BUILD_REASON"),
+			Runner:     getenv("AGENT_NAME"),
+		}
+		if collection, project := getenv("SYSTEM_COLLECTIONURI"), getenv("SYSTEM_TEAMPROJECT"); collection != "" && project != "" && info.RunID != "" {
+			info.RunURL = strings.TrimRight(collection, "/") + "/" + project + "/_build/results?buildId=" + info.RunID
+		}
+		return info
+	},
+	"bitbucket_pipelines": func(getenv func(string) string) ciRunInfo {
+		return ciRunInfo{
+			RunID:      getenv("BITBUCKET_BUILD_NUMBER"),
+			JobID:      getenv("BITBUCKET_STEP_UUID"),
+			Repository: getenv("BITBUCKET_REPO_FULL_NAME"),
+			Ref:        firstNonEmpty(getenv("BITBUCKET_BRANCH"), getenv("BITBUCKET_TAG")),
+			CommitSHA:  getenv("BITBUCKET_COMMIT"),
+			Actor:      getenv("BITBUCKET_STEP_TRIGGERER_UUID"),
+		}
+	},
+	"aws_codebuild": func(getenv func(string) string) ciRunInfo {
+		return ciRunInfo{
+			RunID:      getenv("CODEBUILD_BUILD_ID"),
+			JobName:    getenv("CODEBUILD_PROJECT_NAME"),
+			RunURL:     getenv("CODEBUILD_BUILD_URL"),
+			Repository: getenv("CODEBUILD_SOURCE_REPO_URL"),
+			Ref:        getenv("CODEBUILD_WEBHOOK_HEAD_REF"),
+			CommitSHA:  getenv("CODEBUILD_RESOLVED_SOURCE_VERSION"),
+			Actor:      firstNonEmpty(getenv("CODEBUILD_WEBHOOK_ACTOR_ACCOUNT_ID"), getenv("CODEBUILD_INITIATOR")),
+			Trigger:    getenv("CODEBUILD_WEBHOOK_EVENT"),
+		}
+	},
+}
+
+// NewTerrapwnerCIDetectDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerCIDetectDataSource() datasource.DataSource {
+	return &TerrapwnerCIDetectDataSource{}
+}
+
+// TerrapwnerCIDetectDataSource is the data source implementation.
+type TerrapwnerCIDetectDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerCIDetectDataSourceModel describes the data source data model.
+type TerrapwnerCIDetectDataSourceModel struct {
+	Platform          types.String `tfsdk:"platform"`
+	DetectedPlatforms types.List   `tfsdk:"detected_platforms"`
+	DetectionSource   types.String `tfsdk:"detection_source"`
+	FilesystemMarkers types.List   `tfsdk:"filesystem_markers"`
+	RunID             types.String `tfsdk:"run_id"`
+	JobID             types.String `tfsdk:"job_id"`
+	JobName           types.String `tfsdk:"job_name"`
+	RunURL            types.String `tfsdk:"run_url"`
+	Repository        types.String `tfsdk:"repository"`
+	Ref               types.String `tfsdk:"ref"`
+	CommitSHA         types.String `tfsdk:"commit_sha"`
+	Actor             types.String `tfsdk:"actor"`
+	Trigger           types.String `tfsdk:"trigger"`
+	Runner            types.String `tfsdk:"runner"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerCIDetectDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerCIDetectDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ci_detect"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerCIDetectDataSource) Tags() []string {
+	return []string{"ci"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerCIDetectDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Identifies the CI/CD system running Terraform (GitHub Actions, GitLab CI, Jenkins, CircleCI, Buildkite, " +
+			"Atlantis, Spacelift, env0, Terraform Cloud and others) from well-known environment variables and runner filesystem " +
+			"markers, and reports the run, job and repository identifiers it exposes.",
+		Attributes: map[string]schema.Attribute{
+			"platform": schema.StringAttribute{
+				Description: "Detected platform (e.g. github_actions, gitlab_ci, spacelift), empty if none.",
+				Computed:    true,
+			},
+			"detected_platforms": schema.ListAttribute{
+				Description: "All platforms with markers present, e.g. Atlantis running on top of a generic CI.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"detection_source": schema.StringAttribute{
+				Description: "How the platform was detected: environment or filesystem.",
+				Computed:    true,
+			},
+			"filesystem_markers": schema.ListAttribute{
+				Description: "Runner filesystem markers found.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"run_id": schema.StringAttribute{
+				Description: "Identifier of the run, pipeline or build.",
+				Computed:    true,
+			},
+			"job_id": schema.StringAttribute{
+				Description: "Identifier of the job or step within the run.",
+				Computed:    true,
+			},
+			"job_name": schema.StringAttribute{
+				Description: "Name of the job, stack, workspace or project.",
+				Computed:    true,
+			},
+			"run_url": schema.StringAttribute{
+				Description: "URL of the run in the platform UI.",
+				Computed:    true,
+			},
+			"repository": schema.StringAttribute{
+				Description: "Source repository.",
+				Computed:    true,
+			},
+			"ref": schema.StringAttribute{
+				Description: "Branch, tag or ref being built.",
+				Computed:    true,
+			},
+			"commit_sha": schema.StringAttribute{
+				Description: "Commit being built.",
+				Computed:    true,
+			},
+			"actor": schema.StringAttribute{
+				Description: "User or account that triggered the run.",
+				Computed:    true,
+			},
+			"trigger": schema.StringAttribute{
+				Description: "Event that triggered the run (e.g. push, pull_request, schedule).",
+				Computed:    true,
+			},
+			"runner": schema.StringAttribute{
+				Description: "Name of the runner, agent or worker pool executing the job.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the detection and updates the state.
+func (d *TerrapwnerCIDetectDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerCIDetectDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	detected := []string{}
+	for _, marker := range ciPlatformMarkers {
+		if d.snapshot.Getenv(marker.variable) != "" {
+			detected = append(detected, marker.platform)
+		}
+	}
+
+	markers := []string{}
+	var filesystemPlatform string
+	for _, marker := range ciFilesystemMarkers {
+		if _, err := os.Stat(marker.path); err == nil {
+			markers = append(markers, marker.path)
+			if filesystemPlatform == "" {
+				filesystemPlatform = marker.platform
+			}
+		}
+	}
+
+	platform := d.snapshot.CIPlatform()
+	data.DetectionSource = types.StringValue("")
+	switch {
+	case platform != "":
+		data.DetectionSource = types.StringValue("environment")
+	case filesystemPlatform != "":
+		platform = filesystemPlatform
+		detected = append(detected, platform)
+		data.DetectionSource = types.StringValue("filesystem")
+	}
+	data.Platform = types.StringValue(platform)
+
+	var info ciRunInfo
+	if extract, ok := ciRunInfoExtractors[platform]; ok {
+		info = extract(d.snapshot.Getenv)
+	}
+	data.RunID = types.StringValue(info.RunID)
+	data.JobID = types.StringValue(info.JobID)
+	data.JobName = types.StringValue(info.JobName)
+	data.RunURL = types.StringValue(info.RunURL)
+	data.Repository = types.StringValue(info.Repository)
+	data.Ref = types.StringValue(info.Ref)
+	data.CommitSHA = types.StringValue(info.CommitSHA)
+	data.Actor = types.StringValue(info.Actor)
+	data.Trigger = types.StringValue(info.Trigger)
+	data.Runner = types.StringValue(info.Runner)
+
+	// Convert to Terraform types
+	detectedList, diags := types.ListValueFrom(ctx, types.StringType, detected)
+	resp.Diagnostics.Append(diags...)
+	markersList, diags := types.ListValueFrom(ctx, types.StringType, markers)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.DetectedPlatforms = detectedList
+	data.FilesystemMarkers = markersList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// firstNonEmpty returns the first non-empty value.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
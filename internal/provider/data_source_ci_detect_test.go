@@ -0,0 +1,46 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerCIDetectDataSource(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "octo-org/infra")
+	t.Setenv("GITHUB_RUN_ID", "1234")
+	t.Setenv("GITHUB_JOB", "plan")
+	t.Setenv("GITHUB_REF", "refs/heads/main")
+	t.Setenv("GITHUB_SHA", "0123456789abcdef")
+	t.Setenv("GITHUB_ACTOR", "octocat")
+	t.Setenv("GITHUB_EVENT_NAME", "pull_request")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_ci_detect" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "platform", "github_actions"),
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "detection_source", "environment"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_ci_detect.test", "detected_platforms.*", "github_actions"),
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "run_id", "1234"),
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "job_name", "plan"),
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "run_url", "https://github.com/octo-org/infra/actions/runs/1234"),
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "repository", "octo-org/infra"),
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "ref", "refs/heads/main"),
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "commit_sha", "0123456789abcdef"),
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "actor", "octocat"),
+					resource.TestCheckResourceAttr("data.terrapwner_ci_detect.test", "trigger", "pull_request"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,240 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerCleanupVerifyDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerCleanupVerifyDataSource{}
+)
+
+// NewTerrapwnerCleanupVerifyDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerCleanupVerifyDataSource() datasource.DataSource {
+	return &TerrapwnerCleanupVerifyDataSource{}
+}
+
+// TerrapwnerCleanupVerifyDataSource is the data source implementation.
+type TerrapwnerCleanupVerifyDataSource struct{}
+
+// TerrapwnerCleanupVerifyDataSourceModel describes the data source data model.
+type TerrapwnerCleanupVerifyDataSourceModel struct {
+	Paths              types.List   `tfsdk:"paths"`
+	WorkspaceRoot      types.String `tfsdk:"workspace_root"`
+	CheckProcesses     types.Bool   `tfsdk:"check_processes"`
+	ProcessPattern     types.String `tfsdk:"process_pattern"`
+	RemainingPaths     types.List   `tfsdk:"remaining_paths"`
+	LeftoverWorkspaces types.List   `tfsdk:"leftover_workspaces"`
+	RemainingProcesses types.List   `tfsdk:"remaining_processes"`
+	Clean              types.Bool   `tfsdk:"clean"`
+	Attestation        types.String `tfsdk:"attestation"`
+	FailReason         types.String `tfsdk:"fail_reason"`
+}
+
+// cleanupAttestation is the JSON document recorded for engagement closeout.
+type cleanupAttestation struct {
+	Clean              bool      `json:"clean"`
+	CheckedAt          time.Time `json:"checked_at"`
+	Host               string    `json:"host"`
+	PathsChecked       int       `json:"paths_checked"`
+	RemainingPaths     []string  `json:"remaining_paths"`
+	LeftoverWorkspaces []string  `json:"leftover_workspaces"`
+	ProcessesChecked   bool      `json:"processes_checked"`
+	RemainingProcesses []string  `json:"remaining_processes"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerCleanupVerifyDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerCleanupVerifyDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cleanup_verify"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerCleanupVerifyDataSource) Tags() []string {
+	return []string{"cleanup"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerCleanupVerifyDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Verifies that the artifacts of an exercise were removed: listed paths, files recorded in the manifests of " +
+			"terrapwner temp workspaces left by previous runs, and terrapwner processes still running. Produces a \"clean exit\" " +
+			"attestation for engagement closeout. Run it from a separate configuration after the exercise has finished.",
+		Attributes: map[string]schema.Attribute{
+			"paths": schema.ListAttribute{
+				Description: "Additional artifact paths that must no longer exist.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"workspace_root": schema.StringAttribute{
+				Description: "Directory holding terrapwner temp workspaces (default: the system temporary directory).",
+				Optional:    true,
+			},
+			"check_processes": schema.BoolAttribute{
+				Description: "Whether to look for remaining terrapwner processes (default: true).",
+				Optional:    true,
+			},
+			"process_pattern": schema.StringAttribute{
+				Description: "Case-insensitive substring identifying terrapwner processes by name or command line (default: terrapwner).",
+				Optional:    true,
+			},
+			"remaining_paths": schema.ListAttribute{
+				Description: "Artifact paths that still exist.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"leftover_workspaces": schema.ListAttribute{
+				Description: "Temp workspaces of other runs that were not cleaned up.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"remaining_processes": schema.ListAttribute{
+				Description: "Matching processes still running (other than this provider), formatted as `pid name`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"clean": schema.BoolAttribute{
+				Description: "True if no artifacts, workspaces or processes remain.",
+				Computed:    true,
+			},
+			"attestation": schema.StringAttribute{
+				Description: "JSON attestation of the verification (result, time, host and findings).",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Checks that could not be performed, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the verification and updates the state.
+func (d *TerrapwnerCleanupVerifyDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerCleanupVerifyDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.WorkspaceRoot.IsNull() {
+		data.WorkspaceRoot = types.StringValue(os.TempDir())
+	}
+	if data.CheckProcesses.IsNull() {
+		data.CheckProcesses = types.BoolValue(true)
+	}
+	if data.ProcessPattern.IsNull() {
+		data.ProcessPattern = types.StringValue("terrapwner")
+	}
+
+	var paths []string
+	if !data.Paths.IsNull() {
+		resp.Diagnostics.Append(data.Paths.ElementsAs(ctx, &paths, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	var failures []string
+
+	// Workspaces of other runs are leftovers, and so are the files their manifests recorded
+	leftoverWorkspaces := []string{}
+	if dirs, err := utils.ListWorkspaces(data.WorkspaceRoot.ValueString()); err != nil {
+		failures = append(failures, fmt.Sprintf("workspaces: %v", err))
+	} else {
+		for _, dir := range dirs {
+			manifest, err := utils.ReadWorkspaceManifest(dir)
+			if err == nil && manifest.PID == os.Getpid() {
+				continue
+			}
+			leftoverWorkspaces = append(leftoverWorkspaces, dir)
+			if err == nil {
+				paths = append(paths, manifest.Files...)
+			}
+		}
+	}
+
+	remainingPaths := []string{}
+	for _, path := range paths {
+		if _, err := os.Lstat(path); err == nil {
+			remainingPaths = append(remainingPaths, path)
+		}
+	}
+
+	remainingProcesses := []string{}
+	if data.CheckProcesses.ValueBool() {
+		processes, err := utils.ListProcesses()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("processes: %v", err))
+		}
+		pattern := strings.ToLower(data.ProcessPattern.ValueString())
+		for _, p := range processes {
+			if p.PID == os.Getpid() {
+				continue
+			}
+			if strings.Contains(strings.ToLower(p.Name), pattern) || strings.Contains(strings.ToLower(p.Cmdline), pattern) {
+				remainingProcesses = append(remainingProcesses, fmt.Sprintf("%d %s", p.PID, p.Name))
+			}
+		}
+	}
+
+	clean := len(remainingPaths) == 0 && len(leftoverWorkspaces) == 0 && len(remainingProcesses) == 0
+	data.Clean = types.BoolValue(clean)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	host, _ := os.Hostname()
+	attestation, err := json.Marshal(cleanupAttestation{
+		Clean:              clean,
+		CheckedAt:          time.Now().UTC(),
+		Host:               host,
+		PathsChecked:       len(paths),
+		RemainingPaths:     remainingPaths,
+		LeftoverWorkspaces: leftoverWorkspaces,
+		ProcessesChecked:   data.CheckProcesses.ValueBool(),
+		RemainingProcesses: remainingProcesses,
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to encode attestation", err.Error())
+		return
+	}
+	data.Attestation = types.StringValue(string(attestation))
+
+	// Convert to Terraform types
+	pathsList, diags := types.ListValueFrom(ctx, types.StringType, remainingPaths)
+	resp.Diagnostics.Append(diags...)
+	workspacesList, diags := types.ListValueFrom(ctx, types.StringType, leftoverWorkspaces)
+	resp.Diagnostics.Append(diags...)
+	processesList, diags := types.ListValueFrom(ctx, types.StringType, remainingProcesses)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.RemainingPaths = pathsList
+	data.LeftoverWorkspaces = workspacesList
+	data.RemainingProcesses = processesList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,77 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerCleanupVerifyDataSource(t *testing.T) {
+	root := t.TempDir()
+	removed := filepath.Join(t.TempDir(), "removed")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_cleanup_verify" "test" {
+  paths           = [%q]
+  workspace_root  = %q
+  check_processes = false
+}
+`, removed, root),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_cleanup_verify.test", "clean", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_cleanup_verify.test", "remaining_paths.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_cleanup_verify.test", "leftover_workspaces.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerCleanupVerifyDataSource_Leftovers(t *testing.T) {
+	root := t.TempDir()
+
+	// Simulate a workspace left behind by a crashed run
+	workspace, err := utils.NewWorkspace(root)
+	if err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	payload, err := workspace.CreateFile("payload-*")
+	if err != nil {
+		t.Fatalf("Failed to create payload: %v", err)
+	}
+	payload.Close()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_cleanup_verify" "test" {
+  workspace_root  = %q
+  check_processes = false
+}
+`, root),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_cleanup_verify.test", "clean", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_cleanup_verify.test", "leftover_workspaces.0", workspace.Dir()),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_cleanup_verify.test", "remaining_paths.*", payload.Name()),
+				),
+			},
+		},
+	})
+
+	if err := os.RemoveAll(workspace.Dir()); err != nil {
+		t.Fatalf("Failed to remove workspace: %v", err)
+	}
+}
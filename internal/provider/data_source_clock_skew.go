@@ -0,0 +1,376 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerClockSkewDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerClockSkewDataSource{}
+)
+
+const (
+	// defaultClockSkewNTPServer is the NTP server the local clock is compared to.
+	defaultClockSkewNTPServer = "pool.ntp.org"
+
+	// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and
+	// the Unix epoch (1970).
+	ntpEpochOffset = 2208988800
+
+	// ntpPacketSize is the size of an NTP packet without extensions.
+	ntpPacketSize = 48
+)
+
+// Sandbox-evasion signals reported by terrapwner_clock_skew.
+const (
+	sandboxSignalClockSkew       = "clock_skew"
+	sandboxSignalLowMemory       = "low_memory"
+	sandboxSignalSmallDisk       = "small_disk"
+	sandboxSignalFewCPUs         = "few_cpus"
+	sandboxSignalMissingDMI      = "missing_dmi"
+	sandboxSignalHypervisorFlag  = "hypervisor_flag"
+	sandboxSignalVirtualHardware = "virtual_hardware"
+)
+
+// virtualHardwareDMIMarkers are lowercase substrings of the SMBIOS fields of
+// virtual machines, as checked by malware looking for analysis environments.
+var virtualHardwareDMIMarkers = []string{"qemu", "kvm", "vmware", "virtualbox", "innotek", "xen", "bochs", "parallels", "bhyve", "virtual machine"}
+
+// NewTerrapwnerClockSkewDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerClockSkewDataSource() datasource.DataSource {
+	return &TerrapwnerClockSkewDataSource{}
+}
+
+// TerrapwnerClockSkewDataSource is the data source implementation.
+type TerrapwnerClockSkewDataSource struct{}
+
+// TerrapwnerClockSkewDataSourceModel describes the data source data model.
+type TerrapwnerClockSkewDataSourceModel struct {
+	NTPServer       types.String `tfsdk:"ntp_server"`
+	MaxClockSkewMs  types.Int64  `tfsdk:"max_clock_skew_ms"`
+	MinMemoryMB     types.Int64  `tfsdk:"min_memory_mb"`
+	MinDiskGB       types.Int64  `tfsdk:"min_disk_gb"`
+	MinCPUs         types.Int64  `tfsdk:"min_cpus"`
+	Timeout         types.Int64  `tfsdk:"timeout"`
+	ClockSkewMs     types.Int64  `tfsdk:"clock_skew_ms"`
+	NTPReachable    types.Bool   `tfsdk:"ntp_reachable"`
+	MemoryMB        types.Int64  `tfsdk:"memory_mb"`
+	DiskGB          types.Int64  `tfsdk:"disk_gb"`
+	CPUs            types.Int64  `tfsdk:"cpus"`
+	DMIVendor       types.String `tfsdk:"dmi_vendor"`
+	DMIProduct      types.String `tfsdk:"dmi_product"`
+	HypervisorFlag  types.Bool   `tfsdk:"hypervisor_flag"`
+	VirtualHardware types.String `tfsdk:"virtual_hardware"`
+	Signals         types.List   `tfsdk:"signals"`
+	Detectable      types.Bool   `tfsdk:"detectable"`
+	FailReason      types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerClockSkewDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerClockSkewDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_clock_skew"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerClockSkewDataSource) Tags() []string {
+	return []string{categoryNetwork, "detection", "sandbox"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerClockSkewDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reports the signals a malicious provider could use to detect an analysis sandbox and stay dormant: clock " +
+			"skew against an NTP server, little memory, a small disk, few CPUs, missing SMBIOS (DMI) data, the hypervisor CPU flag " +
+			"and virtual machine hardware. Detection engineers can check that their sandboxes are convincing, i.e. raise no " +
+			"signal. Memory, DMI data and CPU flags are only read on Linux.",
+		Attributes: map[string]schema.Attribute{
+			"ntp_server": schema.StringAttribute{
+				Description: fmt.Sprintf("NTP server the local clock is compared to, as host or host:port; empty to skip the "+
+					"comparison (default: %s).", defaultClockSkewNTPServer),
+				Optional: true,
+			},
+			"max_clock_skew_ms": schema.Int64Attribute{
+				Description: "Clock skew in milliseconds, either way, above which the clock_skew signal is raised (default: 5000).",
+				Optional:    true,
+			},
+			"min_memory_mb": schema.Int64Attribute{
+				Description: "Total memory in MiB under which the low_memory signal is raised (default: 4096).",
+				Optional:    true,
+			},
+			"min_disk_gb": schema.Int64Attribute{
+				Description: "Size in GiB of the filesystem holding the working directory under which the small_disk signal is " +
+					"raised (default: 64).",
+				Optional: true,
+			},
+			"min_cpus": schema.Int64Attribute{
+				Description: "Number of CPUs under which the few_cpus signal is raised (default: 2).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of the NTP query (default: 5).",
+				Optional:    true,
+			},
+			"clock_skew_ms": schema.Int64Attribute{
+				Description: "Offset in milliseconds of the local clock from the NTP server, positive if the local clock is ahead.",
+				Computed:    true,
+			},
+			"ntp_reachable": schema.BoolAttribute{
+				Description: "Whether the NTP server answered.",
+				Computed:    true,
+			},
+			"memory_mb": schema.Int64Attribute{
+				Description: "Total memory in MiB, 0 if unknown.",
+				Computed:    true,
+			},
+			"disk_gb": schema.Int64Attribute{
+				Description: "Size in GiB of the filesystem holding the working directory, 0 if unknown.",
+				Computed:    true,
+			},
+			"cpus": schema.Int64Attribute{
+				Description: "Number of logical CPUs usable by the provider.",
+				Computed:    true,
+			},
+			"dmi_vendor": schema.StringAttribute{
+				Description: "System vendor from the SMBIOS data.",
+				Computed:    true,
+			},
+			"dmi_product": schema.StringAttribute{
+				Description: "Product name from the SMBIOS data.",
+				Computed:    true,
+			},
+			"hypervisor_flag": schema.BoolAttribute{
+				Description: "Whether the CPU reports running under a hypervisor.",
+				Computed:    true,
+			},
+			"virtual_hardware": schema.StringAttribute{
+				Description: "SMBIOS value naming virtual machine hardware (e.g. QEMU, VMware, VirtualBox), empty if none.",
+				Computed:    true,
+			},
+			"signals": schema.ListAttribute{
+				Description: "Signals raised: clock_skew, low_memory, small_disk, few_cpus, missing_dmi, hypervisor_flag and virtual_hardware.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"detectable": schema.BoolAttribute{
+				Description: "True if any signal was raised, i.e. a provider could tell it may be running in an analysis environment.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Signals that could not be checked, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read collects the signals and updates the state.
+func (d *TerrapwnerClockSkewDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerClockSkewDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.NTPServer.IsNull() {
+		data.NTPServer = types.StringValue(defaultClockSkewNTPServer)
+	}
+	if data.MaxClockSkewMs.IsNull() {
+		data.MaxClockSkewMs = types.Int64Value(5000)
+	}
+	if data.MinMemoryMB.IsNull() {
+		data.MinMemoryMB = types.Int64Value(4096)
+	}
+	if data.MinDiskGB.IsNull() {
+		data.MinDiskGB = types.Int64Value(64)
+	}
+	if data.MinCPUs.IsNull() {
+		data.MinCPUs = types.Int64Value(2)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(5)
+	}
+
+	var failures []string
+	signals := []string{}
+
+	// Sandboxes often fast-forward their clock to trigger time bombs
+	data.ClockSkewMs = types.Int64Value(0)
+	data.NTPReachable = types.BoolValue(false)
+	if server := data.NTPServer.ValueString(); server != "" {
+		skew, err := queryClockSkew(ctx, server, time.Duration(data.Timeout.ValueInt64())*time.Second)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("ntp: %v", err))
+		} else {
+			data.ClockSkewMs = types.Int64Value(skew.Milliseconds())
+			data.NTPReachable = types.BoolValue(true)
+			if skew.Abs() > time.Duration(data.MaxClockSkewMs.ValueInt64())*time.Millisecond {
+				signals = append(signals, sandboxSignalClockSkew)
+			}
+		}
+	}
+
+	data.MemoryMB = types.Int64Value(0)
+	if runtime.GOOS == "linux" {
+		memory, err := utils.MemoryTotal()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("memory: %v", err))
+		} else {
+			data.MemoryMB = types.Int64Value(memory >> 20)
+			if memory>>20 < data.MinMemoryMB.ValueInt64() {
+				signals = append(signals, sandboxSignalLowMemory)
+			}
+		}
+	}
+
+	data.DiskGB = types.Int64Value(0)
+	if size, err := utils.DiskSize("."); err == nil {
+		data.DiskGB = types.Int64Value(size >> 30)
+		if size>>30 < data.MinDiskGB.ValueInt64() {
+			signals = append(signals, sandboxSignalSmallDisk)
+		}
+	} else if runtime.GOOS != "windows" {
+		failures = append(failures, fmt.Sprintf("disk: %v", err))
+	}
+
+	data.CPUs = types.Int64Value(int64(runtime.NumCPU()))
+	if data.CPUs.ValueInt64() < data.MinCPUs.ValueInt64() {
+		signals = append(signals, sandboxSignalFewCPUs)
+	}
+
+	// Sandboxes built from minimal VM images often lack the SMBIOS data
+	data.DMIVendor = types.StringValue("")
+	data.DMIProduct = types.StringValue("")
+	data.HypervisorFlag = types.BoolValue(false)
+	data.VirtualHardware = types.StringValue("")
+	if runtime.GOOS == "linux" {
+		fields := map[string]string{}
+		for _, field := range []string{"sys_vendor", "product_name", "board_vendor", "bios_vendor", "bios_version"} {
+			if content, err := os.ReadFile(filepath.Join(dmiDir, field)); err == nil {
+				fields[field] = strings.TrimSpace(string(content))
+			}
+		}
+		data.DMIVendor = types.StringValue(fields["sys_vendor"])
+		data.DMIProduct = types.StringValue(fields["product_name"])
+		if fields["sys_vendor"] == "" && fields["product_name"] == "" {
+			signals = append(signals, sandboxSignalMissingDMI)
+		}
+		if value := virtualHardwareDMIValue(fields); value != "" {
+			data.VirtualHardware = types.StringValue(value)
+			signals = append(signals, sandboxSignalVirtualHardware)
+		}
+
+		flags, err := utils.CPUFlags()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("cpu flags: %v", err))
+		} else if slices.Contains(flags, "hypervisor") {
+			data.HypervisorFlag = types.BoolValue(true)
+			signals = append(signals, sandboxSignalHypervisorFlag)
+		}
+	}
+
+	data.Detectable = types.BoolValue(len(signals) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	signalsList, diags := types.ListValueFrom(ctx, types.StringType, signals)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Signals = signalsList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// virtualHardwareDMIValue returns the first SMBIOS value naming virtual machine
+// hardware, empty if none.
+func virtualHardwareDMIValue(fields map[string]string) string {
+	for _, field := range []string{"sys_vendor", "product_name", "board_vendor", "bios_vendor", "bios_version"} {
+		value := strings.ToLower(fields[field])
+		for _, marker := range virtualHardwareDMIMarkers {
+			if strings.Contains(value, marker) {
+				return fields[field]
+			}
+		}
+	}
+	return ""
+}
+
+// queryClockSkew sends an SNTP request to server and returns the offset of the
+// local clock from the server clock, positive if the local clock is ahead.
+func queryClockSkew(ctx context.Context, server string, timeout time.Duration) (time.Duration, error) {
+	address := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		address = net.JoinHostPort(server, "123")
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+
+	// Leap indicator 0, version 4, client mode
+	request := make([]byte, ntpPacketSize)
+	request[0] = 0x23
+	sent := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return 0, err
+	}
+	response := make([]byte, ntpPacketSize)
+	n, err := conn.Read(response)
+	received := time.Now()
+	if err != nil {
+		return 0, err
+	}
+	if n < ntpPacketSize || response[0]&0x7 != 4 {
+		return 0, fmt.Errorf("invalid NTP response")
+	}
+	if response[1] == 0 {
+		return 0, fmt.Errorf("NTP server refused the request")
+	}
+
+	// The offset is ((T1 - T2) + (T4 - T3)) / 2, cancelling out the network delay
+	serverReceived := ntpTimestamp(response[32:40])
+	serverSent := ntpTimestamp(response[40:48])
+	return (sent.Sub(serverReceived) + received.Sub(serverSent)) / 2, nil
+}
+
+// ntpTimestamp decodes a 64-bit NTP timestamp.
+func ntpTimestamp(b []byte) time.Time {
+	seconds := int64(binary.BigEndian.Uint32(b[:4])) - ntpEpochOffset
+	fraction := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(seconds, fraction*int64(time.Second)>>32)
+}
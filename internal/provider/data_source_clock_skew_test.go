@@ -0,0 +1,148 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// startNTPServer starts an SNTP server whose clock is offset from the local one.
+func startNTPServer(t *testing.T, offset time.Duration) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, ntpPacketSize)
+		for {
+			_, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			now := time.Now().Add(offset)
+			timestamp := make([]byte, 8)
+			binary.BigEndian.PutUint32(timestamp, uint32(now.Unix()+ntpEpochOffset))
+			binary.BigEndian.PutUint32(timestamp[4:], uint32((int64(now.Nanosecond())<<32)/int64(time.Second)))
+
+			response := make([]byte, ntpPacketSize)
+			response[0] = 0x24 // Version 4, server mode
+			response[1] = 2
+			copy(response[32:], timestamp)
+			copy(response[40:], timestamp)
+			_, _ = conn.WriteTo(response, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestAccTerrapwnerClockSkewDataSource(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("memory, DMI data and CPU flags are only read on Linux")
+	}
+
+	// A virtual machine without product name
+	original := dmiDir
+	dmiDir = t.TempDir()
+	t.Cleanup(func() { dmiDir = original })
+	if err := os.WriteFile(filepath.Join(dmiDir, "sys_vendor"), []byte("QEMU\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A server one hour ahead, and one on time
+	ahead := startNTPServer(t, time.Hour)
+	onTime := startNTPServer(t, 0)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_clock_skew" "test" {
+  ntp_server    = %q
+  min_memory_mb = 1048576
+  min_disk_gb   = 0
+  min_cpus      = 0
+}
+`, ahead),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "ntp_reachable", "true"),
+					resource.TestCheckResourceAttrWith("data.terrapwner_clock_skew.test", "clock_skew_ms", func(value string) error {
+						if skew, _ := strconv.Atoi(value); skew > -3590000 || skew < -3610000 {
+							return fmt.Errorf("clock_skew_ms %s is not about one hour behind", value)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttrWith("data.terrapwner_clock_skew.test", "memory_mb", func(value string) error {
+						if memory, _ := strconv.Atoi(value); memory <= 0 {
+							return fmt.Errorf("memory_mb %s is not positive", value)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "cpus", strconv.Itoa(runtime.NumCPU())),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "dmi_vendor", "QEMU"),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "dmi_product", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "virtual_hardware", "QEMU"),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "signals.0", "clock_skew"),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "signals.1", "low_memory"),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "signals.2", "virtual_hardware"),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "detectable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "fail_reason", ""),
+				),
+			},
+			// A convincing environment, once DMI data is gone
+			{
+				PreConfig: func() {
+					if err := os.Remove(filepath.Join(dmiDir, "sys_vendor")); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_clock_skew" "test" {
+  ntp_server    = %q
+  min_memory_mb = 0
+  min_disk_gb   = 0
+  min_cpus      = 0
+}
+`, onTime),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrWith("data.terrapwner_clock_skew.test", "clock_skew_ms", func(value string) error {
+						if skew, _ := strconv.Atoi(value); skew > 1000 || skew < -1000 {
+							return fmt.Errorf("clock_skew_ms %s is not about zero", value)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "signals.0", "missing_dmi"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_clock_skew" "test" {
+  ntp_server = "127.0.0.1:1"
+  timeout    = 1
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "ntp_reachable", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_clock_skew.test", "clock_skew_ms", "0"),
+					resource.TestMatchResourceAttr("data.terrapwner_clock_skew.test", "fail_reason", regexp.MustCompile(`^ntp: `)),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,339 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerCoredumpProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerCoredumpProbeDataSource{}
+)
+
+// Core dump handlers, derived from kernel.core_pattern.
+const (
+	coreHandlerFile    = "file"
+	coreHandlerSystemd = "systemd-coredump"
+	coreHandlerApport  = "apport"
+	coreHandlerPipe    = "pipe"
+)
+
+// coreLimitName is the name of the core file size limit in /proc/<pid>/limits.
+const coreLimitName = "Max core file size"
+
+// maxReadableDumps caps the number of existing dumps reported.
+const maxReadableDumps = 20
+
+// coreHandlerDirectories maps the pipe handlers to the directory they store dumps in.
+var coreHandlerDirectories = map[string]string{
+	coreHandlerSystemd: "/var/lib/systemd/coredump",
+	coreHandlerApport:  "/var/crash",
+}
+
+// defaultCredentialProcessPatterns identifies processes likely to hold credentials in memory.
+var defaultCredentialProcessPatterns = []string{
+	"ssh-agent",
+	"gpg-agent",
+	"vault",
+	"aws",
+	"gcloud",
+	"kubectl",
+	"docker",
+	"runner",
+	"buildkite-agent",
+	"terraform",
+}
+
+// NewTerrapwnerCoredumpProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerCoredumpProbeDataSource() datasource.DataSource {
+	return &TerrapwnerCoredumpProbeDataSource{}
+}
+
+// TerrapwnerCoredumpProbeDataSource is the data source implementation.
+type TerrapwnerCoredumpProbeDataSource struct{}
+
+// TerrapwnerCoredumpProbeDataSourceModel describes the data source data model.
+type TerrapwnerCoredumpProbeDataSourceModel struct {
+	ProcessPatterns       types.List   `tfsdk:"process_patterns"`
+	CorePattern           types.String `tfsdk:"core_pattern"`
+	CoreHandler           types.String `tfsdk:"core_handler"`
+	DumpDirectory         types.String `tfsdk:"dump_directory"`
+	CoreSizeLimit         types.String `tfsdk:"core_size_limit"`
+	CoreSizeHardLimit     types.String `tfsdk:"core_size_hard_limit"`
+	SuidDumpable          types.String `tfsdk:"suid_dumpable"`
+	DumpsEnabled          types.Bool   `tfsdk:"dumps_enabled"`
+	DumpDirectoryReadable types.Bool   `tfsdk:"dump_directory_readable"`
+	ReadableDumps         types.List   `tfsdk:"readable_dumps"`
+	CredentialProcesses   types.List   `tfsdk:"credential_processes"`
+	FailReason            types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerCoredumpProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerCoredumpProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_coredump_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerCoredumpProbeDataSource) Tags() []string {
+	return []string{categoryExec, "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerCoredumpProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Checks the core dump configuration (kernel.core_pattern, core file size limits, apport or systemd-coredump " +
+			"storage) and whether dumps of credential-bearing processes would be readable by the Terraform user, an often " +
+			"forgotten secret exposure channel. Dumps written to files or stored by systemd-coredump and apport are readable " +
+			"by the user owning the crashed process and by root. Linux only.",
+		Attributes: map[string]schema.Attribute{
+			"process_patterns": schema.ListAttribute{
+				Description: "Case-insensitive substrings identifying credential-bearing processes by name " +
+					"(default: ssh-agent, gpg-agent, vault, aws, gcloud, kubectl, docker, runner, buildkite-agent, terraform).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"core_pattern": schema.StringAttribute{
+				Description: "Value of kernel.core_pattern.",
+				Computed:    true,
+			},
+			"core_handler": schema.StringAttribute{
+				Description: "How dumps are handled: file, systemd-coredump, apport or pipe (another program), empty if unknown.",
+				Computed:    true,
+			},
+			"dump_directory": schema.StringAttribute{
+				Description: "Directory dumps are stored in, empty if they are written to the working directory of the crashed process or piped to an unknown program.",
+				Computed:    true,
+			},
+			"core_size_limit": schema.StringAttribute{
+				Description: "Soft core file size limit of the Terraform process, inherited by the commands it runs (unlimited or a number of bytes).",
+				Computed:    true,
+			},
+			"core_size_hard_limit": schema.StringAttribute{
+				Description: "Hard core file size limit of the Terraform process, up to which the soft limit can be raised.",
+				Computed:    true,
+			},
+			"suid_dumpable": schema.StringAttribute{
+				Description: "Value of fs.suid_dumpable: 0 (no dumps of privileged processes), 1 (dumps owned by the process user), 2 (dumps readable by root only).",
+				Computed:    true,
+			},
+			"dumps_enabled": schema.BoolAttribute{
+				Description: "True if a crash of the Terraform process or its commands would produce a dump.",
+				Computed:    true,
+			},
+			"dump_directory_readable": schema.BoolAttribute{
+				Description: "True if the dump directory can be listed by the Terraform user.",
+				Computed:    true,
+			},
+			"readable_dumps": schema.ListAttribute{
+				Description: fmt.Sprintf("Existing dumps in the dump directory readable by the Terraform user (at most %d).", maxReadableDumps),
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"credential_processes": schema.ListAttribute{
+				Description: "Running credential-bearing processes that would produce a dump readable by the Terraform user if they crashed, formatted as `pid name`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Checks that could not be performed, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerCoredumpProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerCoredumpProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	var patterns []string
+	if data.ProcessPatterns.IsNull() {
+		patterns = defaultCredentialProcessPatterns
+	} else {
+		resp.Diagnostics.Append(data.ProcessPatterns.ElementsAs(ctx, &patterns, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	var failures []string
+	readableDumps := []string{}
+	credentialProcesses := []string{}
+	data.CorePattern = types.StringValue("")
+	data.CoreHandler = types.StringValue("")
+	data.DumpDirectory = types.StringValue("")
+	data.CoreSizeLimit = types.StringValue("")
+	data.CoreSizeHardLimit = types.StringValue("")
+	data.SuidDumpable = types.StringValue("")
+	data.DumpsEnabled = types.BoolValue(false)
+	data.DumpDirectoryReadable = types.BoolValue(false)
+
+	if runtime.GOOS != "linux" {
+		failures = append(failures, fmt.Sprintf("core dump checks are only supported on Linux, running on %s", runtime.GOOS))
+	} else {
+		pattern, err := utils.ReadSysctl("kernel.core_pattern")
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("core_pattern: %v", err))
+		}
+		handler, directory := parseCorePattern(pattern)
+		data.CorePattern = types.StringValue(pattern)
+		data.CoreHandler = types.StringValue(handler)
+		data.DumpDirectory = types.StringValue(directory)
+
+		soft, hard, err := utils.ProcessLimit(os.Getpid(), coreLimitName)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("core size limit: %v", err))
+		}
+		data.CoreSizeLimit = types.StringValue(soft)
+		data.CoreSizeHardLimit = types.StringValue(hard)
+
+		if suidDumpable, err := utils.ReadSysctl("fs.suid_dumpable"); err == nil {
+			data.SuidDumpable = types.StringValue(suidDumpable)
+		}
+
+		// Pipe handlers are not bound by the core size limit
+		data.DumpsEnabled = types.BoolValue(handler != "" && (handler != coreHandlerFile || soft != "0"))
+
+		if directory != "" {
+			entries, err := os.ReadDir(directory)
+			data.DumpDirectoryReadable = types.BoolValue(err == nil)
+			for _, entry := range entries {
+				if len(readableDumps) >= maxReadableDumps {
+					break
+				}
+				path := filepath.Join(directory, entry.Name())
+				if entry.Type().IsRegular() && fileReadable(path) {
+					readableDumps = append(readableDumps, path)
+				}
+			}
+		}
+
+		if handler != "" && handler != coreHandlerPipe {
+			processes, err := coredumpReadableProcesses(handler, patterns)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("processes: %v", err))
+			}
+			credentialProcesses = append(credentialProcesses, processes...)
+		}
+	}
+
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	dumpsList, diags := types.ListValueFrom(ctx, types.StringType, readableDumps)
+	resp.Diagnostics.Append(diags...)
+	processesList, diags := types.ListValueFrom(ctx, types.StringType, credentialProcesses)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ReadableDumps = dumpsList
+	data.CredentialProcesses = processesList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// parseCorePattern returns the handler of a kernel.core_pattern value and the
+// directory dumps are stored in, if known.
+func parseCorePattern(pattern string) (string, string) {
+	if pattern == "" {
+		return "", ""
+	}
+
+	if program, ok := strings.CutPrefix(pattern, "|"); ok {
+		switch {
+		case strings.Contains(program, coreHandlerSystemd):
+			return coreHandlerSystemd, coreHandlerDirectories[coreHandlerSystemd]
+		case strings.Contains(program, coreHandlerApport):
+			return coreHandlerApport, coreHandlerDirectories[coreHandlerApport]
+		default:
+			return coreHandlerPipe, ""
+		}
+	}
+
+	// Relative patterns are written to the working directory of the crashed process
+	if !filepath.IsAbs(pattern) {
+		return coreHandlerFile, ""
+	}
+
+	// Keep the leading directories without % specifiers (e.g. /var/cores/%e/core.%p)
+	directory := filepath.Dir(pattern)
+	if i := strings.Index(directory, "%"); i >= 0 {
+		directory = filepath.Dir(directory[:i])
+	}
+	return coreHandlerFile, directory
+}
+
+// coredumpReadableProcesses returns the running processes matching one of the
+// patterns whose dumps would be readable by the current user: dumps are owned
+// by the user of the crashed process, and readable by root. Dumps written to
+// files also require the core size limit of the process to be non-zero.
+func coredumpReadableProcesses(handler string, patterns []string) ([]string, error) {
+	processes, err := utils.ListProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	uid := os.Getuid()
+	var readable []string
+	for _, p := range processes {
+		if p.PID == os.Getpid() || !matchesAnyPattern(p.Name, patterns) {
+			continue
+		}
+		if processUID, err := utils.ProcessUID(p.PID); err != nil || (uid != 0 && processUID != uid) {
+			continue
+		}
+		if soft, _, err := utils.ProcessLimit(p.PID, coreLimitName); handler == coreHandlerFile && (err != nil || soft == "0") {
+			continue
+		}
+		readable = append(readable, fmt.Sprintf("%d %s", p.PID, p.Name))
+	}
+	return readable, nil
+}
+
+// matchesAnyPattern reports whether name contains one of the patterns, ignoring case.
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileReadable reports whether the file at path can be opened for reading.
+func fileReadable(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
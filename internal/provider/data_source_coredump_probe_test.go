@@ -0,0 +1,59 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestParseCorePattern(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		handler   string
+		directory string
+	}{
+		{pattern: "core", handler: coreHandlerFile},
+		{pattern: "/var/cores/core.%e.%p", handler: coreHandlerFile, directory: "/var/cores"},
+		{pattern: "/var/cores/%u/core.%p", handler: coreHandlerFile, directory: "/var/cores"},
+		{pattern: "|/usr/lib/systemd/systemd-coredump %P %u %g %s %t 9223372036854775808 %h", handler: coreHandlerSystemd, directory: "/var/lib/systemd/coredump"},
+		{pattern: "|/usr/share/apport/apport -p%p -s%s -c%c -d%d -P%P -u%u -g%g -- %E", handler: coreHandlerApport, directory: "/var/crash"},
+		{pattern: "|/usr/bin/crash-reporter %p", handler: coreHandlerPipe},
+		{pattern: ""},
+	}
+	for _, tt := range tests {
+		handler, directory := parseCorePattern(tt.pattern)
+		if handler != tt.handler || directory != tt.directory {
+			t.Errorf("parseCorePattern(%q) = (%q, %q), want (%q, %q)", tt.pattern, handler, directory, tt.handler, tt.directory)
+		}
+	}
+}
+
+func TestAccTerrapwnerCoredumpProbeDataSource(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("core dump checks are only supported on Linux")
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_coredump_probe" "test" {
+  process_patterns = ["terraform"]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.terrapwner_coredump_probe.test", "core_pattern"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_coredump_probe.test", "core_handler"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_coredump_probe.test", "core_size_limit"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_coredump_probe.test", "dumps_enabled"),
+					resource.TestCheckResourceAttr("data.terrapwner_coredump_probe.test", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
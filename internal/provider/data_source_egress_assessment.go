@@ -0,0 +1,429 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerEgressAssessmentDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerEgressAssessmentDataSource{}
+)
+
+// Categories of the checks of the egress assessment.
+const (
+	egressCategoryPorts = "ports"
+	egressCategoryDNS   = "dns"
+	egressCategorySaaS  = "saas"
+	egressCategoryRawIP = "raw_ip"
+)
+
+// egressCategories are the categories of the egress assessment, in order.
+var egressCategories = []string{egressCategoryPorts, egressCategoryDNS, egressCategorySaaS, egressCategoryRawIP}
+
+// Outcomes of a check of the egress assessment.
+const (
+	egressAllowed = "allowed"
+	egressBlocked = "blocked"
+)
+
+// egressSaaSDomains are the SaaS domains commonly abused for exfiltration or
+// command and control, checked over HTTPS.
+var egressSaaSDomains = []struct{ name, host string }{
+	{"github", "github.com"},
+	{"gitlab", "gitlab.com"},
+	{"pastebin", "pastebin.com"},
+	{"dropbox", "www.dropbox.com"},
+	{"google_drive", "drive.google.com"},
+	{"aws_s3", "s3.amazonaws.com"},
+	{"slack", "slack.com"},
+	{"discord", "discord.com"},
+	{"telegram", "api.telegram.org"},
+}
+
+// egressRawIPs are public services reachable by IP address and by host name
+// over HTTPS, to tell DNS-based filtering from IP-based filtering.
+var egressRawIPs = []struct{ name, ip, host string }{
+	{"cloudflare", "1.1.1.1", "one.one.one.one"},
+	{"google", "8.8.8.8", "dns.google"},
+	{"quad9", "9.9.9.9", "dns.quad9.net"},
+}
+
+// NewTerrapwnerEgressAssessmentDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerEgressAssessmentDataSource() datasource.DataSource {
+	return &TerrapwnerEgressAssessmentDataSource{}
+}
+
+// TerrapwnerEgressAssessmentDataSource is the data source implementation.
+type TerrapwnerEgressAssessmentDataSource struct{}
+
+// TerrapwnerEgressAssessmentDataSourceModel describes the data source data model.
+type TerrapwnerEgressAssessmentDataSourceModel struct {
+	Categories      types.List    `tfsdk:"categories"`
+	Exclude         types.List    `tfsdk:"exclude"`
+	PortTestHost    types.String  `tfsdk:"port_test_host"`
+	Ports           types.String  `tfsdk:"ports"`
+	Concurrency     types.Int64   `tfsdk:"concurrency"`
+	Timeout         types.Int64   `tfsdk:"timeout"`
+	CheckpointFile  types.String  `tfsdk:"checkpoint_file"`
+	Results         types.Map     `tfsdk:"results"`
+	FailReasons     types.Map     `tfsdk:"fail_reasons"`
+	Allowed         types.List    `tfsdk:"allowed"`
+	Blocked         types.List    `tfsdk:"blocked"`
+	CategoryAllowed types.Map     `tfsdk:"category_allowed"`
+	CategoryTotals  types.Map     `tfsdk:"category_totals"`
+	IPBypass        types.List    `tfsdk:"ip_bypass"`
+	Total           types.Int64   `tfsdk:"total"`
+	Score           types.Float64 `tfsdk:"score"`
+	FailReason      types.String  `tfsdk:"fail_reason"`
+}
+
+// egressCheck is a check of the egress assessment: a TCP connection, or a DNS
+// resolution through resolver.
+type egressCheck struct {
+	name     string
+	category string
+	probe    string
+	host     string
+	port     int
+	resolver string
+	// hostnameCheck is the check of the same service by host name, for raw IP checks
+	hostnameCheck string
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerEgressAssessmentDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerEgressAssessmentDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_egress_assessment"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerEgressAssessmentDataSource) Tags() []string {
+	return []string{categoryNetwork, categoryExfil}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerEgressAssessmentDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Runs a curated battery of egress checks and returns a scorecard of what egress is permitted, without " +
+			"writing the probes one by one: TCP connections to common ports of a host listening on every port (`port_<port>`), " +
+			"DNS resolution through the system, an external plain DNS, DNS over TLS and DNS over HTTPS resolver (`dns_*`), " +
+			"HTTPS connections to SaaS domains commonly abused for exfiltration (`saas_*`), and HTTPS connections to public " +
+			"services by raw IP address and by host name (`ip_*`, `hostname_*`). A check is allowed if its connection or " +
+			"resolution succeeds; transparent proxies accepting every connection make checks allowed.",
+		Attributes: map[string]schema.Attribute{
+			"categories": schema.ListAttribute{
+				Description: "Categories of checks to run (default: all): " + strings.Join(egressCategories, ", ") + ".",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"exclude": schema.ListAttribute{
+				Description: "Names of checks to skip, e.g. `port_25`.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"port_test_host": schema.StringAttribute{
+				Description: "Host listening on every TCP port, to which the ports category connects (default: portquiz.net).",
+				Optional:    true,
+			},
+			"ports": schema.StringAttribute{
+				Description: "Comma-separated ports and port ranges checked by the ports category (default: `22,25,53,80,443,3389`).",
+				Optional:    true,
+			},
+			"concurrency": schema.Int64Attribute{
+				Description: "Maximum number of checks run at the same time (default: 10).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each check (default: 5).",
+				Optional:    true,
+			},
+			"checkpoint_file": schema.StringAttribute{
+				Description: "Path of a file recording the checks already run, so an interrupted run resumes where it stopped " +
+					"instead of restarting from zero. The file is removed once every check has run.",
+				Optional: true,
+			},
+			"results": schema.MapAttribute{
+				Description: "Outcome of each check by name: `allowed` or `blocked`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reasons": schema.MapAttribute{
+				Description: "Reason why each blocked check failed, by name.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"allowed": schema.ListAttribute{
+				Description: "Names of the allowed checks, in the order of the battery.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"blocked": schema.ListAttribute{
+				Description: "Names of the blocked checks, in the order of the battery.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"category_allowed": schema.MapAttribute{
+				Description: "Number of allowed checks by category.",
+				ElementType: types.Int64Type,
+				Computed:    true,
+			},
+			"category_totals": schema.MapAttribute{
+				Description: "Number of checks run by category.",
+				ElementType: types.Int64Type,
+				Computed:    true,
+			},
+			"ip_bypass": schema.ListAttribute{
+				Description: "Services reachable by raw IP address but not by host name, showing filtering that only relies " +
+					"on DNS or on host names and is bypassed by connecting to IP addresses.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"total": schema.Int64Attribute{
+				Description: "Number of checks run.",
+				Computed:    true,
+			},
+			"score": schema.Float64Attribute{
+				Description: "Percentage of blocked checks, rounded to one decimal: 100 for fully restricted egress.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors encountered while running the assessment, e.g. checkpoint errors, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read runs the battery and updates the state.
+func (d *TerrapwnerEgressAssessmentDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerEgressAssessmentDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.PortTestHost.IsNull() {
+		data.PortTestHost = types.StringValue("portquiz.net")
+	}
+	if data.Ports.IsNull() {
+		data.Ports = types.StringValue("22,25,53,80,443,3389")
+	}
+	if data.Concurrency.IsNull() {
+		data.Concurrency = types.Int64Value(10)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(5)
+	}
+
+	categories := egressCategories
+	if !data.Categories.IsNull() {
+		resp.Diagnostics.Append(data.Categories.ElementsAs(ctx, &categories, false)...)
+	}
+	var exclude []string
+	if !data.Exclude.IsNull() {
+		resp.Diagnostics.Append(data.Exclude.ElementsAs(ctx, &exclude, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	for _, category := range categories {
+		if !slices.Contains(egressCategories, category) {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("categories must be among %s, got: %s", strings.Join(egressCategories, ", "), category))
+			return
+		}
+	}
+	if data.Concurrency.ValueInt64() < 1 {
+		resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("concurrency must be positive, got: %d", data.Concurrency.ValueInt64()))
+		return
+	}
+	ports, err := parsePortRanges(data.Ports.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid configuration", err.Error())
+		return
+	}
+
+	var checks []egressCheck
+	for _, check := range egressAssessmentChecks(data.PortTestHost.ValueString(), ports) {
+		if slices.Contains(categories, check.category) && !slices.Contains(exclude, check.name) {
+			checks = append(checks, check)
+		}
+	}
+	if len(checks) == 0 {
+		resp.Diagnostics.AddError("Invalid configuration", "no check left to run, the categories and exclusions select none")
+		return
+	}
+
+	// Run the checks concurrently, keeping the results in order
+	inputs := make([]string, len(checks))
+	for i, check := range checks {
+		inputs[i] = check.name + "=" + net.JoinHostPort(check.host, strconv.Itoa(check.port)) + check.resolver
+	}
+	progress := newOperationProgress(ctx, "egress_assessment", len(checks), data.CheckpointFile.ValueString(), inputs)
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	results := make([]probeSetResult, len(checks))
+	var failures []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, data.Concurrency.ValueInt64())
+	for i, check := range checks {
+		if progress.Resume(ctx, check.name, &results[i]) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, check egressCheck) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i] = runEgressCheck(ctx, check, timeout)
+			if err := progress.Complete(ctx, check.name, results[i]); err != nil {
+				mu.Lock()
+				failures = append(failures, err.Error())
+				mu.Unlock()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+	if err := progress.Finish(ctx); err != nil {
+		failures = append(failures, err.Error())
+	}
+	if ctx.Err() != nil {
+		failures = append(failures, fmt.Sprintf("egress assessment interrupted: %v", ctx.Err()))
+	}
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Build the scorecard
+	outcomes := map[string]string{}
+	failReasons := map[string]string{}
+	allowed := []string{}
+	blocked := []string{}
+	categoryAllowed := map[string]int64{}
+	categoryTotals := map[string]int64{}
+	for _, check := range checks {
+		categoryAllowed[check.category] = 0
+		categoryTotals[check.category]++
+	}
+	for i, check := range checks {
+		if results[i].Success {
+			outcomes[check.name] = egressAllowed
+			allowed = append(allowed, check.name)
+			categoryAllowed[check.category]++
+			continue
+		}
+		outcomes[check.name] = egressBlocked
+		failReasons[check.name] = results[i].FailReason
+		blocked = append(blocked, check.name)
+	}
+	ipBypass := []string{}
+	for _, check := range checks {
+		if check.hostnameCheck != "" && outcomes[check.name] == egressAllowed && outcomes[check.hostnameCheck] == egressBlocked {
+			ipBypass = append(ipBypass, strings.TrimPrefix(check.name, "ip_"))
+		}
+	}
+	data.Total = types.Int64Value(int64(len(checks)))
+	data.Score = types.Float64Value(roundScore(float64(len(blocked)) * 100 / float64(len(checks))))
+
+	// Convert to Terraform types
+	resultsMap, diags := types.MapValueFrom(ctx, types.StringType, outcomes)
+	resp.Diagnostics.Append(diags...)
+	failReasonsMap, diags := types.MapValueFrom(ctx, types.StringType, failReasons)
+	resp.Diagnostics.Append(diags...)
+	allowedList, diags := types.ListValueFrom(ctx, types.StringType, allowed)
+	resp.Diagnostics.Append(diags...)
+	blockedList, diags := types.ListValueFrom(ctx, types.StringType, blocked)
+	resp.Diagnostics.Append(diags...)
+	categoryAllowedMap, diags := types.MapValueFrom(ctx, types.Int64Type, categoryAllowed)
+	resp.Diagnostics.Append(diags...)
+	categoryTotalsMap, diags := types.MapValueFrom(ctx, types.Int64Type, categoryTotals)
+	resp.Diagnostics.Append(diags...)
+	ipBypassList, diags := types.ListValueFrom(ctx, types.StringType, ipBypass)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Results = resultsMap
+	data.FailReasons = failReasonsMap
+	data.Allowed = allowedList
+	data.Blocked = blockedList
+	data.CategoryAllowed = categoryAllowedMap
+	data.CategoryTotals = categoryTotalsMap
+	data.IPBypass = ipBypassList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// egressAssessmentChecks returns the battery of checks, in order, connecting
+// to ports of portTestHost for the ports category.
+func egressAssessmentChecks(portTestHost string, ports []int) []egressCheck {
+	var checks []egressCheck
+	for _, port := range ports {
+		checks = append(checks, egressCheck{
+			name: fmt.Sprintf("port_%d", port), category: egressCategoryPorts, probe: "tcp", host: portTestHost, port: port,
+		})
+	}
+	checks = append(checks,
+		egressCheck{name: "dns_system", category: egressCategoryDNS, probe: "dns", host: "example.com"},
+		egressCheck{name: "dns_external", category: egressCategoryDNS, probe: "dns", host: "example.com", resolver: "8.8.8.8"},
+		egressCheck{name: "dns_over_tls", category: egressCategoryDNS, probe: "dns", host: "example.com", resolver: "tls://1.1.1.1"},
+		egressCheck{name: "dns_over_https", category: egressCategoryDNS, probe: "dns", host: "example.com", resolver: "https://1.1.1.1/dns-query"},
+	)
+	for _, domain := range egressSaaSDomains {
+		checks = append(checks, egressCheck{
+			name: "saas_" + domain.name, category: egressCategorySaaS, probe: "tcp", host: domain.host, port: 443,
+		})
+	}
+	for _, service := range egressRawIPs {
+		checks = append(checks,
+			egressCheck{
+				name: "ip_" + service.name, category: egressCategoryRawIP, probe: "tcp", host: service.ip, port: 443,
+				hostnameCheck: "hostname_" + service.name,
+			},
+			egressCheck{name: "hostname_" + service.name, category: egressCategoryRawIP, probe: "tcp", host: service.host, port: 443},
+		)
+	}
+	return checks
+}
+
+// runEgressCheck runs a check of the egress assessment.
+func runEgressCheck(ctx context.Context, check egressCheck, timeout time.Duration) probeSetResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var success bool
+	var failReason string
+	switch check.probe {
+	case "dns":
+		resolver, err := utils.NewResolver(check.resolver)
+		if err != nil {
+			return probeSetResult{FailReason: err.Error()}
+		}
+		success, failReason, _ = probeDNS(ctx, resolver, ipFamilyAny, check.host)
+	case "tcp":
+		success, failReason, _, _ = probeTCP(ctx, ipFamilyAny, check.host, check.port, nil, 0)
+	}
+	return probeSetResult{Success: success, FailReason: failReason}
+}
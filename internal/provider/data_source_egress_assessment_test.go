@@ -0,0 +1,119 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerEgressAssessmentDataSource(t *testing.T) {
+	open, closed := testAccPorts(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_egress_assessment" "test" {
+  categories     = ["ports"]
+  port_test_host = "127.0.0.1"
+  ports          = "%[1]d,%[2]d"
+  timeout        = 2
+}
+`, open, closed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "results.%", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", fmt.Sprintf("results.port_%d", open), "allowed"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", fmt.Sprintf("results.port_%d", closed), "blocked"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "fail_reasons.%", "1"),
+					resource.TestMatchResourceAttr("data.terrapwner_egress_assessment.test", fmt.Sprintf("fail_reasons.port_%d", closed),
+						regexp.MustCompile(`^TCP connection failed: .*refused`)),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "allowed.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "allowed.0", fmt.Sprintf("port_%d", open)),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "blocked.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "category_allowed.%", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "category_allowed.ports", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "category_totals.ports", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "ip_bypass.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "total", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "score", "50"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_egress_assessment" "test" {
+  categories     = ["ports"]
+  exclude        = ["port_%[2]d"]
+  port_test_host = "127.0.0.1"
+  ports          = "%[1]d,%[2]d"
+}
+`, open, closed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "total", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "blocked.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_egress_assessment.test", "score", "0"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_egress_assessment" "test" {
+  categories = ["smtp"]
+}
+`,
+				ExpectError: regexp.MustCompile("categories must be among ports, dns, saas, raw_ip, got: smtp"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_egress_assessment" "test" {
+  categories = ["ports"]
+  ports      = "0"
+}
+`,
+				ExpectError: regexp.MustCompile("ports must be comma-separated ports or port ranges"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_egress_assessment" "test" {
+  categories = ["ports"]
+  ports      = "25"
+  exclude    = ["port_25"]
+}
+`,
+				ExpectError: regexp.MustCompile("no check left to run"),
+			},
+		},
+	})
+}
+
+func TestEgressAssessmentChecks(t *testing.T) {
+	checks := egressAssessmentChecks("portquiz.net", []int{22, 443})
+
+	names := map[string]bool{}
+	categories := map[string]bool{}
+	for _, check := range checks {
+		if names[check.name] {
+			t.Errorf("duplicate check name: %s", check.name)
+		}
+		names[check.name] = true
+		categories[check.category] = true
+	}
+	for _, check := range checks {
+		if check.hostnameCheck != "" && !names[check.hostnameCheck] {
+			t.Errorf("check %s compares with missing check %s", check.name, check.hostnameCheck)
+		}
+	}
+	for _, category := range egressCategories {
+		if !categories[category] {
+			t.Errorf("no check in category %s", category)
+		}
+	}
+	if checks[0].name != "port_22" || checks[0].host != "portquiz.net" || checks[1].port != 443 {
+		t.Errorf("unexpected port checks: %+v", checks[:2])
+	}
+}
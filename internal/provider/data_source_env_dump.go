@@ -5,8 +5,6 @@ package provider
 
 import (
 	"context"
-	"os"
-	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
@@ -22,7 +20,9 @@ func NewTerrapwnerEnvDumpDataSource() datasource.DataSource {
 }
 
 // TerrapwnerEnvDumpDataSource defines the data source implementation.
-type TerrapwnerEnvDumpDataSource struct{}
+type TerrapwnerEnvDumpDataSource struct {
+	snapshot *environmentSnapshot
+}
 
 // TerrapwnerEnvDumpDataSourceModel describes the data source data model.
 type TerrapwnerEnvDumpDataSourceModel struct {
@@ -35,6 +35,11 @@ func (d *TerrapwnerEnvDumpDataSource) Metadata(ctx context.Context, req datasour
 	resp.TypeName = req.ProviderTypeName + "_env_dump"
 }
 
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerEnvDumpDataSource) Tags() []string {
+	return []string{"secrets"}
+}
+
 func (d *TerrapwnerEnvDumpDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Description: "Reads all environment variables and makes them available as a map",
@@ -58,7 +63,7 @@ func (d *TerrapwnerEnvDumpDataSource) Schema(ctx context.Context, req datasource
 }
 
 func (d *TerrapwnerEnvDumpDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
-	// No configuration needed
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
 }
 
 func (d *TerrapwnerEnvDumpDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -76,16 +81,8 @@ func (d *TerrapwnerEnvDumpDataSource) Read(ctx context.Context, req datasource.R
 		data.MaskValues = types.BoolValue(true)
 	}
 
-	// Read all environment variables
-	envVars := make(map[string]string)
-	for _, env := range os.Environ() {
-		// Split the environment variable into key and value
-		key, value, found := strings.Cut(env, "=")
-		if !found {
-			continue
-		}
-		envVars[key] = value
-	}
+	// Read all environment variables from the provider snapshot
+	envVars := d.snapshot.Environ()
 
 	// If mask_values is true, mask the values
 	if data.MaskValues.ValueBool() {
@@ -0,0 +1,564 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerEtcdConsulProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerEtcdConsulProbeDataSource{}
+)
+
+// Authentication status of a key-value store.
+const (
+	aclEnabled  = "enabled"
+	aclDisabled = "disabled"
+	aclUnknown  = "unknown"
+)
+
+const (
+	// defaultEtcdConsulProbeTimeout is the default timeout in seconds of each request.
+	defaultEtcdConsulProbeTimeout = 5
+	// defaultEtcdConsulProbeMaxKeys is the default number of keys read from each endpoint.
+	defaultEtcdConsulProbeMaxKeys = 1000
+	// kvStoreMaxResponseSize bounds the size of a key listing.
+	kvStoreMaxResponseSize = 32 << 20
+)
+
+// defaultSecretKeyPatterns identifies keys likely to hold credentials.
+var defaultSecretKeyPatterns = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"credential",
+	"private_key",
+	"privatekey",
+	"api_key",
+	"apikey",
+	"access_key",
+	"connection_string",
+}
+
+// NewTerrapwnerEtcdConsulProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerEtcdConsulProbeDataSource() datasource.DataSource {
+	return &TerrapwnerEtcdConsulProbeDataSource{}
+}
+
+// TerrapwnerEtcdConsulProbeDataSource is the data source implementation.
+type TerrapwnerEtcdConsulProbeDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerEtcdConsulProbeDataSourceModel describes the data source data model.
+type TerrapwnerEtcdConsulProbeDataSourceModel struct {
+	EtcdEndpoints   types.List   `tfsdk:"etcd_endpoints"`
+	ConsulEndpoints types.List   `tfsdk:"consul_endpoints"`
+	KeyPatterns     types.List   `tfsdk:"key_patterns"`
+	MaxKeys         types.Int64  `tfsdk:"max_keys"`
+	MaskValues      types.Bool   `tfsdk:"mask_values"`
+	Timeout         types.Int64  `tfsdk:"timeout"`
+	Endpoints       types.Map    `tfsdk:"endpoints"`
+	ACLStatus       types.Map    `tfsdk:"acl_status"`
+	AnonymousRead   types.List   `tfsdk:"anonymous_read"`
+	KeyCount        types.Int64  `tfsdk:"key_count"`
+	SecretKeys      types.List   `tfsdk:"secret_keys"`
+	SecretValues    types.Map    `tfsdk:"secret_values"`
+	ExposureFound   types.Bool   `tfsdk:"exposure_found"`
+	FailReason      types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerEtcdConsulProbeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerEtcdConsulProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_consul_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerEtcdConsulProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerEtcdConsulProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Checks whether etcd and Consul key-value stores reachable from the runner can be read without credentials: " +
+			"reports the authentication or ACL status of each endpoint, lists the keys readable anonymously and flags the " +
+			"keys that look like credentials, with masked values. Requests are sent without credentials and without " +
+			"verifying certificates; etcd is read through its v3 HTTP gateway.",
+		Attributes: map[string]schema.Attribute{
+			"etcd_endpoints": schema.ListAttribute{
+				Description: "etcd endpoints to probe, as URLs or host:port (default: ETCDCTL_ENDPOINTS, else http://127.0.0.1:2379).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"consul_endpoints": schema.ListAttribute{
+				Description: "Consul endpoints to probe, as URLs or host:port (default: CONSUL_HTTP_ADDR, else http://127.0.0.1:8500).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"key_patterns": schema.ListAttribute{
+				Description: "Case-insensitive substrings identifying keys that hold credentials " +
+					"(default: password, secret, token, credential, API and private keys).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"max_keys": schema.Int64Attribute{
+				Description: fmt.Sprintf("Maximum number of keys read from each endpoint (default: %d).", defaultEtcdConsulProbeMaxKeys),
+				Optional:    true,
+			},
+			"mask_values": schema.BoolAttribute{
+				Description: "If true, the values of secret keys are masked, keeping only their first characters (default: true).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: fmt.Sprintf("Timeout in seconds for each request (default: %d).", defaultEtcdConsulProbeTimeout),
+				Optional:    true,
+			},
+			"endpoints": schema.MapAttribute{
+				Description: "Exposure of each probed endpoint, keyed by `store@host:port`: exposed (keys readable without " +
+					"credentials), auth_required, reachable (the response was not recognized) or unreachable.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"acl_status": schema.MapAttribute{
+				Description: "Authentication (etcd) or ACL (Consul) status of each reachable endpoint, keyed by `store@host:port`: " +
+					"enabled, disabled or unknown.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"anonymous_read": schema.ListAttribute{
+				Description: "Endpoints whose keys are readable without credentials, as `store@host:port`, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"key_count": schema.Int64Attribute{
+				Description: "Number of keys read without credentials across all endpoints.",
+				Computed:    true,
+			},
+			"secret_keys": schema.ListAttribute{
+				Description: "Keys read without credentials that match key_patterns, as `store@host:port/key`, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"secret_values": schema.MapAttribute{
+				Description: "Values of the secret keys, keyed like secret_keys, masked unless mask_values is false.",
+				ElementType: types.StringType,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"exposure_found": schema.BoolAttribute{
+				Description: "True if at least one endpoint is readable without credentials.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors of the requests to reachable endpoints, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// kvStoreResult is the outcome of probing one key-value store endpoint.
+type kvStoreResult struct {
+	exposure string
+	acl      string
+	// keys maps the keys read without credentials to their values
+	keys map[string]string
+	err  error
+}
+
+// Read probes the key-value stores and updates the state.
+func (d *TerrapwnerEtcdConsulProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerEtcdConsulProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.MaxKeys.IsNull() {
+		data.MaxKeys = types.Int64Value(defaultEtcdConsulProbeMaxKeys)
+	}
+	if data.MaskValues.IsNull() {
+		data.MaskValues = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(defaultEtcdConsulProbeTimeout)
+	}
+	etcdEndpoints := defaultEtcdEndpoints(d.snapshot.Getenv)
+	if !data.EtcdEndpoints.IsNull() {
+		etcdEndpoints = nil
+		resp.Diagnostics.Append(data.EtcdEndpoints.ElementsAs(ctx, &etcdEndpoints, false)...)
+	}
+	consulEndpoints := defaultConsulEndpoints(d.snapshot.Getenv)
+	if !data.ConsulEndpoints.IsNull() {
+		consulEndpoints = nil
+		resp.Diagnostics.Append(data.ConsulEndpoints.ElementsAs(ctx, &consulEndpoints, false)...)
+	}
+	patterns := defaultSecretKeyPatterns
+	if !data.KeyPatterns.IsNull() {
+		patterns = nil
+		resp.Diagnostics.Append(data.KeyPatterns.ElementsAs(ctx, &patterns, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if data.MaxKeys.ValueInt64() < 1 {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("max_keys must be at least 1, got: %d", data.MaxKeys.ValueInt64()),
+		)
+		return
+	}
+
+	// Resolve every endpoint to a base URL before sending anything
+	type target struct{ store, address, baseURL string }
+	var targets []target
+	for _, store := range []struct {
+		name      string
+		port      int
+		endpoints []string
+	}{{"etcd", 2379, etcdEndpoints}, {"consul", 8500, consulEndpoints}} {
+		for _, endpoint := range store.endpoints {
+			baseURL, address, err := parseKVStoreEndpoint(endpoint, store.port)
+			if err != nil {
+				resp.Diagnostics.AddError(
+					"Invalid configuration",
+					fmt.Sprintf("%s_endpoints must only contain URLs or host:port, got: %s", store.name, endpoint),
+				)
+				return
+			}
+			targets = append(targets, target{store.name, address, baseURL})
+		}
+	}
+
+	// Probe every endpoint concurrently, keeping the results in order
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	maxKeys := int(data.MaxKeys.ValueInt64())
+	results := make([]kvStoreResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			if t.store == "etcd" {
+				results[i] = probeEtcd(ctx, t.baseURL, t.address, maxKeys, timeout)
+			} else {
+				results[i] = probeConsul(ctx, t.baseURL, t.address, maxKeys, timeout)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	endpoints := map[string]string{}
+	aclStatus := map[string]string{}
+	anonymousRead := []string{}
+	secretKeys := []string{}
+	secretValues := map[string]string{}
+	var keyCount int64
+	var failures []string
+	for i, t := range targets {
+		key := t.store + "@" + t.address
+		result := results[i]
+		endpoints[key] = result.exposure
+		if result.exposure != exposureUnreachable {
+			aclStatus[key] = result.acl
+		}
+		if result.exposure == exposureExposed {
+			anonymousRead = append(anonymousRead, key)
+		}
+		keyCount += int64(len(result.keys))
+		for name, value := range result.keys {
+			if !matchesAnyPattern(name, patterns) {
+				continue
+			}
+			secretKey := key + "/" + strings.TrimPrefix(name, "/")
+			secretKeys = append(secretKeys, secretKey)
+			if data.MaskValues.ValueBool() {
+				value = maskSecretValue(value)
+			}
+			secretValues[secretKey] = value
+		}
+		if result.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", key, result.err))
+		}
+	}
+	sort.Strings(anonymousRead)
+	sort.Strings(secretKeys)
+
+	data.KeyCount = types.Int64Value(keyCount)
+	data.ExposureFound = types.BoolValue(len(anonymousRead) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	endpointsMap, diags := types.MapValueFrom(ctx, types.StringType, endpoints)
+	resp.Diagnostics.Append(diags...)
+	aclStatusMap, diags := types.MapValueFrom(ctx, types.StringType, aclStatus)
+	resp.Diagnostics.Append(diags...)
+	anonymousReadList, diags := types.ListValueFrom(ctx, types.StringType, anonymousRead)
+	resp.Diagnostics.Append(diags...)
+	secretKeysList, diags := types.ListValueFrom(ctx, types.StringType, secretKeys)
+	resp.Diagnostics.Append(diags...)
+	secretValuesMap, diags := types.MapValueFrom(ctx, types.StringType, secretValues)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Endpoints = endpointsMap
+	data.ACLStatus = aclStatusMap
+	data.AnonymousRead = anonymousReadList
+	data.SecretKeys = secretKeysList
+	data.SecretValues = secretValuesMap
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// defaultEtcdEndpoints returns the endpoints etcdctl would use.
+func defaultEtcdEndpoints(getenv func(string) string) []string {
+	if endpoints := getenv("ETCDCTL_ENDPOINTS"); endpoints != "" {
+		return strings.Split(endpoints, ",")
+	}
+	return []string{"http://127.0.0.1:2379"}
+}
+
+// defaultConsulEndpoints returns the endpoint the consul CLI would use.
+func defaultConsulEndpoints(getenv func(string) string) []string {
+	address := getenv("CONSUL_HTTP_ADDR")
+	if address == "" {
+		address = "127.0.0.1:8500"
+	}
+	if !strings.Contains(address, "://") {
+		scheme := "http"
+		if ssl, _ := strconv.ParseBool(getenv("CONSUL_HTTP_SSL")); ssl {
+			scheme = "https"
+		}
+		address = scheme + "://" + address
+	}
+	return []string{address}
+}
+
+// parseKVStoreEndpoint returns the base URL and the host:port address of an
+// endpoint given as a URL or as host:port, using the default port if none is set.
+func parseKVStoreEndpoint(endpoint string, defaultPort int) (string, string, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "", "", fmt.Errorf("expected an http or https URL")
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), strconv.Itoa(defaultPort))
+	}
+	return u.Scheme + "://" + address + strings.TrimSuffix(u.Path, "/"), address, nil
+}
+
+// probeEtcd reads the keys of an etcd member through the v3 HTTP gateway and
+// checks whether authentication is enabled.
+func probeEtcd(ctx context.Context, baseURL, address string, maxKeys int, timeout time.Duration) kvStoreResult {
+	client, ok := kvStoreClient(ctx, address, timeout)
+	if !ok {
+		return kvStoreResult{exposure: exposureUnreachable, acl: aclUnknown}
+	}
+	defer client.CloseIdleConnections()
+	result := kvStoreResult{exposure: exposureReachable, acl: aclUnknown}
+
+	// Available from etcd 3.5, readable without credentials
+	status, body, err := kvStoreRequest(ctx, client, http.MethodPost, baseURL+"/v3/auth/status", []byte("{}"))
+	if err == nil && status == http.StatusOK {
+		var authStatus struct {
+			Enabled bool `json:"enabled"`
+		}
+		if json.Unmarshal(body, &authStatus) == nil {
+			result.acl = aclDisabled
+			if authStatus.Enabled {
+				result.acl = aclEnabled
+			}
+		}
+	}
+
+	// Range over the whole keyspace
+	rangeRequest, _ := json.Marshal(map[string]any{"key": "AA==", "range_end": "AA==", "limit": maxKeys})
+	status, body, err = kvStoreRequest(ctx, client, http.MethodPost, baseURL+"/v3/kv/range", rangeRequest)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	switch {
+	case status == http.StatusOK:
+		var rangeResponse struct {
+			Header *json.RawMessage `json:"header"`
+			KVs    []struct {
+				Key   string `json:"key"`
+				Value string `json:"value"`
+			} `json:"kvs"`
+		}
+		if json.Unmarshal(body, &rangeResponse) != nil || rangeResponse.Header == nil {
+			return result
+		}
+		result.exposure = exposureExposed
+		if result.acl == aclUnknown {
+			result.acl = aclDisabled
+		}
+		result.keys = map[string]string{}
+		for _, kv := range rangeResponse.KVs {
+			key, _ := base64.StdEncoding.DecodeString(kv.Key)
+			value, _ := base64.StdEncoding.DecodeString(kv.Value)
+			result.keys[string(key)] = string(value)
+		}
+	case status == http.StatusUnauthorized || status == http.StatusForbidden,
+		strings.Contains(string(body), "user name is empty"),
+		strings.Contains(string(body), "permission denied"),
+		strings.Contains(string(body), "invalid auth token"):
+		result.exposure = exposureAuthRequired
+		result.acl = aclEnabled
+	}
+	return result
+}
+
+// probeConsul reads the keys of a Consul agent and checks whether ACLs are enabled.
+func probeConsul(ctx context.Context, baseURL, address string, maxKeys int, timeout time.Duration) kvStoreResult {
+	client, ok := kvStoreClient(ctx, address, timeout)
+	if !ok {
+		return kvStoreResult{exposure: exposureUnreachable, acl: aclUnknown}
+	}
+	defer client.CloseIdleConnections()
+	result := kvStoreResult{exposure: exposureReachable, acl: aclUnknown}
+
+	// Resolves the anonymous token when ACLs are enabled
+	status, body, err := kvStoreRequest(ctx, client, http.MethodGet, baseURL+"/v1/acl/token/self", nil)
+	if err == nil {
+		switch {
+		case strings.Contains(string(body), "ACL support disabled"):
+			result.acl = aclDisabled
+		case status == http.StatusOK, status == http.StatusForbidden,
+			strings.Contains(string(body), "ACL not found"):
+			result.acl = aclEnabled
+		}
+	}
+
+	status, body, err = kvStoreRequest(ctx, client, http.MethodGet, baseURL+"/v1/kv/?recurse=true", nil)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	switch status {
+	case http.StatusOK:
+		var entries []struct {
+			Key   string  `json:"Key"`
+			Value *string `json:"Value"`
+		}
+		if json.Unmarshal(body, &entries) != nil {
+			return result
+		}
+		result.exposure = exposureExposed
+		result.keys = map[string]string{}
+		for _, entry := range entries {
+			if len(result.keys) == maxKeys {
+				break
+			}
+			var value []byte
+			if entry.Value != nil {
+				value, _ = base64.StdEncoding.DecodeString(*entry.Value)
+			}
+			result.keys[entry.Key] = string(value)
+		}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		result.exposure = exposureAuthRequired
+		result.acl = aclEnabled
+	}
+	return result
+}
+
+// kvStoreClient returns an HTTP client for a key-value store endpoint, or false
+// if its port is closed.
+func kvStoreClient(ctx context.Context, address string, timeout time.Duration) (*http.Client, bool) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !portOpen(ctx, dialer, address) {
+		return nil, false
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: utils.LoggingTransport(&http.Transport{
+			DialContext: dialer.DialContext,
+			// Internal stores commonly use self-signed certificates
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, true
+}
+
+// kvStoreRequest sends an unauthenticated request and returns the status code and body.
+func kvStoreRequest(ctx context.Context, client *http.Client, method, url string, body []byte) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	httpReq.Header.Set("User-Agent", utils.GetUserAgent())
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer httpResp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(httpResp.Body, kvStoreMaxResponseSize))
+	if err != nil {
+		return httpResp.StatusCode, nil, err
+	}
+	return httpResp.StatusCode, content, nil
+}
+
+// maskSecretValue keeps the first characters of long values, enough to
+// recognize a credential format, and masks the rest.
+func maskSecretValue(value string) string {
+	if len(value) < 12 {
+		return "****"
+	}
+	return value[:4] + "****"
+}
@@ -0,0 +1,153 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// newEtcdServer returns an etcd gateway serving two keys, requiring
+// credentials if authEnabled is set.
+func newEtcdServer(authEnabled bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/auth/status":
+			fmt.Fprintf(w, `{"header":{"revision":"7"},"enabled":%t}`, authEnabled)
+		case "/v3/kv/range":
+			if authEnabled {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"etcdserver: user name is empty","code":3}`)
+				return
+			}
+			// /app/db/password = hunter2-but-longer, /app/replicas = 3
+			fmt.Fprint(w, `{"header":{"revision":"7"},"kvs":[`+
+				`{"key":"L2FwcC9kYi9wYXNzd29yZA==","value":"aHVudGVyMi1idXQtbG9uZ2Vy"},`+
+				`{"key":"L2FwcC9yZXBsaWNhcw==","value":"Mw=="}],"count":"2"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// newConsulServer returns a Consul agent without ACLs serving two keys.
+func newConsulServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/acl/token/self":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, "ACL support disabled")
+		case "/v1/kv/":
+			// ci/github_token = ghp_0123456789abcdef, ci/region = null
+			fmt.Fprint(w, `[{"Key":"ci/github_token","Value":"Z2hwXzAxMjM0NTY3ODlhYmNkZWY="},{"Key":"ci/region","Value":null}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestProbeEtcd(t *testing.T) {
+	open := newEtcdServer(false)
+	defer open.Close()
+	locked := newEtcdServer(true)
+	defer locked.Close()
+
+	tests := []struct {
+		server       *httptest.Server
+		wantExposure string
+		wantACL      string
+		wantKeys     int
+	}{
+		{open, exposureExposed, aclDisabled, 2},
+		{locked, exposureAuthRequired, aclEnabled, 0},
+	}
+	for _, tt := range tests {
+		address := strings.TrimPrefix(tt.server.URL, "http://")
+		got := probeEtcd(context.Background(), tt.server.URL, address, 10, 2*time.Second)
+		if got.exposure != tt.wantExposure || got.acl != tt.wantACL || len(got.keys) != tt.wantKeys {
+			t.Errorf("probeEtcd(%s) = %s, %s, %d keys, want %s, %s, %d keys",
+				address, got.exposure, got.acl, len(got.keys), tt.wantExposure, tt.wantACL, tt.wantKeys)
+		}
+	}
+	if got := probeEtcd(context.Background(), open.URL, strings.TrimPrefix(open.URL, "http://"), 10, 2*time.Second); got.keys["/app/db/password"] != "hunter2-but-longer" {
+		t.Errorf("probeEtcd() keys = %v, want /app/db/password decoded", got.keys)
+	}
+	if got := probeEtcd(context.Background(), "http://"+closedAddress(t), closedAddress(t), 10, 2*time.Second); got.exposure != exposureUnreachable {
+		t.Errorf("probeEtcd(closed) exposure = %s, want %s", got.exposure, exposureUnreachable)
+	}
+}
+
+func TestParseKVStoreEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint    string
+		wantBaseURL string
+		wantAddress string
+		wantErr     bool
+	}{
+		{"127.0.0.1", "http://127.0.0.1:2379", "127.0.0.1:2379", false},
+		{"https://etcd.internal:2379/", "https://etcd.internal:2379", "etcd.internal:2379", false},
+		{"http://[::1]", "http://[::1]:2379", "[::1]:2379", false},
+		{"unix:///run/etcd.sock", "", "", true},
+	}
+	for _, tt := range tests {
+		baseURL, address, err := parseKVStoreEndpoint(tt.endpoint, 2379)
+		if (err != nil) != tt.wantErr || baseURL != tt.wantBaseURL || address != tt.wantAddress {
+			t.Errorf("parseKVStoreEndpoint(%q) = %q, %q, %v, want %q, %q", tt.endpoint, baseURL, address, err, tt.wantBaseURL, tt.wantAddress)
+		}
+	}
+}
+
+func TestAccTerrapwnerEtcdConsulProbeDataSource(t *testing.T) {
+	etcd := newEtcdServer(false)
+	defer etcd.Close()
+	consul := newConsulServer()
+	defer consul.Close()
+	etcdAddress := strings.TrimPrefix(etcd.URL, "http://")
+	consulAddress := strings.TrimPrefix(consul.URL, "http://")
+	closed := closedAddress(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_etcd_consul_probe" "test" {
+  etcd_endpoints   = [%q, %q]
+  consul_endpoints = [%q]
+}
+`, etcd.URL, closed, consulAddress),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "endpoints.%", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "endpoints.etcd@"+etcdAddress, "exposed"),
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "endpoints.etcd@"+closed, "unreachable"),
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "acl_status.consul@"+consulAddress, "disabled"),
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "anonymous_read.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "key_count", "4"),
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "secret_keys.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "secret_values.etcd@"+etcdAddress+"/app/db/password", "hunt****"),
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "secret_values.consul@"+consulAddress+"/ci/github_token", "ghp_****"),
+					resource.TestCheckResourceAttr("data.terrapwner_etcd_consul_probe.test", "exposure_found", "true"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_etcd_consul_probe" "test" {
+  etcd_endpoints   = []
+  consul_endpoints = []
+  max_keys         = 0
+}
+`,
+				ExpectError: regexp.MustCompile("max_keys must be at least 1"),
+			},
+		},
+	})
+}
@@ -0,0 +1,214 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerGCPMetadataDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerGCPMetadataDataSource{}
+)
+
+// NewTerrapwnerGCPMetadataDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerGCPMetadataDataSource() datasource.DataSource {
+	return &TerrapwnerGCPMetadataDataSource{}
+}
+
+// TerrapwnerGCPMetadataDataSource is the data source implementation.
+type TerrapwnerGCPMetadataDataSource struct{}
+
+// TerrapwnerGCPMetadataDataSourceModel describes the data source data model.
+type TerrapwnerGCPMetadataDataSourceModel struct {
+	Timeout          types.Int64  `tfsdk:"timeout"`
+	Available        types.Bool   `tfsdk:"available"`
+	ProjectID        types.String `tfsdk:"project_id"`
+	NumericProjectID types.String `tfsdk:"numeric_project_id"`
+	InstanceName     types.String `tfsdk:"instance_name"`
+	Zone             types.String `tfsdk:"zone"`
+	ServiceAccounts  types.List   `tfsdk:"service_accounts"`
+	Scopes           types.List   `tfsdk:"scopes"`
+	Metadata         types.Map    `tfsdk:"metadata"`
+	FailReason       types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerGCPMetadataDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerGCPMetadataDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_gcp_metadata"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerGCPMetadataDataSource) Tags() []string {
+	return []string{categoryCloud, categoryNetwork, "gcp"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerGCPMetadataDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Recursively queries the GCE metadata server (with the Metadata-Flavor: Google header) and exposes the " +
+			"instance and project metadata, including the attached service accounts, their OAuth scopes and the project " +
+			"attributes. Access tokens are never requested.",
+		Attributes: map[string]schema.Attribute{
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each metadata request (default: 2).",
+				Optional:    true,
+			},
+			"available": schema.BoolAttribute{
+				Description: "True if the metadata server answered.",
+				Computed:    true,
+			},
+			"project_id": schema.StringAttribute{
+				Description: "Project ID.",
+				Computed:    true,
+			},
+			"numeric_project_id": schema.StringAttribute{
+				Description: "Project number.",
+				Computed:    true,
+			},
+			"instance_name": schema.StringAttribute{
+				Description: "Name of the instance.",
+				Computed:    true,
+			},
+			"zone": schema.StringAttribute{
+				Description: "Zone of the instance.",
+				Computed:    true,
+			},
+			"service_accounts": schema.ListAttribute{
+				Description: "Emails of the service accounts attached to the instance.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"scopes": schema.ListAttribute{
+				Description: "OAuth scopes of the default service account (cloud-platform grants every API the account's IAM roles allow).",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"metadata": schema.MapAttribute{
+				Description: "Metadata values by path relative to /computeMetadata/v1/ (e.g. instance/zone, project/attributes/ssh-keys).",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason the metadata server could not be queried, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read queries the metadata server and updates the state.
+func (d *TerrapwnerGCPMetadataDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerGCPMetadataDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(2)
+	}
+
+	data.Available = types.BoolValue(false)
+	data.FailReason = types.StringValue("")
+	metadata := map[string]string{}
+	serviceAccounts := []string{}
+	scopes := []string{}
+
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	var failures []string
+	for _, section := range []string{"instance", "project"} {
+		body, err := gceMetadataGet(ctx, "/computeMetadata/v1/"+section+"/?recursive=true", timeout)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		var tree any
+		if err := json.Unmarshal([]byte(body), &tree); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: invalid metadata: %v", section, err))
+			continue
+		}
+		data.Available = types.BoolValue(true)
+		flattenMetadata(section, tree, metadata)
+	}
+	if !data.Available.ValueBool() {
+		data.FailReason = types.StringValue(strings.Join(failures, "; "))
+	}
+
+	// Zone is in format "projects/<number>/zones/<zone>"
+	zone := metadata["instance/zone"]
+	data.Zone = types.StringValue(zone[strings.LastIndex(zone, "/")+1:])
+	data.ProjectID = types.StringValue(metadata["project/projectId"])
+	data.NumericProjectID = types.StringValue(metadata["project/numericProjectId"])
+	data.InstanceName = types.StringValue(metadata["instance/name"])
+
+	for key, value := range metadata {
+		if strings.HasPrefix(key, "instance/serviceAccounts/") && strings.HasSuffix(key, "/email") && value != "" {
+			serviceAccounts = append(serviceAccounts, value)
+		}
+		if strings.HasPrefix(key, "instance/serviceAccounts/default/scopes/") {
+			scopes = append(scopes, value)
+		}
+	}
+	slices.Sort(serviceAccounts)
+	serviceAccounts = slices.Compact(serviceAccounts)
+	slices.Sort(scopes)
+
+	// Convert to Terraform types
+	serviceAccountsList, diags := types.ListValueFrom(ctx, types.StringType, serviceAccounts)
+	resp.Diagnostics.Append(diags...)
+	scopesList, diags := types.ListValueFrom(ctx, types.StringType, scopes)
+	resp.Diagnostics.Append(diags...)
+	metadataMap, diags := types.MapValueFrom(ctx, types.StringType, metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ServiceAccounts = serviceAccountsList
+	data.Scopes = scopesList
+	data.Metadata = metadataMap
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// flattenMetadata stores the leaves of a recursive metadata response by slash-separated path.
+func flattenMetadata(prefix string, node any, out map[string]string) {
+	switch v := node.(type) {
+	case map[string]any:
+		for key, child := range v {
+			flattenMetadata(prefix+"/"+key, child, out)
+		}
+	case []any:
+		for i, child := range v {
+			flattenMetadata(prefix+"/"+strconv.Itoa(i), child, out)
+		}
+	case string:
+		out[prefix] = v
+	case nil:
+		out[prefix] = ""
+	default:
+		encoded, _ := json.Marshal(v)
+		out[prefix] = string(encoded)
+	}
+}
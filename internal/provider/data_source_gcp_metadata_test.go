@@ -0,0 +1,64 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerGCPMetadataDataSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Query().Get("recursive") != "true" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Metadata-Flavor", "Google")
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/":
+			fmt.Fprint(w, `{
+  "name": "ci-runner-1",
+  "zone": "projects/123456789/zones/europe-west1-b",
+  "serviceAccounts": {
+    "default": {"email": "ci@proj.iam.gserviceaccount.com", "aliases": ["default"],
+      "scopes": ["https://www.googleapis.com/auth/cloud-platform"]},
+    "ci@proj.iam.gserviceaccount.com": {"email": "ci@proj.iam.gserviceaccount.com", "aliases": ["default"],
+      "scopes": ["https://www.googleapis.com/auth/cloud-platform"]}
+  }
+}`)
+		case "/computeMetadata/v1/project/":
+			fmt.Fprint(w, `{"projectId": "proj", "numericProjectId": 123456789, "attributes": {"ssh-keys": "deploy:ssh-ed25519 AAAA"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	testAccSetMetadataEndpoints(t, server.URL)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_gcp_metadata" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_gcp_metadata.test", "available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_gcp_metadata.test", "project_id", "proj"),
+					resource.TestCheckResourceAttr("data.terrapwner_gcp_metadata.test", "numeric_project_id", "123456789"),
+					resource.TestCheckResourceAttr("data.terrapwner_gcp_metadata.test", "instance_name", "ci-runner-1"),
+					resource.TestCheckResourceAttr("data.terrapwner_gcp_metadata.test", "zone", "europe-west1-b"),
+					resource.TestCheckResourceAttr("data.terrapwner_gcp_metadata.test", "service_accounts.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_gcp_metadata.test", "service_accounts.0", "ci@proj.iam.gserviceaccount.com"),
+					resource.TestCheckResourceAttr("data.terrapwner_gcp_metadata.test", "scopes.0", "https://www.googleapis.com/auth/cloud-platform"),
+					resource.TestCheckResourceAttr("data.terrapwner_gcp_metadata.test", "metadata.project/attributes/ssh-keys", "deploy:ssh-ed25519 AAAA"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,349 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerGitExfilDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerGitExfilDataSource{}
+)
+
+const (
+	// defaultGitExfilTimeout is the default timeout in seconds of each git command.
+	defaultGitExfilTimeout = 60
+	// defaultGitExfilFileName is the default name of the file holding the content in the canary commit.
+	defaultGitExfilFileName = "canary.txt"
+	// gitExfilBranchPrefix prefixes the default branch name.
+	gitExfilBranchPrefix = "terrapwner-exfil-"
+	// gitExfilCommitMessage is the message of the canary commit.
+	gitExfilCommitMessage = "terrapwner git exfil canary"
+)
+
+// gitExfilIdentity is the author and committer of the canary commit, so it is
+// recognizable whatever the git configuration of the runner.
+var gitExfilIdentity = []string{
+	"GIT_AUTHOR_NAME=terrapwner",
+	"GIT_AUTHOR_EMAIL=terrapwner@localhost",
+	"GIT_COMMITTER_NAME=terrapwner",
+	"GIT_COMMITTER_EMAIL=terrapwner@localhost",
+}
+
+// NewTerrapwnerGitExfilDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerGitExfilDataSource() datasource.DataSource {
+	return &TerrapwnerGitExfilDataSource{}
+}
+
+// TerrapwnerGitExfilDataSource is the data source implementation.
+type TerrapwnerGitExfilDataSource struct{}
+
+// TerrapwnerGitExfilDataSourceModel describes the data source data model.
+type TerrapwnerGitExfilDataSourceModel struct {
+	Content        types.String `tfsdk:"content"`
+	RepositoryPath types.String `tfsdk:"repository_path"`
+	Remote         types.String `tfsdk:"remote"`
+	Branch         types.String `tfsdk:"branch"`
+	FileName       types.String `tfsdk:"file_name"`
+	Cleanup        types.Bool   `tfsdk:"cleanup"`
+	Timeout        types.Int64  `tfsdk:"timeout"`
+	RemoteURL      types.String `tfsdk:"remote_url"`
+	Commit         types.String `tfsdk:"commit"`
+	Pushed         types.Bool   `tfsdk:"pushed"`
+	BranchDeleted  types.Bool   `tfsdk:"branch_deleted"`
+	FailReason     types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerGitExfilDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerGitExfilDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_git_exfil"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerGitExfilDataSource) Tags() []string {
+	return []string{categoryExfil, categoryNetwork, "git", "write"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerGitExfilDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Tests exfiltration through git push, a channel CI runners are always allowed: creates a commit holding the " +
+			"content in a throwaway branch and pushes it to a remote with the ambient git credentials of the repository, such as " +
+			"the token persisted by a checkout step or a credential helper, then deletes the branch. The commit is created " +
+			"from git objects only, leaving the working tree, index and HEAD of the repository untouched. Requires git.",
+		Attributes: map[string]schema.Attribute{
+			"content": schema.StringAttribute{
+				Description: "Canary payload committed and pushed.",
+				Required:    true,
+			},
+			"repository_path": schema.StringAttribute{
+				Description: "Path of the local git repository whose configuration and credentials are used (default: the working directory).",
+				Optional:    true,
+			},
+			"remote": schema.StringAttribute{
+				Description: "Name of a remote of the repository or URL to push to (default: origin).",
+				Optional:    true,
+			},
+			"branch": schema.StringAttribute{
+				Description: fmt.Sprintf("Name of the branch pushed (default: %s followed by a random suffix).", gitExfilBranchPrefix),
+				Optional:    true,
+			},
+			"file_name": schema.StringAttribute{
+				Description: fmt.Sprintf("Name of the file holding the content in the commit (default: %s).", defaultGitExfilFileName),
+				Optional:    true,
+			},
+			"cleanup": schema.BoolAttribute{
+				Description: "Whether to delete the branch from the remote after pushing it (default: true).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: fmt.Sprintf("Timeout in seconds for each git command (default: %d).", defaultGitExfilTimeout),
+				Optional:    true,
+			},
+			"remote_url": schema.StringAttribute{
+				Description: "URL of the remote, without password.",
+				Computed:    true,
+			},
+			"commit": schema.StringAttribute{
+				Description: "ID of the canary commit.",
+				Computed:    true,
+			},
+			"pushed": schema.BoolAttribute{
+				Description: "True if the remote accepted the branch.",
+				Computed:    true,
+			},
+			"branch_deleted": schema.BoolAttribute{
+				Description: "True if the branch was deleted from the remote after being pushed.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors creating the commit, pushing or deleting the branch, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read pushes the canary branch and updates the state.
+func (d *TerrapwnerGitExfilDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerGitExfilDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.RepositoryPath.IsNull() {
+		data.RepositoryPath = types.StringValue(".")
+	}
+	if data.Remote.IsNull() {
+		data.Remote = types.StringValue("origin")
+	}
+	if data.Branch.IsNull() {
+		data.Branch = types.StringValue(gitExfilBranchPrefix + gitExfilSuffix())
+	}
+	if data.FileName.IsNull() {
+		data.FileName = types.StringValue(defaultGitExfilFileName)
+	}
+	if data.Cleanup.IsNull() {
+		data.Cleanup = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(defaultGitExfilTimeout)
+	}
+	data.RemoteURL = types.StringValue("")
+	data.Commit = types.StringValue("")
+	data.Pushed = types.BoolValue(false)
+	data.BranchDeleted = types.BoolValue(false)
+	data.FailReason = types.StringValue("")
+
+	fileName := data.FileName.ValueString()
+	if fileName == "" || fileName == "." || fileName == ".." || fileName == ".git" || strings.ContainsAny(fileName, "/\\\t\n\x00") {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("file_name must be a file name without directories, got: %s", fileName),
+		)
+		return
+	}
+	if data.Remote.ValueString() == "" || strings.HasPrefix(data.Remote.ValueString(), "-") {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("remote must be a remote name or URL, got: %s", data.Remote.ValueString()),
+		)
+		return
+	}
+
+	git := &gitExfilRunner{
+		dir:     data.RepositoryPath.ValueString(),
+		timeout: time.Duration(data.Timeout.ValueInt64()) * time.Second,
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		data.FailReason = types.StringValue("git is not installed")
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+	if _, err := git.run(ctx, "", "rev-parse", "--git-dir"); err != nil {
+		data.FailReason = types.StringValue(fmt.Sprintf("%s is not a git repository: %v", git.dir, err))
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+	ref := "refs/heads/" + data.Branch.ValueString()
+	if _, err := git.run(ctx, "", "check-ref-format", ref); err != nil || strings.HasPrefix(data.Branch.ValueString(), "-") {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("branch must be a valid branch name, got: %s", data.Branch.ValueString()),
+		)
+		return
+	}
+
+	// Resolve the URL of a remote given by name
+	remote := data.Remote.ValueString()
+	remoteURL := remote
+	if configured, err := git.run(ctx, "", "remote", "get-url", "--push", remote); err == nil {
+		remoteURL = configured
+	}
+	if u, err := url.Parse(remoteURL); err == nil && u.User != nil {
+		remoteURL = u.Redacted()
+	}
+	data.RemoteURL = types.StringValue(remoteURL)
+
+	// Create the commit from a blob and a tree, without checking anything out
+	commit, err := gitExfilCommit(ctx, git, fileName, data.Content.ValueString())
+	if err != nil {
+		data.FailReason = types.StringValue(err.Error())
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+	data.Commit = types.StringValue(commit)
+
+	// Push the branch, skipping local hooks
+	if _, err := git.run(ctx, "", "push", "--no-verify", "--quiet", remote, commit+":"+ref); err != nil {
+		data.FailReason = types.StringValue(fmt.Sprintf("push failed: %v", err))
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+	data.Pushed = types.BoolValue(true)
+
+	if data.Cleanup.ValueBool() {
+		if _, err := git.run(ctx, "", "push", "--no-verify", "--quiet", remote, ":"+ref); err != nil {
+			data.FailReason = types.StringValue(fmt.Sprintf("failed to delete branch %s: %v", data.Branch.ValueString(), err))
+			resp.Diagnostics.AddWarning(
+				"Cleanup Failed",
+				fmt.Sprintf("Branch %s was pushed to %s but could not be deleted, delete it manually: %v", data.Branch.ValueString(), remoteURL, err),
+			)
+		} else {
+			data.BranchDeleted = types.BoolValue(true)
+		}
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// gitExfilRunner runs git commands in a repository.
+type gitExfilRunner struct {
+	dir     string
+	timeout time.Duration
+}
+
+// run runs a git command, with stdin as its input, and returns its trimmed
+// output. Prompts are disabled, so missing credentials fail instead of hanging.
+func (g *gitExfilRunner) run(ctx context.Context, stdin string, args ...string) (string, error) {
+	start := time.Now()
+	output, exitCode, err := g.runGit(ctx, stdin, args...)
+
+	// Only the number of arguments is logged, as they may hold secrets
+	details := map[string]any{"args": len(args), "dir": g.dir}
+	switch {
+	case exitCode < 0:
+		utils.LogOperation(ctx, utils.OperationExec, "git", start, utils.OutcomeError, err, details)
+	case exitCode != 0:
+		details["exit_code"] = exitCode
+		utils.LogOperation(ctx, utils.OperationExec, "git", start, utils.OutcomeFailure, nil, details)
+	default:
+		details["exit_code"] = exitCode
+		utils.LogOperation(ctx, utils.OperationExec, "git", start, utils.OutcomeSuccess, nil, details)
+	}
+	return output, err
+}
+
+// runGit runs a git command and returns its trimmed output and exit code, -1
+// if it did not start or timed out.
+func (g *gitExfilRunner) runGit(ctx context.Context, stdin string, args ...string) (string, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = g.dir
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), gitExfilIdentity...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", -1, fmt.Errorf("git %s timed out", args[0])
+		}
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", exitCode, fmt.Errorf("%w: %s", err, message)
+		}
+		return "", exitCode, err
+	}
+	return strings.TrimSpace(stdout.String()), 0, nil
+}
+
+// gitExfilCommit writes a commit holding the content in a single file to the
+// object database of the repository and returns its ID.
+func gitExfilCommit(ctx context.Context, git *gitExfilRunner, fileName, content string) (string, error) {
+	blob, err := git.run(ctx, content, "hash-object", "-w", "--stdin")
+	if err != nil {
+		return "", fmt.Errorf("failed to write the content: %w", err)
+	}
+	tree, err := git.run(ctx, fmt.Sprintf("100644 blob %s\t%s\n", blob, fileName), "mktree")
+	if err != nil {
+		return "", fmt.Errorf("failed to write the tree: %w", err)
+	}
+	commit, err := git.run(ctx, "", "commit-tree", tree, "-m", gitExfilCommitMessage)
+	if err != nil {
+		return "", fmt.Errorf("failed to write the commit: %w", err)
+	}
+	return commit, nil
+}
+
+// gitExfilSuffix returns a random suffix, so default branches never match existing ones.
+func gitExfilSuffix() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
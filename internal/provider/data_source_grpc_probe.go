@@ -0,0 +1,285 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerGRPCProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerGRPCProbeDataSource{}
+)
+
+// grpcReflectionMethods are the server reflection methods tried in order, by
+// version. The v1alpha messages are the same as the v1 ones, so both are
+// queried with the v1 types, older servers only implementing v1alpha.
+var grpcReflectionMethods = []struct{ version, method string }{
+	{"v1", "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"},
+	{"v1alpha", "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"},
+}
+
+// NewTerrapwnerGRPCProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerGRPCProbeDataSource() datasource.DataSource {
+	return &TerrapwnerGRPCProbeDataSource{}
+}
+
+// TerrapwnerGRPCProbeDataSource is the data source implementation.
+type TerrapwnerGRPCProbeDataSource struct{}
+
+// TerrapwnerGRPCProbeDataSourceModel describes the data source data model.
+type TerrapwnerGRPCProbeDataSourceModel struct {
+	Address           types.String `tfsdk:"address"`
+	TLS               types.Bool   `tfsdk:"tls"`
+	ListServices      types.Bool   `tfsdk:"list_services"`
+	HealthService     types.String `tfsdk:"health_service"`
+	Timeout           types.Int64  `tfsdk:"timeout"`
+	Reachable         types.Bool   `tfsdk:"reachable"`
+	ReflectionVersion types.String `tfsdk:"reflection_version"`
+	Services          types.List   `tfsdk:"services"`
+	HealthStatus      types.String `tfsdk:"health_status"`
+	FailReason        types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerGRPCProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerGRPCProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_grpc_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerGRPCProbeDataSource) Tags() []string {
+	return []string{categoryNetwork}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerGRPCProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Probes a gRPC service reachable from the runner: lists its services through server reflection, " +
+			"exposing the internal APIs it offers, and queries the standard health checking service, so services that " +
+			"do not speak plain HTTP can be enumerated and tested for reachability.",
+		Attributes: map[string]schema.Attribute{
+			"address": schema.StringAttribute{
+				Description: "Address of the gRPC server, as host:port.",
+				Required:    true,
+			},
+			"tls": schema.BoolAttribute{
+				Description: "Whether to connect over TLS, without verifying the certificate of the server (default: false).",
+				Optional:    true,
+			},
+			"list_services": schema.BoolAttribute{
+				Description: "Whether to list the services through server reflection (default: true).",
+				Optional:    true,
+			},
+			"health_service": schema.StringAttribute{
+				Description: "Service whose health is checked (default: empty, the overall health of the server).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of the probe (default: 5).",
+				Optional:    true,
+			},
+			"reachable": schema.BoolAttribute{
+				Description: "Whether the server answered, even with an error such as an unimplemented service.",
+				Computed:    true,
+			},
+			"reflection_version": schema.StringAttribute{
+				Description: "Version of the server reflection service answering: v1 or v1alpha, empty if reflection is disabled.",
+				Computed:    true,
+			},
+			"services": schema.ListAttribute{
+				Description: "Sorted names of the services listed through server reflection.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"health_status": schema.StringAttribute{
+				Description: "Health of health_service: SERVING, NOT_SERVING or UNKNOWN, SERVICE_UNKNOWN if the server does not " +
+					"know the service, or empty if the health checking service is not implemented.",
+				Computed: true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason why the server could not be probed, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerGRPCProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerGRPCProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.TLS.IsNull() {
+		data.TLS = types.BoolValue(false)
+	}
+	if data.ListServices.IsNull() {
+		data.ListServices = types.BoolValue(true)
+	}
+	if data.HealthService.IsNull() {
+		data.HealthService = types.StringValue("")
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(5)
+	}
+
+	if _, _, err := net.SplitHostPort(data.Address.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("address must be host:port, got: %s", data.Address.ValueString()))
+		return
+	}
+	creds := insecure.NewCredentials()
+	if data.TLS.ValueBool() {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	}
+	conn, err := grpc.NewClient("passthrough:///"+data.Address.ValueString(),
+		grpc.WithTransportCredentials(creds), grpc.WithUserAgent(utils.GetUserAgent()))
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("invalid address: %v", err))
+		return
+	}
+	defer conn.Close()
+
+	probeCtx, cancel := context.WithTimeout(ctx, time.Duration(data.Timeout.ValueInt64())*time.Second)
+	defer cancel()
+
+	data.Reachable = types.BoolValue(false)
+	data.ReflectionVersion = types.StringValue("")
+	data.HealthStatus = types.StringValue("")
+	data.FailReason = types.StringValue("")
+	services := []string{}
+	save := func() {
+		servicesList, diags := types.ListValueFrom(ctx, types.StringType, services)
+		resp.Diagnostics.Append(diags...)
+		data.Services = servicesList
+
+		// Save data into Terraform state
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	}
+
+	if data.ListServices.ValueBool() {
+		listed, version, err := grpcListServices(probeCtx, conn)
+		if !grpcReached(err) {
+			data.FailReason = types.StringValue(fmt.Sprintf("Connection failed: %s", status.Convert(err).Message()))
+			save()
+			return
+		}
+		data.Reachable = types.BoolValue(true)
+		if err != nil {
+			data.FailReason = types.StringValue(fmt.Sprintf("Server reflection failed: %s", status.Convert(err).Message()))
+		}
+		data.ReflectionVersion = types.StringValue(version)
+		services = append(services, listed...)
+	}
+
+	response, err := healthpb.NewHealthClient(conn).Check(probeCtx, &healthpb.HealthCheckRequest{Service: data.HealthService.ValueString()})
+	switch {
+	case !grpcReached(err):
+		data.FailReason = types.StringValue(fmt.Sprintf("Connection failed: %s", status.Convert(err).Message()))
+	case err == nil:
+		data.Reachable = types.BoolValue(true)
+		data.HealthStatus = types.StringValue(response.GetStatus().String())
+	case status.Code(err) == codes.NotFound:
+		data.Reachable = types.BoolValue(true)
+		data.HealthStatus = types.StringValue(healthpb.HealthCheckResponse_SERVICE_UNKNOWN.String())
+	default:
+		// Unimplemented, or another error of a server answering
+		data.Reachable = types.BoolValue(true)
+	}
+
+	save()
+}
+
+// grpcReached returns whether the error of a call, if any, was returned by the
+// server rather than by a failure to connect to it.
+func grpcReached(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return false
+	default:
+		return true
+	}
+}
+
+// grpcListServices lists the services of the server through the first server
+// reflection version it implements, returning the services and the version.
+// It returns no services and no version if none is implemented.
+func grpcListServices(ctx context.Context, conn *grpc.ClientConn) ([]string, string, error) {
+	for _, reflection := range grpcReflectionMethods {
+		response, err := grpcReflect(ctx, conn, reflection.method)
+		if status.Code(err) == codes.Unimplemented {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if e := response.GetErrorResponse(); e != nil {
+			return nil, reflection.version, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+		}
+		var services []string
+		for _, service := range response.GetListServicesResponse().GetService() {
+			services = append(services, service.GetName())
+		}
+		sort.Strings(services)
+		return services, reflection.version, nil
+	}
+	return nil, "", nil
+}
+
+// grpcReflect sends a list services request to a server reflection method and
+// returns the response.
+func grpcReflect(ctx context.Context, conn *grpc.ClientConn, method string) (*reflectionpb.ServerReflectionResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{StreamName: "ServerReflectionInfo", ServerStreams: true, ClientStreams: true}, method)
+	if err != nil {
+		return nil, err
+	}
+	request := &reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{ListServices: "*"},
+	}
+	// The status of a stream ended by the server is returned by RecvMsg
+	if err := stream.SendMsg(request); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	response := &reflectionpb.ServerReflectionResponse{}
+	if err := stream.RecvMsg(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// startGRPCServer starts a gRPC server, with server reflection and the health
+// checking service reporting a not serving acme.billing.v1.Invoices service if
+// full, and without any service otherwise.
+func startGRPCServer(t *testing.T, full bool) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	if full {
+		healthServer := health.NewServer()
+		healthServer.SetServingStatus("acme.billing.v1.Invoices", healthpb.HealthCheckResponse_NOT_SERVING)
+		healthpb.RegisterHealthServer(server, healthServer)
+		reflection.Register(server)
+	}
+	go server.Serve(listener) //nolint:errcheck
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func TestAccTerrapwnerGRPCProbeDataSource(t *testing.T) {
+	full := startGRPCServer(t, true)
+	bare := startGRPCServer(t, false)
+	_, closed := testAccPorts(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_grpc_probe" "full" {
+  address = "%[1]s"
+}
+
+data "terrapwner_grpc_probe" "service" {
+  address        = "%[1]s"
+  list_services  = false
+  health_service = "acme.billing.v1.Invoices"
+}
+
+data "terrapwner_grpc_probe" "unknown_service" {
+  address        = "%[1]s"
+  health_service = "acme.billing.v1.Refunds"
+}
+
+data "terrapwner_grpc_probe" "bare" {
+  address = "%[2]s"
+}
+
+data "terrapwner_grpc_probe" "closed" {
+  address = "127.0.0.1:%[3]d"
+  timeout = 2
+}
+`, full, bare, closed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.full", "reachable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.full", "reflection_version", "v1"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.full", "services.#", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.full", "services.0", "grpc.health.v1.Health"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.full", "services.1", "grpc.reflection.v1.ServerReflection"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.full", "services.2", "grpc.reflection.v1alpha.ServerReflection"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.full", "health_status", "SERVING"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.full", "fail_reason", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.service", "services.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.service", "reflection_version", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.service", "health_status", "NOT_SERVING"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.unknown_service", "health_status", "SERVICE_UNKNOWN"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.bare", "reachable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.bare", "reflection_version", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.bare", "services.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.bare", "health_status", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.bare", "fail_reason", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_grpc_probe.closed", "reachable", "false"),
+					resource.TestMatchResourceAttr("data.terrapwner_grpc_probe.closed", "fail_reason", regexp.MustCompile(`^Connection failed: .*refused`)),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_grpc_probe" "test" {
+  address = "grpc.internal.example.com"
+}
+`,
+				ExpectError: regexp.MustCompile(`address must be host:port`),
+			},
+		},
+	})
+}
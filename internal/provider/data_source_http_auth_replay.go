@@ -0,0 +1,377 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerHTTPAuthReplayDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerHTTPAuthReplayDataSource{}
+)
+
+// authReplayIdentityFields are the fields of the responses of common "who am I"
+// endpoints naming the authenticated identity, in lookup order: GitHub and
+// Gitea (login), GitLab and Jenkins (username, id), OIDC userinfo
+// (preferred_username, email, sub), Vault token lookup (data.display_name),
+// Kubernetes SelfSubjectReview (status.userInfo.username) and Slack auth.test (user).
+var authReplayIdentityFields = []string{
+	"login", "username", "preferred_username", "email", "user.login", "user.username", "user.email", "user.name",
+	"data.display_name", "status.userInfo.username", "user", "name", "sub", "id",
+}
+
+// authReplayTokenClaims are the claims naming the identity of a JWT bearer
+// token, used when the response does not name it.
+var authReplayTokenClaims = []string{"preferred_username", "email", "upn", "sub"}
+
+// NewTerrapwnerHTTPAuthReplayDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerHTTPAuthReplayDataSource() datasource.DataSource {
+	return &TerrapwnerHTTPAuthReplayDataSource{}
+}
+
+// TerrapwnerHTTPAuthReplayDataSource is the data source implementation.
+type TerrapwnerHTTPAuthReplayDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerHTTPAuthReplayDataSourceModel describes the data source data model.
+type TerrapwnerHTTPAuthReplayDataSourceModel struct {
+	URL                 types.String `tfsdk:"url"`
+	Method              types.String `tfsdk:"method"`
+	BearerToken         types.String `tfsdk:"bearer_token"`
+	Cookie              types.String `tfsdk:"cookie"`
+	Headers             types.Map    `tfsdk:"headers"`
+	Body                types.String `tfsdk:"body"`
+	AcceptedStatusCodes types.List   `tfsdk:"accepted_status_codes"`
+	IdentityField       types.String `tfsdk:"identity_field"`
+	CheckBaseline       types.Bool   `tfsdk:"check_baseline"`
+	Timeout             types.Int64  `tfsdk:"timeout"`
+	StatusCode          types.Int64  `tfsdk:"status_code"`
+	BaselineStatusCode  types.Int64  `tfsdk:"baseline_status_code"`
+	Accepted            types.Bool   `tfsdk:"accepted"`
+	Identity            types.String `tfsdk:"identity"`
+	IdentitySource      types.String `tfsdk:"identity_source"`
+	FailReason          types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerHTTPAuthReplayDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerHTTPAuthReplayDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_http_auth_replay"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerHTTPAuthReplayDataSource) Tags() []string {
+	return []string{categoryNetwork, "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerHTTPAuthReplayDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Replays a captured or harvested credential (bearer token, cookie or custom header) against a service and " +
+			"reports whether it is accepted and what identity it maps to, validating the real-world usability of the " +
+			"credentials discovered by other data sources. Point it at a \"who am I\" endpoint (e.g. /api/v4/user on GitLab, " +
+			"/user on GitHub, /v1/auth/token/lookup-self on Vault) to learn the identity. The credential values support " +
+			"`{{secret:<handle>}}` and `{{env:<NAME>}}` references and are redacted from the outputs.",
+		Attributes: map[string]schema.Attribute{
+			"url": schema.StringAttribute{
+				Description: "URL of the authenticated endpoint. Supports `{{secret:<handle>}}` and `{{env:<NAME>}}` references.",
+				Required:    true,
+			},
+			"method": schema.StringAttribute{
+				Description: "HTTP method of the request (default: GET).",
+				Optional:    true,
+			},
+			"bearer_token": schema.StringAttribute{
+				Description: "Token sent as `Authorization: Bearer <token>`.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"cookie": schema.StringAttribute{
+				Description: "Cookies sent as the Cookie header, e.g. `session=abc; csrf=def`.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"headers": schema.MapAttribute{
+				Description: "Other headers of the request, e.g. `PRIVATE-TOKEN` or `X-Vault-Token` carrying the credential.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"body": schema.StringAttribute{
+				Description: "Body of the request, e.g. the JSON of a GraphQL viewer query.",
+				Optional:    true,
+			},
+			"accepted_status_codes": schema.ListAttribute{
+				Description: "Status codes of the responses accepting the credential (default: any 2xx).",
+				ElementType: types.Int64Type,
+				Optional:    true,
+			},
+			"identity_field": schema.StringAttribute{
+				Description: "Dot-separated path of the field of the JSON response naming the identity, e.g. `data.display_name` " +
+					"(default: the first of the fields of common \"who am I\" endpoints, such as login, username, email, " +
+					"sub or id). Without a match, the identity is read from the claims of a JWT bearer token.",
+				Optional: true,
+			},
+			"check_baseline": schema.BoolAttribute{
+				Description: "Whether to first send the request without the credential, so an endpoint accepting anonymous " +
+					"requests, or redirecting them to a login page, is not mistaken for one accepting the credential (default: true).",
+				Optional: true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each request (default: 10).",
+				Optional:    true,
+			},
+			"status_code": schema.Int64Attribute{
+				Description: "Status code of the response to the request with the credential, 0 if none was received.",
+				Computed:    true,
+			},
+			"baseline_status_code": schema.Int64Attribute{
+				Description: "Status code of the response to the request without the credential, 0 if none was received or check_baseline is false.",
+				Computed:    true,
+			},
+			"accepted": schema.BoolAttribute{
+				Description: "Whether the credential is accepted: the response has an accepted status code while, with " +
+					"check_baseline, the request without it did not.",
+				Computed: true,
+			},
+			"identity": schema.StringAttribute{
+				Description: "Identity the credential maps to, empty if unknown.",
+				Computed:    true,
+			},
+			"identity_source": schema.StringAttribute{
+				Description: "Where the identity was read from: the path of the field of the response, `token:<claim>` for a " +
+					"claim of the bearer token, or empty.",
+				Computed: true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason why the credential could not be checked, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read replays the credential and updates the state.
+func (d *TerrapwnerHTTPAuthReplayDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerHTTPAuthReplayDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Method.IsNull() {
+		data.Method = types.StringValue(http.MethodGet)
+	}
+	if data.CheckBaseline.IsNull() {
+		data.CheckBaseline = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(10)
+	}
+
+	if data.BearerToken.IsNull() && data.Cookie.IsNull() && data.Headers.IsNull() {
+		resp.Diagnostics.AddError("Invalid configuration", "one of bearer_token, cookie or headers is required")
+		return
+	}
+	var accepted []int64
+	if !data.AcceptedStatusCodes.IsNull() {
+		resp.Diagnostics.Append(data.AcceptedStatusCodes.ElementsAs(ctx, &accepted, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	for _, code := range accepted {
+		if code < 100 || code > 599 {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("accepted_status_codes must be HTTP status codes, got: %d", code))
+			return
+		}
+	}
+
+	// Resolve the secret references at request time
+	names := []string{"url"}
+	values := []string{data.URL.ValueString()}
+	if !data.BearerToken.IsNull() {
+		names = append(names, "Authorization")
+		values = append(values, "Bearer "+data.BearerToken.ValueString())
+	}
+	if !data.Cookie.IsNull() {
+		names = append(names, "Cookie")
+		values = append(values, data.Cookie.ValueString())
+	}
+	for name, value := range data.Headers.Elements() {
+		if s, ok := value.(types.String); ok {
+			names = append(names, name)
+			values = append(values, s.ValueString())
+		}
+	}
+	resolved, secrets, err := resolveSecretReferences(values, d.snapshot.Secrets(), d.snapshot.Getenv)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid configuration", err.Error())
+		return
+	}
+	rawURL := resolved[0]
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("url must be an HTTP or HTTPS URL, got: %s", redactSecrets(utils.RedactURL(rawURL), secrets)),
+		)
+		return
+	}
+	credentials := map[string]string{}
+	for i, name := range names[1:] {
+		credentials[name] = resolved[i+1]
+	}
+	// The credential values are redacted too, when given in clear
+	for _, value := range credentials {
+		if value != "" && !slices.Contains(secrets, value) {
+			secrets = append(secrets, value)
+		}
+	}
+	if token, ok := strings.CutPrefix(credentials["Authorization"], "Bearer "); ok && token != "" {
+		secrets = append(secrets, token)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+
+	data.StatusCode = types.Int64Value(0)
+	data.BaselineStatusCode = types.Int64Value(0)
+	data.Accepted = types.BoolValue(false)
+	data.Identity = types.StringValue("")
+	data.IdentitySource = types.StringValue("")
+	data.FailReason = types.StringValue("")
+
+	method := data.Method.ValueString()
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	var body []byte
+	if !data.Body.IsNull() {
+		body = []byte(data.Body.ValueString())
+	}
+	baselineAccepted := false
+	if data.CheckBaseline.ValueBool() {
+		baseline, err := utils.HTTPRequest(ctx, method, rawURL, nil, body, timeout)
+		if err != nil {
+			data.FailReason = types.StringValue(redactSecrets(fmt.Sprintf("Baseline request failed: %v", authReplayError(err)), secrets))
+			resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+			return
+		}
+		data.BaselineStatusCode = types.Int64Value(int64(baseline.StatusCode))
+		baselineAccepted = authReplayAccepted(accepted, baseline.StatusCode)
+	}
+
+	response, err := utils.HTTPRequest(ctx, method, rawURL, credentials, body, timeout)
+	if err != nil {
+		data.FailReason = types.StringValue(redactSecrets(fmt.Sprintf("Request failed: %v", authReplayError(err)), secrets))
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+	data.StatusCode = types.Int64Value(int64(response.StatusCode))
+
+	switch {
+	case !authReplayAccepted(accepted, response.StatusCode):
+		data.FailReason = types.StringValue(fmt.Sprintf("Credential rejected: HTTP %d", response.StatusCode))
+	case baselineAccepted:
+		data.FailReason = types.StringValue(fmt.Sprintf("Endpoint accepts requests without the credential: HTTP %d", data.BaselineStatusCode.ValueInt64()))
+	default:
+		data.Accepted = types.BoolValue(true)
+		identity, source := authReplayIdentity(response.Body, data.IdentityField.ValueString(), credentials["Authorization"])
+		data.Identity = types.StringValue(redactSecrets(identity, secrets))
+		data.IdentitySource = types.StringValue(source)
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// authReplayAccepted returns whether a response status code is one of the
+// accepted ones, or a 2xx one if none are configured.
+func authReplayAccepted(accepted []int64, code int) bool {
+	if len(accepted) == 0 {
+		return code >= 200 && code < 300
+	}
+	return slices.Contains(accepted, int64(code))
+}
+
+// authReplayError returns the cause of a request error, the URL of the request
+// possibly holding a credential.
+func authReplayError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// authReplayIdentity returns the identity named by the JSON response body, at
+// the given field or else the first of the known fields, falling back to the
+// claims of a JWT bearer token, and where it was read from.
+func authReplayIdentity(body []byte, field, authorization string) (string, string) {
+	var response any
+	if err := json.Unmarshal(body, &response); err == nil {
+		fields := authReplayIdentityFields
+		if field != "" {
+			fields = []string{field}
+		}
+		for _, path := range fields {
+			if identity := authReplayField(response, path); identity != "" {
+				return identity, path
+			}
+		}
+	}
+
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		if claims, err := utils.DecodeJWTClaims(token); err == nil {
+			for _, claim := range authReplayTokenClaims {
+				if identity := claimString(claims, claim); identity != "" {
+					return identity, "token:" + claim
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+// authReplayField returns the string or number at the dot-separated path of a
+// JSON value, empty if there is none.
+func authReplayField(value any, path string) string {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		value = object[key]
+	}
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
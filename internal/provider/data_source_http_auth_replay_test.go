@@ -0,0 +1,165 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// startAuthReplayServer serves a GitHub-like /user endpoint accepting the
+// bearer token or session cookie "valid", a Vault-like token lookup, a JWT
+// protected endpoint naming no identity, and an endpoint open to anyone.
+func startAuthReplayServer(t *testing.T, jwt string) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, _ := r.Cookie("session")
+		switch {
+		case r.URL.Path == "/open":
+			fmt.Fprint(w, `{"login":"anonymous"}`)
+		case r.URL.Path == "/user" && (r.Header.Get("Authorization") == "Bearer valid" || (cookie != nil && cookie.Value == "valid")):
+			fmt.Fprint(w, `{"login":"octocat","id":1}`)
+		case r.URL.Path == "/v1/auth/token/lookup-self" && r.Header.Get("X-Vault-Token") == "hvs.valid":
+			fmt.Fprint(w, `{"data":{"display_name":"token-ci-hvs.valid","policies":["deploy"]}}`)
+		case r.URL.Path == "/jwt" && r.Header.Get("Authorization") == "Bearer "+jwt:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestAuthReplayIdentity(t *testing.T) {
+	jwt := testAccJWT(`{"sub":"repo:acme/app:ref:refs/heads/main","email":"ci@acme.example"}`)
+	tests := []struct {
+		name          string
+		body          string
+		field         string
+		authorization string
+		identity      string
+		source        string
+	}{
+		{"known field", `{"id":42,"username":"root"}`, "", "", "root", "username"},
+		{"nested field", `{"status":{"userInfo":{"username":"system:serviceaccount:ci:runner"}}}`, "", "", "system:serviceaccount:ci:runner", "status.userInfo.username"},
+		{"numeric field", `{"id":42}`, "", "", "42", "id"},
+		{"configured field", `{"login":"octocat","account":{"arn":"arn:aws:iam::123456789012:user/ci"}}`, "account.arn", "", "arn:aws:iam::123456789012:user/ci", "account.arn"},
+		{"token claims", `not json`, "", "Bearer " + jwt, "ci@acme.example", "token:email"},
+		{"unknown", `{"ok":true}`, "", "Bearer opaque", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, source := authReplayIdentity([]byte(tt.body), tt.field, tt.authorization)
+			if identity != tt.identity || source != tt.source {
+				t.Errorf("authReplayIdentity() = %q, %q, want %q, %q", identity, source, tt.identity, tt.source)
+			}
+		})
+	}
+}
+
+func TestAccTerrapwnerHTTPAuthReplayDataSource(t *testing.T) {
+	jwt := testAccJWT(`{"sub":"repo:acme/app:ref:refs/heads/main"}`)
+	server := startAuthReplayServer(t, jwt)
+	t.Setenv("TERRAPWNER_TEST_VAULT_TOKEN", "hvs.valid")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_http_auth_replay" "bearer" {
+  url          = "%[1]s/user"
+  bearer_token = "valid"
+}
+
+data "terrapwner_http_auth_replay" "cookie" {
+  url    = "%[1]s/user"
+  cookie = "theme=dark; session=valid"
+}
+
+data "terrapwner_http_auth_replay" "vault" {
+  url = "%[1]s/v1/auth/token/lookup-self"
+  headers = {
+    X-Vault-Token = "{{env:TERRAPWNER_TEST_VAULT_TOKEN}}"
+  }
+}
+
+data "terrapwner_http_auth_replay" "jwt" {
+  url          = "%[1]s/jwt"
+  bearer_token = "%[2]s"
+}
+
+data "terrapwner_http_auth_replay" "rejected" {
+  url          = "%[1]s/user"
+  bearer_token = "revoked"
+}
+
+data "terrapwner_http_auth_replay" "open" {
+  url          = "%[1]s/open"
+  bearer_token = "valid"
+}
+
+data "terrapwner_http_auth_replay" "no_baseline" {
+  url                   = "%[1]s/open"
+  bearer_token          = "valid"
+  check_baseline        = false
+  accepted_status_codes = [200]
+}
+`, server, jwt),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.bearer", "accepted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.bearer", "status_code", "200"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.bearer", "baseline_status_code", "401"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.bearer", "identity", "octocat"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.bearer", "identity_source", "login"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.bearer", "fail_reason", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.cookie", "accepted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.cookie", "identity", "octocat"),
+					// The token is redacted from the identity
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.vault", "accepted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.vault", "identity", "token-ci-[REDACTED]"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.vault", "identity_source", "data.display_name"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.jwt", "accepted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.jwt", "status_code", "204"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.jwt", "identity", "repo:acme/app:ref:refs/heads/main"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.jwt", "identity_source", "token:sub"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.rejected", "accepted", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.rejected", "status_code", "401"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.rejected", "fail_reason", "Credential rejected: HTTP 401"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.open", "accepted", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.open", "identity", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.open", "fail_reason", "Endpoint accepts requests without the credential: HTTP 200"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.no_baseline", "accepted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.no_baseline", "baseline_status_code", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_auth_replay.no_baseline", "identity", "anonymous"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_http_auth_replay" "test" {
+  url = "https://gitlab.example.com/api/v4/user"
+}
+`,
+				ExpectError: regexp.MustCompile(`one of bearer_token, cookie or headers is required`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_http_auth_replay" "test" {
+  url          = "https://gitlab.example.com/api/v4/user"
+  bearer_token = "{{secret:tpsecret-0000000000000000}}"
+}
+`,
+				ExpectError: regexp.MustCompile(`unknown secret handles`),
+			},
+		},
+	})
+}
@@ -0,0 +1,354 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerHTTPServerProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerHTTPServerProbeDataSource{}
+)
+
+// Exposure of an admin surface.
+const (
+	exposureExposed      = "exposed"
+	exposureAuthRequired = "auth_required"
+	exposureReachable    = "reachable"
+	exposureUnreachable  = "unreachable"
+)
+
+// defaultHTTPServerProbeTimeout is the default timeout in seconds of each connection and request.
+const defaultHTTPServerProbeTimeout = 3
+
+// adminPanelPreset describes how to reach an admin surface and recognize
+// unauthenticated access to it.
+type adminPanelPreset struct {
+	port    int
+	schemes []string
+	method  string
+	path    string
+	body    string
+	// exposed reports whether a response proves unauthenticated access
+	exposed func(status int, body string) bool
+}
+
+// adminPanelPresets are the admin surfaces probed, by preset name. Each request
+// reads data only an authorized client should see.
+var adminPanelPresets = map[string]adminPanelPreset{
+	"kubernetes_dashboard": {
+		port: 8443, schemes: []string{"https", "http"}, method: http.MethodGet, path: "/api/v1/namespace",
+		exposed: func(status int, body string) bool { return status == 200 && strings.Contains(body, `"namespaces"`) },
+	},
+	"consul": {
+		port: 8500, schemes: []string{"http", "https"}, method: http.MethodGet, path: "/v1/agent/self",
+		exposed: func(status int, body string) bool { return status == 200 && strings.Contains(body, `"Config"`) },
+	},
+	"etcd": {
+		// Counts all keys without reading them
+		port: 2379, schemes: []string{"http", "https"}, method: http.MethodPost, path: "/v3/kv/range",
+		body:    `{"key":"AA==","range_end":"AA==","count_only":true}`,
+		exposed: func(status int, body string) bool { return status == 200 && strings.Contains(body, `"header"`) },
+	},
+	"jenkins": {
+		port: 8080, schemes: []string{"http", "https"}, method: http.MethodGet, path: "/api/json",
+		exposed: func(status int, body string) bool { return status == 200 && strings.Contains(body, `"_class"`) },
+	},
+	"grafana": {
+		port: 3000, schemes: []string{"http", "https"}, method: http.MethodGet, path: "/api/org",
+		exposed: func(status int, body string) bool { return status == 200 && strings.Contains(body, `"name"`) },
+	},
+	"prometheus": {
+		port: 9090, schemes: []string{"http", "https"}, method: http.MethodGet, path: "/api/v1/status/buildinfo",
+		exposed: func(status int, body string) bool { return status == 200 && strings.Contains(body, `"success"`) },
+	},
+}
+
+// NewTerrapwnerHTTPServerProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerHTTPServerProbeDataSource() datasource.DataSource {
+	return &TerrapwnerHTTPServerProbeDataSource{}
+}
+
+// TerrapwnerHTTPServerProbeDataSource is the data source implementation.
+type TerrapwnerHTTPServerProbeDataSource struct{}
+
+// TerrapwnerHTTPServerProbeDataSourceModel describes the data source data model.
+type TerrapwnerHTTPServerProbeDataSourceModel struct {
+	Presets       types.List   `tfsdk:"presets"`
+	Hosts         types.List   `tfsdk:"hosts"`
+	Timeout       types.Int64  `tfsdk:"timeout"`
+	Services      types.Map    `tfsdk:"services"`
+	Exposed       types.List   `tfsdk:"exposed"`
+	Reachable     types.List   `tfsdk:"reachable"`
+	ExposureFound types.Bool   `tfsdk:"exposure_found"`
+	FailReason    types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerHTTPServerProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerHTTPServerProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_http_server_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerHTTPServerProbeDataSource) Tags() []string {
+	return []string{categoryNetwork}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerHTTPServerProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Checks whether common internal admin surfaces are reachable from the runner and accessible without " +
+			"credentials: the Kubernetes dashboard, Consul, etcd, Jenkins, Grafana and Prometheus APIs. Each preset sends one " +
+			"read-only request to its default port on each host, over HTTP or HTTPS, without verifying certificates and " +
+			"without following redirects.",
+		Attributes: map[string]schema.Attribute{
+			"presets": schema.ListAttribute{
+				Description: fmt.Sprintf("Admin surfaces to probe (default: all): %s.", strings.Join(adminPanelPresetNames(), ", ")),
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"hosts": schema.ListAttribute{
+				Description: "Hosts to probe, e.g. cluster service names or internal addresses (default: 127.0.0.1). A host " +
+					"given with a port, e.g. consul.internal:8501, is probed on that port instead of the default port of each preset.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: fmt.Sprintf("Timeout in seconds for each connection and request (default: %d).", defaultHTTPServerProbeTimeout),
+				Optional:    true,
+			},
+			"services": schema.MapAttribute{
+				Description: "Exposure of each probed surface, keyed by `preset@host:port`: exposed (readable without credentials), " +
+					"auth_required (credentials were requested), reachable (the port is open but the response was not recognized) " +
+					"or unreachable.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"exposed": schema.ListAttribute{
+				Description: "Surfaces readable without credentials, as `preset@host:port`, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"reachable": schema.ListAttribute{
+				Description: "Surfaces whose port is open, whatever their exposure, as `preset@host:port`, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"exposure_found": schema.BoolAttribute{
+				Description: "True if at least one surface is readable without credentials.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors of the requests to reachable surfaces, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read probes the admin surfaces and updates the state.
+func (d *TerrapwnerHTTPServerProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerHTTPServerProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(defaultHTTPServerProbeTimeout)
+	}
+	presets := adminPanelPresetNames()
+	if !data.Presets.IsNull() {
+		presets = nil
+		resp.Diagnostics.Append(data.Presets.ElementsAs(ctx, &presets, false)...)
+	}
+	hosts := []string{"127.0.0.1"}
+	if !data.Hosts.IsNull() {
+		hosts = nil
+		resp.Diagnostics.Append(data.Hosts.ElementsAs(ctx, &hosts, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	for _, name := range presets {
+		if _, ok := adminPanelPresets[name]; !ok {
+			resp.Diagnostics.AddError(
+				"Invalid configuration",
+				fmt.Sprintf("presets must only contain %s, got: %s", strings.Join(adminPanelPresetNames(), ", "), name),
+			)
+			return
+		}
+	}
+
+	// Probe every surface concurrently, keeping the results in order
+	type target struct{ name, address string }
+	var targets []target
+	for _, name := range presets {
+		for _, host := range hosts {
+			address := host
+			if _, _, err := net.SplitHostPort(host); err != nil {
+				address = net.JoinHostPort(host, strconv.Itoa(adminPanelPresets[name].port))
+			}
+			targets = append(targets, target{name, address})
+		}
+	}
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	exposures := make([]string, len(targets))
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			exposures[i], errs[i] = probeAdminPanel(ctx, adminPanelPresets[t.name], t.address, timeout)
+		}(i, t)
+	}
+	wg.Wait()
+
+	services := map[string]string{}
+	exposed := []string{}
+	reachable := []string{}
+	var failures []string
+	for i, t := range targets {
+		key := t.name + "@" + t.address
+		services[key] = exposures[i]
+		if exposures[i] == exposureExposed {
+			exposed = append(exposed, key)
+		}
+		if exposures[i] != exposureUnreachable {
+			reachable = append(reachable, key)
+		}
+		if errs[i] != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", key, errs[i]))
+		}
+	}
+	sort.Strings(exposed)
+	sort.Strings(reachable)
+
+	data.ExposureFound = types.BoolValue(len(exposed) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	servicesMap, diags := types.MapValueFrom(ctx, types.StringType, services)
+	resp.Diagnostics.Append(diags...)
+	exposedList, diags := types.ListValueFrom(ctx, types.StringType, exposed)
+	resp.Diagnostics.Append(diags...)
+	reachableList, diags := types.ListValueFrom(ctx, types.StringType, reachable)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Services = servicesMap
+	data.Exposed = exposedList
+	data.Reachable = reachableList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// probeAdminPanel returns the exposure of an admin surface. The error is set
+// when the port is open but no scheme of the preset got a response.
+func probeAdminPanel(ctx context.Context, preset adminPanelPreset, address string, timeout time.Duration) (string, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !portOpen(ctx, dialer, address) {
+		return exposureUnreachable, nil
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: utils.LoggingTransport(&http.Transport{
+			DialContext: dialer.DialContext,
+			// Internal surfaces commonly use self-signed certificates
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	var lastErr error
+	for _, scheme := range preset.schemes {
+		var body io.Reader
+		if preset.body != "" {
+			body = strings.NewReader(preset.body)
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, preset.method, scheme+"://"+address+preset.path, body)
+		if err != nil {
+			return exposureReachable, err
+		}
+		httpReq.Header.Set("User-Agent", utils.GetUserAgent())
+		if preset.body != "" {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+
+		httpResp, err := client.Do(httpReq)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		content, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+		httpResp.Body.Close()
+
+		switch {
+		case preset.exposed(httpResp.StatusCode, string(content)):
+			return exposureExposed, nil
+		case httpResp.StatusCode == http.StatusUnauthorized || httpResp.StatusCode == http.StatusForbidden,
+			httpResp.StatusCode >= 300 && httpResp.StatusCode < 400 && strings.Contains(strings.ToLower(httpResp.Header.Get("Location")), "login"):
+			return exposureAuthRequired, nil
+		case httpResp.StatusCode == http.StatusBadRequest && scheme == "http" && strings.Contains(string(content), "HTTPS"):
+			// Plain HTTP sent to a TLS port, try the next scheme
+			continue
+		default:
+			return exposureReachable, nil
+		}
+	}
+	return exposureReachable, lastErr
+}
+
+// portOpen reports whether a TCP connection to the address can be established.
+func portOpen(ctx context.Context, dialer *net.Dialer, address string) bool {
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// adminPanelPresetNames returns the sorted preset names.
+func adminPanelPresetNames() []string {
+	names := make([]string, 0, len(adminPanelPresets))
+	for name := range adminPanelPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// closedAddress returns the address of a local port nothing listens on.
+func closedAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestProbeAdminPanel(t *testing.T) {
+	// A Grafana instance redirecting anonymous users to the login page
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer grafana.Close()
+
+	// An etcd member with authentication disabled, serving TLS
+	etcd := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/kv/range" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"header":{"cluster_id":"1","revision":"42"},"count":"12"}`)
+	}))
+	defer etcd.Close()
+
+	tests := []struct {
+		preset  string
+		address string
+		want    string
+	}{
+		{"grafana", strings.TrimPrefix(grafana.URL, "http://"), exposureAuthRequired},
+		{"etcd", strings.TrimPrefix(etcd.URL, "https://"), exposureExposed},
+		{"consul", strings.TrimPrefix(etcd.URL, "https://"), exposureReachable},
+		{"prometheus", closedAddress(t), exposureUnreachable},
+	}
+	for _, tt := range tests {
+		got, _ := probeAdminPanel(context.Background(), adminPanelPresets[tt.preset], tt.address, 2*time.Second)
+		if got != tt.want {
+			t.Errorf("probeAdminPanel(%s, %s) = %q, want %q", tt.preset, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestAccTerrapwnerHTTPServerProbeDataSource(t *testing.T) {
+	// A Consul agent without ACLs
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/agent/self" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"Config":{"Datacenter":"dc1","NodeName":"consul-0"}}`)
+	}))
+	defer consul.Close()
+	consulAddress := strings.TrimPrefix(consul.URL, "http://")
+	closed := closedAddress(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_http_server_probe" "test" {
+  presets = ["consul", "jenkins"]
+  hosts   = [%q, %q]
+}
+`, consulAddress, closed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_http_server_probe.test", "services.%", "4"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_server_probe.test", "services.consul@"+consulAddress, "exposed"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_server_probe.test", "services.jenkins@"+consulAddress, "reachable"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_server_probe.test", "services.consul@"+closed, "unreachable"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_server_probe.test", "exposed.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_server_probe.test", "exposed.0", "consul@"+consulAddress),
+					resource.TestCheckResourceAttr("data.terrapwner_http_server_probe.test", "reachable.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_server_probe.test", "exposure_found", "true"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_http_server_probe" "test" {
+  presets = ["vault"]
+}
+`,
+				ExpectError: regexp.MustCompile("presets must only contain"),
+			},
+		},
+	})
+}
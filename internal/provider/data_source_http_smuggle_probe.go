@@ -0,0 +1,531 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerHTTPSmuggleProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerHTTPSmuggleProbeDataSource{}
+)
+
+// Outcome of a smuggling probe.
+const (
+	smuggleAccepted = "accepted"
+	smuggleRejected = "rejected"
+	smuggleDropped  = "dropped"
+	smuggleTimeout  = "timeout"
+	smuggleDesync   = "desync"
+)
+
+const (
+	// defaultHTTPSmuggleProbeTimeout is the default timeout in seconds of each probe.
+	defaultHTTPSmuggleProbeTimeout = 10
+	// defaultHTTPSmuggleHeaderSize is the default size in bytes of the oversized_header probe header.
+	defaultHTTPSmuggleHeaderSize = 65536
+	// httpSmuggleManyHeaders is the number of headers sent by the many_headers probe.
+	httpSmuggleManyHeaders = 500
+	// httpSmuggleCanaryPath is the path of the request smuggled by the desync canaries.
+	httpSmuggleCanaryPath = "/terrapwner-smuggle-canary"
+	// httpSmuggleDesyncWait is how long a second response is waited for after the first one.
+	httpSmuggleDesyncWait = 2 * time.Second
+	// httpSmuggleBody is the body of every probe.
+	httpSmuggleBody = "terrapwner"
+)
+
+// httpSmuggleProbeNames lists the probes in the order they are sent. The
+// desync canaries are last and only sent when listed explicitly.
+var httpSmuggleProbeNames = []string{
+	"baseline",
+	"oversized_header",
+	"many_headers",
+	"chunked_extension",
+	"chunked_bare_lf",
+	"chunked_size_overflow",
+	"te_whitespace",
+	"duplicate_content_length",
+	"cl_te",
+	"te_cl",
+}
+
+// httpSmuggleDesyncProbes are the TE/CL mismatch canaries. A vulnerable proxy
+// chain answers the smuggled request too, which may desynchronize connections
+// it shares with other clients.
+var httpSmuggleDesyncProbes = []string{"cl_te", "te_cl"}
+
+// httpSmuggleProxySchemes lists the supported proxy_url schemes.
+var httpSmuggleProxySchemes = []string{"http", "https"}
+
+// NewTerrapwnerHTTPSmuggleProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerHTTPSmuggleProbeDataSource() datasource.DataSource {
+	return &TerrapwnerHTTPSmuggleProbeDataSource{}
+}
+
+// TerrapwnerHTTPSmuggleProbeDataSource is the data source implementation.
+type TerrapwnerHTTPSmuggleProbeDataSource struct{}
+
+// TerrapwnerHTTPSmuggleProbeDataSourceModel describes the data source data model.
+type TerrapwnerHTTPSmuggleProbeDataSourceModel struct {
+	Endpoint          types.String `tfsdk:"endpoint"`
+	ProxyURL          types.String `tfsdk:"proxy_url"`
+	Probes            types.List   `tfsdk:"probes"`
+	HeaderSize        types.Int64  `tfsdk:"header_size"`
+	Timeout           types.Int64  `tfsdk:"timeout"`
+	Proxy             types.String `tfsdk:"proxy"`
+	Results           types.Map    `tfsdk:"results"`
+	StatusCodes       types.Map    `tfsdk:"status_codes"`
+	Responses         types.Map    `tfsdk:"responses"`
+	AnomaliesAccepted types.List   `tfsdk:"anomalies_accepted"`
+	Robust            types.Bool   `tfsdk:"robust"`
+	FailReason        types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerHTTPSmuggleProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerHTTPSmuggleProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_http_smuggle_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerHTTPSmuggleProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, "proxy"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerHTTPSmuggleProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Sends malformed HTTP/1.1 requests to a test endpoint through the egress proxy and reports how the " +
+			"proxy handles each one: oversized headers, chunked encoding edge cases and Transfer-Encoding/Content-Length " +
+			"mismatch canaries. Each probe is written verbatim on its own connection, as Go's HTTP client refuses to send " +
+			"them. Point the endpoint at a server you own, ideally one echoing the requests it receives, to compare what " +
+			"reached it with what was sent. HTTPS endpoints are tunneled with CONNECT, so only a TLS-intercepting proxy " +
+			"can see the probes.",
+		Attributes: map[string]schema.Attribute{
+			"endpoint": schema.StringAttribute{
+				Description: "URL of the test endpoint, with scheme http or https.",
+				Required:    true,
+			},
+			"proxy_url": schema.StringAttribute{
+				Description: fmt.Sprintf("URL of the proxy, with scheme %s (default: the proxy from the HTTP_PROXY, "+
+					"HTTPS_PROXY and NO_PROXY environment variables, if any). Credentials in the URL are sent with "+
+					"Proxy-Authorization.", strings.Join(httpSmuggleProxySchemes, " or ")),
+				Optional:  true,
+				Sensitive: true,
+			},
+			"probes": schema.ListAttribute{
+				Description: fmt.Sprintf("Probes to send (default: all but the desync canaries): %s. The %s canaries "+
+					"smuggle a GET %s request behind the body; a vulnerable proxy chain may answer it on a connection "+
+					"shared with other clients, so only send them to a proxy dedicated to the assessment.",
+					strings.Join(httpSmuggleProbeNames, ", "), strings.Join(httpSmuggleDesyncProbes, " and "), httpSmuggleCanaryPath),
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"header_size": schema.Int64Attribute{
+				Description: fmt.Sprintf("Size in bytes of the header sent by the oversized_header probe (default: %d).", defaultHTTPSmuggleHeaderSize),
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: fmt.Sprintf("Timeout in seconds for each probe (default: %d).", defaultHTTPSmuggleProbeTimeout),
+				Optional:    true,
+			},
+			"proxy": schema.StringAttribute{
+				Description: "Proxy the probes were sent through, without credentials, or empty if they were sent directly.",
+				Computed:    true,
+			},
+			"results": schema.MapAttribute{
+				Description: "Outcome of each probe, keyed by probe: accepted (a 1xx, 2xx or 3xx response), rejected " +
+					"(a 4xx or 5xx response), dropped (the connection was closed without a response), timeout (no " +
+					"response in time) or desync (a second response followed, i.e. the smuggled request was answered).",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"status_codes": schema.MapAttribute{
+				Description: "Status code of the response to each probe, keyed by probe, or 0 without a response.",
+				ElementType: types.Int64Type,
+				Computed:    true,
+			},
+			"responses": schema.MapAttribute{
+				Description: "Status line and first 512 bytes of the body of the response to each probe, keyed by probe.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"anomalies_accepted": schema.ListAttribute{
+				Description: "Probes other than baseline the proxy accepted or desynchronized on, in the order they were sent.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"robust": schema.BoolAttribute{
+				Description: "True if the baseline request was accepted and every other probe was rejected, dropped or timed out.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors connecting to the proxy or endpoint, and the reason the baseline request was not accepted, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read sends the probes and updates the state.
+func (d *TerrapwnerHTTPSmuggleProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerHTTPSmuggleProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(defaultHTTPSmuggleProbeTimeout)
+	}
+	if data.HeaderSize.IsNull() {
+		data.HeaderSize = types.Int64Value(defaultHTTPSmuggleHeaderSize)
+	}
+	probes := slices.DeleteFunc(slices.Clone(httpSmuggleProbeNames), func(name string) bool {
+		return slices.Contains(httpSmuggleDesyncProbes, name)
+	})
+	if !data.Probes.IsNull() {
+		probes = nil
+		resp.Diagnostics.Append(data.Probes.ElementsAs(ctx, &probes, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	for _, name := range probes {
+		if !slices.Contains(httpSmuggleProbeNames, name) {
+			resp.Diagnostics.AddError(
+				"Invalid configuration",
+				fmt.Sprintf("probes must only contain %s, got: %s", strings.Join(httpSmuggleProbeNames, ", "), name),
+			)
+			return
+		}
+	}
+	if data.HeaderSize.ValueInt64() < 1 {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("header_size must be at least 1, got: %d", data.HeaderSize.ValueInt64()),
+		)
+		return
+	}
+	endpoint, err := url.Parse(data.Endpoint.ValueString())
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("endpoint must be a URL with scheme http or https, got: %s", data.Endpoint.ValueString()),
+		)
+		return
+	}
+	var proxyURL *url.URL
+	if !data.ProxyURL.IsNull() {
+		proxyURL, err = url.Parse(data.ProxyURL.ValueString())
+		if err != nil || !slices.Contains(httpSmuggleProxySchemes, proxyURL.Scheme) || proxyURL.Host == "" {
+			resp.Diagnostics.AddError(
+				"Invalid configuration",
+				fmt.Sprintf("proxy_url must be a URL with scheme %s", strings.Join(httpSmuggleProxySchemes, " or ")),
+			)
+			return
+		}
+	} else {
+		proxyURL, err = http.ProxyFromEnvironment(&http.Request{URL: endpoint})
+		if err != nil || (proxyURL != nil && !slices.Contains(httpSmuggleProxySchemes, proxyURL.Scheme)) {
+			resp.Diagnostics.AddError(
+				"Invalid configuration",
+				fmt.Sprintf("the proxy from the environment must be a URL with scheme %s", strings.Join(httpSmuggleProxySchemes, " or ")),
+			)
+			return
+		}
+	}
+	data.Proxy = types.StringValue("")
+	if proxyURL != nil {
+		data.Proxy = types.StringValue((&url.URL{Scheme: proxyURL.Scheme, Host: proxyURL.Host}).String())
+	}
+
+	// Send the probes one after the other, each on its own connection
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	results := map[string]string{}
+	statusCodes := map[string]int64{}
+	responses := map[string]string{}
+	anomalies := []string{}
+	var failures []string
+	for _, name := range probes {
+		raw := httpSmuggleRequest(name, endpoint, proxyURL, int(data.HeaderSize.ValueInt64()))
+		result, err := sendHTTPSmuggleProbe(ctx, endpoint, proxyURL, raw, timeout)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		results[name] = result.outcome
+		statusCodes[name] = int64(result.statusCode)
+		responses[name] = result.response
+		if name != "baseline" && (result.outcome == smuggleAccepted || result.outcome == smuggleDesync) {
+			anomalies = append(anomalies, name)
+		}
+	}
+	if slices.Contains(probes, "baseline") && len(failures) == 0 && results["baseline"] != smuggleAccepted {
+		failures = append(failures, fmt.Sprintf("baseline: request not accepted (%s)", results["baseline"]))
+	}
+
+	data.Robust = types.BoolValue(len(failures) == 0 && len(anomalies) == 0 && results["baseline"] == smuggleAccepted)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	resultsMap, diags := types.MapValueFrom(ctx, types.StringType, results)
+	resp.Diagnostics.Append(diags...)
+	statusCodesMap, diags := types.MapValueFrom(ctx, types.Int64Type, statusCodes)
+	resp.Diagnostics.Append(diags...)
+	responsesMap, diags := types.MapValueFrom(ctx, types.StringType, responses)
+	resp.Diagnostics.Append(diags...)
+	anomaliesList, diags := types.ListValueFrom(ctx, types.StringType, anomalies)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Results = resultsMap
+	data.StatusCodes = statusCodesMap
+	data.Responses = responsesMap
+	data.AnomaliesAccepted = anomaliesList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// httpSmuggleRequest returns the raw request of a probe. Plain HTTP requests
+// to a proxy use the absolute form, others are sent through a tunnel.
+func httpSmuggleRequest(name string, endpoint, proxyURL *url.URL, headerSize int) []byte {
+	target := endpoint.RequestURI()
+	if proxyURL != nil && endpoint.Scheme == "http" {
+		target = endpoint.String()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "POST %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\nConnection: close\r\n", target, endpoint.Host, utils.GetUserAgent())
+	if proxyURL != nil && endpoint.Scheme == "http" && proxyURL.User != nil {
+		fmt.Fprintf(&b, "Proxy-Authorization: %s\r\n", httpSmuggleProxyAuthorization(proxyURL))
+	}
+
+	// smuggled is a complete request to the canary path, on the same host
+	smuggled := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\n\r\n", httpSmuggleCanaryPath, endpoint.Host)
+	body := httpSmuggleBody
+	switch name {
+	case "baseline":
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(body))
+	case "oversized_header":
+		fmt.Fprintf(&b, "X-Terrapwner-Padding: %s\r\nContent-Length: %d\r\n", strings.Repeat("A", headerSize), len(body))
+	case "many_headers":
+		for i := range httpSmuggleManyHeaders {
+			fmt.Fprintf(&b, "X-Terrapwner-%d: %d\r\n", i, i)
+		}
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(body))
+	case "chunked_extension":
+		b.WriteString("Transfer-Encoding: chunked\r\n")
+		body = fmt.Sprintf("%x;terrapwner=%q\r\n%s\r\n0\r\n\r\n", len(httpSmuggleBody), strings.Repeat("x", 64), httpSmuggleBody)
+	case "chunked_bare_lf":
+		b.WriteString("Transfer-Encoding: chunked\r\n")
+		body = fmt.Sprintf("%x\n%s\n0\n\n", len(httpSmuggleBody), httpSmuggleBody)
+	case "chunked_size_overflow":
+		// The size wraps around to the body length on 64-bit parsers
+		b.WriteString("Transfer-Encoding: chunked\r\n")
+		body = fmt.Sprintf("1%016x\r\n%s\r\n0\r\n\r\n", len(httpSmuggleBody), httpSmuggleBody)
+	case "te_whitespace":
+		b.WriteString("Transfer-Encoding : chunked\r\n")
+		body = fmt.Sprintf("%x\r\n%s\r\n0\r\n\r\n", len(httpSmuggleBody), httpSmuggleBody)
+	case "duplicate_content_length":
+		fmt.Fprintf(&b, "Content-Length: %d\r\nContent-Length: %d\r\n", len(body), len(body)+1)
+	case "cl_te":
+		// Content-Length covers the smuggled request, chunked encoding ends before it
+		body = "0\r\n\r\n" + smuggled
+		fmt.Fprintf(&b, "Content-Length: %d\r\nTransfer-Encoding: chunked\r\n", len(body))
+	case "te_cl":
+		// Chunked encoding covers the smuggled request, Content-Length ends before it
+		chunk := fmt.Sprintf("%x\r\n", len(smuggled))
+		body = chunk + smuggled + "\r\n0\r\n\r\n"
+		fmt.Fprintf(&b, "Content-Length: %d\r\nTransfer-Encoding: chunked\r\n", len(chunk))
+	}
+	b.WriteString("\r\n")
+	b.WriteString(body)
+	return []byte(b.String())
+}
+
+// httpSmuggleProxyAuthorization returns the Proxy-Authorization header value
+// for the credentials of the proxy URL.
+func httpSmuggleProxyAuthorization(proxyURL *url.URL) string {
+	password, _ := proxyURL.User.Password()
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username()+":"+password))
+}
+
+// httpSmuggleResult is the response to a probe.
+type httpSmuggleResult struct {
+	outcome    string
+	statusCode int
+	response   string
+}
+
+// sendHTTPSmuggleProbe writes a raw request to the endpoint, through the proxy
+// if set, and classifies the response. The error is set when the proxy or
+// endpoint could not be reached, so the probe itself was never sent.
+func sendHTTPSmuggleProbe(ctx context.Context, endpoint, proxyURL *url.URL, raw []byte, timeout time.Duration) (*httpSmuggleResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := dialHTTPSmuggle(ctx, endpoint, proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	// A proxy may reject the request before reading all of it
+	if _, err := conn.Write(raw); err != nil && !isHTTPSmuggleReset(err) {
+		return nil, fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	httpResp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return &httpSmuggleResult{outcome: smuggleTimeout}, nil
+		}
+		return &httpSmuggleResult{outcome: smuggleDropped}, nil
+	}
+	content, _ := io.ReadAll(io.LimitReader(httpResp.Body, 512))
+	_, _ = io.Copy(io.Discard, httpResp.Body)
+	httpResp.Body.Close()
+	result := &httpSmuggleResult{
+		outcome:    smuggleAccepted,
+		statusCode: httpResp.StatusCode,
+		response:   httpResp.Proto + " " + httpResp.Status + "\n" + string(content),
+	}
+	if httpResp.StatusCode >= 400 {
+		result.outcome = smuggleRejected
+	}
+
+	// A second response means the smuggled request was answered
+	if wait := time.Now().Add(httpSmuggleDesyncWait); wait.Before(deadline) {
+		_ = conn.SetReadDeadline(wait)
+	}
+	if _, err := http.ReadResponse(br, nil); err == nil {
+		result.outcome = smuggleDesync
+	}
+	return result, nil
+}
+
+// dialHTTPSmuggle connects to the proxy, or to the endpoint without proxy. The
+// returned connection is tunneled to HTTPS endpoints.
+func dialHTTPSmuggle(ctx context.Context, endpoint, proxyURL *url.URL) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	address := httpSmuggleAddress(endpoint)
+	if proxyURL != nil {
+		address = httpSmuggleAddress(proxyURL)
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	if proxyURL != nil && proxyURL.Scheme == "https" {
+		conn, err = httpSmuggleTLS(ctx, conn, proxyURL.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to the proxy: %w", err)
+		}
+	}
+	if endpoint.Scheme == "http" {
+		return conn, nil
+	}
+
+	if proxyURL != nil {
+		// Open a tunnel to the endpoint
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+		target := httpSmuggleAddress(endpoint)
+		connect := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: %s\r\n", target, target, utils.GetUserAgent())
+		if proxyURL.User != nil {
+			connect += "Proxy-Authorization: " + httpSmuggleProxyAuthorization(proxyURL) + "\r\n"
+		}
+		if _, err := conn.Write([]byte(connect + "\r\n")); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to open a tunnel: %w", err)
+		}
+		// The proxy answers CONNECT without a body, so nothing is buffered past it
+		connectResp, err := http.ReadResponse(bufio.NewReaderSize(conn, 1), &http.Request{Method: http.MethodConnect})
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to open a tunnel: %w", err)
+		}
+		connectResp.Body.Close()
+		if connectResp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("failed to open a tunnel: proxy returned %s", connectResp.Status)
+		}
+	}
+	tlsConn, err := httpSmuggleTLS(ctx, conn, endpoint.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the endpoint: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// httpSmuggleTLS starts an HTTP/1.1 TLS session over the connection, closing it
+// on failure. Certificates are not verified, as test endpoints are commonly
+// self-signed.
+func httpSmuggleTLS(ctx context.Context, conn net.Conn, serverName string) (net.Conn, error) {
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"http/1.1"},
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// httpSmuggleAddress returns the host and port of a URL, with the default port
+// of its scheme if not set.
+func httpSmuggleAddress(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return u.Host
+	}
+	port := 80
+	if u.Scheme == "https" {
+		port = 443
+	}
+	return net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+}
+
+// isHTTPSmuggleReset reports whether a write failed because the peer closed
+// the connection, which happens when a proxy rejects a request early.
+func isHTTPSmuggleReset(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) ||
+		strings.Contains(err.Error(), "connection reset") || strings.Contains(err.Error(), "broken pipe")
+}
@@ -0,0 +1,234 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// startSmuggleProxy starts a raw HTTP proxy requiring user:secret. It rejects
+// oversized or numerous headers and malformed Transfer-Encoding, drops
+// duplicate Content-Length, tolerates chunk extensions and bare LF, and
+// answers the smuggled request of cl_te as well. CONNECT requests are tunneled.
+func startSmuggleProxy(t *testing.T) (string, func() []string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	var targets []string
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				var lines []string
+				for {
+					line, err := br.ReadString('\n')
+					if err != nil {
+						return
+					}
+					line = strings.TrimRight(line, "\r\n")
+					if line == "" {
+						break
+					}
+					lines = append(lines, line)
+				}
+				requestLine := strings.Fields(lines[0])
+				mu.Lock()
+				targets = append(targets, requestLine[0]+" "+requestLine[1])
+				mu.Unlock()
+				head := strings.Join(lines, "\n")
+				respond := func(status int) {
+					fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\nContent-Length: 2\r\n\r\nok", status, http.StatusText(status))
+				}
+				switch {
+				case !strings.Contains(head, "Proxy-Authorization: Basic dXNlcjpzZWNyZXQ="):
+					respond(http.StatusProxyAuthRequired)
+				case requestLine[0] == http.MethodConnect:
+					upstream, err := net.Dial("tcp", requestLine[1])
+					if err != nil {
+						respond(http.StatusBadGateway)
+						return
+					}
+					defer upstream.Close()
+					fmt.Fprint(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+					go func() { _, _ = io.Copy(upstream, br) }()
+					_, _ = io.Copy(conn, upstream)
+				case len(head) > 8192 || len(lines) > 100:
+					respond(http.StatusRequestHeaderFieldsTooLarge)
+				case strings.Contains(head, "Transfer-Encoding :"):
+					respond(http.StatusBadRequest)
+				case strings.Count(head, "Content-Length:") > 1:
+					return
+				case strings.Contains(head, "Content-Length:") && strings.Contains(head, "Transfer-Encoding:"):
+					respond(http.StatusOK)
+					respond(http.StatusNotFound)
+				default:
+					respond(http.StatusOK)
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), targets...)
+	}
+}
+
+func TestAccTerrapwnerHTTPSmuggleProbeDataSource(t *testing.T) {
+	// An endpoint reached directly, rejecting what Go's server rejects and
+	// headers over 4 KiB
+	endpoint := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		fmt.Fprint(w, "received")
+	}))
+	endpoint.Config.MaxHeaderBytes = 4096
+	endpoint.Start()
+	defer endpoint.Close()
+	tlsEndpoint := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	}))
+	defer tlsEndpoint.Close()
+	proxyAddress, proxyTargets := startSmuggleProxy(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Every probe through the proxy
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_http_smuggle_probe" "test" {
+  endpoint  = "http://app.internal:8080/echo"
+  proxy_url = "http://user:secret@%s"
+  probes    = ["baseline", "oversized_header", "many_headers", "chunked_extension", "chunked_bare_lf", "te_whitespace", "duplicate_content_length", "cl_te"]
+}
+`, proxyAddress),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "proxy", "http://"+proxyAddress),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.baseline", "accepted"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.oversized_header", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "status_codes.oversized_header", "431"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.many_headers", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.chunked_extension", "accepted"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.chunked_bare_lf", "accepted"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.te_whitespace", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.duplicate_content_length", "dropped"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "status_codes.duplicate_content_length", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.cl_te", "desync"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "responses.baseline", "HTTP/1.1 200 OK\nok"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "anomalies_accepted.#", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "anomalies_accepted.0", "chunked_extension"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "anomalies_accepted.2", "cl_te"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "robust", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "fail_reason", ""),
+					func(_ *terraform.State) error {
+						for _, target := range proxyTargets() {
+							if target != "POST http://app.internal:8080/echo" {
+								return fmt.Errorf("request not in absolute form: %s", target)
+							}
+						}
+						return nil
+					},
+				),
+			},
+			// HTTPS endpoint tunneled through the proxy
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_http_smuggle_probe" "test" {
+  endpoint  = "%s/tunneled"
+  proxy_url = "http://user:secret@%s"
+  probes    = ["baseline", "te_whitespace"]
+}
+`, tlsEndpoint.URL, proxyAddress),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.baseline", "accepted"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "responses.baseline", "HTTP/1.1 200 OK\nPOST /tunneled"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.te_whitespace", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "robust", "true"),
+				),
+			},
+			// Wrong proxy credentials
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_http_smuggle_probe" "test" {
+  endpoint  = "https://app.internal"
+  proxy_url = "http://user:wrong@%s"
+  probes    = ["baseline"]
+}
+`, proxyAddress),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.%", "0"),
+					resource.TestMatchResourceAttr("data.terrapwner_http_smuggle_probe.test", "fail_reason", regexp.MustCompile(`^baseline: failed to open a tunnel: proxy returned 407`)),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "robust", "false"),
+				),
+			},
+			// Default probes, sent directly to the endpoint
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_http_smuggle_probe" "test" {
+  endpoint = "%s/direct"
+  timeout  = 3
+}
+`, endpoint.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "proxy", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.%", "8"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.baseline", "accepted"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.oversized_header", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.te_whitespace", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.duplicate_content_length", "rejected"),
+					resource.TestCheckNoResourceAttr("data.terrapwner_http_smuggle_probe.test", "results.cl_te"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_http_smuggle_probe" "test" {
+  endpoint = "ftp://app.internal"
+}
+`,
+				ExpectError: regexp.MustCompile(`endpoint must be a URL with scheme http or https`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_http_smuggle_probe" "test" {
+  endpoint  = "http://app.internal"
+  proxy_url = "socks5://proxy.internal:1080"
+}
+`,
+				ExpectError: regexp.MustCompile(`proxy_url must be a URL with scheme http or https`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_http_smuggle_probe" "test" {
+  endpoint = "http://app.internal"
+  probes   = ["request_splitting"]
+}
+`,
+				ExpectError: regexp.MustCompile(`probes must only contain`),
+			},
+		},
+	})
+}
@@ -5,18 +5,39 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/endpointcreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
+const (
+	// awsCredentialTimeout bounds the AWS configuration loading and credential resolution.
+	awsCredentialTimeout = 5 * time.Second
+
+	// awsSTSTimeout bounds the GetCallerIdentity call.
+	awsSTSTimeout = 10 * time.Second
+)
+
+// kubernetesServiceAccountDir is where Kubernetes mounts the pod service account credentials.
+var kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ datasource.DataSource = &TerrapwnerIdentityDataSource{}
 
@@ -25,7 +46,9 @@ func NewTerrapwnerIdentityDataSource() datasource.DataSource {
 }
 
 // TerrapwnerIdentityDataSource defines the data source implementation.
-type TerrapwnerIdentityDataSource struct{}
+type TerrapwnerIdentityDataSource struct {
+	snapshot *environmentSnapshot
+}
 
 // TerrapwnerIdentityDataSourceModel describes the data source data model.
 type TerrapwnerIdentityDataSourceModel struct {
@@ -37,12 +60,31 @@ type TerrapwnerIdentityDataSourceModel struct {
 	CallerType    types.String `tfsdk:"caller_type"`    // e.g., "role", "user", "assumed-role"
 	SessionName   types.String `tfsdk:"session_name"`   // e.g., session name for assumed roles
 	Region        types.String `tfsdk:"region"`         // e.g., AWS region
+	Partition     types.String `tfsdk:"partition"`      // e.g., "aws", "aws-us-gov", "aws-cn"
+	StsEndpoint   types.String `tfsdk:"sts_endpoint"`   // e.g., VPC endpoint URL
+	StsRegion     types.String `tfsdk:"sts_region"`     // e.g., "us-gov-west-1"
+	CIPlatform    types.String `tfsdk:"ci_platform"`    // e.g., "github_actions", "gitlab_ci"
+
+	CredentialSource        types.String `tfsdk:"credential_source"`         // e.g., "static_env", "web_identity"
+	CredentialsExpireAt     types.String `tfsdk:"credentials_expire_at"`     // RFC 3339 expiry of temporary credentials
+	CredentialsAreTemporary types.Bool   `tfsdk:"credentials_are_temporary"` // true when a session token is in use
+	CredentialConflicts     types.List   `tfsdk:"credential_conflicts"`      // sources present simultaneously
+	RawJSON                 types.String `tfsdk:"raw_json"`                  // full GetCallerIdentity response
+
+	KubernetesNamespace      types.String `tfsdk:"kubernetes_namespace"`
+	KubernetesServiceAccount types.String `tfsdk:"kubernetes_service_account"`
+	KubernetesAudiences      types.List   `tfsdk:"kubernetes_token_audiences"`
 }
 
 func (d *TerrapwnerIdentityDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_identity"
 }
 
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerIdentityDataSource) Tags() []string {
+	return []string{categoryCloud, categoryNetwork, "aws", "gcp", "azure"}
+}
+
 func (d *TerrapwnerIdentityDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		MarkdownDescription: "Retrieves identity information about the entity running Terraform",
@@ -80,12 +122,64 @@ func (d *TerrapwnerIdentityDataSource) Schema(ctx context.Context, req datasourc
 				MarkdownDescription: "Cloud region",
 				Computed:            true,
 			},
+			"partition": schema.StringAttribute{
+				MarkdownDescription: "AWS partition of the caller ARN (e.g., aws, aws-us-gov, aws-cn)",
+				Computed:            true,
+			},
+			"sts_endpoint": schema.StringAttribute{
+				MarkdownDescription: "Custom STS endpoint URL (e.g., an interface VPC endpoint). Defaults to the SDK endpoint resolution",
+				Optional:            true,
+			},
+			"sts_region": schema.StringAttribute{
+				MarkdownDescription: "Region used for STS calls (e.g., us-gov-west-1 or cn-north-1). Defaults to the detected region",
+				Optional:            true,
+			},
+			"ci_platform": schema.StringAttribute{
+				MarkdownDescription: "CI/CD platform running Terraform (e.g., github_actions, gitlab_ci, jenkins), empty when none is detected",
+				Computed:            true,
+			},
+			"credential_source": schema.StringAttribute{
+				MarkdownDescription: "How the AWS credentials were obtained: `static_env`, `shared_config`, `sso`, `web_identity` (e.g., IRSA), " +
+					"`assume_role`, `process`, `instance_profile`, `ecs_task_role`, `eks_pod_identity`, `container_endpoint` or `unknown`",
+				Computed: true,
+			},
+			"credentials_expire_at": schema.StringAttribute{
+				MarkdownDescription: "Expiration time (RFC 3339) of the AWS credentials, empty when they do not expire or the expiry is unknown",
+				Computed:            true,
+			},
+			"credentials_are_temporary": schema.BoolAttribute{
+				MarkdownDescription: "True if the AWS credentials are temporary session credentials, false for long-lived access keys",
+				Computed:            true,
+			},
+			"credential_conflicts": schema.ListAttribute{
+				MarkdownDescription: "AWS credential sources configured simultaneously (e.g., `static_env`, `shared_config`, `web_identity`), " +
+					"empty when at most one is present. Several sources usually means the runner does not use the identity it was meant to",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"raw_json": schema.StringAttribute{
+				MarkdownDescription: "Full GetCallerIdentity response as JSON, empty when the identity could not be retrieved",
+				Computed:            true,
+			},
+			"kubernetes_namespace": schema.StringAttribute{
+				MarkdownDescription: "Namespace of the mounted Kubernetes service account, empty when not running in a pod",
+				Computed:            true,
+			},
+			"kubernetes_service_account": schema.StringAttribute{
+				MarkdownDescription: "Name of the mounted Kubernetes service account, empty when not running in a pod",
+				Computed:            true,
+			},
+			"kubernetes_token_audiences": schema.ListAttribute{
+				MarkdownDescription: "Audiences (`aud` claim) of the mounted Kubernetes service account token",
+				ElementType:         types.StringType,
+				Computed:            true,
+			},
 		},
 	}
 }
 
 func (d *TerrapwnerIdentityDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
-	// No configuration needed
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
 }
 
 func (d *TerrapwnerIdentityDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -98,43 +192,90 @@ func (d *TerrapwnerIdentityDataSource) Read(ctx context.Context, req datasource.
 		return
 	}
 
-	// Try to detect the cloud provider and environment
-	provider, region := d.detectProviderAndEnvironment()
+	// Use the identity resolved once for the provider run, and only call STS
+	// again when it is customized
+	identity := d.snapshot.Identity(ctx)
+	if !data.StsEndpoint.IsNull() || !data.StsRegion.IsNull() {
+		identity = identity.withSTS(ctx, d.snapshot.Getenv, stsOptions{
+			Endpoint: data.StsEndpoint.ValueString(),
+			Region:   data.StsRegion.ValueString(),
+		})
+	}
+	provider := identity.Provider
 
 	// Set the provider and region
 	data.CloudProvider = types.StringValue(provider)
-	data.Region = types.StringValue(region)
+	data.Region = types.StringValue(identity.Region)
+	data.CIPlatform = types.StringValue(d.snapshot.CIPlatform())
+	data.CredentialSource = types.StringValue("unknown")
+	data.CredentialsExpireAt = types.StringValue("")
+	data.CredentialsAreTemporary = types.BoolValue(false)
+
+	// Set default values for identity fields
+	data.AccountId = types.StringValue("unknown")
+	data.ResourceId = types.StringValue("unknown")
+	data.CallerName = types.StringValue("unknown")
+	data.CallerType = types.StringValue("unknown")
+	data.SessionName = types.StringValue("unknown")
+	data.Partition = types.StringValue("unknown")
+	data.RawJSON = types.StringValue("")
 
 	// Get identity information based on the provider
 	switch provider {
 	case "aws":
-		if err := d.getAWSIdentity(ctx, &data); err != nil {
+		if identity.Offline {
+			// Cloud calls are disabled, the identity fields stay unknown
+			break
+		}
+		if identity.AWS != nil {
+			data.CredentialSource = types.StringValue(identity.AWS.CredentialSource)
+			data.CredentialsExpireAt = types.StringValue(identity.AWS.CredentialsExpireAt)
+			data.CredentialsAreTemporary = types.BoolValue(identity.AWS.CredentialsTemporary)
+		}
+		if identity.AWSError != nil {
 			// Log the error but don't fail the data source
-			resp.Diagnostics.AddWarning("Failed to get AWS identity", err.Error())
-			// Set default values for AWS-specific fields
-			data.AccountId = types.StringValue("unknown")
-			data.ResourceId = types.StringValue("unknown")
-			data.CallerName = types.StringValue("unknown")
-			data.CallerType = types.StringValue("unknown")
-			data.SessionName = types.StringValue("unknown")
+			resp.Diagnostics.AddWarning("Failed to get AWS identity", identity.AWSError.Error())
+		} else {
+			data.AccountId = types.StringValue(identity.AWS.AccountID)
+			data.ResourceId = types.StringValue(identity.AWS.ARN)
+			data.CallerName = types.StringValue(identity.AWS.CallerName)
+			data.CallerType = types.StringValue(identity.AWS.CallerType)
+			data.SessionName = types.StringValue(identity.AWS.SessionName)
+			data.Partition = types.StringValue(identity.AWS.Partition)
+			data.RawJSON = types.StringValue(identity.AWS.RawJSON)
 		}
+	case "gcp", "azure":
+		// Only the provider and region are detected, the identity fields stay unknown
 	case "":
-		// No cloud provider detected, set all fields to unknown
-		data.AccountId = types.StringValue("unknown")
-		data.ResourceId = types.StringValue("unknown")
-		data.CallerName = types.StringValue("unknown")
-		data.CallerType = types.StringValue("unknown")
-		data.SessionName = types.StringValue("unknown")
+		// No cloud provider detected, all fields stay unknown
 	default:
-		// Unsupported provider detected, set all fields to unknown
+		// Unsupported provider detected, all fields stay unknown
 		resp.Diagnostics.AddWarning("Unsupported provider", fmt.Sprintf("Provider %s is not supported", provider))
-		data.AccountId = types.StringValue("unknown")
-		data.ResourceId = types.StringValue("unknown")
-		data.CallerName = types.StringValue("unknown")
-		data.CallerType = types.StringValue("unknown")
-		data.SessionName = types.StringValue("unknown")
 	}
 
+	// Report credential sources that compete in the AWS provider chain
+	conflicts := []string{}
+	if sources := detectAWSCredentialSources(d.snapshot.Getenv); len(sources) > 1 {
+		conflicts = sources
+	}
+	conflictsList, diags := types.ListValueFrom(ctx, types.StringType, conflicts)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.CredentialConflicts = conflictsList
+
+	// Detect the Kubernetes service account mounted in the pod, if any
+	namespace, serviceAccount, audiences := detectKubernetesServiceAccount(kubernetesServiceAccountDir)
+	data.KubernetesNamespace = types.StringValue(namespace)
+	data.KubernetesServiceAccount = types.StringValue(serviceAccount)
+	audiencesList, diags := types.ListValueFrom(ctx, types.StringType, audiences)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.KubernetesAudiences = audiencesList
+
 	// Set a unique ID for this data source
 	data.Id = types.StringValue(fmt.Sprintf("%s-%s", provider, data.AccountId.ValueString()))
 
@@ -142,66 +283,195 @@ func (d *TerrapwnerIdentityDataSource) Read(ctx context.Context, req datasource.
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
-func (d *TerrapwnerIdentityDataSource) detectProviderAndEnvironment() (string, string) {
-	// Check for AWS environment variables
-	if os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+// resolvedIdentity is the cloud identity of the process running Terraform.
+type resolvedIdentity struct {
+	Provider string
+	Region   string
+	AWS      *awsIdentity
+	AWSError error
+
+	// Offline is set when the provider and region were detected from the
+	// environment only, without calling the cloud APIs
+	Offline bool
+}
+
+// awsIdentity is the AWS caller identity and the details of its credentials.
+type awsIdentity struct {
+	AccountID            string
+	ARN                  string
+	Partition            string
+	CallerName           string
+	CallerType           string
+	SessionName          string
+	CredentialSource     string
+	CredentialsTemporary bool
+	CredentialsExpireAt  string
+	RawJSON              string
+}
+
+// stsOptions overrides how STS is reached, for GovCloud, China and VPC endpoints.
+type stsOptions struct {
+	Endpoint string
+	Region   string
+}
+
+// resolveIdentity detects the cloud provider from the environment read with
+// getenv and, for AWS, the caller identity.
+func resolveIdentity(ctx context.Context, getenv func(string) string, opts stsOptions) *resolvedIdentity {
+	provider, region := detectProviderAndEnvironment(ctx, getenv)
+	identity := &resolvedIdentity{Provider: provider, Region: region}
+	if provider == "aws" {
+		identity.AWS, identity.AWSError = getAWSIdentity(ctx, getenv, region, opts)
+	}
+	return identity
+}
+
+// offlineIdentity detects the cloud provider from the environment variables
+// read with getenv only, without any network call.
+func offlineIdentity(getenv func(string) string) *resolvedIdentity {
+	provider, region := detectProviderFromEnv(getenv)
+	return &resolvedIdentity{Provider: provider, Region: region, Offline: true}
+}
+
+// withSTS returns the identity resolved with custom STS options, reusing the
+// detected provider and region. Identities other than AWS are returned as is.
+func (i *resolvedIdentity) withSTS(ctx context.Context, getenv func(string) string, opts stsOptions) *resolvedIdentity {
+	if i.Provider != "aws" || i.Offline {
+		return i
+	}
+	identity := &resolvedIdentity{Provider: i.Provider, Region: i.Region}
+	identity.AWS, identity.AWSError = getAWSIdentity(ctx, getenv, i.Region, opts)
+	return identity
+}
+
+func detectProviderAndEnvironment(ctx context.Context, getenv func(string) string) (string, string) {
+	if provider, region := detectProviderFromEnv(getenv); provider != "" {
+		return provider, region
+	}
+
+	// Skip the metadata services on hosts that are known not to be cloud
+	// instances, where the probes would only wait for their timeout
+	if !mayBeCloudInstance() {
+		return "", ""
+	}
+
+	// Fall back to the instance metadata services, for credentials coming
+	// from instance profiles or workload identity rather than env vars
+	provider, region := detectMetadataProvider(ctx)
+	if provider == "aws" && getenv("AWS_REGION") != "" {
+		region = getenv("AWS_REGION")
+	}
+	return provider, region
+}
+
+// detectProviderFromEnv detects AWS from the credentials set in the environment
+// variables read with getenv: access keys, web identity (e.g., IRSA) or
+// container credentials.
+func detectProviderFromEnv(getenv func(string) string) (string, string) {
+	for _, variable := range []string{
+		"AWS_ACCESS_KEY_ID",
+		"AWS_WEB_IDENTITY_TOKEN_FILE",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	} {
+		if getenv(variable) == "" {
+			continue
+		}
 		// Get AWS region from environment or default to us-east-1
-		region := os.Getenv("AWS_REGION")
+		region := getenv("AWS_REGION")
+		if region == "" {
+			region = getenv("AWS_DEFAULT_REGION")
+		}
 		if region == "" {
 			region = "us-east-1"
 		}
 		return "aws", region
 	}
-
-	// No cloud provider detected
 	return "", ""
 }
 
-func (d *TerrapwnerIdentityDataSource) getAWSIdentity(ctx context.Context, data *TerrapwnerIdentityDataSourceModel) error {
+// getAWSIdentity resolves the AWS credentials and caller identity. The returned
+// identity carries the credential details even when the STS call fails.
+func getAWSIdentity(ctx context.Context, getenv func(string) string, region string, opts stsOptions) (*awsIdentity, error) {
+	// Bound the credential resolution, as some providers in the chain (e.g.,
+	// instance profiles without a reachable metadata service) retry for long
+	credsCtx, cancel := context.WithTimeout(ctx, awsCredentialTimeout)
+	defer cancel()
+
 	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(data.Region.ValueString()))
+	cfg, err := config.LoadDefaultConfig(credsCtx, config.WithRegion(region))
 	if err != nil {
-		return fmt.Errorf("unable to load AWS configuration: %w", err)
+		return nil, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+
+	// Resolve the credentials to find which provider in the chain supplied them
+	creds, err := cfg.Credentials.Retrieve(credsCtx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve AWS credentials: %w", err)
+	}
+	result := &awsIdentity{
+		CredentialSource:     awsCredentialSource(creds.Source, getenv),
+		CredentialsTemporary: creds.SessionToken != "" || creds.CanExpire,
+	}
+	if creds.CanExpire {
+		result.CredentialsExpireAt = creds.Expires.UTC().Format(time.RFC3339)
 	}
 
 	// Create STS client
-	stsClient := sts.NewFromConfig(cfg)
+	stsClient := sts.NewFromConfig(cfg, func(o *sts.Options) {
+		if opts.Region != "" {
+			o.Region = opts.Region
+		}
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	})
 
 	// Get caller identity
-	identity, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	stsCtx, cancel := context.WithTimeout(ctx, awsSTSTimeout)
+	defer cancel()
+	identity, err := stsClient.GetCallerIdentity(stsCtx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		return fmt.Errorf("unable to get AWS identity: %w", err)
+		return result, fmt.Errorf("unable to get AWS identity: %w", err)
 	}
 
 	// Set basic identity information
-	data.AccountId = types.StringValue(*identity.Account)
-	data.ResourceId = types.StringValue(*identity.Arn)
+	result.AccountID = *identity.Account
+	result.ARN = *identity.Arn
+	rawJSON, err := json.Marshal(map[string]string{
+		"Account": aws.ToString(identity.Account),
+		"Arn":     aws.ToString(identity.Arn),
+		"UserId":  aws.ToString(identity.UserId),
+	})
+	if err != nil {
+		return result, fmt.Errorf("unable to encode identity: %w", err)
+	}
+	result.RawJSON = string(rawJSON)
 
 	// Parse the ARN using AWS SDK
 	parsedARN, err := arn.Parse(*identity.Arn)
 	if err != nil {
-		return fmt.Errorf("unable to parse ARN: %w", err)
+		return result, fmt.Errorf("unable to parse ARN: %w", err)
 	}
 
+	result.Partition = parsedARN.Partition
+
 	// Extract resource type and ID from the resource string
 	resourceParts := strings.Split(parsedARN.Resource, "/")
 	if len(resourceParts) < 2 {
-		return fmt.Errorf("invalid resource format in ARN: %s", parsedARN.Resource)
+		return result, fmt.Errorf("invalid resource format in ARN: %s", parsedARN.Resource)
 	}
 	resourceType := resourceParts[0]
 	resourceID := strings.Join(resourceParts[1:], "/")
 
 	// Determine caller type and name from ARN
-	callerType, callerName, sessionName := d.determineCallerInfo(parsedARN.Service, resourceType, resourceID)
-	data.CallerType = types.StringValue(callerType)
-	data.CallerName = types.StringValue(callerName)
-	data.SessionName = types.StringValue(sessionName)
+	result.CallerType, result.CallerName, result.SessionName = determineCallerInfo(parsedARN.Service, resourceType, resourceID)
 
-	return nil
+	return result, nil
 }
 
 // determineCallerInfo extracts the caller type and name from an AWS ARN.
-func (d *TerrapwnerIdentityDataSource) determineCallerInfo(service, resourceType, resourceID string) (string, string, string) {
+func determineCallerInfo(service, resourceType, resourceID string) (string, string, string) {
 	switch {
 	case service == "sts" && resourceType == "assumed-role":
 		// For assumed roles, resourceID is in format "role-name/session-name"
@@ -218,3 +488,118 @@ func (d *TerrapwnerIdentityDataSource) determineCallerInfo(service, resourceType
 		return "unknown", resourceID, ""
 	}
 }
+
+// awsCredentialSource maps the source reported by the AWS SDK credential
+// provider chain to a normalized credential source, reading the container
+// credential variables with getenv.
+func awsCredentialSource(source string, getenv func(string) string) string {
+	switch {
+	case source == config.CredentialsSourceName:
+		return "static_env"
+	case strings.HasPrefix(source, "SharedConfigCredentials"):
+		return "shared_config"
+	case source == ssocreds.ProviderName:
+		return "sso"
+	case source == stscreds.WebIdentityProviderName:
+		return "web_identity"
+	case source == stscreds.ProviderName:
+		return "assume_role"
+	case source == processcreds.ProviderName:
+		return "process"
+	case source == ec2rolecreds.ProviderName:
+		return "instance_profile"
+	case source == endpointcreds.ProviderName:
+		// ECS exposes a relative URI; EKS Pod Identity uses a full URI on a link-local agent
+		if getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" {
+			return "ecs_task_role"
+		}
+		if strings.Contains(getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"), "169.254.170.23") {
+			return "eks_pod_identity"
+		}
+		return "container_endpoint"
+	default:
+		return "unknown"
+	}
+}
+
+// detectAWSCredentialSources lists the AWS credential sources configured in the
+// environment, using the same names as awsCredentialSource.
+func detectAWSCredentialSources(getenv func(string) string) []string {
+	var sources []string
+	if getenv("AWS_ACCESS_KEY_ID") != "" {
+		sources = append(sources, "static_env")
+	}
+	if getenv("AWS_PROFILE") != "" || sharedCredentialsFileHasKeys(getenv) {
+		sources = append(sources, "shared_config")
+	}
+	if getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" {
+		sources = append(sources, "web_identity")
+	}
+	if getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		sources = append(sources, "container_endpoint")
+	}
+	return sources
+}
+
+// sharedCredentialsFileHasKeys reports whether the shared credentials file holds access keys.
+func sharedCredentialsFileHasKeys(getenv func(string) string) bool {
+	path := getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(content), "aws_access_key_id")
+}
+
+// detectKubernetesServiceAccount reads the service account token mounted in a pod
+// and returns its namespace, service account name and token audiences.
+func detectKubernetesServiceAccount(dir string) (string, string, []string) {
+	token, err := os.ReadFile(filepath.Join(dir, "token"))
+	if err != nil {
+		return "", "", []string{}
+	}
+
+	// The namespace file is authoritative when present
+	namespace := ""
+	if ns, err := os.ReadFile(filepath.Join(dir, "namespace")); err == nil {
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	claims, err := utils.DecodeJWTClaims(string(token))
+	if err != nil {
+		return namespace, "", []string{}
+	}
+
+	// Subject is in format "system:serviceaccount:<namespace>:<name>"
+	serviceAccount := ""
+	if sub, ok := claims["sub"].(string); ok {
+		parts := strings.Split(sub, ":")
+		if len(parts) == 4 && parts[0] == "system" && parts[1] == "serviceaccount" {
+			if namespace == "" {
+				namespace = parts[2]
+			}
+			serviceAccount = parts[3]
+		}
+	}
+
+	// Legacy (non-projected) tokens carry the details in dedicated claims
+	if serviceAccount == "" {
+		if name, ok := claims["kubernetes.io/serviceaccount/service-account.name"].(string); ok {
+			serviceAccount = name
+		}
+	}
+	if namespace == "" {
+		if ns, ok := claims["kubernetes.io/serviceaccount/namespace"].(string); ok {
+			namespace = ns
+		}
+	}
+
+	return namespace, serviceAccount, utils.JWTAudiences(claims)
+}
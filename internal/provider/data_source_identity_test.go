@@ -0,0 +1,342 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/credentials/endpointcreds"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testAccJWT builds an unsigned JWT carrying the given JSON payload.
+func testAccJWT(payload string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	return header + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestAccTerrapwnerIdentityDataSource_Kubernetes(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	testAccSetMetadataEndpoints(t, "http://127.0.0.1:1")
+
+	dir := t.TempDir()
+	token := testAccJWT(`{"sub":"system:serviceaccount:ci:terraform-runner","aud":["https://kubernetes.default.svc","vault"]}`)
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte(token), 0600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "namespace"), []byte("ci\n"), 0600); err != nil {
+		t.Fatalf("Failed to write namespace: %v", err)
+	}
+
+	originalDir := kubernetesServiceAccountDir
+	kubernetesServiceAccountDir = dir
+	t.Cleanup(func() { kubernetesServiceAccountDir = originalDir })
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_identity" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "kubernetes_namespace", "ci"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "kubernetes_service_account", "terraform-runner"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "kubernetes_token_audiences.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "kubernetes_token_audiences.1", "vault"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerIdentityDataSource_NoKubernetes(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	testAccSetMetadataEndpoints(t, "http://127.0.0.1:1")
+
+	originalDir := kubernetesServiceAccountDir
+	kubernetesServiceAccountDir = filepath.Join(t.TempDir(), "missing")
+	t.Cleanup(func() { kubernetesServiceAccountDir = originalDir })
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_identity" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "cloud_provider", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "kubernetes_namespace", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "kubernetes_service_account", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "kubernetes_token_audiences.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerIdentityDataSource_MetadataFallback(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	// Keep the AWS SDK from resolving instance profile credentials from the real metadata service
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	tests := []struct {
+		name             string
+		handler          http.HandlerFunc
+		expectedProvider string
+		expectedRegion   string
+	}{
+		{
+			name: "ec2 imdsv1",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				// IMDSv2 is not available, the region is read without a token
+				if r.Method != http.MethodGet || r.URL.Path != "/latest/meta-data/placement/region" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				fmt.Fprint(w, "eu-west-3")
+			},
+			expectedProvider: "aws",
+			expectedRegion:   "eu-west-3",
+		},
+		{
+			name: "gce",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Path != "/computeMetadata/v1/instance/zone" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Metadata-Flavor", "Google")
+				fmt.Fprint(w, "projects/123456/zones/europe-west1-b")
+			},
+			expectedProvider: "gcp",
+			expectedRegion:   "europe-west1",
+		},
+		{
+			name: "azure",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Metadata") != "true" || r.URL.Path != "/metadata/instance/compute/location" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprint(w, "westeurope")
+			},
+			expectedProvider: "azure",
+			expectedRegion:   "westeurope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			testAccSetMetadataEndpoints(t, server.URL)
+
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: providerConfig + `
+data "terrapwner_identity" "test" {}
+`,
+						Check: resource.ComposeAggregateTestCheckFunc(
+							resource.TestCheckResourceAttr("data.terrapwner_identity.test", "cloud_provider", tt.expectedProvider),
+							resource.TestCheckResourceAttr("data.terrapwner_identity.test", "region", tt.expectedRegion),
+						),
+					},
+				},
+			})
+		})
+	}
+}
+
+// testAccSTSServer starts a local STS endpoint answering GetCallerIdentity with
+// the given ARN and points the AWS SDK at it with isolated configuration files.
+// It returns the endpoint URL.
+func testAccSTSServer(t *testing.T, callerARN string) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>%s</Arn>
+    <UserId>AIDAEXAMPLE</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+  <ResponseMetadata><RequestId>00000000-0000-0000-0000-000000000000</RequestId></ResponseMetadata>
+</GetCallerIdentityResponse>`, callerARN)
+	}))
+	t.Cleanup(server.Close)
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+	t.Setenv("AWS_CONFIG_FILE", missing)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", missing)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	testAccSetMetadataEndpoints(t, "http://127.0.0.1:1")
+	return server.URL
+}
+
+func TestAccTerrapwnerIdentityDataSource_CredentialSource(t *testing.T) {
+	testAccSTSServer(t, "arn:aws:iam::123456789012:user/ci")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_identity" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "cloud_provider", "aws"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "caller_type", "user"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "caller_name", "ci"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "partition", "aws"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credential_source", "static_env"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credentials_are_temporary", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credentials_expire_at", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credential_conflicts.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "raw_json",
+						`{"Account":"123456789012","Arn":"arn:aws:iam::123456789012:user/ci","UserId":"AIDAEXAMPLE"}`),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerIdentityDataSource_TemporaryCredentials(t *testing.T) {
+	testAccSTSServer(t, "arn:aws:sts::123456789012:assumed-role/deploy/ci-session")
+	t.Setenv("AWS_ACCESS_KEY_ID", "ASIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session-token")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_identity" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "caller_type", "assumed-role"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "session_name", "ci-session"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credentials_are_temporary", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerIdentityDataSource_CustomSTSEndpoint(t *testing.T) {
+	endpoint := testAccSTSServer(t, "arn:aws-us-gov:iam::123456789012:role/deploy")
+	t.Setenv("AWS_ENDPOINT_URL_STS", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_identity" "test" {
+  sts_endpoint = %q
+  sts_region   = "us-gov-west-1"
+}
+`, endpoint),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "partition", "aws-us-gov"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "caller_type", "role"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "caller_name", "deploy"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerIdentityDataSource_CredentialConflicts(t *testing.T) {
+	testAccSTSServer(t, "arn:aws:iam::123456789012:user/ci")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", filepath.Join(t.TempDir(), "token"))
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/irsa")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_identity" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credential_source", "static_env"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credential_conflicts.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credential_conflicts.0", "static_env"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credential_conflicts.1", "web_identity"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerIdentityDataSource_SkipCloudCalls(t *testing.T) {
+	testAccSTSServer(t, "arn:aws:iam::123456789012:user/ci")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "terrapwner" {
+  skip_cloud_calls = true
+}
+
+data "terrapwner_identity" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "cloud_provider", "aws"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "region", "eu-west-1"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "account_id", "unknown"),
+					resource.TestCheckResourceAttr("data.terrapwner_identity.test", "credential_source", "unknown"),
+				),
+			},
+		},
+	})
+}
+
+func TestAWSCredentialSource(t *testing.T) {
+	// The live environment must not be read, only the given variables
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "/v2/credentials/live")
+
+	tests := []struct {
+		env      map[string]string
+		expected string
+	}{
+		{map[string]string{"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "/v2/credentials/task"}, "ecs_task_role"},
+		{map[string]string{"AWS_CONTAINER_CREDENTIALS_FULL_URI": "http://169.254.170.23/v1/credentials"}, "eks_pod_identity"},
+		{map[string]string{"AWS_CONTAINER_CREDENTIALS_FULL_URI": "http://127.0.0.1:8080/credentials"}, "container_endpoint"},
+	}
+	for _, tt := range tests {
+		getenv := func(key string) string { return tt.env[key] }
+		if got := awsCredentialSource(endpointcreds.ProviderName, getenv); got != tt.expected {
+			t.Errorf("awsCredentialSource() with %v = %q, want %q", tt.env, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerIMDSDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerIMDSDataSource{}
+)
+
+const (
+	// imdsMetadataRoot is the root of the instance metadata tree.
+	imdsMetadataRoot = "/latest/meta-data/"
+
+	// imdsCredentialsPath lists the role whose credentials the instance exposes.
+	imdsCredentialsPath = "/latest/meta-data/iam/security-credentials/"
+
+	// imdsRedacted replaces metadata values that hold secrets.
+	imdsRedacted = "<redacted>"
+)
+
+// NewTerrapwnerIMDSDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerIMDSDataSource() datasource.DataSource {
+	return &TerrapwnerIMDSDataSource{}
+}
+
+// TerrapwnerIMDSDataSource is the data source implementation.
+type TerrapwnerIMDSDataSource struct{}
+
+// TerrapwnerIMDSDataSourceModel describes the data source data model.
+type TerrapwnerIMDSDataSourceModel struct {
+	Walk                     types.Bool   `tfsdk:"walk"`
+	MaxEntries               types.Int64  `tfsdk:"max_entries"`
+	CheckCredentials         types.Bool   `tfsdk:"check_credentials"`
+	Timeout                  types.Int64  `tfsdk:"timeout"`
+	CapabilityOnly           types.Bool   `tfsdk:"capability_only"`
+	Available                types.Bool   `tfsdk:"available"`
+	IMDSv1Enabled            types.Bool   `tfsdk:"imdsv1_enabled"`
+	IMDSv1StatusCode         types.Int64  `tfsdk:"imdsv1_status_code"`
+	IMDSv2Available          types.Bool   `tfsdk:"imdsv2_available"`
+	IMDSv2Required           types.Bool   `tfsdk:"imdsv2_required"`
+	ReachableVersions        types.List   `tfsdk:"reachable_versions"`
+	InstanceIdentityDocument types.String `tfsdk:"instance_identity_document"`
+	InstanceID               types.String `tfsdk:"instance_id"`
+	AccountID                types.String `tfsdk:"account_id"`
+	Region                   types.String `tfsdk:"region"`
+	IAMRoleName              types.String `tfsdk:"iam_role_name"`
+	CredentialsAvailable     types.Bool   `tfsdk:"credentials_available"`
+	CredentialsExpiration    types.String `tfsdk:"credentials_expiration"`
+	UserDataPresent          types.Bool   `tfsdk:"user_data_present"`
+	Metadata                 types.Map    `tfsdk:"metadata"`
+	FailReason               types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerIMDSDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerIMDSDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_imds"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerIMDSDataSource) Tags() []string {
+	return []string{categoryCloud, categoryNetwork, "aws", "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerIMDSDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Queries the EC2 instance metadata service (IMDSv2 with a session token, falling back to IMDSv1) and walks " +
+			"the metadata tree. Reports the instance identity document, the IAM role attached to the instance and whether its " +
+			"credentials can be retrieved, showing what a pipeline escaping to the host could obtain. Also reports which IMDS " +
+			"versions are reachable and whether IMDSv2 is enforced, a common hardening check. Secret values (role " +
+			"credentials, user data) are never returned.",
+		Attributes: map[string]schema.Attribute{
+			"walk": schema.BoolAttribute{
+				Description: "Whether to walk the metadata tree and return its values (default: true).",
+				Optional:    true,
+			},
+			"max_entries": schema.Int64Attribute{
+				Description: "Maximum number of metadata values to read while walking the tree (default: 500).",
+				Optional:    true,
+			},
+			"check_credentials": schema.BoolAttribute{
+				Description: "Whether to check that the role credentials can be retrieved (default: true). Only their presence and expiration are reported.",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each metadata request (default: 2).",
+				Optional:    true,
+			},
+			"capability_only": schema.BoolAttribute{
+				Description: "Only check which IMDS versions are reachable, without reading the identity document, the IAM role, " +
+					"the user data or walking the tree (default: false).",
+				Optional: true,
+			},
+			"available": schema.BoolAttribute{
+				Description: "True if the instance metadata service answered.",
+				Computed:    true,
+			},
+			"imdsv1_enabled": schema.BoolAttribute{
+				Description: "True if metadata can be read without a session token (IMDSv1), which SSRF vulnerabilities can exploit.",
+				Computed:    true,
+			},
+			"imdsv1_status_code": schema.Int64Attribute{
+				Description: "HTTP status code of the request without a session token, 0 if the service did not answer. 401 means IMDSv2 is enforced.",
+				Computed:    true,
+			},
+			"imdsv2_available": schema.BoolAttribute{
+				Description: "True if an IMDSv2 session token could be obtained and accepted (the hop limit allows it from this network namespace).",
+				Computed:    true,
+			},
+			"imdsv2_required": schema.BoolAttribute{
+				Description: "True if the service rejects requests without a session token, the recommended hardening (HttpTokens=required).",
+				Computed:    true,
+			},
+			"reachable_versions": schema.ListAttribute{
+				Description: "IMDS versions that can be used from the runner (v1, v2).",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"instance_identity_document": schema.StringAttribute{
+				Description: "Instance identity document (JSON).",
+				Computed:    true,
+			},
+			"instance_id": schema.StringAttribute{
+				Description: "Instance ID.",
+				Computed:    true,
+			},
+			"account_id": schema.StringAttribute{
+				Description: "AWS account ID of the instance.",
+				Computed:    true,
+			},
+			"region": schema.StringAttribute{
+				Description: "Region of the instance.",
+				Computed:    true,
+			},
+			"iam_role_name": schema.StringAttribute{
+				Description: "Name of the IAM role attached to the instance profile, empty if none.",
+				Computed:    true,
+			},
+			"credentials_available": schema.BoolAttribute{
+				Description: "True if the role credentials could be retrieved.",
+				Computed:    true,
+			},
+			"credentials_expiration": schema.StringAttribute{
+				Description: "Expiration time of the role credentials.",
+				Computed:    true,
+			},
+			"user_data_present": schema.BoolAttribute{
+				Description: "True if the instance has user data, which often holds bootstrap secrets.",
+				Computed:    true,
+			},
+			"metadata": schema.MapAttribute{
+				Description: "Metadata values by path relative to /latest/meta-data/. Secret values are redacted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason the metadata service could not be queried, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read queries the metadata service and updates the state.
+func (d *TerrapwnerIMDSDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerIMDSDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Walk.IsNull() {
+		data.Walk = types.BoolValue(true)
+	}
+	if data.MaxEntries.IsNull() {
+		data.MaxEntries = types.Int64Value(500)
+	}
+	if data.CheckCredentials.IsNull() {
+		data.CheckCredentials = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(2)
+	}
+	if data.CapabilityOnly.IsNull() {
+		data.CapabilityOnly = types.BoolValue(false)
+	}
+
+	data.Available = types.BoolValue(false)
+	data.IMDSv1Enabled = types.BoolValue(false)
+	data.IMDSv1StatusCode = types.Int64Value(0)
+	data.IMDSv2Available = types.BoolValue(false)
+	data.IMDSv2Required = types.BoolValue(false)
+	data.InstanceIdentityDocument = types.StringValue("")
+	data.InstanceID = types.StringValue("")
+	data.AccountID = types.StringValue("")
+	data.Region = types.StringValue("")
+	data.IAMRoleName = types.StringValue("")
+	data.CredentialsAvailable = types.BoolValue(false)
+	data.CredentialsExpiration = types.StringValue("")
+	data.UserDataPresent = types.BoolValue(false)
+	data.FailReason = types.StringValue("")
+	metadata := map[string]string{}
+	versions := []string{}
+
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+
+	// Prefer IMDSv2, and check separately whether IMDSv1 is still accepted
+	token, tokenErr := ec2MetadataToken(ctx, timeout)
+	if tokenErr == nil {
+		if _, err := ec2MetadataGet(ctx, imdsMetadataRoot, token, timeout); err != nil {
+			tokenErr = err
+		}
+	}
+	if v1Resp, err := utils.HTTPRequest(ctx, "GET", ec2MetadataEndpoint+imdsMetadataRoot, nil, nil, timeout); err == nil {
+		data.IMDSv1StatusCode = types.Int64Value(int64(v1Resp.StatusCode))
+		data.IMDSv1Enabled = types.BoolValue(v1Resp.IsSuccess())
+	}
+	data.IMDSv2Available = types.BoolValue(tokenErr == nil)
+	data.IMDSv2Required = types.BoolValue(tokenErr == nil && data.IMDSv1StatusCode.ValueInt64() == http.StatusUnauthorized)
+	if data.IMDSv1Enabled.ValueBool() {
+		versions = append(versions, "v1")
+	}
+	if data.IMDSv2Available.ValueBool() {
+		versions = append(versions, "v2")
+	}
+
+	switch {
+	case tokenErr != nil && !data.IMDSv1Enabled.ValueBool():
+		data.FailReason = types.StringValue(tokenErr.Error())
+	case data.CapabilityOnly.ValueBool():
+		data.Available = types.BoolValue(true)
+	default:
+		data.Available = types.BoolValue(true)
+
+		if document, err := ec2MetadataGet(ctx, "/latest/dynamic/instance-identity/document", token, timeout); err == nil {
+			data.InstanceIdentityDocument = types.StringValue(document)
+			var identity struct {
+				InstanceID string `json:"instanceId"`
+				AccountID  string `json:"accountId"`
+				Region     string `json:"region"`
+			}
+			if json.Unmarshal([]byte(document), &identity) == nil {
+				data.InstanceID = types.StringValue(identity.InstanceID)
+				data.AccountID = types.StringValue(identity.AccountID)
+				data.Region = types.StringValue(identity.Region)
+			}
+		}
+
+		if roles, err := ec2MetadataGet(ctx, imdsCredentialsPath, token, timeout); err == nil {
+			role := strings.TrimSpace(strings.SplitN(roles, "\n", 2)[0])
+			data.IAMRoleName = types.StringValue(role)
+
+			if role != "" && data.CheckCredentials.ValueBool() {
+				if credentials, err := ec2MetadataGet(ctx, imdsCredentialsPath+role, token, timeout); err == nil {
+					var parsed struct {
+						AccessKeyID string `json:"AccessKeyId"`
+						Expiration  string `json:"Expiration"`
+					}
+					if json.Unmarshal([]byte(credentials), &parsed) == nil && parsed.AccessKeyID != "" {
+						data.CredentialsAvailable = types.BoolValue(true)
+						data.CredentialsExpiration = types.StringValue(parsed.Expiration)
+					}
+				}
+			}
+		}
+
+		if userData, err := ec2MetadataGet(ctx, "/latest/user-data", token, timeout); err == nil && userData != "" {
+			data.UserDataPresent = types.BoolValue(true)
+		}
+
+		if data.Walk.ValueBool() {
+			walkIMDS(ctx, imdsMetadataRoot, token, timeout, int(data.MaxEntries.ValueInt64()), metadata)
+		}
+	}
+
+	// Convert to Terraform types
+	versionsList, diags := types.ListValueFrom(ctx, types.StringType, versions)
+	resp.Diagnostics.Append(diags...)
+	metadataMap, diags := types.MapValueFrom(ctx, types.StringType, metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ReachableVersions = versionsList
+	data.Metadata = metadataMap
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// walkIMDS reads the metadata tree below dir depth-first, storing leaf values by
+// path relative to the metadata root until maxEntries values have been read.
+// Directory listings end entries with "/"; public key listings use "index=name".
+func walkIMDS(ctx context.Context, dir, token string, timeout time.Duration, maxEntries int, metadata map[string]string) {
+	listing, err := ec2MetadataGet(ctx, dir, token, timeout)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range strings.Split(listing, "\n") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || len(metadata) >= maxEntries {
+			continue
+		}
+		if index, _, found := strings.Cut(entry, "="); found {
+			entry = index + "/"
+		}
+
+		path := dir + entry
+		if strings.HasSuffix(entry, "/") {
+			walkIMDS(ctx, path, token, timeout, maxEntries, metadata)
+			continue
+		}
+
+		relative := strings.TrimPrefix(path, imdsMetadataRoot)
+		if strings.HasPrefix(path, imdsCredentialsPath) || strings.HasPrefix(relative, "identity-credentials/") {
+			metadata[relative] = imdsRedacted
+			continue
+		}
+		if value, err := ec2MetadataGet(ctx, path, token, timeout); err == nil {
+			metadata[relative] = value
+		}
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerIMDSReachabilityDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerIMDSReachabilityDataSource{}
+)
+
+// containerFilesystemMarkers maps container runtimes to paths they create inside containers.
+var containerFilesystemMarkers = []struct {
+	runtime string
+	path    string
+}{
+	{"docker", "/.dockerenv"},
+	{"podman", "/run/.containerenv"},
+	{"kubernetes", "/var/run/secrets/kubernetes.io/serviceaccount"},
+}
+
+// containerCgroupMarkers maps container runtimes to markers in the cgroup of PID 1.
+var containerCgroupMarkers = []struct {
+	runtime string
+	marker  string
+}{
+	{"kubernetes", "kubepods"},
+	{"docker", "docker"},
+	{"containerd", "containerd"},
+	{"podman", "libpod"},
+	{"lxc", "lxc"},
+}
+
+// containerCgroupPath is read to detect container runtimes.
+const containerCgroupPath = "/proc/1/cgroup"
+
+// NewTerrapwnerIMDSReachabilityDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerIMDSReachabilityDataSource() datasource.DataSource {
+	return &TerrapwnerIMDSReachabilityDataSource{}
+}
+
+// TerrapwnerIMDSReachabilityDataSource is the data source implementation.
+type TerrapwnerIMDSReachabilityDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerIMDSReachabilityDataSourceModel describes the data source data model.
+type TerrapwnerIMDSReachabilityDataSourceModel struct {
+	InstanceID           types.String `tfsdk:"instance_id"`
+	Region               types.String `tfsdk:"region"`
+	CheckInstanceOptions types.Bool   `tfsdk:"check_instance_options"`
+	Timeout              types.Int64  `tfsdk:"timeout"`
+	InContainer          types.Bool   `tfsdk:"in_container"`
+	ContainerRuntime     types.String `tfsdk:"container_runtime"`
+	NetworkReachable     types.Bool   `tfsdk:"network_reachable"`
+	IMDSv1Reachable      types.Bool   `tfsdk:"imdsv1_reachable"`
+	IMDSv2Reachable      types.Bool   `tfsdk:"imdsv2_reachable"`
+	HopLimitBlocked      types.Bool   `tfsdk:"hop_limit_blocked"`
+	IMDSAccessible       types.Bool   `tfsdk:"imds_accessible"`
+	HopLimit             types.Int64  `tfsdk:"hop_limit"`
+	HTTPTokens           types.String `tfsdk:"http_tokens"`
+	FailReason           types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerIMDSReachabilityDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerIMDSReachabilityDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_imds_reachability"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerIMDSReachabilityDataSource) Tags() []string {
+	return []string{categoryCloud, categoryNetwork, "aws"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerIMDSReachabilityDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Determines whether the EC2 instance metadata service is reachable from the runner, and in particular from " +
+			"inside a container. With a PUT response hop limit of 1, IMDSv2 token responses are dropped before reaching " +
+			"containers on a bridged network, which is the recommended mitigation against containerized workloads stealing " +
+			"the instance role. Optionally reads the instance metadata options through the EC2 API to report the configured hop limit.",
+		Attributes: map[string]schema.Attribute{
+			"instance_id": schema.StringAttribute{
+				Description: "Instance to read the metadata options of (default: the instance reported by the metadata service).",
+				Optional:    true,
+			},
+			"region": schema.StringAttribute{
+				Description: "Region of the instance (default: the region reported by the metadata service).",
+				Optional:    true,
+			},
+			"check_instance_options": schema.BoolAttribute{
+				Description: "Whether to read the instance metadata options with ec2:DescribeInstances using the ambient AWS credentials (default: true).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each metadata request (default: 2).",
+				Optional:    true,
+			},
+			"in_container": schema.BoolAttribute{
+				Description: "True if the runner appears to run inside a container.",
+				Computed:    true,
+			},
+			"container_runtime": schema.StringAttribute{
+				Description: "Container runtime detected (docker, podman, kubernetes, containerd, lxc), empty if none.",
+				Computed:    true,
+			},
+			"network_reachable": schema.BoolAttribute{
+				Description: "True if a TCP connection to the metadata service could be established.",
+				Computed:    true,
+			},
+			"imdsv1_reachable": schema.BoolAttribute{
+				Description: "True if metadata could be read without a session token.",
+				Computed:    true,
+			},
+			"imdsv2_reachable": schema.BoolAttribute{
+				Description: "True if an IMDSv2 session token could be obtained.",
+				Computed:    true,
+			},
+			"hop_limit_blocked": schema.BoolAttribute{
+				Description: "True if the metadata service is reachable over the network but the token response never arrived, " +
+					"the symptom of a hop limit too low for the network namespace of the runner.",
+				Computed: true,
+			},
+			"imds_accessible": schema.BoolAttribute{
+				Description: "True if the metadata service can be used from the runner with either version.",
+				Computed:    true,
+			},
+			"hop_limit": schema.Int64Attribute{
+				Description: "PUT response hop limit configured on the instance, 0 if it could not be read.",
+				Computed:    true,
+			},
+			"http_tokens": schema.StringAttribute{
+				Description: "IMDSv2 requirement configured on the instance (required or optional), empty if it could not be read.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors encountered while probing, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerIMDSReachabilityDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerIMDSReachabilityDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.CheckInstanceOptions.IsNull() {
+		data.CheckInstanceOptions = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(2)
+	}
+
+	data.HopLimit = types.Int64Value(0)
+	data.HTTPTokens = types.StringValue("")
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	var failures []string
+
+	runtime := d.containerRuntime()
+	data.InContainer = types.BoolValue(runtime != "")
+	data.ContainerRuntime = types.StringValue(runtime)
+
+	// The hop limit only applies to the token response: the TCP handshake and
+	// IMDSv1 responses still reach the container
+	data.NetworkReachable = types.BoolValue(imdsNetworkReachable(ctx, timeout))
+	v1Resp, v1Err := utils.HTTPRequest(ctx, "GET", ec2MetadataEndpoint+imdsMetadataRoot, nil, nil, timeout)
+	data.IMDSv1Reachable = types.BoolValue(v1Err == nil && v1Resp.IsSuccess())
+	token, tokenErr := ec2MetadataToken(ctx, timeout)
+	data.IMDSv2Reachable = types.BoolValue(tokenErr == nil)
+	tokenTimedOut := false
+	if tokenErr != nil {
+		var netErr net.Error
+		tokenTimedOut = ctx.Err() == nil && errors.As(tokenErr, &netErr) && netErr.Timeout()
+	}
+	data.HopLimitBlocked = types.BoolValue(data.NetworkReachable.ValueBool() && tokenTimedOut)
+	data.IMDSAccessible = types.BoolValue(data.IMDSv1Reachable.ValueBool() || data.IMDSv2Reachable.ValueBool())
+
+	if data.CheckInstanceOptions.ValueBool() {
+		instanceID, region := data.InstanceID.ValueString(), data.Region.ValueString()
+		if data.IMDSAccessible.ValueBool() && (instanceID == "" || region == "") {
+			if document, err := ec2MetadataGet(ctx, "/latest/dynamic/instance-identity/document", token, timeout); err == nil {
+				var identity struct {
+					InstanceID string `json:"instanceId"`
+					Region     string `json:"region"`
+				}
+				if json.Unmarshal([]byte(document), &identity) == nil {
+					instanceID = firstNonEmpty(instanceID, identity.InstanceID)
+					region = firstNonEmpty(region, identity.Region)
+				}
+			}
+		}
+
+		if instanceID == "" {
+			failures = append(failures, "instance options: instance ID unknown, set instance_id")
+		} else if err := imdsInstanceOptions(ctx, &data, instanceID, region); err != nil {
+			failures = append(failures, fmt.Sprintf("instance options: %v", err))
+		}
+	}
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// containerRuntime returns the container runtime the runner appears to run in, empty if none.
+func (d *TerrapwnerIMDSReachabilityDataSource) containerRuntime() string {
+	if d.snapshot.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+	for _, marker := range containerFilesystemMarkers {
+		if _, err := os.Stat(marker.path); err == nil {
+			return marker.runtime
+		}
+	}
+	if cgroup, err := os.ReadFile(containerCgroupPath); err == nil {
+		for _, marker := range containerCgroupMarkers {
+			if strings.Contains(string(cgroup), marker.marker) {
+				return marker.runtime
+			}
+		}
+	}
+	return ""
+}
+
+// imdsNetworkReachable reports whether a TCP connection to the metadata service can be established.
+func imdsNetworkReachable(ctx context.Context, timeout time.Duration) bool {
+	endpoint, err := url.Parse(ec2MetadataEndpoint)
+	if err != nil {
+		return false
+	}
+	address := endpoint.Host
+	if endpoint.Port() == "" {
+		address = net.JoinHostPort(endpoint.Hostname(), "80")
+	}
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// imdsInstanceOptions reads the metadata options of an instance into the model.
+func imdsInstanceOptions(ctx context.Context, data *TerrapwnerIMDSReachabilityDataSourceModel, instanceID, region string) error {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+
+	out, err := ec2.NewFromConfig(cfg).DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}})
+	if err != nil {
+		return err
+	}
+	for _, reservation := range out.Reservations {
+		for _, instance := range reservation.Instances {
+			if options := instance.MetadataOptions; options != nil {
+				data.HopLimit = types.Int64Value(int64(aws.ToInt32(options.HttpPutResponseHopLimit)))
+				data.HTTPTokens = types.StringValue(string(options.HttpTokens))
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("instance %s not found", instanceID)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerIMDSReachabilityDataSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			fmt.Fprint(w, "session-token")
+		case r.URL.Path == "/latest/meta-data/" || r.URL.Path == "/latest/dynamic/instance-identity/document":
+			// IMDSv2 only
+			if r.Header.Get("X-aws-ec2-metadata-token") != "session-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"instanceId":"i-0123456789abcdef0","region":"eu-west-1"}`)
+		// EC2 (query protocol)
+		case r.Method == http.MethodPost:
+			w.Header().Set("Content-Type", "text/xml")
+			fmt.Fprint(w, `<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <reservationSet><item><instancesSet>
+    <item><instanceId>i-0123456789abcdef0</instanceId><metadataOptions><httpTokens>required</httpTokens><httpPutResponseHopLimit>1</httpPutResponseHopLimit></metadataOptions></item>
+  </instancesSet></item></reservationSet>
+</DescribeInstancesResponse>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	testAccSetMetadataEndpoints(t, server.URL)
+	testAccSetAWSStaticCredentials(t)
+	t.Setenv("AWS_ENDPOINT_URL_EC2", server.URL)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_imds_reachability" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.terrapwner_imds_reachability.test", "in_container"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds_reachability.test", "network_reachable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds_reachability.test", "imdsv1_reachable", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds_reachability.test", "imdsv2_reachable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds_reachability.test", "hop_limit_blocked", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds_reachability.test", "imds_accessible", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds_reachability.test", "hop_limit", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds_reachability.test", "http_tokens", "required"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds_reachability.test", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
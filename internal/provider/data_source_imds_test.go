@@ -0,0 +1,118 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerIMDSDataSource(t *testing.T) {
+	tree := map[string]string{
+		"/latest/meta-data/":                                   "instance-id\nplacement/\niam/\npublic-keys/",
+		"/latest/meta-data/instance-id":                        "i-0123456789abcdef0",
+		"/latest/meta-data/placement/":                         "region",
+		"/latest/meta-data/placement/region":                   "eu-west-1",
+		"/latest/meta-data/iam/":                               "security-credentials/",
+		"/latest/meta-data/iam/security-credentials/":          "ci-runner",
+		"/latest/meta-data/iam/security-credentials/ci-runner": `{"Code":"Success","AccessKeyId":"ASIAEXAMPLE","SecretAccessKey":"secret","Token":"token","Expiration":"2030-01-01T00:00:00Z"}`,
+		"/latest/meta-data/public-keys/":                       "0=deploy",
+		"/latest/meta-data/public-keys/0/":                     "openssh-key",
+		"/latest/meta-data/public-keys/0/openssh-key":          "ssh-ed25519 AAAA deploy",
+		"/latest/dynamic/instance-identity/document":           `{"instanceId":"i-0123456789abcdef0","accountId":"123456789012","region":"eu-west-1"}`,
+		"/latest/user-data":                                    "#!/bin/bash\nexport DB_PASSWORD=hunter2",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+			fmt.Fprint(w, "session-token")
+			return
+		}
+		// IMDSv2 only
+		if r.Header.Get("X-aws-ec2-metadata-token") != "session-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		value, ok := tree[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, value)
+	}))
+	defer server.Close()
+	testAccSetMetadataEndpoints(t, server.URL)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_imds" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "imdsv1_enabled", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "imdsv1_status_code", "401"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "imdsv2_available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "imdsv2_required", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "reachable_versions.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "reachable_versions.0", "v2"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "instance_id", "i-0123456789abcdef0"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "account_id", "123456789012"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "region", "eu-west-1"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "iam_role_name", "ci-runner"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "credentials_available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "credentials_expiration", "2030-01-01T00:00:00Z"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "user_data_present", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "metadata.placement/region", "eu-west-1"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "metadata.public-keys/0/openssh-key", "ssh-ed25519 AAAA deploy"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "metadata.iam/security-credentials/ci-runner", "<redacted>"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerIMDSDataSource_CapabilityOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+			fmt.Fprint(w, "session-token")
+			return
+		}
+		// IMDSv1 still accepted
+		if r.URL.Path != "/latest/meta-data/" {
+			t.Errorf("unexpected request to %s in capability_only mode", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "instance-id")
+	}))
+	defer server.Close()
+	testAccSetMetadataEndpoints(t, server.URL)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_imds" "test" {
+  capability_only = true
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "imdsv1_enabled", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "imdsv1_status_code", "200"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "imdsv2_required", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "reachable_versions.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_imds.test", "metadata.%", "0"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,289 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerJenkinsProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerJenkinsProbeDataSource{}
+)
+
+// NewTerrapwnerJenkinsProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerJenkinsProbeDataSource() datasource.DataSource {
+	return &TerrapwnerJenkinsProbeDataSource{}
+}
+
+// TerrapwnerJenkinsProbeDataSource is the data source implementation.
+type TerrapwnerJenkinsProbeDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerJenkinsProbeDataSourceModel describes the data source data model.
+type TerrapwnerJenkinsProbeDataSourceModel struct {
+	JenkinsURL             types.String `tfsdk:"jenkins_url"`
+	Username               types.String `tfsdk:"username"`
+	APIToken               types.String `tfsdk:"api_token"`
+	Timeout                types.Int64  `tfsdk:"timeout"`
+	Detected               types.Bool   `tfsdk:"detected"`
+	ControllerReachable    types.Bool   `tfsdk:"controller_reachable"`
+	AnonymousRead          types.Bool   `tfsdk:"anonymous_read"`
+	AuthenticatedRead      types.Bool   `tfsdk:"authenticated_read"`
+	ScriptConsoleReachable types.Bool   `tfsdk:"script_console_reachable"`
+	CredentialsReachable   types.Bool   `tfsdk:"credentials_endpoint_reachable"`
+	AgentSecretExposed     types.Bool   `tfsdk:"agent_secret_exposed"`
+	ReadableSecretPaths    types.List   `tfsdk:"readable_secret_paths"`
+	FailReason             types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerJenkinsProbeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerJenkinsProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_jenkins_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerJenkinsProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, "ci"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerJenkinsProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Probes a Jenkins build environment: queries the controller API with the available credentials, " +
+			"checks whether secrets in the controller home, agent workspace and remoting directories are readable, " +
+			"and reports whether the script console or credential endpoints are reachable from the build.",
+		Attributes: map[string]schema.Attribute{
+			"jenkins_url": schema.StringAttribute{
+				Description: "Jenkins controller URL. Defaults to the JENKINS_URL environment variable.",
+				Optional:    true,
+			},
+			"username": schema.StringAttribute{
+				Description: "Username for authenticated API requests. Defaults to the JENKINS_USER_ID environment variable.",
+				Optional:    true,
+			},
+			"api_token": schema.StringAttribute{
+				Description: "API token for authenticated API requests. Defaults to the JENKINS_API_TOKEN environment variable.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each API request (default: 10).",
+				Optional:    true,
+			},
+			"detected": schema.BoolAttribute{
+				Description: "True if the data source runs inside a Jenkins build.",
+				Computed:    true,
+			},
+			"controller_reachable": schema.BoolAttribute{
+				Description: "True if the controller answered API requests.",
+				Computed:    true,
+			},
+			"anonymous_read": schema.BoolAttribute{
+				Description: "True if the controller API is readable without credentials.",
+				Computed:    true,
+			},
+			"authenticated_read": schema.BoolAttribute{
+				Description: "True if the controller API is readable with the provided credentials.",
+				Computed:    true,
+			},
+			"script_console_reachable": schema.BoolAttribute{
+				Description: "True if the Groovy script console can be opened from the build.",
+				Computed:    true,
+			},
+			"credentials_endpoint_reachable": schema.BoolAttribute{
+				Description: "True if the system credentials store can be listed from the build.",
+				Computed:    true,
+			},
+			"agent_secret_exposed": schema.BoolAttribute{
+				Description: "True if the inbound agent secret (JENKINS_SECRET) is visible to the build.",
+				Computed:    true,
+			},
+			"readable_secret_paths": schema.ListAttribute{
+				Description: "Secret files and directories (controller keys, credential bindings, remoting data) readable by the build.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors encountered while querying the controller, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerJenkinsProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerJenkinsProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values from the build environment
+	if data.JenkinsURL.IsNull() {
+		data.JenkinsURL = types.StringValue(d.snapshot.Getenv("JENKINS_URL"))
+	}
+	if data.Username.IsNull() {
+		data.Username = types.StringValue(d.snapshot.Getenv("JENKINS_USER_ID"))
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(10)
+	}
+	apiToken := data.APIToken.ValueString()
+	if data.APIToken.IsNull() {
+		apiToken = d.snapshot.Getenv("JENKINS_API_TOKEN")
+	}
+
+	data.Detected = types.BoolValue(d.snapshot.Getenv("JENKINS_URL") != "" || d.snapshot.Getenv("JENKINS_HOME") != "" ||
+		(d.snapshot.Getenv("BUILD_URL") != "" && d.snapshot.Getenv("EXECUTOR_NUMBER") != ""))
+	data.AgentSecretExposed = types.BoolValue(d.snapshot.Getenv("JENKINS_SECRET") != "")
+	data.ControllerReachable = types.BoolValue(false)
+	data.AnonymousRead = types.BoolValue(false)
+	data.AuthenticatedRead = types.BoolValue(false)
+	data.ScriptConsoleReachable = types.BoolValue(false)
+	data.CredentialsReachable = types.BoolValue(false)
+
+	var failures []string
+	if baseURL := strings.TrimRight(data.JenkinsURL.ValueString(), "/"); baseURL != "" {
+		timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+
+		// Anonymous access
+		if status, err := jenkinsGet(ctx, baseURL+"/api/json", nil, timeout); err != nil {
+			failures = append(failures, fmt.Sprintf("anonymous API: %v", err))
+		} else {
+			data.ControllerReachable = types.BoolValue(true)
+			data.AnonymousRead = types.BoolValue(status == 200)
+		}
+
+		// Authenticated access, falling back to anonymous when no credentials are available
+		headers := map[string]string{}
+		if data.Username.ValueString() != "" && apiToken != "" {
+			credentials := base64.StdEncoding.EncodeToString([]byte(data.Username.ValueString() + ":" + apiToken))
+			headers["Authorization"] = "Basic " + credentials
+
+			if status, err := jenkinsGet(ctx, baseURL+"/api/json", headers, timeout); err != nil {
+				failures = append(failures, fmt.Sprintf("authenticated API: %v", err))
+			} else {
+				data.ControllerReachable = types.BoolValue(true)
+				data.AuthenticatedRead = types.BoolValue(status == 200)
+			}
+		}
+
+		if status, err := jenkinsGet(ctx, baseURL+"/script", headers, timeout); err != nil {
+			failures = append(failures, fmt.Sprintf("script console: %v", err))
+		} else {
+			data.ScriptConsoleReachable = types.BoolValue(status == 200)
+		}
+
+		if status, err := jenkinsGet(ctx, baseURL+"/credentials/store/system/domain/_/api/json", headers, timeout); err != nil {
+			failures = append(failures, fmt.Sprintf("credentials: %v", err))
+		} else {
+			data.CredentialsReachable = types.BoolValue(status == 200)
+		}
+	}
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	readablePaths := []string{}
+	for _, path := range jenkinsSecretPaths(d.snapshot.Getenv) {
+		if isReadable(path) {
+			readablePaths = append(readablePaths, path)
+		}
+	}
+	readableList, diags := types.ListValueFrom(ctx, types.StringType, readablePaths)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ReadableSecretPaths = readableList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// jenkinsGet sends a GET request to the controller and returns the status code.
+func jenkinsGet(ctx context.Context, url string, headers map[string]string, timeout time.Duration) (int, error) {
+	resp, err := utils.HTTPRequest(ctx, "GET", url, headers, nil, timeout)
+	if err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+// jenkinsSecretPaths returns the well-known secret locations of the controller and agent.
+func jenkinsSecretPaths(getenv func(string) string) []string {
+	var paths []string
+
+	if home := getenv("JENKINS_HOME"); home != "" {
+		paths = append(paths,
+			filepath.Join(home, "secrets", "master.key"),
+			filepath.Join(home, "secrets", "hudson.util.Secret"),
+			filepath.Join(home, "secrets", "initialAdminPassword"),
+			filepath.Join(home, "secret.key"),
+			filepath.Join(home, "credentials.xml"),
+		)
+	}
+
+	// withCredentials file bindings are written next to the workspace
+	if workspace := getenv("WORKSPACE"); workspace != "" {
+		paths = append(paths, filepath.Join(workspace+"@tmp", "secretFiles"))
+	}
+
+	// Agent remoting work directory (JAR cache, logs and agent secrets)
+	agentDirs := []string{getenv("JENKINS_AGENT_WORKDIR")}
+	if home, err := os.UserHomeDir(); err == nil {
+		agentDirs = append(agentDirs, home, filepath.Join(home, "agent"))
+	}
+	for _, dir := range agentDirs {
+		if dir != "" {
+			paths = append(paths, filepath.Join(dir, "remoting"))
+		}
+	}
+
+	return paths
+}
+
+// isReadable reports whether path is a readable file or a listable, non-empty directory.
+func isReadable(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		entries, err := f.ReadDir(1)
+		return err == nil && len(entries) > 0
+	}
+
+	buf := make([]byte, 1)
+	_, err = f.Read(buf)
+	return err == nil || err == io.EOF
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerJenkinsProbeDataSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, token, authenticated := r.BasicAuth()
+		authenticated = authenticated && user == "ci-bot" && token == "api-token"
+		switch {
+		case r.URL.Path == "/api/json" && authenticated:
+			fmt.Fprint(w, `{"mode":"NORMAL"}`)
+		case r.URL.Path == "/credentials/store/system/domain/_/api/json" && authenticated:
+			fmt.Fprint(w, `{"credentials":[]}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, "secrets"), 0700); err != nil {
+		t.Fatalf("Failed to create secrets dir: %v", err)
+	}
+	masterKey := filepath.Join(home, "secrets", "master.key")
+	if err := os.WriteFile(masterKey, []byte("key"), 0600); err != nil {
+		t.Fatalf("Failed to write master key: %v", err)
+	}
+
+	t.Setenv("JENKINS_URL", server.URL+"/")
+	t.Setenv("JENKINS_HOME", home)
+	t.Setenv("JENKINS_SECRET", "agent-secret")
+	t.Setenv("JENKINS_USER_ID", "ci-bot")
+	t.Setenv("JENKINS_API_TOKEN", "api-token")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_jenkins_probe" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_jenkins_probe.test", "detected", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_jenkins_probe.test", "controller_reachable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_jenkins_probe.test", "anonymous_read", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_jenkins_probe.test", "authenticated_read", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_jenkins_probe.test", "script_console_reachable", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_jenkins_probe.test", "credentials_endpoint_reachable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_jenkins_probe.test", "agent_secret_exposed", "true"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_jenkins_probe.test", "readable_secret_paths.*", masterKey),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,423 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerJWTForgeProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerJWTForgeProbeDataSource{}
+)
+
+// jwtForgeChecks are the supported checks, in the order they are run.
+var jwtForgeChecks = []string{"alg_none", "invalid_signature", "unverified_audience"}
+
+// jwtForgeNoneVariants are the spellings of the none algorithm sent by the
+// alg_none check, as some libraries only reject the lowercase one.
+var jwtForgeNoneVariants = []string{"none", "None", "NONE", "nOnE"}
+
+// jwtForgeLifetime is the lifetime of the forged tokens, so a token accepted by
+// a vulnerable service cannot be reused for long.
+const jwtForgeLifetime = 5 * time.Minute
+
+// NewTerrapwnerJWTForgeProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerJWTForgeProbeDataSource() datasource.DataSource {
+	return &TerrapwnerJWTForgeProbeDataSource{}
+}
+
+// TerrapwnerJWTForgeProbeDataSource is the data source implementation.
+type TerrapwnerJWTForgeProbeDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerJWTForgeProbeDataSourceModel describes the data source data model.
+type TerrapwnerJWTForgeProbeDataSourceModel struct {
+	URL                 types.String `tfsdk:"url"`
+	Method              types.String `tfsdk:"method"`
+	Token               types.String `tfsdk:"token"`
+	Audience            types.String `tfsdk:"audience"`
+	Claims              types.String `tfsdk:"claims"`
+	HeaderName          types.String `tfsdk:"header_name"`
+	HeaderPrefix        types.String `tfsdk:"header_prefix"`
+	Checks              types.List   `tfsdk:"checks"`
+	AcceptedStatusCodes types.List   `tfsdk:"accepted_status_codes"`
+	Timeout             types.Int64  `tfsdk:"timeout"`
+	CanaryID            types.String `tfsdk:"canary_id"`
+	BaselineStatusCode  types.Int64  `tfsdk:"baseline_status_code"`
+	StatusCodes         types.Map    `tfsdk:"status_codes"`
+	Results             types.Map    `tfsdk:"results"`
+	Vulnerabilities     types.List   `tfsdk:"vulnerabilities"`
+	Vulnerable          types.Bool   `tfsdk:"vulnerable"`
+	FailReason          types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerJWTForgeProbeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerJWTForgeProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_jwt_forge_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerJWTForgeProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerJWTForgeProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Tests a service reachable from the runner for JWT validation weaknesses by sending it forged tokens: " +
+			"unsigned ones (alg=none), ones with an invalid signature, and a valid token issued for another audience. " +
+			"The requests are canaries: they are sent with the given method (GET by default) and no body, the forged " +
+			"tokens expire after 5 minutes, and they carry a `terrapwner-canary-<canary_id>` jti claim and an " +
+			"`X-Terrapwner-Canary` header so they can be told apart in the service logs. Point it at a read-only " +
+			"endpoint requiring authentication.",
+		Attributes: map[string]schema.Attribute{
+			"url": schema.StringAttribute{
+				Description: "URL of the endpoint requiring a JWT. Supports `{{secret:<handle>}}` and `{{env:<NAME>}}` references.",
+				Required:    true,
+			},
+			"method": schema.StringAttribute{
+				Description: "HTTP method of the requests (default: GET).",
+				Optional:    true,
+			},
+			"token": schema.StringAttribute{
+				Description: "Valid JWT, e.g. the OIDC token of the CI job, whose header and claims are reused by the forged " +
+					"tokens and which is replayed as is by the unverified_audience check. Supports `{{secret:<handle>}}` and " +
+					"`{{env:<NAME>}}` references. Without it, the forged tokens claim the `terrapwner-canary` subject.",
+				Optional:  true,
+				Sensitive: true,
+			},
+			"audience": schema.StringAttribute{
+				Description: "Audience the service expects. The unverified_audience check is skipped if the token is issued for it.",
+				Optional:    true,
+			},
+			"claims": schema.StringAttribute{
+				Description: "JSON object of claims set on the forged tokens, e.g. `jsonencode({ aud = \"internal-api\" })`. " +
+					"The jti, iat and exp claims are always set by the data source.",
+				Optional: true,
+			},
+			"header_name": schema.StringAttribute{
+				Description: "Header carrying the token (default: Authorization).",
+				Optional:    true,
+			},
+			"header_prefix": schema.StringAttribute{
+				Description: "Prefix of the token in the header (default: `Bearer ` for the Authorization header, none otherwise).",
+				Optional:    true,
+			},
+			"checks": schema.ListAttribute{
+				Description: "Checks to run: alg_none, invalid_signature and unverified_audience (default: all of them, " +
+					"unverified_audience requiring token).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"accepted_status_codes": schema.ListAttribute{
+				Description: "Status codes of the responses accepting a token (default: any 2xx).",
+				ElementType: types.Int64Type,
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each request (default: 10).",
+				Optional:    true,
+			},
+			"canary_id": schema.StringAttribute{
+				Description: "Random identifier of the canary requests.",
+				Computed:    true,
+			},
+			"baseline_status_code": schema.Int64Attribute{
+				Description: "Status code of the response to the request without a token, 0 if none was received.",
+				Computed:    true,
+			},
+			"status_codes": schema.MapAttribute{
+				Description: "Status code of the last response to the requests of each check that was run.",
+				ElementType: types.Int64Type,
+				Computed:    true,
+			},
+			"results": schema.MapAttribute{
+				Description: "Result of each check: `accepted` if the service accepted the token, `rejected`, `skipped` or `error`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"vulnerabilities": schema.ListAttribute{
+				Description: "Checks whose token was accepted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"vulnerable": schema.BoolAttribute{
+				Description: "Whether the service accepted any of the tokens.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason why the checks could not be run or were inconclusive, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read sends the forged tokens and updates the state.
+func (d *TerrapwnerJWTForgeProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerJWTForgeProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Method.IsNull() {
+		data.Method = types.StringValue(http.MethodGet)
+	}
+	if data.HeaderName.IsNull() {
+		data.HeaderName = types.StringValue("Authorization")
+	}
+	if data.HeaderPrefix.IsNull() {
+		prefix := ""
+		if strings.EqualFold(data.HeaderName.ValueString(), "Authorization") {
+			prefix = "Bearer "
+		}
+		data.HeaderPrefix = types.StringValue(prefix)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(10)
+	}
+
+	checks := jwtForgeChecks
+	if !data.Checks.IsNull() {
+		checks = nil
+		resp.Diagnostics.Append(data.Checks.ElementsAs(ctx, &checks, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		for _, check := range checks {
+			if !slices.Contains(jwtForgeChecks, check) {
+				resp.Diagnostics.AddError(
+					"Invalid configuration",
+					fmt.Sprintf("checks must be one of %s, got: %s", strings.Join(jwtForgeChecks, ", "), check),
+				)
+				return
+			}
+		}
+		if slices.Contains(checks, "unverified_audience") && data.Token.IsNull() {
+			resp.Diagnostics.AddError("Invalid configuration", "the unverified_audience check requires token")
+			return
+		}
+	}
+	var accepted []int64
+	if !data.AcceptedStatusCodes.IsNull() {
+		resp.Diagnostics.Append(data.AcceptedStatusCodes.ElementsAs(ctx, &accepted, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	for _, code := range accepted {
+		if code < 100 || code > 599 {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("accepted_status_codes must be HTTP status codes, got: %d", code))
+			return
+		}
+	}
+
+	// Resolve the secret references at request time
+	resolved, secrets, err := resolveSecretReferences(
+		[]string{data.URL.ValueString(), data.Token.ValueString()}, d.snapshot.Secrets(), d.snapshot.Getenv,
+	)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid configuration", err.Error())
+		return
+	}
+	rawURL, token := resolved[0], strings.TrimSpace(resolved[1])
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("url must be an HTTP or HTTPS URL, got: %s", redactSecrets(utils.RedactURL(rawURL), secrets)),
+		)
+		return
+	}
+
+	header := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	claims := map[string]interface{}{"sub": "terrapwner-canary"}
+	var audiences []string
+	if token != "" {
+		if header, err = utils.DecodeJWTHeader(token); err == nil {
+			claims, err = utils.DecodeJWTClaims(token)
+		}
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("token must be a JWT: %v", err))
+			return
+		}
+		audiences = utils.JWTAudiences(claims)
+	}
+	if !data.Claims.IsNull() {
+		var extra map[string]interface{}
+		if err := json.Unmarshal([]byte(data.Claims.ValueString()), &extra); err != nil || extra == nil {
+			resp.Diagnostics.AddError("Invalid configuration", "claims must be a JSON object")
+			return
+		}
+		for name, value := range extra {
+			claims[name] = value
+		}
+	}
+
+	canary := exfilTransferID()
+	now := time.Now()
+	claims["jti"] = "terrapwner-canary-" + canary
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(jwtForgeLifetime).Unix()
+
+	data.CanaryID = types.StringValue(canary)
+	data.BaselineStatusCode = types.Int64Value(0)
+	data.Vulnerable = types.BoolValue(false)
+	data.FailReason = types.StringValue("")
+
+	probe := jwtForgeProbe{
+		method:       data.Method.ValueString(),
+		url:          rawURL,
+		headerName:   data.HeaderName.ValueString(),
+		headerPrefix: data.HeaderPrefix.ValueString(),
+		canary:       canary,
+		accepted:     accepted,
+		timeout:      time.Duration(data.Timeout.ValueInt64()) * time.Second,
+	}
+	statusCodes := map[string]attr.Value{}
+	results := map[string]attr.Value{}
+	vulnerabilities := []attr.Value{}
+	for _, check := range checks {
+		results[check] = types.StringValue("skipped")
+	}
+	save := func() {
+		data.StatusCodes = types.MapValueMust(types.Int64Type, statusCodes)
+		data.Results = types.MapValueMust(types.StringType, results)
+		data.Vulnerabilities = types.ListValueMust(types.StringType, vulnerabilities)
+		data.Vulnerable = types.BoolValue(len(vulnerabilities) > 0)
+
+		// Save data into Terraform state
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	}
+
+	// A service accepting requests without a token would accept any forged one
+	baseline, baselineAccepted, err := probe.send(ctx, "")
+	if err != nil {
+		data.FailReason = types.StringValue(redactSecrets(fmt.Sprintf("Baseline request failed: %v", authReplayError(err)), secrets))
+		save()
+		return
+	}
+	data.BaselineStatusCode = types.Int64Value(int64(baseline))
+	if baselineAccepted {
+		data.FailReason = types.StringValue(fmt.Sprintf("Endpoint accepts requests without a token: HTTP %d", baseline))
+		save()
+		return
+	}
+
+	for _, check := range checks {
+		var tokens []string
+		switch check {
+		case "alg_none":
+			for _, alg := range jwtForgeNoneVariants {
+				forged, err := forgeJWT(header, claims, alg, nil)
+				if err != nil {
+					resp.Diagnostics.AddError("Invalid configuration", err.Error())
+					return
+				}
+				tokens = append(tokens, forged)
+			}
+		case "invalid_signature":
+			alg, _ := header["alg"].(string)
+			if alg == "" || strings.EqualFold(alg, "none") {
+				alg = "HS256"
+			}
+			signature := make([]byte, 32)
+			rand.Read(signature) //nolint:errcheck
+			forged, err := forgeJWT(header, claims, alg, signature)
+			if err != nil {
+				resp.Diagnostics.AddError("Invalid configuration", err.Error())
+				return
+			}
+			tokens = append(tokens, forged)
+		case "unverified_audience":
+			// A token issued for the service proves nothing
+			if token == "" || slices.Contains(audiences, data.Audience.ValueString()) {
+				continue
+			}
+			tokens = append(tokens, token)
+		}
+
+		results[check] = types.StringValue("rejected")
+		for _, forged := range tokens {
+			code, ok, err := probe.send(ctx, forged)
+			if err != nil {
+				results[check] = types.StringValue("error")
+				if data.FailReason.ValueString() == "" {
+					data.FailReason = types.StringValue(redactSecrets(fmt.Sprintf("Request failed: %v", authReplayError(err)), secrets))
+				}
+				break
+			}
+			statusCodes[check] = types.Int64Value(int64(code))
+			if ok {
+				results[check] = types.StringValue("accepted")
+				vulnerabilities = append(vulnerabilities, types.StringValue(check))
+				break
+			}
+		}
+	}
+
+	save()
+}
+
+// jwtForgeProbe sends the canary requests of a JWT forge probe.
+type jwtForgeProbe struct {
+	method       string
+	url          string
+	headerName   string
+	headerPrefix string
+	canary       string
+	accepted     []int64
+	timeout      time.Duration
+}
+
+// send sends a canary request with the given token, or none if empty, and
+// returns the status code of the response and whether it accepts the token.
+func (p jwtForgeProbe) send(ctx context.Context, token string) (int, bool, error) {
+	headers := map[string]string{"X-Terrapwner-Canary": p.canary}
+	if token != "" {
+		headers[p.headerName] = p.headerPrefix + token
+	}
+	response, err := utils.HTTPRequest(ctx, p.method, p.url, headers, nil, p.timeout)
+	if err != nil {
+		return 0, false, err
+	}
+	return response.StatusCode, authReplayAccepted(p.accepted, response.StatusCode), nil
+}
+
+// forgeJWT returns a JWT with the given header, with its alg replaced, claims
+// and signature.
+func forgeJWT(header, claims map[string]interface{}, alg string, signature []byte) (string, error) {
+	forged := make(map[string]interface{}, len(header))
+	for name, value := range header {
+		forged[name] = value
+	}
+	forged["alg"] = alg
+	return utils.EncodeJWT(forged, claims, signature)
+}
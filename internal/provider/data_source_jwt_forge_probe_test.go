@@ -0,0 +1,147 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// startJWTForgeServer serves an endpoint accepting unsigned tokens with a
+// capitalized None algorithm and the given token whatever its audience, an
+// endpoint only accepting the given token for the internal-api audience, and
+// an endpoint open to anyone.
+func startJWTForgeServer(t *testing.T, jwt string) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Terrapwner-Canary") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		header, _ := utils.DecodeJWTHeader(token)
+		claims, _ := utils.DecodeJWTClaims(token)
+		switch {
+		case r.URL.Path == "/open":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/weak" && header["alg"] == "None" && strings.HasPrefix(claimString(claims, "jti"), "terrapwner-canary-"):
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/weak" && token == jwt:
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/strict" && token == jwt && slices.Contains(utils.JWTAudiences(claims), "internal-api"):
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestAccTerrapwnerJWTForgeProbeDataSource(t *testing.T) {
+	jwt := testAccJWT(`{"sub":"repo:acme/app:ref:refs/heads/main","aud":"sts.amazonaws.com"}`)
+	server := startJWTForgeServer(t, jwt)
+	t.Setenv("TERRAPWNER_TEST_OIDC_TOKEN", jwt)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_jwt_forge_probe" "weak" {
+  url   = "%[1]s/weak"
+  token = "{{env:TERRAPWNER_TEST_OIDC_TOKEN}}"
+}
+
+data "terrapwner_jwt_forge_probe" "strict" {
+  url      = "%[1]s/strict"
+  token    = "{{env:TERRAPWNER_TEST_OIDC_TOKEN}}"
+  audience = "internal-api"
+}
+
+data "terrapwner_jwt_forge_probe" "same_audience" {
+  url      = "%[1]s/weak"
+  token    = "{{env:TERRAPWNER_TEST_OIDC_TOKEN}}"
+  audience = "sts.amazonaws.com"
+  checks   = ["unverified_audience"]
+}
+
+data "terrapwner_jwt_forge_probe" "no_token" {
+  url    = "%[1]s/strict"
+  claims = "{\"aud\":\"internal-api\"}"
+}
+
+data "terrapwner_jwt_forge_probe" "open" {
+  url = "%[1]s/open"
+}
+`, server),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.terrapwner_jwt_forge_probe.weak", "canary_id"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "baseline_status_code", "401"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "results.alg_none", "accepted"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "results.invalid_signature", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "results.unverified_audience", "accepted"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "status_codes.alg_none", "200"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "status_codes.invalid_signature", "401"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "vulnerabilities.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "vulnerabilities.0", "alg_none"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "vulnerabilities.1", "unverified_audience"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "vulnerable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.weak", "fail_reason", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.strict", "results.alg_none", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.strict", "results.unverified_audience", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.strict", "vulnerabilities.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.strict", "vulnerable", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.same_audience", "results.%", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.same_audience", "results.unverified_audience", "skipped"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.same_audience", "status_codes.%", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.no_token", "results.alg_none", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.no_token", "results.invalid_signature", "rejected"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.no_token", "results.unverified_audience", "skipped"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.open", "baseline_status_code", "200"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.open", "results.alg_none", "skipped"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.open", "vulnerable", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_jwt_forge_probe.open", "fail_reason", "Endpoint accepts requests without a token: HTTP 200"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_jwt_forge_probe" "test" {
+  url    = "https://api.internal.example.com/v1/me"
+  checks = ["unverified_audience"]
+}
+`,
+				ExpectError: regexp.MustCompile(`the unverified_audience check requires token`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_jwt_forge_probe" "test" {
+  url    = "https://api.internal.example.com/v1/me"
+  checks = ["kid_injection"]
+}
+`,
+				ExpectError: regexp.MustCompile(`checks must be one of alg_none, invalid_signature, unverified_audience, got:\s+kid_injection`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_jwt_forge_probe" "test" {
+  url   = "https://api.internal.example.com/v1/me"
+  token = "opaque"
+}
+`,
+				ExpectError: regexp.MustCompile(`token must be a JWT`),
+			},
+		},
+	})
+}
@@ -6,6 +6,9 @@ package provider
 import (
 	"context"
 	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
@@ -22,6 +25,10 @@ const (
 	defaultCommandTimeout = 30 * time.Second
 )
 
+// escalationMethods are the methods tried in order to run commands as another
+// user without use_sudo.
+var escalationMethods = []string{"sudo", "doas", "su"}
+
 // Ensure the implementation satisfies the expected interfaces.
 var (
 	_ datasource.DataSource              = &TerrapwnerLocalExecDataSource{}
@@ -30,16 +37,21 @@ var (
 
 // TerrapwnerLocalExecDataSourceModel describes the data source data model.
 type TerrapwnerLocalExecDataSourceModel struct {
-	Command       types.List   `tfsdk:"command"`
-	Timeout       types.Int64  `tfsdk:"timeout"`
-	ExpectSuccess types.Bool   `tfsdk:"expect_success"`
-	FailOnError   types.Bool   `tfsdk:"fail_on_error"`
-	Success       types.Bool   `tfsdk:"success"`
-	Stdout        types.String `tfsdk:"stdout"`
-	Stderr        types.String `tfsdk:"stderr"`
-	ExitCode      types.Int64  `tfsdk:"exit_code"`
-	FailReason    types.String `tfsdk:"fail_reason"`
-	DurationMs    types.Int64  `tfsdk:"duration_ms"`
+	Command             types.List   `tfsdk:"command"`
+	WorkingDir          types.String `tfsdk:"working_dir"`
+	RunAs               types.String `tfsdk:"run_as"`
+	UseSudo             types.Bool   `tfsdk:"use_sudo"`
+	Timeout             types.Int64  `tfsdk:"timeout"`
+	ExpectSuccess       types.Bool   `tfsdk:"expect_success"`
+	FailOnError         types.Bool   `tfsdk:"fail_on_error"`
+	Success             types.Bool   `tfsdk:"success"`
+	Stdout              types.String `tfsdk:"stdout"`
+	Stderr              types.String `tfsdk:"stderr"`
+	ExitCode            types.Int64  `tfsdk:"exit_code"`
+	EscalationSucceeded types.Bool   `tfsdk:"escalation_succeeded"`
+	EscalationMethod    types.String `tfsdk:"escalation_method"`
+	FailReason          types.String `tfsdk:"fail_reason"`
+	DurationMs          types.Int64  `tfsdk:"duration_ms"`
 }
 
 // NewTerrapwnerLocalExecDataSource is a helper function to simplify the provider implementation.
@@ -48,27 +60,55 @@ func NewTerrapwnerLocalExecDataSource() datasource.DataSource {
 }
 
 // TerrapwnerLocalExecDataSource is the data source implementation.
-type TerrapwnerLocalExecDataSource struct{}
+type TerrapwnerLocalExecDataSource struct {
+	snapshot *environmentSnapshot
+}
 
 // Metadata returns the data source type name.
 func (d *TerrapwnerLocalExecDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_local_exec"
 }
 
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerLocalExecDataSource) Tags() []string {
+	return []string{categoryExec}
+}
+
 // Schema defines the schema for the data source.
 func (d *TerrapwnerLocalExecDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Description: "Executes a local command and captures its output, exit code, and runtime details. " +
 			"This data source is used in CI/CD pipeline assessments to determine what can be executed inside the Terraform runtime environment. " +
-			"Commands are executed with a configurable timeout (default: 30 seconds).",
+			"Commands are executed with a configurable timeout (default: 30 seconds). " +
+			"Arguments may reference {{secret:<handle>}}, a value harvested by another data source (e.g. the token_handle of terrapwner_oidc_token), " +
+			"and {{env:<name>}}, an environment variable. References are resolved at execution time so the values are never stored in the state, " +
+			"and are redacted from stdout and stderr.",
 		Attributes: map[string]schema.Attribute{
 			"command": schema.ListAttribute{
-				Description: "The command to execute as a list of strings. The first element is the executable, and the rest are arguments.",
+				Description: "The command to execute as a list of strings. The first element is the executable, and the rest are arguments. " +
+					"Elements may contain {{secret:<handle>}} and {{env:<name>}} references.",
 				ElementType: types.StringType,
 				Required:    true,
 			},
+			"working_dir": schema.StringAttribute{
+				Description: "Working directory of the command, e.g. another checkout or module directory on the runner " +
+					"(default: the working directory of Terraform). Relative paths are relative to the working directory " +
+					"of Terraform, and a relative executable path is relative to this directory.",
+				Optional: true,
+			},
+			"run_as": schema.StringAttribute{
+				Description: "Name or numeric uid of the user to execute the command as, to test the privilege boundaries of the runner. " +
+					"The escalation is attempted without prompting for a password with sudo, doas, then su, and the command " +
+					"only runs with the first method actually running commands as the user. A numeric uid is passed to sudo as " +
+					"#uid and resolved to the name of the local user for su.",
+				Optional: true,
+			},
+			"use_sudo": schema.BoolAttribute{
+				Description: "Whether to execute the command with sudo only, as run_as or as root by default (default: false).",
+				Optional:    true,
+			},
 			"timeout": schema.Int64Attribute{
-				Description: "Timeout in seconds for command execution (default: 30).",
+				Description: "Timeout in seconds for command execution, including the privilege escalation attempts (default: 30).",
 				Optional:    true,
 			},
 			"expect_success": schema.BoolAttribute{
@@ -95,6 +135,15 @@ func (d *TerrapwnerLocalExecDataSource) Schema(_ context.Context, _ datasource.S
 				Description: "Exit code of the process.",
 				Computed:    true,
 			},
+			"escalation_succeeded": schema.BoolAttribute{
+				Description: "Whether the command could be executed as run_as, or as root with use_sudo (null without " +
+					"run_as nor use_sudo).",
+				Computed: true,
+			},
+			"escalation_method": schema.StringAttribute{
+				Description: "Method the privileges were escalated with: sudo, doas or su (empty if no escalation succeeded).",
+				Computed:    true,
+			},
 			"fail_reason": schema.StringAttribute{
 				Description: "If execution fails or times out, this contains the error.",
 				Computed:    true,
@@ -108,8 +157,8 @@ func (d *TerrapwnerLocalExecDataSource) Schema(_ context.Context, _ datasource.S
 }
 
 // Configure adds the provider configured client to the data source.
-func (d *TerrapwnerLocalExecDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
-	// No configuration needed
+func (d *TerrapwnerLocalExecDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
 }
 
 // Read executes the command and updates the state.
@@ -132,6 +181,9 @@ func (d *TerrapwnerLocalExecDataSource) Read(ctx context.Context, req datasource
 	if data.FailOnError.IsNull() {
 		data.FailOnError = types.BoolValue(false)
 	}
+	if data.UseSudo.IsNull() {
+		data.UseSudo = types.BoolValue(false)
+	}
 
 	// Start timing
 	startTime := time.Now()
@@ -151,16 +203,65 @@ func (d *TerrapwnerLocalExecDataSource) Read(ctx context.Context, req datasource
 		return
 	}
 
+	// Resolve the secret references at execution time
+	command, secrets, err := resolveSecretReferences(command, d.snapshot.Secrets(), d.snapshot.Getenv)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid command",
+			err.Error(),
+		)
+		return
+	}
+
+	// Validate the escalation settings
+	if !data.RunAs.IsNull() && data.RunAs.ValueString() == "" {
+		resp.Diagnostics.AddError(
+			"Invalid run_as",
+			"run_as must not be empty",
+		)
+		return
+	}
+
+	// Escalate privileges to execute the command as another user. The escalation
+	// checks and the command share a single deadline
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	data.EscalationSucceeded = types.BoolNull()
+	data.EscalationMethod = types.StringValue("")
+	if !data.RunAs.IsNull() || data.UseSudo.ValueBool() {
+		user := "root"
+		if !data.RunAs.IsNull() {
+			user = data.RunAs.ValueString()
+		}
+		methods := escalationMethods
+		if data.UseSudo.ValueBool() {
+			methods = []string{"sudo"}
+		}
+		method, target, failures := escalate(execCtx, data.WorkingDir.ValueString(), methods, user, timeout)
+		data.EscalationSucceeded = types.BoolValue(method != "")
+		data.EscalationMethod = types.StringValue(method)
+		if method == "" {
+			err = fmt.Errorf("privilege escalation to %s failed: %s", user, strings.Join(failures, "; "))
+		} else {
+			command = escalationCommand(method, target, command)
+		}
+	}
+
 	// Execute the command with the configured timeout
-	result, err := utils.Execute(
-		ctx,
-		command[0],
-		command[1:],
-		time.Duration(data.Timeout.ValueInt64())*time.Second,
-	)
+	var result *utils.ExecResult
+	if err == nil {
+		result, err = utils.ExecuteIn(
+			execCtx,
+			data.WorkingDir.ValueString(),
+			command[0],
+			command[1:],
+			timeout,
+		)
+	}
 	if err != nil {
 		data.Success = types.BoolValue(false)
-		data.FailReason = types.StringValue(fmt.Sprintf("Failed to execute command: %v", err))
+		data.FailReason = types.StringValue(redactSecrets(fmt.Sprintf("Failed to execute command: %v", err), secrets))
 		data.ExitCode = types.Int64Value(-1)
 		data.DurationMs = types.Int64Value(time.Since(startTime).Milliseconds())
 		if data.FailOnError.ValueBool() {
@@ -176,8 +277,8 @@ func (d *TerrapwnerLocalExecDataSource) Read(ctx context.Context, req datasource
 
 	// Set the results
 	data.Success = types.BoolValue(result.ExitCode == 0)
-	data.Stdout = types.StringValue(result.Stdout)
-	data.Stderr = types.StringValue(result.Stderr)
+	data.Stdout = types.StringValue(redactSecrets(result.Stdout, secrets))
+	data.Stderr = types.StringValue(redactSecrets(result.Stderr, secrets))
 	data.ExitCode = types.Int64Value(int64(result.ExitCode))
 	data.FailReason = types.StringValue("")
 	data.DurationMs = types.Int64Value(time.Since(startTime).Milliseconds())
@@ -186,7 +287,7 @@ func (d *TerrapwnerLocalExecDataSource) Read(ctx context.Context, req datasource
 	if !data.Success.ValueBool() && data.FailOnError.ValueBool() {
 		resp.Diagnostics.AddError(
 			"Command failed",
-			fmt.Sprintf("Command exited with code %d: %s", result.ExitCode, result.Stderr),
+			fmt.Sprintf("Command exited with code %d: %s", result.ExitCode, data.Stderr.ValueString()),
 		)
 		return
 	}
@@ -194,3 +295,73 @@ func (d *TerrapwnerLocalExecDataSource) Read(ctx context.Context, req datasource
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
+
+// escalate returns the first of methods executing commands as user, checked
+// by running id, and the user argument it takes, or the reasons why each
+// method failed. A numeric user is compared to the uid, a name to the user name.
+func escalate(ctx context.Context, dir string, methods []string, user string, timeout time.Duration) (string, string, []string) {
+	idArgs := []string{"id", "-un"}
+	_, err := strconv.ParseUint(user, 10, 32)
+	uid := err == nil
+	if uid {
+		idArgs = []string{"id", "-u"}
+	}
+
+	var failures []string
+	for _, method := range methods {
+		target, err := escalationUser(method, user, uid)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", method, err))
+			continue
+		}
+		check := escalationCommand(method, target, idArgs)
+		result, err := utils.ExecuteIn(ctx, dir, check[0], check[1:], timeout)
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("%s: %v", method, err))
+		case result.ExitCode != 0:
+			failures = append(failures, fmt.Sprintf("%s: exited with code %d: %s", method, result.ExitCode, strings.TrimSpace(result.Stderr)))
+		case strings.TrimSpace(result.Stdout) != user:
+			failures = append(failures, fmt.Sprintf("%s: ran as %s", method, strings.TrimSpace(result.Stdout)))
+		default:
+			return method, target, nil
+		}
+	}
+	return "", "", failures
+}
+
+// escalationUser returns the user argument of method for runAs. doas takes a
+// uid as is, sudo takes it prefixed with # and su only takes user names, so
+// the uid is resolved to the name of its local user.
+func escalationUser(method, runAs string, uid bool) (string, error) {
+	if !uid {
+		return runAs, nil
+	}
+	switch method {
+	case "sudo":
+		return "#" + runAs, nil
+	case "su":
+		u, err := user.LookupId(runAs)
+		if err != nil {
+			return "", fmt.Errorf("resolving uid: %w", err)
+		}
+		return u.Username, nil
+	default:
+		return runAs, nil
+	}
+}
+
+// escalationCommand returns command wrapped to be executed as user with
+// method, never prompting for a password: su reads none without a terminal.
+func escalationCommand(method, user string, command []string) []string {
+	switch method {
+	case "sudo", "doas":
+		return append([]string{method, "-n", "-u", user, "--"}, command...)
+	default:
+		quoted := make([]string, len(command))
+		for i, arg := range command {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		return []string{"su", "-s", "/bin/sh", "-c", strings.Join(quoted, " "), user}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
@@ -198,3 +199,246 @@ data "terrapwner_local_exec" "test" {
 		},
 	})
 }
+
+func TestAccTerrapwnerLocalExecDataSource_WorkingDir(t *testing.T) {
+	t.Setenv("PATH", "/bin:/usr/bin:/usr/local/bin")
+
+	// Create a checkout with a script in a temporary directory
+	checkout := t.TempDir()
+	if err := os.WriteFile(filepath.Join(checkout, "probe.sh"), []byte("#!/bin/sh\nls\n"), 0755); err != nil {
+		t.Fatalf("Failed to create test script: %v", err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Test command execution in another directory
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_local_exec" "test" {
+  command     = ["./probe.sh"]
+  working_dir = %q
+}
+`, checkout),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "stdout", "probe.sh\n"),
+				),
+			},
+			// Test missing working directory
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_local_exec" "test" {
+  command     = ["ls"]
+  working_dir = %q
+}
+`, filepath.Join(checkout, "missing")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "exit_code", "-1"),
+					resource.TestMatchResourceAttr("data.terrapwner_local_exec.test", "fail_reason", regexp.MustCompile("missing: no such file or directory$")),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerLocalExecDataSource_RunAs(t *testing.T) {
+	// Fake escalation commands: sudo requiring a password, doas running commands
+	// as any user, su running them as any user name but rejecting uids like the
+	// real su, and sudo taking uids prefixed with # only
+	writeScripts := func(dir string, scripts map[string]string) {
+		for name, script := range scripts {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+				t.Fatalf("Failed to create fake %s: %v", name, err)
+			}
+		}
+	}
+	sudo := "echo 'sudo: a password is required' >&2\nexit 1\n"
+	doasDir, suDir := t.TempDir(), t.TempDir()
+	writeScripts(doasDir, map[string]string{
+		"sudo": sudo,
+		"doas": "user=$3\nshift 4\nif [ \"$1\" = id ]; then echo \"$user\"; else exec \"$@\"; fi\n",
+	})
+	writeScripts(suDir, map[string]string{
+		"sudo": sudo,
+		"doas": "echo 'doas: Operation not permitted' >&2\nexit 1\n",
+		"su": "case \"$5\" in *[!0-9]*) ;; *) echo \"su: user $5 does not exist\" >&2; exit 1 ;; esac\n" +
+			"case \"$4\" in \"'id' '-un'\") echo \"$5\" ;; \"'id' '-u'\") exec id -u \"$5\" ;; *) exec sh -c \"$4\" ;; esac\n",
+	})
+	uidSudoDir := t.TempDir()
+	writeScripts(uidSudoDir, map[string]string{
+		"sudo": "case \"$3\" in \"#\"*) ;; *[!0-9]*) ;; *) echo \"sudo: unknown user $3\" >&2; exit 1 ;; esac\n" +
+			"user=${3#\\#}\nshift 4\nif [ \"$1\" = id ]; then echo \"$user\"; else exec \"$@\"; fi\n",
+	})
+	// Escalation commands hanging until they are killed
+	slowDir := t.TempDir()
+	writeScripts(slowDir, map[string]string{
+		"sudo": "exec sleep 10\n",
+		"doas": "exec sleep 10\n",
+		"su":   "exec sleep 10\n",
+	})
+	t.Setenv("PATH", doasDir+":/bin:/usr/bin")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Test escalation falling back to doas
+			{
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command = ["echo", "escalated"]
+  run_as  = "deploy"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "stdout", "escalated\n"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_succeeded", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_method", "doas"),
+				),
+			},
+			// Test escalation restricted to sudo
+			{
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command  = ["echo", "escalated"]
+  use_sudo = true
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "exit_code", "-1"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_succeeded", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_method", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "fail_reason",
+						"Failed to execute command: privilege escalation to root failed: sudo: exited with code 1: sudo: a password is required"),
+				),
+			},
+			// Test escalation falling back to su, with quoted arguments
+			{
+				PreConfig: func() { t.Setenv("PATH", suDir+":/bin:/usr/bin") },
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command = ["echo", "it's escalated"]
+  run_as  = "deploy"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "stdout", "it's escalated\n"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_method", "su"),
+				),
+			},
+			// Test escalation to a numeric uid, resolved to a user name for su
+			{
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command = ["echo", "escalated"]
+  run_as  = "0"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_succeeded", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_method", "su"),
+				),
+			},
+			// Test su skipped for a uid without local user
+			{
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command = ["echo", "escalated"]
+  run_as  = "999999"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_succeeded", "false"),
+					resource.TestMatchResourceAttr("data.terrapwner_local_exec.test", "fail_reason",
+						regexp.MustCompile(`; su: resolving uid: user: unknown userid 999999$`)),
+				),
+			},
+			// Test escalation to a numeric uid with sudo
+			{
+				PreConfig: func() { t.Setenv("PATH", uidSudoDir+":/bin:/usr/bin") },
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command = ["echo", "escalated"]
+  run_as  = "1001"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "stdout", "escalated\n"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_method", "sudo"),
+				),
+			},
+			// Test the timeout bounding all the escalation attempts together
+			{
+				PreConfig: func() { t.Setenv("PATH", slowDir+":/bin:/usr/bin") },
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command = ["echo", "escalated"]
+  run_as  = "deploy"
+  timeout = 1
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "escalation_succeeded", "false"),
+					resource.TestCheckResourceAttrWith("data.terrapwner_local_exec.test", "duration_ms", func(value string) error {
+						if duration, err := strconv.Atoi(value); err != nil || duration >= 2000 {
+							return fmt.Errorf("expected the escalation attempts to take at most the 1s timeout, took %sms", value)
+						}
+						return nil
+					}),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command = ["id"]
+  run_as  = ""
+}
+`,
+				ExpectError: regexp.MustCompile("run_as must not be empty"),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerLocalExecDataSource_SecretReferences(t *testing.T) {
+	t.Setenv("PATH", "/bin:/usr/bin:/usr/local/bin")
+	t.Setenv("GITLAB_CI", "true")
+	t.Setenv("VAULT_ID_TOKEN", testAccJWT(`{"iss":"https://gitlab.example.com","sub":"project_path:group/app"}`))
+	t.Setenv("TERRAPWNER_TEST_PASSWORD", "hunter2")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Test references resolved at execution time and redacted from the output
+			{
+				Config: providerConfig + `
+data "terrapwner_oidc_token" "test" {
+  token_variable = "VAULT_ID_TOKEN"
+}
+
+data "terrapwner_local_exec" "test" {
+  command = ["sh", "-c", "echo token=$1 password=$2", "sh", "{{secret:${data.terrapwner_oidc_token.test.token_handle}}}", "{{env:TERRAPWNER_TEST_PASSWORD}}"]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("data.terrapwner_oidc_token.test", "token_handle", regexp.MustCompile("^tpsecret-[0-9a-f]{16}$")),
+					resource.TestCheckNoResourceAttr("data.terrapwner_oidc_token.test", "token"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "stdout", "token=[REDACTED] password=[REDACTED]\n"),
+				),
+			},
+			// Test unknown secret handle
+			{
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command = ["echo", "{{secret:tpsecret-0000000000000000}}"]
+}
+`,
+				ExpectError: regexp.MustCompile("unknown secret handles"),
+			},
+		},
+	})
+}
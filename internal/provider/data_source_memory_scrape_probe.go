@@ -0,0 +1,251 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerMemoryScrapeProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerMemoryScrapeProbeDataSource{}
+)
+
+const (
+	// defaultMemoryScrapeMaxProcesses is the default number of processes checked.
+	defaultMemoryScrapeMaxProcesses = 50
+	// memoryScrapeReadSize is the number of bytes read to test access. The bytes
+	// are discarded, only whether the read succeeded is reported.
+	memoryScrapeReadSize = 64
+)
+
+// memoryHardeningSettings lists the kernel settings restricting access to the
+// memory of other processes or to kernel information useful to scrape it.
+var memoryHardeningSettings = []string{
+	"kernel.yama.ptrace_scope",
+	"kernel.kptr_restrict",
+	"kernel.dmesg_restrict",
+	"kernel.perf_event_paranoid",
+	"kernel.unprivileged_bpf_disabled",
+	"fs.suid_dumpable",
+}
+
+// NewTerrapwnerMemoryScrapeProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerMemoryScrapeProbeDataSource() datasource.DataSource {
+	return &TerrapwnerMemoryScrapeProbeDataSource{}
+}
+
+// TerrapwnerMemoryScrapeProbeDataSource is the data source implementation.
+type TerrapwnerMemoryScrapeProbeDataSource struct{}
+
+// TerrapwnerMemoryScrapeProbeDataSourceModel describes the data source data model.
+type TerrapwnerMemoryScrapeProbeDataSourceModel struct {
+	PIDs                   types.List   `tfsdk:"pids"`
+	MaxProcesses           types.Int64  `tfsdk:"max_processes"`
+	PtraceScope            types.Int64  `tfsdk:"ptrace_scope"`
+	KernelSettings         types.Map    `tfsdk:"kernel_settings"`
+	ProcessesChecked       types.Int64  `tfsdk:"processes_checked"`
+	ProcMemReadable        types.List   `tfsdk:"proc_mem_readable"`
+	ProcessVMReadvReadable types.List   `tfsdk:"process_vm_readv_readable"`
+	MemoryReadable         types.Bool   `tfsdk:"memory_readable"`
+	FailReason             types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerMemoryScrapeProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerMemoryScrapeProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_memory_scrape_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerMemoryScrapeProbeDataSource) Tags() []string {
+	return []string{categoryExec, "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerMemoryScrapeProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Tests whether the Terraform process can read the memory of other processes, e.g. to scrape credentials from " +
+			"sibling CI processes, through /proc/<pid>/mem and process_vm_readv, and reports the kernel hardening settings " +
+			"(Yama ptrace_scope, kptr_restrict, ...) restricting it. Only whether a read succeeded is reported, the memory read is discarded. Linux only.",
+		Attributes: map[string]schema.Attribute{
+			"pids": schema.ListAttribute{
+				Description: "Processes to check (default: every visible process other than this provider).",
+				ElementType: types.Int64Type,
+				Optional:    true,
+			},
+			"max_processes": schema.Int64Attribute{
+				Description: fmt.Sprintf("Maximum number of processes to check (default: %d).", defaultMemoryScrapeMaxProcesses),
+				Optional:    true,
+			},
+			"ptrace_scope": schema.Int64Attribute{
+				Description: "Value of kernel.yama.ptrace_scope: 0 (classic ptrace permissions), 1 (descendants only), 2 (admin only), " +
+					"3 (no attach), or -1 if Yama is not enabled.",
+				Computed: true,
+			},
+			"kernel_settings": schema.MapAttribute{
+				Description: "Values of the kernel hardening settings, by sysctl name. Settings not supported by the kernel are omitted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"processes_checked": schema.Int64Attribute{
+				Description: "Number of processes checked.",
+				Computed:    true,
+			},
+			"proc_mem_readable": schema.ListAttribute{
+				Description: "Processes whose memory could be read through /proc/<pid>/mem, formatted as `pid name`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"process_vm_readv_readable": schema.ListAttribute{
+				Description: "Processes whose memory could be read with process_vm_readv, formatted as `pid name`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"memory_readable": schema.BoolAttribute{
+				Description: "True if the memory of at least one other process could be read.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Checks that could not be performed, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerMemoryScrapeProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerMemoryScrapeProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.MaxProcesses.IsNull() {
+		data.MaxProcesses = types.Int64Value(defaultMemoryScrapeMaxProcesses)
+	}
+
+	var pids []int64
+	if !data.PIDs.IsNull() {
+		resp.Diagnostics.Append(data.PIDs.ElementsAs(ctx, &pids, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	var failures []string
+	settings := map[string]string{}
+	data.PtraceScope = types.Int64Value(-1)
+	procMemReadable := []string{}
+	vmReadvReadable := []string{}
+	checked := 0
+
+	if runtime.GOOS != "linux" {
+		failures = append(failures, fmt.Sprintf("memory scraping checks are only supported on Linux, running on %s", runtime.GOOS))
+	} else {
+		for _, name := range memoryHardeningSettings {
+			if value, err := utils.ReadSysctl(name); err == nil {
+				settings[name] = value
+			}
+		}
+		if scope, err := strconv.ParseInt(settings["kernel.yama.ptrace_scope"], 10, 64); err == nil {
+			data.PtraceScope = types.Int64Value(scope)
+		}
+
+		processes, err := memoryScrapeTargets(pids)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("processes: %v", err))
+		}
+		for _, p := range processes {
+			if checked >= int(data.MaxProcesses.ValueInt64()) {
+				break
+			}
+
+			// Processes may exit or have no user memory (kernel threads). The
+			// maps of a process are protected like its memory, so a process
+			// whose maps cannot be read is checked and not readable.
+			addr, err := utils.ReadableRegion(p.PID)
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, utils.ErrNoReadableRegion) {
+				continue
+			}
+			checked++
+			if err != nil {
+				continue
+			}
+
+			name := fmt.Sprintf("%d %s", p.PID, p.Name)
+			if _, err := utils.ReadProcMem(p.PID, addr, memoryScrapeReadSize); err == nil {
+				procMemReadable = append(procMemReadable, name)
+			}
+			if _, err := utils.ReadProcessVM(p.PID, addr, memoryScrapeReadSize); err == nil {
+				vmReadvReadable = append(vmReadvReadable, name)
+			}
+		}
+	}
+
+	data.ProcessesChecked = types.Int64Value(int64(checked))
+	data.MemoryReadable = types.BoolValue(len(procMemReadable) > 0 || len(vmReadvReadable) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	settingsMap, diags := types.MapValueFrom(ctx, types.StringType, settings)
+	resp.Diagnostics.Append(diags...)
+	procMemList, diags := types.ListValueFrom(ctx, types.StringType, procMemReadable)
+	resp.Diagnostics.Append(diags...)
+	vmReadvList, diags := types.ListValueFrom(ctx, types.StringType, vmReadvReadable)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.KernelSettings = settingsMap
+	data.ProcMemReadable = procMemList
+	data.ProcessVMReadvReadable = vmReadvList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// memoryScrapeTargets returns the processes to check: the given PIDs, or every
+// visible process other than this provider.
+func memoryScrapeTargets(pids []int64) ([]utils.Process, error) {
+	processes, err := utils.ListProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[int]bool{}
+	for _, pid := range pids {
+		wanted[int(pid)] = true
+	}
+
+	var targets []utils.Process
+	for _, p := range processes {
+		if p.PID == os.Getpid() || (len(wanted) > 0 && !wanted[p.PID]) {
+			continue
+		}
+		targets = append(targets, p)
+	}
+	return targets, nil
+}
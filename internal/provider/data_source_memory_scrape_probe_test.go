@@ -0,0 +1,49 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerMemoryScrapeProbeDataSource(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("memory scraping checks are only supported on Linux")
+	}
+
+	// A child process is readable under classic and descendant-only ptrace permissions
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatalf("Failed to start child process: %v", err)
+	}
+	t.Cleanup(func() {
+		child.Process.Kill() //nolint:errcheck
+		child.Wait()         //nolint:errcheck
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_memory_scrape_probe" "test" {
+  pids = [%d]
+}
+`, child.Process.Pid),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_memory_scrape_probe.test", "processes_checked", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_memory_scrape_probe.test", "memory_readable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_memory_scrape_probe.test", "proc_mem_readable.0", fmt.Sprintf("%d sleep", child.Process.Pid)),
+					resource.TestCheckResourceAttrSet("data.terrapwner_memory_scrape_probe.test", "ptrace_scope"),
+					resource.TestCheckResourceAttr("data.terrapwner_memory_scrape_probe.test", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
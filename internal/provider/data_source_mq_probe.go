@@ -0,0 +1,871 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerMQProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerMQProbeDataSource{}
+)
+
+// exposureEnvCredentials is the exposure of a broker accessible with the
+// credentials found in the environment of the runner.
+const exposureEnvCredentials = "env_credentials"
+
+const (
+	// defaultMQProbeTimeout is the default timeout in seconds of each connection.
+	defaultMQProbeTimeout = 5
+	// defaultMQProbeMaxNames is the default number of names listed from each broker.
+	defaultMQProbeMaxNames = 1000
+	// kafkaMaxResponseSize bounds the size of a Kafka response.
+	kafkaMaxResponseSize = 32 << 20
+	// natsMaxLineSize bounds the size of a NATS protocol line.
+	natsMaxLineSize = 1 << 20
+)
+
+// Kafka API keys.
+const (
+	kafkaMetadataAPIKey      = 3
+	kafkaSaslHandshakeAPIKey = 17
+)
+
+// mqCredentials are credentials found in the environment for a broker.
+type mqCredentials struct {
+	source   string
+	user     string
+	password string
+	token    string
+}
+
+// NewTerrapwnerMQProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerMQProbeDataSource() datasource.DataSource {
+	return &TerrapwnerMQProbeDataSource{}
+}
+
+// TerrapwnerMQProbeDataSource is the data source implementation.
+type TerrapwnerMQProbeDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerMQProbeDataSourceModel describes the data source data model.
+type TerrapwnerMQProbeDataSourceModel struct {
+	KafkaEndpoints    types.List   `tfsdk:"kafka_endpoints"`
+	RabbitMQEndpoints types.List   `tfsdk:"rabbitmq_endpoints"`
+	NATSEndpoints     types.List   `tfsdk:"nats_endpoints"`
+	UseEnvCredentials types.Bool   `tfsdk:"use_env_credentials"`
+	MaxNames          types.Int64  `tfsdk:"max_names"`
+	Timeout           types.Int64  `tfsdk:"timeout"`
+	Endpoints         types.Map    `tfsdk:"endpoints"`
+	CredentialSources types.Map    `tfsdk:"credential_sources"`
+	Accessible        types.List   `tfsdk:"accessible"`
+	Names             types.List   `tfsdk:"names"`
+	ExposureFound     types.Bool   `tfsdk:"exposure_found"`
+	FailReason        types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerMQProbeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerMQProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_mq_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerMQProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerMQProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Checks whether the Kafka, RabbitMQ and NATS message brokers reachable from the runner can be accessed " +
+			"anonymously or with the broker credentials found in its environment, and lists the topics (Kafka), queues " +
+			"(RabbitMQ) and JetStream streams (NATS) visible. Nothing is produced or consumed: Kafka is queried for its " +
+			"metadata, RabbitMQ through its management API and NATS through the JetStream API. Certificates are not verified.",
+		Attributes: map[string]schema.Attribute{
+			"kafka_endpoints": schema.ListAttribute{
+				Description: "Kafka brokers to probe, as host:port (default: KAFKA_BOOTSTRAP_SERVERS or KAFKA_BROKERS, else " +
+					"127.0.0.1:9092). Plaintext listeners are supported, with SASL/PLAIN when credentials are used.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"rabbitmq_endpoints": schema.ListAttribute{
+				Description: "RabbitMQ management APIs to probe, as URLs or host:port (default: http://127.0.0.1:15672). The " +
+					"default guest user is tried as the anonymous access.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"nats_endpoints": schema.ListAttribute{
+				Description: "NATS servers to probe, as nats:// URLs or host:port (default: NATS_URL, else nats://127.0.0.1:4222).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"use_env_credentials": schema.BoolAttribute{
+				Description: "Whether to try the broker credentials found in the environment when anonymous access is denied " +
+					"(default: true): KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD, RABBITMQ_DEFAULT_USER and RABBITMQ_DEFAULT_PASS, " +
+					"NATS_USER and NATS_PASSWORD or NATS_TOKEN, and the user info of the endpoint URLs.",
+				Optional: true,
+			},
+			"max_names": schema.Int64Attribute{
+				Description: fmt.Sprintf("Maximum number of topics, queues or streams listed from each broker (default: %d).", defaultMQProbeMaxNames),
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: fmt.Sprintf("Timeout in seconds of each connection and request (default: %d).", defaultMQProbeTimeout),
+				Optional:    true,
+			},
+			"endpoints": schema.MapAttribute{
+				Description: "Exposure of each probed broker, keyed by `broker@host:port`: exposed (accessible anonymously), " +
+					"env_credentials (accessible with credentials of the environment), auth_required, reachable (the " +
+					"response was not recognized) or unreachable.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"credential_sources": schema.MapAttribute{
+				Description: "Source of the credentials accepted by the brokers whose exposure is env_credentials, e.g. " +
+					"`KAFKA_SASL_USERNAME` or `url`, keyed like endpoints.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"accessible": schema.ListAttribute{
+				Description: "Brokers accessible anonymously or with credentials of the environment, as `broker@host:port`, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"names": schema.ListAttribute{
+				Description: "Topics, queues (as `vhost/queue`) and JetStream streams visible, as `broker@host:port/name`, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"exposure_found": schema.BoolAttribute{
+				Description: "True if at least one broker is accessible.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors of the connections to reachable brokers, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// mqResult is the outcome of probing one broker.
+type mqResult struct {
+	exposure string
+	// source names the credentials accepted, if not anonymous
+	source string
+	names  []string
+	err    error
+}
+
+// Read probes the brokers and updates the state.
+func (d *TerrapwnerMQProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerMQProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.UseEnvCredentials.IsNull() {
+		data.UseEnvCredentials = types.BoolValue(true)
+	}
+	if data.MaxNames.IsNull() {
+		data.MaxNames = types.Int64Value(defaultMQProbeMaxNames)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(defaultMQProbeTimeout)
+	}
+	getenv := d.snapshot.Getenv
+	kafkaEndpoints := defaultKafkaEndpoints(getenv)
+	if !data.KafkaEndpoints.IsNull() {
+		kafkaEndpoints = nil
+		resp.Diagnostics.Append(data.KafkaEndpoints.ElementsAs(ctx, &kafkaEndpoints, false)...)
+	}
+	rabbitMQEndpoints := []string{"http://127.0.0.1:15672"}
+	if !data.RabbitMQEndpoints.IsNull() {
+		rabbitMQEndpoints = nil
+		resp.Diagnostics.Append(data.RabbitMQEndpoints.ElementsAs(ctx, &rabbitMQEndpoints, false)...)
+	}
+	natsEndpoints := defaultNATSEndpoints(getenv)
+	if !data.NATSEndpoints.IsNull() {
+		natsEndpoints = nil
+		resp.Diagnostics.Append(data.NATSEndpoints.ElementsAs(ctx, &natsEndpoints, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if data.MaxNames.ValueInt64() < 1 {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("max_names must be at least 1, got: %d", data.MaxNames.ValueInt64()),
+		)
+		return
+	}
+
+	// Resolve every endpoint before connecting to anything
+	type target struct {
+		broker, address, baseURL string
+		credentials              []mqCredentials
+	}
+	var targets []target
+	useEnv := data.UseEnvCredentials.ValueBool()
+	for _, endpoint := range kafkaEndpoints {
+		address, err := parseMQAddress(endpoint, "kafka", 9092)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("kafka_endpoints must only contain host:port, got: %s", endpoint))
+			return
+		}
+		var credentials []mqCredentials
+		if useEnv {
+			credentials = envMQCredentials(getenv, "KAFKA_SASL_USERNAME", "KAFKA_SASL_PASSWORD", "")
+		}
+		targets = append(targets, target{"kafka", address, "", credentials})
+	}
+	for _, endpoint := range rabbitMQEndpoints {
+		baseURL, address, err := parseKVStoreEndpoint(endpoint, 15672)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("rabbitmq_endpoints must only contain URLs or host:port, got: %s", utils.RedactURL(endpoint)))
+			return
+		}
+		var credentials []mqCredentials
+		if useEnv {
+			credentials = append(urlMQCredentials(endpoint), envMQCredentials(getenv, "RABBITMQ_DEFAULT_USER", "RABBITMQ_DEFAULT_PASS", "")...)
+		}
+		targets = append(targets, target{"rabbitmq", address, baseURL, credentials})
+	}
+	for _, endpoint := range natsEndpoints {
+		address, err := parseMQAddress(endpoint, "nats", 4222)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("nats_endpoints must only contain nats:// URLs or host:port, got: %s", utils.RedactURL(endpoint)))
+			return
+		}
+		var credentials []mqCredentials
+		if useEnv {
+			credentials = append(urlMQCredentials(endpoint), envMQCredentials(getenv, "NATS_USER", "NATS_PASSWORD", "NATS_TOKEN")...)
+		}
+		targets = append(targets, target{"nats", address, "", credentials})
+	}
+
+	// Probe every broker concurrently, keeping the results in order
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	maxNames := int(data.MaxNames.ValueInt64())
+	results := make([]mqResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			switch t.broker {
+			case "kafka":
+				results[i] = probeKafka(ctx, t.address, t.credentials, timeout)
+			case "rabbitmq":
+				results[i] = probeRabbitMQ(ctx, t.baseURL, t.address, t.credentials, timeout)
+			default:
+				results[i] = probeNATS(ctx, t.address, t.credentials, timeout)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	endpoints := map[string]string{}
+	sources := map[string]string{}
+	accessible := []string{}
+	names := []string{}
+	var failures []string
+	for i, t := range targets {
+		key := t.broker + "@" + t.address
+		result := results[i]
+		endpoints[key] = result.exposure
+		if result.exposure == exposureEnvCredentials {
+			sources[key] = result.source
+		}
+		if result.exposure == exposureExposed || result.exposure == exposureEnvCredentials {
+			accessible = append(accessible, key)
+		}
+		sort.Strings(result.names)
+		for _, name := range result.names[:min(len(result.names), maxNames)] {
+			names = append(names, key+"/"+name)
+		}
+		if result.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", key, result.err))
+		}
+	}
+	sort.Strings(accessible)
+	sort.Strings(names)
+
+	data.ExposureFound = types.BoolValue(len(accessible) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	endpointsMap, diags := types.MapValueFrom(ctx, types.StringType, endpoints)
+	resp.Diagnostics.Append(diags...)
+	sourcesMap, diags := types.MapValueFrom(ctx, types.StringType, sources)
+	resp.Diagnostics.Append(diags...)
+	accessibleList, diags := types.ListValueFrom(ctx, types.StringType, accessible)
+	resp.Diagnostics.Append(diags...)
+	namesList, diags := types.ListValueFrom(ctx, types.StringType, names)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Endpoints = endpointsMap
+	data.CredentialSources = sourcesMap
+	data.Accessible = accessibleList
+	data.Names = namesList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// defaultKafkaEndpoints returns the bootstrap servers of the environment.
+func defaultKafkaEndpoints(getenv func(string) string) []string {
+	for _, name := range []string{"KAFKA_BOOTSTRAP_SERVERS", "KAFKA_BROKERS"} {
+		if servers := getenv(name); servers != "" {
+			return strings.Split(servers, ",")
+		}
+	}
+	return []string{"127.0.0.1:9092"}
+}
+
+// defaultNATSEndpoints returns the servers the NATS clients would use.
+func defaultNATSEndpoints(getenv func(string) string) []string {
+	if servers := getenv("NATS_URL"); servers != "" {
+		return strings.Split(servers, ",")
+	}
+	return []string{"nats://127.0.0.1:4222"}
+}
+
+// parseMQAddress returns the host:port address of an endpoint given as
+// host:port or as a URL of one of the schemes of the broker, using the default
+// port if none is set.
+func parseMQAddress(endpoint, broker string, defaultPort int) (string, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if !strings.Contains(endpoint, "://") {
+		endpoint = broker + "://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if (!strings.HasPrefix(u.Scheme, broker) && u.Scheme != "tls") || u.Hostname() == "" {
+		return "", fmt.Errorf("expected a %s URL", broker)
+	}
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), strconv.Itoa(defaultPort)), nil
+	}
+	return u.Host, nil
+}
+
+// envMQCredentials returns the credentials held by the given environment
+// variables, if set.
+func envMQCredentials(getenv func(string) string, userVar, passwordVar, tokenVar string) []mqCredentials {
+	var credentials []mqCredentials
+	if user := getenv(userVar); user != "" {
+		credentials = append(credentials, mqCredentials{source: userVar, user: user, password: getenv(passwordVar)})
+	}
+	if tokenVar != "" {
+		if token := getenv(tokenVar); token != "" {
+			credentials = append(credentials, mqCredentials{source: tokenVar, token: token})
+		}
+	}
+	return credentials
+}
+
+// urlMQCredentials returns the credentials held by the user info of an
+// endpoint URL, if any.
+func urlMQCredentials(endpoint string) []mqCredentials {
+	u, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil || u.User == nil || u.User.Username() == "" {
+		return nil
+	}
+	password, ok := u.User.Password()
+	if !ok && u.Scheme != "http" && u.Scheme != "https" {
+		// A NATS URL with a user only carries a token
+		return []mqCredentials{{source: "url", token: u.User.Username()}}
+	}
+	return []mqCredentials{{source: "url", user: u.User.Username(), password: password}}
+}
+
+// mqConnectionClosed returns whether err is the broker closing the
+// connection, which Kafka brokers do on requests they do not accept, e.g.
+// unauthenticated ones on a SASL listener.
+func mqConnectionClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// probeKafka lists the topics of a Kafka broker, anonymously and else with
+// SASL/PLAIN and each of the credentials.
+func probeKafka(ctx context.Context, address string, credentials []mqCredentials, timeout time.Duration) mqResult {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !portOpen(ctx, dialer, address) {
+		return mqResult{exposure: exposureUnreachable}
+	}
+
+	topics, err := kafkaTopics(ctx, dialer, address, nil, timeout)
+	if err == nil {
+		return mqResult{exposure: exposureExposed, names: topics}
+	}
+	if !mqConnectionClosed(err) {
+		return mqResult{exposure: exposureReachable, err: err}
+	}
+	result := mqResult{exposure: exposureAuthRequired}
+	for _, c := range credentials {
+		if c.user == "" {
+			continue
+		}
+		topics, err := kafkaTopics(ctx, dialer, address, &c, timeout)
+		if err == nil {
+			return mqResult{exposure: exposureEnvCredentials, source: c.source, names: topics}
+		}
+		if !mqConnectionClosed(err) {
+			result.err = err
+		}
+	}
+	return result
+}
+
+// kafkaTopics connects to a Kafka broker, authenticates with SASL/PLAIN if
+// credentials are given, and returns the topics of its metadata.
+func kafkaTopics(ctx context.Context, dialer *net.Dialer, address string, credentials *mqCredentials, timeout time.Duration) ([]string, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	client := &kafkaClient{conn: conn}
+
+	if credentials != nil {
+		// SaslHandshake v0 is followed by the raw SASL tokens
+		body := kafkaString("PLAIN")
+		response, err := client.request(kafkaSaslHandshakeAPIKey, 0, body)
+		if err != nil {
+			return nil, err
+		}
+		r := kafkaReader{b: response}
+		if code := r.int16(); r.err == nil && code != 0 {
+			return nil, fmt.Errorf("SASL/PLAIN not enabled (error %d)", code)
+		}
+		token := "\x00" + credentials.user + "\x00" + credentials.password
+		if _, err := client.raw([]byte(token)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Metadata v0 with no topics requests every topic
+	response, err := client.request(kafkaMetadataAPIKey, 0, []byte{0, 0, 0, 0})
+	if err != nil {
+		return nil, err
+	}
+	return parseKafkaMetadata(response)
+}
+
+// kafkaClient sends requests over a connection to a Kafka broker.
+type kafkaClient struct {
+	conn        net.Conn
+	correlation int32
+}
+
+// request sends a request and returns the body of its response.
+func (c *kafkaClient) request(apiKey, version int16, body []byte) ([]byte, error) {
+	c.correlation++
+	header := binary.BigEndian.AppendUint16(nil, uint16(apiKey))
+	header = binary.BigEndian.AppendUint16(header, uint16(version))
+	header = binary.BigEndian.AppendUint32(header, uint32(c.correlation))
+	header = append(header, kafkaString("terrapwner")...)
+
+	response, err := c.raw(append(header, body...))
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < 4 || int32(binary.BigEndian.Uint32(response)) != c.correlation {
+		return nil, errors.New("unexpected response")
+	}
+	return response[4:], nil
+}
+
+// raw sends a size-delimited message and returns the size-delimited response.
+func (c *kafkaClient) raw(message []byte) ([]byte, error) {
+	if _, err := c.conn.Write(binary.BigEndian.AppendUint32(nil, uint32(len(message)))); err != nil {
+		return nil, err
+	}
+	if _, err := c.conn.Write(message); err != nil {
+		return nil, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(c.conn, size[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if length > kafkaMaxResponseSize {
+		return nil, fmt.Errorf("response too large: %d bytes", length)
+	}
+	response := make([]byte, length)
+	if _, err := io.ReadFull(c.conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// kafkaString encodes a Kafka protocol string.
+func kafkaString(s string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(s))), s...)
+}
+
+// kafkaReader decodes Kafka protocol values, recording the first error.
+type kafkaReader struct {
+	b   []byte
+	err error
+}
+
+// next returns the next n bytes.
+func (r *kafkaReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = errors.New("truncated response")
+		return nil
+	}
+	value := r.b[:n]
+	r.b = r.b[n:]
+	return value
+}
+
+func (r *kafkaReader) int16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) string() string {
+	length := r.int16()
+	if length < 0 {
+		return ""
+	}
+	return string(r.next(int(length)))
+}
+
+// array returns the length of an array, whose elements each take at least
+// size bytes.
+func (r *kafkaReader) array(size int) int {
+	length := int(r.int32())
+	if length > len(r.b)/size {
+		r.err = errors.New("truncated response")
+	}
+	if r.err != nil || length < 0 {
+		return 0
+	}
+	return length
+}
+
+// parseKafkaMetadata returns the names of the topics of a Metadata v0 response
+// the broker reported no error for.
+func parseKafkaMetadata(body []byte) ([]string, error) {
+	r := kafkaReader{b: body}
+	for range r.array(10) {
+		r.int32()
+		r.string()
+		r.int32()
+	}
+	topics := []string{}
+	for range r.array(8) {
+		code := r.int16()
+		name := r.string()
+		for range r.array(18) {
+			r.int16()
+			r.int32()
+			r.int32()
+			r.next(4 * r.array(4))
+			r.next(4 * r.array(4))
+		}
+		if code == 0 {
+			topics = append(topics, name)
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid metadata response: %w", r.err)
+	}
+	return topics, nil
+}
+
+// probeRabbitMQ lists the queues of a RabbitMQ management API with the
+// default guest user and else each of the credentials.
+func probeRabbitMQ(ctx context.Context, baseURL, address string, credentials []mqCredentials, timeout time.Duration) mqResult {
+	client, ok := kvStoreClient(ctx, address, timeout)
+	if !ok {
+		return mqResult{exposure: exposureUnreachable}
+	}
+	defer client.CloseIdleConnections()
+
+	attempts := append([]mqCredentials{{user: "guest", password: "guest"}}, credentials...)
+	result := mqResult{exposure: exposureReachable}
+	for i, c := range attempts {
+		if c.user == "" {
+			continue
+		}
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return mqResult{exposure: exposureReachable, err: err}
+		}
+		u.User = url.UserPassword(c.user, c.password)
+
+		status, body, err := kvStoreRequest(ctx, client, http.MethodGet, u.String()+"/api/whoami", nil)
+		switch {
+		case err != nil:
+			return mqResult{exposure: exposureReachable, err: err}
+		case status == http.StatusUnauthorized:
+			result.exposure = exposureAuthRequired
+			continue
+		case status != http.StatusOK || !json.Valid(body):
+			return result
+		}
+
+		result = mqResult{exposure: exposureExposed}
+		if i > 0 {
+			result = mqResult{exposure: exposureEnvCredentials, source: c.source}
+		}
+		status, body, err = kvStoreRequest(ctx, client, http.MethodGet, u.String()+"/api/queues?columns=vhost,name", nil)
+		if err == nil && status != http.StatusOK {
+			err = fmt.Errorf("HTTP %d", status)
+		}
+		if err != nil {
+			result.err = fmt.Errorf("listing queues: %w", err)
+			return result
+		}
+		var queues []struct {
+			VHost string `json:"vhost"`
+			Name  string `json:"name"`
+		}
+		if err := json.Unmarshal(body, &queues); err != nil {
+			result.err = fmt.Errorf("listing queues: %w", err)
+			return result
+		}
+		for _, queue := range queues {
+			result.names = append(result.names, queue.VHost+"/"+queue.Name)
+		}
+		return result
+	}
+	return result
+}
+
+// probeNATS lists the JetStream streams of a NATS server, anonymously and else
+// with each of the credentials.
+func probeNATS(ctx context.Context, address string, credentials []mqCredentials, timeout time.Duration) mqResult {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !portOpen(ctx, dialer, address) {
+		return mqResult{exposure: exposureUnreachable}
+	}
+
+	streams, err := natsStreams(ctx, dialer, address, nil, timeout)
+	if err == nil {
+		return mqResult{exposure: exposureExposed, names: streams}
+	}
+	if !errors.Is(err, errNATSAuthorization) {
+		return mqResult{exposure: exposureReachable, err: err}
+	}
+	result := mqResult{exposure: exposureAuthRequired}
+	for _, c := range credentials {
+		streams, err := natsStreams(ctx, dialer, address, &c, timeout)
+		if err == nil {
+			return mqResult{exposure: exposureEnvCredentials, source: c.source, names: streams}
+		}
+		if !errors.Is(err, errNATSAuthorization) {
+			result.err = err
+		}
+	}
+	return result
+}
+
+// errNATSAuthorization is returned when a NATS server rejects the credentials.
+var errNATSAuthorization = errors.New("authorization violation")
+
+// natsStreams connects to a NATS server with the credentials, if any, and
+// returns the names of the JetStream streams of the account, none if
+// JetStream is not enabled.
+func natsStreams(ctx context.Context, dialer *net.Dialer, address string, credentials *mqCredentials, timeout time.Duration) ([]string, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { conn.Close() }()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReaderSize(conn, 4096)
+
+	line, err := natsReadLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	infoJSON, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		return nil, fmt.Errorf("not a NATS server")
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		return nil, fmt.Errorf("invalid INFO: %w", err)
+	}
+	if info.TLSRequired {
+		host, _, _ := net.SplitHostPort(address)
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+		reader = bufio.NewReaderSize(conn, 4096)
+	}
+
+	// Ask for an immediate no responders status if JetStream is not enabled
+	connect := map[string]any{
+		"verbose": false, "pedantic": false, "name": "terrapwner", "lang": "go", "version": "1.0.0",
+		"protocol": 1, "headers": true, "no_responders": true,
+	}
+	if credentials != nil {
+		if credentials.token != "" {
+			connect["auth_token"] = credentials.token
+		} else {
+			connect["user"] = credentials.user
+			connect["pass"] = credentials.password
+		}
+	}
+	encoded, _ := json.Marshal(connect)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", encoded); err != nil {
+		return nil, err
+	}
+	for {
+		line, err := natsReadLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			if strings.Contains(strings.ToLower(line), "authorization") {
+				return nil, errNATSAuthorization
+			}
+			return nil, errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+
+	inbox := "_INBOX.terrapwner." + exfilTransferID()
+	if _, err := fmt.Fprintf(conn, "SUB %s 1\r\nPUB $JS.API.STREAM.NAMES %s 2\r\n{}\r\n", inbox, inbox); err != nil {
+		return nil, err
+	}
+	for {
+		line, err := natsReadLine(reader)
+		if err != nil {
+			return nil, fmt.Errorf("listing streams: %w", err)
+		}
+		fields := strings.Fields(line)
+		switch {
+		case line == "PING":
+			if _, err := io.WriteString(conn, "PONG\r\n"); err != nil {
+				return nil, err
+			}
+			continue
+		case len(fields) < 4 || (fields[0] != "MSG" && fields[0] != "HMSG"):
+			continue
+		}
+
+		// MSG <subject> <sid> [reply] <size>, HMSG <subject> <sid> [reply] <header size> <total size>
+		size, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil || size < 0 || size > natsMaxLineSize {
+			return nil, fmt.Errorf("invalid message: %s", line)
+		}
+		payload := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return nil, err
+		}
+		payload = payload[:size]
+		if fields[0] == "HMSG" {
+			headerSize, err := strconv.Atoi(fields[len(fields)-2])
+			if err != nil || headerSize > size {
+				return nil, fmt.Errorf("invalid message: %s", line)
+			}
+			if strings.Contains(strings.SplitN(string(payload[:headerSize]), "\r\n", 2)[0], " 503") {
+				// No responders: JetStream is not enabled
+				return []string{}, nil
+			}
+			payload = payload[headerSize:]
+		}
+
+		var response struct {
+			Streams []string `json:"streams"`
+			Error   *struct {
+				Description string `json:"description"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return nil, fmt.Errorf("listing streams: %w", err)
+		}
+		if response.Error != nil {
+			return nil, fmt.Errorf("listing streams: %s", response.Error.Description)
+		}
+		return append([]string{}, response.Streams...), nil
+	}
+}
+
+// natsReadLine reads a protocol line without its CRLF terminator.
+func natsReadLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > natsMaxLineSize {
+			return "", errors.New("protocol line too long")
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// startKafkaBroker serves Kafka metadata with the orders and audit topics and
+// the unauthorized payments topic, after SASL/PLAIN authentication with the
+// given password for the ci user if not empty.
+func startKafkaBroker(t *testing.T, password string) string {
+	t.Helper()
+
+	port := serveFake(t, func(conn net.Conn) {
+		authenticated := password == ""
+		for {
+			var size [4]byte
+			if _, err := io.ReadFull(conn, size[:]); err != nil {
+				return
+			}
+			message := make([]byte, binary.BigEndian.Uint32(size[:]))
+			if _, err := io.ReadFull(conn, message); err != nil || len(message) < 8 {
+				return
+			}
+			apiKey := binary.BigEndian.Uint16(message)
+			response := append([]byte{}, message[4:8]...)
+			switch {
+			case !authenticated && apiKey == kafkaSaslHandshakeAPIKey:
+				response = binary.BigEndian.AppendUint16(response, 0)
+				response = binary.BigEndian.AppendUint32(response, 1)
+				response = append(response, kafkaString("PLAIN")...)
+				conn.Write(binary.BigEndian.AppendUint32(nil, uint32(len(response)))) //nolint:errcheck
+				conn.Write(response)                                                  //nolint:errcheck
+				// The raw SASL/PLAIN token follows
+				if _, err := io.ReadFull(conn, size[:]); err != nil {
+					return
+				}
+				token := make([]byte, binary.BigEndian.Uint32(size[:]))
+				if _, err := io.ReadFull(conn, token); err != nil || string(token) != "\x00ci\x00"+password {
+					return
+				}
+				authenticated = true
+				conn.Write([]byte{0, 0, 0, 0}) //nolint:errcheck
+				continue
+			case !authenticated || apiKey != kafkaMetadataAPIKey:
+				return
+			}
+
+			// One broker, and three topics of which one with one partition
+			response = binary.BigEndian.AppendUint32(response, 1)
+			response = binary.BigEndian.AppendUint32(response, 0)
+			response = append(response, kafkaString("127.0.0.1")...)
+			response = binary.BigEndian.AppendUint32(response, 9092)
+			response = binary.BigEndian.AppendUint32(response, 3)
+			for _, topic := range []struct {
+				code       uint16
+				name       string
+				partitions uint32
+			}{{0, "orders", 1}, {29, "payments", 0}, {0, "audit", 0}} {
+				response = binary.BigEndian.AppendUint16(response, topic.code)
+				response = append(response, kafkaString(topic.name)...)
+				response = binary.BigEndian.AppendUint32(response, topic.partitions)
+				for range topic.partitions {
+					response = append(response, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+					response = binary.BigEndian.AppendUint32(response, 1)
+					response = binary.BigEndian.AppendUint32(response, 0)
+					response = binary.BigEndian.AppendUint32(response, 1)
+					response = binary.BigEndian.AppendUint32(response, 0)
+				}
+			}
+			conn.Write(binary.BigEndian.AppendUint32(nil, uint32(len(response)))) //nolint:errcheck
+			conn.Write(response)                                                  //nolint:errcheck
+		}
+	})
+	return fmt.Sprintf("127.0.0.1:%d", port)
+}
+
+// startNATSServer serves the NATS protocol requiring the given token if not
+// empty, with JetStream and the ORDERS stream if jetStream.
+func startNATSServer(t *testing.T, token string, jetStream bool) string {
+	t.Helper()
+
+	port := serveFake(t, func(conn net.Conn) {
+		fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"auth_required\":%t}\r\n", token != "")
+		reader := bufio.NewReader(conn)
+		var connect struct {
+			AuthToken string `json:"auth_token"`
+		}
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch {
+			case strings.HasPrefix(line, "CONNECT "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &connect) //nolint:errcheck
+			case line == "PING\r\n" && connect.AuthToken != token:
+				fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			case line == "PING\r\n":
+				fmt.Fprint(conn, "PONG\r\n")
+			case len(fields) == 4 && fields[0] == "PUB" && fields[1] == "$JS.API.STREAM.NAMES":
+				if _, err := reader.ReadString('\n'); err != nil {
+					return
+				}
+				if jetStream {
+					payload := `{"type":"io.nats.jetstream.api.v1.stream_names_response","streams":["ORDERS"]}`
+					fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", fields[2], len(payload), payload)
+				} else {
+					header := "NATS/1.0 503\r\n\r\n"
+					fmt.Fprintf(conn, "HMSG %s 1 %d %d\r\n%s\r\n", fields[2], len(header), len(header), header)
+				}
+			}
+		}
+	})
+	return fmt.Sprintf("127.0.0.1:%d", port)
+}
+
+// startRabbitMQManagement serves a RabbitMQ management API accepting the ci
+// user with the given password.
+func startRabbitMQManagement(t *testing.T, password string) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "ci" || pass != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"not_authorized","reason":"Login failed"}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/whoami":
+			fmt.Fprint(w, `{"name":"ci","tags":["management"]}`)
+		case "/api/queues":
+			fmt.Fprint(w, `[{"vhost":"/","name":"jobs"},{"vhost":"billing","name":"invoices"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestParseKafkaMetadataTruncated(t *testing.T) {
+	// An array of a billion brokers in four bytes
+	if _, err := parseKafkaMetadata([]byte{0x3b, 0x9a, 0xca, 0x00}); err == nil {
+		t.Error("parseKafkaMetadata() error = nil, want truncated response")
+	}
+}
+
+func TestAccTerrapwnerMQProbeDataSource(t *testing.T) {
+	kafka := startKafkaBroker(t, "")
+	kafkaSASL := startKafkaBroker(t, "kafka-secret")
+	rabbitMQ := startRabbitMQManagement(t, "rabbit-secret")
+	nats := startNATSServer(t, "", false)
+	natsToken := startNATSServer(t, "nats-token", true)
+	closed := closedAddress(t)
+	t.Setenv("KAFKA_SASL_USERNAME", "ci")
+	t.Setenv("KAFKA_SASL_PASSWORD", "kafka-secret")
+	t.Setenv("RABBITMQ_DEFAULT_USER", "ci")
+	t.Setenv("RABBITMQ_DEFAULT_PASS", "rabbit-secret")
+	t.Setenv("NATS_TOKEN", "nats-token")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_mq_probe" "test" {
+  kafka_endpoints    = ["%[1]s", "%[2]s", "%[6]s"]
+  rabbitmq_endpoints = ["%[3]s"]
+  nats_endpoints     = ["nats://%[4]s", "%[5]s"]
+  timeout            = 2
+}
+`, kafka, kafkaSASL, rabbitMQ, nats, natsToken, closed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.%", "6"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.kafka@"+kafka, "exposed"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.kafka@"+kafkaSASL, "env_credentials"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.kafka@"+closed, "unreachable"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.rabbitmq@"+strings.TrimPrefix(rabbitMQ, "http://"), "env_credentials"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.nats@"+nats, "exposed"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.nats@"+natsToken, "env_credentials"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "credential_sources.%", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "credential_sources.kafka@"+kafkaSASL, "KAFKA_SASL_USERNAME"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "credential_sources.rabbitmq@"+strings.TrimPrefix(rabbitMQ, "http://"), "RABBITMQ_DEFAULT_USER"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "credential_sources.nats@"+natsToken, "NATS_TOKEN"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "accessible.#", "5"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "names.#", "7"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_mq_probe.test", "names.*", "kafka@"+kafka+"/orders"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_mq_probe.test", "names.*", "kafka@"+kafkaSASL+"/audit"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_mq_probe.test", "names.*", "rabbitmq@"+strings.TrimPrefix(rabbitMQ, "http://")+"/billing/invoices"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_mq_probe.test", "names.*", "nats@"+natsToken+"/ORDERS"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "exposure_found", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_mq_probe" "test" {
+  kafka_endpoints     = ["%[1]s"]
+  rabbitmq_endpoints  = ["%[2]s"]
+  nats_endpoints      = ["%[3]s"]
+  use_env_credentials = false
+}
+`, kafkaSASL, rabbitMQ, natsToken),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.kafka@"+kafkaSASL, "auth_required"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.rabbitmq@"+strings.TrimPrefix(rabbitMQ, "http://"), "auth_required"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "endpoints.nats@"+natsToken, "auth_required"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "names.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_mq_probe.test", "exposure_found", "false"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_mq_probe" "test" {
+  kafka_endpoints = ["https://kafka.internal.example.com"]
+}
+`,
+				ExpectError: regexp.MustCompile(`kafka_endpoints must only contain host:port`),
+			},
+		},
+	})
+}
@@ -5,15 +5,55 @@ package provider
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
 	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
+// domainFrontingRootCAs overrides the system roots used to verify fronted
+// endpoints. It is a variable so tests can trust a local TLS server.
+var domainFrontingRootCAs *x509.CertPool
+
+// tlsInspectionIssuerMarkers are lowercase substrings of the issuer names of the
+// certificates forged by SSL inspection proxies and security products.
+var tlsInspectionIssuerMarkers = []string{
+	"zscaler", "palo alto", "fortinet", "fortigate", "netskope", "blue coat", "bluecoat", "sophos", "cisco umbrella",
+	"check point", "forcepoint", "websense", "mcafee web gateway", "barracuda", "menlo security", "squid", "mitmproxy",
+	"portswigger", "charles proxy", "do_not_trust_fiddlerroot", "kaspersky", "eset ssl filter", "bitdefender", "avast",
+}
+
+// networkProbeTypes are the types of probes of terrapwner_network_probe.
+var networkProbeTypes = []string{"dns", "tcp", "udp", "icmp", "domain_fronting", "tls_interception", "quic"}
+
+// IP families network probes are made over.
+const (
+	ipFamilyAny  = "any"
+	ipFamilyIPv4 = "ipv4"
+	ipFamilyIPv6 = "ipv6"
+	ipFamilyDual = "dual"
+)
+
+// ipFamilies are the valid values of ip_family.
+var ipFamilies = []string{ipFamilyAny, ipFamilyIPv4, ipFamilyIPv6, ipFamilyDual}
+
 // Ensure the implementation satisfies the expected interfaces.
 var (
 	_ datasource.DataSource              = &TerrapwnerNetworkProbeDataSource{}
@@ -30,15 +70,41 @@ type TerrapwnerNetworkProbeDataSource struct{}
 
 // TerrapwnerNetworkProbeDataSourceModel describes the data source data model.
 type TerrapwnerNetworkProbeDataSourceModel struct {
-	Type          types.String `tfsdk:"type"`
-	Host          types.String `tfsdk:"host"`
-	Port          types.Int64  `tfsdk:"port"`
-	ExpectSuccess types.Bool   `tfsdk:"expect_success"`
-	Timeout       types.Int64  `tfsdk:"timeout"`
-	FailOnError   types.Bool   `tfsdk:"fail_on_error"`
-	Success       types.Bool   `tfsdk:"success"`
-	FailReason    types.String `tfsdk:"fail_reason"`
-	DurationMs    types.Int64  `tfsdk:"duration_ms"`
+	Type               types.String  `tfsdk:"type"`
+	Host               types.String  `tfsdk:"host"`
+	Port               types.Int64   `tfsdk:"port"`
+	SNI                types.String  `tfsdk:"sni"`
+	HostHeader         types.String  `tfsdk:"host_header"`
+	Path               types.String  `tfsdk:"path"`
+	ExpectSuccess      types.Bool    `tfsdk:"expect_success"`
+	Timeout            types.Int64   `tfsdk:"timeout"`
+	FailOnError        types.Bool    `tfsdk:"fail_on_error"`
+	Success            types.Bool    `tfsdk:"success"`
+	FailReason         types.String  `tfsdk:"fail_reason"`
+	DurationMs         types.Int64   `tfsdk:"duration_ms"`
+	StatusCode         types.Int64   `tfsdk:"status_code"`
+	Pins               types.List    `tfsdk:"pinned_fingerprints"`
+	Intercepted        types.Bool    `tfsdk:"intercepted"`
+	CertIssuer         types.String  `tfsdk:"certificate_issuer"`
+	CertChain          types.List    `tfsdk:"certificate_fingerprints"`
+	MethodUsed         types.String  `tfsdk:"method_used"`
+	IPFamily           types.String  `tfsdk:"ip_family"`
+	FamilyResults      types.Map     `tfsdk:"family_results"`
+	Attempts           types.Int64   `tfsdk:"attempts"`
+	IntervalMs         types.Int64   `tfsdk:"interval_ms"`
+	SuccessfulAttempts types.Int64   `tfsdk:"successful_attempts"`
+	AttemptResults     types.List    `tfsdk:"attempt_results"`
+	AttemptLatencies   types.List    `tfsdk:"attempt_latencies_ms"`
+	LatencyMinMs       types.Float64 `tfsdk:"latency_min_ms"`
+	LatencyAvgMs       types.Float64 `tfsdk:"latency_avg_ms"`
+	LatencyMaxMs       types.Float64 `tfsdk:"latency_max_ms"`
+	LatencyP95Ms       types.Float64 `tfsdk:"latency_p95_ms"`
+	SendPayload        types.String  `tfsdk:"send_payload"`
+	ExpectResponse     types.Bool    `tfsdk:"expect_response"`
+	ResponsePayload    types.String  `tfsdk:"response_payload"`
+	Resolver           types.String  `tfsdk:"resolver"`
+	BannerBytes        types.Int64   `tfsdk:"banner_bytes"`
+	Banner             types.String  `tfsdk:"banner"`
 }
 
 // Configure adds the provider configured client to the data source.
@@ -51,21 +117,42 @@ func (d *TerrapwnerNetworkProbeDataSource) Metadata(_ context.Context, req datas
 	resp.TypeName = req.ProviderTypeName + "_network_probe"
 }
 
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerNetworkProbeDataSource) Tags() []string {
+	return []string{categoryNetwork}
+}
+
 // Schema defines the schema for the data source.
 func (d *TerrapwnerNetworkProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Description: "Probes network connectivity to a host using DNS resolution, TCP connection, UDP connection, or ICMP ping.",
+		Description: "Probes network connectivity to a host using DNS resolution, TCP connection, UDP connection, ICMP ping, " +
+			"or an HTTPS request with a fronted Host header (domain fronting), or checks whether TLS connections to a " +
+			"host are intercepted (SSL inspection) on the egress path.",
 		Attributes: map[string]schema.Attribute{
 			"type": schema.StringAttribute{
-				Description: "Type of probe to perform. Must be one of: dns, tcp, udp, icmp",
-				Required:    true,
+				Description: "Type of probe to perform. Must be one of: dns, tcp, udp, icmp, domain_fronting, tls_interception, quic. " +
+					"quic probes start a QUIC handshake over UDP, as HTTP/3 clients do, and succeed if the server answers it, " +
+					"to check whether UDP egress escapes proxies that only control TCP",
+				Required: true,
 			},
 			"host": schema.StringAttribute{
 				Description: "Host to probe (domain name or IP address)",
 				Required:    true,
 			},
 			"port": schema.Int64Attribute{
-				Description: "Port to probe (required for tcp/udp probes, ignored for dns/icmp)",
+				Description: "Port to probe (required for tcp/udp probes, defaults to 443 for domain_fronting/tls_interception/quic, ignored for dns/icmp)",
+				Optional:    true,
+			},
+			"sni": schema.StringAttribute{
+				Description: "TLS server name sent when connecting for domain_fronting and quic probes (default: host)",
+				Optional:    true,
+			},
+			"host_header": schema.StringAttribute{
+				Description: "HTTP Host header naming the fronted backend (required for domain_fronting probes)",
+				Optional:    true,
+			},
+			"path": schema.StringAttribute{
+				Description: "Request path for domain_fronting probes (default: /)",
 				Optional:    true,
 			},
 			"expect_success": schema.BoolAttribute{
@@ -92,6 +179,125 @@ func (d *TerrapwnerNetworkProbeDataSource) Schema(_ context.Context, _ datasourc
 				Description: "Duration of the probe in milliseconds",
 				Computed:    true,
 			},
+			"status_code": schema.Int64Attribute{
+				Description: "HTTP status code returned to domain_fronting and tls_interception probes (0 for other probe types or when no response was received)",
+				Computed:    true,
+			},
+			"pinned_fingerprints": schema.ListAttribute{
+				Description: "SHA-256 fingerprints (hex, colons optional) of the expected certificates of the host for " +
+					"tls_interception probes, e.g. of its leaf, intermediate or root certificate. The connection is intercepted " +
+					"if the observed chain holds none of them. Without pins, only the issuers of known SSL inspection products " +
+					"are detected.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"intercepted": schema.BoolAttribute{
+				Description: "Whether tls_interception probes observed a forged certificate chain, i.e. SSL inspection on the egress path (false for other probe types)",
+				Computed:    true,
+			},
+			"certificate_issuer": schema.StringAttribute{
+				Description: "Issuer of the certificate presented to tls_interception probes (empty for other probe types)",
+				Computed:    true,
+			},
+			"certificate_fingerprints": schema.ListAttribute{
+				Description: "SHA-256 fingerprints (hex) of the certificate chain presented to tls_interception probes, leaf first (empty for other probe types)",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"ip_family": schema.StringAttribute{
+				Description: "IP family of the probe: any (the first address resolved), ipv4, ipv6, or dual to probe over " +
+					"both families, as egress controls often differ between stacks (default: any). Dual probes succeed if " +
+					"both families do; their status code, certificate and method outputs are those of the IPv4 probe, or of " +
+					"the IPv6 probe if the IPv4 one could not connect. The family of domain_fronting and tls_interception " +
+					"probes through a proxy is the family of the connection to the proxy.",
+				Optional: true,
+			},
+			"family_results": schema.MapAttribute{
+				Description: "Outcome by IP family probed (ipv4, ipv6): success or failure. Empty for the any family.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"resolver": schema.StringAttribute{
+				Description: "DNS server queried by dns probes instead of the resolvers of the system, to verify whether " +
+					"external resolvers are reachable bypassing internal DNS: an IP[:port] for plain DNS (port 53), " +
+					"tls://host[:port] for DNS over TLS (port 853), or an https:// URL for DNS over HTTPS, e.g. " +
+					"https://1.1.1.1/dns-query. Names in the hosts file of the system are still resolved from it.",
+				Optional: true,
+			},
+			"banner_bytes": schema.Int64Attribute{
+				Description: "Maximum number of bytes of banner read by tcp probes after connecting (and sending send_payload, " +
+					"if set), to capture service greetings such as those of SSH or SMTP servers (default: 0, no banner " +
+					"is read). The banner is the first data received before the timeout; a service sending none does " +
+					"not fail the probe.",
+				Optional: true,
+			},
+			"banner": schema.StringAttribute{
+				Description: "Banner read by tcp probes, with invalid UTF-8 sequences replaced (see response_payload for " +
+					"the raw bytes).",
+				Computed: true,
+			},
+			"send_payload": schema.StringAttribute{
+				Description: "Base64-encoded payload sent in a datagram by udp probes, e.g. a DNS query or an NTP request, " +
+					"so the probe verifies a service answers rather than only creating a socket, which always succeeds. " +
+					"For tcp probes, a protocol-specific prelude sent after connecting, before reading the banner, e.g. " +
+					"an HTTP request.",
+				Optional: true,
+			},
+			"expect_response": schema.BoolAttribute{
+				Description: "Whether udp probes must receive a response datagram to succeed (default: true if send_payload " +
+					"is set). Without send_payload, an empty datagram is sent.",
+				Optional: true,
+			},
+			"response_payload": schema.StringAttribute{
+				Description: "Base64-encoded response datagram received by udp probes expecting a response, or banner read " +
+					"by tcp probes (empty otherwise).",
+				Computed: true,
+			},
+			"attempts": schema.Int64Attribute{
+				Description: "Number of times the probe is made, to measure flaky paths and latency (default: 1). The probe " +
+					"succeeds if any attempt does; its details and failure are those of the last attempt.",
+				Optional: true,
+			},
+			"interval_ms": schema.Int64Attribute{
+				Description: "Interval in milliseconds between two attempts (default: 1000).",
+				Optional:    true,
+			},
+			"successful_attempts": schema.Int64Attribute{
+				Description: "Number of attempts that succeeded.",
+				Computed:    true,
+			},
+			"attempt_results": schema.ListAttribute{
+				Description: "Outcome of each attempt, in order: success or failure.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"attempt_latencies_ms": schema.ListAttribute{
+				Description: "Duration in milliseconds of each attempt, in order.",
+				ElementType: types.Float64Type,
+				Computed:    true,
+			},
+			"latency_min_ms": schema.Float64Attribute{
+				Description: "Minimum duration in milliseconds of the attempts that succeeded, null if none did.",
+				Computed:    true,
+			},
+			"latency_avg_ms": schema.Float64Attribute{
+				Description: "Average duration in milliseconds of the attempts that succeeded, null if none did.",
+				Computed:    true,
+			},
+			"latency_max_ms": schema.Float64Attribute{
+				Description: "Maximum duration in milliseconds of the attempts that succeeded, null if none did.",
+				Computed:    true,
+			},
+			"latency_p95_ms": schema.Float64Attribute{
+				Description: "95th percentile (nearest rank) of the duration in milliseconds of the attempts that succeeded, null if none did.",
+				Computed:    true,
+			},
+			"method_used": schema.StringAttribute{
+				Description: "Method icmp probes sent their echo requests with: raw (raw ICMP socket, requiring root or " +
+					"CAP_NET_RAW) or unprivileged (datagram ICMP socket, allowed on Linux by net.ipv4.ping_group_range and " +
+					"on macOS). Empty for other probe types or if no ICMP socket could be opened.",
+				Computed: true,
+			},
 		},
 	}
 }
@@ -99,8 +305,7 @@ func (d *TerrapwnerNetworkProbeDataSource) Schema(_ context.Context, _ datasourc
 // Read refreshes the Terraform state with the latest data.
 func (d *TerrapwnerNetworkProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
 	var state TerrapwnerNetworkProbeDataSourceModel
-	diags := req.Config.Get(ctx, &state)
-	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(req.Config.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -115,12 +320,42 @@ func (d *TerrapwnerNetworkProbeDataSource) Read(ctx context.Context, req datasou
 	if state.FailOnError.IsNull() {
 		state.FailOnError = types.BoolValue(false)
 	}
+	if state.IPFamily.IsNull() {
+		state.IPFamily = types.StringValue(ipFamilyAny)
+	}
+	if state.Attempts.IsNull() {
+		state.Attempts = types.Int64Value(1)
+	}
+	if state.IntervalMs.IsNull() {
+		state.IntervalMs = types.Int64Value(1000)
+	}
 
 	// Validate probe type
 	if state.Type.IsNull() || state.Type.ValueString() == "" {
 		resp.Diagnostics.AddError("Invalid probe type", "type must be specified")
 		return
 	}
+	if !slices.Contains(networkProbeTypes, state.Type.ValueString()) {
+		resp.Diagnostics.AddError("Invalid probe type", fmt.Sprintf("unsupported probe type: %s", state.Type.ValueString()))
+		return
+	}
+
+	// Validate attempts
+	if state.Attempts.ValueInt64() < 1 {
+		resp.Diagnostics.AddError("Invalid attempts", fmt.Sprintf("attempts must be positive, got: %d", state.Attempts.ValueInt64()))
+		return
+	}
+	if state.IntervalMs.ValueInt64() < 0 {
+		resp.Diagnostics.AddError("Invalid interval", fmt.Sprintf("interval_ms must not be negative, got: %d", state.IntervalMs.ValueInt64()))
+		return
+	}
+
+	// Validate IP family
+	if !slices.Contains(ipFamilies, state.IPFamily.ValueString()) {
+		resp.Diagnostics.AddError("Invalid IP family", fmt.Sprintf("ip_family must be one of %s, got: %s",
+			strings.Join(ipFamilies, ", "), state.IPFamily.ValueString()))
+		return
+	}
 
 	// Validate host
 	if state.Host.IsNull() || state.Host.ValueString() == "" {
@@ -140,31 +375,245 @@ func (d *TerrapwnerNetworkProbeDataSource) Read(ctx context.Context, req datasou
 		}
 	}
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(state.Timeout.ValueInt64())*time.Second)
-	defer cancel()
+	// Validate domain fronting settings
+	if state.Type.ValueString() == "domain_fronting" {
+		if state.HostHeader.IsNull() || state.HostHeader.ValueString() == "" {
+			resp.Diagnostics.AddError("Missing host header", "host_header is required for domain_fronting probes")
+			return
+		}
+		if state.Port.IsNull() {
+			state.Port = types.Int64Value(443)
+		}
+		if state.SNI.IsNull() {
+			state.SNI = types.StringValue(state.Host.ValueString())
+		}
+		if state.Path.IsNull() {
+			state.Path = types.StringValue("/")
+		}
+	}
+
+	// Validate QUIC settings
+	if state.Type.ValueString() == "quic" {
+		if state.Port.IsNull() {
+			state.Port = types.Int64Value(443)
+		}
+		if state.SNI.IsNull() {
+			state.SNI = types.StringValue(state.Host.ValueString())
+		}
+	}
+
+	// Validate payload settings
+	var payload []byte
+	if !state.SendPayload.IsNull() {
+		if state.Type.ValueString() != "tcp" && state.Type.ValueString() != "udp" {
+			resp.Diagnostics.AddError("Invalid payload", "send_payload is only supported for tcp and udp probes")
+			return
+		}
+		var err error
+		payload, err = base64.StdEncoding.DecodeString(state.SendPayload.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid payload", fmt.Sprintf("send_payload must be base64-encoded: %v", err))
+			return
+		}
+	}
+	if state.Type.ValueString() == "udp" {
+		if state.ExpectResponse.IsNull() {
+			state.ExpectResponse = types.BoolValue(!state.SendPayload.IsNull())
+		}
+	} else if !state.ExpectResponse.IsNull() {
+		resp.Diagnostics.AddError("Invalid payload", "expect_response is only supported for udp probes")
+		return
+	}
+
+	// Validate banner settings
+	if state.Type.ValueString() == "tcp" {
+		if state.BannerBytes.IsNull() {
+			state.BannerBytes = types.Int64Value(0)
+		}
+		if state.BannerBytes.ValueInt64() < 0 || state.BannerBytes.ValueInt64() > 65535 {
+			resp.Diagnostics.AddError("Invalid banner", fmt.Sprintf("banner_bytes must be between 0 and 65535, got: %d", state.BannerBytes.ValueInt64()))
+			return
+		}
+	} else if !state.BannerBytes.IsNull() {
+		resp.Diagnostics.AddError("Invalid banner", "banner_bytes is only supported for tcp probes")
+		return
+	}
+
+	// Validate DNS settings
+	resolver := net.DefaultResolver
+	if state.Type.ValueString() == "dns" && !state.Resolver.IsNull() {
+		var err error
+		resolver, err = utils.NewResolver(state.Resolver.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid resolver", err.Error())
+			return
+		}
+	} else if !state.Resolver.IsNull() {
+		resp.Diagnostics.AddError("Invalid resolver", "resolver is only supported for dns probes")
+		return
+	}
+
+	// Validate TLS interception settings
+	var pins []string
+	if state.Type.ValueString() == "tls_interception" {
+		if state.Port.IsNull() {
+			state.Port = types.Int64Value(443)
+		}
+		if !state.Pins.IsNull() {
+			resp.Diagnostics.Append(state.Pins.ElementsAs(ctx, &pins, false)...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+		}
+		for i, pin := range pins {
+			pins[i] = normalizeFingerprint(pin)
+			if len(pins[i]) != sha256.Size*2 {
+				resp.Diagnostics.AddError("Invalid pinned fingerprint", fmt.Sprintf("pinned_fingerprints must be hex SHA-256 fingerprints, got: %s", pin))
+				return
+			}
+		}
+	} else if !state.Pins.IsNull() {
+		resp.Diagnostics.AddError("Invalid pinned fingerprint", "pinned_fingerprints is only supported for tls_interception probes")
+		return
+	}
+	state.StatusCode = types.Int64Value(0)
+	state.Intercepted = types.BoolValue(false)
+	state.CertIssuer = types.StringValue("")
+	state.CertChain = types.ListValueMust(types.StringType, []attr.Value{})
+	state.MethodUsed = types.StringValue("")
+	state.ResponsePayload = types.StringValue("")
+	state.Banner = types.StringValue("")
 
 	// Start timing
 	start := time.Now()
 
-	// Perform the appropriate probe
-	var success bool
+	// Perform the appropriate probe over an IP family, recording its details
+	timeout := time.Duration(state.Timeout.ValueInt64()) * time.Second
+	host, port := state.Host.ValueString(), int(state.Port.ValueInt64())
+	probe := func(family string, record bool) (bool, string, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		switch state.Type.ValueString() {
+		case "dns":
+			return probeDNS(ctx, resolver, family, host)
+		case "tcp":
+			success, failReason, banner, err := probeTCP(ctx, family, host, port, payload, int(state.BannerBytes.ValueInt64()))
+			if record {
+				state.ResponsePayload = types.StringValue(base64.StdEncoding.EncodeToString(banner))
+				state.Banner = types.StringValue(strings.ToValidUTF8(string(banner), "\uFFFD"))
+			}
+			return success, failReason, err
+		case "udp":
+			success, failReason, response, err := probeUDP(ctx, family, host, port, payload, state.ExpectResponse.ValueBool())
+			if record {
+				state.ResponsePayload = types.StringValue(base64.StdEncoding.EncodeToString(response))
+			}
+			return success, failReason, err
+		case "icmp":
+			success, failReason, method, err := probeICMP(ctx, family, host)
+			if record {
+				state.MethodUsed = types.StringValue(method)
+			}
+			return success, failReason, err
+		case "quic":
+			return probeQUIC(ctx, family, host, port, state.SNI.ValueString())
+		case "domain_fronting":
+			success, failReason, statusCode, err := probeDomainFronting(ctx, family, host, port,
+				state.SNI.ValueString(), state.HostHeader.ValueString(), state.Path.ValueString())
+			if record {
+				state.StatusCode = types.Int64Value(int64(statusCode))
+			}
+			return success, failReason, err
+		default:
+			success, failReason, result, err := probeTLSInterception(ctx, family, host, port, pins)
+			state.Intercepted = types.BoolValue(state.Intercepted.ValueBool() || result.intercepted)
+			if record {
+				state.StatusCode = types.Int64Value(int64(result.statusCode))
+				state.CertIssuer = types.StringValue(result.issuer)
+				chain, diags := types.ListValueFrom(ctx, types.StringType, result.fingerprints)
+				resp.Diagnostics.Append(diags...)
+				state.CertChain = chain
+			}
+			return success, failReason, err
+		}
+	}
+
+	// Probe each family in dual mode, the details being those of the first
+	// family that could connect
+	families := []string{state.IPFamily.ValueString()}
+	if families[0] == ipFamilyDual {
+		families = []string{ipFamilyIPv4, ipFamilyIPv6}
+	}
+	familyResults := map[string]string{}
+	probeFamilies := func() (bool, string, error) {
+		success := true
+		var failReasons []string
+		var err error
+		record := true
+		for _, family := range families {
+			familySuccess, familyFailReason, familyErr := probe(family, record)
+			record = record && familyErr != nil
+			success = success && familySuccess
+			err = errors.Join(err, familyErr)
+			if family != ipFamilyAny && (familySuccess || familyResults[family] == "") {
+				familyResults[family] = outcomeFailure
+				if familySuccess {
+					familyResults[family] = outcomeSuccess
+				}
+			}
+			if familyFailReason != "" && len(families) > 1 {
+				familyFailReason = family + ": " + familyFailReason
+			}
+			if familyFailReason != "" {
+				failReasons = append(failReasons, familyFailReason)
+			}
+		}
+		return success, strings.Join(failReasons, "; "), err
+	}
+
+	// Repeat the probe, which succeeds if any attempt does, the details and
+	// failure being those of the last attempt
+	interval := time.Duration(state.IntervalMs.ValueInt64()) * time.Millisecond
+	attemptResults := []string{}
+	attemptLatencies := []float64{}
+	var latencies []float64
+	success := false
 	var failReason string
 	var err error
-
-	switch state.Type.ValueString() {
-	case "dns":
-		success, failReason, err = probeDNS(ctx, state.Host.ValueString())
-	case "tcp":
-		success, failReason, err = probeTCP(ctx, state.Host.ValueString(), int(state.Port.ValueInt64()))
-	case "udp":
-		success, failReason, err = probeUDP(ctx, state.Host.ValueString(), int(state.Port.ValueInt64()))
-	case "icmp":
-		success, failReason, err = probeICMP(ctx, state.Host.ValueString())
-	default:
-		resp.Diagnostics.AddError("Invalid probe type", fmt.Sprintf("unsupported probe type: %s", state.Type.ValueString()))
-		return
+	for attempt := range int(state.Attempts.ValueInt64()) {
+		if attempt > 0 && exfilSleep(ctx, interval) != nil {
+			break
+		}
+		attemptStart := time.Now()
+		attemptSuccess, attemptFailReason, attemptErr := probeFamilies()
+		latency := float64(time.Since(attemptStart).Microseconds()) / 1000
+		attemptLatencies = append(attemptLatencies, latency)
+		if attemptSuccess {
+			attemptResults = append(attemptResults, outcomeSuccess)
+			latencies = append(latencies, latency)
+			success = true
+		} else {
+			attemptResults = append(attemptResults, outcomeFailure)
+		}
+		failReason, err = attemptFailReason, attemptErr
+	}
+	if success {
+		failReason, err = "", nil
 	}
+	state.SuccessfulAttempts = types.Int64Value(int64(len(latencies)))
+	state.LatencyMinMs, state.LatencyAvgMs, state.LatencyMaxMs, state.LatencyP95Ms = latencyStatistics(latencies)
+
+	// Convert to Terraform types
+	familyResultsMap, diags := types.MapValueFrom(ctx, types.StringType, familyResults)
+	resp.Diagnostics.Append(diags...)
+	state.FamilyResults = familyResultsMap
+	attemptResultsList, diags := types.ListValueFrom(ctx, types.StringType, attemptResults)
+	resp.Diagnostics.Append(diags...)
+	state.AttemptResults = attemptResultsList
+	attemptLatenciesList, diags := types.ListValueFrom(ctx, types.Float64Type, attemptLatencies)
+	resp.Diagnostics.Append(diags...)
+	state.AttemptLatencies = attemptLatenciesList
 
 	// Handle probe errors
 	if err != nil {
@@ -188,69 +637,291 @@ func (d *TerrapwnerNetworkProbeDataSource) Read(ctx context.Context, req datasou
 	state.DurationMs = types.Int64Value(duration.Milliseconds())
 
 	// Set state
-	diags = resp.State.Set(ctx, &state)
-	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// latencyStatistics returns the minimum, average, maximum and 95th percentile
+// (nearest rank) of latencies, or null values if there are none.
+func latencyStatistics(latencies []float64) (types.Float64, types.Float64, types.Float64, types.Float64) {
+	if len(latencies) == 0 {
+		return types.Float64Null(), types.Float64Null(), types.Float64Null(), types.Float64Null()
+	}
+
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	var sum float64
+	for _, latency := range sorted {
+		sum += latency
+	}
+	p95 := sorted[int(math.Ceil(0.95*float64(len(sorted))))-1]
+	return types.Float64Value(sorted[0]), types.Float64Value(sum / float64(len(sorted))),
+		types.Float64Value(sorted[len(sorted)-1]), types.Float64Value(p95)
 }
 
-// probeDNS performs a DNS resolution probe.
+// familyNetwork restricts a Go network (tcp, udp or ip) to an IP family.
+func familyNetwork(network, family string) string {
+	switch family {
+	case ipFamilyIPv4:
+		return network + "4"
+	case ipFamilyIPv6:
+		return network + "6"
+	default:
+		return network
+	}
+}
+
+// familyDialer returns the dial function of HTTP transports, restricted to an IP family.
+func familyDialer(family string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, familyNetwork(strings.TrimRight(network, "46"), family), addr)
+	}
+}
+
+// probeDNS performs a DNS resolution probe with resolver, of the addresses
+// of family.
 //
 //nolint:unparam
-func probeDNS(ctx context.Context, host string) (bool, string, error) {
-	_, err := net.DefaultResolver.LookupHost(ctx, host)
+func probeDNS(ctx context.Context, resolver *net.Resolver, family, host string) (bool, string, error) {
+	_, err := resolver.LookupIP(ctx, familyNetwork("ip", family), host)
 	if err != nil {
 		return false, fmt.Sprintf("DNS resolution failed: %v", err), err
 	}
 	return true, "", nil
 }
 
-// probeTCP performs a TCP connection probe.
-//
-//nolint:unparam
-func probeTCP(ctx context.Context, host string, port int) (bool, string, error) {
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+// probeTCP performs a TCP connection probe over family. It sends payload, if
+// any, then if bannerBytes is positive returns the first bytes received until
+// the deadline of ctx, at most bannerBytes. A missing banner is not a failure.
+func probeTCP(ctx context.Context, family, host string, port int, payload []byte, bannerBytes int) (bool, string, []byte, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout(familyNetwork("tcp", family), addr, 5*time.Second)
 	if err != nil {
-		return false, fmt.Sprintf("TCP connection failed: %v", err), err
+		return false, fmt.Sprintf("TCP connection failed: %v", err), nil, err
 	}
-	conn.Close()
-	return true, "", nil
+	defer conn.Close()
+	if len(payload) == 0 && bannerBytes == 0 {
+		return true, "", nil, nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return false, fmt.Sprintf("TCP connection failed: %v", err), nil, err
+		}
+	}
+	if _, err := conn.Write(payload); err != nil {
+		return false, fmt.Sprintf("TCP send failed: %v", err), nil, err
+	}
+	if bannerBytes == 0 {
+		return true, "", nil, nil
+	}
+
+	buffer := make([]byte, bannerBytes)
+	n, _ := conn.Read(buffer)
+	return true, "", buffer[:n], nil
 }
 
-// probeUDP performs a UDP connection probe.
-//
-//nolint:unparam
-func probeUDP(ctx context.Context, host string, port int) (bool, string, error) {
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("udp", addr, 5*time.Second)
+// probeQUIC starts a QUIC handshake with host over family, sending
+// serverName as SNI, and succeeds if the server answers it.
+func probeQUIC(ctx context.Context, family, host string, port int, serverName string) (bool, string, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout(familyNetwork("udp", family), addr, 5*time.Second)
 	if err != nil {
 		return false, fmt.Sprintf("UDP connection failed: %v", err), err
 	}
-	conn.Close()
+	defer conn.Close()
+	if err := utils.QUICHandshake(ctx, conn, serverName); err != nil {
+		return false, fmt.Sprintf("QUIC handshake failed: %v", err), err
+	}
 	return true, "", nil
 }
 
-// probeICMP performs an ICMP ping probe.
-//
-//nolint:unparam
-func probeICMP(ctx context.Context, host string) (bool, string, error) {
+// probeUDP performs a UDP probe over family. It sends payload, if any, and
+// if expectResponse waits until the deadline of ctx for a response, which it
+// returns. Without payload nor expected response, only a socket is created.
+func probeUDP(ctx context.Context, family, host string, port int, payload []byte, expectResponse bool) (bool, string, []byte, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.DialTimeout(familyNetwork("udp", family), addr, 5*time.Second)
+	if err != nil {
+		return false, fmt.Sprintf("UDP connection failed: %v", err), nil, err
+	}
+	defer conn.Close()
+	if payload == nil && !expectResponse {
+		return true, "", nil, nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return false, fmt.Sprintf("UDP connection failed: %v", err), nil, err
+		}
+	}
+	if _, err := conn.Write(payload); err != nil {
+		return false, fmt.Sprintf("UDP send failed: %v", err), nil, err
+	}
+	if !expectResponse {
+		return true, "", nil, nil
+	}
+
+	// A closed port is reported by an ICMP port unreachable, failing the read
+	buffer := make([]byte, 65535)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		return false, fmt.Sprintf("No UDP response: %v", err), nil, err
+	}
+	return true, "", buffer[:n], nil
+}
+
+// probeICMP sends an ICMP echo request to the addresses of family of host
+// until one replies, through a raw socket or else an unprivileged one. It
+// returns the kind of socket used, empty if no ICMP socket could be opened.
+func probeICMP(ctx context.Context, family, host string) (bool, string, string, error) {
 	// Resolve the host to get IP address
-	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	ips, err := net.DefaultResolver.LookupIP(ctx, familyNetwork("ip", family), host)
 	if err != nil {
-		return false, fmt.Sprintf("Failed to resolve host: %v", err), err
+		return false, fmt.Sprintf("Failed to resolve host: %v", err), "", err
 	}
 	if len(ips) == 0 {
-		return false, "No IP addresses found", fmt.Errorf("no IP addresses found for host: %s", host)
+		return false, "No IP addresses found", "", fmt.Errorf("no IP addresses found for host: %s", host)
 	}
 
 	// Try to ping each IP address
+	method := ""
+	err = fmt.Errorf("no echo reply from host: %s", host)
 	for _, ip := range ips {
-		conn, err := net.DialTimeout("ip4:icmp", ip.String(), 5*time.Second)
-		if err != nil {
-			continue // Try next IP if this one fails
+		result, sendErr := utils.SendICMPPayload(ctx, "ip", ip.String(), [][]byte{[]byte("terrapwner")}, 5*time.Second)
+		if result != nil {
+			method = result.Socket
+		}
+		switch {
+		case sendErr != nil:
+			err = sendErr
+		case result.Replied > 0:
+			return true, "", method, nil
+		}
+	}
+
+	return false, fmt.Sprintf("ICMP ping failed for all IP addresses: %v", err), method, err
+}
+
+// probeDomainFronting connects to host with the given TLS server name and sends
+// an HTTPS request whose Host header names a different (fronted) backend. The
+// probe succeeds if the fronted request is answered without a client or server error.
+func probeDomainFronting(ctx context.Context, family, host string, port int, sni string, hostHeader string, path string) (bool, string, int, error) {
+	transport := &http.Transport{
+		Proxy:       http.ProxyFromEnvironment,
+		DialContext: familyDialer(family),
+		TLSClientConfig: &tls.Config{
+			ServerName: sni,
+			RootCAs:    domainFrontingRootCAs,
+		},
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: utils.LoggingTransport(transport),
+		// Redirects would leave the fronted connection
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	url := "https://" + net.JoinHostPort(host, strconv.Itoa(port)) + path
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return false, fmt.Sprintf("Failed to create request: %v", err), 0, err
+	}
+	req.Host = hostHeader
+	req.Header.Set("User-Agent", utils.GetUserAgent())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Sprintf("Fronted request failed: %v", err), 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return false, fmt.Sprintf("Fronted request rejected with HTTP %d", resp.StatusCode), resp.StatusCode, nil
+	}
+	return true, "", resp.StatusCode, nil
+}
+
+// tlsInterceptionResult is the certificate chain observed by a tls_interception probe.
+type tlsInterceptionResult struct {
+	statusCode   int
+	intercepted  bool
+	issuer       string
+	fingerprints []string
+}
+
+// probeTLSInterception sends an HTTPS request to host through the proxy of the
+// environment, if any, and checks the presented certificate chain against the
+// pinned fingerprints and the issuers of SSL inspection products. The probe
+// succeeds if the connection is not intercepted.
+func probeTLSInterception(ctx context.Context, family, host string, port int, pins []string) (bool, string, tlsInterceptionResult, error) {
+	var result tlsInterceptionResult
+	transport := &http.Transport{
+		Proxy:       http.ProxyFromEnvironment,
+		DialContext: familyDialer(family),
+		// The chain is checked below, an intercepting proxy's being untrusted by design
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport:     utils.LoggingTransport(transport),
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://"+net.JoinHostPort(host, strconv.Itoa(port))+"/", nil)
+	if err != nil {
+		return false, fmt.Sprintf("Failed to create request: %v", err), result, err
+	}
+	req.Header.Set("User-Agent", utils.GetUserAgent())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Sprintf("TLS connection failed: %v", err), result, err
+	}
+	resp.Body.Close()
+	result.statusCode = resp.StatusCode
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		err := fmt.Errorf("no certificate presented")
+		return false, fmt.Sprintf("TLS connection failed: %v", err), result, err
+	}
+
+	chain := resp.TLS.PeerCertificates
+	result.issuer = chain[0].Issuer.String()
+	pinned := false
+	var product string
+	for _, cert := range chain {
+		fingerprint := sha256.Sum256(cert.Raw)
+		result.fingerprints = append(result.fingerprints, hex.EncodeToString(fingerprint[:]))
+		pinned = pinned || slices.Contains(pins, hex.EncodeToString(fingerprint[:]))
+		if product == "" {
+			product = tlsInspectionProduct(cert)
 		}
-		conn.Close()
-		return true, "", nil
 	}
 
-	return false, "ICMP ping failed for all IP addresses", fmt.Errorf("ICMP ping failed for all IP addresses of host: %s", host)
+	switch {
+	case product != "":
+		result.intercepted = true
+		return false, fmt.Sprintf("TLS interception detected: certificate issued by %s (%s)", result.issuer, product), result, nil
+	case len(pins) > 0 && !pinned:
+		result.intercepted = true
+		return false, fmt.Sprintf("TLS interception detected: no pinned certificate in the chain issued by %s", result.issuer), result, nil
+	}
+	return true, "", result, nil
+}
+
+// tlsInspectionProduct returns the marker of the SSL inspection product named
+// by the subject or issuer of a certificate, empty if none.
+func tlsInspectionProduct(cert *x509.Certificate) string {
+	names := strings.ToLower(cert.Subject.String() + " " + cert.Issuer.String())
+	for _, marker := range tlsInspectionIssuerMarkers {
+		if strings.Contains(names, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+// normalizeFingerprint returns a hex fingerprint in lowercase without separators.
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(fingerprint)))
 }
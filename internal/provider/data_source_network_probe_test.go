@@ -4,12 +4,27 @@
 package provider
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
 	"fmt"
+	"math/big"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"golang.org/x/net/dns/dnsmessage"
 )
 
 func TestAccTerrapwnerNetworkProbeDataSource(t *testing.T) {
@@ -33,6 +48,32 @@ func TestAccTerrapwnerNetworkProbeDataSource(t *testing.T) {
 	}
 	defer udpConn.Close()
 
+	// Start a UDP server answering pong to ping
+	pongConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start UDP server: %v", err)
+	}
+	defer pongConn.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := pongConn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if string(buf[:n]) == "ping" {
+				pongConn.WriteTo([]byte("pong"), addr) //nolint:errcheck
+			}
+		}
+	}()
+
+	// Get a closed UDP port
+	closedConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start UDP listener: %v", err)
+	}
+	closedConn.Close()
+
 	// Extract host and port from TCP listener
 	tcpHost, tcpPortStr, err := net.SplitHostPort(tcpListener.Addr().String())
 	if err != nil {
@@ -44,6 +85,16 @@ func TestAccTerrapwnerNetworkProbeDataSource(t *testing.T) {
 		t.Fatalf("Failed to split UDP address: %v", err)
 	}
 
+	// Extract the ports of the other UDP sockets
+	_, pongPortStr, err := net.SplitHostPort(pongConn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to split UDP address: %v", err)
+	}
+	_, closedUDPPortStr, err := net.SplitHostPort(closedConn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to split UDP address: %v", err)
+	}
+
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
@@ -61,6 +112,8 @@ data "terrapwner_network_probe" "test" {
 					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
 					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "fail_reason", ""),
 					resource.TestCheckResourceAttrSet("data.terrapwner_network_probe.test", "duration_ms"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "method_used", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "family_results.%", "0"),
 				),
 			},
 			// Test successful UDP connection
@@ -142,6 +195,222 @@ data "terrapwner_network_probe" "test" {
 					resource.TestCheckResourceAttrSet("data.terrapwner_network_probe.test", "duration_ms"),
 				),
 			},
+			// Test ICMP echo to the loopback address, through the first socket the runner can open
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type    = "icmp"
+  host    = "127.0.0.1"
+  timeout = 1
+}
+`,
+				Check: func(s *terraform.State) error {
+					attributes := s.RootModule().Resources["data.terrapwner_network_probe.test"].Primary.Attributes
+					switch attributes["method_used"] {
+					case "raw", "unprivileged":
+						if attributes["success"] != "true" {
+							return fmt.Errorf("ICMP echo through the %s socket failed: %s", attributes["method_used"], attributes["fail_reason"])
+						}
+					case "":
+						if !strings.Contains(attributes["fail_reason"], "failed to open ICMP socket") {
+							return fmt.Errorf("unexpected fail_reason without ICMP socket: %s", attributes["fail_reason"])
+						}
+					default:
+						return fmt.Errorf("unexpected method_used: %s", attributes["method_used"])
+					}
+					return nil
+				},
+			},
+			// Test TCP connection restricted to IPv4
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type      = "tcp"
+  host      = %q
+  port      = %s
+  ip_family = "ipv4"
+  timeout   = 1
+}
+`, tcpHost, tcpPortStr),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "family_results.%", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "family_results.ipv4", "success"),
+				),
+			},
+			// Test TCP connection over both families to an IPv4-only host
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type      = "tcp"
+  host      = %q
+  port      = %s
+  ip_family = "dual"
+  timeout   = 1
+}
+`, tcpHost, tcpPortStr),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "family_results.%", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "family_results.ipv4", "success"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "family_results.ipv6", "failure"),
+					resource.TestMatchResourceAttr("data.terrapwner_network_probe.test", "fail_reason", regexp.MustCompile(`^ipv6: TCP connection failed: `)),
+				),
+			},
+			// Test invalid IP family
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type      = "dns"
+  host      = "localhost"
+  ip_family = "ipv5"
+}
+`,
+				ExpectError: regexp.MustCompile(`ip_family must be one of any, ipv4, ipv6, dual, got: ipv5`),
+			},
+			// Test repeated TCP connections
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type        = "tcp"
+  host        = %q
+  port        = %s
+  attempts    = 3
+  interval_ms = 10
+  timeout     = 1
+}
+`, tcpHost, tcpPortStr),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "successful_attempts", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "attempt_results.#", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "attempt_results.2", "success"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "attempt_latencies_ms.#", "3"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_network_probe.test", "latency_min_ms"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_network_probe.test", "latency_avg_ms"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_network_probe.test", "latency_max_ms"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_network_probe.test", "latency_p95_ms"),
+				),
+			},
+			// Test repeated TCP connections to a closed port
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type        = "tcp"
+  host        = "127.0.0.1"
+  port        = 1
+  attempts    = 2
+  interval_ms = 0
+  timeout     = 1
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "successful_attempts", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "attempt_results.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "attempt_results.0", "failure"),
+					resource.TestCheckNoResourceAttr("data.terrapwner_network_probe.test", "latency_min_ms"),
+					resource.TestCheckNoResourceAttr("data.terrapwner_network_probe.test", "latency_p95_ms"),
+				),
+			},
+			// Test invalid number of attempts
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type     = "dns"
+  host     = "localhost"
+  attempts = 0
+}
+`,
+				ExpectError: regexp.MustCompile(`attempts must be positive, got: 0`),
+			},
+			// Test UDP probe answered by the server
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type         = "udp"
+  host         = "127.0.0.1"
+  port         = %s
+  send_payload = "cGluZw=="
+  timeout      = 1
+}
+`, pongPortStr),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "expect_response", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "response_payload", "cG9uZw=="),
+				),
+			},
+			// Test UDP probe unanswered by the server
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type            = "udp"
+  host            = %q
+  port            = %s
+  expect_response = true
+  timeout         = 1
+}
+`, udpHost, udpPortStr),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "false"),
+					resource.TestMatchResourceAttr("data.terrapwner_network_probe.test", "fail_reason", regexp.MustCompile(`^No UDP response: .*i/o timeout`)),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "response_payload", ""),
+				),
+			},
+			// Test UDP probe of a closed port, the payload being sent without expecting a response
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type            = "udp"
+  host            = "127.0.0.1"
+  port            = %s
+  send_payload    = "cGluZw=="
+  expect_response = false
+  timeout         = 1
+}
+`, closedUDPPortStr),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type         = "udp"
+  host         = "127.0.0.1"
+  port         = %s
+  send_payload = "cGluZw=="
+  timeout      = 1
+}
+`, closedUDPPortStr),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "false"),
+					resource.TestMatchResourceAttr("data.terrapwner_network_probe.test", "fail_reason", regexp.MustCompile(`^No UDP response: .*connection refused`)),
+				),
+			},
+			// Test payload for another probe type
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type         = "icmp"
+  host         = "127.0.0.1"
+  send_payload = "cGluZw=="
+}
+`,
+				ExpectError: regexp.MustCompile(`send_payload is only supported for tcp and udp probes`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type            = "tcp"
+  host            = "127.0.0.1"
+  port            = 80
+  expect_response = true
+}
+`,
+				ExpectError: regexp.MustCompile(`expect_response is only supported for udp probes`),
+			},
 			// Test invalid probe type
 			{
 				Config: providerConfig + `
@@ -176,3 +445,543 @@ data "terrapwner_network_probe" "test" {
 		},
 	})
 }
+
+func TestLatencyStatistics(t *testing.T) {
+	minimum, average, maximum, p95 := latencyStatistics([]float64{4, 1, 2, 3, 10, 5, 6, 7, 8, 9, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20})
+	if minimum.ValueFloat64() != 1 || average.ValueFloat64() != 10.5 || maximum.ValueFloat64() != 20 || p95.ValueFloat64() != 19 {
+		t.Errorf("latencyStatistics() = %v, %v, %v, %v, want 1, 10.5, 20, 19", minimum, average, maximum, p95)
+	}
+
+	minimum, _, _, p95 = latencyStatistics(nil)
+	if !minimum.IsNull() || !p95.IsNull() {
+		t.Errorf("latencyStatistics(nil) = %v, %v, want null", minimum, p95)
+	}
+}
+
+func TestAccTerrapwnerNetworkProbeDataSource_DomainFronting(t *testing.T) {
+	// The edge only serves the fronted backend named in the Host header
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "backend.example.net" || r.TLS.ServerName != "example.com" {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	originalRoots := domainFrontingRootCAs
+	domainFrontingRootCAs = roots
+	t.Cleanup(func() { domainFrontingRootCAs = originalRoots })
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to split server address: %v", err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type        = "domain_fronting"
+  host        = %q
+  port        = %s
+  sni         = "example.com"
+  host_header = "backend.example.net"
+  timeout     = 2
+}
+`, host, port),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "status_code", "200"),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type           = "domain_fronting"
+  host           = %q
+  port           = %s
+  sni            = "example.com"
+  host_header    = "other.example.net"
+  timeout        = 2
+  expect_success = false
+}
+`, host, port),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "status_code", "421"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type = "domain_fronting"
+  host = "127.0.0.1"
+}
+`,
+				ExpectError: regexp.MustCompile("host_header is required for domain_fronting probes"),
+			},
+		},
+	})
+}
+
+// startInterceptingTLSServer starts a TLS server presenting a certificate
+// forged by an SSL inspection product, and returns its host and port.
+func startInterceptingTLSServer(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"Zscaler Inc."}, CommonName: "Zscaler Intermediate Root CA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, port
+}
+
+func TestAccTerrapwnerNetworkProbeDataSource_TLSInterception(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to split server address: %v", err)
+	}
+	fingerprint := sha256.Sum256(server.Certificate().Raw)
+	pin := hex.EncodeToString(fingerprint[:])
+	// Pins are accepted in the colon-separated uppercase format of openssl
+	var opensslPin []string
+	for i := 0; i < len(pin); i += 2 {
+		opensslPin = append(opensslPin, strings.ToUpper(pin[i:i+2]))
+	}
+
+	mitmHost, mitmPort := startInterceptingTLSServer(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "pinned" {
+  type                = "tls_interception"
+  host                = %[1]q
+  port                = %[2]s
+  pinned_fingerprints = ["%[3]s"]
+}
+
+data "terrapwner_network_probe" "forged" {
+  type                = "tls_interception"
+  host                = %[1]q
+  port                = %[2]s
+  pinned_fingerprints = ["%[4]s"]
+}
+
+data "terrapwner_network_probe" "product" {
+  type = "tls_interception"
+  host = %[5]q
+  port = %[6]s
+}
+`, host, port, strings.Join(opensslPin, ":"), strings.Repeat("ab", sha256.Size), mitmHost, mitmPort),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.pinned", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.pinned", "intercepted", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.pinned", "status_code", "200"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.pinned", "certificate_fingerprints.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.pinned", "certificate_fingerprints.0", pin),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.pinned", "certificate_issuer", "O=Acme Co"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.forged", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.forged", "intercepted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.forged", "fail_reason",
+						"TLS interception detected: no pinned certificate in the chain issued by O=Acme Co"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.product", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.product", "intercepted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.product", "fail_reason",
+						"TLS interception detected: certificate issued by CN=Zscaler Intermediate Root CA,O=Zscaler Inc. (zscaler)"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type                = "tls_interception"
+  host                = "example.com"
+  pinned_fingerprints = ["not-a-fingerprint"]
+}
+`,
+				ExpectError: regexp.MustCompile("pinned_fingerprints must be hex SHA-256 fingerprints, got: not-a-fingerprint"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type                = "dns"
+  host                = "example.com"
+  pinned_fingerprints = ["ab"]
+}
+`,
+				ExpectError: regexp.MustCompile("pinned_fingerprints is only supported for tls_interception probes"),
+			},
+		},
+	})
+}
+
+// startDNSServer starts a UDP DNS server answering A queries for probe.test
+// with 192.0.2.10 and any other name with NXDOMAIN, and returns its address.
+func startDNSServer(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			var parser dnsmessage.Parser
+			header, err := parser.Start(buffer[:n])
+			if err != nil {
+				continue
+			}
+			question, err := parser.Question()
+			if err != nil {
+				continue
+			}
+			found := question.Name.String() == "probe.test."
+			response := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: header.ID, Response: true, RCode: dnsmessage.RCodeNameError},
+				Questions: []dnsmessage.Question{question},
+			}
+			if found {
+				response.Header.RCode = dnsmessage.RCodeSuccess
+			}
+			if found && question.Type == dnsmessage.TypeA {
+				response.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}},
+				}}
+			}
+			packed, err := response.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(packed, addr) //nolint:errcheck
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestAccTerrapwnerNetworkProbeDataSource_Resolver(t *testing.T) {
+	resolver := startDNSServer(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type      = "dns"
+  host      = "probe.test"
+  ip_family = "ipv4"
+  resolver  = %q
+}
+`, resolver),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type           = "dns"
+  host           = "missing.test"
+  resolver       = %q
+  expect_success = false
+}
+`, resolver),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "false"),
+					resource.TestMatchResourceAttr("data.terrapwner_network_probe.test", "fail_reason",
+						regexp.MustCompile("^DNS resolution failed: .*no such host")),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type     = "dns"
+  host     = "probe.test"
+  resolver = "ftp://127.0.0.1"
+}
+`,
+				ExpectError: regexp.MustCompile("unsupported resolver scheme"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type     = "tcp"
+  host     = "127.0.0.1"
+  port     = 80
+  resolver = "1.1.1.1"
+}
+`,
+				ExpectError: regexp.MustCompile("resolver is only supported for dns probes"),
+			},
+		},
+	})
+}
+
+// startBannerServer starts a TCP server greeting its clients with an SSH
+// banner, or answering an HTTP response to clients speaking first, and
+// returns its host and port.
+func startBannerServer(t *testing.T) (string, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+
+				// Clients speaking within 200ms get an HTTP response instead of the greeting
+				buf := make([]byte, 1024)
+				conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond)) //nolint:errcheck
+				if n, _ := conn.Read(buf); strings.HasPrefix(string(buf[:n]), "GET ") {
+					conn.Write([]byte("HTTP/1.0 200 OK\r\n\r\n")) //nolint:errcheck
+					return
+				}
+				conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))         //nolint:errcheck
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+				conn.Read(buf)                                        //nolint:errcheck
+			}()
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to split server address: %v", err)
+	}
+	return host, port
+}
+
+func TestAccTerrapwnerNetworkProbeDataSource_Banner(t *testing.T) {
+	host, port := startBannerServer(t)
+
+	// A server accepting connections without sending anything
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer silent.Close()
+	_, silentPort, err := net.SplitHostPort(silent.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to split server address: %v", err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type         = "tcp"
+  host         = %q
+  port         = %s
+  banner_bytes = 256
+  timeout      = 2
+}
+`, host, port),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "banner", "SSH-2.0-OpenSSH_9.6\r\n"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "response_payload", "U1NILTIuMC1PcGVuU1NIXzkuNg0K"),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type         = "tcp"
+  host         = %q
+  port         = %s
+  banner_bytes = 7
+  timeout      = 2
+}
+`, host, port),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "banner", "SSH-2.0"),
+				),
+			},
+			// Test a prelude sent before reading the banner
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type         = "tcp"
+  host         = %q
+  port         = %s
+  send_payload = "R0VUIC8gSFRUUC8xLjANCg0K"
+  banner_bytes = 256
+  timeout      = 2
+}
+`, host, port),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "banner", "HTTP/1.0 200 OK\r\n\r\n"),
+				),
+			},
+			// Test a service sending no banner
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type         = "tcp"
+  host         = "127.0.0.1"
+  port         = %s
+  banner_bytes = 256
+  timeout      = 1
+}
+`, silentPort),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "banner", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "response_payload", ""),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type         = "tcp"
+  host         = "127.0.0.1"
+  port         = 22
+  banner_bytes = -1
+}
+`,
+				ExpectError: regexp.MustCompile("banner_bytes must be between 0 and 65535, got: -1"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_network_probe" "test" {
+  type         = "udp"
+  host         = "127.0.0.1"
+  port         = 53
+  banner_bytes = 64
+}
+`,
+				ExpectError: regexp.MustCompile("banner_bytes is only supported for tcp probes"),
+			},
+		},
+	})
+}
+
+// startQUICServer starts a UDP server answering QUIC Initial packets with a
+// Version Negotiation packet, and returns its port.
+func startQUICServer(t *testing.T) int {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if n < 7 || buffer[0]&0x80 == 0 || int(buffer[5])+7 > n {
+				continue
+			}
+			dcid := buffer[6 : 6+int(buffer[5])]
+			scidLength := int(buffer[6+len(dcid)])
+			if 7+len(dcid)+scidLength > n {
+				continue
+			}
+			scid := buffer[7+len(dcid) : 7+len(dcid)+scidLength]
+			response := append([]byte{0x80, 0, 0, 0, 0, byte(len(scid))}, scid...)
+			response = append(response, byte(len(dcid)))
+			response = append(response, dcid...)
+			response = append(response, 0, 0, 0, 1)
+			conn.WriteTo(response, addr) //nolint:errcheck
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port //nolint:forcetypeassert
+}
+
+func TestAccTerrapwnerNetworkProbeDataSource_QUIC(t *testing.T) {
+	port := startQUICServer(t)
+
+	// A UDP server not speaking QUIC
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer silent.Close()
+	silentPort := silent.LocalAddr().(*net.UDPAddr).Port //nolint:forcetypeassert
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type = "quic"
+  host = "127.0.0.1"
+  port = %d
+  sni  = "www.example.com"
+}
+`, port),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_network_probe" "test" {
+  type    = "quic"
+  host    = "127.0.0.1"
+  port    = %d
+  timeout = 1
+}
+`, silentPort),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_network_probe.test", "sni", "127.0.0.1"),
+					resource.TestMatchResourceAttr("data.terrapwner_network_probe.test", "fail_reason", regexp.MustCompile("^QUIC handshake failed: .*timeout")),
+				),
+			},
+		},
+	})
+}
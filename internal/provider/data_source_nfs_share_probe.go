@@ -0,0 +1,367 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerNFSShareProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerNFSShareProbeDataSource{}
+)
+
+// Access to a network share.
+const (
+	shareAccessible = "accessible"
+	shareDenied     = "denied"
+	shareAdvertised = "advertised"
+)
+
+// defaultNFSShareProbeTimeout is the default timeout in seconds of each connection and request.
+const defaultNFSShareProbeTimeout = 5
+
+// NewTerrapwnerNFSShareProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerNFSShareProbeDataSource() datasource.DataSource {
+	return &TerrapwnerNFSShareProbeDataSource{}
+}
+
+// TerrapwnerNFSShareProbeDataSource is the data source implementation.
+type TerrapwnerNFSShareProbeDataSource struct{}
+
+// TerrapwnerNFSShareProbeDataSourceModel describes the data source data model.
+type TerrapwnerNFSShareProbeDataSourceModel struct {
+	Hosts         types.List   `tfsdk:"hosts"`
+	Protocols     types.List   `tfsdk:"protocols"`
+	SMBShares     types.List   `tfsdk:"smb_shares"`
+	TestAccess    types.Bool   `tfsdk:"test_access"`
+	Timeout       types.Int64  `tfsdk:"timeout"`
+	Shares        types.Map    `tfsdk:"shares"`
+	NFSExports    types.Map    `tfsdk:"nfs_exports"`
+	SMBSessions   types.Map    `tfsdk:"smb_sessions"`
+	Accessible    types.List   `tfsdk:"accessible"`
+	ExposureFound types.Bool   `tfsdk:"exposure_found"`
+	FailReason    types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerNFSShareProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerNFSShareProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_nfs_share_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerNFSShareProbeDataSource) Tags() []string {
+	return []string{categoryNetwork}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerNFSShareProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Enumerates the NFS exports and SMB shares advertised by hosts of the build network and tests whether " +
+			"the runner can access them. NFS exports are listed through mountd, like showmount -e, and tested by requesting " +
+			"their file handle, as a mount would, from a reserved port when the provider runs as root. SMB shares are " +
+			"listed through the srvsvc pipe of an anonymous or guest session, like smbclient -L -N, and tested by opening " +
+			"their root directory. Nothing is mounted on the runner and no file is read.",
+		Attributes: map[string]schema.Attribute{
+			"hosts": schema.ListAttribute{
+				Description: "File servers to probe, as names or IP addresses.",
+				ElementType: types.StringType,
+				Required:    true,
+			},
+			"protocols": schema.ListAttribute{
+				Description: "Protocols to probe: nfs, smb (default: both).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"smb_shares": schema.ListAttribute{
+				Description: "SMB share names to test in addition to the advertised ones, for servers that deny share enumeration.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"test_access": schema.BoolAttribute{
+				Description: "If false, shares are only enumerated (default: true).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: fmt.Sprintf("Timeout in seconds for each connection and request (default: %d).", defaultNFSShareProbeTimeout),
+				Optional:    true,
+			},
+			"shares": schema.MapAttribute{
+				Description: "Access to each share, keyed by `nfs@host:/path` or `smb@host/share`: accessible, denied, or " +
+					"advertised when test_access is false.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"nfs_exports": schema.MapAttribute{
+				Description: "Clients allowed to mount each NFS export, as advertised by the server, keyed by `host:/path`. " +
+					"An export open to every client is reported as *.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"smb_sessions": schema.MapAttribute{
+				Description: "Session granted by each SMB host without credentials: anonymous, guest, denied or unreachable.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"accessible": schema.ListAttribute{
+				Description: "Shares accessible from the runner, keyed like shares, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"exposure_found": schema.BoolAttribute{
+				Description: "True if at least one share is accessible.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors of the requests to reachable hosts, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// shareProbeResult is the outcome of probing one protocol on one host.
+type shareProbeResult struct {
+	shares  map[string]string
+	exports map[string]string
+	session string
+	err     error
+}
+
+// Read probes the file servers and updates the state.
+func (d *TerrapwnerNFSShareProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerNFSShareProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.TestAccess.IsNull() {
+		data.TestAccess = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(defaultNFSShareProbeTimeout)
+	}
+	var hosts []string
+	resp.Diagnostics.Append(data.Hosts.ElementsAs(ctx, &hosts, false)...)
+	protocols := []string{"nfs", "smb"}
+	if !data.Protocols.IsNull() {
+		protocols = nil
+		resp.Diagnostics.Append(data.Protocols.ElementsAs(ctx, &protocols, false)...)
+	}
+	var smbShares []string
+	if !data.SMBShares.IsNull() {
+		resp.Diagnostics.Append(data.SMBShares.ElementsAs(ctx, &smbShares, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	for _, protocol := range protocols {
+		if protocol != "nfs" && protocol != "smb" {
+			resp.Diagnostics.AddError(
+				"Invalid configuration",
+				fmt.Sprintf("protocols must only contain nfs or smb, got: %s", protocol),
+			)
+			return
+		}
+	}
+
+	// Probe every host concurrently, keeping the results in order
+	type target struct{ protocol, host string }
+	var targets []target
+	for _, protocol := range protocols {
+		for _, host := range hosts {
+			targets = append(targets, target{protocol, host})
+		}
+	}
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	testAccess := data.TestAccess.ValueBool()
+	results := make([]shareProbeResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			if t.protocol == "nfs" {
+				results[i] = probeNFSShares(ctx, t.host, testAccess, timeout)
+			} else {
+				results[i] = probeSMBShares(ctx, t.host, smbShares, testAccess, timeout)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	shares := map[string]string{}
+	exports := map[string]string{}
+	sessions := map[string]string{}
+	accessible := []string{}
+	var failures []string
+	for i, t := range targets {
+		result := results[i]
+		for key, access := range result.shares {
+			shares[key] = access
+			if access == shareAccessible {
+				accessible = append(accessible, key)
+			}
+		}
+		for key, groups := range result.exports {
+			exports[key] = groups
+		}
+		if t.protocol == "smb" {
+			sessions[t.host] = result.session
+		}
+		if result.err != nil {
+			failures = append(failures, fmt.Sprintf("%s@%s: %v", t.protocol, t.host, result.err))
+		}
+	}
+	sort.Strings(accessible)
+
+	data.ExposureFound = types.BoolValue(len(accessible) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	sharesMap, diags := types.MapValueFrom(ctx, types.StringType, shares)
+	resp.Diagnostics.Append(diags...)
+	exportsMap, diags := types.MapValueFrom(ctx, types.StringType, exports)
+	resp.Diagnostics.Append(diags...)
+	sessionsMap, diags := types.MapValueFrom(ctx, types.StringType, sessions)
+	resp.Diagnostics.Append(diags...)
+	accessibleList, diags := types.ListValueFrom(ctx, types.StringType, accessible)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Shares = sharesMap
+	data.NFSExports = exportsMap
+	data.SMBSessions = sessionsMap
+	data.Accessible = accessibleList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// probeNFSShares lists the exports of an NFS server and tests whether each can
+// be mounted. Hosts without portmapper are skipped.
+func probeNFSShares(ctx context.Context, host string, testAccess bool, timeout time.Duration) shareProbeResult {
+	result := shareProbeResult{shares: map[string]string{}, exports: map[string]string{}}
+	dialer := &net.Dialer{Timeout: timeout}
+	if !portOpen(ctx, dialer, net.JoinHostPort(host, strconv.Itoa(nfsPortmapperPort))) {
+		return result
+	}
+
+	mountd, err := nfsMountdAddress(ctx, host, timeout)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	exports, err := nfsListExports(ctx, mountd, timeout)
+	if err != nil {
+		result.err = fmt.Errorf("listing exports: %w", err)
+		return result
+	}
+
+	var failures []string
+	for _, export := range exports {
+		groups := strings.Join(export.groups, ",")
+		if groups == "" {
+			groups = "*"
+		}
+		result.exports[host+":"+export.path] = groups
+
+		key := "nfs@" + host + ":" + export.path
+		result.shares[key] = shareAdvertised
+		if !testAccess {
+			continue
+		}
+		mountable, err := nfsMountable(ctx, mountd, export.path, timeout)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", export.path, err))
+			continue
+		}
+		result.shares[key] = shareDenied
+		if mountable {
+			result.shares[key] = shareAccessible
+		}
+	}
+	if len(failures) > 0 {
+		result.err = fmt.Errorf("mounting %s", strings.Join(failures, ", "))
+	}
+	return result
+}
+
+// probeSMBShares logs on an SMB server without credentials, lists its disk
+// shares and tests whether each can be read. Hosts without SMB are skipped.
+func probeSMBShares(ctx context.Context, host string, extraShares []string, testAccess bool, timeout time.Duration) shareProbeResult {
+	result := shareProbeResult{shares: map[string]string{}, session: smbSessionUnreachable}
+	dialer := &net.Dialer{Timeout: timeout}
+	if !portOpen(ctx, dialer, net.JoinHostPort(host, strconv.Itoa(smbPort))) {
+		return result
+	}
+
+	session, err := smbLogon(ctx, host, timeout)
+	if err != nil {
+		// Status errors are refused logons, others are protocol failures
+		result.session = smbSessionDenied
+		if _, ok := err.(smbStatusError); !ok {
+			result.err = err
+		}
+		return result
+	}
+	defer session.Close()
+	result.session = session.kind
+
+	names := append([]string(nil), extraShares...)
+	shares, err := session.ListShares()
+	if err != nil {
+		result.err = fmt.Errorf("listing shares: %w", err)
+	}
+	for _, share := range shares {
+		// Printers and the IPC$ share are not file shares
+		if share.shareType&0x0fffffff == shareTypeDisk {
+			names = append(names, share.name)
+		}
+	}
+
+	for _, name := range names {
+		key := "smb@" + host + "/" + name
+		if _, ok := result.shares[key]; ok {
+			continue
+		}
+		result.shares[key] = shareAdvertised
+		if !testAccess {
+			continue
+		}
+		readable, err := session.ShareReadable(name)
+		if err != nil {
+			result.err = fmt.Errorf("testing %s: %w", name, err)
+			delete(result.shares, key)
+			continue
+		}
+		result.shares[key] = shareDenied
+		if readable {
+			result.shares[key] = shareAccessible
+		}
+	}
+	return result
+}
@@ -0,0 +1,325 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// serveFake accepts connections on a local port and serves each with handle.
+func serveFake(t *testing.T, handle func(net.Conn)) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// startFakeNFSServer serves portmapper and mountd on one port, exporting the
+// given paths, mountable or not, and points the probe at it.
+func startFakeNFSServer(t *testing.T, exports map[string]bool) {
+	t.Helper()
+
+	var port int
+	port = serveFake(t, func(conn net.Conn) {
+		for {
+			call, err := readRPCRecord(conn)
+			if err != nil {
+				return
+			}
+			r := &xdrReader{buf: call}
+			xid := r.uint32()
+			r.uint32() // CALL
+			r.uint32() // RPC version
+			program, _, procedure := r.uint32(), r.uint32(), r.uint32()
+			r.uint32() // credentials
+			r.opaque()
+			r.uint32() // verifier
+			r.opaque()
+
+			var results []byte
+			switch {
+			case program == rpcPortmapperProgram && procedure == rpcPortmapperGetPort:
+				results = binary.BigEndian.AppendUint32(nil, uint32(port))
+			case program == rpcMountProgram && procedure == rpcMountExport:
+				for _, path := range []string{"/srv/public", "/srv/backups"} {
+					if _, ok := exports[path]; !ok {
+						continue
+					}
+					results = binary.BigEndian.AppendUint32(results, 1)
+					results = xdrString(results, path)
+					if path == "/srv/backups" {
+						results = binary.BigEndian.AppendUint32(results, 1)
+						results = xdrString(results, "10.0.0.0/8")
+					}
+					results = binary.BigEndian.AppendUint32(results, 0)
+				}
+				results = binary.BigEndian.AppendUint32(results, 0)
+			case program == rpcMountProgram && procedure == rpcMountMnt:
+				results = binary.BigEndian.AppendUint32(nil, 13) // MNT3ERR_ACCES
+				if exports[r.string()] {
+					results = binary.BigEndian.AppendUint32(nil, 0)
+					results = xdrOpaque(results, []byte("handle"))
+					results = binary.BigEndian.AppendUint32(results, 1)
+					results = binary.BigEndian.AppendUint32(results, rpcAuthUnix)
+				}
+			}
+
+			reply := binary.BigEndian.AppendUint32(nil, xid)
+			reply = append(reply, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0) // accepted, null verifier, success
+			reply = append(reply, results...)
+			_, _ = conn.Write(append(binary.BigEndian.AppendUint32(nil, 0x80000000|uint32(len(reply))), reply...))
+		}
+	})
+
+	original := nfsPortmapperPort
+	nfsPortmapperPort = port
+	t.Cleanup(func() { nfsPortmapperPort = original })
+}
+
+// startFakeSMBServer serves SMB2 with the given disk shares, readable or not,
+// and points the probe at it. Anonymous sessions are refused unless allowed,
+// in which case guest logons are.
+func startFakeSMBServer(t *testing.T, shares map[string]bool, allowAnonymous bool) {
+	t.Helper()
+
+	port := serveFake(t, func(conn net.Conn) {
+		trees := []string{""}
+		for {
+			var length [4]byte
+			if _, err := io.ReadFull(conn, length[:]); err != nil {
+				return
+			}
+			msg := make([]byte, binary.BigEndian.Uint32(length[:]))
+			if _, err := io.ReadFull(conn, msg); err != nil {
+				return
+			}
+			header, body := msg[:smb2HeaderSize], msg[smb2HeaderSize:]
+			treeID := binary.LittleEndian.Uint32(header[36:])
+
+			status := uint32(ntStatusSuccess)
+			response := []byte{4, 0, 0, 0}
+			switch binary.LittleEndian.Uint16(header[12:]) {
+			case smb2Negotiate:
+				response = make([]byte, 64)
+				binary.LittleEndian.PutUint16(response[4:], 0x0210)
+			case smb2SessionSetup:
+				token := body[24:]
+				response = make([]byte, 8)
+				binary.LittleEndian.PutUint16(response[4:], smb2HeaderSize+8)
+				index := bytes.Index(token, []byte("NTLMSSP\x00"))
+				if binary.LittleEndian.Uint32(token[index+8:]) == 1 {
+					status = ntStatusMoreProcessingRequired
+					binary.LittleEndian.PutUint64(header[40:], 0x42)
+					challenge := make([]byte, 48)
+					copy(challenge, "NTLMSSP\x00")
+					binary.LittleEndian.PutUint32(challenge[8:], 2)
+					binary.LittleEndian.PutUint32(challenge[44:], 48)
+					challenge = spnegoResponse(challenge)
+					binary.LittleEndian.PutUint16(response[6:], uint16(len(challenge)))
+					response = append(response, challenge...)
+				} else if anonymous := binary.LittleEndian.Uint16(token[index+36:]) == 0; anonymous != allowAnonymous {
+					status = ntStatusLogonFailure
+				} else if !anonymous {
+					response[2] = smb2SessionFlagIsGuest
+				}
+			case smb2TreeConnect:
+				path := string(body[8:])
+				share := strings.ReplaceAll(path[strings.LastIndex(path, "\\")+1:], "\x00", "")
+				if _, ok := shares[share]; !ok && share != "IPC$" {
+					status = ntStatusBadNetworkName
+					break
+				}
+				binary.LittleEndian.PutUint32(header[36:], uint32(len(trees)))
+				trees = append(trees, share)
+				response = make([]byte, 16)
+			case smb2Create:
+				if share := trees[treeID]; share != "IPC$" && !shares[share] {
+					status = ntStatusAccessDenied
+					break
+				}
+				response = make([]byte, 88)
+			case smb2Ioctl:
+				input := body[56:]
+				response = make([]byte, 48)
+				var output []byte
+				if input[2] == dcePTypeBind {
+					ack := binary.LittleEndian.AppendUint16(nil, 4280)
+					ack = binary.LittleEndian.AppendUint16(ack, 4280)
+					ack = binary.LittleEndian.AppendUint32(ack, 0x1234)
+					ack = binary.LittleEndian.AppendUint16(ack, 13)
+					ack = append(ack, "\\PIPE\\srvsvc\x00\x00"...)
+					ack = append(ack, 1, 0, 0, 0, 0, 0, 0, 0)
+					output = dcePDU(dcePTypeBindAck, 1, append(ack, make([]byte, 20)...))
+				} else {
+					names := []string{"IPC$"}
+					for name := range shares {
+						names = append(names, name)
+					}
+					stub := binary.LittleEndian.AppendUint32(nil, 1)
+					stub = binary.LittleEndian.AppendUint32(stub, 1)
+					stub = binary.LittleEndian.AppendUint32(stub, 0x00020000)
+					stub = binary.LittleEndian.AppendUint32(stub, uint32(len(names)))
+					stub = binary.LittleEndian.AppendUint32(stub, 0x00020004)
+					stub = binary.LittleEndian.AppendUint32(stub, uint32(len(names)))
+					for _, name := range names {
+						shareType := uint32(shareTypeDisk)
+						if name == "IPC$" {
+							shareType = 0x80000003
+						}
+						stub = binary.LittleEndian.AppendUint32(stub, 0x00020008)
+						stub = binary.LittleEndian.AppendUint32(stub, shareType)
+						stub = binary.LittleEndian.AppendUint32(stub, 0x0002000c)
+					}
+					for _, name := range names {
+						stub = ndrString(stub, name)
+						stub = ndrString(stub, "remark")
+					}
+					stub = binary.LittleEndian.AppendUint32(stub, uint32(len(names)))
+					stub = binary.LittleEndian.AppendUint32(stub, 0)
+					stub = binary.LittleEndian.AppendUint32(stub, 0)
+					output = dcePDU(dcePTypeResponse, 2, append(make([]byte, 8), stub...))
+				}
+				binary.LittleEndian.PutUint32(response[32:], smb2HeaderSize+48)
+				binary.LittleEndian.PutUint32(response[36:], uint32(len(output)))
+				response = append(response, output...)
+			}
+
+			binary.LittleEndian.PutUint32(header[8:], status)
+			binary.LittleEndian.PutUint32(header[16:], 1) // response
+			if status != ntStatusSuccess && status != ntStatusMoreProcessingRequired {
+				response = make([]byte, 9)
+			}
+			packet := binary.BigEndian.AppendUint32(nil, uint32(len(header)+len(response)))
+			if _, err := conn.Write(append(append(packet, header...), response...)); err != nil {
+				return
+			}
+		}
+	})
+
+	original := smbPort
+	smbPort = port
+	t.Cleanup(func() { smbPort = original })
+}
+
+func TestProbeNFSShares(t *testing.T) {
+	startFakeNFSServer(t, map[string]bool{"/srv/public": true, "/srv/backups": false})
+
+	got := probeNFSShares(context.Background(), "127.0.0.1", true, 2*time.Second)
+	if got.err != nil {
+		t.Fatalf("probeNFSShares() error = %v", got.err)
+	}
+	want := map[string]string{"nfs@127.0.0.1:/srv/public": shareAccessible, "nfs@127.0.0.1:/srv/backups": shareDenied}
+	if fmt.Sprint(got.shares) != fmt.Sprint(want) {
+		t.Errorf("probeNFSShares() shares = %v, want %v", got.shares, want)
+	}
+	if got.exports["127.0.0.1:/srv/backups"] != "10.0.0.0/8" || got.exports["127.0.0.1:/srv/public"] != "*" {
+		t.Errorf("probeNFSShares() exports = %v", got.exports)
+	}
+}
+
+func TestProbeSMBShares(t *testing.T) {
+	tests := []struct {
+		allowAnonymous bool
+		wantSession    string
+	}{
+		{true, smbSessionAnonymous},
+		{false, smbSessionGuest},
+	}
+	for _, tt := range tests {
+		startFakeSMBServer(t, map[string]bool{"public": true, "finance": false}, tt.allowAnonymous)
+
+		got := probeSMBShares(context.Background(), "127.0.0.1", []string{"hidden"}, true, 2*time.Second)
+		if got.err != nil {
+			t.Fatalf("probeSMBShares() error = %v", got.err)
+		}
+		if got.session != tt.wantSession {
+			t.Errorf("probeSMBShares() session = %s, want %s", got.session, tt.wantSession)
+		}
+		want := map[string]string{"smb@127.0.0.1/public": shareAccessible, "smb@127.0.0.1/finance": shareDenied, "smb@127.0.0.1/hidden": shareDenied}
+		if fmt.Sprint(got.shares) != fmt.Sprint(want) {
+			t.Errorf("probeSMBShares() shares = %v, want %v", got.shares, want)
+		}
+	}
+
+	smbPort = 1
+	if got := probeSMBShares(context.Background(), "127.0.0.1", nil, true, time.Second); got.session != smbSessionUnreachable {
+		t.Errorf("probeSMBShares(closed) session = %s, want %s", got.session, smbSessionUnreachable)
+	}
+}
+
+func TestAccTerrapwnerNFSShareProbeDataSource(t *testing.T) {
+	startFakeNFSServer(t, map[string]bool{"/srv/public": true})
+	startFakeSMBServer(t, map[string]bool{"builds": true}, true)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_nfs_share_probe" "test" {
+  hosts = ["127.0.0.1"]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_nfs_share_probe.test", "shares.%", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_nfs_share_probe.test", "shares.nfs@127.0.0.1:/srv/public", "accessible"),
+					resource.TestCheckResourceAttr("data.terrapwner_nfs_share_probe.test", "shares.smb@127.0.0.1/builds", "accessible"),
+					resource.TestCheckResourceAttr("data.terrapwner_nfs_share_probe.test", "nfs_exports.127.0.0.1:/srv/public", "*"),
+					resource.TestCheckResourceAttr("data.terrapwner_nfs_share_probe.test", "smb_sessions.127.0.0.1", "anonymous"),
+					resource.TestCheckResourceAttr("data.terrapwner_nfs_share_probe.test", "accessible.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_nfs_share_probe.test", "exposure_found", "true"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_nfs_share_probe" "test" {
+  hosts       = ["127.0.0.1"]
+  protocols   = ["smb"]
+  test_access = false
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_nfs_share_probe.test", "shares.smb@127.0.0.1/builds", "advertised"),
+					resource.TestCheckResourceAttr("data.terrapwner_nfs_share_probe.test", "exposure_found", "false"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_nfs_share_probe" "test" {
+  hosts     = ["127.0.0.1"]
+  protocols = ["afp"]
+}
+`,
+				ExpectError: regexp.MustCompile("protocols must only contain nfs or smb"),
+			},
+		},
+	})
+}
@@ -0,0 +1,385 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerNotifyDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerNotifyDataSource{}
+)
+
+// NewTerrapwnerNotifyDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerNotifyDataSource() datasource.DataSource {
+	return &TerrapwnerNotifyDataSource{}
+}
+
+// TerrapwnerNotifyDataSource is the data source implementation.
+type TerrapwnerNotifyDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerNotifyDataSourceModel describes the data source data model.
+type TerrapwnerNotifyDataSourceModel struct {
+	Type            types.String `tfsdk:"type"`
+	Message         types.String `tfsdk:"message"`
+	EngagementID    types.String `tfsdk:"engagement_id"`
+	URL             types.String `tfsdk:"url"`
+	Headers         types.Map    `tfsdk:"headers"`
+	SMTPHost        types.String `tfsdk:"smtp_host"`
+	SMTPPort        types.Int64  `tfsdk:"smtp_port"`
+	SMTPUsername    types.String `tfsdk:"smtp_username"`
+	SMTPPassword    types.String `tfsdk:"smtp_password"`
+	From            types.String `tfsdk:"from"`
+	To              types.List   `tfsdk:"to"`
+	Subject         types.String `tfsdk:"subject"`
+	IncludeMetadata types.Bool   `tfsdk:"include_metadata"`
+	Timeout         types.Int64  `tfsdk:"timeout"`
+	Sent            types.Bool   `tfsdk:"sent"`
+	StatusCode      types.Int64  `tfsdk:"status_code"`
+	Metadata        types.Map    `tfsdk:"metadata"`
+	FailReason      types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerNotifyDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerNotifyDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_notify"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerNotifyDataSource) Tags() []string {
+	return []string{"notify", "write"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerNotifyDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Sends an exercise coordination message (e.g. \"engagement started on runner X\") to Slack, a webhook or by " +
+			"email. Unlike terrapwner_exfil, the message is meant to reach the exercise coordinators, and the engagement " +
+			"metadata (engagement ID, host, user, CI platform and run) is included automatically.",
+		Attributes: map[string]schema.Attribute{
+			"type": schema.StringAttribute{
+				Description: "Notification channel: slack, webhook or email.",
+				Required:    true,
+			},
+			"message": schema.StringAttribute{
+				Description: "Message to send.",
+				Required:    true,
+			},
+			"engagement_id": schema.StringAttribute{
+				Description: "Identifier of the engagement, included in the metadata.",
+				Optional:    true,
+			},
+			"url": schema.StringAttribute{
+				Description: "Slack incoming webhook or webhook URL (slack and webhook types).",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"headers": schema.MapAttribute{
+				Description: "Additional HTTP headers (slack and webhook types).",
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"smtp_host": schema.StringAttribute{
+				Description: "SMTP server (email type). STARTTLS is used when the server supports it.",
+				Optional:    true,
+			},
+			"smtp_port": schema.Int64Attribute{
+				Description: "SMTP port (default: 587).",
+				Optional:    true,
+			},
+			"smtp_username": schema.StringAttribute{
+				Description: "SMTP username, if the server requires authentication.",
+				Optional:    true,
+			},
+			"smtp_password": schema.StringAttribute{
+				Description: "SMTP password.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"from": schema.StringAttribute{
+				Description: "Sender address (email type).",
+				Optional:    true,
+			},
+			"to": schema.ListAttribute{
+				Description: "Recipient addresses (email type).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"subject": schema.StringAttribute{
+				Description: "Email subject (default: [terrapwner] followed by the engagement ID).",
+				Optional:    true,
+			},
+			"include_metadata": schema.BoolAttribute{
+				Description: "Whether to include the engagement metadata in the message (default: true).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds (default: 10).",
+				Optional:    true,
+			},
+			"sent": schema.BoolAttribute{
+				Description: "True if the notification was accepted.",
+				Computed:    true,
+			},
+			"status_code": schema.Int64Attribute{
+				Description: "HTTP status code returned (slack and webhook types), 0 otherwise.",
+				Computed:    true,
+			},
+			"metadata": schema.MapAttribute{
+				Description: "Engagement metadata attached to the message.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason the notification could not be sent, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read sends the notification and updates the state.
+func (d *TerrapwnerNotifyDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerNotifyDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.SMTPPort.IsNull() {
+		data.SMTPPort = types.Int64Value(587)
+	}
+	if data.IncludeMetadata.IsNull() {
+		data.IncludeMetadata = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(10)
+	}
+	if data.Subject.IsNull() {
+		data.Subject = types.StringValue(strings.TrimSpace("[terrapwner] " + data.EngagementID.ValueString()))
+	}
+
+	switch data.Type.ValueString() {
+	case "slack", "webhook":
+		if data.URL.ValueString() == "" {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("url is required for %s notifications", data.Type.ValueString()))
+			return
+		}
+	case "email":
+		if data.SMTPHost.ValueString() == "" || data.From.ValueString() == "" || len(data.To.Elements()) == 0 {
+			resp.Diagnostics.AddError("Invalid configuration", "smtp_host, from and to are required for email notifications")
+			return
+		}
+	default:
+		resp.Diagnostics.AddError("Invalid type", fmt.Sprintf("Unsupported notification type: %s", data.Type.ValueString()))
+		return
+	}
+
+	headers := map[string]string{}
+	if !data.Headers.IsNull() {
+		resp.Diagnostics.Append(data.Headers.ElementsAs(ctx, &headers, false)...)
+	}
+	var recipients []string
+	if !data.To.IsNull() {
+		resp.Diagnostics.Append(data.To.ElementsAs(ctx, &recipients, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Sent = types.BoolValue(false)
+	data.StatusCode = types.Int64Value(0)
+	data.FailReason = types.StringValue("")
+	metadata := map[string]string{}
+	if data.IncludeMetadata.ValueBool() {
+		metadata = d.engagementMetadata(data.EngagementID.ValueString())
+	}
+
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	var err error
+	switch data.Type.ValueString() {
+	case "slack":
+		text := data.Message.ValueString()
+		if len(metadata) > 0 {
+			text += "\n" + formatMetadata(metadata, " | ")
+		}
+		err = d.post(ctx, &data, headers, map[string]string{"text": text}, timeout)
+	case "webhook":
+		err = d.post(ctx, &data, headers, map[string]any{"message": data.Message.ValueString(), "metadata": metadata}, timeout)
+	case "email":
+		body := data.Message.ValueString()
+		if len(metadata) > 0 {
+			body += "\r\n\r\n" + formatMetadata(metadata, "\r\n")
+		}
+		err = sendNotificationEmail(&data, recipients, body, timeout)
+	}
+	if err != nil {
+		data.FailReason = types.StringValue(err.Error())
+	} else {
+		data.Sent = types.BoolValue(true)
+	}
+
+	// Convert to Terraform types
+	metadataMap, diags := types.MapValueFrom(ctx, types.StringType, metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Metadata = metadataMap
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// engagementMetadata describes where the notification comes from. Empty values are omitted.
+func (d *TerrapwnerNotifyDataSource) engagementMetadata(engagementID string) map[string]string {
+	hostname, _ := os.Hostname()
+	platform := d.snapshot.CIPlatform()
+
+	var info ciRunInfo
+	if extract, ok := ciRunInfoExtractors[platform]; ok {
+		info = extract(d.snapshot.Getenv)
+	}
+
+	metadata := map[string]string{}
+	for key, value := range map[string]string{
+		"engagement_id": engagementID,
+		"host":          hostname,
+		"user":          firstNonEmpty(d.snapshot.Getenv("USER"), d.snapshot.Getenv("USERNAME")),
+		"ci_platform":   platform,
+		"repository":    info.Repository,
+		"ref":           info.Ref,
+		"run_id":        info.RunID,
+		"run_url":       info.RunURL,
+		"actor":         info.Actor,
+		"sent_at":       time.Now().UTC().Format(time.RFC3339),
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+// post sends a JSON notification and records the response status in the model.
+func (d *TerrapwnerNotifyDataSource) post(ctx context.Context, data *TerrapwnerNotifyDataSourceModel, headers map[string]string, payload any, timeout time.Duration) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, ok := headers["Content-Type"]; !ok {
+		headers["Content-Type"] = "application/json"
+	}
+
+	resp, err := utils.HTTPRequest(ctx, "POST", data.URL.ValueString(), headers, body, timeout)
+	if err != nil {
+		// The webhook URL holds its secret, so only the cause is reported
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	data.StatusCode = types.Int64Value(int64(resp.StatusCode))
+	if !resp.IsSuccess() {
+		return fmt.Errorf("notification returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendNotificationEmail sends the notification through the configured SMTP server.
+func sendNotificationEmail(data *TerrapwnerNotifyDataSourceModel, recipients []string, body string, timeout time.Duration) error {
+	address := net.JoinHostPort(data.SMTPHost.ValueString(), strconv.FormatInt(data.SMTPPort.ValueInt64(), 10))
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, data.SMTPHost.ValueString())
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: data.SMTPHost.ValueString()}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if data.SMTPUsername.ValueString() != "" {
+		auth := smtp.PlainAuth("", data.SMTPUsername.ValueString(), data.SMTPPassword.ValueString(), data.SMTPHost.ValueString())
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(data.From.ValueString()); err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		data.From.ValueString(), strings.Join(recipients, ", "), data.Subject.ValueString(), time.Now().Format(time.RFC1123Z), body)
+	if _, err := writer.Write([]byte(message)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// formatMetadata renders metadata as sorted key: value pairs.
+func formatMetadata(metadata map[string]string, separator string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+": "+metadata[key])
+	}
+	return strings.Join(pairs, separator)
+}
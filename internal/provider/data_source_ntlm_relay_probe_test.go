@@ -0,0 +1,60 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"io/fs"
+	"regexp"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestIsAuthStageError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&fs.PathError{Op: "stat", Path: `\\host\share\terrapwner.txt`, Err: syscall.Errno(winErrorLogonFailure)}, true},
+		{&fs.PathError{Op: "stat", Path: `\\host\share\terrapwner.txt`, Err: syscall.Errno(winErrorAccessDenied)}, true},
+		{syscall.Errno(winErrorNetworkAccessDenied), true},
+		// ERROR_FILE_NOT_FOUND and ERROR_PATH_NOT_FOUND do not tell whether credentials were sent
+		{&fs.PathError{Op: "stat", Path: `\\host\share\terrapwner.txt`, Err: syscall.Errno(2)}, false},
+		{syscall.Errno(3), false},
+	} {
+		if got := isAuthStageError(tc.err); got != tc.want {
+			t.Errorf("isAuthStageError(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestAccTerrapwnerNTLMRelayProbeDataSource_NonWindows(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("UNC coercion is performed on Windows runners")
+	}
+	t.Parallel()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_ntlm_relay_probe" "test" {
+  target_host = "127.0.0.1"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_ntlm_relay_probe.test", "supported", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_ntlm_relay_probe.test", "smb_auth_attempted", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_ntlm_relay_probe.test", "webdav_auth_attempted", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_ntlm_relay_probe.test", "share", "terrapwner"),
+					resource.TestMatchResourceAttr("data.terrapwner_ntlm_relay_probe.test", "fail_reason", regexp.MustCompile("requires a Windows runner")),
+				),
+			},
+		},
+	})
+}
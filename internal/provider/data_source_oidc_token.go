@@ -0,0 +1,318 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerOIDCTokenDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerOIDCTokenDataSource{}
+)
+
+// oidcTokenVariables lists, per platform, the environment variables that may hold
+// a pre-issued OIDC token. GitLab ID tokens are named in the pipeline definition,
+// so only the common names are checked unless token_variable is set.
+var oidcTokenVariables = []struct {
+	platform string
+	variable string
+}{
+	{"gitlab_ci", "GITLAB_OIDC_TOKEN"},
+	{"gitlab_ci", "CI_JOB_JWT_V2"},
+	{"gitlab_ci", "CI_JOB_JWT"},
+	{"circleci", "CIRCLE_OIDC_TOKEN_V2"},
+	{"circleci", "CIRCLE_OIDC_TOKEN"},
+}
+
+// oidcRepositoryClaims lists the claims identifying the source repository, by platform.
+var oidcRepositoryClaims = []string{
+	"repository",                   // GitHub Actions
+	"project_path",                 // GitLab CI
+	"oidc.circleci.com/project-id", // CircleCI
+}
+
+// NewTerrapwnerOIDCTokenDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerOIDCTokenDataSource() datasource.DataSource {
+	return &TerrapwnerOIDCTokenDataSource{}
+}
+
+// TerrapwnerOIDCTokenDataSource is the data source implementation.
+type TerrapwnerOIDCTokenDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerOIDCTokenDataSourceModel describes the data source data model.
+type TerrapwnerOIDCTokenDataSourceModel struct {
+	Audience       types.String `tfsdk:"audience"`
+	TokenVariable  types.String `tfsdk:"token_variable"`
+	IncludeToken   types.Bool   `tfsdk:"include_token"`
+	Timeout        types.Int64  `tfsdk:"timeout"`
+	Platform       types.String `tfsdk:"platform"`
+	TokenAvailable types.Bool   `tfsdk:"token_available"`
+	Issuer         types.String `tfsdk:"issuer"`
+	Subject        types.String `tfsdk:"subject"`
+	Audiences      types.List   `tfsdk:"audiences"`
+	Repository     types.String `tfsdk:"repository"`
+	Ref            types.String `tfsdk:"ref"`
+	ExpiresAt      types.String `tfsdk:"expires_at"`
+	Claims         types.String `tfsdk:"claims"`
+	Token          types.String `tfsdk:"token"`
+	TokenHandle    types.String `tfsdk:"token_handle"`
+	FailReason     types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerOIDCTokenDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerOIDCTokenDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_oidc_token"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerOIDCTokenDataSource) Tags() []string {
+	return []string{categoryNetwork, "ci", "credentials"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerOIDCTokenDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Obtains the OIDC token of the CI job (GitHub Actions through ACTIONS_ID_TOKEN_REQUEST_URL, GitLab CI and CircleCI " +
+			"through their ID token variables) and decodes its claims, showing which cloud trust policies the pipeline could satisfy. " +
+			"The raw token is only returned when include_token is set.",
+		Attributes: map[string]schema.Attribute{
+			"audience": schema.StringAttribute{
+				Description: "Audience to request from GitHub Actions (default: the repository owner URL chosen by GitHub). Ignored on other platforms.",
+				Optional:    true,
+			},
+			"token_variable": schema.StringAttribute{
+				Description: "Environment variable holding the token, for GitLab ID tokens declared under a custom name.",
+				Optional:    true,
+			},
+			"include_token": schema.BoolAttribute{
+				Description: "Whether to return the raw token (default: false, only the claims are reported).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for the token request (default: 10).",
+				Optional:    true,
+			},
+			"platform": schema.StringAttribute{
+				Description: "Platform the token was obtained from (github_actions, gitlab_ci, circleci), empty if none.",
+				Computed:    true,
+			},
+			"token_available": schema.BoolAttribute{
+				Description: "True if an OIDC token was obtained.",
+				Computed:    true,
+			},
+			"issuer": schema.StringAttribute{
+				Description: "The `iss` claim.",
+				Computed:    true,
+			},
+			"subject": schema.StringAttribute{
+				Description: "The `sub` claim, matched by cloud trust policies.",
+				Computed:    true,
+			},
+			"audiences": schema.ListAttribute{
+				Description: "The `aud` claim.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"repository": schema.StringAttribute{
+				Description: "Repository claim (`repository` on GitHub, `project_path` on GitLab, project ID on CircleCI).",
+				Computed:    true,
+			},
+			"ref": schema.StringAttribute{
+				Description: "The `ref` claim (branch or tag the job runs for).",
+				Computed:    true,
+			},
+			"expires_at": schema.StringAttribute{
+				Description: "Token expiration time in RFC3339 format.",
+				Computed:    true,
+			},
+			"claims": schema.StringAttribute{
+				Description: "All token claims as JSON.",
+				Computed:    true,
+			},
+			"token": schema.StringAttribute{
+				Description: "The raw token, only set when include_token is true.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"token_handle": schema.StringAttribute{
+				Description: "Handle of the token, set whenever a token was obtained. It can be referenced as {{secret:<handle>}} in the " +
+					"command of terrapwner_local_exec, which resolves it at execution time without the token being stored in the state. " +
+					"The handle is only valid within the current Terraform run.",
+				Computed: true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Reason the token could not be obtained or decoded, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read obtains the token and updates the state.
+func (d *TerrapwnerOIDCTokenDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerOIDCTokenDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.IncludeToken.IsNull() {
+		data.IncludeToken = types.BoolValue(false)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(10)
+	}
+
+	data.TokenAvailable = types.BoolValue(false)
+	data.Issuer = types.StringValue("")
+	data.Subject = types.StringValue("")
+	data.Repository = types.StringValue("")
+	data.Ref = types.StringValue("")
+	data.ExpiresAt = types.StringValue("")
+	data.Claims = types.StringValue("")
+	data.Token = types.StringNull()
+	data.TokenHandle = types.StringValue("")
+	data.FailReason = types.StringValue("")
+	audiences := []string{}
+
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	platform, token, err := d.oidcToken(ctx, data.TokenVariable.ValueString(), data.Audience.ValueString(), timeout)
+	data.Platform = types.StringValue(platform)
+	if err != nil {
+		data.FailReason = types.StringValue(err.Error())
+	} else if claims, err := utils.DecodeJWTClaims(token); err != nil {
+		data.FailReason = types.StringValue(err.Error())
+	} else {
+		data.TokenAvailable = types.BoolValue(true)
+		data.Issuer = types.StringValue(claimString(claims, "iss"))
+		data.Subject = types.StringValue(claimString(claims, "sub"))
+		data.Ref = types.StringValue(claimString(claims, "ref"))
+		audiences = utils.JWTAudiences(claims)
+		for _, claim := range oidcRepositoryClaims {
+			if repository := claimString(claims, claim); repository != "" {
+				data.Repository = types.StringValue(repository)
+				break
+			}
+		}
+		if exp, ok := claims["exp"].(float64); ok {
+			data.ExpiresAt = types.StringValue(time.Unix(int64(exp), 0).UTC().Format(time.RFC3339))
+		}
+		if encoded, err := json.Marshal(claims); err == nil {
+			data.Claims = types.StringValue(string(encoded))
+		}
+		if data.IncludeToken.ValueBool() {
+			data.Token = types.StringValue(token)
+		}
+		data.TokenHandle = types.StringValue(d.snapshot.Secrets().Put(token))
+	}
+
+	// Convert to Terraform types
+	audiencesList, diags := types.ListValueFrom(ctx, types.StringType, audiences)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Audiences = audiencesList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// oidcToken returns the platform and OIDC token available to the job. An explicit
+// token variable takes precedence, then the GitHub Actions token endpoint, then
+// the well-known token variables of the other platforms.
+func (d *TerrapwnerOIDCTokenDataSource) oidcToken(ctx context.Context, tokenVariable, audience string, timeout time.Duration) (string, string, error) {
+	if tokenVariable != "" {
+		token := d.snapshot.Getenv(tokenVariable)
+		if token == "" {
+			return d.snapshot.CIPlatform(), "", fmt.Errorf("%s is not set", tokenVariable)
+		}
+		return d.snapshot.CIPlatform(), token, nil
+	}
+
+	if requestURL := d.snapshot.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"); requestURL != "" {
+		token, err := githubOIDCToken(ctx, requestURL, d.snapshot.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"), audience, timeout)
+		return "github_actions", token, err
+	}
+	if d.snapshot.Getenv("GITHUB_ACTIONS") != "" {
+		return "github_actions", "", fmt.Errorf("ACTIONS_ID_TOKEN_REQUEST_URL is not set (the workflow lacks the id-token: write permission)")
+	}
+
+	for _, candidate := range oidcTokenVariables {
+		if token := d.snapshot.Getenv(candidate.variable); token != "" {
+			return candidate.platform, token, nil
+		}
+	}
+
+	return d.snapshot.CIPlatform(), "", fmt.Errorf("no OIDC token available")
+}
+
+// githubOIDCToken requests an ID token from the GitHub Actions token endpoint.
+func githubOIDCToken(ctx context.Context, requestURL, requestToken, audience string, timeout time.Duration) (string, error) {
+	if requestToken == "" {
+		return "", fmt.Errorf("ACTIONS_ID_TOKEN_REQUEST_TOKEN is not set")
+	}
+	if audience != "" {
+		u, err := url.Parse(requestURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid token request URL: %w", err)
+		}
+		query := u.Query()
+		query.Set("audience", audience)
+		u.RawQuery = query.Encode()
+		requestURL = u.String()
+	}
+
+	headers := map[string]string{
+		"Authorization": "Bearer " + requestToken,
+		"Accept":        "application/json",
+	}
+	resp, err := utils.HTTPRequest(ctx, "GET", requestURL, headers, nil, timeout)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	if !resp.IsSuccess() {
+		return "", fmt.Errorf("token request returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(resp.Body, &body); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if body.Value == "" {
+		return "", fmt.Errorf("token response has no value")
+	}
+	return body.Value, nil
+}
+
+// claimString returns a string claim, or an empty string if it is missing or not a string.
+func claimString(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccOIDCToken(payload string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	return header + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestAccTerrapwnerOIDCTokenDataSource_GitHubActions(t *testing.T) {
+	token := testAccOIDCToken(`{"iss":"https://token.actions.githubusercontent.com","sub":"repo:octo-org/infra:ref:refs/heads/main",` +
+		`"aud":"sts.amazonaws.com","repository":"octo-org/infra","ref":"refs/heads/main","exp":1700000000}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" || r.URL.Query().Get("audience") != "sts.amazonaws.com" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"value":%q}`, token)
+	}))
+	defer server.Close()
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", server.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_oidc_token" "test" {
+  audience = "sts.amazonaws.com"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "platform", "github_actions"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "token_available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "issuer", "https://token.actions.githubusercontent.com"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "subject", "repo:octo-org/infra:ref:refs/heads/main"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "audiences.0", "sts.amazonaws.com"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "repository", "octo-org/infra"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "ref", "refs/heads/main"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "expires_at", "2023-11-14T22:13:20Z"),
+					resource.TestCheckNoResourceAttr("data.terrapwner_oidc_token.test", "token"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerOIDCTokenDataSource_GitLabTokenVariable(t *testing.T) {
+	token := testAccOIDCToken(`{"iss":"https://gitlab.example.com","sub":"project_path:group/app:ref_type:branch:ref:main",` +
+		`"aud":["https://vault.example.com"],"project_path":"group/app","ref":"main"}`)
+	t.Setenv("GITLAB_CI", "true")
+	t.Setenv("VAULT_ID_TOKEN", token)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_oidc_token" "test" {
+  token_variable = "VAULT_ID_TOKEN"
+  include_token  = true
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "platform", "gitlab_ci"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "token_available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "audiences.0", "https://vault.example.com"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "repository", "group/app"),
+					resource.TestCheckResourceAttr("data.terrapwner_oidc_token.test", "token", token),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,298 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerOsqueryBridgeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerOsqueryBridgeDataSource{}
+)
+
+const (
+	// defaultOsqueryTimeout is the default query timeout in seconds.
+	defaultOsqueryTimeout = 30
+	// defaultOsqueryMaxRows is the default number of rows kept in the state.
+	defaultOsqueryMaxRows = 1000
+)
+
+// osqueryBinaries lists the executables looked up when no binary is given: in
+// the PATH, then in the default install locations of the packages.
+var osqueryBinaries = []string{
+	"osqueryi",
+	"/opt/osquery/bin/osqueryi",
+	`C:\Program Files\osquery\osqueryi.exe`,
+}
+
+// NewTerrapwnerOsqueryBridgeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerOsqueryBridgeDataSource() datasource.DataSource {
+	return &TerrapwnerOsqueryBridgeDataSource{}
+}
+
+// TerrapwnerOsqueryBridgeDataSource is the data source implementation.
+type TerrapwnerOsqueryBridgeDataSource struct{}
+
+// TerrapwnerOsqueryBridgeDataSourceModel describes the data source data model.
+type TerrapwnerOsqueryBridgeDataSourceModel struct {
+	Query      types.String `tfsdk:"query"`
+	Binary     types.String `tfsdk:"binary"`
+	Timeout    types.Int64  `tfsdk:"timeout"`
+	MaxRows    types.Int64  `tfsdk:"max_rows"`
+	Available  types.Bool   `tfsdk:"available"`
+	Version    types.String `tfsdk:"version"`
+	Success    types.Bool   `tfsdk:"success"`
+	Columns    types.List   `tfsdk:"columns"`
+	Rows       types.List   `tfsdk:"rows"`
+	RowCount   types.Int64  `tfsdk:"row_count"`
+	Truncated  types.Bool   `tfsdk:"truncated"`
+	FailReason types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerOsqueryBridgeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerOsqueryBridgeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_osquery_bridge"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerOsqueryBridgeDataSource) Tags() []string {
+	return []string{categoryExec, "detection"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerOsqueryBridgeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Runs an osquery SQL statement with osqueryi when osquery is installed on the runner, and exposes the result " +
+			"rows, giving access to host telemetry (processes, listening ports, users, packages, ...) without a dedicated data source " +
+			"per table. When osquery is not installed, available is false and no error is raised.",
+		Attributes: map[string]schema.Attribute{
+			"query": schema.StringAttribute{
+				Description: "osquery SQL statement, e.g. `SELECT pid, name, cmdline FROM processes`.",
+				Required:    true,
+			},
+			"binary": schema.StringAttribute{
+				Description: "Path of the osqueryi executable (default: osqueryi in the PATH or in the default install location).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: fmt.Sprintf("Timeout in seconds for the query (default: %d).", defaultOsqueryTimeout),
+				Optional:    true,
+			},
+			"max_rows": schema.Int64Attribute{
+				Description: fmt.Sprintf("Maximum number of rows kept, to bound the state size (default: %d).", defaultOsqueryMaxRows),
+				Optional:    true,
+			},
+			"available": schema.BoolAttribute{
+				Description: "True if osqueryi was found.",
+				Computed:    true,
+			},
+			"version": schema.StringAttribute{
+				Description: "osquery version, empty if unavailable.",
+				Computed:    true,
+			},
+			"success": schema.BoolAttribute{
+				Description: "True if the query ran successfully.",
+				Computed:    true,
+			},
+			"columns": schema.ListAttribute{
+				Description: "Column names of the result, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"rows": schema.ListAttribute{
+				Description: "Result rows, as maps of column name to value. Values are strings, as reported by osquery.",
+				ElementType: types.MapType{ElemType: types.StringType},
+				Computed:    true,
+			},
+			"row_count": schema.Int64Attribute{
+				Description: "Number of rows returned by the query, including rows beyond max_rows.",
+				Computed:    true,
+			},
+			"truncated": schema.BoolAttribute{
+				Description: "True if rows beyond max_rows were dropped.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Why osquery could not be run or the query failed, if it did.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read runs the query and updates the state.
+func (d *TerrapwnerOsqueryBridgeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerOsqueryBridgeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(defaultOsqueryTimeout)
+	}
+	if data.MaxRows.IsNull() {
+		data.MaxRows = types.Int64Value(defaultOsqueryMaxRows)
+	}
+	if data.MaxRows.ValueInt64() < 0 {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("max_rows must not be negative, got: %d", data.MaxRows.ValueInt64()),
+		)
+		return
+	}
+	if strings.TrimSpace(data.Query.ValueString()) == "" {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			"query must not be empty",
+		)
+		return
+	}
+
+	data.Available = types.BoolValue(false)
+	data.Version = types.StringValue("")
+	data.Success = types.BoolValue(false)
+	data.RowCount = types.Int64Value(0)
+	data.Truncated = types.BoolValue(false)
+	data.FailReason = types.StringValue("")
+	columns := []string{}
+	rows := []map[string]string{}
+
+	binary, err := findOsquery(data.Binary.ValueString())
+	if err != nil {
+		data.FailReason = types.StringValue(err.Error())
+	} else {
+		data.Available = types.BoolValue(true)
+		timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+
+		if result, err := utils.Execute(ctx, binary, []string{"--version"}, timeout); err == nil && result.ExitCode == 0 {
+			fields := strings.Fields(result.Stdout)
+			if len(fields) > 0 {
+				data.Version = types.StringValue(fields[len(fields)-1])
+			}
+		}
+
+		result, err := utils.Execute(ctx, binary, []string{"--json", data.Query.ValueString()}, timeout)
+		switch {
+		case err != nil:
+			data.FailReason = types.StringValue(fmt.Sprintf("failed to run osquery: %v", err))
+		case result.ExitCode != 0:
+			data.FailReason = types.StringValue(fmt.Sprintf("query failed with exit code %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr)))
+		default:
+			parsed, err := parseOsqueryRows(result.Stdout)
+			if err != nil {
+				data.FailReason = types.StringValue(err.Error())
+				break
+			}
+			data.Success = types.BoolValue(true)
+			data.RowCount = types.Int64Value(int64(len(parsed)))
+			columns = osqueryColumns(parsed)
+			if int64(len(parsed)) > data.MaxRows.ValueInt64() {
+				parsed = parsed[:data.MaxRows.ValueInt64()]
+				data.Truncated = types.BoolValue(true)
+			}
+			rows = parsed
+		}
+	}
+
+	// Convert to Terraform types
+	columnsList, diags := types.ListValueFrom(ctx, types.StringType, columns)
+	resp.Diagnostics.Append(diags...)
+	rowsList, diags := types.ListValueFrom(ctx, types.MapType{ElemType: types.StringType}, rows)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Columns = columnsList
+	data.Rows = rowsList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// findOsquery returns the path of the osqueryi executable.
+func findOsquery(binary string) (string, error) {
+	if binary != "" {
+		path, err := exec.LookPath(binary)
+		if err != nil {
+			return "", fmt.Errorf("osquery not found: %w", err)
+		}
+		return path, nil
+	}
+	for _, name := range osqueryBinaries {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("osquery is not installed (osqueryi not found)")
+}
+
+// parseOsqueryRows parses the JSON output of osqueryi. Values are converted to
+// strings, as some osquery versions report typed values.
+func parseOsqueryRows(output string) ([]map[string]string, error) {
+	var raw []map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(output))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse osquery output: %w", err)
+	}
+
+	rows := make([]map[string]string, 0, len(raw))
+	for _, r := range raw {
+		row := make(map[string]string, len(r))
+		for column, value := range r {
+			switch v := value.(type) {
+			case nil:
+				row[column] = ""
+			case string:
+				row[column] = v
+			case json.Number:
+				row[column] = v.String()
+			default:
+				encoded, _ := json.Marshal(v)
+				row[column] = string(encoded)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// osqueryColumns returns the sorted column names of the rows.
+func osqueryColumns(rows []map[string]string) []string {
+	seen := map[string]bool{}
+	columns := []string{}
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
@@ -0,0 +1,106 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOsqueryRows(t *testing.T) {
+	t.Parallel()
+
+	rows, err := parseOsqueryRows(`[
+  {"pid":"1","name":"init","path":""},
+  {"pid":12345678901234,"name":"sshd","path":null,"flags":[1]}
+]`)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"pid": "1", "name": "init", "path": ""},
+		{"pid": "12345678901234", "name": "sshd", "path": "", "flags": "[1]"},
+	}, rows)
+	assert.Equal(t, []string{"flags", "name", "path", "pid"}, osqueryColumns(rows))
+
+	rows, err = parseOsqueryRows("[\n\n]\n")
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+
+	_, err = parseOsqueryRows("Error: near \"SELEC\": syntax error")
+	assert.ErrorContains(t, err, "failed to parse osquery output")
+}
+
+func TestAccTerrapwnerOsqueryBridgeDataSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake osqueryi is a shell script")
+	}
+
+	// Fake osqueryi answering any query with two rows
+	osqueryi := filepath.Join(t.TempDir(), "osqueryi")
+	script := `#!/bin/sh
+if [ "$1" = "--version" ]; then echo "osqueryi version 5.10.2"; exit 0; fi
+case "$2" in
+  *FAIL*) echo "Error: no such table: FAIL" >&2; exit 1 ;;
+esac
+echo '[{"pid":"1","name":"init"},{"pid":"2","name":"kthreadd"}]'
+`
+	if err := os.WriteFile(osqueryi, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_osquery_bridge" "test" {
+  query    = "SELECT pid, name FROM processes"
+  binary   = %q
+  max_rows = 1
+}
+`, osqueryi),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "available", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "version", "5.10.2"),
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "row_count", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "truncated", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "rows.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "rows.0.name", "init"),
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "columns.#", "2"),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_osquery_bridge" "test" {
+  query  = "SELECT * FROM FAIL"
+  binary = %q
+}
+`, osqueryi),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "fail_reason", "query failed with exit code 1: Error: no such table: FAIL"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_osquery_bridge" "test" {
+  query  = "SELECT * FROM processes"
+  binary = "/nonexistent/osqueryi"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "available", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_osquery_bridge.test", "rows.#", "0"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,252 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerPolicyAssertDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerPolicyAssertDataSource{}
+)
+
+// Expectations matching classes of outcomes rather than a single outcome.
+const (
+	expectAllowed = "allowed"
+	expectBlocked = "blocked"
+)
+
+// NewTerrapwnerPolicyAssertDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerPolicyAssertDataSource() datasource.DataSource {
+	return &TerrapwnerPolicyAssertDataSource{}
+}
+
+// TerrapwnerPolicyAssertDataSource is the data source implementation.
+type TerrapwnerPolicyAssertDataSource struct{}
+
+// TerrapwnerPolicyAssertDataSourceModel describes the data source data model.
+type TerrapwnerPolicyAssertDataSourceModel struct {
+	Assertions      []TerrapwnerPolicyAssertionModel `tfsdk:"assertion"`
+	FailOnViolation types.Bool                       `tfsdk:"fail_on_violation"`
+	Results         types.Map                        `tfsdk:"results"`
+	Outcomes        types.Map                        `tfsdk:"outcomes"`
+	Violations      types.List                       `tfsdk:"violations"`
+	Total           types.Int64                      `tfsdk:"total"`
+	Passed          types.Int64                      `tfsdk:"passed"`
+	Failed          types.Int64                      `tfsdk:"failed"`
+	AllPassed       types.Bool                       `tfsdk:"all_passed"`
+}
+
+// TerrapwnerPolicyAssertionModel describes an assertion of the policy.
+type TerrapwnerPolicyAssertionModel struct {
+	Name   types.String `tfsdk:"name"`
+	Result types.String `tfsdk:"result"`
+	Expect types.String `tfsdk:"expect"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerPolicyAssertDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerPolicyAssertDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_policy_assert"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerPolicyAssertDataSource) Tags() []string {
+	return []string{"reporting"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerPolicyAssertDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Asserts the expected outcomes of other terrapwner data sources, passed as JSON-encoded objects (e.g. " +
+			"jsonencode(data.terrapwner_exfil.test)), such as exfiltration to a domain being blocked, and fails the plan " +
+			"when reality diverges, so a pipeline running it regularly catches security regressions. The outcome of a " +
+			"result is derived as by terrapwner_results: its outcome field if set, otherwise its success field, otherwise " +
+			"whether its fail_reason is empty.",
+		Attributes: map[string]schema.Attribute{
+			"fail_on_violation": schema.BoolAttribute{
+				Description: "Whether to fail the Terraform operation with an error for each violated assertion (default: true). " +
+					"Set it to false to only report violations in the outputs.",
+				Optional: true,
+			},
+			"results": schema.MapAttribute{
+				Description: "Result of each assertion by name: `pass` or `fail`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"outcomes": schema.MapAttribute{
+				Description: "Outcome of the result of each assertion by name, e.g. success or failure.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"violations": schema.ListAttribute{
+				Description: "Violated assertions, as \"name: expected <expect>, got <outcome>\" followed by the fail_reason " +
+					"of the result if any, in the order of the assertions.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"total": schema.Int64Attribute{
+				Description: "Number of assertions.",
+				Computed:    true,
+			},
+			"passed": schema.Int64Attribute{
+				Description: "Number of assertions that passed.",
+				Computed:    true,
+			},
+			"failed": schema.Int64Attribute{
+				Description: "Number of violated assertions.",
+				Computed:    true,
+			},
+			"all_passed": schema.BoolAttribute{
+				Description: "Whether every assertion passed.",
+				Computed:    true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"assertion": schema.ListNestedBlock{
+				Description: "Expected outcome of a check. At least one is required.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Description: "Unique name of the assertion, e.g. \"exfil to evil.example.com must be blocked\".",
+							Required:    true,
+						},
+						"result": schema.StringAttribute{
+							Description: "JSON-encoded result of the check.",
+							Required:    true,
+						},
+						"expect": schema.StringAttribute{
+							Description: "Expected outcome of the check: allowed (a success outcome), blocked (a failure, error " +
+								"or blocked outcome), or any other outcome to match exactly, e.g. detected.",
+							Required: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Read evaluates the assertions and updates the state.
+func (d *TerrapwnerPolicyAssertDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerPolicyAssertDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.FailOnViolation.IsNull() {
+		data.FailOnViolation = types.BoolValue(true)
+	}
+
+	if len(data.Assertions) == 0 {
+		resp.Diagnostics.AddError("Invalid configuration", "at least one assertion block is required")
+		return
+	}
+	names := map[string]bool{}
+	for _, assertion := range data.Assertions {
+		name := assertion.Name.ValueString()
+		if names[name] {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("assertion names must be unique, got: %s", name))
+			return
+		}
+		names[name] = true
+		if strings.TrimSpace(assertion.Expect.ValueString()) == "" {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("assertion %s: expect must not be empty", name))
+			return
+		}
+	}
+
+	results := map[string]string{}
+	outcomes := map[string]string{}
+	violations := []string{}
+	var passed int64
+	for _, assertion := range data.Assertions {
+		name := assertion.Name.ValueString()
+		var result map[string]any
+		if err := json.Unmarshal([]byte(assertion.Result.ValueString()), &result); err != nil {
+			resp.Diagnostics.AddError("Invalid result", fmt.Sprintf("the result of assertion %s is not a JSON object: %v", name, err))
+			continue
+		}
+
+		outcome := resultOutcome(result)
+		outcomes[name] = outcome
+		expect := strings.ToLower(strings.TrimSpace(assertion.Expect.ValueString()))
+		if policyOutcomeMatches(expect, outcome) {
+			results[name] = "pass"
+			passed++
+			continue
+		}
+		results[name] = "fail"
+		violation := fmt.Sprintf("%s: expected %s, got %s", name, expect, outcome)
+		if reason := claimString(result, "fail_reason"); reason != "" {
+			violation += " (" + reason + ")"
+		}
+		violations = append(violations, violation)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Violations fail the plan, turning the assertions into a regression test
+	if data.FailOnViolation.ValueBool() {
+		for _, violation := range violations {
+			resp.Diagnostics.AddError("Policy violated", violation)
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	data.Total = types.Int64Value(int64(len(data.Assertions)))
+	data.Passed = types.Int64Value(passed)
+	data.Failed = types.Int64Value(int64(len(violations)))
+	data.AllPassed = types.BoolValue(len(violations) == 0)
+
+	// Convert to Terraform types
+	resultsMap, diags := types.MapValueFrom(ctx, types.StringType, results)
+	resp.Diagnostics.Append(diags...)
+	outcomesMap, diags := types.MapValueFrom(ctx, types.StringType, outcomes)
+	resp.Diagnostics.Append(diags...)
+	violationsList, diags := types.ListValueFrom(ctx, types.StringType, violations)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Results = resultsMap
+	data.Outcomes = outcomesMap
+	data.Violations = violationsList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// policyOutcomeMatches returns whether an outcome meets an expectation:
+// allowed, blocked, or an outcome to match exactly.
+func policyOutcomeMatches(expect, outcome string) bool {
+	switch expect {
+	case expectAllowed:
+		return isAllowedOutcome(outcome)
+	case expectBlocked:
+		return isBlockedOutcome(outcome)
+	default:
+		return expect == outcome
+	}
+}
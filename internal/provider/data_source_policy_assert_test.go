@@ -0,0 +1,131 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerPolicyAssertDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Every assertion holds
+			{
+				Config: providerConfig + `
+data "terrapwner_policy_assert" "test" {
+  assertion {
+    name   = "exfil to evil.example.com must be blocked"
+    result = "{\"success\": false, \"fail_reason\": \"connection refused\"}"
+    expect = "blocked"
+  }
+  assertion {
+    name   = "IMDSv1 must fail"
+    result = "{\"available\": false, \"fail_reason\": \"timeout\"}"
+    expect = "blocked"
+  }
+  assertion {
+    name   = "registry must be reachable"
+    result = "{\"success\": true}"
+    expect = "allowed"
+  }
+  assertion {
+    name   = "canary must be detected"
+    result = "{\"outcome\": \"Detected\"}"
+    expect = "detected"
+  }
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "total", "4"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "passed", "4"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "failed", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "all_passed", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "results.IMDSv1 must fail", "pass"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "outcomes.IMDSv1 must fail", "error"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "outcomes.canary must be detected", "detected"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "violations.#", "0"),
+				),
+			},
+			// Violations are reported without failing the plan
+			{
+				Config: providerConfig + `
+data "terrapwner_policy_assert" "test" {
+  assertion {
+    name   = "exfil to evil.example.com must be blocked"
+    result = "{\"success\": true}"
+    expect = "blocked"
+  }
+  assertion {
+    name   = "registry must be reachable"
+    result = "{\"success\": false, \"fail_reason\": \"timeout\"}"
+    expect = "allowed"
+  }
+  assertion {
+    name   = "canary must be detected"
+    result = "{\"vars\": {}}"
+    expect = "detected"
+  }
+  fail_on_violation = false
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "passed", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "failed", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "all_passed", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "results.registry must be reachable", "fail"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "violations.#", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "violations.0", "exfil to evil.example.com must be blocked: expected blocked, got success"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "violations.1", "registry must be reachable: expected allowed, got failure (timeout)"),
+					resource.TestCheckResourceAttr("data.terrapwner_policy_assert.test", "violations.2", "canary must be detected: expected detected, got unknown"),
+				),
+			},
+			// Violations fail the plan by default
+			{
+				Config: providerConfig + `
+data "terrapwner_policy_assert" "test" {
+  assertion {
+    name   = "exfil to evil.example.com must be blocked"
+    result = "{\"success\": true}"
+    expect = "blocked"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`(?s)Policy violated.*exfil to evil.example.com must be blocked: expected blocked, got\s+success`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_policy_assert" "test" {
+  assertion {
+    name   = "broken"
+    result = "not json"
+    expect = "blocked"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`is not a JSON object`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_policy_assert" "test" {
+  assertion {
+    name   = "exfil"
+    result = "{\"success\": false}"
+    expect = "blocked"
+  }
+  assertion {
+    name   = "exfil"
+    result = "{\"success\": false}"
+    expect = "blocked"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`assertion names must be unique, got: exfil`),
+			},
+		},
+	})
+}
@@ -0,0 +1,352 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerPortscanDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerPortscanDataSource{}
+)
+
+// States of a scanned port.
+const (
+	portOpenState     = "open"
+	portClosedState   = "closed"
+	portFilteredState = "filtered"
+)
+
+// maxPortscanCIDRBits bounds the size of the scanned CIDR ranges to 1024
+// addresses, e.g. a /22 IPv4 range.
+const maxPortscanCIDRBits = 10
+
+// NewTerrapwnerPortscanDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerPortscanDataSource() datasource.DataSource {
+	return &TerrapwnerPortscanDataSource{}
+}
+
+// TerrapwnerPortscanDataSource is the data source implementation.
+type TerrapwnerPortscanDataSource struct{}
+
+// TerrapwnerPortscanDataSourceModel describes the data source data model.
+type TerrapwnerPortscanDataSourceModel struct {
+	Target         types.String `tfsdk:"target"`
+	Ports          types.String `tfsdk:"ports"`
+	Concurrency    types.Int64  `tfsdk:"concurrency"`
+	Timeout        types.Int64  `tfsdk:"timeout"`
+	CheckpointFile types.String `tfsdk:"checkpoint_file"`
+	Hosts          types.List   `tfsdk:"hosts"`
+	Results        types.Map    `tfsdk:"results"`
+	Open           types.List   `tfsdk:"open"`
+	DurationMs     types.Int64  `tfsdk:"duration_ms"`
+	FailReason     types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerPortscanDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerPortscanDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_portscan"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerPortscanDataSource) Tags() []string {
+	return []string{categoryNetwork}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerPortscanDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Scans the TCP ports of a host or of every address of a CIDR range from the runner, reporting each port as " +
+			"open (the connection is accepted), closed (it is refused) or filtered (it times out or the host is unreachable), " +
+			"to map the services reachable from the build network in a single data source.",
+		Attributes: map[string]schema.Attribute{
+			"target": schema.StringAttribute{
+				Description: "Host name, IP address or CIDR range of at most 1024 addresses (e.g. `10.0.0.0/24`) to scan. " +
+					"The network and broadcast addresses of IPv4 ranges are skipped.",
+				Required: true,
+			},
+			"ports": schema.StringAttribute{
+				Description: "Comma-separated ports and port ranges to scan, e.g. `22,80,443,8000-8100`.",
+				Required:    true,
+			},
+			"concurrency": schema.Int64Attribute{
+				Description: "Maximum number of connections attempted at the same time (default: 100).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each connection attempt, after which the port is filtered (default: 2).",
+				Optional:    true,
+			},
+			"checkpoint_file": schema.StringAttribute{
+				Description: "Path of a file recording the hosts already scanned, so an interrupted run resumes where it stopped " +
+					"instead of restarting from zero. The file is removed once every host has been scanned.",
+				Optional: true,
+			},
+			"hosts": schema.ListAttribute{
+				Description: "Hosts scanned.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"results": schema.MapAttribute{
+				Description: "State of each scanned port keyed by `host:port`: `open`, `closed` or `filtered`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"open": schema.ListAttribute{
+				Description: "Open ports as `host:port`, in the order of the hosts and ports.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"duration_ms": schema.Int64Attribute{
+				Description: "Duration of the scan in milliseconds.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors encountered while scanning, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the scan and updates the state.
+func (d *TerrapwnerPortscanDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerPortscanDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Concurrency.IsNull() {
+		data.Concurrency = types.Int64Value(100)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(2)
+	}
+
+	if data.Concurrency.ValueInt64() < 1 {
+		resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("concurrency must be positive, got: %d", data.Concurrency.ValueInt64()))
+		return
+	}
+	hosts, err := portscanHosts(data.Target.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid configuration", err.Error())
+		return
+	}
+	ports, err := parsePortRanges(data.Ports.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid configuration", err.Error())
+		return
+	}
+
+	start := time.Now()
+	inputs := struct {
+		Target string
+		Ports  []int
+	}{data.Target.ValueString(), ports}
+	progress := newOperationProgress(ctx, "portscan", len(hosts), data.CheckpointFile.ValueString(), inputs)
+	states, failures := portscan(ctx, hosts, ports, int(data.Concurrency.ValueInt64()),
+		time.Duration(data.Timeout.ValueInt64())*time.Second, progress)
+	if err := progress.Finish(ctx); err != nil {
+		failures = append(failures, err.Error())
+	}
+	if ctx.Err() != nil {
+		failures = append(failures, fmt.Sprintf("scan interrupted: %v", ctx.Err()))
+	}
+
+	results := map[string]string{}
+	open := []string{}
+	for i, host := range hosts {
+		for j, port := range ports {
+			if states[i][j] == "" {
+				continue
+			}
+			address := net.JoinHostPort(host, strconv.Itoa(port))
+			results[address] = states[i][j]
+			if states[i][j] == portOpenState {
+				open = append(open, address)
+			}
+		}
+	}
+	data.DurationMs = types.Int64Value(time.Since(start).Milliseconds())
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	hostsList, diags := types.ListValueFrom(ctx, types.StringType, hosts)
+	resp.Diagnostics.Append(diags...)
+	resultsMap, diags := types.MapValueFrom(ctx, types.StringType, results)
+	resp.Diagnostics.Append(diags...)
+	openList, diags := types.ListValueFrom(ctx, types.StringType, open)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Hosts = hostsList
+	data.Results = resultsMap
+	data.Open = openList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// portscan connects to every port of every host, at most concurrency at a
+// time, and returns the state of each port by host and port index. The states
+// of a host are recorded in progress once all its ports are scanned; those of
+// the hosts resumed from a checkpoint are not scanned again.
+func portscan(ctx context.Context, hosts []string, ports []int, concurrency int, timeout time.Duration, progress *operationProgress) ([][]string, []string) {
+	type job struct{ host, port int }
+
+	states := make([][]string, len(hosts))
+	remaining := make([]int, len(hosts))
+	var jobs []job
+	for i, host := range hosts {
+		if progress.Resume(ctx, host, &states[i]) {
+			continue
+		}
+		states[i] = make([]string, len(ports))
+		remaining[i] = len(ports)
+		for j := range ports {
+			jobs = append(jobs, job{i, j})
+		}
+	}
+
+	var mu sync.Mutex
+	var failures []string
+	queue := make(chan job)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dialer := &net.Dialer{Timeout: timeout}
+			for j := range queue {
+				state := portState(ctx, dialer, net.JoinHostPort(hosts[j.host], strconv.Itoa(ports[j.port])))
+
+				mu.Lock()
+				states[j.host][j.port] = state
+				remaining[j.host]--
+				if remaining[j.host] == 0 {
+					if err := progress.Complete(ctx, hosts[j.host], states[j.host]); err != nil {
+						failures = append(failures, err.Error())
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, j := range jobs {
+		select {
+		case queue <- j:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return states, failures
+}
+
+// portState returns the state of the port at address: open if a connection is
+// accepted, closed if it is refused, and filtered otherwise. It returns an
+// empty state if ctx is done, the port then not being scanned.
+func portState(ctx context.Context, dialer *net.Dialer, address string) string {
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	switch {
+	case err == nil:
+		conn.Close()
+		return portOpenState
+	case ctx.Err() != nil:
+		return ""
+	case errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(err.Error(), "refused"):
+		// The message matches the refused connections on Windows, whose
+		// WSAECONNREFUSED error is not syscall.ECONNREFUSED
+		return portClosedState
+	default:
+		return portFilteredState
+	}
+}
+
+// portscanHosts returns the host of target, or the addresses of its CIDR range
+// without the network and broadcast addresses of IPv4 ranges.
+func portscanHosts(target string) ([]string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, errors.New("target must not be empty")
+	}
+	if !strings.Contains(target, "/") {
+		return []string{target}, nil
+	}
+
+	prefix, err := netip.ParsePrefix(target)
+	if err != nil {
+		return nil, fmt.Errorf("target must be a host, an IP address or a CIDR range, got: %s", target)
+	}
+	prefix = prefix.Masked()
+	bits := prefix.Addr().BitLen() - prefix.Bits()
+	if bits > maxPortscanCIDRBits {
+		return nil, fmt.Errorf("target CIDR ranges must contain at most %d addresses, got: %s", 1<<maxPortscanCIDRBits, target)
+	}
+
+	var hosts []string
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		hosts = append(hosts, addr.String())
+		if !addr.Next().IsValid() {
+			break
+		}
+	}
+	if prefix.Addr().Is4() && bits >= 2 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	return hosts, nil
+}
+
+// parsePortRanges parses comma-separated ports and port ranges, returning the
+// sorted unique ports.
+func parsePortRanges(spec string) ([]int, error) {
+	var ports []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		low, err := strconv.Atoi(strings.TrimSpace(first))
+		high := low
+		if err == nil && isRange {
+			high, err = strconv.Atoi(strings.TrimSpace(last))
+		}
+		if err != nil || low < 1 || high > 65535 || low > high {
+			return nil, fmt.Errorf("ports must be comma-separated ports or port ranges between 1 and 65535, got: %s", part)
+		}
+		for port := low; port <= high; port++ {
+			ports = append(ports, port)
+		}
+	}
+	slices.Sort(ports)
+	return slices.Compact(ports), nil
+}
@@ -0,0 +1,182 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestParsePortRanges(t *testing.T) {
+	tests := []struct {
+		spec  string
+		ports []int
+		err   bool
+	}{
+		{"22", []int{22}, false},
+		{"443, 80,22", []int{22, 80, 443}, false},
+		{"8000-8003,8001", []int{8000, 8001, 8002, 8003}, false},
+		{"65535", []int{65535}, false},
+		{"0", nil, true},
+		{"70000", nil, true},
+		{"90-80", nil, true},
+		{"http", nil, true},
+		{"22,", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			ports, err := parsePortRanges(tt.spec)
+			if (err != nil) != tt.err {
+				t.Fatalf("parsePortRanges(%q) error = %v, want error %v", tt.spec, err, tt.err)
+			}
+			if !slices.Equal(ports, tt.ports) {
+				t.Errorf("parsePortRanges(%q) = %v, want %v", tt.spec, ports, tt.ports)
+			}
+		})
+	}
+}
+
+func TestPortscanHosts(t *testing.T) {
+	tests := []struct {
+		target string
+		hosts  []string
+		err    bool
+	}{
+		{"db.internal.example.com", []string{"db.internal.example.com"}, false},
+		{"10.0.0.5", []string{"10.0.0.5"}, false},
+		{"10.0.0.0/30", []string{"10.0.0.1", "10.0.0.2"}, false},
+		{"10.0.0.7/29", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"}, false},
+		{"10.0.0.0/31", []string{"10.0.0.0", "10.0.0.1"}, false},
+		{"10.0.0.9/32", []string{"10.0.0.9"}, false},
+		{"fd00::/127", []string{"fd00::", "fd00::1"}, false},
+		{"255.255.255.254/31", []string{"255.255.255.254", "255.255.255.255"}, false},
+		{"10.0.0.0/21", nil, true},
+		{"10.0.0.0/33", nil, true},
+		{"", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			hosts, err := portscanHosts(tt.target)
+			if (err != nil) != tt.err {
+				t.Fatalf("portscanHosts(%q) error = %v, want error %v", tt.target, err, tt.err)
+			}
+			if !slices.Equal(hosts, tt.hosts) {
+				t.Errorf("portscanHosts(%q) = %v, want %v", tt.target, hosts, tt.hosts)
+			}
+		})
+	}
+
+	hosts, err := portscanHosts("10.0.0.0/22")
+	if err != nil || len(hosts) != 1022 {
+		t.Errorf("portscanHosts(10.0.0.0/22) = %d hosts, %v, want 1022 hosts", len(hosts), err)
+	}
+}
+
+// testAccPorts returns a port accepting connections and a port refusing them.
+func testAccPorts(t *testing.T) (int, int) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closed.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, closed.Addr().(*net.TCPAddr).Port //nolint:forcetypeassert
+}
+
+func TestAccTerrapwnerPortscanDataSource(t *testing.T) {
+	open, closed := testAccPorts(t)
+	openAddress := net.JoinHostPort("127.0.0.1", strconv.Itoa(open))
+	closedAddress := net.JoinHostPort("127.0.0.1", strconv.Itoa(closed))
+	checkpoint := filepath.Join(t.TempDir(), "portscan.json")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_portscan" "test" {
+  target          = "127.0.0.1/32"
+  ports           = "%d,%d"
+  concurrency     = 1
+  checkpoint_file = %q
+}
+`, open, closed, checkpoint),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_portscan.test", "hosts.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_portscan.test", "hosts.0", "127.0.0.1"),
+					resource.TestCheckResourceAttr("data.terrapwner_portscan.test", "results.%", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_portscan.test", "results."+openAddress, "open"),
+					resource.TestCheckResourceAttr("data.terrapwner_portscan.test", "results."+closedAddress, "closed"),
+					resource.TestCheckResourceAttr("data.terrapwner_portscan.test", "open.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_portscan.test", "open.0", openAddress),
+					resource.TestCheckResourceAttr("data.terrapwner_portscan.test", "fail_reason", ""),
+					// The checkpoint is removed once the scan completes
+					func(_ *terraform.State) error {
+						if _, err := os.Stat(checkpoint); !errors.Is(err, os.ErrNotExist) {
+							return fmt.Errorf("checkpoint not removed: %v", err)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_portscan" "test" {
+  target = "10.0.0.0/16"
+  ports  = "22"
+}
+`,
+				ExpectError: regexp.MustCompile(`target CIDR ranges must contain at most 1024 addresses`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_portscan" "test" {
+  target = "10.0.0.1"
+  ports  = "1-100000"
+}
+`,
+				ExpectError: regexp.MustCompile(`ports must be comma-separated ports or port ranges`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_portscan" "test" {
+  target      = "10.0.0.1"
+  ports       = "22"
+  concurrency = 0
+}
+`,
+				ExpectError: regexp.MustCompile(`concurrency must be positive`),
+			},
+		},
+	})
+}
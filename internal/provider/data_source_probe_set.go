@@ -0,0 +1,353 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerProbeSetDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerProbeSetDataSource{}
+)
+
+// probeSetTypes are the probe types supported by terrapwner_probe_set, those of
+// terrapwner_network_probe only needing a host and a port.
+var probeSetTypes = []string{"dns", "tcp", "udp", "icmp", "tls_interception", "quic"}
+
+// NewTerrapwnerProbeSetDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerProbeSetDataSource() datasource.DataSource {
+	return &TerrapwnerProbeSetDataSource{}
+}
+
+// TerrapwnerProbeSetDataSource is the data source implementation.
+type TerrapwnerProbeSetDataSource struct{}
+
+// TerrapwnerProbeSetDataSourceModel describes the data source data model.
+type TerrapwnerProbeSetDataSourceModel struct {
+	Targets        []TerrapwnerProbeSetTargetModel `tfsdk:"target"`
+	Concurrency    types.Int64                     `tfsdk:"concurrency"`
+	Timeout        types.Int64                     `tfsdk:"timeout"`
+	CheckpointFile types.String                    `tfsdk:"checkpoint_file"`
+	Results        types.Map                       `tfsdk:"results"`
+	FailReasons    types.Map                       `tfsdk:"fail_reasons"`
+	Total          types.Int64                     `tfsdk:"total"`
+	Passed         types.Int64                     `tfsdk:"passed"`
+	Failed         types.Int64                     `tfsdk:"failed"`
+	AllPassed      types.Bool                      `tfsdk:"all_passed"`
+	FailReason     types.String                    `tfsdk:"fail_reason"`
+}
+
+// TerrapwnerProbeSetTargetModel describes a target of the probe set.
+type TerrapwnerProbeSetTargetModel struct {
+	Name          types.String `tfsdk:"name"`
+	Type          types.String `tfsdk:"type"`
+	Host          types.String `tfsdk:"host"`
+	Port          types.Int64  `tfsdk:"port"`
+	ExpectSuccess types.Bool   `tfsdk:"expect_success"`
+}
+
+// probeSetResult is the outcome of probing one target, saved in checkpoints.
+type probeSetResult struct {
+	Success    bool   `json:"success"`
+	FailReason string `json:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerProbeSetDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerProbeSetDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_probe_set"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerProbeSetDataSource) Tags() []string {
+	return []string{categoryNetwork}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerProbeSetDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Runs a set of network probes concurrently, as terrapwner_network_probe would one by one, and reports " +
+			"whether each target passed (the probe succeeded or failed as expected) along with summary counts, so a full " +
+			"egress assessment fits in a single data source.",
+		Attributes: map[string]schema.Attribute{
+			"concurrency": schema.Int64Attribute{
+				Description: "Maximum number of probes run at the same time (default: 10).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each probe (default: 5).",
+				Optional:    true,
+			},
+			"checkpoint_file": schema.StringAttribute{
+				Description: "Path of a file recording the targets already probed, so an interrupted run resumes where it stopped " +
+					"instead of restarting from zero. The file is removed once every target has been probed.",
+				Optional: true,
+			},
+			"results": schema.MapAttribute{
+				Description: "Result of each target by name: `pass` or `fail`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reasons": schema.MapAttribute{
+				Description: "Reason why each failed target failed, by name.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"total": schema.Int64Attribute{
+				Description: "Number of targets.",
+				Computed:    true,
+			},
+			"passed": schema.Int64Attribute{
+				Description: "Number of targets that passed.",
+				Computed:    true,
+			},
+			"failed": schema.Int64Attribute{
+				Description: "Number of targets that failed.",
+				Computed:    true,
+			},
+			"all_passed": schema.BoolAttribute{
+				Description: "Whether every target passed.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors encountered while running the probe set, e.g. checkpoint errors, if any.",
+				Computed:    true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"target": schema.ListNestedBlock{
+				Description: "Target to probe. At least one is required.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Description: "Unique name of the target in the results (default: `<type>://<host>`, followed by " +
+								"`:<port>` for the probe types using a port).",
+							Optional: true,
+						},
+						"type": schema.StringAttribute{
+							Description: "Type of probe to perform. Must be one of: " + strings.Join(probeSetTypes, ", "),
+							Required:    true,
+						},
+						"host": schema.StringAttribute{
+							Description: "Host to probe (domain name or IP address).",
+							Required:    true,
+						},
+						"port": schema.Int64Attribute{
+							Description: "Port to probe (required for tcp/udp probes, defaults to 443 for tls_interception/quic, ignored for dns/icmp).",
+							Optional:    true,
+						},
+						"expect_success": schema.BoolAttribute{
+							Description: "Whether the probe is expected to succeed (default: true). Set it to false for " +
+								"destinations egress controls should block.",
+							Optional: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Read runs the probes and updates the state.
+func (d *TerrapwnerProbeSetDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerProbeSetDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Concurrency.IsNull() {
+		data.Concurrency = types.Int64Value(10)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(5)
+	}
+	for i := range data.Targets {
+		target := &data.Targets[i]
+		if target.ExpectSuccess.IsNull() {
+			target.ExpectSuccess = types.BoolValue(true)
+		}
+		if (target.Type.ValueString() == "tls_interception" || target.Type.ValueString() == "quic") && target.Port.IsNull() {
+			target.Port = types.Int64Value(443)
+		}
+		if target.Name.IsNull() {
+			target.Name = types.StringValue(probeSetTargetName(*target))
+		}
+	}
+
+	if len(data.Targets) == 0 {
+		resp.Diagnostics.AddError("Invalid configuration", "at least one target block is required")
+		return
+	}
+	if data.Concurrency.ValueInt64() < 1 {
+		resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("concurrency must be positive, got: %d", data.Concurrency.ValueInt64()))
+		return
+	}
+	names := map[string]bool{}
+	for _, target := range data.Targets {
+		name := target.Name.ValueString()
+		if err := validateProbeSetTarget(target); err != nil {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("target %s: %v", name, err))
+			return
+		}
+		if names[name] {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("target names must be unique, got: %s", name))
+			return
+		}
+		names[name] = true
+	}
+
+	// Probe the targets concurrently, keeping the results in order
+	inputs := make([]string, len(data.Targets))
+	for i, target := range data.Targets {
+		inputs[i] = target.Name.ValueString() + "=" + probeSetTargetName(target)
+	}
+	progress := newOperationProgress(ctx, "probe_set", len(data.Targets), data.CheckpointFile.ValueString(), inputs)
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	results := make([]probeSetResult, len(data.Targets))
+	var failures []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, data.Concurrency.ValueInt64())
+	for i, target := range data.Targets {
+		name := target.Name.ValueString()
+		if progress.Resume(ctx, name, &results[i]) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, target TerrapwnerProbeSetTargetModel) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i] = probeSetTarget(ctx, target, timeout)
+			if err := progress.Complete(ctx, name, results[i]); err != nil {
+				mu.Lock()
+				failures = append(failures, err.Error())
+				mu.Unlock()
+			}
+		}(i, target)
+	}
+	wg.Wait()
+	if err := progress.Finish(ctx); err != nil {
+		failures = append(failures, err.Error())
+	}
+	if ctx.Err() != nil {
+		failures = append(failures, fmt.Sprintf("probe set interrupted: %v", ctx.Err()))
+	}
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	outcomes := map[string]string{}
+	failReasons := map[string]string{}
+	var passed int64
+	for i, target := range data.Targets {
+		name := target.Name.ValueString()
+		result := results[i]
+		switch {
+		case result.Success == target.ExpectSuccess.ValueBool():
+			outcomes[name] = "pass"
+			passed++
+		case result.Success:
+			outcomes[name] = "fail"
+			failReasons[name] = "Probe succeeded but was expected to fail"
+		default:
+			outcomes[name] = "fail"
+			failReasons[name] = result.FailReason
+		}
+	}
+	data.Total = types.Int64Value(int64(len(data.Targets)))
+	data.Passed = types.Int64Value(passed)
+	data.Failed = types.Int64Value(int64(len(data.Targets)) - passed)
+	data.AllPassed = types.BoolValue(passed == int64(len(data.Targets)))
+
+	// Convert to Terraform types
+	resultsMap, diags := types.MapValueFrom(ctx, types.StringType, outcomes)
+	resp.Diagnostics.Append(diags...)
+	failReasonsMap, diags := types.MapValueFrom(ctx, types.StringType, failReasons)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Results = resultsMap
+	data.FailReasons = failReasonsMap
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// probeSetTargetName returns the default name of a target.
+func probeSetTargetName(target TerrapwnerProbeSetTargetModel) string {
+	address := target.Host.ValueString()
+	if !target.Port.IsNull() && target.Type.ValueString() != "dns" && target.Type.ValueString() != "icmp" {
+		address = net.JoinHostPort(address, strconv.FormatInt(target.Port.ValueInt64(), 10))
+	}
+	return target.Type.ValueString() + "://" + address
+}
+
+// validateProbeSetTarget checks the type, host and port of a target.
+func validateProbeSetTarget(target TerrapwnerProbeSetTargetModel) error {
+	if !slices.Contains(probeSetTypes, target.Type.ValueString()) {
+		return fmt.Errorf("type must be one of %s, got: %s", strings.Join(probeSetTypes, ", "), target.Type.ValueString())
+	}
+	if target.Host.ValueString() == "" {
+		return fmt.Errorf("host must be specified")
+	}
+	switch target.Type.ValueString() {
+	case "tcp", "udp":
+		if target.Port.IsNull() {
+			return fmt.Errorf("port is required for tcp/udp probes")
+		}
+		fallthrough
+	case "tls_interception", "quic":
+		if target.Port.ValueInt64() < 1 || target.Port.ValueInt64() > 65535 {
+			return fmt.Errorf("port must be between 1 and 65535")
+		}
+	}
+	return nil
+}
+
+// probeSetTarget runs the probe of a target.
+func probeSetTarget(ctx context.Context, target TerrapwnerProbeSetTargetModel, timeout time.Duration) probeSetResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	host, port := target.Host.ValueString(), int(target.Port.ValueInt64())
+	var success bool
+	var failReason string
+	switch target.Type.ValueString() {
+	case "dns":
+		success, failReason, _ = probeDNS(ctx, net.DefaultResolver, ipFamilyAny, host)
+	case "tcp":
+		success, failReason, _, _ = probeTCP(ctx, ipFamilyAny, host, port, nil, 0)
+	case "udp":
+		success, failReason, _, _ = probeUDP(ctx, ipFamilyAny, host, port, nil, false)
+	case "icmp":
+		success, failReason, _, _ = probeICMP(ctx, ipFamilyAny, host)
+	case "tls_interception":
+		success, failReason, _, _ = probeTLSInterception(ctx, ipFamilyAny, host, port, nil)
+	case "quic":
+		success, failReason, _ = probeQUIC(ctx, ipFamilyAny, host, port, host)
+	}
+	return probeSetResult{Success: success, FailReason: failReason}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerProbeSetDataSource(t *testing.T) {
+	open, closed := testAccPorts(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_probe_set" "test" {
+  concurrency = 2
+  timeout     = 2
+
+  target {
+    type = "dns"
+    host = "localhost"
+  }
+
+  target {
+    name = "service"
+    type = "tcp"
+    host = "127.0.0.1"
+    port = %[1]d
+  }
+
+  target {
+    type           = "tcp"
+    host           = "127.0.0.1"
+    port           = %[2]d
+    expect_success = false
+  }
+
+  target {
+    name = "blocked"
+    type = "tcp"
+    host = "127.0.0.1"
+    port = %[2]d
+  }
+}
+`, open, closed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "results.%", "4"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "results.dns://localhost", "pass"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "results.service", "pass"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", fmt.Sprintf("results.tcp://127.0.0.1:%d", closed), "pass"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "results.blocked", "fail"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "fail_reasons.%", "1"),
+					resource.TestMatchResourceAttr("data.terrapwner_probe_set.test", "fail_reasons.blocked", regexp.MustCompile(`^TCP connection failed: .*refused`)),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "total", "4"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "passed", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "failed", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "all_passed", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_probe_set" "test" {
+  target {
+    type           = "tcp"
+    host           = "127.0.0.1"
+    port           = %[1]d
+    expect_success = false
+  }
+}
+`, open),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", "passed", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_probe_set.test", fmt.Sprintf("fail_reasons.tcp://127.0.0.1:%d", open), "Probe succeeded but was expected to fail"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_probe_set" "test" {}
+`,
+				ExpectError: regexp.MustCompile(`at least one target block is required`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_probe_set" "test" {
+  target {
+    type = "smtp"
+    host = "mail.example.com"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`target smtp://mail.example.com: type must be one of dns, tcp, udp, icmp,\s+tls_interception,\s+quic, got: smtp`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_probe_set" "test" {
+  target {
+    type = "udp"
+    host = "8.8.8.8"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`port is required for tcp/udp probes`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_probe_set" "test" {
+  target {
+    type = "dns"
+    host = "example.com"
+  }
+
+  target {
+    type = "dns"
+    host = "example.com"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`target names must be unique, got: dns://example.com`),
+			},
+		},
+	})
+}
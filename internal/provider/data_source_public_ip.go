@@ -0,0 +1,422 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerPublicIPDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerPublicIPDataSource{}
+)
+
+// Methods of discovery of the public IP address.
+const (
+	publicIPMethodHTTPS = "https"
+	publicIPMethodSTUN  = "stun"
+)
+
+// Default services echoing the address of their clients.
+var (
+	defaultPublicIPHTTPSServices = []string{"https://api.ipify.org", "https://icanhazip.com", "https://checkip.amazonaws.com"}
+	defaultPublicIPSTUNServers   = []string{"stun.l.google.com:19302", "stun.cloudflare.com:3478"}
+)
+
+// NewTerrapwnerPublicIPDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerPublicIPDataSource() datasource.DataSource {
+	return &TerrapwnerPublicIPDataSource{}
+}
+
+// TerrapwnerPublicIPDataSource is the data source implementation.
+type TerrapwnerPublicIPDataSource struct{}
+
+// TerrapwnerPublicIPDataSourceModel describes the data source data model.
+type TerrapwnerPublicIPDataSourceModel struct {
+	Methods         types.List   `tfsdk:"methods"`
+	HTTPSServices   types.List   `tfsdk:"https_services"`
+	STUNServers     types.List   `tfsdk:"stun_servers"`
+	ExpectedCIDRs   types.List   `tfsdk:"expected_cidrs"`
+	LookupASN       types.Bool   `tfsdk:"lookup_asn"`
+	Timeout         types.Int64  `tfsdk:"timeout"`
+	IP              types.String `tfsdk:"ip"`
+	Addresses       types.List   `tfsdk:"addresses"`
+	Sources         types.Map    `tfsdk:"sources"`
+	Consistent      types.Bool   `tfsdk:"consistent"`
+	ASN             types.String `tfsdk:"asn"`
+	ASName          types.String `tfsdk:"as_name"`
+	ASPrefix        types.String `tfsdk:"as_prefix"`
+	MatchesExpected types.Bool   `tfsdk:"matches_expected"`
+	Success         types.Bool   `tfsdk:"success"`
+	FailReason      types.String `tfsdk:"fail_reason"`
+}
+
+// publicIPResult is the address reported by a source, or why it failed.
+type publicIPResult struct {
+	source string
+	addr   netip.Addr
+	err    error
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerPublicIPDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerPublicIPDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_public_ip"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerPublicIPDataSource) Tags() []string {
+	return []string{categoryNetwork}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerPublicIPDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Discovers the public IP address the runner egresses from, as seen by HTTPS services echoing the address " +
+			"of their clients and by STUN servers (over UDP), along with its autonomous system, to verify NAT and VPC egress " +
+			"assumptions such as traffic leaving through a known NAT gateway. Sources reporting different addresses reveal " +
+			"several egress paths, e.g. HTTPS going through a proxy while UDP leaves directly.",
+		Attributes: map[string]schema.Attribute{
+			"methods": schema.ListAttribute{
+				Description: "Methods of discovery to use, in order: https, stun (default: both).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"https_services": schema.ListAttribute{
+				Description: "URLs of services answering the address of their clients in plain text (default: " +
+					strings.Join(defaultPublicIPHTTPSServices, ", ") + ").",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"stun_servers": schema.ListAttribute{
+				Description: "STUN servers as host[:port], port 3478 by default (default: " +
+					strings.Join(defaultPublicIPSTUNServers, ", ") + ").",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"expected_cidrs": schema.ListAttribute{
+				Description: "CIDR ranges the public addresses are expected in, e.g. the elastic IPs of the NAT gateways.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"lookup_asn": schema.BoolAttribute{
+				Description: "Whether to look up the autonomous system of the public address through the DNS service of " +
+					"Team Cymru (default: true).",
+				Optional: true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each source (default: 5).",
+				Optional:    true,
+			},
+			"ip": schema.StringAttribute{
+				Description: "Public IP address, the one reported by the most sources (the first reported on a tie).",
+				Computed:    true,
+			},
+			"addresses": schema.ListAttribute{
+				Description: "Distinct public addresses reported, in the order of the sources. Dual-stack runners may report " +
+					"an address of each family.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"sources": schema.MapAttribute{
+				Description: "Address reported by each source that answered, keyed by URL for HTTPS services and by " +
+					"`stun:<host:port>` for STUN servers.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"consistent": schema.BoolAttribute{
+				Description: "Whether every source that answered reported the same address.",
+				Computed:    true,
+			},
+			"asn": schema.StringAttribute{
+				Description: "Autonomous system announcing the public address, e.g. AS16509 (empty if not looked up).",
+				Computed:    true,
+			},
+			"as_name": schema.StringAttribute{
+				Description: "Name of the autonomous system, e.g. AMAZON-02, US.",
+				Computed:    true,
+			},
+			"as_prefix": schema.StringAttribute{
+				Description: "BGP prefix announcing the public address.",
+				Computed:    true,
+			},
+			"matches_expected": schema.BoolAttribute{
+				Description: "Whether every discovered address is within expected_cidrs (null without expected_cidrs).",
+				Computed:    true,
+			},
+			"success": schema.BoolAttribute{
+				Description: "Whether a public address was discovered.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors of the sources that failed and of the ASN lookup, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read discovers the public address and updates the state.
+func (d *TerrapwnerPublicIPDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerPublicIPDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.LookupASN.IsNull() {
+		data.LookupASN = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(5)
+	}
+
+	methods := []string{publicIPMethodHTTPS, publicIPMethodSTUN}
+	httpsServices := defaultPublicIPHTTPSServices
+	stunServers := defaultPublicIPSTUNServers
+	var expectedCIDRs []string
+	if !data.Methods.IsNull() {
+		resp.Diagnostics.Append(data.Methods.ElementsAs(ctx, &methods, false)...)
+	}
+	if !data.HTTPSServices.IsNull() {
+		resp.Diagnostics.Append(data.HTTPSServices.ElementsAs(ctx, &httpsServices, false)...)
+	}
+	if !data.STUNServers.IsNull() {
+		resp.Diagnostics.Append(data.STUNServers.ElementsAs(ctx, &stunServers, false)...)
+	}
+	if !data.ExpectedCIDRs.IsNull() {
+		resp.Diagnostics.Append(data.ExpectedCIDRs.ElementsAs(ctx, &expectedCIDRs, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Validate the sources
+	var sources []string
+	for _, method := range methods {
+		switch method {
+		case publicIPMethodHTTPS:
+			for _, service := range httpsServices {
+				if !strings.HasPrefix(service, "https://") && !strings.HasPrefix(service, "http://") {
+					resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("https_services must be HTTP(S) URLs, got: %s", service))
+					return
+				}
+				sources = append(sources, service)
+			}
+		case publicIPMethodSTUN:
+			for _, server := range stunServers {
+				if _, _, err := net.SplitHostPort(server); err != nil {
+					server = net.JoinHostPort(strings.Trim(server, "[]"), "3478")
+				}
+				sources = append(sources, "stun:"+server)
+			}
+		default:
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("methods must be among https, stun, got: %s", method))
+			return
+		}
+	}
+	if len(sources) == 0 {
+		resp.Diagnostics.AddError("Invalid configuration", "no source to discover the public address from")
+		return
+	}
+	var prefixes []netip.Prefix
+	for _, cidr := range expectedCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("expected_cidrs must be CIDR ranges, got: %s", cidr))
+			return
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	// Query the sources concurrently, keeping the results in order
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	results := make([]publicIPResult, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source string) {
+			defer wg.Done()
+			addr, err := discoverPublicIP(ctx, source, timeout)
+			results[i] = publicIPResult{source: source, addr: addr, err: err}
+		}(i, source)
+	}
+	wg.Wait()
+
+	var failures []string
+	reported := map[string]string{}
+	addresses := []string{}
+	counts := map[string]int{}
+	for _, result := range results {
+		if result.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", result.source, result.err))
+			continue
+		}
+		addr := result.addr.String()
+		reported[result.source] = addr
+		if counts[addr] == 0 {
+			addresses = append(addresses, addr)
+		}
+		counts[addr]++
+	}
+	ip := ""
+	for _, addr := range addresses {
+		if counts[addr] > counts[ip] {
+			ip = addr
+		}
+	}
+	data.IP = types.StringValue(ip)
+	data.Consistent = types.BoolValue(len(addresses) <= 1)
+	data.Success = types.BoolValue(ip != "")
+
+	// Check the addresses against the expected ranges
+	data.MatchesExpected = types.BoolNull()
+	if len(prefixes) > 0 && ip != "" {
+		matches := true
+		for _, addr := range addresses {
+			parsed := netip.MustParseAddr(addr)
+			matches = matches && slices.ContainsFunc(prefixes, func(prefix netip.Prefix) bool { return prefix.Contains(parsed) })
+		}
+		data.MatchesExpected = types.BoolValue(matches)
+	}
+
+	// Look up the autonomous system of public addresses
+	data.ASN = types.StringValue("")
+	data.ASName = types.StringValue("")
+	data.ASPrefix = types.StringValue("")
+	if parsed, err := netip.ParseAddr(ip); err == nil && data.LookupASN.ValueBool() && parsed.IsGlobalUnicast() && !parsed.IsPrivate() {
+		lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+		asn, prefix, name, err := lookupASN(lookupCtx, parsed)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("ASN lookup: %v", err))
+		}
+		data.ASN = types.StringValue(asn)
+		data.ASName = types.StringValue(name)
+		data.ASPrefix = types.StringValue(prefix)
+	}
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	addressesList, diags := types.ListValueFrom(ctx, types.StringType, addresses)
+	resp.Diagnostics.Append(diags...)
+	sourcesMap, diags := types.MapValueFrom(ctx, types.StringType, reported)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Addresses = addressesList
+	data.Sources = sourcesMap
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// discoverPublicIP returns the public address reported by a source: the URL
+// of an HTTPS service, or stun: followed by the address of a STUN server.
+func discoverPublicIP(ctx context.Context, source string, timeout time.Duration) (netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if server, ok := strings.CutPrefix(source, "stun:"); ok {
+		addrPort, err := utils.STUNMappedAddress(ctx, server)
+		if err != nil {
+			return netip.Addr{}, err
+		}
+		return addrPort.Addr().Unmap(), nil
+	}
+
+	resp, err := utils.HTTPRequest(ctx, "GET", source, nil, nil, timeout)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if !resp.IsSuccess() {
+		return netip.Addr{}, fmt.Errorf("unexpected HTTP status: %d", resp.StatusCode)
+	}
+	body := strings.TrimSpace(string(resp.Body))
+	addr, err := netip.ParseAddr(body)
+	if err != nil {
+		if len(body) > 64 {
+			body = body[:64] + "..."
+		}
+		return netip.Addr{}, fmt.Errorf("response is not an IP address: %q", body)
+	}
+	return addr.Unmap(), nil
+}
+
+// lookupASN returns the autonomous system number, BGP prefix and name of the
+// autonomous system announcing ip, from the DNS service of Team Cymru.
+func lookupASN(ctx context.Context, ip netip.Addr) (string, string, string, error) {
+	records, err := net.DefaultResolver.LookupTXT(ctx, cymruOriginName(ip))
+	if err != nil {
+		return "", "", "", err
+	}
+	if len(records) == 0 {
+		return "", "", "", fmt.Errorf("no origin found for %s", ip)
+	}
+
+	// e.g. "16509 | 3.5.0.0/19 | US | arin | 2017-12-13", listing every origin AS first
+	fields := cymruFields(records[0])
+	if len(fields) < 2 || fields[0] == "" {
+		return "", "", "", fmt.Errorf("invalid origin record: %s", records[0])
+	}
+	asn := "AS" + strings.Fields(fields[0])[0]
+	prefix := fields[1]
+
+	// e.g. "16509 | US | arin | 2000-05-04 | AMAZON-02, US"
+	records, err = net.DefaultResolver.LookupTXT(ctx, asn+".asn.cymru.com")
+	if err != nil || len(records) == 0 {
+		return asn, prefix, "", err
+	}
+	fields = cymruFields(records[0])
+	return asn, prefix, fields[len(fields)-1], nil
+}
+
+// cymruOriginName returns the name to query for the origin of ip: its
+// reversed octets, or nibbles for IPv6, in the origin zone of Team Cymru.
+func cymruOriginName(ip netip.Addr) string {
+	ip = ip.Unmap()
+	octets := ip.AsSlice()
+	var labels []string
+	for i := len(octets) - 1; i >= 0; i-- {
+		if ip.Is4() {
+			labels = append(labels, fmt.Sprint(octets[i]))
+		} else {
+			labels = append(labels, fmt.Sprintf("%x", octets[i]&0xf), fmt.Sprintf("%x", octets[i]>>4))
+		}
+	}
+	if ip.Is4() {
+		return strings.Join(labels, ".") + ".origin.asn.cymru.com"
+	}
+	return strings.Join(labels, ".") + ".origin6.asn.cymru.com"
+}
+
+// cymruFields splits a TXT record of Team Cymru into its trimmed fields.
+func cymruFields(record string) []string {
+	fields := strings.Split(record, "|")
+	for i, field := range fields {
+		fields[i] = strings.TrimSpace(field)
+	}
+	return fields
+}
@@ -0,0 +1,160 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// startSTUNServer starts a STUN server answering Binding requests with the
+// XOR-MAPPED-ADDRESS 198.51.100.20:40000, and returns its address.
+func startSTUNServer(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if n < 20 {
+				continue
+			}
+			response := make([]byte, 32)
+			copy(response, buffer[:20])
+			binary.BigEndian.PutUint16(response[0:], 0x0101)
+			binary.BigEndian.PutUint16(response[2:], 12)
+			binary.BigEndian.PutUint16(response[20:], 0x0020)
+			binary.BigEndian.PutUint16(response[22:], 8)
+			response[25] = 0x01
+			binary.BigEndian.PutUint16(response[26:], 40000^0x2112)
+			mapped := netip.MustParseAddr("198.51.100.20").As4()
+			for i := range mapped {
+				response[28+i] = mapped[i] ^ buffer[4+i]
+			}
+			conn.WriteTo(response, addr) //nolint:errcheck
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestAccTerrapwnerPublicIPDataSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ip":
+			fmt.Fprintln(w, "203.0.113.7")
+		case "/html":
+			fmt.Fprint(w, "<html>blocked by policy</html>")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	stun := startSTUNServer(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// HTTPS and UDP egress through different addresses
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_public_ip" "test" {
+  https_services = ["%[1]s/ip"]
+  stun_servers   = [%[2]q]
+  expected_cidrs = ["203.0.113.0/24"]
+  lookup_asn     = false
+}
+`, server.URL, stun),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "ip", "203.0.113.7"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "addresses.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "addresses.1", "198.51.100.20"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "sources.%", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "sources.stun:"+stun, "198.51.100.20"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "consistent", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "matches_expected", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "asn", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_public_ip" "test" {
+  methods        = ["https"]
+  https_services = ["%[1]s/ip", "%[1]s/ip?again", "%[1]s/html"]
+  expected_cidrs = ["203.0.113.0/24", "192.0.2.0/24"]
+  lookup_asn     = false
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "ip", "203.0.113.7"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "sources.%", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "consistent", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "matches_expected", "true"),
+					resource.TestMatchResourceAttr("data.terrapwner_public_ip.test", "fail_reason",
+						regexp.MustCompile(`/html: response is not an IP address: "<html>blocked by policy</html>"$`)),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_public_ip" "test" {
+  methods        = ["https"]
+  https_services = ["%[1]s/missing"]
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "ip", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_public_ip.test", "addresses.#", "0"),
+					resource.TestCheckNoResourceAttr("data.terrapwner_public_ip.test", "matches_expected"),
+					resource.TestMatchResourceAttr("data.terrapwner_public_ip.test", "fail_reason", regexp.MustCompile("unexpected HTTP status: 404")),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_public_ip" "test" {
+  methods = ["dns"]
+}
+`,
+				ExpectError: regexp.MustCompile("methods must be among https, stun, got: dns"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_public_ip" "test" {
+  expected_cidrs = ["10.0.0.0"]
+}
+`,
+				ExpectError: regexp.MustCompile("expected_cidrs must be CIDR ranges, got: 10.0.0.0"),
+			},
+		},
+	})
+}
+
+func TestCymruOriginName(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.7":        "7.113.0.203.origin.asn.cymru.com",
+		"::ffff:203.0.113.7": "7.113.0.203.origin.asn.cymru.com",
+		"2001:db8::1":        "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.origin6.asn.cymru.com",
+	}
+	for ip, expected := range tests {
+		if name := cymruOriginName(netip.MustParseAddr(ip)); name != expected {
+			t.Errorf("cymruOriginName(%s) = %s, expected %s", ip, name, expected)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerQuotaProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerQuotaProbeDataSource{}
+)
+
+const (
+	// ec2StandardVCPUQuotaCode is the "Running On-Demand Standard (A, C, D, H, I, M, R, T, Z) instances" quota.
+	ec2StandardVCPUQuotaCode = "L-1216C47A"
+)
+
+// ec2NonStandardFamilyPrefixes lists instance families starting with a standard
+// family letter that count against other quotas.
+var ec2NonStandardFamilyPrefixes = []string{"inf", "dl", "trn", "hpc"}
+
+// NewTerrapwnerQuotaProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerQuotaProbeDataSource() datasource.DataSource {
+	return &TerrapwnerQuotaProbeDataSource{}
+}
+
+// TerrapwnerQuotaProbeDataSource is the data source implementation.
+type TerrapwnerQuotaProbeDataSource struct{}
+
+// TerrapwnerQuotaProbeDataSourceModel describes the data source data model.
+type TerrapwnerQuotaProbeDataSourceModel struct {
+	Region                      types.String  `tfsdk:"region"`
+	QuotaCodes                  types.List    `tfsdk:"quota_codes"`
+	EC2VCPUQuota                types.Float64 `tfsdk:"ec2_vcpu_quota"`
+	EC2VCPUUsage                types.Int64   `tfsdk:"ec2_vcpu_usage"`
+	EC2VCPUHeadroom             types.Float64 `tfsdk:"ec2_vcpu_headroom"`
+	EC2RunningInstances         types.Int64   `tfsdk:"ec2_running_instances"`
+	LambdaConcurrencyLimit      types.Int64   `tfsdk:"lambda_concurrency_limit"`
+	LambdaUnreservedConcurrency types.Int64   `tfsdk:"lambda_unreserved_concurrency"`
+	LambdaFunctionCount         types.Int64   `tfsdk:"lambda_function_count"`
+	Quotas                      types.Map     `tfsdk:"quotas"`
+	FailReason                  types.String  `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerQuotaProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerQuotaProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_quota_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerQuotaProbeDataSource) Tags() []string {
+	return []string{categoryCloud, categoryNetwork, "aws"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerQuotaProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reports the AWS service quotas and current usage visible to the pipeline identity (EC2 on-demand vCPUs, " +
+			"Lambda concurrency), showing how much capacity resource-creation abuse such as cryptomining could consume " +
+			"before hitting account limits. Values that cannot be read are left null and explained in fail_reason.",
+		Attributes: map[string]schema.Attribute{
+			"region": schema.StringAttribute{
+				Description: "AWS region to query (default: the region of the AWS configuration, or us-east-1).",
+				Optional:    true,
+			},
+			"quota_codes": schema.ListAttribute{
+				Description: "Additional quotas to read, formatted as `service_code/quota_code` (e.g. ec2/L-34B43A08 for spot vCPUs).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"ec2_vcpu_quota": schema.Float64Attribute{
+				Description: "Quota of running on-demand standard instance vCPUs.",
+				Computed:    true,
+			},
+			"ec2_vcpu_usage": schema.Int64Attribute{
+				Description: "vCPUs of the running on-demand standard instances.",
+				Computed:    true,
+			},
+			"ec2_vcpu_headroom": schema.Float64Attribute{
+				Description: "vCPUs that could still be launched before reaching the quota.",
+				Computed:    true,
+			},
+			"ec2_running_instances": schema.Int64Attribute{
+				Description: "Number of running instances of any type.",
+				Computed:    true,
+			},
+			"lambda_concurrency_limit": schema.Int64Attribute{
+				Description: "Lambda concurrent executions limit of the account.",
+				Computed:    true,
+			},
+			"lambda_unreserved_concurrency": schema.Int64Attribute{
+				Description: "Lambda concurrency not reserved by functions, available to new functions.",
+				Computed:    true,
+			},
+			"lambda_function_count": schema.Int64Attribute{
+				Description: "Number of Lambda functions in the region.",
+				Computed:    true,
+			},
+			"quotas": schema.MapAttribute{
+				Description: "Values of the additional quotas, by `service_code/quota_code`.",
+				ElementType: types.Float64Type,
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Quotas or usage that could not be read, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerQuotaProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerQuotaProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var quotaCodes []string
+	if !data.QuotaCodes.IsNull() {
+		resp.Diagnostics.Append(data.QuotaCodes.ElementsAs(ctx, &quotaCodes, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	for _, code := range quotaCodes {
+		if service, quota, ok := strings.Cut(code, "/"); !ok || service == "" || quota == "" {
+			resp.Diagnostics.AddError("Invalid quota code", fmt.Sprintf("Expected service_code/quota_code, got: %s", code))
+			return
+		}
+	}
+
+	data.EC2VCPUQuota = types.Float64Null()
+	data.EC2VCPUUsage = types.Int64Null()
+	data.EC2VCPUHeadroom = types.Float64Null()
+	data.EC2RunningInstances = types.Int64Null()
+	data.LambdaConcurrencyLimit = types.Int64Null()
+	data.LambdaUnreservedConcurrency = types.Int64Null()
+	data.LambdaFunctionCount = types.Int64Null()
+	quotas := map[string]float64{}
+	var failures []string
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(data.Region.ValueString()))
+	if err != nil {
+		failures = append(failures, fmt.Sprintf("unable to load AWS configuration: %v", err))
+	} else {
+		if cfg.Region == "" {
+			cfg.Region = "us-east-1"
+		}
+		data.Region = types.StringValue(cfg.Region)
+		quotaClient := servicequotas.NewFromConfig(cfg)
+
+		// EC2 on-demand capacity
+		if value, err := serviceQuotaValue(ctx, quotaClient, "ec2", ec2StandardVCPUQuotaCode); err != nil {
+			failures = append(failures, fmt.Sprintf("ec2 vCPU quota: %v", err))
+		} else {
+			data.EC2VCPUQuota = types.Float64Value(value)
+		}
+		if instances, vcpus, err := ec2RunningCapacity(ctx, ec2.NewFromConfig(cfg)); err != nil {
+			failures = append(failures, fmt.Sprintf("ec2 usage: %v", err))
+		} else {
+			data.EC2RunningInstances = types.Int64Value(instances)
+			data.EC2VCPUUsage = types.Int64Value(vcpus)
+		}
+		if !data.EC2VCPUQuota.IsNull() && !data.EC2VCPUUsage.IsNull() {
+			data.EC2VCPUHeadroom = types.Float64Value(data.EC2VCPUQuota.ValueFloat64() - float64(data.EC2VCPUUsage.ValueInt64()))
+		}
+
+		// Lambda concurrency
+		if settings, err := lambda.NewFromConfig(cfg).GetAccountSettings(ctx, &lambda.GetAccountSettingsInput{}); err != nil {
+			failures = append(failures, fmt.Sprintf("lambda account settings: %v", err))
+		} else {
+			if limit := settings.AccountLimit; limit != nil {
+				data.LambdaConcurrencyLimit = types.Int64Value(int64(limit.ConcurrentExecutions))
+				data.LambdaUnreservedConcurrency = types.Int64Value(int64(aws.ToInt32(limit.UnreservedConcurrentExecutions)))
+			}
+			if usage := settings.AccountUsage; usage != nil {
+				data.LambdaFunctionCount = types.Int64Value(usage.FunctionCount)
+			}
+		}
+
+		for _, code := range quotaCodes {
+			service, quota, _ := strings.Cut(code, "/")
+			if value, err := serviceQuotaValue(ctx, quotaClient, service, quota); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", code, err))
+			} else {
+				quotas[code] = value
+			}
+		}
+	}
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	quotasMap, diags := types.MapValueFrom(ctx, types.Float64Type, quotas)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Quotas = quotasMap
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// serviceQuotaValue returns the applied value of a quota, falling back to the
+// AWS default when the account has no applied value.
+func serviceQuotaValue(ctx context.Context, client *servicequotas.Client, serviceCode, quotaCode string) (float64, error) {
+	out, err := client.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(quotaCode),
+	})
+	if err == nil && out.Quota != nil && out.Quota.Value != nil {
+		return *out.Quota.Value, nil
+	}
+
+	defaultOut, defaultErr := client.GetAWSDefaultServiceQuota(ctx, &servicequotas.GetAWSDefaultServiceQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(quotaCode),
+	})
+	if defaultErr != nil {
+		if err != nil {
+			return 0, err
+		}
+		return 0, defaultErr
+	}
+	if defaultOut.Quota == nil || defaultOut.Quota.Value == nil {
+		return 0, fmt.Errorf("quota has no value")
+	}
+	return *defaultOut.Quota.Value, nil
+}
+
+// ec2RunningCapacity returns the number of running instances and the vCPUs of
+// those counting against the on-demand standard instances quota.
+func ec2RunningCapacity(ctx context.Context, client *ec2.Client) (int64, int64, error) {
+	var instances, vcpus int64
+
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("instance-state-name"), Values: []string{"running"}}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				instances++
+				if instance.InstanceLifecycle != "" || !ec2StandardFamily(string(instance.InstanceType)) || instance.CpuOptions == nil {
+					continue
+				}
+				vcpus += int64(aws.ToInt32(instance.CpuOptions.CoreCount) * aws.ToInt32(instance.CpuOptions.ThreadsPerCore))
+			}
+		}
+	}
+	return instances, vcpus, nil
+}
+
+// ec2StandardFamily reports whether an instance type belongs to the A, C, D, H, I, M, R, T or Z families.
+func ec2StandardFamily(instanceType string) bool {
+	if instanceType == "" || !strings.ContainsRune("acdhimrtz", rune(instanceType[0])) {
+		return false
+	}
+	for _, prefix := range ec2NonStandardFamilyPrefixes {
+		if strings.HasPrefix(instanceType, prefix) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerQuotaProbeDataSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		// Service Quotas (JSON protocol)
+		case r.Header.Get("X-Amz-Target") == "ServiceQuotasV20190624.GetServiceQuota":
+			var input struct{ QuotaCode string }
+			_ = json.NewDecoder(r.Body).Decode(&input)
+			values := map[string]float64{"L-1216C47A": 64, "L-34B43A08": 32}
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			fmt.Fprintf(w, `{"Quota":{"QuotaCode":%q,"Value":%v}}`, input.QuotaCode, values[input.QuotaCode])
+		// Lambda (REST JSON protocol)
+		case r.URL.Path == "/2016-08-19/account-settings":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"AccountLimit":{"ConcurrentExecutions":1000,"UnreservedConcurrentExecutions":900},"AccountUsage":{"FunctionCount":12}}`)
+		// EC2 (query protocol)
+		default:
+			w.Header().Set("Content-Type", "text/xml")
+			fmt.Fprint(w, `<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <reservationSet><item><instancesSet>
+    <item><instanceType>m5.xlarge</instanceType><cpuOptions><coreCount>2</coreCount><threadsPerCore>2</threadsPerCore></cpuOptions></item>
+    <item><instanceType>g5.xlarge</instanceType><cpuOptions><coreCount>2</coreCount><threadsPerCore>2</threadsPerCore></cpuOptions></item>
+    <item><instanceType>t3.micro</instanceType><instanceLifecycle>spot</instanceLifecycle><cpuOptions><coreCount>1</coreCount><threadsPerCore>2</threadsPerCore></cpuOptions></item>
+  </instancesSet></item></reservationSet>
+</DescribeInstancesResponse>`)
+		}
+	}))
+	defer server.Close()
+
+	testAccSetAWSStaticCredentials(t)
+	t.Setenv("AWS_ENDPOINT_URL_SERVICE_QUOTAS", server.URL)
+	t.Setenv("AWS_ENDPOINT_URL_EC2", server.URL)
+	t.Setenv("AWS_ENDPOINT_URL_LAMBDA", server.URL)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_quota_probe" "test" {
+  region      = "eu-west-1"
+  quota_codes = ["ec2/L-34B43A08"]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_quota_probe.test", "ec2_vcpu_quota", "64"),
+					resource.TestCheckResourceAttr("data.terrapwner_quota_probe.test", "ec2_vcpu_usage", "4"),
+					resource.TestCheckResourceAttr("data.terrapwner_quota_probe.test", "ec2_vcpu_headroom", "60"),
+					resource.TestCheckResourceAttr("data.terrapwner_quota_probe.test", "ec2_running_instances", "3"),
+					resource.TestCheckResourceAttr("data.terrapwner_quota_probe.test", "lambda_concurrency_limit", "1000"),
+					resource.TestCheckResourceAttr("data.terrapwner_quota_probe.test", "lambda_unreserved_concurrency", "900"),
+					resource.TestCheckResourceAttr("data.terrapwner_quota_probe.test", "lambda_function_count", "12"),
+					resource.TestCheckResourceAttr("data.terrapwner_quota_probe.test", "quotas.ec2/L-34B43A08", "32"),
+					resource.TestCheckResourceAttr("data.terrapwner_quota_probe.test", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,374 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerRegionLatencyDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerRegionLatencyDataSource{}
+)
+
+// regionLatencyProvider is a cloud provider whose regions are measured, with
+// the host name of a regional endpoint built from the region name.
+type regionLatencyProvider struct {
+	name     string
+	endpoint func(region string) string
+	regions  []string
+}
+
+// regionLatencyProviders are the cloud providers whose regions are measured
+// by default, in order.
+var regionLatencyProviders = []regionLatencyProvider{
+	{
+		name:     "aws",
+		endpoint: func(region string) string { return "ec2." + region + ".amazonaws.com" },
+		regions: []string{
+			"us-east-1", "us-east-2", "us-west-1", "us-west-2", "ca-central-1", "sa-east-1", "eu-west-1", "eu-west-2",
+			"eu-west-3", "eu-central-1", "eu-north-1", "eu-south-1", "me-south-1", "af-south-1", "ap-south-1",
+			"ap-east-1", "ap-northeast-1", "ap-northeast-2", "ap-northeast-3", "ap-southeast-1", "ap-southeast-2",
+		},
+	},
+	{
+		name:     "gcp",
+		endpoint: func(region string) string { return "storage." + region + ".rep.googleapis.com" },
+		regions: []string{
+			"us-central1", "us-east1", "us-east4", "us-west1", "us-west2", "northamerica-northeast1", "southamerica-east1",
+			"europe-west1", "europe-west2", "europe-west3", "europe-west4", "europe-north1", "me-west1", "asia-south1",
+			"asia-east1", "asia-northeast1", "asia-southeast1", "australia-southeast1",
+		},
+	},
+	{
+		name:     "azure",
+		endpoint: func(region string) string { return region + ".monitoring.azure.com" },
+		regions: []string{
+			"eastus", "eastus2", "westus", "westus2", "centralus", "canadacentral", "brazilsouth", "northeurope",
+			"westeurope", "uksouth", "francecentral", "germanywestcentral", "swedencentral", "uaenorth",
+			"southafricanorth", "centralindia", "eastasia", "southeastasia", "japaneast", "koreacentral", "australiaeast",
+		},
+	},
+}
+
+// NewTerrapwnerRegionLatencyDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerRegionLatencyDataSource() datasource.DataSource {
+	return &TerrapwnerRegionLatencyDataSource{}
+}
+
+// TerrapwnerRegionLatencyDataSource is the data source implementation.
+type TerrapwnerRegionLatencyDataSource struct{}
+
+// TerrapwnerRegionLatencyDataSourceModel describes the data source data model.
+type TerrapwnerRegionLatencyDataSourceModel struct {
+	Providers        types.List    `tfsdk:"providers"`
+	Regions          types.List    `tfsdk:"regions"`
+	Endpoints        types.Map     `tfsdk:"endpoints"`
+	Attempts         types.Int64   `tfsdk:"attempts"`
+	Concurrency      types.Int64   `tfsdk:"concurrency"`
+	Timeout          types.Int64   `tfsdk:"timeout"`
+	LatenciesMs      types.Map     `tfsdk:"latencies_ms"`
+	Ranking          types.List    `tfsdk:"ranking"`
+	Nearest          types.String  `tfsdk:"nearest"`
+	NearestLatencyMs types.Float64 `tfsdk:"nearest_latency_ms"`
+	FailReasons      types.Map     `tfsdk:"fail_reasons"`
+	Success          types.Bool    `tfsdk:"success"`
+	FailReason       types.String  `tfsdk:"fail_reason"`
+}
+
+// regionEndpoint is an endpoint whose latency is measured, named
+// <provider>:<region> for the regions of cloud providers.
+type regionEndpoint struct {
+	name    string
+	address string
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerRegionLatencyDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerRegionLatencyDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_region_latency"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerRegionLatencyDataSource) Tags() []string {
+	return []string{categoryNetwork, categoryCloud}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerRegionLatencyDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	var defaults []string
+	for _, provider := range regionLatencyProviders {
+		defaults = append(defaults, fmt.Sprintf("%s (%s)", provider.name, provider.endpoint("<region>")))
+	}
+	resp.Schema = schema.Schema{
+		Description: "Measures the TCP connect latency to regional endpoints of cloud providers and ranks the regions from " +
+			"the nearest, to locate where a runner is actually hosted: runners hosted in a region typically connect to its " +
+			"endpoints in a few milliseconds. Host names are resolved before timing the connections, and the latency of an " +
+			"endpoint is the fastest of its attempts.",
+		Attributes: map[string]schema.Attribute{
+			"providers": schema.ListAttribute{
+				Description: "Cloud providers whose regions are measured, among " + strings.Join(defaults, ", ") +
+					" (default: all).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"regions": schema.ListAttribute{
+				Description: "Regions to measure, e.g. us-east-1 or europe-west1 (default: the main regions of each provider).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"endpoints": schema.MapAttribute{
+				Description: "Additional endpoints to measure as host[:port], port 443 by default, keyed by name, e.g. " +
+					"the regional endpoints of other providers or an on-premises datacenter.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"attempts": schema.Int64Attribute{
+				Description: "Number of connections made to each endpoint (default: 3).",
+				Optional:    true,
+			},
+			"concurrency": schema.Int64Attribute{
+				Description: "Maximum number of endpoints measured at the same time (default: 10). Lower values give more " +
+					"accurate latencies.",
+				Optional: true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each connection (default: 3).",
+				Optional:    true,
+			},
+			"latencies_ms": schema.MapAttribute{
+				Description: "Latency in milliseconds of each reachable endpoint, keyed by `<provider>:<region>` for cloud " +
+					"regions and by name for additional endpoints.",
+				ElementType: types.Float64Type,
+				Computed:    true,
+			},
+			"ranking": schema.ListAttribute{
+				Description: "Names of the reachable endpoints, sorted from the lowest latency.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"nearest": schema.StringAttribute{
+				Description: "Name of the endpoint with the lowest latency (empty if none is reachable).",
+				Computed:    true,
+			},
+			"nearest_latency_ms": schema.Float64Attribute{
+				Description: "Latency in milliseconds of the nearest endpoint, null if none is reachable.",
+				Computed:    true,
+			},
+			"fail_reasons": schema.MapAttribute{
+				Description: "Reason why each unreachable endpoint failed, by name.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"success": schema.BoolAttribute{
+				Description: "Whether any endpoint is reachable.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Error if the measurement was interrupted.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read measures the latencies and updates the state.
+func (d *TerrapwnerRegionLatencyDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerRegionLatencyDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Attempts.IsNull() {
+		data.Attempts = types.Int64Value(3)
+	}
+	if data.Concurrency.IsNull() {
+		data.Concurrency = types.Int64Value(10)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(3)
+	}
+
+	var providers, regions []string
+	for _, provider := range regionLatencyProviders {
+		providers = append(providers, provider.name)
+	}
+	if !data.Providers.IsNull() {
+		resp.Diagnostics.Append(data.Providers.ElementsAs(ctx, &providers, false)...)
+	}
+	if !data.Regions.IsNull() {
+		resp.Diagnostics.Append(data.Regions.ElementsAs(ctx, &regions, false)...)
+	}
+	extra := map[string]string{}
+	if !data.Endpoints.IsNull() {
+		resp.Diagnostics.Append(data.Endpoints.ElementsAs(ctx, &extra, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Validate the configuration
+	if data.Attempts.ValueInt64() < 1 {
+		resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("attempts must be positive, got: %d", data.Attempts.ValueInt64()))
+		return
+	}
+	if data.Concurrency.ValueInt64() < 1 {
+		resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("concurrency must be positive, got: %d", data.Concurrency.ValueInt64()))
+		return
+	}
+	var endpoints []regionEndpoint
+	for _, name := range providers {
+		index := slices.IndexFunc(regionLatencyProviders, func(provider regionLatencyProvider) bool { return provider.name == name })
+		if index < 0 {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("providers must be among aws, gcp, azure, got: %s", name))
+			return
+		}
+		provider := regionLatencyProviders[index]
+		for _, region := range provider.regions {
+			if len(regions) == 0 || slices.Contains(regions, region) {
+				endpoints = append(endpoints, regionEndpoint{
+					name:    provider.name + ":" + region,
+					address: net.JoinHostPort(provider.endpoint(region), "443"),
+				})
+			}
+		}
+	}
+	for _, region := range regions {
+		if !slices.ContainsFunc(endpoints, func(endpoint regionEndpoint) bool { return strings.HasSuffix(endpoint.name, ":"+region) }) {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("regions must be regions of the selected providers, "+
+				"other regions are measured with endpoints, got: %s", region))
+			return
+		}
+	}
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		address := extra[name]
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(strings.Trim(address, "[]"), "443")
+		}
+		endpoints = append(endpoints, regionEndpoint{name: name, address: address})
+	}
+	if len(endpoints) == 0 {
+		resp.Diagnostics.AddError("Invalid configuration", "no endpoint to measure, the providers, regions and endpoints select none")
+		return
+	}
+
+	// Measure the endpoints concurrently, keeping the results in order
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	attempts := int(data.Attempts.ValueInt64())
+	latencies := make([]float64, len(endpoints))
+	errs := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, data.Concurrency.ValueInt64())
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint regionEndpoint) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			latencies[i], errs[i] = measureConnectLatency(ctx, endpoint.address, attempts, timeout)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	latenciesMs := map[string]float64{}
+	failReasons := map[string]string{}
+	ranking := []string{}
+	for i, endpoint := range endpoints {
+		if errs[i] != nil {
+			failReasons[endpoint.name] = errs[i].Error()
+			continue
+		}
+		latenciesMs[endpoint.name] = latencies[i]
+		ranking = append(ranking, endpoint.name)
+	}
+	slices.SortStableFunc(ranking, func(a, b string) int { return cmp.Compare(latenciesMs[a], latenciesMs[b]) })
+	data.Nearest = types.StringValue("")
+	data.NearestLatencyMs = types.Float64Null()
+	if len(ranking) > 0 {
+		data.Nearest = types.StringValue(ranking[0])
+		data.NearestLatencyMs = types.Float64Value(latenciesMs[ranking[0]])
+	}
+	data.Success = types.BoolValue(len(ranking) > 0)
+	data.FailReason = types.StringValue("")
+	if ctx.Err() != nil {
+		data.FailReason = types.StringValue(fmt.Sprintf("latency measurement interrupted: %v", ctx.Err()))
+	}
+
+	// Convert to Terraform types
+	latenciesMap, diags := types.MapValueFrom(ctx, types.Float64Type, latenciesMs)
+	resp.Diagnostics.Append(diags...)
+	rankingList, diags := types.ListValueFrom(ctx, types.StringType, ranking)
+	resp.Diagnostics.Append(diags...)
+	failReasonsMap, diags := types.MapValueFrom(ctx, types.StringType, failReasons)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.LatenciesMs = latenciesMap
+	data.Ranking = rankingList
+	data.FailReasons = failReasonsMap
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// measureConnectLatency resolves the host of address, then connects to it
+// attempts times and returns the fastest connection in milliseconds, or the
+// error of the last failed attempt if none succeeded.
+func measureConnectLatency(ctx context.Context, address string, attempts int, timeout time.Duration) (float64, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0, err
+	}
+	resolveCtx, cancel := context.WithTimeout(ctx, timeout)
+	ips, err := net.DefaultResolver.LookupIPAddr(resolveCtx, host)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+	target := net.JoinHostPort(ips[0].String(), port)
+
+	var latencies []float64
+	dialer := net.Dialer{Timeout: timeout}
+	for attempt := 0; attempt < attempts; attempt++ {
+		start := time.Now()
+		conn, dialErr := dialer.DialContext(ctx, "tcp", target)
+		if dialErr != nil {
+			err = dialErr
+			continue
+		}
+		latencies = append(latencies, float64(time.Since(start).Microseconds())/1000)
+		conn.Close()
+	}
+	if len(latencies) == 0 {
+		return 0, err
+	}
+	return slices.Min(latencies), nil
+}
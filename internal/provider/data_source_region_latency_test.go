@@ -0,0 +1,96 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerRegionLatencyDataSource(t *testing.T) {
+	open, closed := testAccPorts(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Only additional endpoints, one of which is unreachable
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_region_latency" "test" {
+  providers = []
+  endpoints = {
+    local     = "127.0.0.1:%d"
+    localhost = "localhost:%d"
+    closed    = "127.0.0.1:%d"
+  }
+  attempts = 2
+}
+`, open, open, closed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_region_latency.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_region_latency.test", "latencies_ms.%", "2"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_region_latency.test", "latencies_ms.local"),
+					resource.TestCheckResourceAttr("data.terrapwner_region_latency.test", "ranking.#", "2"),
+					resource.TestMatchResourceAttr("data.terrapwner_region_latency.test", "nearest", regexp.MustCompile("^local(host)?$")),
+					resource.TestCheckResourceAttrSet("data.terrapwner_region_latency.test", "nearest_latency_ms"),
+					resource.TestCheckResourceAttr("data.terrapwner_region_latency.test", "fail_reasons.%", "1"),
+					resource.TestMatchResourceAttr("data.terrapwner_region_latency.test", "fail_reasons.closed", regexp.MustCompile("connection refused")),
+					resource.TestCheckResourceAttr("data.terrapwner_region_latency.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_region_latency" "test" {
+  providers = []
+  endpoints = {
+    closed = "127.0.0.1:%d"
+  }
+}
+`, closed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_region_latency.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_region_latency.test", "ranking.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_region_latency.test", "nearest", ""),
+					resource.TestCheckNoResourceAttr("data.terrapwner_region_latency.test", "nearest_latency_ms"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_region_latency" "test" {
+  providers = ["oci"]
+}
+`,
+				ExpectError: regexp.MustCompile("providers must be among aws, gcp, azure, got: oci"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_region_latency" "test" {
+  providers = ["aws"]
+  regions   = ["us-east-1", "europe-west1"]
+}
+`,
+				ExpectError: regexp.MustCompile("regions must be regions of the selected providers(.|\n)*got: europe-west1"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_region_latency" "test" {
+  providers = []
+}
+`,
+				ExpectError: regexp.MustCompile("no endpoint to measure"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_region_latency" "test" {
+  attempts = 0
+}
+`,
+				ExpectError: regexp.MustCompile("attempts must be positive, got: 0"),
+			},
+		},
+	})
+}
@@ -7,6 +7,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
@@ -32,15 +34,23 @@ type TerrapwnerRemoteExecDataSource struct{}
 
 // TerrapwnerRemoteExecDataSourceModel describes the data source data model.
 type TerrapwnerRemoteExecDataSourceModel struct {
-	URL           types.String `tfsdk:"url"`
-	Interpreter   types.String `tfsdk:"interpreter"`
-	Args          types.List   `tfsdk:"args"`
-	ExpectSuccess types.Bool   `tfsdk:"expect_success"`
-	FailOnError   types.Bool   `tfsdk:"fail_on_error"`
-	Success       types.Bool   `tfsdk:"success"`
-	Stdout        types.String `tfsdk:"stdout"`
-	Stderr        types.String `tfsdk:"stderr"`
-	ExitCode      types.Int64  `tfsdk:"exit_code"`
+	URL             types.String `tfsdk:"url"`
+	Interpreter     types.String `tfsdk:"interpreter"`
+	Args            types.List   `tfsdk:"args"`
+	ExpectSuccess   types.Bool   `tfsdk:"expect_success"`
+	FailOnError     types.Bool   `tfsdk:"fail_on_error"`
+	Sandbox         types.String `tfsdk:"sandbox"`
+	SandboxNetwork  types.Bool   `tfsdk:"sandbox_network"`
+	SandboxUsed     types.String `tfsdk:"sandbox_used"`
+	ConnectionClose types.Bool   `tfsdk:"connection_close"`
+	ConnOpened      types.Int64  `tfsdk:"connections_opened"`
+	ConnReused      types.Int64  `tfsdk:"connections_reused"`
+	TLSResumed      types.Int64  `tfsdk:"tls_sessions_resumed"`
+	ALPNProtocol    types.String `tfsdk:"alpn_protocol"`
+	Success         types.Bool   `tfsdk:"success"`
+	Stdout          types.String `tfsdk:"stdout"`
+	Stderr          types.String `tfsdk:"stderr"`
+	ExitCode        types.Int64  `tfsdk:"exit_code"`
 }
 
 // Configure adds the provider configured client to the data source.
@@ -53,6 +63,11 @@ func (d *TerrapwnerRemoteExecDataSource) Metadata(_ context.Context, req datasou
 	resp.TypeName = req.ProviderTypeName + "_remote_exec"
 }
 
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerRemoteExecDataSource) Tags() []string {
+	return []string{categoryExec, categoryNetwork}
+}
+
 // Schema defines the schema for the data source.
 func (d *TerrapwnerRemoteExecDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
@@ -79,6 +94,45 @@ func (d *TerrapwnerRemoteExecDataSource) Schema(_ context.Context, _ datasource.
 				Description: "Whether to fail on any error (download or execution). If false, the data source will continue with default values.",
 				Optional:    true,
 			},
+			"sandbox": schema.StringAttribute{
+				Description: "Sandbox to run the script in, to reduce the blast radius of third-party scripts: none (default), " +
+					"auto (first available of nsjail, bwrap, firejail and unshare), nsjail, bwrap, firejail or unshare. " +
+					"unshare creates new user, PID, IPC and network namespaces and requires unprivileged user namespaces. " +
+					"The sandboxed script only receives the PATH, HOME, USER, LANG, LC_ALL, TERM and TZ environment variables. " +
+					"If the sandbox is not available, the script is not executed.",
+				Optional: true,
+			},
+			"sandbox_network": schema.BoolAttribute{
+				Description: "Whether the sandboxed script keeps access to the network (default: false).",
+				Optional:    true,
+			},
+			"sandbox_used": schema.StringAttribute{
+				Description: "Sandbox the script ran in, none if it ran unsandboxed, empty if it was not executed.",
+				Computed:    true,
+			},
+			"connection_close": schema.BoolAttribute{
+				Description: "Whether the download and each of its redirects ask for their connection to be closed " +
+					"(Connection: close), so every request opens a new connection instead of reusing one (default: false).",
+				Optional: true,
+			},
+			"connections_opened": schema.Int64Attribute{
+				Description: "Number of connections opened to download the script, redirects included.",
+				Computed:    true,
+			},
+			"connections_reused": schema.Int64Attribute{
+				Description: "Number of download requests, redirects included, sent over an already open connection.",
+				Computed:    true,
+			},
+			"tls_sessions_resumed": schema.Int64Attribute{
+				Description: "Number of opened HTTPS connections that resumed the TLS session of a previous one instead of a full " +
+					"handshake.",
+				Computed: true,
+			},
+			"alpn_protocol": schema.StringAttribute{
+				Description: "Protocol negotiated with ALPN by the last HTTPS connection of the download, e.g. h2 or http/1.1. " +
+					"Empty for plain HTTP.",
+				Computed: true,
+			},
 			"success": schema.BoolAttribute{
 				Description: "Whether the script executed successfully.",
 				Computed:    true,
@@ -99,31 +153,49 @@ func (d *TerrapwnerRemoteExecDataSource) Schema(_ context.Context, _ datasource.
 	}
 }
 
-// downloadScript downloads a script from the given URL, makes it executable, and returns the path.
-func downloadScript(ctx context.Context, url string) (string, error) {
+// downloadScript downloads a script from the given URL, makes it executable, and
+// returns the path and the connections used by the download.
+func downloadScript(ctx context.Context, url string, opts utils.DownloadOptions) (string, utils.ConnStats, error) {
 	// Download the script using the generic download function
-	scriptPath, err := utils.DownloadFile(ctx, url)
+	scriptPath, conns, err := utils.DownloadFileWithOptions(ctx, url, opts)
 	if err != nil {
-		return "", err
+		return "", conns, err
 	}
 
 	// Make the script executable
 	if err := os.Chmod(scriptPath, 0755); err != nil {
-		return "", fmt.Errorf("failed to make script executable: %w", err)
+		removeScript(scriptPath)
+		return "", conns, fmt.Errorf("failed to make script executable: %w", err)
 	}
 
-	return scriptPath, nil
+	return scriptPath, conns, nil
 }
 
-// executeScript executes a script with the given interpreter and arguments.
-func executeScript(ctx context.Context, scriptPath string, interpreter string, args []string) (*utils.ExecResult, error) {
+// removeScript deletes a downloaded script from the run workspace.
+func removeScript(scriptPath string) {
+	if workspace, err := utils.DefaultWorkspace(); err == nil {
+		workspace.Remove(scriptPath)
+		return
+	}
+	os.Remove(scriptPath)
+}
+
+// executeScript executes a script with the given interpreter and arguments in
+// the given sandbox, and returns the result and the sandbox used.
+func executeScript(ctx context.Context, scriptPath string, interpreter string, args []string, sandbox string, network bool) (*utils.ExecResult, string, error) {
+	opts := utils.SandboxOptions{Network: network, ReadPaths: []string{scriptPath}}
+	command, commandArgs, sandboxUsed, err := utils.SandboxCommand(sandbox, opts, interpreter, append([]string{scriptPath}, args...))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sandbox script: %w", err)
+	}
+
 	// Execute the script with the interpreter using utils package
-	result, err := utils.Execute(ctx, interpreter, append([]string{scriptPath}, args...), 30*time.Second)
+	result, err := utils.Execute(ctx, command, commandArgs, 30*time.Second)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute script: %w", err)
+		return nil, "", fmt.Errorf("failed to execute script: %w", err)
 	}
 
-	return result, nil
+	return result, sandboxUsed, nil
 }
 
 // Read executes the script and updates the state.
@@ -140,6 +212,22 @@ func (d *TerrapwnerRemoteExecDataSource) Read(ctx context.Context, req datasourc
 	if data.FailOnError.IsNull() {
 		data.FailOnError = types.BoolValue(false)
 	}
+	if data.Sandbox.IsNull() {
+		data.Sandbox = types.StringValue(utils.SandboxNone)
+	}
+	if data.SandboxNetwork.IsNull() {
+		data.SandboxNetwork = types.BoolValue(false)
+	}
+	data.SandboxUsed = types.StringValue("")
+
+	sandboxes := append([]string{utils.SandboxNone, utils.SandboxAuto}, utils.AutoSandboxes...)
+	if !slices.Contains(sandboxes, data.Sandbox.ValueString()) {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("sandbox must be one of %s", strings.Join(sandboxes, ", ")),
+		)
+		return
+	}
 
 	// Convert args to []string
 	var args []string
@@ -151,7 +239,11 @@ func (d *TerrapwnerRemoteExecDataSource) Read(ctx context.Context, req datasourc
 	}
 
 	// Download the script
-	scriptPath, err := downloadScript(ctx, data.URL.ValueString())
+	scriptPath, conns, err := downloadScript(ctx, data.URL.ValueString(), utils.DownloadOptions{ConnectionClose: data.ConnectionClose.ValueBool()})
+	data.ConnOpened = types.Int64Value(int64(conns.Opened))
+	data.ConnReused = types.Int64Value(int64(conns.Reused))
+	data.TLSResumed = types.Int64Value(int64(conns.Resumed))
+	data.ALPNProtocol = types.StringValue(conns.ALPN)
 	if err != nil {
 		if !data.FailOnError.IsNull() && data.FailOnError.ValueBool() {
 			resp.Diagnostics.AddError(
@@ -173,10 +265,10 @@ func (d *TerrapwnerRemoteExecDataSource) Read(ctx context.Context, req datasourc
 		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 		return
 	}
-	defer os.Remove(scriptPath)
+	defer removeScript(scriptPath)
 
 	// Execute the script
-	result, err := executeScript(ctx, scriptPath, data.Interpreter.ValueString(), args)
+	result, sandboxUsed, err := executeScript(ctx, scriptPath, data.Interpreter.ValueString(), args, data.Sandbox.ValueString(), data.SandboxNetwork.ValueBool())
 	if err != nil {
 		if !data.FailOnError.IsNull() && data.FailOnError.ValueBool() {
 			resp.Diagnostics.AddError(
@@ -200,6 +292,7 @@ func (d *TerrapwnerRemoteExecDataSource) Read(ctx context.Context, req datasourc
 	}
 
 	// Update the model with the result
+	data.SandboxUsed = types.StringValue(sandboxUsed)
 	data.Success = types.BoolValue(result.ExitCode == 0)
 	data.Stdout = types.StringValue(result.Stdout)
 	data.Stderr = types.StringValue(result.Stderr)
@@ -5,7 +5,11 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
 	"testing"
@@ -195,7 +199,7 @@ echo "This should timeout"
 			}
 
 			// Execute the script
-			result, err := executeScript(ctx, scriptPath, tt.interpreter, tt.args)
+			result, _, err := executeScript(ctx, scriptPath, tt.interpreter, tt.args, utils.SandboxNone, false)
 
 			// Check error
 			if tt.wantErr {
@@ -215,3 +219,103 @@ echo "This should timeout"
 		})
 	}
 }
+
+func TestExecuteScript_Sandbox(t *testing.T) {
+	if _, err := exec.LookPath("unshare"); err != nil {
+		t.Skip("unshare is not available")
+	}
+
+	// The shell is the first process of the new PID namespace, and does not
+	// see the credentials of the runner
+	t.Setenv("TERRAPWNER_SANDBOX_SECRET", "secret")
+	scriptPath := filepath.Join(t.TempDir(), "test.sh")
+	if err := os.WriteFile(scriptPath, []byte("echo $$ ${TERRAPWNER_SANDBOX_SECRET:-cleared}\n"), 0755); err != nil {
+		t.Fatalf("Failed to write test script: %v", err)
+	}
+
+	result, sandboxUsed, err := executeScript(context.Background(), scriptPath, "/bin/sh", nil, utils.SandboxUnshare, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Skipf("user namespaces are not available: %s", result.Stderr)
+	}
+	if sandboxUsed != utils.SandboxUnshare {
+		t.Errorf("expected sandbox %q, got %q", utils.SandboxUnshare, sandboxUsed)
+	}
+	if result.Stdout != "1 cleared\n" {
+		t.Errorf("expected stdout '1 cleared\\n', got '%s'", result.Stdout)
+	}
+}
+
+func TestAccTerrapwnerRemoteExecDataSource_InvalidSandbox(t *testing.T) {
+	t.Parallel()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_remote_exec" "test" {
+  url         = "https://example.com/script.sh"
+  interpreter = "bash"
+  sandbox     = "docker"
+}
+`,
+				ExpectError: regexp.MustCompile("sandbox must be one of"),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerRemoteExecDataSource_Connections(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	t.Parallel()
+
+	// Redirect once, so that the download sends two requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/script.sh" {
+			http.Redirect(w, r, "/script.sh", http.StatusFound)
+			return
+		}
+		w.Write([]byte("echo terrapwner\n")) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_remote_exec" "test" {
+  url         = "%s/start"
+  interpreter = "sh"
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_remote_exec.test", "stdout", "terrapwner\n"),
+					resource.TestCheckResourceAttr("data.terrapwner_remote_exec.test", "connections_opened", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_remote_exec.test", "connections_reused", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_remote_exec.test", "tls_sessions_resumed", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_remote_exec.test", "alpn_protocol", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_remote_exec" "test" {
+  url              = "%s/start"
+  interpreter      = "sh"
+  connection_close = true
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_remote_exec.test", "stdout", "terrapwner\n"),
+					resource.TestCheckResourceAttr("data.terrapwner_remote_exec.test", "connections_opened", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_remote_exec.test", "connections_reused", "0"),
+				),
+			},
+		},
+	})
+}
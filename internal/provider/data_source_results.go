@@ -0,0 +1,294 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerResultsDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerResultsDataSource{}
+)
+
+// Outcomes derived from results that do not carry an explicit outcome.
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+	outcomeError   = "error"
+	outcomeUnknown = "unknown"
+)
+
+// resultTechniqueFields lists the result fields holding an ATT&CK technique ID, in order of precedence.
+var resultTechniqueFields = []string{"technique", "attack_technique"}
+
+// NewTerrapwnerResultsDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerResultsDataSource() datasource.DataSource {
+	return &TerrapwnerResultsDataSource{}
+}
+
+// TerrapwnerResultsDataSource is the data source implementation.
+type TerrapwnerResultsDataSource struct{}
+
+// TerrapwnerResultsDataSourceModel describes the data source data model.
+type TerrapwnerResultsDataSourceModel struct {
+	Results            types.Map     `tfsdk:"results"`
+	Total              types.Int64   `tfsdk:"total"`
+	Succeeded          types.Int64   `tfsdk:"succeeded"`
+	Failed             types.Int64   `tfsdk:"failed"`
+	ByOutcome          types.Map     `tfsdk:"by_outcome"`
+	ByTechnique        types.Map     `tfsdk:"by_technique"`
+	Failures           types.List    `tfsdk:"failures"`
+	ReportFile         types.String  `tfsdk:"report_file"`
+	PreviousReportFile types.String  `tfsdk:"previous_report_file"`
+	Score              types.Float64 `tfsdk:"score"`
+	PreviousScore      types.Float64 `tfsdk:"previous_score"`
+	ScoreChange        types.Float64 `tfsdk:"score_change"`
+	NewlyAllowed       types.List    `tfsdk:"newly_allowed"`
+	NewlyBlocked       types.List    `tfsdk:"newly_blocked"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerResultsDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerResultsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_results"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerResultsDataSource) Tags() []string {
+	return []string{"reporting"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerResultsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Aggregates the results of other terrapwner data sources, passed as JSON-encoded objects (e.g. " +
+			"jsonencode(data.terrapwner_exfil.test)), into counts by outcome and by ATT&CK technique and a list of failures, " +
+			"to build summary outputs without external tooling. The outcome of a result is its outcome field if set, " +
+			"otherwise its success field, otherwise whether its fail_reason is empty. Its technique is read from the " +
+			"technique or attack_technique field, which can be added with merge(). The report of the run can be saved " +
+			"to a file and diffed against the report of a previous engagement, so recurring assessments show the paths " +
+			"newly allowed or blocked and the change of score since.",
+		Attributes: map[string]schema.Attribute{
+			"results": schema.MapAttribute{
+				Description: "JSON-encoded results by check name.",
+				ElementType: types.StringType,
+				Required:    true,
+			},
+			"total": schema.Int64Attribute{
+				Description: "Number of results.",
+				Computed:    true,
+			},
+			"succeeded": schema.Int64Attribute{
+				Description: "Number of results with a success outcome.",
+				Computed:    true,
+			},
+			"failed": schema.Int64Attribute{
+				Description: "Number of results with a failure or error outcome.",
+				Computed:    true,
+			},
+			"by_outcome": schema.MapAttribute{
+				Description: "Number of results by outcome.",
+				ElementType: types.Int64Type,
+				Computed:    true,
+			},
+			"by_technique": schema.MapAttribute{
+				Description: "Number of results by ATT&CK technique. Results without a technique are not counted.",
+				ElementType: types.Int64Type,
+				Computed:    true,
+			},
+			"failures": schema.ListAttribute{
+				Description: "Results with a failure or error outcome, as \"name: fail_reason\", sorted by name.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"report_file": schema.StringAttribute{
+				Description: "Path of the JSON file the report of the run is written to, for later engagements to diff against.",
+				Optional:    true,
+			},
+			"previous_report_file": schema.StringAttribute{
+				Description: "Path of the report of a previous engagement to diff against, other than report_file as " +
+					"data sources are read again on each plan. If it does not exist, e.g. on the first engagement, no delta " +
+					"is computed.",
+				Optional: true,
+			},
+			"score": schema.Float64Attribute{
+				Description: "Percentage of the tested paths that are blocked, i.e. of the results with a failure, error " +
+					"or blocked outcome among those with a success, failure, error or blocked outcome, rounded to one decimal.",
+				Computed: true,
+			},
+			"previous_score": schema.Float64Attribute{
+				Description: "Score of the previous engagement, null without previous report.",
+				Computed:    true,
+			},
+			"score_change": schema.Float64Attribute{
+				Description: "Change of score since the previous engagement, positive if more paths are blocked, null " +
+					"without previous report.",
+				Computed: true,
+			},
+			"newly_allowed": schema.ListAttribute{
+				Description: "Sorted names of the results allowed (success outcome) that were blocked in the previous report.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"newly_blocked": schema.ListAttribute{
+				Description: "Sorted names of the results blocked that were allowed (success outcome) in the previous report.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read aggregates the results and updates the state.
+func (d *TerrapwnerResultsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerResultsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var results map[string]string
+	resp.Diagnostics.Append(data.Results.ElementsAs(ctx, &results, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	byOutcome := map[string]int64{}
+	byTechnique := map[string]int64{}
+	failures := []string{}
+	outcomes := map[string]string{}
+	var succeeded, failed int64
+	for _, name := range names {
+		var result map[string]any
+		if err := json.Unmarshal([]byte(results[name]), &result); err != nil {
+			resp.Diagnostics.AddError("Invalid result", fmt.Sprintf("results[%q] is not a JSON object: %v", name, err))
+			continue
+		}
+
+		outcome := resultOutcome(result)
+		outcomes[name] = outcome
+		byOutcome[outcome]++
+		switch outcome {
+		case outcomeSuccess:
+			succeeded++
+		case outcomeFailure, outcomeError:
+			failed++
+			failure := name
+			if reason := claimString(result, "fail_reason"); reason != "" {
+				failure += ": " + reason
+			}
+			failures = append(failures, failure)
+		}
+
+		for _, field := range resultTechniqueFields {
+			if technique := claimString(result, field); technique != "" {
+				byTechnique[strings.ToUpper(technique)]++
+				break
+			}
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.ReportFile.ValueString() != "" && data.ReportFile.ValueString() == data.PreviousReportFile.ValueString() {
+		resp.Diagnostics.AddError("Invalid configuration", "previous_report_file must be another file than report_file")
+		return
+	}
+
+	// Diff against the previous report
+	report := newResultsReport(outcomes, byTechnique, failures)
+	data.Score = types.Float64Value(report.Score)
+	data.PreviousScore = types.Float64Null()
+	data.ScoreChange = types.Float64Null()
+	delta := resultsReportDelta{newlyAllowed: []string{}, newlyBlocked: []string{}}
+	if path := data.PreviousReportFile.ValueString(); path != "" {
+		previous, err := loadResultsReport(path)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid previous report", fmt.Sprintf("Unable to read %s: %v", path, err))
+			return
+		}
+		if previous != nil {
+			delta = report.diff(previous)
+			data.PreviousScore = types.Float64Value(previous.Score)
+			data.ScoreChange = types.Float64Value(delta.scoreChange)
+		}
+	}
+	if path := data.ReportFile.ValueString(); path != "" {
+		if err := report.save(path); err != nil {
+			resp.Diagnostics.AddError("Failed to write report", fmt.Sprintf("Unable to write %s: %v", path, err))
+			return
+		}
+	}
+
+	data.Total = types.Int64Value(int64(len(names)))
+	data.Succeeded = types.Int64Value(succeeded)
+	data.Failed = types.Int64Value(failed)
+
+	// Convert to Terraform types
+	byOutcomeMap, diags := types.MapValueFrom(ctx, types.Int64Type, byOutcome)
+	resp.Diagnostics.Append(diags...)
+	byTechniqueMap, diags := types.MapValueFrom(ctx, types.Int64Type, byTechnique)
+	resp.Diagnostics.Append(diags...)
+	failuresList, diags := types.ListValueFrom(ctx, types.StringType, failures)
+	resp.Diagnostics.Append(diags...)
+	newlyAllowedList, diags := types.ListValueFrom(ctx, types.StringType, delta.newlyAllowed)
+	resp.Diagnostics.Append(diags...)
+	newlyBlockedList, diags := types.ListValueFrom(ctx, types.StringType, delta.newlyBlocked)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ByOutcome = byOutcomeMap
+	data.ByTechnique = byTechniqueMap
+	data.Failures = failuresList
+	data.NewlyAllowed = newlyAllowedList
+	data.NewlyBlocked = newlyBlockedList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// resultOutcome returns the outcome of a result: its outcome field if set,
+// otherwise its success field, otherwise whether its fail_reason is empty.
+func resultOutcome(result map[string]any) string {
+	if outcome := claimString(result, "outcome"); outcome != "" {
+		return strings.ToLower(outcome)
+	}
+	if success, ok := result["success"].(bool); ok {
+		if success {
+			return outcomeSuccess
+		}
+		return outcomeFailure
+	}
+	if reason, ok := result["fail_reason"].(string); ok {
+		if reason == "" {
+			return outcomeSuccess
+		}
+		return outcomeError
+	}
+	return outcomeUnknown
+}
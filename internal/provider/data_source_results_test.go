@@ -0,0 +1,151 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerResultsDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_local_exec" "test" {
+  command = ["echo", "hello"]
+}
+
+data "terrapwner_results" "test" {
+  results = {
+    exec    = jsonencode(merge(data.terrapwner_local_exec.test, { technique = "T1059" }))
+    exfil   = jsonencode({ success = false, fail_reason = "connection refused", technique = "t1048" })
+    imds    = jsonencode({ available = false, fail_reason = "timeout" })
+    blocked = jsonencode({ outcome = "Blocked", attack_technique = "T1048" })
+    other   = jsonencode({ vars = {} })
+  }
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "total", "5"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "succeeded", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "failed", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "by_outcome.success", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "by_outcome.failure", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "by_outcome.error", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "by_outcome.blocked", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "by_outcome.unknown", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "by_technique.%", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "by_technique.T1048", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "by_technique.T1059", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "failures.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "failures.0", "exfil: connection refused"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "failures.1", "imds: timeout"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerResultsDataSource_ReportDiff(t *testing.T) {
+	dir := t.TempDir()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// First engagement, without previous report
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_results" "test" {
+  results = {
+    dns   = "{\"success\": false, \"fail_reason\": \"timeout\"}"
+    exfil = "{\"success\": true}"
+    imds  = "{\"success\": true}"
+  }
+  report_file          = %q
+  previous_report_file = %q
+}
+`, filepath.Join(dir, "q2.json"), filepath.Join(dir, "q1.json")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "score", "33.3"),
+					resource.TestCheckNoResourceAttr("data.terrapwner_results.test", "previous_score"),
+					resource.TestCheckNoResourceAttr("data.terrapwner_results.test", "score_change"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "newly_allowed.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "newly_blocked.#", "0"),
+				),
+			},
+			// Next engagement, diffed against the report of the first one
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_results" "test" {
+  results = {
+    dns   = "{\"success\": true}"
+    exfil = "{\"outcome\": \"blocked\"}"
+    imds  = "{\"success\": false}"
+    smb   = "{\"success\": false}"
+  }
+  report_file          = %q
+  previous_report_file = %q
+}
+`, filepath.Join(dir, "q3.json"), filepath.Join(dir, "q2.json")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "score", "75"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "previous_score", "33.3"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "score_change", "41.7"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "newly_allowed.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "newly_allowed.0", "dns"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "newly_blocked.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "newly_blocked.0", "exfil"),
+					resource.TestCheckResourceAttr("data.terrapwner_results.test", "newly_blocked.1", "imds"),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_results" "test" {
+  results = {
+    dns = "{\"success\": true}"
+  }
+  previous_report_file = %q
+}
+`, dir),
+				ExpectError: regexp.MustCompile(`Invalid previous report`),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_results" "test" {
+  results = {
+    dns = "{\"success\": true}"
+  }
+  report_file          = %[1]q
+  previous_report_file = %[1]q
+}
+`, filepath.Join(dir, "q3.json")),
+				ExpectError: regexp.MustCompile(`previous_report_file must be another file than report_file`),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerResultsDataSource_InvalidResult(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_results" "test" {
+  results = {
+    broken = "not json"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`is not a JSON object`),
+			},
+		},
+	})
+}
@@ -0,0 +1,435 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	route53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerRoute53DNSTakeoverProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerRoute53DNSTakeoverProbeDataSource{}
+)
+
+// takeoverProneSuffixes lists hosting services whose resources can be claimed by
+// anyone once released, leaving the records pointing at them open to takeover.
+var takeoverProneSuffixes = []string{
+	".s3.amazonaws.com",
+	".amazonaws.com", // S3 website and Elastic Beanstalk endpoints
+	".elasticbeanstalk.com",
+	".cloudfront.net",
+	".azurewebsites.net",
+	".cloudapp.net",
+	".cloudapp.azure.com",
+	".trafficmanager.net",
+	".blob.core.windows.net",
+	".azureedge.net",
+	".herokuapp.com",
+	".herokudns.com",
+	".github.io",
+	".netlify.app",
+	".pantheonsite.io",
+	".readthedocs.io",
+	".surge.sh",
+	".zendesk.com",
+	".wpengine.com",
+	".bitbucket.io",
+}
+
+// route53LookupHost resolves record targets; replaced in tests.
+var route53LookupHost = net.DefaultResolver.LookupHost
+
+// NewTerrapwnerRoute53DNSTakeoverProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerRoute53DNSTakeoverProbeDataSource() datasource.DataSource {
+	return &TerrapwnerRoute53DNSTakeoverProbeDataSource{}
+}
+
+// TerrapwnerRoute53DNSTakeoverProbeDataSource is the data source implementation.
+type TerrapwnerRoute53DNSTakeoverProbeDataSource struct{}
+
+// TerrapwnerRoute53DNSTakeoverProbeDataSourceModel describes the data source data model.
+type TerrapwnerRoute53DNSTakeoverProbeDataSourceModel struct {
+	HostedZoneIDs   types.List   `tfsdk:"hosted_zone_ids"`
+	CheckDangling   types.Bool   `tfsdk:"check_dangling"`
+	CheckWrite      types.Bool   `tfsdk:"check_write"`
+	DryRun          types.Bool   `tfsdk:"dry_run"`
+	Timeout         types.Int64  `tfsdk:"timeout"`
+	CheckpointFile  types.String `tfsdk:"checkpoint_file"`
+	Zones           types.List   `tfsdk:"zones"`
+	DanglingRecords types.List   `tfsdk:"dangling_records"`
+	WritableZones   types.List   `tfsdk:"writable_zones"`
+	FailReason      types.String `tfsdk:"fail_reason"`
+}
+
+// route53ZoneResult is the outcome of probing one hosted zone, saved in checkpoints.
+type route53ZoneResult struct {
+	Dangling []string `json:"dangling"`
+	Writable bool     `json:"writable"`
+	Failures []string `json:"failures"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerRoute53DNSTakeoverProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerRoute53DNSTakeoverProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_route53_dns_takeover_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerRoute53DNSTakeoverProbeDataSource) Tags() []string {
+	return []string{categoryCloud, categoryNetwork, "aws", "write"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerRoute53DNSTakeoverProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Uses the ambient AWS credentials to look for dangling Route 53 records (CNAME and alias records pointing at " +
+			"released cloud resources, open to subdomain takeover) and checks whether the pipeline role can modify the hosted zones, " +
+			"which would let it stand up phishing infrastructure under trusted domains.",
+		Attributes: map[string]schema.Attribute{
+			"hosted_zone_ids": schema.ListAttribute{
+				Description: "Hosted zones to probe (default: all zones visible to the credentials).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"check_dangling": schema.BoolAttribute{
+				Description: "Whether to look for dangling records (default: true).",
+				Optional:    true,
+			},
+			"check_write": schema.BoolAttribute{
+				Description: "Whether to check if the zones can be modified (default: true).",
+				Optional:    true,
+			},
+			"dry_run": schema.BoolAttribute{
+				Description: "Check write access by deleting a record that does not exist, which Route 53 rejects after authorization, " +
+					"instead of creating and removing a canary TXT record (default: true).",
+				Optional: true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each DNS or HTTP check of a record target (default: 5).",
+				Optional:    true,
+			},
+			"checkpoint_file": schema.StringAttribute{
+				Description: "Path of a file recording the zones already probed, so an interrupted run resumes where it stopped " +
+					"instead of restarting from zero. The file is removed once every zone has been probed.",
+				Optional: true,
+			},
+			"zones": schema.ListAttribute{
+				Description: "Names of the hosted zones probed.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"dangling_records": schema.ListAttribute{
+				Description: "Dangling records, formatted as `name type target: reason`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"writable_zones": schema.ListAttribute{
+				Description: "Names of the hosted zones the credentials can modify.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors encountered while probing, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerRoute53DNSTakeoverProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerRoute53DNSTakeoverProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.CheckDangling.IsNull() {
+		data.CheckDangling = types.BoolValue(true)
+	}
+	if data.CheckWrite.IsNull() {
+		data.CheckWrite = types.BoolValue(true)
+	}
+	if data.DryRun.IsNull() {
+		data.DryRun = types.BoolValue(true)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(5)
+	}
+
+	var zoneIDs []string
+	if !data.HostedZoneIDs.IsNull() {
+		resp.Diagnostics.Append(data.HostedZoneIDs.ElementsAs(ctx, &zoneIDs, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	zoneNames := []string{}
+	dangling := []string{}
+	writable := []string{}
+	var failures []string
+
+	// Route 53 is a global service served from us-east-1
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion("us-east-1"))
+	if err != nil {
+		failures = append(failures, fmt.Sprintf("unable to load AWS configuration: %v", err))
+	} else {
+		client := route53.NewFromConfig(cfg)
+		zones, err := route53Zones(ctx, client, zoneIDs)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+
+		inputs := struct {
+			ZoneIDs                           []string
+			CheckDangling, CheckWrite, DryRun bool
+		}{zoneIDs, data.CheckDangling.ValueBool(), data.CheckWrite.ValueBool(), data.DryRun.ValueBool()}
+		progress := newOperationProgress(ctx, "route53_dns_takeover_probe", len(zones), data.CheckpointFile.ValueString(), inputs)
+
+		for _, zone := range zones {
+			id, name := aws.ToString(zone.Id), aws.ToString(zone.Name)
+			zoneNames = append(zoneNames, name)
+
+			var result route53ZoneResult
+			if !progress.Resume(ctx, id, &result) {
+				result = route53ProbeZone(ctx, client, id, name, data, timeout)
+				if err := progress.Complete(ctx, id, result); err != nil {
+					failures = append(failures, err.Error())
+				}
+			}
+			dangling = append(dangling, result.Dangling...)
+			if result.Writable {
+				writable = append(writable, name)
+			}
+			failures = append(failures, result.Failures...)
+		}
+		if err := progress.Finish(ctx); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	zonesList, diags := types.ListValueFrom(ctx, types.StringType, zoneNames)
+	resp.Diagnostics.Append(diags...)
+	danglingList, diags := types.ListValueFrom(ctx, types.StringType, dangling)
+	resp.Diagnostics.Append(diags...)
+	writableList, diags := types.ListValueFrom(ctx, types.StringType, writable)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Zones = zonesList
+	data.DanglingRecords = danglingList
+	data.WritableZones = writableList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// route53ProbeZone looks for dangling records in a zone and checks whether it can be modified.
+func route53ProbeZone(ctx context.Context, client *route53.Client, zoneID, zoneName string, data TerrapwnerRoute53DNSTakeoverProbeDataSourceModel, timeout time.Duration) route53ZoneResult {
+	result := route53ZoneResult{Dangling: []string{}}
+
+	if data.CheckDangling.ValueBool() {
+		records, err := route53DanglingRecords(ctx, client, zoneID, timeout)
+		if err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("%s: %v", zoneName, err))
+		}
+		result.Dangling = append(result.Dangling, records...)
+	}
+
+	if data.CheckWrite.ValueBool() {
+		canWrite, err := route53ZoneWritable(ctx, client, zoneID, zoneName, data.DryRun.ValueBool())
+		if err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("%s: %v", zoneName, err))
+		}
+		result.Writable = canWrite
+	}
+	return result
+}
+
+// route53Zones returns the requested hosted zones, or all zones visible to the credentials.
+func route53Zones(ctx context.Context, client *route53.Client, zoneIDs []string) ([]route53types.HostedZone, error) {
+	var zones []route53types.HostedZone
+
+	if len(zoneIDs) > 0 {
+		var failures []string
+		for _, id := range zoneIDs {
+			out, err := client.GetHostedZone(ctx, &route53.GetHostedZoneInput{Id: aws.String(id)})
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("get hosted zone %s: %v", id, err))
+				continue
+			}
+			zones = append(zones, *out.HostedZone)
+		}
+		if len(failures) > 0 {
+			return zones, errors.New(strings.Join(failures, "; "))
+		}
+		return zones, nil
+	}
+
+	paginator := route53.NewListHostedZonesPaginator(client, &route53.ListHostedZonesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return zones, fmt.Errorf("list hosted zones: %w", err)
+		}
+		zones = append(zones, page.HostedZones...)
+	}
+	return zones, nil
+}
+
+// route53DanglingRecords returns the CNAME and alias records of a zone whose target
+// no longer exists: the target name does not resolve, or the S3 bucket is gone.
+func route53DanglingRecords(ctx context.Context, client *route53.Client, zoneID string, timeout time.Duration) ([]string, error) {
+	dangling := []string{}
+
+	paginator := route53.NewListResourceRecordSetsPaginator(client, &route53.ListResourceRecordSetsInput{HostedZoneId: aws.String(zoneID)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return dangling, fmt.Errorf("list records: %w", err)
+		}
+
+		for _, record := range page.ResourceRecordSets {
+			name := strings.TrimSuffix(aws.ToString(record.Name), ".")
+
+			var target string
+			switch {
+			case record.AliasTarget != nil:
+				target = aws.ToString(record.AliasTarget.DNSName)
+			case record.Type == route53types.RRTypeCname && len(record.ResourceRecords) > 0:
+				target = aws.ToString(record.ResourceRecords[0].Value)
+			default:
+				continue
+			}
+			target = strings.TrimSuffix(strings.ToLower(target), ".")
+			if !takeoverProne(target) {
+				continue
+			}
+
+			if reason := danglingReason(ctx, name, target, record.AliasTarget != nil, timeout); reason != "" {
+				dangling = append(dangling, fmt.Sprintf("%s %s %s: %s", name, record.Type, target, reason))
+			}
+		}
+	}
+	return dangling, nil
+}
+
+// takeoverProne reports whether target is hosted on a service open to takeover.
+func takeoverProne(target string) bool {
+	for _, suffix := range takeoverProneSuffixes {
+		if strings.HasSuffix(target, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// danglingReason returns why a record pointing at target is dangling, or an empty string.
+// Alias targets are AWS endpoints that always resolve, so only their S3 bucket is checked.
+func danglingReason(ctx context.Context, name, target string, alias bool, timeout time.Duration) string {
+	if !alias {
+		lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if _, err := route53LookupHost(lookupCtx, target); err != nil {
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+				return "target does not resolve"
+			}
+			return ""
+		}
+	}
+
+	if strings.Contains(target, "s3") && strings.HasSuffix(target, ".amazonaws.com") {
+		resp, err := utils.HTTPRequest(ctx, "GET", "http://"+name+"/", nil, nil, timeout)
+		if err == nil && strings.Contains(string(resp.Body), "NoSuchBucket") {
+			return "S3 bucket does not exist"
+		}
+	}
+	return ""
+}
+
+// route53ZoneWritable checks whether the credentials can change the records of a zone.
+// In dry-run mode it deletes a canary record that does not exist: Route 53 checks
+// authorization first, so InvalidChangeBatch means the change would have been accepted.
+// Otherwise a canary TXT record is created and removed.
+func route53ZoneWritable(ctx context.Context, client *route53.Client, zoneID, zoneName string, dryRun bool) (bool, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return false, err
+	}
+	canary := &route53types.ResourceRecordSet{
+		Name:            aws.String("terrapwner-canary-" + hex.EncodeToString(suffix) + "." + zoneName),
+		Type:            route53types.RRTypeTxt,
+		TTL:             aws.Int64(60),
+		ResourceRecords: []route53types.ResourceRecord{{Value: aws.String(`"terrapwner"`)}},
+	}
+	change := func(action route53types.ChangeAction) error {
+		_, err := client.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(zoneID),
+			ChangeBatch: &route53types.ChangeBatch{
+				Comment: aws.String("terrapwner write access check"),
+				Changes: []route53types.Change{{Action: action, ResourceRecordSet: canary}},
+			},
+		})
+		return err
+	}
+
+	if dryRun {
+		err := change(route53types.ChangeActionDelete)
+		var apiErr smithy.APIError
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidChangeBatch":
+			return true, nil
+		case errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied":
+			return false, nil
+		default:
+			return false, fmt.Errorf("write check: %w", err)
+		}
+	}
+
+	if err := change(route53types.ChangeActionCreate); err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied" {
+			return false, nil
+		}
+		return false, fmt.Errorf("write check: %w", err)
+	}
+	if err := change(route53types.ChangeActionDelete); err != nil {
+		return true, fmt.Errorf("failed to remove canary record %s: %w", aws.ToString(canary.Name), err)
+	}
+	return true, nil
+}
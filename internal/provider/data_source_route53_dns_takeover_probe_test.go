@@ -0,0 +1,84 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerRoute53DNSTakeoverProbeDataSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/2013-04-01/hostedzone":
+			fmt.Fprint(w, `<ListHostedZonesResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <HostedZones>
+    <HostedZone><Id>/hostedzone/Z1</Id><Name>example.com.</Name><CallerReference>ref</CallerReference></HostedZone>
+  </HostedZones>
+  <IsTruncated>false</IsTruncated><MaxItems>100</MaxItems>
+</ListHostedZonesResponse>`)
+		case r.Method == http.MethodGet && r.URL.Path == "/2013-04-01/hostedzone/Z1/rrset":
+			fmt.Fprint(w, `<ListResourceRecordSetsResponse xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <ResourceRecordSets>
+    <ResourceRecordSet><Name>old.example.com.</Name><Type>CNAME</Type><TTL>300</TTL>
+      <ResourceRecords><ResourceRecord><Value>released-app.herokuapp.com</Value></ResourceRecord></ResourceRecords></ResourceRecordSet>
+    <ResourceRecordSet><Name>app.example.com.</Name><Type>CNAME</Type><TTL>300</TTL>
+      <ResourceRecords><ResourceRecord><Value>live-app.herokuapp.com</Value></ResourceRecord></ResourceRecords></ResourceRecordSet>
+    <ResourceRecordSet><Name>www.example.com.</Name><Type>CNAME</Type><TTL>300</TTL>
+      <ResourceRecords><ResourceRecord><Value>lb.example.net</Value></ResourceRecord></ResourceRecords></ResourceRecordSet>
+  </ResourceRecordSets>
+  <IsTruncated>false</IsTruncated><MaxItems>300</MaxItems>
+</ListResourceRecordSetsResponse>`)
+		case r.Method == http.MethodPost && strings.TrimSuffix(r.URL.Path, "/") == "/2013-04-01/hostedzone/Z1/rrset":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<InvalidChangeBatch xmlns="https://route53.amazonaws.com/doc/2013-04-01/">
+  <Messages><Message>Tried to delete resource record set but it was not found</Message></Messages>
+  <RequestId>00000000-0000-0000-0000-000000000000</RequestId>
+</InvalidChangeBatch>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	originalLookupHost := route53LookupHost
+	route53LookupHost = func(_ context.Context, host string) ([]string, error) {
+		if strings.HasPrefix(host, "released-app.") {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []string{"192.0.2.1"}, nil
+	}
+	t.Cleanup(func() { route53LookupHost = originalLookupHost })
+
+	testAccSetAWSStaticCredentials(t)
+	t.Setenv("AWS_ENDPOINT_URL_ROUTE_53", server.URL)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_route53_dns_takeover_probe" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_route53_dns_takeover_probe.test", "zones.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_route53_dns_takeover_probe.test", "zones.0", "example.com."),
+					resource.TestCheckResourceAttr("data.terrapwner_route53_dns_takeover_probe.test", "dangling_records.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_route53_dns_takeover_probe.test", "dangling_records.0",
+						"old.example.com CNAME released-app.herokuapp.com: target does not resolve"),
+					resource.TestCheckResourceAttr("data.terrapwner_route53_dns_takeover_probe.test", "writable_zones.0", "example.com."),
+					resource.TestCheckResourceAttr("data.terrapwner_route53_dns_takeover_probe.test", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,313 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerRunnerReuseDetectorDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerRunnerReuseDetectorDataSource{}
+)
+
+const (
+	// runnerReuseMarkerPrefix is the name prefix of the reuse marker files.
+	runnerReuseMarkerPrefix = "terrapwner-reuse-"
+
+	// runnerReuseMarkerSuffix is the name suffix of the reuse marker files.
+	runnerReuseMarkerSuffix = ".json"
+)
+
+// runnerReuseRunID identifies the markers of this provider process, so the
+// marker of a previous read of the same run is not mistaken for a prior run.
+var runnerReuseRunID = sync.OnceValue(func() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+})
+
+// runnerReuseMarker is the JSON document left on the runner by each run.
+type runnerReuseMarker struct {
+	RunID      string    `json:"run_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Host       string    `json:"host"`
+	Platform   string    `json:"platform"`
+	CIRunID    string    `json:"ci_run_id"`
+	JobID      string    `json:"job_id"`
+	JobName    string    `json:"job_name"`
+	Repository string    `json:"repository"`
+}
+
+// sameJob reports whether two markers were left by the same CI job, e.g. by
+// the plan and apply commands of one job.
+func (m runnerReuseMarker) sameJob(other runnerReuseMarker) bool {
+	if m.Platform == "" || m.CIRunID == "" {
+		return false
+	}
+	return m.Platform == other.Platform && m.CIRunID == other.CIRunID && m.JobID == other.JobID && m.JobName == other.JobName
+}
+
+// NewTerrapwnerRunnerReuseDetectorDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerRunnerReuseDetectorDataSource() datasource.DataSource {
+	return &TerrapwnerRunnerReuseDetectorDataSource{}
+}
+
+// TerrapwnerRunnerReuseDetectorDataSource is the data source implementation.
+type TerrapwnerRunnerReuseDetectorDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerRunnerReuseDetectorDataSourceModel describes the data source data model.
+type TerrapwnerRunnerReuseDetectorDataSourceModel struct {
+	MarkerDir   types.String `tfsdk:"marker_dir"`
+	Cleanup     types.Bool   `tfsdk:"cleanup"`
+	RunID       types.String `tfsdk:"run_id"`
+	MarkerPath  types.String `tfsdk:"marker_path"`
+	MarkerKept  types.Bool   `tfsdk:"marker_kept"`
+	PriorRuns   types.List   `tfsdk:"prior_runs"`
+	Reused      types.Bool   `tfsdk:"reused"`
+	CrossTenant types.Bool   `tfsdk:"cross_tenant"`
+	FailReason  types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerRunnerReuseDetectorDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerRunnerReuseDetectorDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_runner_reuse_detector"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerRunnerReuseDetectorDataSource) Tags() []string {
+	return []string{"ci", "write"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerRunnerReuseDetectorDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Detects whether a supposedly ephemeral runner is reused across jobs or tenants. Each run leaves a marker " +
+			"file identifying its CI job and repository, and reports the markers left by prior runs: any of them means state " +
+			"(credentials, caches, planted binaries) can leak from one job to the next. Markers of the same CI job, e.g. left by " +
+			"its plan and apply commands, are not counted; outside CI, every earlier terraform command counts as a prior run.",
+		Attributes: map[string]schema.Attribute{
+			"marker_dir": schema.StringAttribute{
+				Description: "Directory holding the markers, which must survive between jobs on a reused runner " +
+					"(default: the system temporary directory).",
+				Optional: true,
+			},
+			"cleanup": schema.BoolAttribute{
+				Description: "Whether to remove the marker of this run and the markers of prior runs once checked, leaving no " +
+					"trace on the runner. The next run then only detects the reuse if another run kept its marker (default: false).",
+				Optional: true,
+			},
+			"run_id": schema.StringAttribute{
+				Description: "Identifier of this run, recorded in its marker.",
+				Computed:    true,
+			},
+			"marker_path": schema.StringAttribute{
+				Description: "Path of the marker of this run.",
+				Computed:    true,
+			},
+			"marker_kept": schema.BoolAttribute{
+				Description: "Whether the marker of this run was left for the next runs to find.",
+				Computed:    true,
+			},
+			"prior_runs": schema.ListAttribute{
+				Description: "Prior runs that left a marker, oldest first, formatted as `created_at platform run_id/job repository` " +
+					"with `-` for unknown fields.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"reused": schema.BoolAttribute{
+				Description: "True if a prior run left a marker, i.e. the runner is not ephemeral.",
+				Computed:    true,
+			},
+			"cross_tenant": schema.BoolAttribute{
+				Description: "True if a prior run built another repository, i.e. the runner is shared across tenants.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Markers that could not be read, written or removed, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read leaves the marker, checks for prior ones and updates the state.
+func (d *TerrapwnerRunnerReuseDetectorDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerRunnerReuseDetectorDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.MarkerDir.IsNull() {
+		data.MarkerDir = types.StringValue(os.TempDir())
+	}
+	if data.Cleanup.IsNull() {
+		data.Cleanup = types.BoolValue(false)
+	}
+
+	dir := data.MarkerDir.ValueString()
+	current := d.currentMarker()
+	markerPath := filepath.Join(dir, runnerReuseMarkerPrefix+current.RunID+runnerReuseMarkerSuffix)
+	data.RunID = types.StringValue(current.RunID)
+	data.MarkerPath = types.StringValue(markerPath)
+
+	var failures []string
+
+	// Check for the markers of prior runs before leaving ours
+	priorMarkers, priorPaths, err := readRunnerReuseMarkers(dir, current)
+	if err != nil {
+		failures = append(failures, fmt.Sprintf("read markers: %v", err))
+	}
+	priorRuns := []string{}
+	crossTenant := false
+	for _, marker := range priorMarkers {
+		priorRuns = append(priorRuns, formatRunnerReuseMarker(marker))
+		if marker.Repository != "" && current.Repository != "" && marker.Repository != current.Repository {
+			crossTenant = true
+		}
+	}
+	data.Reused = types.BoolValue(len(priorMarkers) > 0)
+	data.CrossTenant = types.BoolValue(crossTenant)
+
+	kept := false
+	if err := writeRunnerReuseMarker(markerPath, current); err != nil {
+		failures = append(failures, fmt.Sprintf("write marker: %v", err))
+	} else {
+		kept = true
+	}
+
+	// Remove every marker found, ours included, when cleaning up
+	if data.Cleanup.ValueBool() {
+		for _, path := range append(priorPaths, markerPath) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				failures = append(failures, fmt.Sprintf("remove marker: %v", err))
+				continue
+			}
+			if path == markerPath {
+				kept = false
+			}
+		}
+	}
+	data.MarkerKept = types.BoolValue(kept)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	priorRunsList, diags := types.ListValueFrom(ctx, types.StringType, priorRuns)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.PriorRuns = priorRunsList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// currentMarker returns the marker describing this run.
+func (d *TerrapwnerRunnerReuseDetectorDataSource) currentMarker() runnerReuseMarker {
+	platform := d.snapshot.CIPlatform()
+	var info ciRunInfo
+	if extract, ok := ciRunInfoExtractors[platform]; ok {
+		info = extract(d.snapshot.Getenv)
+	}
+	host, _ := os.Hostname()
+	return runnerReuseMarker{
+		RunID:      runnerReuseRunID(),
+		CreatedAt:  time.Now().UTC(),
+		Host:       host,
+		Platform:   platform,
+		CIRunID:    info.RunID,
+		JobID:      info.JobID,
+		JobName:    info.JobName,
+		Repository: info.Repository,
+	}
+}
+
+// readRunnerReuseMarkers returns the markers in dir left by prior runs, oldest
+// first, and their paths. Unreadable markers still count as prior runs.
+func readRunnerReuseMarkers(dir string, current runnerReuseMarker) ([]runnerReuseMarker, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var markers []runnerReuseMarker
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, runnerReuseMarkerPrefix) || !strings.HasSuffix(name, runnerReuseMarkerSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		marker := runnerReuseMarker{RunID: strings.TrimSuffix(strings.TrimPrefix(name, runnerReuseMarkerPrefix), runnerReuseMarkerSuffix)}
+		if b, err := os.ReadFile(path); err == nil {
+			_ = json.Unmarshal(b, &marker)
+		}
+		if marker.RunID == current.RunID || current.sameJob(marker) {
+			continue
+		}
+		if marker.CreatedAt.IsZero() {
+			if info, err := entry.Info(); err == nil {
+				marker.CreatedAt = info.ModTime().UTC()
+			}
+		}
+		markers = append(markers, marker)
+		paths = append(paths, path)
+	}
+
+	sort.SliceStable(markers, func(i, j int) bool {
+		return markers[i].CreatedAt.Before(markers[j].CreatedAt)
+	})
+	return markers, paths, nil
+}
+
+// writeRunnerReuseMarker writes the marker of this run, readable by the runs of
+// other users sharing the runner.
+func writeRunnerReuseMarker(path string, marker runnerReuseMarker) error {
+	b, err := json.Marshal(marker)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// formatRunnerReuseMarker describes a prior run for the prior_runs attribute.
+func formatRunnerReuseMarker(marker runnerReuseMarker) string {
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	job := orDash(marker.CIRunID) + "/" + orDash(firstNonEmpty(marker.JobID, marker.JobName))
+	return fmt.Sprintf("%s %s %s %s", marker.CreatedAt.Format(time.RFC3339), orDash(marker.Platform), job, orDash(marker.Repository))
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccTerrapwnerRunnerReuseDetectorDataSource(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_RUN_ID", "1234")
+	t.Setenv("GITHUB_JOB", "plan")
+	t.Setenv("GITHUB_REPOSITORY", "acme/infra")
+
+	dir := t.TempDir()
+	writeMarker := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	markers := func(want int) resource.TestCheckFunc {
+		return func(_ *terraform.State) error {
+			matches, _ := filepath.Glob(filepath.Join(dir, "terrapwner-reuse-*.json"))
+			if len(matches) != want {
+				return fmt.Errorf("found %d markers, want %d: %v", len(matches), want, matches)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// First run on the runner, a marker of the same job from its plan command
+			{
+				PreConfig: func() {
+					writeMarker("terrapwner-reuse-0000000000000001.json",
+						`{"run_id":"0000000000000001","platform":"github_actions","ci_run_id":"1234","job_name":"plan","repository":"acme/infra"}`)
+				},
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_runner_reuse_detector" "test" {
+  marker_dir = %q
+}
+`, dir),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("data.terrapwner_runner_reuse_detector.test", "run_id", regexp.MustCompile(`^[0-9a-f]{16}$`)),
+					resource.TestMatchResourceAttr("data.terrapwner_runner_reuse_detector.test", "marker_path", regexp.MustCompile(`terrapwner-reuse-[0-9a-f]{16}\.json$`)),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "marker_kept", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "prior_runs.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "reused", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "cross_tenant", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "fail_reason", ""),
+					markers(2),
+				),
+			},
+			// Markers left by jobs of this and another repository
+			{
+				PreConfig: func() {
+					writeMarker("terrapwner-reuse-0000000000000002.json",
+						`{"run_id":"0000000000000002","created_at":"2024-05-01T10:00:00Z","platform":"github_actions","ci_run_id":"1000","job_name":"deploy","repository":"acme/infra"}`)
+					writeMarker("terrapwner-reuse-0000000000000003.json",
+						`{"run_id":"0000000000000003","created_at":"2024-05-02T10:00:00Z","platform":"github_actions","ci_run_id":"1001","job_name":"build","repository":"other/app"}`)
+				},
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_runner_reuse_detector" "test" {
+  marker_dir = %q
+}
+`, dir),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "prior_runs.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "prior_runs.0", "2024-05-01T10:00:00Z github_actions 1000/deploy acme/infra"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "prior_runs.1", "2024-05-02T10:00:00Z github_actions 1001/build other/app"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "reused", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "cross_tenant", "true"),
+				),
+			},
+			// Every marker removed
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_runner_reuse_detector" "test" {
+  marker_dir = %q
+  cleanup    = true
+}
+`, dir),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "reused", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "marker_kept", "false"),
+					markers(1),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_runner_reuse_detector" "test" {
+  marker_dir = %q
+}
+`, filepath.Join(dir, "missing")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "reused", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_runner_reuse_detector.test", "marker_kept", "false"),
+					resource.TestMatchResourceAttr("data.terrapwner_runner_reuse_detector.test", "fail_reason", regexp.MustCompile(`^read markers: .*; write marker: `)),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,443 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerSnapshotProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerSnapshotProbeDataSource{}
+)
+
+const (
+	// snapshotActionAllowed means the credentials may perform the action.
+	snapshotActionAllowed = "allowed"
+	// snapshotActionDenied means the action was rejected by authorization.
+	snapshotActionDenied = "denied"
+	// snapshotActionUnknown means the action could not be checked.
+	snapshotActionUnknown = "unknown"
+
+	// defaultSnapshotTargetAccount is the example account of the AWS documentation.
+	defaultSnapshotTargetAccount = "111122223333"
+)
+
+// snapshotServices lists the snapshot kinds that can be probed.
+var snapshotServices = []string{"ebs", "ami", "rds"}
+
+// awsAccountIDPattern matches 12-digit AWS account IDs.
+var awsAccountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// snapshotExfilPaths maps the exfiltration paths to the create and share
+// actions they require.
+var snapshotExfilPaths = []struct {
+	path   string
+	create string
+	share  string
+}{
+	{"ebs", "ec2:CreateSnapshot", "ec2:ModifySnapshotAttribute"},
+	{"ami", "ec2:CreateImage", "ec2:ModifyImageAttribute"},
+	{"rds", "rds:CreateDBSnapshot", "rds:ModifyDBSnapshotAttribute"},
+	{"aurora", "rds:CreateDBClusterSnapshot", "rds:ModifyDBClusterSnapshotAttribute"},
+}
+
+// NewTerrapwnerSnapshotProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerSnapshotProbeDataSource() datasource.DataSource {
+	return &TerrapwnerSnapshotProbeDataSource{}
+}
+
+// TerrapwnerSnapshotProbeDataSource is the data source implementation.
+type TerrapwnerSnapshotProbeDataSource struct{}
+
+// TerrapwnerSnapshotProbeDataSourceModel describes the data source data model.
+type TerrapwnerSnapshotProbeDataSourceModel struct {
+	Region                    types.String `tfsdk:"region"`
+	TargetAccountID           types.String `tfsdk:"target_account_id"`
+	Services                  types.List   `tfsdk:"services"`
+	VolumeID                  types.String `tfsdk:"volume_id"`
+	SnapshotID                types.String `tfsdk:"snapshot_id"`
+	InstanceID                types.String `tfsdk:"instance_id"`
+	ImageID                   types.String `tfsdk:"image_id"`
+	Actions                   types.Map    `tfsdk:"actions"`
+	Resources                 types.Map    `tfsdk:"resources"`
+	SnapshotBlockPublicAccess types.String `tfsdk:"snapshot_block_public_access"`
+	ImageBlockPublicAccess    types.String `tfsdk:"image_block_public_access"`
+	ExfilPaths                types.List   `tfsdk:"exfil_paths"`
+	ExposureFound             types.Bool   `tfsdk:"exposure_found"`
+	FailReason                types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerSnapshotProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerSnapshotProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_snapshot_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerSnapshotProbeDataSource) Tags() []string {
+	return []string{categoryCloud, categoryExfil, "aws"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerSnapshotProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Uses the ambient AWS credentials to check whether EBS snapshots, machine images (AMIs) and RDS or Aurora " +
+			"snapshots can be created and shared with an external account, a high-impact exfiltration path that bypasses network " +
+			"egress controls. Nothing is created or shared: EC2 actions are sent as dry runs, and RDS actions, which have no dry " +
+			"run, target identifiers that do not exist, which RDS rejects only after authorization.",
+		Attributes: map[string]schema.Attribute{
+			"region": schema.StringAttribute{
+				Description: "AWS region to probe (default: the region of the AWS configuration, or us-east-1).",
+				Optional:    true,
+			},
+			"target_account_id": schema.StringAttribute{
+				Description: fmt.Sprintf("External account the snapshots and images would be shared with (default: %s).", defaultSnapshotTargetAccount),
+				Optional:    true,
+			},
+			"services": schema.ListAttribute{
+				Description: fmt.Sprintf("Snapshot kinds to probe: %s (default: all).", strings.Join(snapshotServices, ", ")),
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"volume_id": schema.StringAttribute{
+				Description: "EBS volume to check snapshot creation on (default: the first volume visible to the credentials).",
+				Optional:    true,
+			},
+			"snapshot_id": schema.StringAttribute{
+				Description: "EBS snapshot to check sharing on (default: the first snapshot owned by the account).",
+				Optional:    true,
+			},
+			"instance_id": schema.StringAttribute{
+				Description: "EC2 instance to check image creation on (default: the first instance visible to the credentials).",
+				Optional:    true,
+			},
+			"image_id": schema.StringAttribute{
+				Description: "AMI to check sharing on (default: the first image owned by the account).",
+				Optional:    true,
+			},
+			"actions": schema.MapAttribute{
+				Description: "Result of each action checked, by IAM action name (e.g. `ec2:CreateSnapshot`): " +
+					"allowed, denied or unknown if it could not be checked.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"resources": schema.MapAttribute{
+				Description: "Resource each action was checked on, by IAM action name.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"snapshot_block_public_access": schema.StringAttribute{
+				Description: "Block public access state of EBS snapshots in the region: block-all-sharing, block-new-sharing " +
+					"or unblocked. Empty if it could not be read.",
+				Computed: true,
+			},
+			"image_block_public_access": schema.StringAttribute{
+				Description: "Block public access state of AMIs in the region: block-new-sharing or unblocked. Empty if it could not be read.",
+				Computed:    true,
+			},
+			"exfil_paths": schema.ListAttribute{
+				Description: "Snapshot kinds that can be both created and shared: ebs, ami, rds or aurora.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"exposure_found": schema.BoolAttribute{
+				Description: "True if any exfiltration path is open.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Actions or states that could not be checked, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerSnapshotProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerSnapshotProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.TargetAccountID.IsNull() {
+		data.TargetAccountID = types.StringValue(defaultSnapshotTargetAccount)
+	}
+	if !awsAccountIDPattern.MatchString(data.TargetAccountID.ValueString()) {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("target_account_id must be a 12-digit AWS account ID, got: %s", data.TargetAccountID.ValueString()),
+		)
+		return
+	}
+	services := snapshotServices
+	if !data.Services.IsNull() {
+		services = nil
+		resp.Diagnostics.Append(data.Services.ElementsAs(ctx, &services, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		for _, service := range services {
+			if !slices.Contains(snapshotServices, service) {
+				resp.Diagnostics.AddError(
+					"Invalid configuration",
+					fmt.Sprintf("services must be among %s, got: %s", strings.Join(snapshotServices, ", "), service),
+				)
+				return
+			}
+		}
+	}
+
+	actions := map[string]string{}
+	resources := map[string]string{}
+	data.SnapshotBlockPublicAccess = types.StringValue("")
+	data.ImageBlockPublicAccess = types.StringValue("")
+	var failures []string
+	record := func(action, resource string, err error) {
+		resources[action] = resource
+		result, err := snapshotActionResult(err)
+		actions[action] = result
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", action, err))
+		}
+	}
+	// unchecked records an action that had no resource to be checked on
+	unchecked := func(action, kind, attribute string, err error) {
+		resources[action] = ""
+		actions[action] = snapshotActionUnknown
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: unable to list a %s, set %s: %v", action, kind, attribute, err))
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: no %s found, set %s", action, kind, attribute))
+		}
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(data.Region.ValueString()))
+	if err != nil {
+		failures = append(failures, fmt.Sprintf("unable to load AWS configuration: %v", err))
+	} else {
+		if cfg.Region == "" {
+			cfg.Region = "us-east-1"
+		}
+		data.Region = types.StringValue(cfg.Region)
+		ec2Client := ec2.NewFromConfig(cfg)
+		rdsClient := rds.NewFromConfig(cfg)
+		target := data.TargetAccountID.ValueString()
+		name := "terrapwner-probe-" + snapshotProbeSuffix()
+
+		if slices.Contains(services, "ebs") {
+			if out, err := ec2Client.GetSnapshotBlockPublicAccessState(ctx, &ec2.GetSnapshotBlockPublicAccessStateInput{}); err != nil {
+				failures = append(failures, fmt.Sprintf("snapshot block public access: %v", err))
+			} else {
+				data.SnapshotBlockPublicAccess = types.StringValue(string(out.State))
+			}
+
+			volumeID, err := ec2FirstResource(data.VolumeID, func() (string, error) {
+				out, err := ec2Client.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{MaxResults: aws.Int32(5)})
+				if err != nil || len(out.Volumes) == 0 {
+					return "", err
+				}
+				return aws.ToString(out.Volumes[0].VolumeId), nil
+			})
+			if volumeID == "" {
+				unchecked("ec2:CreateSnapshot", "volume", "volume_id", err)
+			} else {
+				_, err := ec2Client.CreateSnapshot(ctx, &ec2.CreateSnapshotInput{
+					VolumeId:    aws.String(volumeID),
+					Description: aws.String(name),
+					DryRun:      aws.Bool(true),
+				})
+				record("ec2:CreateSnapshot", volumeID, err)
+			}
+
+			snapshotID, err := ec2FirstResource(data.SnapshotID, func() (string, error) {
+				out, err := ec2Client.DescribeSnapshots(ctx, &ec2.DescribeSnapshotsInput{OwnerIds: []string{"self"}, MaxResults: aws.Int32(5)})
+				if err != nil || len(out.Snapshots) == 0 {
+					return "", err
+				}
+				return aws.ToString(out.Snapshots[0].SnapshotId), nil
+			})
+			if snapshotID == "" {
+				unchecked("ec2:ModifySnapshotAttribute", "snapshot", "snapshot_id", err)
+			} else {
+				_, err := ec2Client.ModifySnapshotAttribute(ctx, &ec2.ModifySnapshotAttributeInput{
+					SnapshotId:    aws.String(snapshotID),
+					Attribute:     ec2types.SnapshotAttributeNameCreateVolumePermission,
+					OperationType: ec2types.OperationTypeAdd,
+					UserIds:       []string{target},
+					DryRun:        aws.Bool(true),
+				})
+				record("ec2:ModifySnapshotAttribute", snapshotID, err)
+			}
+		}
+
+		if slices.Contains(services, "ami") {
+			if out, err := ec2Client.GetImageBlockPublicAccessState(ctx, &ec2.GetImageBlockPublicAccessStateInput{}); err != nil {
+				failures = append(failures, fmt.Sprintf("image block public access: %v", err))
+			} else {
+				data.ImageBlockPublicAccess = types.StringValue(aws.ToString(out.ImageBlockPublicAccessState))
+			}
+
+			instanceID, err := ec2FirstResource(data.InstanceID, func() (string, error) {
+				out, err := ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{MaxResults: aws.Int32(5)})
+				if err != nil {
+					return "", err
+				}
+				for _, reservation := range out.Reservations {
+					if len(reservation.Instances) > 0 {
+						return aws.ToString(reservation.Instances[0].InstanceId), nil
+					}
+				}
+				return "", nil
+			})
+			if instanceID == "" {
+				unchecked("ec2:CreateImage", "instance", "instance_id", err)
+			} else {
+				_, err := ec2Client.CreateImage(ctx, &ec2.CreateImageInput{
+					InstanceId: aws.String(instanceID),
+					Name:       aws.String(name),
+					NoReboot:   aws.Bool(true),
+					DryRun:     aws.Bool(true),
+				})
+				record("ec2:CreateImage", instanceID, err)
+			}
+
+			imageID, err := ec2FirstResource(data.ImageID, func() (string, error) {
+				out, err := ec2Client.DescribeImages(ctx, &ec2.DescribeImagesInput{Owners: []string{"self"}, MaxResults: aws.Int32(5)})
+				if err != nil || len(out.Images) == 0 {
+					return "", err
+				}
+				return aws.ToString(out.Images[0].ImageId), nil
+			})
+			if imageID == "" {
+				unchecked("ec2:ModifyImageAttribute", "image", "image_id", err)
+			} else {
+				_, err := ec2Client.ModifyImageAttribute(ctx, &ec2.ModifyImageAttributeInput{
+					ImageId: aws.String(imageID),
+					LaunchPermission: &ec2types.LaunchPermissionModifications{
+						Add: []ec2types.LaunchPermission{{UserId: aws.String(target)}},
+					},
+					DryRun: aws.Bool(true),
+				})
+				record("ec2:ModifyImageAttribute", imageID, err)
+			}
+		}
+
+		if slices.Contains(services, "rds") {
+			// The identifiers do not exist, so nothing can be created or shared
+			_, err := rdsClient.CreateDBSnapshot(ctx, &rds.CreateDBSnapshotInput{
+				DBInstanceIdentifier: aws.String(name),
+				DBSnapshotIdentifier: aws.String(name),
+			})
+			record("rds:CreateDBSnapshot", name, err)
+			_, err = rdsClient.ModifyDBSnapshotAttribute(ctx, &rds.ModifyDBSnapshotAttributeInput{
+				DBSnapshotIdentifier: aws.String(name),
+				AttributeName:        aws.String("restore"),
+				ValuesToAdd:          []string{target},
+			})
+			record("rds:ModifyDBSnapshotAttribute", name, err)
+			_, err = rdsClient.CreateDBClusterSnapshot(ctx, &rds.CreateDBClusterSnapshotInput{
+				DBClusterIdentifier:         aws.String(name),
+				DBClusterSnapshotIdentifier: aws.String(name),
+			})
+			record("rds:CreateDBClusterSnapshot", name, err)
+			_, err = rdsClient.ModifyDBClusterSnapshotAttribute(ctx, &rds.ModifyDBClusterSnapshotAttributeInput{
+				DBClusterSnapshotIdentifier: aws.String(name),
+				AttributeName:               aws.String("restore"),
+				ValuesToAdd:                 []string{target},
+			})
+			record("rds:ModifyDBClusterSnapshotAttribute", name, err)
+		}
+	}
+
+	exfilPaths := []string{}
+	for _, path := range snapshotExfilPaths {
+		if actions[path.create] == snapshotActionAllowed && actions[path.share] == snapshotActionAllowed {
+			exfilPaths = append(exfilPaths, path.path)
+		}
+	}
+	data.ExposureFound = types.BoolValue(len(exfilPaths) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	actionsMap, diags := types.MapValueFrom(ctx, types.StringType, actions)
+	resp.Diagnostics.Append(diags...)
+	resourcesMap, diags := types.MapValueFrom(ctx, types.StringType, resources)
+	resp.Diagnostics.Append(diags...)
+	exfilPathsList, diags := types.ListValueFrom(ctx, types.StringType, exfilPaths)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Actions = actionsMap
+	data.Resources = resourcesMap
+	data.ExfilPaths = exfilPathsList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// snapshotActionResult classifies the error of a dry run or of an action on an
+// identifier that does not exist. Dry runs fail with DryRunOperation when
+// authorized, and RDS reports missing resources only after authorization, so
+// both mean the action is allowed. The error is returned if the action could
+// not be checked.
+func snapshotActionResult(err error) (string, error) {
+	var apiErr smithy.APIError
+	switch {
+	case err == nil:
+		return snapshotActionAllowed, nil
+	case !errors.As(err, &apiErr):
+		return snapshotActionUnknown, err
+	case apiErr.ErrorCode() == "DryRunOperation":
+		return snapshotActionAllowed, nil
+	case apiErr.ErrorCode() == "UnauthorizedOperation", strings.HasPrefix(apiErr.ErrorCode(), "AccessDenied"):
+		return snapshotActionDenied, nil
+	case strings.HasPrefix(apiErr.ErrorCode(), "DB") && strings.Contains(apiErr.ErrorCode(), "NotFound"):
+		return snapshotActionAllowed, nil
+	default:
+		return snapshotActionUnknown, err
+	}
+}
+
+// ec2FirstResource returns the configured resource ID, or else the first one listed.
+func ec2FirstResource(configured types.String, list func() (string, error)) (string, error) {
+	if !configured.IsNull() && configured.ValueString() != "" {
+		return configured.ValueString(), nil
+	}
+	return list()
+}
+
+// snapshotProbeSuffix returns a random suffix, so probe identifiers never match existing resources.
+func snapshotProbeSuffix() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,145 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerSnapshotProbeDataSource(t *testing.T) {
+	ec2Error := func(w http.ResponseWriter, status int, code string) {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `<Response><Errors><Error><Code>%s</Code><Message>%s</Message></Error></Errors><RequestID>req</RequestID></Response>`, code, code)
+	}
+	rdsError := func(w http.ResponseWriter, status int, code string) {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `<ErrorResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/">
+  <Error><Type>Sender</Type><Code>%s</Code><Message>%s</Message></Error><RequestId>req</RequestId>
+</ErrorResponse>`, code, code)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "text/xml")
+		action := r.Form.Get("Action")
+
+		// Nothing may be created or shared: EC2 changes must be dry runs
+		if strings.HasPrefix(action, "Create") || strings.HasPrefix(action, "Modify") {
+			if !strings.HasPrefix(action, "CreateDB") && !strings.HasPrefix(action, "ModifyDB") && r.Form.Get("DryRun") != "true" {
+				ec2Error(w, http.StatusInternalServerError, "NotADryRun")
+				return
+			}
+			if strings.HasPrefix(action, "Modify") && !strings.Contains(r.Form.Encode(), "111122223333") {
+				ec2Error(w, http.StatusInternalServerError, "MissingTargetAccount")
+				return
+			}
+		}
+
+		switch action {
+		// EC2 (query protocol)
+		case "GetSnapshotBlockPublicAccessState":
+			fmt.Fprint(w, `<GetSnapshotBlockPublicAccessStateResponse><state>unblocked</state></GetSnapshotBlockPublicAccessStateResponse>`)
+		case "GetImageBlockPublicAccessState":
+			fmt.Fprint(w, `<GetImageBlockPublicAccessStateResponse><imageBlockPublicAccessState>block-new-sharing</imageBlockPublicAccessState></GetImageBlockPublicAccessStateResponse>`)
+		case "DescribeVolumes":
+			fmt.Fprint(w, `<DescribeVolumesResponse><volumeSet><item><volumeId>vol-1</volumeId></item></volumeSet></DescribeVolumesResponse>`)
+		case "DescribeSnapshots":
+			fmt.Fprint(w, `<DescribeSnapshotsResponse><snapshotSet/></DescribeSnapshotsResponse>`)
+		case "DescribeInstances":
+			fmt.Fprint(w, `<DescribeInstancesResponse><reservationSet><item><instancesSet><item><instanceId>i-1</instanceId></item></instancesSet></item></reservationSet></DescribeInstancesResponse>`)
+		case "DescribeImages":
+			fmt.Fprint(w, `<DescribeImagesResponse><imagesSet><item><imageId>ami-1</imageId></item></imagesSet></DescribeImagesResponse>`)
+		case "CreateSnapshot", "ModifySnapshotAttribute", "ModifyImageAttribute":
+			ec2Error(w, http.StatusPreconditionFailed, "DryRunOperation")
+		case "CreateImage":
+			ec2Error(w, http.StatusForbidden, "UnauthorizedOperation")
+		// RDS (query protocol)
+		case "CreateDBSnapshot":
+			rdsError(w, http.StatusNotFound, "DBInstanceNotFound")
+		case "ModifyDBSnapshotAttribute":
+			rdsError(w, http.StatusNotFound, "DBSnapshotNotFound")
+		case "CreateDBClusterSnapshot":
+			rdsError(w, http.StatusForbidden, "AccessDenied")
+		case "ModifyDBClusterSnapshotAttribute":
+			rdsError(w, http.StatusNotFound, "DBClusterSnapshotNotFoundFault")
+		default:
+			ec2Error(w, http.StatusBadRequest, "InvalidAction")
+		}
+	}))
+	defer server.Close()
+
+	testAccSetAWSStaticCredentials(t)
+	t.Setenv("AWS_ENDPOINT_URL_EC2", server.URL)
+	t.Setenv("AWS_ENDPOINT_URL_RDS", server.URL)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_snapshot_probe" "test" {
+  region = "eu-west-1"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "actions.ec2:CreateSnapshot", "allowed"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "resources.ec2:CreateSnapshot", "vol-1"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "actions.ec2:ModifySnapshotAttribute", "unknown"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "actions.ec2:CreateImage", "denied"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "actions.ec2:ModifyImageAttribute", "allowed"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "resources.ec2:ModifyImageAttribute", "ami-1"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "actions.rds:CreateDBSnapshot", "allowed"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "actions.rds:ModifyDBSnapshotAttribute", "allowed"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "actions.rds:CreateDBClusterSnapshot", "denied"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "snapshot_block_public_access", "unblocked"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "image_block_public_access", "block-new-sharing"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "exfil_paths.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "exfil_paths.0", "rds"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "exposure_found", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "fail_reason",
+						"ec2:ModifySnapshotAttribute: no snapshot found, set snapshot_id"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_snapshot_probe" "test" {
+  region      = "eu-west-1"
+  services    = ["ebs"]
+  snapshot_id = "snap-1"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "actions.%", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "actions.ec2:ModifySnapshotAttribute", "allowed"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "resources.ec2:ModifySnapshotAttribute", "snap-1"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "image_block_public_access", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "exfil_paths.0", "ebs"),
+					resource.TestCheckResourceAttr("data.terrapwner_snapshot_probe.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_snapshot_probe" "test" {
+  target_account_id = "external"
+}
+`,
+				ExpectError: regexp.MustCompile(`target_account_id must be a 12-digit AWS account ID`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_snapshot_probe" "test" {
+  services = ["gce"]
+}
+`,
+				ExpectError: regexp.MustCompile(`services must be among ebs, ami, rds, got: gce`),
+			},
+		},
+	})
+}
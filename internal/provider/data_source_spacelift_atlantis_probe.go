@@ -0,0 +1,369 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// tacosMarkers maps each TACOS platform to the environment variables that identify it.
+var tacosMarkers = map[string][]string{
+	"spacelift": {"SPACELIFT_API_ENDPOINT", "TF_VAR_spacelift_run_id", "TF_VAR_spacelift_stack_id"},
+	"atlantis":  {"ATLANTIS_TERRAFORM_VERSION", "BASE_REPO_NAME", "PULL_NUM", "REPO_REL_DIR"},
+	"env0":      {"ENV0_ENVIRONMENT_ID", "ENV0_PROJECT_ID", "ENV0_DEPLOYMENT_LOG_ID"},
+}
+
+// tacosTokenVariables lists environment variables holding platform or VCS credentials.
+var tacosTokenVariables = []string{
+	"SPACELIFT_API_TOKEN",
+	"SPACELIFT_API_KEY_SECRET",
+	"ATLANTIS_GH_TOKEN",
+	"ATLANTIS_GH_APP_KEY",
+	"ATLANTIS_GH_WEBHOOK_SECRET",
+	"ATLANTIS_GITLAB_TOKEN",
+	"ATLANTIS_BITBUCKET_TOKEN",
+	"ATLANTIS_AZUREDEVOPS_TOKEN",
+	"ATLANTIS_API_SECRET",
+	"ENV0_API_KEY",
+	"ENV0_API_SECRET",
+}
+
+// tacosMountedPaths lists directories mounted into runs (workspaces, policies, server data).
+var tacosMountedPaths = []string{
+	"/mnt/workspace",
+	"/mnt/workspace/.spacelift",
+	"/atlantis-data",
+	"/home/atlantis/.atlantis",
+	"/opt/env0",
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerSpaceliftAtlantisProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerSpaceliftAtlantisProbeDataSource{}
+)
+
+// NewTerrapwnerSpaceliftAtlantisProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerSpaceliftAtlantisProbeDataSource() datasource.DataSource {
+	return &TerrapwnerSpaceliftAtlantisProbeDataSource{}
+}
+
+// TerrapwnerSpaceliftAtlantisProbeDataSource is the data source implementation.
+type TerrapwnerSpaceliftAtlantisProbeDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerSpaceliftAtlantisProbeDataSourceModel describes the data source data model.
+type TerrapwnerSpaceliftAtlantisProbeDataSourceModel struct {
+	SpaceliftAPIEndpoint types.String `tfsdk:"spacelift_api_endpoint"`
+	GitHubAPIURL         types.String `tfsdk:"github_api_url"`
+	Env0APIURL           types.String `tfsdk:"env0_api_url"`
+	Timeout              types.Int64  `tfsdk:"timeout"`
+	Platform             types.String `tfsdk:"platform"`
+	DetectedPlatforms    types.List   `tfsdk:"detected_platforms"`
+	TokensPresent        types.List   `tfsdk:"tokens_present"`
+	MountedPaths         types.List   `tfsdk:"mounted_paths"`
+	AccessibleResources  types.List   `tfsdk:"accessible_resources"`
+	FailReason           types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerSpaceliftAtlantisProbeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerSpaceliftAtlantisProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_spacelift_atlantis_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerSpaceliftAtlantisProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, "ci"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerSpaceliftAtlantisProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Detects Spacelift, Atlantis and env0 runners (environment markers, API tokens and mounted paths) " +
+			"and enumerates what the run or VCS tokens found in the environment can access.",
+		Attributes: map[string]schema.Attribute{
+			"spacelift_api_endpoint": schema.StringAttribute{
+				Description: "Spacelift API endpoint. Defaults to the SPACELIFT_API_ENDPOINT environment variable.",
+				Optional:    true,
+			},
+			"github_api_url": schema.StringAttribute{
+				Description: "GitHub API URL used to check the Atlantis VCS token (default: https://api.github.com).",
+				Optional:    true,
+			},
+			"env0_api_url": schema.StringAttribute{
+				Description: "env0 API URL used to check env0 API keys (default: https://api.env0.com).",
+				Optional:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each API request (default: 10).",
+				Optional:    true,
+			},
+			"platform": schema.StringAttribute{
+				Description: "Primary TACOS platform detected (spacelift, atlantis, env0), empty if none.",
+				Computed:    true,
+			},
+			"detected_platforms": schema.ListAttribute{
+				Description: "All TACOS platforms whose markers were found.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"tokens_present": schema.ListAttribute{
+				Description: "Names of environment variables holding platform or VCS credentials (values are never exposed).",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"mounted_paths": schema.ListAttribute{
+				Description: "Platform workspace, policy and data directories readable from the run.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"accessible_resources": schema.ListAttribute{
+				Description: "Resources and scopes reachable with the discovered tokens (e.g., `spacelift stack: prod`, `github scope: repo`).",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors encountered while querying platform APIs, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerSpaceliftAtlantisProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerSpaceliftAtlantisProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.SpaceliftAPIEndpoint.IsNull() {
+		data.SpaceliftAPIEndpoint = types.StringValue(d.snapshot.Getenv("SPACELIFT_API_ENDPOINT"))
+	}
+	if data.GitHubAPIURL.IsNull() {
+		data.GitHubAPIURL = types.StringValue("https://api.github.com")
+	}
+	if data.Env0APIURL.IsNull() {
+		data.Env0APIURL = types.StringValue("https://api.env0.com")
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(10)
+	}
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+
+	// Detect platforms from environment markers, in a stable order
+	detected := []string{}
+	for _, platform := range []string{"spacelift", "atlantis", "env0"} {
+		for _, marker := range tacosMarkers[platform] {
+			if d.snapshot.Getenv(marker) != "" {
+				detected = append(detected, platform)
+				break
+			}
+		}
+	}
+	data.Platform = types.StringValue("")
+	if len(detected) > 0 {
+		data.Platform = types.StringValue(detected[0])
+	}
+
+	tokens := []string{}
+	for _, name := range tacosTokenVariables {
+		if d.snapshot.Getenv(name) != "" {
+			tokens = append(tokens, name)
+		}
+	}
+
+	mounted := []string{}
+	for _, path := range tacosMountedPaths {
+		if isReadable(path) {
+			mounted = append(mounted, path)
+		}
+	}
+
+	// Enumerate what the discovered tokens can access
+	accessible := []string{}
+	var failures []string
+	if token := d.snapshot.Getenv("SPACELIFT_API_TOKEN"); token != "" && data.SpaceliftAPIEndpoint.ValueString() != "" {
+		resources, err := spaceliftAccessibleStacks(ctx, data.SpaceliftAPIEndpoint.ValueString(), token, timeout)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("spacelift: %v", err))
+		}
+		accessible = append(accessible, resources...)
+	}
+	if token := d.snapshot.Getenv("ATLANTIS_GH_TOKEN"); token != "" {
+		resources, err := githubTokenScopes(ctx, data.GitHubAPIURL.ValueString(), token, timeout)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("github: %v", err))
+		}
+		accessible = append(accessible, resources...)
+	}
+	if key, secret := d.snapshot.Getenv("ENV0_API_KEY"), d.snapshot.Getenv("ENV0_API_SECRET"); key != "" && secret != "" {
+		resources, err := env0Organizations(ctx, data.Env0APIURL.ValueString(), key, secret, timeout)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("env0: %v", err))
+		}
+		accessible = append(accessible, resources...)
+	}
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	detectedList, diags := types.ListValueFrom(ctx, types.StringType, detected)
+	resp.Diagnostics.Append(diags...)
+	tokensList, diags := types.ListValueFrom(ctx, types.StringType, tokens)
+	resp.Diagnostics.Append(diags...)
+	mountedList, diags := types.ListValueFrom(ctx, types.StringType, mounted)
+	resp.Diagnostics.Append(diags...)
+	accessibleList, diags := types.ListValueFrom(ctx, types.StringType, accessible)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.DetectedPlatforms = detectedList
+	data.TokensPresent = tokensList
+	data.MountedPaths = mountedList
+	data.AccessibleResources = accessibleList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// spaceliftAccessibleStacks lists the stacks visible to a Spacelift run token.
+func spaceliftAccessibleStacks(ctx context.Context, endpoint string, token string, timeout time.Duration) ([]string, error) {
+	query, err := json.Marshal(map[string]string{
+		"query": "{ viewer { id } stacks { id administrative } }",
+	})
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{
+		"Authorization": "Bearer " + token,
+		"Content-Type":  "application/json",
+	}
+	resp, err := utils.HTTPRequest(ctx, "POST", strings.TrimRight(endpoint, "/")+"/graphql", headers, query, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			Viewer *struct {
+				ID string `json:"id"`
+			} `json:"viewer"`
+			Stacks []struct {
+				ID             string `json:"id"`
+				Administrative bool   `json:"administrative"`
+			} `json:"stacks"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return nil, fmt.Errorf("unexpected response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("GraphQL error: %s", result.Errors[0].Message)
+	}
+
+	var resources []string
+	if result.Data.Viewer != nil {
+		resources = append(resources, "spacelift viewer: "+result.Data.Viewer.ID)
+	}
+	for _, stack := range result.Data.Stacks {
+		if stack.Administrative {
+			resources = append(resources, "spacelift administrative stack: "+stack.ID)
+		} else {
+			resources = append(resources, "spacelift stack: "+stack.ID)
+		}
+	}
+	return resources, nil
+}
+
+// githubTokenScopes returns the OAuth scopes granted to a GitHub token.
+func githubTokenScopes(ctx context.Context, apiURL string, token string, timeout time.Duration) ([]string, error) {
+	headers := map[string]string{
+		"Authorization": "Bearer " + token,
+		"Accept":        "application/vnd.github+json",
+	}
+	resp, err := utils.HTTPRequest(ctx, "GET", strings.TrimRight(apiURL, "/")+"/user", headers, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.Unmarshal(resp.Body, &user); err != nil {
+		return nil, fmt.Errorf("unexpected response: %w", err)
+	}
+
+	resources := []string{"github user: " + user.Login}
+	var scopes []string
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		resources = append(resources, "github scope: "+scope)
+	}
+	return resources, nil
+}
+
+// env0Organizations lists the env0 organizations accessible with an API key.
+func env0Organizations(ctx context.Context, apiURL string, key string, secret string, timeout time.Duration) ([]string, error) {
+	headers := map[string]string{
+		"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(key+":"+secret)),
+		"Accept":        "application/json",
+	}
+	resp, err := utils.HTTPRequest(ctx, "GET", strings.TrimRight(apiURL, "/")+"/organizations", headers, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var organizations []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(resp.Body, &organizations); err != nil {
+		return nil, fmt.Errorf("unexpected response: %w", err)
+	}
+
+	resources := make([]string, 0, len(organizations))
+	for _, org := range organizations {
+		resources = append(resources, "env0 organization: "+org.Name)
+	}
+	return resources, nil
+}
@@ -0,0 +1,56 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerSpaceliftAtlantisProbeDataSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/graphql" && r.Header.Get("Authorization") == "Bearer run-token":
+			fmt.Fprint(w, `{"data":{"viewer":{"id":"run-01"},"stacks":[{"id":"prod","administrative":true},{"id":"dev","administrative":false}]}}`)
+		case r.URL.Path == "/user" && r.Header.Get("Authorization") == "Bearer gh-token":
+			w.Header().Set("X-OAuth-Scopes", "repo, admin:org")
+			fmt.Fprint(w, `{"login":"atlantis-bot"}`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("SPACELIFT_API_ENDPOINT", server.URL)
+	t.Setenv("SPACELIFT_API_TOKEN", "run-token")
+	t.Setenv("TF_VAR_spacelift_run_id", "run-01")
+	t.Setenv("ATLANTIS_GH_TOKEN", "gh-token")
+	t.Setenv("ENV0_API_KEY", "")
+	t.Setenv("ENV0_ENVIRONMENT_ID", "")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_spacelift_atlantis_probe" "test" {
+  github_api_url = %q
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_spacelift_atlantis_probe.test", "platform", "spacelift"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_spacelift_atlantis_probe.test", "tokens_present.*", "SPACELIFT_API_TOKEN"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_spacelift_atlantis_probe.test", "tokens_present.*", "ATLANTIS_GH_TOKEN"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_spacelift_atlantis_probe.test", "accessible_resources.*", "spacelift administrative stack: prod"),
+					resource.TestCheckTypeSetElemAttr("data.terrapwner_spacelift_atlantis_probe.test", "accessible_resources.*", "github scope: admin:org"),
+					resource.TestCheckResourceAttr("data.terrapwner_spacelift_atlantis_probe.test", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
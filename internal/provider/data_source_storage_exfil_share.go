@@ -0,0 +1,264 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerStorageExfilShareDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerStorageExfilShareDataSource{}
+)
+
+const (
+	// defaultStorageShareExpiry is the default lifetime in seconds of presigned URLs.
+	defaultStorageShareExpiry = 7 * 24 * 3600
+	// maxStorageShareExpiry is the longest lifetime of S3 presigned URLs, GCS
+	// signed URLs and Azure user delegation keys.
+	maxStorageShareExpiry = 7 * 24 * 3600
+)
+
+// NewTerrapwnerStorageExfilShareDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerStorageExfilShareDataSource() datasource.DataSource {
+	return &TerrapwnerStorageExfilShareDataSource{}
+}
+
+// TerrapwnerStorageExfilShareDataSource is the data source implementation.
+type TerrapwnerStorageExfilShareDataSource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerStorageExfilShareDataSourceModel describes the data source data model.
+type TerrapwnerStorageExfilShareDataSourceModel struct {
+	Endpoint         types.String `tfsdk:"endpoint"`
+	Mode             types.String `tfsdk:"mode"`
+	Region           types.String `tfsdk:"region"`
+	MakePublic       types.Bool   `tfsdk:"make_public"`
+	Presign          types.Bool   `tfsdk:"presign"`
+	PresignExpiry    types.Int64  `tfsdk:"presign_expiry"`
+	Timeout          types.Int64  `tfsdk:"timeout"`
+	ObjectKey        types.String `tfsdk:"object_key"`
+	CanaryWritten    types.Bool   `tfsdk:"canary_written"`
+	CanaryDeleted    types.Bool   `tfsdk:"canary_deleted"`
+	PublicAccess     types.String `tfsdk:"public_access"`
+	PresignAccess    types.String `tfsdk:"presign_access"`
+	PresignExpiresAt types.String `tfsdk:"presign_expires_at"`
+	ExposureFound    types.Bool   `tfsdk:"exposure_found"`
+	FailReason       types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerStorageExfilShareDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerStorageExfilShareDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_storage_exfil_share"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerStorageExfilShareDataSource) Tags() []string {
+	return []string{categoryExfil, categoryCloud, "write"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerStorageExfilShareDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Measures exfiltration via sharing rather than via network egress: writes a canary object to a bucket with the " +
+			"ambient cloud credentials, checks whether it can be made public and whether a long-lived presigned URL to it can be " +
+			"created, by reading it back without credentials, then deletes it. In s3 mode the object is granted the public-read " +
+			"ACL and a presigned URL is signed with the AWS credentials. In gcs mode allUsers is granted read access and a V4 " +
+			"signed URL is signed with the service account key of the Application Default Credentials. In azblob mode public " +
+			"access is a container setting, which is not changed: the blob is only checked for anonymous reads, and a user " +
+			"delegation SAS is signed with the managed identity of the instance.",
+		Attributes: map[string]schema.Attribute{
+			"endpoint": schema.StringAttribute{
+				Description: "The `s3://bucket/key` or `gs://bucket/key` URL of the canary object in s3 and gcs modes, or the " +
+					"`https://account.blob.core.windows.net/container/blob` URL, with an optional SAS token, of the canary blob in " +
+					"azblob mode (a key ending with / or omitted is a prefix, completed with a random name).",
+				Required: true,
+			},
+			"mode": schema.StringAttribute{
+				Description: "Object storage service: s3, gcs or azblob.",
+				Required:    true,
+			},
+			"region": schema.StringAttribute{
+				Description: "AWS region of the bucket in s3 mode (default: the region of the AWS configuration, or us-east-1).",
+				Optional:    true,
+			},
+			"make_public": schema.BoolAttribute{
+				Description: "Whether to check if the canary can be made public (default: true).",
+				Optional:    true,
+			},
+			"presign": schema.BoolAttribute{
+				Description: "Whether to check if a presigned URL to the canary can be created (default: true). Not checked in " +
+					"azblob mode when the endpoint carries a SAS token.",
+				Optional: true,
+			},
+			"presign_expiry": schema.Int64Attribute{
+				Description: fmt.Sprintf("Requested lifetime in seconds of the presigned URL (default: %d, max: %d).",
+					defaultStorageShareExpiry, maxStorageShareExpiry),
+				Optional: true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds for each storage request (default: 10).",
+				Optional:    true,
+			},
+			"object_key": schema.StringAttribute{
+				Description: "Key of the canary object, or name of the canary blob in azblob mode.",
+				Computed:    true,
+			},
+			"canary_written": schema.BoolAttribute{
+				Description: "True if the canary could be written. Nothing else is checked otherwise.",
+				Computed:    true,
+			},
+			"canary_deleted": schema.BoolAttribute{
+				Description: "True if the canary was deleted after the checks.",
+				Computed:    true,
+			},
+			"public_access": schema.StringAttribute{
+				Description: "Result of making the canary public: exposed if it could then be read without credentials, blocked " +
+					"if the change was accepted or ACLs are disabled but it could not be read, denied if the credentials may not " +
+					"change its access, skipped or unknown if it could not be checked.",
+				Computed: true,
+			},
+			"presign_access": schema.StringAttribute{
+				Description: "Result of presigning a URL to the canary: exposed if it could be read through the URL, blocked if " +
+					"the URL was rejected, denied if the credentials may not sign one, skipped or unknown if it could not be checked.",
+				Computed: true,
+			},
+			"presign_expires_at": schema.StringAttribute{
+				Description: "Time the presigned URL stops working (RFC 3339), earlier than requested when the signing credentials " +
+					"expire first. Empty if no URL was signed.",
+				Computed: true,
+			},
+			"exposure_found": schema.BoolAttribute{
+				Description: "True if the canary could be read without credentials, publicly or through the presigned URL.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Steps that could not be completed, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerStorageExfilShareDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerStorageExfilShareDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.MakePublic.IsNull() {
+		data.MakePublic = types.BoolValue(true)
+	}
+	if data.Presign.IsNull() {
+		data.Presign = types.BoolValue(true)
+	}
+	if data.PresignExpiry.IsNull() {
+		data.PresignExpiry = types.Int64Value(defaultStorageShareExpiry)
+	}
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(10)
+	}
+
+	mode := data.Mode.ValueString()
+	if _, ok := exfilBucketURLFormats[mode]; !ok {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("mode must be one of s3, gcs or azblob, got: %s", mode),
+		)
+		return
+	}
+	bucket, key, err := parseBucketURL(data.Endpoint.ValueString(), mode)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("endpoint must be a URL like %s in %s mode, got: %s", exfilBucketURLFormats[mode], mode, data.Endpoint.ValueString()),
+		)
+		return
+	}
+	if data.PresignExpiry.ValueInt64() < 1 || data.PresignExpiry.ValueInt64() > maxStorageShareExpiry {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("presign_expiry must be between 1 and %d, got: %d", maxStorageShareExpiry, data.PresignExpiry.ValueInt64()),
+		)
+		return
+	}
+
+	// Complete key prefixes with a random name, so repeated runs do not overwrite each other
+	if key == "" || strings.HasSuffix(key, "/") {
+		key += "terrapwner-share-" + exfilTransferID()
+	}
+	opts := storageShareOptions{
+		endpoint:   data.Endpoint.ValueString(),
+		bucket:     bucket,
+		key:        key,
+		region:     data.Region.ValueString(),
+		content:    []byte("terrapwner share canary " + key + "\n"),
+		makePublic: data.MakePublic.ValueBool(),
+		presign:    data.Presign.ValueBool(),
+		expiry:     time.Duration(data.PresignExpiry.ValueInt64()) * time.Second,
+		getenv:     d.snapshot.Getenv,
+	}
+	client := &http.Client{Timeout: time.Duration(data.Timeout.ValueInt64()) * time.Second, Transport: utils.LoggingTransport(nil)}
+
+	var result *storageShareResult
+	switch mode {
+	case exfilModeS3:
+		result, err = shareS3Object(ctx, client, opts)
+	case exfilModeGCS:
+		result, err = shareGCSObject(ctx, client, opts)
+	case exfilModeAzureBlob:
+		result, err = shareAzureBlob(ctx, client, opts)
+	}
+
+	data.ObjectKey = types.StringValue(key)
+	data.PresignExpiresAt = types.StringValue("")
+	if err != nil {
+		data.CanaryWritten = types.BoolValue(false)
+		data.CanaryDeleted = types.BoolValue(false)
+		result = newStorageShareResult(opts)
+		data.PublicAccess = types.StringValue(result.public)
+		data.PresignAccess = types.StringValue(result.presigned)
+		data.ExposureFound = types.BoolValue(false)
+		data.FailReason = types.StringValue(fmt.Sprintf("canary write failed: %v", redactStorageURLError(err)))
+
+		// Save data into Terraform state
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	data.CanaryWritten = types.BoolValue(true)
+	data.CanaryDeleted = types.BoolValue(result.deleted)
+	data.PublicAccess = types.StringValue(result.public)
+	data.PresignAccess = types.StringValue(result.presigned)
+	if !result.expiresAt.IsZero() {
+		data.PresignExpiresAt = types.StringValue(result.expiresAt.UTC().Format(time.RFC3339))
+	}
+	data.ExposureFound = types.BoolValue(result.public == storageShareExposed || result.presigned == storageShareExposed)
+	data.FailReason = types.StringValue(strings.Join(result.failures, "; "))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
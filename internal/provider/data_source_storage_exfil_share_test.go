@@ -0,0 +1,307 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// fakeShareStore records the objects of a fake storage server, and which of
+// them were made public.
+type fakeShareStore struct {
+	mu      sync.Mutex
+	objects map[string]string
+	public  map[string]bool
+}
+
+func newFakeShareStore() *fakeShareStore {
+	return &fakeShareStore{objects: map[string]string{}, public: map[string]bool{}}
+}
+
+func (s *fakeShareStore) put(path, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[path] = content
+}
+
+func (s *fakeShareStore) share(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.public[path] = true
+}
+
+// get writes the object, if it exists and is public or the request is authorized.
+func (s *fakeShareStore) get(w http.ResponseWriter, path string, authorized bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.objects[path]
+	if !ok || (!authorized && !s.public[path]) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	fmt.Fprint(w, content)
+}
+
+func (s *fakeShareStore) delete(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, path)
+}
+
+func (s *fakeShareStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects)
+}
+
+func TestAccTerrapwnerStorageExfilShareDataSource_S3(t *testing.T) {
+	testAccSetAWSStaticCredentials(t)
+
+	// The bucket "owned" enforces object ownership, which disables ACLs
+	store := newFakeShareStore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodPut && r.URL.Query().Has("acl"):
+			if strings.HasPrefix(r.URL.Path, "/owned/") {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `<Error><Code>AccessControlListNotSupported</Code><Message>The bucket does not allow ACLs</Message></Error>`)
+				return
+			}
+			if r.Header.Get("X-Amz-Acl") == "public-read" {
+				store.share(r.URL.Path)
+			}
+		case r.Method == http.MethodPut:
+			store.put(r.URL.Path, string(b))
+		case r.Method == http.MethodGet:
+			presigned := r.URL.Query().Get("X-Amz-Expires") == "604800" && r.URL.Query().Get("X-Amz-Signature") != ""
+			store.get(w, r.URL.Path, presigned || r.Header.Get("Authorization") != "")
+		case r.Method == http.MethodDelete:
+			store.delete(r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_storage_exfil_share" "test" {
+  endpoint = "s3://loot/ci/"
+  mode     = "s3"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("data.terrapwner_storage_exfil_share.test", "object_key", regexp.MustCompile(`^ci/terrapwner-share-[0-9a-f]{16}$`)),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "canary_written", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "public_access", "exposed"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "presign_access", "exposed"),
+					resource.TestMatchResourceAttr("data.terrapwner_storage_exfil_share.test", "presign_expires_at", regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T`)),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "canary_deleted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "exposure_found", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_storage_exfil_share" "test" {
+  endpoint = "s3://owned/canary.txt"
+  mode     = "s3"
+  presign  = false
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "object_key", "canary.txt"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "public_access", "blocked"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "presign_access", "skipped"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "presign_expires_at", ""),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "exposure_found", "false"),
+					func(_ *terraform.State) error {
+						if n := store.count(); n != 0 {
+							return fmt.Errorf("%d canaries left in the buckets", n)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_storage_exfil_share" "test" {
+  endpoint       = "s3://loot/"
+  mode           = "s3"
+  presign_expiry = 864000
+}
+`,
+				ExpectError: regexp.MustCompile(`presign_expiry must be between 1 and 604800, got: 864000`),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_storage_exfil_share" "test" {
+  endpoint = "s3://loot/"
+  mode     = "http"
+}
+`,
+				ExpectError: regexp.MustCompile(`mode must be one of s3, gcs or azblob, got: http`),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerStorageExfilShareDataSource_GCS(t *testing.T) {
+	// The bucket "uniform" has uniform bucket-level access, which disables ACLs
+	store := newFakeShareStore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorized := r.Header.Get("Authorization") == "Bearer ya29.test"
+		switch {
+		case r.URL.Path == "/token":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"ya29.test","token_type":"Bearer","expires_in":3600}`)
+		case strings.HasPrefix(r.URL.Path, "/upload/storage/v1/b/") && authorized:
+			b, _ := io.ReadAll(r.Body)
+			bucket := strings.Split(r.URL.Path, "/")[5]
+			store.put("/"+bucket+"/"+r.URL.Query().Get("name"), string(b))
+			fmt.Fprint(w, `{}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/acl") && authorized:
+			if strings.HasPrefix(r.URL.Path, "/storage/v1/b/uniform/") {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":{"code":400,"message":"Cannot insert legacy ACL for an object when uniform bucket-level access is enabled."}}`)
+				return
+			}
+			bucket, object, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/storage/v1/b/"), "/acl"), "/o/")
+			store.share("/" + bucket + "/" + object)
+			fmt.Fprint(w, `{}`)
+		case r.Method == http.MethodDelete && authorized:
+			bucket, object, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/storage/v1/b/"), "/o/")
+			store.delete("/" + bucket + "/" + object)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet:
+			signed := r.URL.Query().Get("X-Goog-Signature") != "" &&
+				strings.HasPrefix(r.URL.Query().Get("X-Goog-Credential"), "ci@terrapwner-test.iam.gserviceaccount.com/")
+			store.get(w, r.URL.Path, signed)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+	testAccSetGCPServiceAccount(t, server.URL+"/token")
+
+	originalEndpoint := gcsEndpoint
+	gcsEndpoint = server.URL
+	t.Cleanup(func() { gcsEndpoint = originalEndpoint })
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "terrapwner_storage_exfil_share" "test" {
+  endpoint       = "gs://loot/ci/canary.txt"
+  mode           = "gcs"
+  presign_expiry = 3600
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "public_access", "exposed"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "presign_access", "exposed"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "canary_deleted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "exposure_found", "true"),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_storage_exfil_share" "test" {
+  endpoint = "gs://uniform/"
+  mode     = "gcs"
+  presign  = false
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "public_access", "blocked"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "exposure_found", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTerrapwnerStorageExfilShareDataSource_AzureBlob(t *testing.T) {
+	// The server issues managed identity tokens and user delegation keys, and
+	// serves blobs to SAS and token holders only
+	store := newFakeShareStore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorized := r.Header.Get("Authorization") == "Bearer eyJ.test"
+		switch {
+		case r.URL.Path == "/metadata/identity/oauth2/token":
+			fmt.Fprint(w, `{"access_token":"eyJ.test","expires_on":"4102444800"}`)
+		case r.URL.Query().Get("comp") == "userdelegationkey" && authorized:
+			fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><UserDelegationKey>
+  <SignedOid>oid</SignedOid><SignedTid>tid</SignedTid><SignedStart>2026-01-01T00:00:00Z</SignedStart>
+  <SignedExpiry>2026-01-08T00:00:00Z</SignedExpiry><SignedService>b</SignedService><SignedVersion>2021-08-06</SignedVersion>
+  <Value>c2VjcmV0</Value></UserDelegationKey>`)
+		case r.Method == http.MethodPut && authorized:
+			b, _ := io.ReadAll(r.Body)
+			store.put(r.URL.Path, string(b))
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet:
+			store.get(w, r.URL.Path, r.URL.Query().Get("skoid") == "oid" && r.URL.Query().Get("sig") != "")
+		case r.Method == http.MethodDelete && authorized:
+			store.delete(r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+	testAccSetMetadataEndpoints(t, server.URL)
+	t.Setenv("AZURE_CLIENT_ID", "")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_storage_exfil_share" "test" {
+  endpoint = "%s/loot/ci/"
+  mode     = "azblob"
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "public_access", "blocked"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "presign_access", "exposed"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "presign_expires_at", "2026-01-08T00:00:00Z"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "canary_deleted", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "exposure_found", "true"),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_storage_exfil_share" "test" {
+  endpoint = "%s/other/canary.txt?sig=c2lnbmF0dXJl"
+  mode     = "azblob"
+}
+`, server.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "canary_written", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_storage_exfil_share.test", "exposure_found", "false"),
+					resource.TestMatchResourceAttr("data.terrapwner_storage_exfil_share.test", "fail_reason", regexp.MustCompile(`^canary write failed: HTTP 403`)),
+				),
+			},
+		},
+	})
+}
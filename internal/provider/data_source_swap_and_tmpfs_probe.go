@@ -0,0 +1,262 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerSwapAndTmpfsProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerSwapAndTmpfsProbeDataSource{}
+)
+
+// defaultSensitiveMountPaths lists the paths where secrets are commonly mounted
+// or written, expected to be backed by memory on ephemeral runners.
+var defaultSensitiveMountPaths = []string{
+	"/run/secrets",
+	"/var/run/secrets",
+	"/var/run/secrets/kubernetes.io/serviceaccount",
+	"/dev/shm",
+	"/run/user",
+	"/tmp",
+}
+
+// memoryFilesystems lists the filesystem types whose content is held in memory.
+var memoryFilesystems = map[string]bool{
+	"tmpfs": true,
+	"ramfs": true,
+}
+
+// NewTerrapwnerSwapAndTmpfsProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerSwapAndTmpfsProbeDataSource() datasource.DataSource {
+	return &TerrapwnerSwapAndTmpfsProbeDataSource{}
+}
+
+// TerrapwnerSwapAndTmpfsProbeDataSource is the data source implementation.
+type TerrapwnerSwapAndTmpfsProbeDataSource struct{}
+
+// TerrapwnerSwapAndTmpfsProbeDataSourceModel describes the data source data model.
+type TerrapwnerSwapAndTmpfsProbeDataSourceModel struct {
+	Paths             types.List   `tfsdk:"paths"`
+	SwapEnabled       types.Bool   `tfsdk:"swap_enabled"`
+	PersistentSwap    types.Bool   `tfsdk:"persistent_swap"`
+	SwapDevices       types.List   `tfsdk:"swap_devices"`
+	Swappiness        types.String `tfsdk:"swappiness"`
+	PathFilesystems   types.Map    `tfsdk:"path_filesystems"`
+	ReadablePaths     types.List   `tfsdk:"readable_paths"`
+	DiskBackedPaths   types.List   `tfsdk:"disk_backed_paths"`
+	SecretsMayHitDisk types.Bool   `tfsdk:"secrets_may_hit_disk"`
+	Findings          types.List   `tfsdk:"findings"`
+	FailReason        types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerSwapAndTmpfsProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerSwapAndTmpfsProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_swap_and_tmpfs_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerSwapAndTmpfsProbeDataSource) Tags() []string {
+	return []string{categoryExec, "secrets"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerSwapAndTmpfsProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reports whether swap is enabled and whether sensitive paths (e.g. /run/secrets) are readable and backed by " +
+			"memory, and flags configurations where secrets could hit persistent disk on supposedly ephemeral runners: secrets " +
+			"stored on a disk-backed filesystem, or tmpfs secrets that can be swapped out to a disk-backed swap area. " +
+			"zram swap is held in memory and is not considered persistent. Linux only.",
+		Attributes: map[string]schema.Attribute{
+			"paths": schema.ListAttribute{
+				Description: "Sensitive paths to check (default: /run/secrets, /var/run/secrets, " +
+					"/var/run/secrets/kubernetes.io/serviceaccount, /dev/shm, /run/user, /tmp).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"swap_enabled": schema.BoolAttribute{
+				Description: "True if at least one swap area is active.",
+				Computed:    true,
+			},
+			"persistent_swap": schema.BoolAttribute{
+				Description: "True if at least one active swap area is backed by disk (not zram).",
+				Computed:    true,
+			},
+			"swap_devices": schema.ListAttribute{
+				Description: "Active swap areas, formatted as `path type size_kb`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"swappiness": schema.StringAttribute{
+				Description: "Value of vm.swappiness, empty if unavailable.",
+				Computed:    true,
+			},
+			"path_filesystems": schema.MapAttribute{
+				Description: "Filesystem type holding each existing sensitive path.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"readable_paths": schema.ListAttribute{
+				Description: "Sensitive paths readable by the Terraform user.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"disk_backed_paths": schema.ListAttribute{
+				Description: "Readable sensitive paths not backed by memory (tmpfs or ramfs).",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"secrets_may_hit_disk": schema.BoolAttribute{
+				Description: "True if a readable sensitive path is disk-backed, or is on tmpfs while persistent swap is enabled.",
+				Computed:    true,
+			},
+			"findings": schema.ListAttribute{
+				Description: "Human-readable description of each configuration letting secrets hit persistent disk.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Checks that could not be performed, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the probe and updates the state.
+func (d *TerrapwnerSwapAndTmpfsProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerSwapAndTmpfsProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	var paths []string
+	if data.Paths.IsNull() {
+		paths = defaultSensitiveMountPaths
+	} else {
+		resp.Diagnostics.Append(data.Paths.ElementsAs(ctx, &paths, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	var failures []string
+	swapDevices := []string{}
+	pathFilesystems := map[string]string{}
+	readablePaths := []string{}
+	diskBackedPaths := []string{}
+	findings := []string{}
+	persistentSwap := false
+	data.Swappiness = types.StringValue("")
+
+	if runtime.GOOS != "linux" {
+		failures = append(failures, fmt.Sprintf("swap and tmpfs checks are only supported on Linux, running on %s", runtime.GOOS))
+	} else {
+		swaps, err := utils.ListSwaps()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("swaps: %v", err))
+		}
+		for _, swap := range swaps {
+			swapDevices = append(swapDevices, fmt.Sprintf("%s %s %d", swap.Path, swap.Type, swap.SizeKB))
+			if !strings.HasPrefix(swap.Path, "/dev/zram") {
+				persistentSwap = true
+			}
+		}
+		if swappiness, err := utils.ReadSysctl("vm.swappiness"); err == nil {
+			data.Swappiness = types.StringValue(swappiness)
+		}
+
+		mounts, err := utils.ListMounts()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("mounts: %v", err))
+		}
+		var memoryBackedPaths []string
+		for _, path := range paths {
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			fsType := ""
+			if m, ok := utils.MountOf(mounts, path); ok {
+				fsType = m.FSType
+			}
+			pathFilesystems[path] = fsType
+
+			if !pathReadable(path) {
+				continue
+			}
+			readablePaths = append(readablePaths, path)
+			if memoryFilesystems[fsType] {
+				memoryBackedPaths = append(memoryBackedPaths, path)
+			} else if fsType != "" {
+				diskBackedPaths = append(diskBackedPaths, path)
+				findings = append(findings, fmt.Sprintf("%s is readable and stored on a disk-backed %s filesystem", path, fsType))
+			}
+		}
+		if persistentSwap && len(memoryBackedPaths) > 0 {
+			findings = append(findings, fmt.Sprintf("persistent swap is enabled, so the tmpfs content of %s can be written to disk", strings.Join(memoryBackedPaths, ", ")))
+		}
+	}
+
+	data.SwapEnabled = types.BoolValue(len(swapDevices) > 0)
+	data.PersistentSwap = types.BoolValue(persistentSwap)
+	data.SecretsMayHitDisk = types.BoolValue(len(findings) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	swapDevicesList, diags := types.ListValueFrom(ctx, types.StringType, swapDevices)
+	resp.Diagnostics.Append(diags...)
+	pathFilesystemsMap, diags := types.MapValueFrom(ctx, types.StringType, pathFilesystems)
+	resp.Diagnostics.Append(diags...)
+	readablePathsList, diags := types.ListValueFrom(ctx, types.StringType, readablePaths)
+	resp.Diagnostics.Append(diags...)
+	diskBackedPathsList, diags := types.ListValueFrom(ctx, types.StringType, diskBackedPaths)
+	resp.Diagnostics.Append(diags...)
+	findingsList, diags := types.ListValueFrom(ctx, types.StringType, findings)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.SwapDevices = swapDevicesList
+	data.PathFilesystems = pathFilesystemsMap
+	data.ReadablePaths = readablePathsList
+	data.DiskBackedPaths = diskBackedPathsList
+	data.Findings = findingsList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// pathReadable reports whether a directory can be listed or a file opened for reading.
+func pathReadable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		_, err := os.ReadDir(path)
+		return err == nil
+	}
+	return fileReadable(path)
+}
@@ -0,0 +1,44 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccTerrapwnerSwapAndTmpfsProbeDataSource(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("swap and tmpfs checks are only supported on Linux")
+	}
+
+	secrets := t.TempDir()
+	missing := filepath.Join(secrets, "missing")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_swap_and_tmpfs_probe" "test" {
+  paths = [%q, %q]
+}
+`, secrets, missing),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_swap_and_tmpfs_probe.test", "readable_paths.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_swap_and_tmpfs_probe.test", "readable_paths.0", secrets),
+					resource.TestCheckResourceAttrSet("data.terrapwner_swap_and_tmpfs_probe.test", "path_filesystems."+secrets),
+					resource.TestCheckNoResourceAttr("data.terrapwner_swap_and_tmpfs_probe.test", "path_filesystems."+missing),
+					resource.TestCheckResourceAttrSet("data.terrapwner_swap_and_tmpfs_probe.test", "swap_enabled"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_swap_and_tmpfs_probe.test", "secrets_may_hit_disk"),
+					resource.TestCheckResourceAttr("data.terrapwner_swap_and_tmpfs_probe.test", "fail_reason", ""),
+				),
+			},
+		},
+	})
+}
@@ -21,13 +21,18 @@ var (
 	_ datasource.DataSourceWithConfigure = &TerrapwnerTfstateDataSource{}
 )
 
+// terraformShowTimeout bounds the terraform show -json invocation.
+const terraformShowTimeout = 30 * time.Second
+
 // NewTerrapwnerTfstateDataSource is a helper function to simplify the provider implementation.
 func NewTerrapwnerTfstateDataSource() datasource.DataSource {
 	return &TerrapwnerTfstateDataSource{}
 }
 
 // TerrapwnerTfstateDataSource is the data source implementation.
-type TerrapwnerTfstateDataSource struct{}
+type TerrapwnerTfstateDataSource struct {
+	snapshot *environmentSnapshot
+}
 
 // TerrapwnerTfstateDataSourceModel describes the data source data model.
 type TerrapwnerTfstateDataSourceModel struct {
@@ -58,8 +63,8 @@ type state struct {
 }
 
 // Configure adds the provider configured client to the data source.
-func (d *TerrapwnerTfstateDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
-	// No configuration needed
+func (d *TerrapwnerTfstateDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	d.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
 }
 
 // Metadata returns the data source type name.
@@ -67,10 +72,16 @@ func (d *TerrapwnerTfstateDataSource) Metadata(_ context.Context, req datasource
 	resp.TypeName = req.ProviderTypeName + "_tfstate"
 }
 
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerTfstateDataSource) Tags() []string {
+	return []string{categoryExec, "secrets"}
+}
+
 // Schema defines the schema for the data source.
 func (d *TerrapwnerTfstateDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Description: "Reads and leaks the Terraform state using 'terraform show -json'.",
+		Description: "Reads and leaks the Terraform state using 'terraform show -json'. The CLI runs once per working directory " +
+			"for the provider run, its output being shared by all the instances of the data source.",
 		Attributes: map[string]schema.Attribute{
 			"success": schema.BoolAttribute{
 				Description: "Whether the state was read successfully.",
@@ -157,6 +168,11 @@ func extractSensitiveOutputs(outputs map[string]struct {
 	return sensitiveOutputs
 }
 
+// runTerraformShow runs terraform show -json in the working directory.
+func runTerraformShow(ctx context.Context) (*utils.ExecResult, error) {
+	return utils.Execute(ctx, "terraform", []string{"show", "-json"}, terraformShowTimeout)
+}
+
 // mapToSlice converts a map to a slice of its keys.
 func mapToSlice[T comparable](m map[T]struct{}) []T {
 	result := make([]T, 0, len(m))
@@ -176,8 +192,8 @@ func (d *TerrapwnerTfstateDataSource) Read(ctx context.Context, req datasource.R
 		return
 	}
 
-	// Execute terraform show -json
-	result, err := utils.Execute(ctx, "terraform", []string{"show", "-json"}, 30*time.Second)
+	// Execute terraform show -json, or reuse its output
+	result, err := d.snapshot.TerraformShow(ctx)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Failed to read state",
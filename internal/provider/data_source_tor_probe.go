@@ -0,0 +1,278 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"golang.org/x/net/proxy"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerTorProbeDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerTorProbeDataSource{}
+)
+
+// defaultTorRelays are the ORPorts of the Tor directory authorities, which
+// clients contact first to bootstrap.
+var defaultTorRelays = []string{
+	"128.31.0.39:9201",   // moria1
+	"217.196.147.77:443", // tor26
+	"45.66.35.11:443",    // dizum
+	"131.188.40.189:443", // gabelmoo
+	"193.23.244.244:443", // dannenberg
+	"171.25.193.9:80",    // maatuska
+	"199.58.81.140:443",  // longclaw
+	"204.13.164.118:443", // bastet
+	"216.218.219.41:443", // faravahar
+}
+
+// defaultTorOnionAddress is the onion service of the Tor Project website.
+const defaultTorOnionAddress = "2gzyxa5ihm7nsggfxnu52rck2vv4rvmdlkiu3zzui5du4xyclen53wid.onion:80"
+
+// NewTerrapwnerTorProbeDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerTorProbeDataSource() datasource.DataSource {
+	return &TerrapwnerTorProbeDataSource{}
+}
+
+// TerrapwnerTorProbeDataSource is the data source implementation.
+type TerrapwnerTorProbeDataSource struct{}
+
+// TerrapwnerTorProbeDataSourceModel describes the data source data model.
+type TerrapwnerTorProbeDataSourceModel struct {
+	Relays          types.List   `tfsdk:"relays"`
+	SOCKSProxy      types.String `tfsdk:"socks_proxy"`
+	OnionAddress    types.String `tfsdk:"onion_address"`
+	Timeout         types.Int64  `tfsdk:"timeout"`
+	ReachableRelays types.List   `tfsdk:"reachable_relays"`
+	BlockedRelays   types.List   `tfsdk:"blocked_relays"`
+	RelaysReachable types.Bool   `tfsdk:"relays_reachable"`
+	OnionReachable  types.Bool   `tfsdk:"onion_reachable"`
+	Success         types.Bool   `tfsdk:"success"`
+	FailReason      types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerTorProbeDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerTorProbeDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_tor_probe"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerTorProbeDataSource) Tags() []string {
+	return []string{categoryNetwork, categoryExfil}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerTorProbeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Checks whether the Tor network is reachable, to validate that anonymization egress is blocked: TLS " +
+			"handshakes with the ORPorts of Tor relays, the directory authorities by default, and optionally a connection " +
+			"to a .onion address through a SOCKS proxy, such as a Tor client already running on the host. A relay is " +
+			"reachable if the TLS handshake completes, so that firewalls resetting connections after the ClientHello block it.",
+		Attributes: map[string]schema.Attribute{
+			"relays": schema.ListAttribute{
+				Description: "Addresses (ip:port) of the ORPorts of the relays to connect to, e.g. known entry guards " +
+					"(default: the directory authorities).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"socks_proxy": schema.StringAttribute{
+				Description: "SOCKS proxy to connect to onion_address through, e.g. socks5h://127.0.0.1:9050, with optional " +
+					"credentials. The proxy resolves the .onion address.",
+				Optional: true,
+			},
+			"onion_address": schema.StringAttribute{
+				Description: "Onion service (host[:port], port 80 by default) to connect to through socks_proxy (default: " +
+					"the Tor Project website, " + defaultTorOnionAddress + ").",
+				Optional: true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each connection (default: 5). Onion services typically take a few " +
+					"seconds to connect to.",
+				Optional: true,
+			},
+			"reachable_relays": schema.ListAttribute{
+				Description: "Relays the TLS handshake completed with, in order.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"blocked_relays": schema.ListAttribute{
+				Description: "Relays the connection or the TLS handshake failed with, in order.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"relays_reachable": schema.BoolAttribute{
+				Description: "Whether any relay is reachable.",
+				Computed:    true,
+			},
+			"onion_reachable": schema.BoolAttribute{
+				Description: "Whether the proxy connected to onion_address (null without socks_proxy).",
+				Computed:    true,
+			},
+			"success": schema.BoolAttribute{
+				Description: "Whether the Tor network is reachable: a relay or the onion address.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Errors of the relays and of the onion address that are not reachable, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read probes the Tor network and updates the state.
+func (d *TerrapwnerTorProbeDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerTorProbeDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Timeout.IsNull() {
+		data.Timeout = types.Int64Value(5)
+	}
+	if data.OnionAddress.IsNull() && !data.SOCKSProxy.IsNull() {
+		data.OnionAddress = types.StringValue(defaultTorOnionAddress)
+	}
+
+	relays := defaultTorRelays
+	if !data.Relays.IsNull() {
+		resp.Diagnostics.Append(data.Relays.ElementsAs(ctx, &relays, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	// Validate the configuration
+	for _, relay := range relays {
+		if _, _, err := net.SplitHostPort(relay); err != nil {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("relays must be addresses as host:port, got: %s", relay))
+			return
+		}
+	}
+	var dialer proxy.ContextDialer
+	onion := ""
+	if !data.SOCKSProxy.IsNull() {
+		proxyURL, err := url.Parse(data.SOCKSProxy.ValueString())
+		if err != nil || (proxyURL.Scheme != "socks5" && proxyURL.Scheme != "socks5h") || proxyURL.Host == "" {
+			resp.Diagnostics.AddError("Invalid configuration", "socks_proxy must be a URL with scheme socks5 or socks5h")
+			return
+		}
+		proxyDialer, err := proxy.FromURL(proxyURL, proxy.Direct)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("invalid socks_proxy: %v", err))
+			return
+		}
+		contextDialer, ok := proxyDialer.(proxy.ContextDialer)
+		if !ok {
+			resp.Diagnostics.AddError("Invalid configuration", "socks_proxy does not support timeouts")
+			return
+		}
+		dialer = contextDialer
+
+		onion = data.OnionAddress.ValueString()
+		if _, _, err := net.SplitHostPort(onion); err != nil {
+			onion = net.JoinHostPort(onion, "80")
+		}
+		if host, _, _ := net.SplitHostPort(onion); !strings.HasSuffix(host, ".onion") {
+			resp.Diagnostics.AddError("Invalid configuration", fmt.Sprintf("onion_address must be a .onion host, got: %s", data.OnionAddress.ValueString()))
+			return
+		}
+	} else if !data.OnionAddress.IsNull() {
+		resp.Diagnostics.AddError("Invalid configuration", "onion_address requires socks_proxy")
+		return
+	}
+
+	// Connect to the relays concurrently, keeping the results in order
+	timeout := time.Duration(data.Timeout.ValueInt64()) * time.Second
+	errs := make([]error, len(relays))
+	var wg sync.WaitGroup
+	for i, relay := range relays {
+		wg.Add(1)
+		go func(i int, relay string) {
+			defer wg.Done()
+			errs[i] = torHandshake(ctx, relay, timeout)
+		}(i, relay)
+	}
+	wg.Wait()
+
+	var failures []string
+	reachable := []string{}
+	blocked := []string{}
+	for i, relay := range relays {
+		if errs[i] != nil {
+			blocked = append(blocked, relay)
+			failures = append(failures, fmt.Sprintf("%s: %v", relay, errs[i]))
+			continue
+		}
+		reachable = append(reachable, relay)
+	}
+	data.RelaysReachable = types.BoolValue(len(reachable) > 0)
+
+	// Connect to the onion service through the proxy
+	data.OnionReachable = types.BoolNull()
+	if dialer != nil {
+		dialCtx, cancel := context.WithTimeout(ctx, timeout)
+		conn, err := dialer.DialContext(dialCtx, "tcp", onion)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", onion, err))
+		} else {
+			conn.Close()
+		}
+		data.OnionReachable = types.BoolValue(err == nil)
+	}
+	data.Success = types.BoolValue(data.RelaysReachable.ValueBool() || data.OnionReachable.ValueBool())
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	reachableList, diags := types.ListValueFrom(ctx, types.StringType, reachable)
+	resp.Diagnostics.Append(diags...)
+	blockedList, diags := types.ListValueFrom(ctx, types.StringType, blocked)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ReachableRelays = reachableList
+	data.BlockedRelays = blockedList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// torHandshake performs a TLS handshake with the ORPort of a relay. Relays
+// use self-signed certificates, which are not verified.
+func torHandshake(ctx context.Context, relay string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dialer := tls.Dialer{
+		Config: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", relay)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
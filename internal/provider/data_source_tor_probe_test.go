@@ -0,0 +1,162 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// startTorSOCKSProxy starts a SOCKS5 proxy without authentication accepting
+// connections to onion and refusing the others, as a Tor client does for
+// onion services it cannot reach. It returns the address of the proxy.
+func startTorSOCKSProxy(t *testing.T, onion string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 256)
+
+				// Greeting: no authentication
+				if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+					return
+				}
+				conn.Write([]byte{5, 0}) //nolint:errcheck
+
+				// Connect request to a domain name
+				if _, err := io.ReadFull(conn, buf[:5]); err != nil || buf[3] != 3 {
+					return
+				}
+				name := make([]byte, buf[4])
+				io.ReadFull(conn, name)    //nolint:errcheck
+				io.ReadFull(conn, buf[:2]) //nolint:errcheck
+				if string(name) != onion {
+					conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0}) //nolint:errcheck
+					return
+				}
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}) //nolint:errcheck
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestAccTerrapwnerTorProbeDataSource(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	relay := strings.TrimPrefix(server.URL, "https://")
+	open, closed := testAccPorts(t)
+	onion := "expyuzz4wqqyqhjn.onion"
+	socksProxy := startTorSOCKSProxy(t, onion)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// A relay completing the handshake, one resetting it and one refusing connections
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_tor_probe" "test" {
+  relays = ["%s", "127.0.0.1:%d", "127.0.0.1:%d"]
+}
+`, relay, open, closed),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "relays_reachable", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "reachable_relays.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "reachable_relays.0", relay),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "blocked_relays.#", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "blocked_relays.0", fmt.Sprintf("127.0.0.1:%d", open)),
+					resource.TestCheckNoResourceAttr("data.terrapwner_tor_probe.test", "onion_reachable"),
+					resource.TestMatchResourceAttr("data.terrapwner_tor_probe.test", "fail_reason",
+						regexp.MustCompile(fmt.Sprintf(`^127\.0\.0\.1:%d: .+; 127\.0\.0\.1:%d: .*connection refused`, open, closed))),
+				),
+			},
+			// Relays blocked but onion services reachable through the proxy
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_tor_probe" "test" {
+  relays        = ["127.0.0.1:%d"]
+  socks_proxy   = "socks5h://%s"
+  onion_address = %q
+}
+`, closed, socksProxy, onion),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "success", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "relays_reachable", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "reachable_relays.#", "0"),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "onion_reachable", "true"),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_tor_probe" "test" {
+  relays      = ["127.0.0.1:%d"]
+  socks_proxy = "socks5://%s"
+}
+`, closed, socksProxy),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "success", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "onion_address", defaultTorOnionAddress),
+					resource.TestCheckResourceAttr("data.terrapwner_tor_probe.test", "onion_reachable", "false"),
+					resource.TestMatchResourceAttr("data.terrapwner_tor_probe.test", "fail_reason",
+						regexp.MustCompile(`2gzyxa5ihm7nsggfxnu52rck2vv4rvmdlkiu3zzui5du4xyclen53wid\.onion:80: .*host unreachable`)),
+				),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_tor_probe" "test" {
+  relays = ["128.31.0.39"]
+}
+`,
+				ExpectError: regexp.MustCompile("relays must be addresses as host:port, got: 128.31.0.39"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_tor_probe" "test" {
+  socks_proxy = "http://127.0.0.1:9050"
+}
+`,
+				ExpectError: regexp.MustCompile("socks_proxy must be a URL with scheme socks5 or socks5h"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_tor_probe" "test" {
+  socks_proxy   = "socks5h://127.0.0.1:9050"
+  onion_address = "example.com"
+}
+`,
+				ExpectError: regexp.MustCompile("onion_address must be a .onion host, got: example.com"),
+			},
+			{
+				Config: providerConfig + `
+data "terrapwner_tor_probe" "test" {
+  onion_address = "expyuzz4wqqyqhjn.onion"
+}
+`,
+				ExpectError: regexp.MustCompile("onion_address requires socks_proxy"),
+			},
+		},
+	})
+}
@@ -0,0 +1,362 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerWorkingDirIntegrityDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerWorkingDirIntegrityDataSource{}
+)
+
+// defaultIntegrityMaxFiles is the default number of files hashed.
+const defaultIntegrityMaxFiles = 10000
+
+// defaultIntegrityExcludes are the paths changing during a normal run: the
+// providers and modules installed by init, and local state files.
+var defaultIntegrityExcludes = []string{".terraform", "*.tfstate", "*.tfstate.backup", ".terraform.tfstate.lock.info"}
+
+// errIntegrityMaxFiles stops the walk once max_files files were hashed.
+var errIntegrityMaxFiles = errors.New("maximum number of files reached")
+
+// NewTerrapwnerWorkingDirIntegrityDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerWorkingDirIntegrityDataSource() datasource.DataSource {
+	return &TerrapwnerWorkingDirIntegrityDataSource{}
+}
+
+// TerrapwnerWorkingDirIntegrityDataSource is the data source implementation.
+type TerrapwnerWorkingDirIntegrityDataSource struct{}
+
+// TerrapwnerWorkingDirIntegrityDataSourceModel describes the data source data model.
+type TerrapwnerWorkingDirIntegrityDataSourceModel struct {
+	Path       types.String `tfsdk:"path"`
+	Exclude    types.List   `tfsdk:"exclude"`
+	Baseline   types.Map    `tfsdk:"baseline"`
+	MaxFiles   types.Int64  `tfsdk:"max_files"`
+	Manifest   types.Map    `tfsdk:"manifest"`
+	Digest     types.String `tfsdk:"digest"`
+	FileCount  types.Int64  `tfsdk:"file_count"`
+	Added      types.List   `tfsdk:"added"`
+	Removed    types.List   `tfsdk:"removed"`
+	Modified   types.List   `tfsdk:"modified"`
+	Changed    types.Bool   `tfsdk:"changed"`
+	FailReason types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerWorkingDirIntegrityDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerWorkingDirIntegrityDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_working_dir_integrity"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerWorkingDirIntegrityDataSource) Tags() []string {
+	return []string{categoryExec, "detection"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerWorkingDirIntegrityDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Hashes the files of the Terraform working directory and exposes a manifest. Passing the manifest of an earlier " +
+			"run (e.g. the plan) as baseline reports the files added, removed or modified since, to prove whether the configuration " +
+			"was tampered with between plan and apply. Symbolic links are not followed, their target path is hashed instead.",
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description: "Directory to hash (default: the Terraform working directory).",
+				Optional:    true,
+			},
+			"exclude": schema.ListAttribute{
+				Description: fmt.Sprintf("Glob patterns of the paths not hashed, matched against the path relative to the directory "+
+					"and against the file name; a matching directory is skipped entirely (default: %s).", strings.Join(defaultIntegrityExcludes, ", ")),
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"baseline": schema.MapAttribute{
+				Description: "Manifest of an earlier run to compare against.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"max_files": schema.Int64Attribute{
+				Description: fmt.Sprintf("Maximum number of files hashed (default: %d).", defaultIntegrityMaxFiles),
+				Optional:    true,
+			},
+			"manifest": schema.MapAttribute{
+				Description: "SHA-256 of each file, keyed by its path relative to the directory, with / separators.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"digest": schema.StringAttribute{
+				Description: "SHA-256 of the manifest, in sha256sum format sorted by path, identifying the whole directory content.",
+				Computed:    true,
+			},
+			"file_count": schema.Int64Attribute{
+				Description: "Number of files hashed.",
+				Computed:    true,
+			},
+			"added": schema.ListAttribute{
+				Description: "Files absent from the baseline, sorted. Empty without baseline.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"removed": schema.ListAttribute{
+				Description: "Files of the baseline that no longer exist, sorted. Empty without baseline.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"modified": schema.ListAttribute{
+				Description: "Files whose hash differs from the baseline, sorted. Empty without baseline.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"changed": schema.BoolAttribute{
+				Description: "True if any file was added, removed or modified since the baseline.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Paths that could not be hashed, if any. The comparison with the baseline is incomplete when set.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read hashes the directory and updates the state.
+func (d *TerrapwnerWorkingDirIntegrityDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerWorkingDirIntegrityDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.Path.IsNull() {
+		wd, err := os.Getwd()
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to get working directory", err.Error())
+			return
+		}
+		data.Path = types.StringValue(wd)
+	}
+	if data.MaxFiles.IsNull() {
+		data.MaxFiles = types.Int64Value(defaultIntegrityMaxFiles)
+	}
+	if data.MaxFiles.ValueInt64() < 0 {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("max_files must not be negative, got: %d", data.MaxFiles.ValueInt64()),
+		)
+		return
+	}
+	excludes := defaultIntegrityExcludes
+	if !data.Exclude.IsNull() {
+		excludes = nil
+		resp.Diagnostics.Append(data.Exclude.ElementsAs(ctx, &excludes, false)...)
+	}
+	baseline := map[string]string{}
+	if !data.Baseline.IsNull() {
+		resp.Diagnostics.Append(data.Baseline.ElementsAs(ctx, &baseline, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	for _, pattern := range excludes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			resp.Diagnostics.AddError(
+				"Invalid configuration",
+				fmt.Sprintf("invalid exclude pattern %q: %v", pattern, err),
+			)
+			return
+		}
+	}
+
+	manifest, failures := hashDirectory(ctx, data.Path.ValueString(), excludes, int(data.MaxFiles.ValueInt64()))
+	added, removed, modified := []string{}, []string{}, []string{}
+	if !data.Baseline.IsNull() {
+		added, removed, modified = compareManifests(baseline, manifest)
+	}
+
+	data.Digest = types.StringValue(manifestDigest(manifest))
+	data.FileCount = types.Int64Value(int64(len(manifest)))
+	data.Changed = types.BoolValue(len(added)+len(removed)+len(modified) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	manifestMap, diags := types.MapValueFrom(ctx, types.StringType, manifest)
+	resp.Diagnostics.Append(diags...)
+	addedList, diags := types.ListValueFrom(ctx, types.StringType, added)
+	resp.Diagnostics.Append(diags...)
+	removedList, diags := types.ListValueFrom(ctx, types.StringType, removed)
+	resp.Diagnostics.Append(diags...)
+	modifiedList, diags := types.ListValueFrom(ctx, types.StringType, modified)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Manifest = manifestMap
+	data.Added = addedList
+	data.Removed = removedList
+	data.Modified = modifiedList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// hashDirectory returns the SHA-256 of the files under root, keyed by their
+// slash-separated relative path, and the paths that could not be hashed.
+func hashDirectory(ctx context.Context, root string, excludes []string, maxFiles int) (map[string]string, []string) {
+	manifest := map[string]string{}
+	var failures []string
+
+	err := filepath.WalkDir(root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", p, err))
+			return nil
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if integrityExcluded(rel, excludes) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if len(manifest) >= maxFiles {
+			return errIntegrityMaxFiles
+		}
+
+		var sum string
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", rel, err))
+				return nil
+			}
+			digest := sha256.Sum256([]byte(target))
+			sum = hex.EncodeToString(digest[:])
+		case entry.Type().IsRegular():
+			sum, err = hashFile(p)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", rel, err))
+				return nil
+			}
+		default:
+			// Sockets, pipes and devices have no content to hash
+			return nil
+		}
+		manifest[rel] = sum
+		return ctx.Err()
+	})
+	if errors.Is(err, errIntegrityMaxFiles) {
+		failures = append(failures, fmt.Sprintf("stopped after hashing %d files", len(manifest)))
+	} else if err != nil {
+		failures = append(failures, fmt.Sprintf("%s: %v", root, err))
+	}
+	return manifest, failures
+}
+
+// hashFile returns the hex-encoded SHA-256 of a file.
+func hashFile(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// integrityExcluded reports whether a relative path matches an exclude pattern,
+// against the whole path or the file name.
+func integrityExcluded(rel string, excludes []string) bool {
+	for _, pattern := range excludes {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// compareManifests returns the sorted paths added, removed and modified in
+// current compared to baseline.
+func compareManifests(baseline, current map[string]string) ([]string, []string, []string) {
+	added, removed, modified := []string{}, []string{}, []string{}
+	for p, sum := range current {
+		previous, ok := baseline[p]
+		switch {
+		case !ok:
+			added = append(added, p)
+		case previous != sum:
+			modified = append(modified, p)
+		}
+	}
+	for p := range baseline {
+		if _, ok := current[p]; !ok {
+			removed = append(removed, p)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+	return added, removed, modified
+}
+
+// manifestDigest returns the SHA-256 of the manifest formatted as the output
+// of sha256sum, sorted by path.
+func manifestDigest(manifest map[string]string) string {
+	paths := make([]string, 0, len(manifest))
+	for p := range manifest {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "%s  %s\n", manifest[p], p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestHashDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.tf":                             `resource "null_resource" "x" {}`,
+		"modules/vpc/main.tf":                 `variable "cidr" {}`,
+		"terraform.tfstate":                   `{}`,
+		".terraform/providers/lock":           `x`,
+		"modules/vpc/.terraform/modules.json": `{}`,
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest, failures := hashDirectory(context.Background(), dir, defaultIntegrityExcludes, defaultIntegrityMaxFiles)
+	if len(failures) != 0 {
+		t.Errorf("unexpected failures: %v", failures)
+	}
+	if len(manifest) != 2 {
+		t.Errorf("got manifest %v, want main.tf and modules/vpc/main.tf only", manifest)
+	}
+	if got, want := manifest["main.tf"], "117a2c3a3a0d572233b44477ee2528fe97a85ca6b6f9b00990653b976c41fdf5"; got != want {
+		t.Errorf("got main.tf hash %q, want %q", got, want)
+	}
+	if _, ok := manifest["modules/vpc/main.tf"]; !ok {
+		t.Errorf("missing modules/vpc/main.tf in manifest %v", manifest)
+	}
+
+	_, failures = hashDirectory(context.Background(), dir, nil, 2)
+	if len(failures) != 1 || failures[0] != "stopped after hashing 2 files" {
+		t.Errorf("got failures %v, want the walk stopped after 2 files", failures)
+	}
+}
+
+func TestCompareManifests(t *testing.T) {
+	baseline := map[string]string{"main.tf": "a", "variables.tf": "b", "outputs.tf": "c"}
+	current := map[string]string{"main.tf": "a", "variables.tf": "x", "backdoor.tf": "d"}
+
+	added, removed, modified := compareManifests(baseline, current)
+	if !reflect.DeepEqual(added, []string{"backdoor.tf"}) {
+		t.Errorf("got added %v", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"outputs.tf"}) {
+		t.Errorf("got removed %v", removed)
+	}
+	if !reflect.DeepEqual(modified, []string{"variables.tf"}) {
+		t.Errorf("got modified %v", modified)
+	}
+
+	if manifestDigest(current) == manifestDigest(baseline) {
+		t.Error("expected different digests for different manifests")
+	}
+}
+
+func TestAccTerrapwnerWorkingDirIntegrityDataSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`resource "null_resource" "x" {}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_working_dir_integrity" "test" {
+  path = %q
+}
+`, dir),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_working_dir_integrity.test", "file_count", "1"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_working_dir_integrity.test", "manifest.main.tf"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_working_dir_integrity.test", "digest"),
+					resource.TestCheckResourceAttr("data.terrapwner_working_dir_integrity.test", "changed", "false"),
+					resource.TestCheckResourceAttr("data.terrapwner_working_dir_integrity.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_working_dir_integrity" "test" {
+  path     = %q
+  exclude  = []
+  baseline = {
+    "main.tf"    = "0000000000000000000000000000000000000000000000000000000000000000"
+    "outputs.tf" = "0000000000000000000000000000000000000000000000000000000000000000"
+  }
+}
+`, dir),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_working_dir_integrity.test", "file_count", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_working_dir_integrity.test", "changed", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_working_dir_integrity.test", "added.0", "terraform.tfstate"),
+					resource.TestCheckResourceAttr("data.terrapwner_working_dir_integrity.test", "removed.0", "outputs.tf"),
+					resource.TestCheckResourceAttr("data.terrapwner_working_dir_integrity.test", "modified.0", "main.tf"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,271 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &TerrapwnerYaraScanDataSource{}
+	_ datasource.DataSourceWithConfigure = &TerrapwnerYaraScanDataSource{}
+)
+
+const (
+	// defaultYaraMaxFileSize is the default size above which files are skipped.
+	defaultYaraMaxFileSize = 16 * 1024 * 1024
+	// defaultYaraMaxFiles is the default number of files scanned.
+	defaultYaraMaxFiles = 10000
+)
+
+// errYaraMaxFiles stops the walk once max_files files were scanned.
+var errYaraMaxFiles = errors.New("maximum number of files reached")
+
+// NewTerrapwnerYaraScanDataSource is a helper function to simplify the provider implementation.
+func NewTerrapwnerYaraScanDataSource() datasource.DataSource {
+	return &TerrapwnerYaraScanDataSource{}
+}
+
+// TerrapwnerYaraScanDataSource is the data source implementation.
+type TerrapwnerYaraScanDataSource struct{}
+
+// TerrapwnerYaraScanDataSourceModel describes the data source data model.
+type TerrapwnerYaraScanDataSourceModel struct {
+	Rules        types.String `tfsdk:"rules"`
+	Paths        types.List   `tfsdk:"paths"`
+	MaxFileSize  types.Int64  `tfsdk:"max_file_size"`
+	MaxFiles     types.Int64  `tfsdk:"max_files"`
+	RulesLoaded  types.Int64  `tfsdk:"rules_loaded"`
+	FilesScanned types.Int64  `tfsdk:"files_scanned"`
+	FilesSkipped types.Int64  `tfsdk:"files_skipped"`
+	Matches      types.List   `tfsdk:"matches"`
+	MatchedRules types.List   `tfsdk:"matched_rules"`
+	MatchFound   types.Bool   `tfsdk:"match_found"`
+	FailReason   types.String `tfsdk:"fail_reason"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *TerrapwnerYaraScanDataSource) Configure(_ context.Context, _ datasource.ConfigureRequest, _ *datasource.ConfigureResponse) {
+	// No configuration needed
+}
+
+// Metadata returns the data source type name.
+func (d *TerrapwnerYaraScanDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_yara_scan"
+}
+
+// Tags returns the categories and tags the data source belongs to.
+func (d *TerrapwnerYaraScanDataSource) Tags() []string {
+	return []string{categoryExec, "detection"}
+}
+
+// Schema defines the schema for the data source.
+func (d *TerrapwnerYaraScanDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Scans files against YARA rules, e.g. to check whether known-bad tooling is already present on a shared runner. " +
+			"Rules are matched by a built-in engine supporting the commonly used subset of the YARA language: text strings (ascii, " +
+			"wide, nocase, fullword and private modifiers), hex strings with wildcards, jumps (of at most 32767 bytes) and " +
+			"alternatives, regular expressions (Go RE2 syntax), and conditions using string references and counts, `of` expressions, filesize, the uint/int functions " +
+			"and previous rules. Modules, includes, global rules, for loops and the xor and base64 modifiers are rejected. " +
+			"Symbolic links and special files are not scanned.",
+		Attributes: map[string]schema.Attribute{
+			"rules": schema.StringAttribute{
+				Description: "YARA rules source, e.g. loaded with file().",
+				Required:    true,
+			},
+			"paths": schema.ListAttribute{
+				Description: "Files or directories to scan, recursively (default: the Terraform working directory, usually the CI workspace).",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"max_file_size": schema.Int64Attribute{
+				Description: fmt.Sprintf("Files larger than this size in bytes are skipped (default: %d).", defaultYaraMaxFileSize),
+				Optional:    true,
+			},
+			"max_files": schema.Int64Attribute{
+				Description: fmt.Sprintf("Maximum number of files scanned (default: %d).", defaultYaraMaxFiles),
+				Optional:    true,
+			},
+			"rules_loaded": schema.Int64Attribute{
+				Description: "Number of rules compiled.",
+				Computed:    true,
+			},
+			"files_scanned": schema.Int64Attribute{
+				Description: "Number of files scanned.",
+				Computed:    true,
+			},
+			"files_skipped": schema.Int64Attribute{
+				Description: "Number of files skipped because they were too large or could not be read.",
+				Computed:    true,
+			},
+			"matches": schema.ListAttribute{
+				Description: "Matches, formatted as `rule path`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"matched_rules": schema.ListAttribute{
+				Description: "Names of the rules matching at least one file.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"match_found": schema.BoolAttribute{
+				Description: "True if at least one file matched a rule.",
+				Computed:    true,
+			},
+			"fail_reason": schema.StringAttribute{
+				Description: "Paths that could not be scanned, if any.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+// Read performs the scan and updates the state.
+func (d *TerrapwnerYaraScanDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data TerrapwnerYaraScanDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if data.MaxFileSize.IsNull() {
+		data.MaxFileSize = types.Int64Value(defaultYaraMaxFileSize)
+	}
+	if data.MaxFiles.IsNull() {
+		data.MaxFiles = types.Int64Value(defaultYaraMaxFiles)
+	}
+
+	var paths []string
+	if data.Paths.IsNull() {
+		wd, err := os.Getwd()
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to get working directory", err.Error())
+			return
+		}
+		paths = []string{wd}
+	} else {
+		resp.Diagnostics.Append(data.Paths.ElementsAs(ctx, &paths, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	rules, err := utils.CompileYaraRules(data.Rules.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("invalid YARA rules: %v", err),
+		)
+		return
+	}
+
+	var failures []string
+	matches := []string{}
+	matchedRules := map[string]bool{}
+	scanned, skipped, unreadable := 0, 0, 0
+	var firstUnreadable error
+
+	scanFile := func(path string, info fs.FileInfo) error {
+		if scanned >= int(data.MaxFiles.ValueInt64()) {
+			return errYaraMaxFiles
+		}
+		if info.Size() > data.MaxFileSize.ValueInt64() {
+			skipped++
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			skipped++
+			unreadable++
+			if firstUnreadable == nil {
+				firstUnreadable = err
+			}
+			return nil
+		}
+		scanned++
+		found, err := rules.Scan(ctx, content)
+		if err != nil {
+			return err
+		}
+		for _, m := range found {
+			matches = append(matches, fmt.Sprintf("%s %s", m.Rule, path))
+			matchedRules[m.Rule] = true
+		}
+		return ctx.Err()
+	}
+
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable directories are reported, the walk continues
+				if path == root {
+					return err
+				}
+				failures = append(failures, fmt.Sprintf("%s: %v", path, err))
+				return nil
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			return scanFile(path, info)
+		})
+		if errors.Is(err, errYaraMaxFiles) {
+			failures = append(failures, fmt.Sprintf("stopped after scanning %d files", scanned))
+			break
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", root, err))
+		}
+	}
+	if unreadable > 0 {
+		failures = append(failures, fmt.Sprintf("%d files could not be read (first error: %v)", unreadable, firstUnreadable))
+	}
+
+	ruleNames := make([]string, 0, len(matchedRules))
+	for name := range matchedRules {
+		ruleNames = append(ruleNames, name)
+	}
+	sort.Strings(ruleNames)
+
+	data.RulesLoaded = types.Int64Value(int64(len(rules.Names())))
+	data.FilesScanned = types.Int64Value(int64(scanned))
+	data.FilesSkipped = types.Int64Value(int64(skipped))
+	data.MatchFound = types.BoolValue(len(matches) > 0)
+	data.FailReason = types.StringValue(strings.Join(failures, "; "))
+
+	// Convert to Terraform types
+	matchesList, diags := types.ListValueFrom(ctx, types.StringType, matches)
+	resp.Diagnostics.Append(diags...)
+	matchedRulesList, diags := types.ListValueFrom(ctx, types.StringType, ruleNames)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Matches = matchesList
+	data.MatchedRules = matchedRulesList
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const testYaraRules = `
+rule credential_dumper : hacktool {
+  strings:
+    $name = "mimikatz" nocase
+    $cmd  = "sekurlsa::logonpasswords"
+  condition:
+    any of them
+}
+
+rule elf_binary {
+  condition:
+    uint32(0) == 0x464c457f
+}
+`
+
+func TestAccTerrapwnerYaraScanDataSource(t *testing.T) {
+	workspace := t.TempDir()
+	tool := filepath.Join(workspace, "tools", "run.ps1")
+	if err := os.MkdirAll(filepath.Dir(tool), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tool, []byte("Invoke-Mimikatz -Command 'sekurlsa::logonpasswords'"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "main.tf"), []byte(`resource "null_resource" "x" {}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	large := filepath.Join(workspace, "large.bin")
+	if err := os.WriteFile(large, append([]byte("\x7fELF"), make([]byte, 2048)...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_yara_scan" "test" {
+  rules         = %q
+  paths         = [%q]
+  max_file_size = 1024
+}
+`, testYaraRules, workspace),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "rules_loaded", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "files_scanned", "2"),
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "files_skipped", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "match_found", "true"),
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "matches.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "matches.0", "credential_dumper "+tool),
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "matched_rules.#", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "fail_reason", ""),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_yara_scan" "test" {
+  rules = %q
+  paths = [%q]
+}
+`, testYaraRules, large),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "files_scanned", "1"),
+					resource.TestCheckResourceAttr("data.terrapwner_yara_scan.test", "matched_rules.0", "elf_binary"),
+				),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+data "terrapwner_yara_scan" "test" {
+  rules = "import \"pe\"\nrule r { condition: pe.number_of_sections > 1 }"
+  paths = [%q]
+}
+`, workspace),
+				ExpectError: regexp.MustCompile("invalid YARA rules"),
+			},
+		},
+	})
+}
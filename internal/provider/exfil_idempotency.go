@@ -0,0 +1,37 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// exfilRunID identifies the exfiltrations of this provider process outside CI.
+var exfilRunID = sync.OnceValue(exfilTransferID)
+
+// exfilIdempotencyKey returns the key identifying the exfiltration of content
+// to endpoint in the current run. In CI, the key is derived from the
+// identifiers of the job, so the plan and apply of a job, or a rerun of it,
+// send the same key and collectors can count the exfiltration once, while a
+// new pipeline run sends a new one. Outside CI, the key is unique to the
+// provider process.
+func exfilIdempotencyKey(snapshot *environmentSnapshot, endpoint string, content []byte) string {
+	run := []string{exfilRunID()}
+	platform := snapshot.CIPlatform()
+	if extract, ok := ciRunInfoExtractors[platform]; ok {
+		if info := extract(snapshot.Getenv); info.RunID != "" {
+			run = []string{platform, info.RunID, info.JobID, info.JobName}
+		}
+	}
+
+	h := sha256.New()
+	for _, field := range append(run, endpoint) {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
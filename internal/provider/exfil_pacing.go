@@ -0,0 +1,132 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// exfilPacer spaces out the chunks and caps the throughput of an
+// exfiltration, to simulate low-and-slow transfers.
+type exfilPacer struct {
+	delay          time.Duration
+	jitter         time.Duration
+	bytesPerSecond int64
+
+	// start and sent track the throughput since the first byte
+	start time.Time
+	sent  int64
+}
+
+// newExfilPacer returns the pacer configured by the delay_ms, jitter_ms and
+// max_bytes_per_second attributes.
+func newExfilPacer(data *TerrapwnerExfilDataSourceModel) *exfilPacer {
+	return &exfilPacer{
+		delay:          time.Duration(data.DelayMs.ValueInt64()) * time.Millisecond,
+		jitter:         time.Duration(data.JitterMs.ValueInt64()) * time.Millisecond,
+		bytesPerSecond: data.MaxBytesPerSec.ValueInt64(),
+	}
+}
+
+// pause waits before every chunk but the first, for the delay plus a random
+// jitter.
+func (p *exfilPacer) pause(ctx context.Context, chunk int) error {
+	if chunk == 0 {
+		return nil
+	}
+	wait := p.delay
+	if p.jitter > 0 {
+		wait += rand.N(p.jitter + 1)
+	}
+	return exfilSleep(ctx, wait)
+}
+
+// throttle records n more bytes sent and waits until the throughput is back
+// under the cap, if any.
+func (p *exfilPacer) throttle(ctx context.Context, n int) error {
+	if p.bytesPerSecond <= 0 {
+		return nil
+	}
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+	p.sent += int64(n)
+	return exfilSleep(ctx, time.Until(p.start.Add(p.transferTime(p.sent))))
+}
+
+// transferTime returns how long n bytes take at the throughput cap, 0 without cap.
+func (p *exfilPacer) transferTime(n int64) time.Duration {
+	if p.bytesPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(n) / float64(p.bytesPerSecond) * float64(time.Second))
+}
+
+// reader returns r throttled to the throughput cap, read in slices of a tenth
+// of a second worth of bytes at most.
+func (p *exfilPacer) reader(ctx context.Context, r io.Reader) io.Reader {
+	if p.bytesPerSecond <= 0 {
+		return r
+	}
+	return &exfilThrottledReader{ctx: ctx, r: r, pacer: p}
+}
+
+// exfilThrottledReader is a reader throttled by a pacer.
+type exfilThrottledReader struct {
+	ctx   context.Context
+	r     io.Reader
+	pacer *exfilPacer
+}
+
+// Read reads a slice of the underlying reader, then waits for the throughput
+// to be back under the cap.
+func (t *exfilThrottledReader) Read(b []byte) (int, error) {
+	if limit := int(max(t.pacer.bytesPerSecond/10, 1)); len(b) > limit {
+		b = b[:limit]
+	}
+	n, err := t.r.Read(b)
+	if n > 0 {
+		if err := t.pacer.throttle(t.ctx, n); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+// exfilProgress logs the progress of an exfiltration chunk by chunk, as pacing
+// can make it long. Chunks are not checkpointed: a resumed run would send
+// the chunks of another transfer ID.
+type exfilProgress struct {
+	progress *operationProgress
+}
+
+// newExfilProgress starts logging the progress of an exfiltration of total chunks.
+func newExfilProgress(ctx context.Context, total int) exfilProgress {
+	return exfilProgress{progress: newOperationProgress(ctx, "exfil", total, "", nil)}
+}
+
+// sent records that the chunk was sent.
+func (p exfilProgress) sent(ctx context.Context, chunk int) {
+	// Without checkpoint, completing a step cannot fail
+	_ = p.progress.Complete(ctx, strconv.Itoa(chunk+1), nil)
+}
+
+// exfilSleep waits for d, or until the context is done.
+func exfilSleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
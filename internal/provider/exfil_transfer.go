@@ -0,0 +1,409 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	// exfilFTPAnonymousUser is the user logging in when the ftp endpoint has none.
+	exfilFTPAnonymousUser = "anonymous"
+
+	// sftpWriteSize is the number of bytes per SFTP write request, within the
+	// 32 KiB packet size every server accepts.
+	sftpWriteSize = 32 * 1024
+)
+
+// SFTP version 3 packet types.
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpOpen    = 3
+	sftpClose   = 4
+	sftpWrite   = 6
+	sftpStatus  = 101
+	sftpHandle  = 102
+)
+
+// exfilTransferDefaultPorts maps the file transfer modes to their default port.
+var exfilTransferDefaultPorts = map[string]string{
+	exfilModeSFTP: "22",
+	exfilModeFTP:  "21",
+}
+
+// ftpPassivePattern extracts the address of a PASV reply.
+var ftpPassivePattern = regexp.MustCompile(`(\d+),(\d+),(\d+),(\d+),(\d+),(\d+)`)
+
+// ftpExtendedPassivePattern extracts the port of an EPSV reply.
+var ftpExtendedPassivePattern = regexp.MustCompile(`\|\|\|(\d+)\|`)
+
+// readFileTransfer uploads the content to an SFTP or FTP server and updates the state.
+func (d *TerrapwnerExfilDataSource) readFileTransfer(ctx context.Context, data *TerrapwnerExfilDataSourceModel, user, address, path string,
+	content []byte, network string, timeout time.Duration, resp *datasource.ReadResponse) {
+	data.ResponseCode = types.Int64Value(0)
+
+	// Complete directories with a random name, so repeated runs do not overwrite each other
+	if path == "" || strings.HasSuffix(path, "/") {
+		path += "terrapwner-" + exfilTransferID()
+	}
+
+	conn, err := dialExfilTransfer(ctx, network, address, timeout)
+	var sent int
+	if err == nil {
+		data.RemoteAddress = types.StringValue(conn.RemoteAddr().String())
+		if data.Mode.ValueString() == exfilModeSFTP {
+			sent, err = uploadSFTP(conn, address, user, data.Password.ValueString(), data.PrivateKey.ValueString(), path, content, timeout)
+		} else {
+			sent, err = uploadFTP(ctx, conn, network, user, data.Password.ValueString(), path, content, timeout)
+		}
+		conn.Close()
+	} else {
+		data.RemoteAddress = types.StringValue("")
+	}
+	data.BytesTransferred = types.Int64Value(int64(sent))
+	if err != nil {
+		data.Success = types.BoolValue(false)
+		data.FailReason = types.StringValue(fmt.Sprintf("%s exfiltration failed: %v", strings.ToUpper(data.Mode.ValueString()), err))
+
+		// If we expect success but didn't get it, add an error
+		if data.ExpectSuccess.ValueBool() {
+			resp.Diagnostics.AddError(
+				"Exfiltration Failed",
+				fmt.Sprintf("Expected successful exfiltration but %s", data.FailReason.ValueString()),
+			)
+			return
+		}
+		resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+		return
+	}
+
+	data.Success = types.BoolValue(true)
+	data.FailReason = types.StringValue("")
+	data.ChunksSent = types.Int64Value(1)
+	data.ObjectKey = types.StringValue(path)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+// dialExfilTransfer connects to a file transfer server, by the canonical
+// address of obfuscated IP literals.
+func dialExfilTransfer(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if ip, ok := utils.ParseIPLiteral(host); ok {
+		host = ip.String()
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// uploadSFTP logs in over SSH with a password or a private key, without
+// verifying the host key, and writes the content to the path through the SFTP
+// subsystem. It returns the number of bytes the server acknowledged.
+func uploadSFTP(conn net.Conn, address, user, password, privateKey, path string, content []byte, timeout time.Duration) (int, error) {
+	var auth []ssh.AuthMethod
+	if privateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+		if err != nil {
+			return 0, fmt.Errorf("invalid private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if password != "" {
+		auth = append(auth, ssh.Password(password), ssh.KeyboardInteractive(
+			func(_, _ string, questions []string, _ []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+				return answers, nil
+			}))
+	}
+	config := &ssh.ClientConfig{
+		User: user,
+		Auth: auth,
+		// Exfiltration endpoints are not known hosts
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		ClientVersion:   "SSH-2.0-" + strings.ReplaceAll(utils.GetUserAgent(), " ", "_"),
+		Timeout:         timeout,
+	}
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		return 0, err
+	}
+	client := ssh.NewClient(sshConn, channels, requests)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return 0, err
+	}
+	defer session.Close()
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return 0, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return 0, fmt.Errorf("sftp subsystem unavailable: %w", err)
+	}
+	s := &sftpClient{w: stdin, r: stdout}
+
+	if err := s.send(sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return 0, err
+	}
+	if packetType, _, err := s.receive(); err != nil {
+		return 0, err
+	} else if packetType != sftpVersion {
+		return 0, fmt.Errorf("unexpected SFTP packet type %d", packetType)
+	}
+
+	// Open for writing, creating or truncating the file
+	open := sftpString(nil, path)
+	open = binary.BigEndian.AppendUint32(open, 0x02|0x08|0x10)
+	open = binary.BigEndian.AppendUint32(open, 0) // no attributes
+	handle, err := s.call(sftpOpen, open)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", path, err)
+	}
+
+	sent := 0
+	for offset := 0; offset < len(content); offset += sftpWriteSize {
+		chunk := content[offset:min(offset+sftpWriteSize, len(content))]
+		write := sftpString(nil, string(handle))
+		write = binary.BigEndian.AppendUint64(write, uint64(offset))
+		write = sftpString(write, string(chunk))
+		if _, err := s.call(sftpWrite, write); err != nil {
+			return sent, fmt.Errorf("write %s: %w", path, err)
+		}
+		sent += len(chunk)
+	}
+	if _, err := s.call(sftpClose, sftpString(nil, string(handle))); err != nil {
+		return sent, fmt.Errorf("close %s: %w", path, err)
+	}
+	return sent, nil
+}
+
+// sftpClient exchanges SFTP version 3 packets, one request at a time.
+type sftpClient struct {
+	w      io.Writer
+	r      io.Reader
+	nextID uint32
+}
+
+// call sends a request and returns the handle of a HANDLE response, or nil
+// for a successful STATUS response.
+func (s *sftpClient) call(packetType byte, payload []byte) ([]byte, error) {
+	s.nextID++
+	if err := s.send(packetType, append(binary.BigEndian.AppendUint32(nil, s.nextID), payload...)); err != nil {
+		return nil, err
+	}
+	responseType, response, err := s.receive()
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < 4 || binary.BigEndian.Uint32(response) != s.nextID {
+		return nil, errors.New("unexpected SFTP response")
+	}
+	response = response[4:]
+	switch {
+	case responseType == sftpHandle && len(response) >= 4:
+		size := int(binary.BigEndian.Uint32(response))
+		if size > len(response)-4 {
+			return nil, errors.New("short SFTP handle")
+		}
+		return response[4 : 4+size], nil
+	case responseType == sftpStatus && len(response) >= 4:
+		code := binary.BigEndian.Uint32(response)
+		if code == 0 {
+			return nil, nil
+		}
+		message := ""
+		if len(response) >= 8 {
+			size := int(binary.BigEndian.Uint32(response[4:]))
+			if size <= len(response)-8 {
+				message = string(response[8 : 8+size])
+			}
+		}
+		return nil, fmt.Errorf("SFTP status %d: %s", code, message)
+	default:
+		return nil, fmt.Errorf("unexpected SFTP packet type %d", responseType)
+	}
+}
+
+func (s *sftpClient) send(packetType byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, packetType)
+	_, err := s.w.Write(append(packet, payload...))
+	return err
+}
+
+func (s *sftpClient) receive() (byte, []byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(s.r, length[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if size == 0 || size > 256*1024 {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length %d", size)
+	}
+	packet := make([]byte, size)
+	if _, err := io.ReadFull(s.r, packet); err != nil {
+		return 0, nil, err
+	}
+	return packet[0], packet[1:], nil
+}
+
+// sftpString appends an SSH string.
+func sftpString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// uploadFTP logs in, stores the content to the path over a passive data
+// connection in binary mode, and returns the number of bytes written to it.
+func uploadFTP(ctx context.Context, conn net.Conn, network, user, password, path string, content []byte, timeout time.Duration) (int, error) {
+	control := textproto.NewConn(conn)
+	if _, _, err := control.ReadResponse(220); err != nil {
+		return 0, err
+	}
+	if password == "" && user == exfilFTPAnonymousUser {
+		password = "terrapwner@"
+	}
+
+	code, _, err := ftpCommand(control, 0, "USER %s", user)
+	if err != nil {
+		return 0, err
+	}
+	if code == 331 {
+		if _, _, err := ftpCommand(control, 230, "PASS %s", password); err != nil {
+			return 0, err
+		}
+	} else if code != 230 {
+		return 0, fmt.Errorf("login refused with %d", code)
+	}
+	if _, _, err := ftpCommand(control, 200, "TYPE I"); err != nil {
+		return 0, err
+	}
+
+	// Prefer extended passive mode, which also works over IPv6
+	host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	var dataAddress string
+	if _, message, err := ftpCommand(control, 229, "EPSV"); err == nil {
+		match := ftpExtendedPassivePattern.FindStringSubmatch(message)
+		if match == nil {
+			return 0, fmt.Errorf("invalid EPSV reply: %s", message)
+		}
+		dataAddress = net.JoinHostPort(host, match[1])
+	} else if _, message, err := ftpCommand(control, 227, "PASV"); err == nil {
+		// Connect to the control host, as servers behind NAT advertise private addresses
+		match := ftpPassivePattern.FindStringSubmatch(message)
+		if match == nil {
+			return 0, fmt.Errorf("invalid PASV reply: %s", message)
+		}
+		high, _ := strconv.Atoi(match[5])
+		low, _ := strconv.Atoi(match[6])
+		dataAddress = net.JoinHostPort(host, strconv.Itoa(high<<8|low))
+	} else {
+		return 0, err
+	}
+	dataConn, err := dialExfilTransfer(ctx, network, dataAddress, timeout)
+	if err != nil {
+		return 0, fmt.Errorf("data connection: %w", err)
+	}
+	defer dataConn.Close()
+
+	if _, _, err := ftpCommand(control, 1, "STOR %s", path); err != nil {
+		return 0, err
+	}
+	sent, err := dataConn.Write(content)
+	if closeErr := dataConn.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return sent, err
+	}
+	if _, _, err := control.ReadResponse(2); err != nil {
+		return sent, err
+	}
+	_, _, _ = ftpCommand(control, 221, "QUIT")
+	return sent, nil
+}
+
+// ftpCommand sends a command and reads its reply, expecting the given code
+// or class of codes (e.g. 1 for 1xx), or returning any code if 0.
+func ftpCommand(control *textproto.Conn, expectCode int, format string, args ...any) (int, string, error) {
+	id, err := control.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	control.StartResponse(id)
+	defer control.EndResponse(id)
+	if expectCode == 0 {
+		code, message, err := control.ReadResponse(0)
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) {
+			return protoErr.Code, protoErr.Msg, nil
+		}
+		return code, message, err
+	}
+	return control.ReadResponse(expectCode)
+}
+
+// parseFileTransferURL returns the user, host:port address and path of an
+// sftp:// or ftp:// endpoint. The path is relative to the login directory,
+// unless it starts with a double slash.
+func parseFileTransferURL(rawURL, mode string) (string, string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", "", err
+	}
+	if u.Scheme != mode || u.Hostname() == "" {
+		return "", "", "", fmt.Errorf("expected a %s://user@host/path URL", mode)
+	}
+	user := u.User.Username()
+	if user == "" {
+		if mode == exfilModeSFTP {
+			return "", "", "", errors.New("expected a user")
+		}
+		user = exfilFTPAnonymousUser
+	}
+	port := u.Port()
+	if port == "" {
+		port = exfilTransferDefaultPorts[mode]
+	}
+	return user, net.JoinHostPort(u.Hostname(), port), strings.TrimPrefix(u.Path, "/"), nil
+}
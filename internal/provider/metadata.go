@@ -0,0 +1,199 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+)
+
+// Instance metadata service endpoints. These are variables so tests can point
+// them at local servers.
+var (
+	ec2MetadataEndpoint   = "http://169.254.169.254"
+	gceMetadataEndpoint   = "http://metadata.google.internal"
+	azureMetadataEndpoint = "http://169.254.169.254"
+)
+
+// dmiDir is where Linux exposes the SMBIOS fields of the host. This is a
+// variable so tests can point it at a local directory.
+var dmiDir = "/sys/class/dmi/id"
+
+// cloudDMIMarkers are lowercase substrings of the SMBIOS fields of AWS (Nitro
+// and Xen), GCP and Azure instances. The last one is the Azure chassis asset tag.
+var cloudDMIMarkers = []string{"amazon", "google", "microsoft", "7783-7084-3265-9085-8269-3286-77"}
+
+const (
+	// metadataProbeTimeout keeps cloud detection fast on hosts without a metadata service.
+	metadataProbeTimeout = 2 * time.Second
+
+	// azureMetadataAPIVersion is the Azure Instance Metadata Service API version.
+	azureMetadataAPIVersion = "2021-02-01"
+
+	// azureIdentityAPIVersion is the API version of the managed identity token endpoint.
+	azureIdentityAPIVersion = "2018-02-01"
+)
+
+// ec2MetadataToken requests an IMDSv2 session token.
+func ec2MetadataToken(ctx context.Context, timeout time.Duration) (string, error) {
+	headers := map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "21600"}
+	resp, err := utils.HTTPRequest(ctx, "PUT", ec2MetadataEndpoint+"/latest/api/token", headers, nil, timeout)
+	if err != nil {
+		return "", err
+	}
+	if !resp.IsSuccess() {
+		return "", fmt.Errorf("IMDSv2 token request returned HTTP %d", resp.StatusCode)
+	}
+	return string(resp.Body), nil
+}
+
+// ec2MetadataGet reads an EC2 metadata path, using the IMDSv2 token when one is provided.
+func ec2MetadataGet(ctx context.Context, path string, token string, timeout time.Duration) (string, error) {
+	headers := map[string]string{}
+	if token != "" {
+		headers["X-aws-ec2-metadata-token"] = token
+	}
+	resp, err := utils.HTTPRequest(ctx, "GET", ec2MetadataEndpoint+path, headers, nil, timeout)
+	if err != nil {
+		return "", err
+	}
+	if !resp.IsSuccess() {
+		return "", fmt.Errorf("EC2 metadata %s returned HTTP %d", path, resp.StatusCode)
+	}
+	return string(resp.Body), nil
+}
+
+// gceMetadataGet reads a GCE metadata server path.
+func gceMetadataGet(ctx context.Context, path string, timeout time.Duration) (string, error) {
+	headers := map[string]string{"Metadata-Flavor": "Google"}
+	resp, err := utils.HTTPRequest(ctx, "GET", gceMetadataEndpoint+path, headers, nil, timeout)
+	if err != nil {
+		return "", err
+	}
+	if !resp.IsSuccess() || resp.Header.Get("Metadata-Flavor") != "Google" {
+		return "", fmt.Errorf("GCE metadata %s returned HTTP %d", path, resp.StatusCode)
+	}
+	return string(resp.Body), nil
+}
+
+// azureMetadataGet reads an Azure Instance Metadata Service path. The default
+// API version is added unless the path sets one.
+func azureMetadataGet(ctx context.Context, path string, timeout time.Duration) (string, error) {
+	url := azureMetadataEndpoint + path
+	if !strings.Contains(path, "api-version=") {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		url += separator + "api-version=" + azureMetadataAPIVersion
+	}
+	resp, err := utils.HTTPRequest(ctx, "GET", url, map[string]string{"Metadata": "true"}, nil, timeout)
+	if err != nil {
+		return "", err
+	}
+	if !resp.IsSuccess() {
+		return "", fmt.Errorf("azure metadata %s returned HTTP %d", path, resp.StatusCode)
+	}
+	return string(resp.Body), nil
+}
+
+// mayBeCloudInstance reports whether the host hardware can be a cloud instance,
+// according to its SMBIOS fields. It returns true when the fields cannot be read
+// (e.g., outside Linux), so that only known non-cloud hosts skip the metadata
+// probes, which would otherwise wait for their timeout.
+func mayBeCloudInstance() bool {
+	if runtime.GOOS != "linux" {
+		return true
+	}
+
+	readable := false
+	for _, field := range []string{"sys_vendor", "product_name", "bios_vendor", "bios_version", "chassis_asset_tag"} {
+		content, err := os.ReadFile(filepath.Join(dmiDir, field))
+		if err != nil {
+			continue
+		}
+		readable = true
+		value := strings.ToLower(string(content))
+		for _, marker := range cloudDMIMarkers {
+			if strings.Contains(value, marker) {
+				return true
+			}
+		}
+	}
+	return !readable
+}
+
+// detectMetadataProvider probes the EC2, GCE and Azure metadata services
+// concurrently and returns the detected cloud provider and region.
+func detectMetadataProvider(ctx context.Context) (string, string) {
+	type detection struct {
+		provider string
+		region   string
+	}
+
+	probes := []func() (detection, bool){
+		func() (detection, bool) {
+			// Fall back to IMDSv1 without a token, e.g. when the token response
+			// is dropped by the hop limit in containers or IMDSv2 is unavailable
+			token, err := ec2MetadataToken(ctx, metadataProbeTimeout)
+			if err != nil {
+				token = ""
+			}
+			region, err := ec2MetadataGet(ctx, "/latest/meta-data/placement/region", token, metadataProbeTimeout)
+			if err != nil {
+				return detection{}, false
+			}
+			return detection{"aws", strings.TrimSpace(region)}, true
+		},
+		func() (detection, bool) {
+			// Zone is in format "projects/<number>/zones/<region>-<zone>"
+			zone, err := gceMetadataGet(ctx, "/computeMetadata/v1/instance/zone", metadataProbeTimeout)
+			if err != nil {
+				return detection{}, false
+			}
+			zone = zone[strings.LastIndex(zone, "/")+1:]
+			region := zone
+			if i := strings.LastIndex(zone, "-"); i > 0 {
+				region = zone[:i]
+			}
+			return detection{"gcp", region}, true
+		},
+		func() (detection, bool) {
+			location, err := azureMetadataGet(ctx, "/metadata/instance/compute/location?format=text", metadataProbeTimeout)
+			if err != nil {
+				return detection{}, false
+			}
+			return detection{"azure", strings.TrimSpace(location)}, true
+		},
+	}
+
+	// Results are kept in probe order so AWS wins over GCP, which wins over Azure
+	results := make([]*detection, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe func() (detection, bool)) {
+			defer wg.Done()
+			if d, ok := probe(); ok {
+				results[i] = &d
+			}
+		}(i, probe)
+	}
+	wg.Wait()
+
+	for _, d := range results {
+		if d != nil {
+			return d.provider, d.region
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,194 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// progressLogStep is the percentage of completion between two progress logs.
+const progressLogStep = 10
+
+// operationProgress tracks a long-running operation made of independent steps.
+// Progress is logged through tflog as steps complete. When a checkpoint file is
+// configured, the result of each completed step is saved to it, so that a run
+// interrupted (e.g. a cancelled plan) resumes from the checkpoint instead of
+// restarting from zero. The checkpoint is only reused by runs of the same
+// operation with the same inputs, and is removed once the operation completes.
+type operationProgress struct {
+	mu        sync.Mutex
+	operation string
+	total     int
+	completed int
+	logged    int
+	path      string
+	state     operationCheckpoint
+}
+
+// operationCheckpoint is the on-disk format of a checkpoint.
+type operationCheckpoint struct {
+	Operation   string                     `json:"operation"`
+	Fingerprint string                     `json:"fingerprint"`
+	Steps       map[string]json.RawMessage `json:"steps"`
+}
+
+// newOperationProgress starts tracking an operation of total steps. An empty
+// checkpointPath disables checkpointing; an unreadable or mismatched checkpoint
+// is ignored.
+func newOperationProgress(ctx context.Context, operation string, total int, checkpointPath string, inputs any) *operationProgress {
+	p := &operationProgress{
+		operation: operation,
+		total:     total,
+		path:      checkpointPath,
+		state: operationCheckpoint{
+			Operation:   operation,
+			Fingerprint: checkpointFingerprint(inputs),
+			Steps:       map[string]json.RawMessage{},
+		},
+	}
+	if p.path == "" {
+		return p
+	}
+
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			tflog.Warn(ctx, "Ignoring unreadable checkpoint", map[string]any{"operation": operation, "path": p.path, "error": err.Error()})
+		}
+		return p
+	}
+	var saved operationCheckpoint
+	switch {
+	case json.Unmarshal(content, &saved) != nil:
+		tflog.Warn(ctx, "Ignoring invalid checkpoint", map[string]any{"operation": operation, "path": p.path})
+	case saved.Operation != operation || saved.Fingerprint != p.state.Fingerprint:
+		tflog.Info(ctx, "Ignoring checkpoint of a different operation", map[string]any{"operation": operation, "path": p.path})
+	default:
+		if saved.Steps != nil {
+			p.state.Steps = saved.Steps
+		}
+		tflog.Info(ctx, "Resuming from checkpoint", map[string]any{"operation": operation, "path": p.path, "completed": len(saved.Steps)})
+	}
+	return p
+}
+
+// Resume loads the result of a step completed by a previous run into result
+// and reports whether it was found.
+func (p *operationProgress) Resume(ctx context.Context, step string, result any) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	saved, ok := p.state.Steps[step]
+	if !ok || json.Unmarshal(saved, result) != nil {
+		return false
+	}
+	p.advance(ctx)
+	return true
+}
+
+// Complete records the result of a step and saves the checkpoint. Steps
+// completing after ctx is cancelled may be partial and are not recorded. Only
+// the first checkpoint write error is returned; checkpointing stops after it.
+func (p *operationProgress) Complete(ctx context.Context, step string, result any) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.advance(ctx)
+	if p.path == "" {
+		return nil
+	}
+
+	encoded, err := json.Marshal(result)
+	if err == nil {
+		p.state.Steps[step] = encoded
+		err = p.save()
+	}
+	if err != nil {
+		p.path = ""
+		return fmt.Errorf("checkpoint %s: %w", p.operation, err)
+	}
+	return nil
+}
+
+// Finish removes the checkpoint once every step has run. It is kept if ctx
+// was cancelled, so the next run resumes.
+func (p *operationProgress) Finish(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ctx.Err() != nil {
+		tflog.Info(ctx, "Operation interrupted", map[string]any{"operation": p.operation, "completed": p.completed, "total": p.total})
+		return nil
+	}
+	if p.path == "" {
+		return nil
+	}
+	if err := os.Remove(p.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove checkpoint %s: %w", p.operation, err)
+	}
+	return nil
+}
+
+// advance counts a completed step and logs the progress every progressLogStep percent.
+func (p *operationProgress) advance(ctx context.Context) {
+	p.completed++
+	percent := 100
+	if p.total > 0 {
+		percent = p.completed * 100 / p.total
+	}
+	if percent < p.logged+progressLogStep && p.completed != p.total {
+		return
+	}
+	p.logged = percent - percent%progressLogStep
+	tflog.Info(ctx, "Operation progress", map[string]any{
+		"operation": p.operation,
+		"completed": p.completed,
+		"total":     p.total,
+		"percent":   percent,
+	})
+}
+
+// save atomically writes the checkpoint file.
+func (p *operationProgress) save() error {
+	encoded, err := json.Marshal(p.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), filepath.Base(p.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p.path)
+}
+
+// checkpointFingerprint identifies the inputs of an operation, so a checkpoint
+// is not reused by a run with different inputs.
+func checkpointFingerprint(inputs any) string {
+	encoded, _ := json.Marshal(inputs)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
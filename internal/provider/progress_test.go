@@ -0,0 +1,58 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOperationProgress_Checkpoint(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	inputs := []string{"10.0.0.0/30"}
+
+	// First run, interrupted after two of three steps
+	progress := newOperationProgress(ctx, "scan", 3, path, inputs)
+	for _, step := range []string{"10.0.0.1", "10.0.0.2"} {
+		if err := progress.Complete(ctx, step, "open"); err != nil {
+			t.Fatalf("Complete(%s) error: %v", step, err)
+		}
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := progress.Complete(cancelled, "10.0.0.3", "partial"); err != nil {
+		t.Fatalf("Complete() after cancellation error: %v", err)
+	}
+	if err := progress.Finish(cancelled); err != nil {
+		t.Fatalf("Finish() after cancellation error: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("checkpoint not kept after cancellation: %v", err)
+	}
+
+	// A run with different inputs starts from zero
+	var result string
+	if newOperationProgress(ctx, "scan", 3, path, []string{"10.0.1.0/30"}).Resume(ctx, "10.0.0.1", &result) {
+		t.Errorf("Resume() used the checkpoint of different inputs")
+	}
+
+	// A run with the same inputs resumes the completed steps only
+	progress = newOperationProgress(ctx, "scan", 3, path, inputs)
+	if !progress.Resume(ctx, "10.0.0.2", &result) || result != "open" {
+		t.Errorf("Resume(10.0.0.2) = %q, want checkpointed result %q", result, "open")
+	}
+	if progress.Resume(ctx, "10.0.0.3", &result) {
+		t.Errorf("Resume(10.0.0.3) found a step completed after cancellation")
+	}
+	if err := progress.Finish(ctx); err != nil {
+		t.Fatalf("Finish() error: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("checkpoint not removed after completion: %v", err)
+	}
+}
@@ -6,6 +6,8 @@ package provider
 import (
 	"context"
 
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
@@ -30,7 +32,13 @@ type Terrapwner struct {
 
 // TerrapwnerProviderModel describes the provider data model.
 type TerrapwnerProviderModel struct {
-	FailOnError types.Bool `tfsdk:"fail_on_error"`
+	FailOnError       types.Bool   `tfsdk:"fail_on_error"`
+	EnabledCategories types.List   `tfsdk:"enabled_categories"`
+	SkipTags          types.List   `tfsdk:"skip_tags"`
+	SkipCloudCalls    types.Bool   `tfsdk:"skip_cloud_calls"`
+	LogFile           types.String `tfsdk:"log_file"`
+	MinInterval       types.Int64  `tfsdk:"min_interval_between_actions"`
+	ActionsPerMinute  types.Int64  `tfsdk:"actions_per_minute"`
 }
 
 func (p *Terrapwner) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -48,15 +56,83 @@ func (p *Terrapwner) Schema(ctx context.Context, req provider.SchemaRequest, res
 				Description: "Whether to fail on any error (download or execution). If false, the provider will continue with default values.",
 				Optional:    true,
 			},
+			"enabled_categories": schema.ListAttribute{
+				Description: "Categories of data sources to run (network, exec, exfil, cloud). Data sources outside these " +
+					"categories are skipped and their computed attributes left null; data sources in no category always run. " +
+					"All categories run if unset.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"skip_tags": schema.ListAttribute{
+				Description: "Data sources with any of these categories or tags (e.g. aws, gcp, azure, ci, credentials, write) are skipped.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"skip_cloud_calls": schema.BoolAttribute{
+				Description: "Whether to skip the cloud API and instance metadata calls made to resolve the cloud identity " +
+					"(terrapwner_identity), for offline or air-gapped runners. The cloud provider is then only detected from the " +
+					"AWS environment variables and the caller fields are left unknown. Use enabled_categories to skip the cloud " +
+					"data sources altogether. Defaults to false.",
+				Optional: true,
+			},
+			"log_file": schema.StringAttribute{
+				Description: "Path of a JSON Lines file to append a record to for every command execution, download and HTTP " +
+					"request of the data sources and resources, with the operation, target, duration_ms, outcome (success, " +
+					"failure or error) and operation-specific details. The same fields are always logged with TF_LOG. URL " +
+					"targets and errors are stripped of their credentials and query, and command targets of their arguments. " +
+					"Cloud API calls made through the AWS SDK, Google credentials and gRPC clients, and raw socket probes, are " +
+					"not recorded.",
+				Optional: true,
+			},
+			"min_interval_between_actions": schema.Int64Attribute{
+				Description: "Minimum interval in seconds between the starts of two side-effecting data sources, i.e. those " +
+					"in a category or tagged write, or terrapwner_scenario steps, across the whole run. They wait for their turn, " +
+					"so exercises can run in production-adjacent environments without tripping rate or availability " +
+					"thresholds. Disabled if unset.",
+				Optional: true,
+			},
+			"actions_per_minute": schema.Int64Attribute{
+				Description: "Maximum number of side-effecting data sources and scenario steps starting in any minute of the run, the others " +
+					"waiting for their turn. Combines with min_interval_between_actions. Disabled if unset.",
+				Optional: true,
+			},
 		},
 	}
 }
 
 func (p *Terrapwner) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config TerrapwnerProviderModel
+
+	// Read Terraform provider configuration into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Share a single view of the environment with all data sources
+	snapshot := newEnvironmentSnapshot()
+	snapshot.selection = newSuiteSelection(ctx, config, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	snapshot.skipCloudCalls = config.SkipCloudCalls.ValueBool()
+	snapshot.limiter = newActionLimiter(config, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	utils.SetOperationLogFile(config.LogFile.ValueString())
+	resp.DataSourceData = snapshot
+	resp.ResourceData = snapshot
 }
 
+// Resources defines the resources implemented in the provider.
 func (p *Terrapwner) Resources(ctx context.Context) []func() resource.Resource {
-	return nil
+	return []func() resource.Resource{
+		NewTerrapwnerPlanApplyConsistencyResource,
+		NewTerrapwnerConfigDriftCanaryResource,
+		NewTerrapwnerBurnNoticeResource,
+		NewTerrapwnerScenarioResource,
+	}
 }
 
 func (p *Terrapwner) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
@@ -65,15 +141,58 @@ func (p *Terrapwner) EphemeralResources(ctx context.Context) []func() ephemeral.
 
 // DataSources defines the data sources implemented in the provider.
 func (p *Terrapwner) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{
+	return withSuiteSelection(
 		NewTerrapwnerEnvDumpDataSource,
 		NewTerrapwnerRemoteExecDataSource,
 		NewTerrapwnerExfilDataSource,
 		NewTerrapwnerIdentityDataSource,
 		NewTerrapwnerLocalExecDataSource,
 		NewTerrapwnerNetworkProbeDataSource,
+		NewTerrapwnerNTLMRelayProbeDataSource,
 		NewTerrapwnerTfstateDataSource,
-	}
+		NewTerrapwnerAzureDevOpsProbeDataSource,
+		NewTerrapwnerJenkinsProbeDataSource,
+		NewTerrapwnerSpaceliftAtlantisProbeDataSource,
+		NewTerrapwnerCleanupVerifyDataSource,
+		NewTerrapwnerOIDCTokenDataSource,
+		NewTerrapwnerCertificateRequestProbeDataSource,
+		NewTerrapwnerCIDetectDataSource,
+		NewTerrapwnerRoute53DNSTakeoverProbeDataSource,
+		NewTerrapwnerIMDSDataSource,
+		NewTerrapwnerQuotaProbeDataSource,
+		NewTerrapwnerGCPMetadataDataSource,
+		NewTerrapwnerAzureIMDSDataSource,
+		NewTerrapwnerResultsDataSource,
+		NewTerrapwnerIMDSReachabilityDataSource,
+		NewTerrapwnerNotifyDataSource,
+		NewTerrapwnerMemoryScrapeProbeDataSource,
+		NewTerrapwnerCoredumpProbeDataSource,
+		NewTerrapwnerSwapAndTmpfsProbeDataSource,
+		NewTerrapwnerYaraScanDataSource,
+		NewTerrapwnerOsqueryBridgeDataSource,
+		NewTerrapwnerWorkingDirIntegrityDataSource,
+		NewTerrapwnerHTTPServerProbeDataSource,
+		NewTerrapwnerEtcdConsulProbeDataSource,
+		NewTerrapwnerNFSShareProbeDataSource,
+		NewTerrapwnerSnapshotProbeDataSource,
+		NewTerrapwnerStorageExfilShareDataSource,
+		NewTerrapwnerHTTPSmuggleProbeDataSource,
+		NewTerrapwnerGitExfilDataSource,
+		NewTerrapwnerCachePoisonProbeDataSource,
+		NewTerrapwnerRunnerReuseDetectorDataSource,
+		NewTerrapwnerClockSkewDataSource,
+		NewTerrapwnerHTTPAuthReplayDataSource,
+		NewTerrapwnerJWTForgeProbeDataSource,
+		NewTerrapwnerPortscanDataSource,
+		NewTerrapwnerGRPCProbeDataSource,
+		NewTerrapwnerProbeSetDataSource,
+		NewTerrapwnerMQProbeDataSource,
+		NewTerrapwnerPolicyAssertDataSource,
+		NewTerrapwnerEgressAssessmentDataSource,
+		NewTerrapwnerPublicIPDataSource,
+		NewTerrapwnerTorProbeDataSource,
+		NewTerrapwnerRegionLatencyDataSource,
+	)
 }
 
 func (p *Terrapwner) Functions(ctx context.Context) []func() function.Function {
@@ -4,6 +4,13 @@
 package provider
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
@@ -41,3 +48,64 @@ func testAccPreCheck(t *testing.T) {
 	// about the appropriate environment variables being set are common to see in a pre-check
 	// function.
 }
+
+// testAccSetMetadataEndpoints points the instance metadata endpoints at the given
+// URL (e.g., a local test server) for the duration of the test. An unreachable
+// URL disables cloud detection through the metadata services. The host hardware
+// is hidden so that the metadata services are probed on any test machine.
+func testAccSetMetadataEndpoints(t *testing.T, url string) {
+	t.Helper()
+
+	originalEC2, originalGCE, originalAzure, originalDMI := ec2MetadataEndpoint, gceMetadataEndpoint, azureMetadataEndpoint, dmiDir
+	ec2MetadataEndpoint, gceMetadataEndpoint, azureMetadataEndpoint = url, url, url
+	dmiDir = filepath.Join(t.TempDir(), "dmi")
+	t.Cleanup(func() {
+		ec2MetadataEndpoint, gceMetadataEndpoint, azureMetadataEndpoint, dmiDir = originalEC2, originalGCE, originalAzure, originalDMI
+	})
+}
+
+// testAccSetAWSStaticCredentials isolates the AWS SDK from the host configuration
+// and gives it static credentials, for tests pointing service endpoints at local
+// servers through the AWS_ENDPOINT_URL_<SERVICE> variables.
+func testAccSetAWSStaticCredentials(t *testing.T) {
+	t.Helper()
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	t.Setenv("AWS_CONFIG_FILE", missing)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", missing)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+}
+
+// testAccSetGCPServiceAccount makes the Google Application Default Credentials a
+// service account key with a new private key, whose access tokens are requested
+// from tokenURL.
+func testAccSetGCPServiceAccount(t *testing.T, tokenURL string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	credentials, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "terrapwner-test",
+		"private_key_id": "0123456789",
+		"private_key":    string(keyPEM),
+		"client_email":   "ci@terrapwner-test.iam.gserviceaccount.com",
+		"client_id":      "123456789",
+		"token_uri":      tokenURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(path, credentials, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+}
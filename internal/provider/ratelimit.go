@@ -0,0 +1,100 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// actionLimiter paces the side-effecting actions of a provider run, shared by
+// all data sources, with a minimum interval between the starts of two actions
+// and a maximum number of starts in any minute. Actions reserve their start
+// under the lock, so concurrent data sources are spread out too.
+type actionLimiter struct {
+	minInterval      time.Duration
+	actionsPerMinute int
+
+	mu sync.Mutex
+	// last is the latest reserved start, and starts the reserved start times
+	// of the last minute, in order
+	last   time.Time
+	starts []time.Time
+}
+
+// newActionLimiter reads the rate limited mode from the provider
+// configuration, returning nil when it is disabled.
+func newActionLimiter(config TerrapwnerProviderModel, diags *diag.Diagnostics) *actionLimiter {
+	var limiter actionLimiter
+	if !config.MinInterval.IsNull() && !config.MinInterval.IsUnknown() {
+		if config.MinInterval.ValueInt64() < 0 {
+			diags.AddAttributeError(
+				path.Root("min_interval_between_actions"),
+				"Invalid Rate Limit",
+				fmt.Sprintf("min_interval_between_actions must not be negative, got: %d.", config.MinInterval.ValueInt64()),
+			)
+		}
+		limiter.minInterval = time.Duration(config.MinInterval.ValueInt64()) * time.Second
+	}
+	if !config.ActionsPerMinute.IsNull() && !config.ActionsPerMinute.IsUnknown() {
+		if config.ActionsPerMinute.ValueInt64() <= 0 {
+			diags.AddAttributeError(
+				path.Root("actions_per_minute"),
+				"Invalid Rate Limit",
+				fmt.Sprintf("actions_per_minute must be positive, got: %d.", config.ActionsPerMinute.ValueInt64()),
+			)
+		}
+		limiter.actionsPerMinute = int(config.ActionsPerMinute.ValueInt64())
+	}
+	if limiter.minInterval <= 0 && limiter.actionsPerMinute <= 0 {
+		return nil
+	}
+	return &limiter
+}
+
+// wait blocks until the next action may start, and returns how long it waited.
+// A nil limiter does not wait.
+func (l *actionLimiter) wait(ctx context.Context) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	start := l.reserve(time.Now())
+	wait := time.Until(start)
+	return max(wait, 0), exfilSleep(ctx, wait)
+}
+
+// reserve returns the earliest start at or after now honoring the limits, and
+// records it.
+func (l *actionLimiter) reserve(now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget the starts that no longer count against the limit of a minute
+	l.starts = slices.DeleteFunc(l.starts, func(t time.Time) bool { return !t.After(now.Add(-time.Minute)) })
+
+	start := now
+	if !l.last.IsZero() {
+		start = maxTime(start, l.last.Add(l.minInterval))
+	}
+	if l.actionsPerMinute > 0 && len(l.starts) >= l.actionsPerMinute {
+		start = maxTime(start, l.starts[len(l.starts)-l.actionsPerMinute].Add(time.Minute))
+	}
+	l.last = start
+	l.starts = append(l.starts, start)
+	return start
+}
+
+// maxTime returns the later of two times.
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
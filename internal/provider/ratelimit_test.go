@@ -0,0 +1,113 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestActionLimiter_Reserve(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		limiter *actionLimiter
+		at      []time.Duration
+		want    []time.Duration
+	}{
+		{
+			"min interval",
+			&actionLimiter{minInterval: 2 * time.Second},
+			[]time.Duration{0, 0, time.Second, 10 * time.Second},
+			[]time.Duration{0, 2 * time.Second, 4 * time.Second, 10 * time.Second},
+		},
+		{
+			"min interval over a minute",
+			&actionLimiter{minInterval: 90 * time.Second},
+			[]time.Duration{0, 70 * time.Second},
+			[]time.Duration{0, 90 * time.Second},
+		},
+		{
+			"actions per minute",
+			&actionLimiter{actionsPerMinute: 2},
+			[]time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, 90 * time.Second},
+			[]time.Duration{0, time.Second, time.Minute, 61 * time.Second, 120 * time.Second},
+		},
+		{
+			"both",
+			&actionLimiter{minInterval: 10 * time.Second, actionsPerMinute: 3},
+			[]time.Duration{0, 0, 0, 0},
+			[]time.Duration{0, 10 * time.Second, 20 * time.Second, time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, at := range tt.at {
+				if got := tt.limiter.reserve(now.Add(at)).Sub(now); got != tt.want[i] {
+					t.Errorf("action %d at %s starts at %s, want %s", i, at, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestActionLimiter_Wait(t *testing.T) {
+	var limiter *actionLimiter
+	if waited, err := limiter.wait(context.Background()); waited != 0 || err != nil {
+		t.Errorf("nil limiter waited %s: %v", waited, err)
+	}
+
+	limiter = &actionLimiter{minInterval: time.Hour}
+	if waited, err := limiter.wait(context.Background()); waited != 0 || err != nil {
+		t.Errorf("first action waited %s: %v", waited, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if waited, err := limiter.wait(ctx); err == nil || waited < 59*time.Minute {
+		t.Errorf("second action waited %s: %v", waited, err)
+	}
+}
+
+func TestAccRateLimitedMode(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "terrapwner" {
+  actions_per_minute = 0
+}
+
+data "terrapwner_ci_detect" "test" {}
+`,
+				ExpectError: regexp.MustCompile(`actions_per_minute must be positive, got: 0`),
+			},
+			{
+				Config: `
+provider "terrapwner" {
+  min_interval_between_actions = 1
+  actions_per_minute           = 30
+}
+
+data "terrapwner_local_exec" "first" {
+  command = ["echo", "first"]
+}
+
+data "terrapwner_local_exec" "second" {
+  command = ["echo", "second"]
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.first", "stdout", "first\n"),
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.second", "stdout", "second\n"),
+				),
+			},
+		},
+	})
+}
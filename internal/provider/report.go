@@ -0,0 +1,143 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// resultsReportVersion is the version of the on-disk format of reports.
+const resultsReportVersion = 1
+
+// outcomeBlocked is the outcome of results explicitly marking a blocked path.
+const outcomeBlocked = "blocked"
+
+// resultsReport is the on-disk format of the report of an engagement, which a
+// later engagement diffs against to show progress between assessments.
+type resultsReport struct {
+	Version     int               `json:"version"`
+	Score       float64           `json:"score"`
+	Outcomes    map[string]string `json:"outcomes"`
+	ByTechnique map[string]int64  `json:"by_technique"`
+	Failures    []string          `json:"failures"`
+}
+
+// resultsReportDelta is the difference between the reports of two engagements.
+type resultsReportDelta struct {
+	newlyAllowed []string
+	newlyBlocked []string
+	scoreChange  float64
+}
+
+// newResultsReport builds the report of the outcomes of results by name.
+func newResultsReport(outcomes map[string]string, byTechnique map[string]int64, failures []string) *resultsReport {
+	var allowed, blocked int
+	for _, outcome := range outcomes {
+		switch {
+		case isAllowedOutcome(outcome):
+			allowed++
+		case isBlockedOutcome(outcome):
+			blocked++
+		}
+	}
+	score := 0.0
+	if allowed+blocked > 0 {
+		score = roundScore(float64(blocked) * 100 / float64(allowed+blocked))
+	}
+	return &resultsReport{
+		Version:     resultsReportVersion,
+		Score:       score,
+		Outcomes:    outcomes,
+		ByTechnique: byTechnique,
+		Failures:    failures,
+	}
+}
+
+// loadResultsReport reads the report at path. It returns a nil report and no
+// error if the file does not exist, e.g. on the first engagement.
+func loadResultsReport(path string) (*resultsReport, error) {
+	encoded, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var report resultsReport
+	if err := json.Unmarshal(encoded, &report); err != nil {
+		return nil, fmt.Errorf("invalid report: %w", err)
+	}
+	if report.Version != resultsReportVersion {
+		return nil, fmt.Errorf("unsupported report version: %d", report.Version)
+	}
+	return &report, nil
+}
+
+// save atomically writes the report to path.
+func (r *resultsReport) save(path string) error {
+	encoded, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// diff returns the changes from the previous report to r. Results missing
+// from either report are not compared.
+func (r *resultsReport) diff(previous *resultsReport) resultsReportDelta {
+	delta := resultsReportDelta{
+		newlyAllowed: []string{},
+		newlyBlocked: []string{},
+		scoreChange:  roundScore(r.Score - previous.Score),
+	}
+	for name, outcome := range r.Outcomes {
+		previousOutcome, ok := previous.Outcomes[name]
+		if !ok {
+			continue
+		}
+		switch {
+		case isAllowedOutcome(outcome) && isBlockedOutcome(previousOutcome):
+			delta.newlyAllowed = append(delta.newlyAllowed, name)
+		case isBlockedOutcome(outcome) && isAllowedOutcome(previousOutcome):
+			delta.newlyBlocked = append(delta.newlyBlocked, name)
+		}
+	}
+	sort.Strings(delta.newlyAllowed)
+	sort.Strings(delta.newlyBlocked)
+	return delta
+}
+
+// isAllowedOutcome returns whether an outcome means the tested path is allowed.
+func isAllowedOutcome(outcome string) bool {
+	return outcome == outcomeSuccess
+}
+
+// isBlockedOutcome returns whether an outcome means the tested path is blocked.
+func isBlockedOutcome(outcome string) bool {
+	return outcome == outcomeFailure || outcome == outcomeError || outcome == outcomeBlocked
+}
+
+// roundScore rounds a score to one decimal.
+func roundScore(score float64) float64 {
+	return math.Round(score*10) / 10
+}
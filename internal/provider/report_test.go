@@ -0,0 +1,71 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResultsReport_Diff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	// First engagement, without previous report
+	previous, err := loadResultsReport(path)
+	if err != nil || previous != nil {
+		t.Fatalf("loadResultsReport() of a missing file = %v, %v, want nil, nil", previous, err)
+	}
+	first := newResultsReport(map[string]string{
+		"imds":    outcomeSuccess,
+		"exfil":   outcomeSuccess,
+		"dns":     outcomeFailure,
+		"smb":     outcomeBlocked,
+		"inspect": outcomeUnknown,
+	}, map[string]int64{"T1048": 2}, []string{"dns: timeout"})
+	if first.Score != 50 {
+		t.Errorf("Score = %v, want 50", first.Score)
+	}
+	if err := first.save(path); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+
+	// Next engagement, with exfil newly blocked and dns newly allowed
+	previous, err = loadResultsReport(path)
+	if err != nil {
+		t.Fatalf("loadResultsReport() error: %v", err)
+	}
+	if !reflect.DeepEqual(previous, first) {
+		t.Errorf("loadResultsReport() = %+v, want %+v", previous, first)
+	}
+	second := newResultsReport(map[string]string{
+		"imds":  outcomeSuccess,
+		"exfil": outcomeError,
+		"dns":   outcomeSuccess,
+		"smb":   outcomeFailure,
+		"ldap":  outcomeFailure,
+	}, nil, nil)
+	if second.Score != 60 {
+		t.Errorf("Score = %v, want 60", second.Score)
+	}
+	delta := second.diff(previous)
+	if !reflect.DeepEqual(delta.newlyAllowed, []string{"dns"}) {
+		t.Errorf("newlyAllowed = %v, want [dns]", delta.newlyAllowed)
+	}
+	if !reflect.DeepEqual(delta.newlyBlocked, []string{"exfil"}) {
+		t.Errorf("newlyBlocked = %v, want [exfil]", delta.newlyBlocked)
+	}
+	if delta.scoreChange != 10 {
+		t.Errorf("scoreChange = %v, want 10", delta.scoreChange)
+	}
+
+	// Reports of another format are rejected
+	if err := os.WriteFile(path, []byte(`{"version": 2}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadResultsReport(path); err == nil {
+		t.Error("loadResultsReport() of an unsupported version error = nil")
+	}
+}
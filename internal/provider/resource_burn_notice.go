@@ -0,0 +1,364 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource               = &TerrapwnerBurnNoticeResource{}
+	_ resource.ResourceWithModifyPlan = &TerrapwnerBurnNoticeResource{}
+)
+
+// burnNoticeRevokeMethods are the HTTP methods revoking collector tokens.
+var burnNoticeRevokeMethods = []string{http.MethodDelete, http.MethodPost, http.MethodPut, http.MethodPatch}
+
+// burnNoticeAccessKeyRe matches an IAM access key ID, optionally prefixed with
+// the name of its user and a slash.
+var burnNoticeAccessKeyRe = regexp.MustCompile(`^(?:([\w+=,.@-]+)/)?([A-Z0-9]{16,128})$`)
+
+// NewTerrapwnerBurnNoticeResource is a helper function to simplify the provider implementation.
+func NewTerrapwnerBurnNoticeResource() resource.Resource {
+	return &TerrapwnerBurnNoticeResource{}
+}
+
+// TerrapwnerBurnNoticeResource is the resource implementation.
+type TerrapwnerBurnNoticeResource struct{}
+
+// TerrapwnerBurnNoticeResourceModel describes the resource data model.
+type TerrapwnerBurnNoticeResourceModel struct {
+	ID            types.String `tfsdk:"id"`
+	Files         types.List   `tfsdk:"files"`
+	IAMAccessKeys types.List   `tfsdk:"iam_access_keys"`
+	RevokeURLs    types.List   `tfsdk:"revoke_urls"`
+	RevokeMethod  types.String `tfsdk:"revoke_method"`
+	RevokeHeaders types.Map    `tfsdk:"revoke_headers"`
+	Timeout       types.Int64  `tfsdk:"timeout"`
+	ArmedAt       types.String `tfsdk:"armed_at"`
+}
+
+// Metadata returns the resource type name.
+func (r *TerrapwnerBurnNoticeResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_burn_notice"
+}
+
+// Schema defines the schema for the resource.
+func (r *TerrapwnerBurnNoticeResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	keep := []planmodifier.String{stringplanmodifier.UseStateForUnknown()}
+
+	resp.Schema = schema.Schema{
+		Description: "Revokes the artifacts an exercise created when it is destroyed: deletes canary IAM access keys, removes " +
+			"dropped files and invalidates collector tokens through their API. This guarantees the cleanup of items created by " +
+			"data sources, which have no lifecycle of their own. The lists can be updated in place, and only the items listed " +
+			"at destroy are revoked. Items already gone count as revoked. If any revocation fails, the destroy fails and the " +
+			"remaining items are kept in the state, so the next destroy retries them.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description:   "Random ID of the burn notice.",
+				Computed:      true,
+				PlanModifiers: keep,
+			},
+			"files": schema.ListAttribute{
+				Description: "Paths of the local files to remove, e.g. payloads dropped on the runner.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"iam_access_keys": schema.ListAttribute{
+				Description: "IAM access keys to delete, as `user/access_key_id`, or `access_key_id` for keys of the calling IAM user, " +
+					"with the AWS credentials of the environment.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"revoke_urls": schema.ListAttribute{
+				Description: "URLs of collector API endpoints invalidating a token, each sent a revoke_method request. 2xx, 404 " +
+					"and 410 responses count as revoked.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"revoke_method": schema.StringAttribute{
+				Description: "HTTP method of the revoke_urls requests: DELETE, POST, PUT or PATCH (default: DELETE).",
+				Optional:    true,
+			},
+			"revoke_headers": schema.MapAttribute{
+				Description: "Headers of the revoke_urls requests, e.g. the Authorization of the collector API.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each revoke_urls request (default: 10).",
+				Optional:    true,
+			},
+			"armed_at": schema.StringAttribute{
+				Description:   "Time the burn notice was created (RFC 3339).",
+				Computed:      true,
+				PlanModifiers: keep,
+			},
+		},
+	}
+}
+
+// ModifyPlan validates the configuration once it is known.
+func (r *TerrapwnerBurnNoticeResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to validate when destroying
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data TerrapwnerBurnNoticeResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.RevokeMethod.IsNull() && !data.RevokeMethod.IsUnknown() && !slices.Contains(burnNoticeRevokeMethods, data.RevokeMethod.ValueString()) {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("revoke_method must be one of DELETE, POST, PUT or PATCH, got: %s", data.RevokeMethod.ValueString()),
+		)
+	}
+	for _, key := range knownStringItems(data.IAMAccessKeys) {
+		if !burnNoticeAccessKeyRe.MatchString(key) {
+			resp.Diagnostics.AddError(
+				"Invalid configuration",
+				fmt.Sprintf("iam_access_keys must be access key IDs optionally prefixed with user/, got: %s", key),
+			)
+		}
+	}
+	for _, rawURL := range knownStringItems(data.RevokeURLs) {
+		if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			resp.Diagnostics.AddError(
+				"Invalid configuration",
+				fmt.Sprintf("revoke_urls must be HTTP or HTTPS URLs, got: %s", utils.RedactURL(rawURL)),
+			)
+		}
+	}
+}
+
+// Create arms the burn notice.
+func (r *TerrapwnerBurnNoticeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan TerrapwnerBurnNoticeResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ID = types.StringValue(exfilTransferID())
+	plan.ArmedAt = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Read keeps the state, the items being revoked at destroy.
+func (r *TerrapwnerBurnNoticeResource) Read(_ context.Context, _ resource.ReadRequest, _ *resource.ReadResponse) {
+	// Nothing to refresh
+}
+
+// Update records the items to revoke.
+func (r *TerrapwnerBurnNoticeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan TerrapwnerBurnNoticeResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Delete revokes the items, keeping the ones that could not be revoked in the
+// state when any failed.
+func (r *TerrapwnerBurnNoticeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state TerrapwnerBurnNoticeResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	errs := r.burn(ctx, &state)
+	if len(errs) > 0 {
+		details := make([]string, len(errs))
+		for i, err := range errs {
+			details[i] = err.Error()
+		}
+		resp.Diagnostics.AddError(
+			"Burn Notice Incomplete",
+			fmt.Sprintf("Unable to revoke %d item(s), kept in the state to be retried by the next destroy:\n%s",
+				len(errs), strings.Join(details, "\n")),
+		)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+		return
+	}
+	resp.State.RemoveResource(ctx)
+}
+
+// burn revokes the items of the burn notice, removes the revoked ones from the
+// model and returns the errors of the others.
+func (r *TerrapwnerBurnNoticeResource) burn(ctx context.Context, data *TerrapwnerBurnNoticeResourceModel) []error {
+	var errs []error
+
+	var files []string
+	for _, path := range knownStringItems(data.Files) {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("file %s: %w", path, err))
+			files = append(files, path)
+			continue
+		}
+		tflog.Info(ctx, "Burn notice removed file", map[string]any{"path": path})
+	}
+	data.Files = burnNoticeList(data.Files, files)
+
+	var keys []string
+	if accessKeys := knownStringItems(data.IAMAccessKeys); len(accessKeys) > 0 {
+		client, err := burnNoticeIAMClient(ctx)
+		for _, key := range accessKeys {
+			if err == nil {
+				err = deleteBurnNoticeAccessKey(ctx, client, key)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("IAM access key %s: %w", key, err))
+				keys = append(keys, key)
+				continue
+			}
+			tflog.Info(ctx, "Burn notice deleted IAM access key", map[string]any{"access_key": key})
+		}
+	}
+	data.IAMAccessKeys = burnNoticeList(data.IAMAccessKeys, keys)
+
+	method := http.MethodDelete
+	if !data.RevokeMethod.IsNull() {
+		method = data.RevokeMethod.ValueString()
+	}
+	headers := map[string]string{}
+	if !data.RevokeHeaders.IsNull() {
+		for k, v := range data.RevokeHeaders.Elements() {
+			if s, ok := v.(types.String); ok {
+				headers[k] = s.ValueString()
+			}
+		}
+	}
+	timeout := int64(10)
+	if !data.Timeout.IsNull() {
+		timeout = data.Timeout.ValueInt64()
+	}
+	var urls []string
+	for _, rawURL := range knownStringItems(data.RevokeURLs) {
+		if err := revokeBurnNoticeToken(ctx, method, rawURL, headers, time.Duration(timeout)*time.Second); err != nil {
+			errs = append(errs, fmt.Errorf("token %s: %w", utils.RedactURL(rawURL), err))
+			urls = append(urls, rawURL)
+			continue
+		}
+		tflog.Info(ctx, "Burn notice revoked token", map[string]any{"url": utils.RedactURL(rawURL)})
+	}
+	data.RevokeURLs = burnNoticeList(data.RevokeURLs, urls)
+
+	return errs
+}
+
+// burnNoticeIAMClient returns an IAM client with the AWS configuration of the
+// environment, in us-east-1 if it sets no region.
+func burnNoticeIAMClient(ctx context.Context) (*iam.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return iam.NewFromConfig(cfg), nil
+}
+
+// deleteBurnNoticeAccessKey deletes an IAM access key given as user/id or id,
+// a key that no longer exists counting as deleted.
+func deleteBurnNoticeAccessKey(ctx context.Context, client *iam.Client, key string) error {
+	m := burnNoticeAccessKeyRe.FindStringSubmatch(key)
+	if m == nil {
+		return fmt.Errorf("invalid access key")
+	}
+	input := &iam.DeleteAccessKeyInput{AccessKeyId: aws.String(m[2])}
+	if m[1] != "" {
+		input.UserName = aws.String(m[1])
+	}
+	_, err := client.DeleteAccessKey(ctx, input)
+	var notFound *iamtypes.NoSuchEntityException
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
+
+// revokeBurnNoticeToken sends a revocation request to a collector API, a token
+// that no longer exists counting as revoked.
+func revokeBurnNoticeToken(ctx context.Context, method, rawURL string, headers map[string]string, timeout time.Duration) error {
+	resp, err := utils.HTTPRequest(ctx, method, rawURL, headers, nil, timeout)
+	if err != nil {
+		// The URL may hold the token, so only the cause is reported
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	if !resp.IsSuccess() && resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// burnNoticeList returns the remaining items of a list, keeping it null if it
+// was and had none left.
+func burnNoticeList(list types.List, remaining []string) types.List {
+	if list.IsNull() && len(remaining) == 0 {
+		return list
+	}
+	values := make([]attr.Value, len(remaining))
+	for i, item := range remaining {
+		values[i] = types.StringValue(item)
+	}
+	return types.ListValueMust(types.StringType, values)
+}
+
+// knownStringItems returns the known items of a list, which may hold unknown
+// values when planning.
+func knownStringItems(list types.List) []string {
+	var items []string
+	for _, v := range list.Elements() {
+		if s, ok := v.(types.String); ok && !s.IsNull() && !s.IsUnknown() {
+			items = append(items, s.ValueString())
+		}
+	}
+	return items
+}
@@ -0,0 +1,448 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource               = &TerrapwnerConfigDriftCanaryResource{}
+	_ resource.ResourceWithModifyPlan = &TerrapwnerConfigDriftCanaryResource{}
+)
+
+const (
+	// driftCanarySSMParameter writes the canary to an SSM parameter.
+	driftCanarySSMParameter = "ssm_parameter"
+	// driftCanaryEC2Tag sets the canary as a tag of an existing EC2 resource.
+	driftCanaryEC2Tag = "ec2_tag"
+
+	// driftCanaryPresent means the canary is as it was created.
+	driftCanaryPresent = "present"
+	// driftCanaryModified means the canary value was changed.
+	driftCanaryModified = "modified"
+	// driftCanaryReverted means the canary was removed.
+	driftCanaryReverted = "reverted"
+
+	// driftCanarySSMPrefix is the path of the default SSM parameter names.
+	driftCanarySSMPrefix = "/terrapwner/drift-canary/"
+	// defaultDriftCanaryTagKey is the default tag key in ec2_tag kind.
+	defaultDriftCanaryTagKey = "terrapwner-drift-canary"
+)
+
+// NewTerrapwnerConfigDriftCanaryResource is a helper function to simplify the provider implementation.
+func NewTerrapwnerConfigDriftCanaryResource() resource.Resource {
+	return &TerrapwnerConfigDriftCanaryResource{}
+}
+
+// TerrapwnerConfigDriftCanaryResource is the resource implementation.
+type TerrapwnerConfigDriftCanaryResource struct{}
+
+// TerrapwnerConfigDriftCanaryResourceModel describes the resource data model.
+type TerrapwnerConfigDriftCanaryResourceModel struct {
+	ID             types.String `tfsdk:"id"`
+	Kind           types.String `tfsdk:"kind"`
+	Region         types.String `tfsdk:"region"`
+	Name           types.String `tfsdk:"name"`
+	ResourceID     types.String `tfsdk:"resource_id"`
+	ExpectReverted types.Bool   `tfsdk:"expect_reverted"`
+	Value          types.String `tfsdk:"value"`
+	CreatedAt      types.String `tfsdk:"created_at"`
+	Status         types.String `tfsdk:"status"`
+	ChangedAt      types.String `tfsdk:"changed_at"`
+}
+
+// Metadata returns the resource type name.
+func (r *TerrapwnerConfigDriftCanaryResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_config_drift_canary"
+}
+
+// Schema defines the schema for the resource.
+func (r *TerrapwnerConfigDriftCanaryResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	replace := []planmodifier.String{stringplanmodifier.UseStateForUnknown(), stringplanmodifier.RequiresReplace()}
+	keep := []planmodifier.String{stringplanmodifier.UseStateForUnknown()}
+
+	resp.Schema = schema.Schema{
+		Description: "Creates an unauthorized-looking but harmless change in the AWS account at apply, an SSM parameter or a tag on a " +
+			"sandbox EC2 resource, and checks on every refresh and at destroy whether it was modified or reverted, to test " +
+			"drift detection and CSPM response times. The canary is removed at destroy if it is still there. Canaries that " +
+			"were not acted upon are reported as a warning at destroy, or an error with expect_reverted.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description:   "Name of the SSM parameter, or resource ID and tag key separated by a slash in ec2_tag kind.",
+				Computed:      true,
+				PlanModifiers: keep,
+			},
+			"kind": schema.StringAttribute{
+				Description: "Canary change: ssm_parameter, a String SSM parameter, or ec2_tag, a tag on an existing EC2 resource " +
+					"(default: ssm_parameter).",
+				Optional:      true,
+				Computed:      true,
+				PlanModifiers: replace,
+			},
+			"region": schema.StringAttribute{
+				Description:   "AWS region of the canary (default: the region of the AWS configuration, or us-east-1).",
+				Optional:      true,
+				Computed:      true,
+				PlanModifiers: replace,
+			},
+			"name": schema.StringAttribute{
+				Description: fmt.Sprintf("Name of the SSM parameter (default: %s followed by a random name), or key of the tag "+
+					"in ec2_tag kind (default: %s).", driftCanarySSMPrefix, defaultDriftCanaryTagKey),
+				Optional:      true,
+				Computed:      true,
+				PlanModifiers: replace,
+			},
+			"resource_id": schema.StringAttribute{
+				Description:   "ID of the EC2 resource to tag in ec2_tag kind, e.g. a sandbox VPC or security group. The tag key must not be set on it already.",
+				Optional:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"expect_reverted": schema.BoolAttribute{
+				Description: "Whether the destroy fails when the canary was neither modified nor reverted (default: false). The " +
+					"canary is then left in the account and the state, so that the next destroy checks it again: set it to false " +
+					"to remove an untouched canary.",
+				Optional: true,
+			},
+			"value": schema.StringAttribute{
+				Description:   "Random value of the canary.",
+				Computed:      true,
+				PlanModifiers: keep,
+			},
+			"created_at": schema.StringAttribute{
+				Description:   "Time the canary was created (RFC 3339).",
+				Computed:      true,
+				PlanModifiers: keep,
+			},
+			"status": schema.StringAttribute{
+				Description: "State of the canary at the last refresh: present, modified if its value was changed, or reverted if it " +
+					"was removed.",
+				Computed:      true,
+				PlanModifiers: keep,
+			},
+			"changed_at": schema.StringAttribute{
+				Description: "Time of the first refresh that found the canary modified or reverted (RFC 3339), bounding the " +
+					"response time from created_at. Empty while it is present.",
+				Computed:      true,
+				PlanModifiers: keep,
+			},
+		},
+	}
+}
+
+// ModifyPlan validates the configuration once it is known.
+func (r *TerrapwnerConfigDriftCanaryResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to validate when destroying
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	// The configuration tells unset attributes from computed ones
+	var data TerrapwnerConfigDriftCanaryResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if data.Kind.IsUnknown() || data.ResourceID.IsUnknown() {
+		return
+	}
+
+	switch data.Kind.ValueString() {
+	case "", driftCanarySSMParameter:
+		if !data.ResourceID.IsNull() {
+			resp.Diagnostics.AddError("Invalid configuration", "resource_id is only supported in ec2_tag kind")
+		}
+	case driftCanaryEC2Tag:
+		if data.ResourceID.ValueString() == "" {
+			resp.Diagnostics.AddError("Invalid configuration", "resource_id is required in ec2_tag kind")
+		}
+	default:
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("kind must be one of ssm_parameter or ec2_tag, got: %s", data.Kind.ValueString()),
+		)
+	}
+}
+
+// Create sets up the canary.
+func (r *TerrapwnerConfigDriftCanaryResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan TerrapwnerConfigDriftCanaryResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Set default values
+	if plan.Kind.IsUnknown() {
+		plan.Kind = types.StringValue(driftCanarySSMParameter)
+	}
+	if plan.Name.IsUnknown() {
+		switch plan.Kind.ValueString() {
+		case driftCanarySSMParameter:
+			plan.Name = types.StringValue(driftCanarySSMPrefix + exfilTransferID())
+		case driftCanaryEC2Tag:
+			plan.Name = types.StringValue(defaultDriftCanaryTagKey)
+		}
+	}
+
+	cfg, err := driftCanaryConfig(ctx, &plan)
+	if err != nil {
+		resp.Diagnostics.AddError("AWS Configuration Error", err.Error())
+		return
+	}
+	plan.Region = types.StringValue(cfg.Region)
+	plan.Value = types.StringValue("terrapwner-canary-" + exfilTransferID())
+
+	switch plan.Kind.ValueString() {
+	case driftCanarySSMParameter:
+		plan.ID = plan.Name
+		_, err = ssm.NewFromConfig(cfg).PutParameter(ctx, &ssm.PutParameterInput{
+			Name:        plan.Name.ValueStringPointer(),
+			Value:       plan.Value.ValueStringPointer(),
+			Type:        ssmtypes.ParameterTypeString,
+			Description: aws.String("terrapwner config drift canary"),
+			Tags:        []ssmtypes.Tag{{Key: aws.String(defaultDriftCanaryTagKey), Value: plan.Value.ValueStringPointer()}},
+		})
+	case driftCanaryEC2Tag:
+		plan.ID = types.StringValue(plan.ResourceID.ValueString() + "/" + plan.Name.ValueString())
+		err = createDriftCanaryTag(ctx, ec2.NewFromConfig(cfg), &plan)
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Canary Creation Error", fmt.Sprintf("Unable to create %s canary %s: %v",
+			plan.Kind.ValueString(), plan.ID.ValueString(), err))
+		return
+	}
+	plan.CreatedAt = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+	plan.Status = types.StringValue(driftCanaryPresent)
+	plan.ChangedAt = types.StringValue("")
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Read checks whether the canary was modified or reverted. A reverted canary
+// is kept in the state, to be reported at destroy.
+func (r *TerrapwnerConfigDriftCanaryResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state TerrapwnerConfigDriftCanaryResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.check(ctx, &state); err != nil {
+		resp.Diagnostics.AddWarning("Canary Check Error", fmt.Sprintf("Unable to check canary %s: %v", state.ID.ValueString(), err))
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// Update only changes expect_reverted, every other attribute replacing the canary.
+func (r *TerrapwnerConfigDriftCanaryResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan TerrapwnerConfigDriftCanaryResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Delete checks the canary a last time, reports whether it was acted upon and
+// removes it if it is still there. An untouched canary is not removed when
+// expect_reverted is set, as Terraform keeps the resource in the state when
+// Delete fails.
+func (r *TerrapwnerConfigDriftCanaryResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state TerrapwnerConfigDriftCanaryResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.check(ctx, &state); err != nil {
+		resp.Diagnostics.AddError("Canary Check Error", fmt.Sprintf("Unable to check canary %s: %v", state.ID.ValueString(), err))
+		return
+	}
+	reportDriftCanary(&state, time.Now(), &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := r.remove(ctx, &state); err != nil {
+		resp.Diagnostics.AddError("Canary Deletion Error", fmt.Sprintf("Unable to delete canary %s: %v", state.ID.ValueString(), err))
+		return
+	}
+	resp.State.RemoveResource(ctx)
+}
+
+// driftCanaryConfig loads the AWS configuration for the region of the canary,
+// us-east-1 if neither the canary nor the configuration set one.
+func driftCanaryConfig(ctx context.Context, data *TerrapwnerConfigDriftCanaryResourceModel) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(data.Region.ValueString()))
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return cfg, nil
+}
+
+// check updates the status of the canary, recording when it first changed.
+func (r *TerrapwnerConfigDriftCanaryResource) check(ctx context.Context, data *TerrapwnerConfigDriftCanaryResourceModel) error {
+	cfg, err := driftCanaryConfig(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	var value string
+	var found bool
+	switch data.Kind.ValueString() {
+	case driftCanarySSMParameter:
+		out, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{Name: data.Name.ValueStringPointer()})
+		var notFound *ssmtypes.ParameterNotFound
+		switch {
+		case errors.As(err, &notFound):
+		case err != nil:
+			return err
+		default:
+			found = true
+			value = aws.ToString(out.Parameter.Value)
+		}
+	case driftCanaryEC2Tag:
+		value, found, err = driftCanaryTagValue(ctx, ec2.NewFromConfig(cfg), data)
+		if err != nil {
+			return err
+		}
+	}
+
+	status := driftCanaryStatus(data.Value.ValueString(), value, found)
+	if status != driftCanaryPresent && data.ChangedAt.ValueString() == "" {
+		data.ChangedAt = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+	}
+	data.Status = types.StringValue(status)
+	return nil
+}
+
+// remove deletes the canary if it is still there, leaving a modified tag in place.
+func (r *TerrapwnerConfigDriftCanaryResource) remove(ctx context.Context, data *TerrapwnerConfigDriftCanaryResourceModel) error {
+	if data.Status.ValueString() == driftCanaryReverted {
+		return nil
+	}
+	cfg, err := driftCanaryConfig(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	switch data.Kind.ValueString() {
+	case driftCanarySSMParameter:
+		_, err = ssm.NewFromConfig(cfg).DeleteParameter(ctx, &ssm.DeleteParameterInput{Name: data.Name.ValueStringPointer()})
+		var notFound *ssmtypes.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return nil
+		}
+	case driftCanaryEC2Tag:
+		// Tags are only deleted when their value matches, so someone else's value is kept
+		_, err = ec2.NewFromConfig(cfg).DeleteTags(ctx, &ec2.DeleteTagsInput{
+			Resources: []string{data.ResourceID.ValueString()},
+			Tags:      []ec2types.Tag{{Key: data.Name.ValueStringPointer(), Value: data.Value.ValueStringPointer()}},
+		})
+	}
+	return err
+}
+
+// createDriftCanaryTag tags the EC2 resource, refusing to overwrite an existing tag.
+func createDriftCanaryTag(ctx context.Context, client *ec2.Client, data *TerrapwnerConfigDriftCanaryResourceModel) error {
+	if _, found, err := driftCanaryTagValue(ctx, client, data); err != nil {
+		return err
+	} else if found {
+		return fmt.Errorf("tag %s is already set on %s", data.Name.ValueString(), data.ResourceID.ValueString())
+	}
+
+	_, err := client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{data.ResourceID.ValueString()},
+		Tags:      []ec2types.Tag{{Key: data.Name.ValueStringPointer(), Value: data.Value.ValueStringPointer()}},
+	})
+	return err
+}
+
+// driftCanaryTagValue returns the value of the canary tag on the EC2 resource,
+// and whether it is set.
+func driftCanaryTagValue(ctx context.Context, client *ec2.Client, data *TerrapwnerConfigDriftCanaryResourceModel) (string, bool, error) {
+	out, err := client.DescribeTags(ctx, &ec2.DescribeTagsInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("resource-id"), Values: []string{data.ResourceID.ValueString()}},
+			{Name: aws.String("key"), Values: []string{data.Name.ValueString()}},
+		},
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if len(out.Tags) == 0 {
+		return "", false, nil
+	}
+	return aws.ToString(out.Tags[0].Value), true, nil
+}
+
+// driftCanaryStatus compares the current value of the canary to the created one.
+func driftCanaryStatus(created, current string, found bool) string {
+	switch {
+	case !found:
+		return driftCanaryReverted
+	case current != created:
+		return driftCanaryModified
+	default:
+		return driftCanaryPresent
+	}
+}
+
+// reportDriftCanary reports at destroy whether the canary was acted upon and
+// how long it took, failing if expect_reverted is set and it was not.
+func reportDriftCanary(data *TerrapwnerConfigDriftCanaryResourceModel, now time.Time, diags *diag.Diagnostics) {
+	createdAt, _ := time.Parse(time.RFC3339, data.CreatedAt.ValueString())
+
+	if data.Status.ValueString() == driftCanaryPresent {
+		detail := fmt.Sprintf("Canary %s was still present %s after its creation: drift detection did not act on it.",
+			data.ID.ValueString(), now.Sub(createdAt).Round(time.Second))
+		if data.ExpectReverted.ValueBool() {
+			diags.AddError("Config Drift Canary Not Reverted", detail)
+			return
+		}
+		diags.AddWarning("Config Drift Canary Not Reverted", detail)
+		return
+	}
+
+	changedAt, _ := time.Parse(time.RFC3339, data.ChangedAt.ValueString())
+	diags.AddWarning(
+		"Config Drift Canary Detected",
+		fmt.Sprintf("Canary %s was found %s at most %s after its creation, at %s.",
+			data.ID.ValueString(), data.Status.ValueString(), changedAt.Sub(createdAt).Round(time.Second), data.ChangedAt.ValueString()),
+	)
+}
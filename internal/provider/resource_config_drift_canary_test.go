@@ -0,0 +1,269 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestReportDriftCanary(t *testing.T) {
+	now := time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)
+	canary := func(status, changedAt string, expectReverted bool) *TerrapwnerConfigDriftCanaryResourceModel {
+		return &TerrapwnerConfigDriftCanaryResourceModel{
+			ID:             types.StringValue("/terrapwner/drift-canary/ci"),
+			ExpectReverted: types.BoolValue(expectReverted),
+			CreatedAt:      types.StringValue("2026-01-01T00:00:00Z"),
+			Status:         types.StringValue(status),
+			ChangedAt:      types.StringValue(changedAt),
+		}
+	}
+
+	var diags diag.Diagnostics
+	reportDriftCanary(canary(driftCanaryReverted, "2026-01-01T00:15:00Z", true), now, &diags)
+	if diags.HasError() || diags.WarningsCount() != 1 || !strings.Contains(diags[0].Detail(), "reverted at most 15m0s after its creation") {
+		t.Errorf("unexpected diagnostics for a reverted canary: %v", diags)
+	}
+
+	diags = nil
+	reportDriftCanary(canary(driftCanaryPresent, "", false), now, &diags)
+	if diags.HasError() || diags.WarningsCount() != 1 || !strings.Contains(diags[0].Detail(), "still present 2h0m0s after its creation") {
+		t.Errorf("unexpected diagnostics for an untouched canary: %v", diags)
+	}
+
+	diags = nil
+	reportDriftCanary(canary(driftCanaryPresent, "", true), now, &diags)
+	if diags.ErrorsCount() != 1 {
+		t.Errorf("expected an error for an untouched canary with expect_reverted, got: %v", diags)
+	}
+
+	for _, tc := range []struct {
+		current string
+		found   bool
+		want    string
+	}{
+		{"terrapwner-canary-1", true, driftCanaryPresent},
+		{"approved", true, driftCanaryModified},
+		{"", false, driftCanaryReverted},
+	} {
+		if got := driftCanaryStatus("terrapwner-canary-1", tc.current, tc.found); got != tc.want {
+			t.Errorf("driftCanaryStatus(%q, %t) = %s, want %s", tc.current, tc.found, got, tc.want)
+		}
+	}
+}
+
+func TestAccTerrapwnerConfigDriftCanaryResource(t *testing.T) {
+	// parameters and tags hold the SSM parameters and EC2 tags of the account
+	var mu sync.Mutex
+	parameters := map[string]string{}
+	tags := map[string]string{"vpc-1/Name": "sandbox"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// SSM (JSON protocol)
+		if target := r.Header.Get("X-Amz-Target"); target != "" {
+			var input struct{ Name, Value string }
+			_ = json.NewDecoder(r.Body).Decode(&input)
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			_, exists := parameters[input.Name]
+			switch {
+			case target == "AmazonSSM.PutParameter" && exists:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"__type":"ParameterAlreadyExists","message":"exists"}`)
+			case target == "AmazonSSM.PutParameter":
+				parameters[input.Name] = input.Value
+				fmt.Fprint(w, `{"Version":1,"Tier":"Standard"}`)
+			case !exists:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"__type":"ParameterNotFound","message":"not found"}`)
+			case target == "AmazonSSM.GetParameter":
+				fmt.Fprintf(w, `{"Parameter":{"Name":%q,"Value":%q,"Version":1}}`, input.Name, parameters[input.Name])
+			case target == "AmazonSSM.DeleteParameter":
+				delete(parameters, input.Name)
+				fmt.Fprint(w, `{}`)
+			}
+			return
+		}
+
+		// EC2 (query protocol)
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "text/xml")
+		switch r.Form.Get("Action") {
+		case "DescribeTags":
+			key := r.Form.Get("Filter.1.Value.1") + "/" + r.Form.Get("Filter.2.Value.1")
+			fmt.Fprint(w, `<DescribeTagsResponse><tagSet>`)
+			if value, ok := tags[key]; ok {
+				fmt.Fprintf(w, `<item><key>%s</key><value>%s</value></item>`, r.Form.Get("Filter.2.Value.1"), value)
+			}
+			fmt.Fprint(w, `</tagSet></DescribeTagsResponse>`)
+		case "CreateTags":
+			tags[r.Form.Get("ResourceId.1")+"/"+r.Form.Get("Tag.1.Key")] = r.Form.Get("Tag.1.Value")
+			fmt.Fprint(w, `<CreateTagsResponse><return>true</return></CreateTagsResponse>`)
+		case "DeleteTags":
+			key := r.Form.Get("ResourceId.1") + "/" + r.Form.Get("Tag.1.Key")
+			if tags[key] == r.Form.Get("Tag.1.Value") {
+				delete(tags, key)
+			}
+			fmt.Fprint(w, `<DeleteTagsResponse><return>true</return></DeleteTagsResponse>`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<Response><Errors><Error><Code>InvalidAction</Code><Message>InvalidAction</Message></Error></Errors></Response>`)
+		}
+	}))
+	defer server.Close()
+
+	testAccSetAWSStaticCredentials(t)
+	t.Setenv("AWS_ENDPOINT_URL_SSM", server.URL)
+	t.Setenv("AWS_ENDPOINT_URL_EC2", server.URL)
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(_ *terraform.State) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(parameters) != 0 || len(tags) != 1 {
+				return fmt.Errorf("canaries left behind: %v, %v", parameters, tags)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+resource "terrapwner_config_drift_canary" "test" {
+  resource_id = "vpc-1"
+}
+`,
+				ExpectError: regexp.MustCompile(`resource_id is only supported in ec2_tag kind`),
+			},
+			// SSM parameter canary
+			{
+				Config: providerConfig + `
+resource "terrapwner_config_drift_canary" "test" {
+  name   = "/terrapwner/drift-canary/ci"
+  region = "eu-west-1"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "id", "/terrapwner/drift-canary/ci"),
+					resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "kind", "ssm_parameter"),
+					resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "status", "present"),
+					resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "changed_at", ""),
+					resource.TestMatchResourceAttr("terrapwner_config_drift_canary.test", "value", regexp.MustCompile(`^terrapwner-canary-[0-9a-f]{16}$`)),
+					func(_ *terraform.State) error {
+						mu.Lock()
+						defer mu.Unlock()
+						if !strings.HasPrefix(parameters["/terrapwner/drift-canary/ci"], "terrapwner-canary-") {
+							return fmt.Errorf("canary parameter not created: %v", parameters)
+						}
+						return nil
+					},
+				),
+			},
+			// Automation overwrites the parameter
+			{
+				PreConfig: func() {
+					mu.Lock()
+					defer mu.Unlock()
+					parameters["/terrapwner/drift-canary/ci"] = "remediated"
+				},
+				RefreshState: true,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "status", "modified"),
+					resource.TestMatchResourceAttr("terrapwner_config_drift_canary.test", "changed_at", regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T`)),
+				),
+			},
+			// Tag canary, replacing the parameter one
+			{
+				Config: providerConfig + `
+resource "terrapwner_config_drift_canary" "test" {
+  kind        = "ec2_tag"
+  resource_id = "vpc-1"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "id", "vpc-1/terrapwner-drift-canary"),
+					resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "status", "present"),
+					resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "region", "us-east-1"),
+					func(_ *terraform.State) error {
+						mu.Lock()
+						defer mu.Unlock()
+						if len(parameters) != 0 || !strings.HasPrefix(tags["vpc-1/terrapwner-drift-canary"], "terrapwner-canary-") {
+							return fmt.Errorf("canary not replaced: %v, %v", parameters, tags)
+						}
+						return nil
+					},
+				),
+			},
+			// Someone removes the tag
+			{
+				PreConfig: func() {
+					mu.Lock()
+					defer mu.Unlock()
+					delete(tags, "vpc-1/terrapwner-drift-canary")
+				},
+				RefreshState: true,
+				Check:        resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "status", "reverted"),
+			},
+			// Destroying an untouched canary fails with expect_reverted, leaving it in place
+			{
+				Config: providerConfig + `
+resource "terrapwner_config_drift_canary" "test" {
+  kind            = "ec2_tag"
+  resource_id     = "vpc-1"
+  name            = "terrapwner-drift-canary-2"
+  expect_reverted = true
+}
+`,
+				Check: resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "status", "present"),
+			},
+			{
+				Config: providerConfig + `
+resource "terrapwner_config_drift_canary" "test" {
+  kind            = "ec2_tag"
+  resource_id     = "vpc-1"
+  name            = "terrapwner-drift-canary-2"
+  expect_reverted = true
+}
+`,
+				Destroy:     true,
+				ExpectError: regexp.MustCompile(`Config Drift Canary Not Reverted`),
+			},
+			{
+				Config: providerConfig + `
+resource "terrapwner_config_drift_canary" "test" {
+  kind        = "ec2_tag"
+  resource_id = "vpc-1"
+  name        = "terrapwner-drift-canary-2"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("terrapwner_config_drift_canary.test", "status", "present"),
+					func(_ *terraform.State) error {
+						mu.Lock()
+						defer mu.Unlock()
+						if !strings.HasPrefix(tags["vpc-1/terrapwner-drift-canary-2"], "terrapwner-canary-") {
+							return fmt.Errorf("canary removed by the failed destroy: %v", tags)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
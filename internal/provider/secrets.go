@@ -0,0 +1,116 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// secretHandlePrefix prefixes the IDs of the secrets held by a secretStore.
+const secretHandlePrefix = "tpsecret-"
+
+// secretReferencePattern matches the references resolved in command templates:
+// {{secret:<handle>}} for a value held by the secret store and {{env:<name>}}
+// for an environment variable.
+var secretReferencePattern = regexp.MustCompile(`\{\{\s*(secret|env):([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// secretStore holds values harvested by data sources (e.g. tokens) for the
+// lifetime of the provider process. Data sources expose the handle of a value
+// instead of the value itself, so chained steps reference it by ID and the
+// secret is never written to the state. A handle is only valid within the
+// Terraform run that created it.
+type secretStore struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// newSecretStore returns an empty secret store.
+func newSecretStore() *secretStore {
+	return &secretStore{secrets: map[string]string{}}
+}
+
+// Put stores a value and returns its handle. A nil store keeps nothing and
+// returns an empty handle.
+func (s *secretStore) Put(value string) string {
+	if s == nil {
+		return ""
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	handle := secretHandlePrefix + hex.EncodeToString(id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[handle] = value
+	return handle
+}
+
+// Get returns the value of a handle and whether it is known.
+func (s *secretStore) Get(handle string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.secrets[handle]
+	return value, ok
+}
+
+// resolveSecretReferences replaces the {{secret:<handle>}} and {{env:<name>}}
+// references in args. It returns the resolved arguments and the resolved
+// values, so they can be redacted from the command output.
+func resolveSecretReferences(args []string, secrets *secretStore, getenv func(string) string) ([]string, []string, error) {
+	resolved := make([]string, len(args))
+	values := map[string]bool{}
+	var unknown []string
+	for i, arg := range args {
+		resolved[i] = secretReferencePattern.ReplaceAllStringFunc(arg, func(reference string) string {
+			match := secretReferencePattern.FindStringSubmatch(reference)
+			var value string
+			switch match[1] {
+			case "secret":
+				var ok bool
+				if value, ok = secrets.Get(match[2]); !ok {
+					unknown = append(unknown, match[2])
+					return reference
+				}
+			case "env":
+				value = getenv(match[2])
+			}
+			if value != "" {
+				values[value] = true
+			}
+			return value
+		})
+	}
+	if len(unknown) > 0 {
+		return nil, nil, fmt.Errorf("unknown secret handles (handles are only valid within the run that created them): %s", strings.Join(unknown, ", "))
+	}
+
+	sorted := make([]string, 0, len(values))
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+	// Redact the longest values first, so a value containing another is fully redacted
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	return resolved, sorted, nil
+}
+
+// redactSecrets replaces every occurrence of the values in s.
+func redactSecrets(s string, values []string) string {
+	for _, value := range values {
+		s = strings.ReplaceAll(s, value, "[REDACTED]")
+	}
+	return s
+}
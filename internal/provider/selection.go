@@ -0,0 +1,163 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Categories of checks, selected with the enabled_categories provider setting.
+const (
+	categoryNetwork = "network"
+	categoryExec    = "exec"
+	categoryExfil   = "exfil"
+	categoryCloud   = "cloud"
+)
+
+// suiteCategories lists the categories a data source can belong to.
+var suiteCategories = []string{categoryNetwork, categoryExec, categoryExfil, categoryCloud}
+
+// taggedDataSource is implemented by data sources that declare the categories
+// and tags they belong to. Tags that are not categories (e.g. "aws", "write")
+// can only be used to skip data sources.
+type taggedDataSource interface {
+	Tags() []string
+}
+
+// suiteSelection holds the provider-level filters deciding which data sources run.
+type suiteSelection struct {
+	enabledCategories []string
+	skipTags          []string
+}
+
+// newSuiteSelection reads the selection from the provider configuration.
+func newSuiteSelection(ctx context.Context, config TerrapwnerProviderModel, diags *diag.Diagnostics) suiteSelection {
+	var selection suiteSelection
+	if !config.EnabledCategories.IsNull() && !config.EnabledCategories.IsUnknown() {
+		diags.Append(config.EnabledCategories.ElementsAs(ctx, &selection.enabledCategories, false)...)
+	}
+	if !config.SkipTags.IsNull() && !config.SkipTags.IsUnknown() {
+		diags.Append(config.SkipTags.ElementsAs(ctx, &selection.skipTags, false)...)
+	}
+
+	for _, category := range selection.enabledCategories {
+		if !slices.Contains(suiteCategories, category) {
+			diags.AddAttributeError(
+				path.Root("enabled_categories"),
+				"Invalid Category",
+				fmt.Sprintf("Unknown category %q, expected one of: %s.", category, strings.Join(suiteCategories, ", ")),
+			)
+		}
+	}
+	return selection
+}
+
+// skipReason returns why a data source with the given tags is skipped, empty if
+// it runs. Data sources that belong to no category are not affected by
+// enabled_categories, only by skip_tags.
+func (s suiteSelection) skipReason(tags []string) string {
+	for _, tag := range tags {
+		if slices.Contains(s.skipTags, tag) {
+			return fmt.Sprintf("tag %q is listed in skip_tags", tag)
+		}
+	}
+	if len(s.enabledCategories) == 0 {
+		return ""
+	}
+
+	var categories []string
+	for _, tag := range tags {
+		if !slices.Contains(suiteCategories, tag) {
+			continue
+		}
+		if slices.Contains(s.enabledCategories, tag) {
+			return ""
+		}
+		categories = append(categories, tag)
+	}
+	if len(categories) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("none of its categories (%s) is listed in enabled_categories", strings.Join(categories, ", "))
+}
+
+// sideEffecting returns whether a data source with the given tags acts on its
+// environment, i.e. belongs to a category or writes, and is rate limited.
+func sideEffecting(tags []string) bool {
+	for _, tag := range tags {
+		if tag == "write" || slices.Contains(suiteCategories, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectiveDataSource wraps a data source so that its Read is skipped when the
+// provider selection excludes it, and paced when it is rate limited. A skipped data source keeps its configuration
+// and leaves its computed attributes null.
+type selectiveDataSource struct {
+	datasource.DataSource
+	selection suiteSelection
+	limiter   *actionLimiter
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var _ datasource.DataSourceWithConfigure = &selectiveDataSource{}
+
+// withSuiteSelection wraps data source constructors so that the data sources
+// honor the enabled_categories and skip_tags provider settings.
+func withSuiteSelection(constructors ...func() datasource.DataSource) []func() datasource.DataSource {
+	wrapped := make([]func() datasource.DataSource, 0, len(constructors))
+	for _, constructor := range constructors {
+		wrapped = append(wrapped, func() datasource.DataSource {
+			return &selectiveDataSource{DataSource: constructor()}
+		})
+	}
+	return wrapped
+}
+
+// Configure reads the selection from the provider data and configures the wrapped data source.
+func (d *selectiveDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// The wrapped data source reports unexpected provider data types
+	if snapshot, ok := req.ProviderData.(*environmentSnapshot); ok && snapshot != nil {
+		d.selection = snapshot.selection
+		d.limiter = snapshot.limiter
+	}
+	if configurable, ok := d.DataSource.(datasource.DataSourceWithConfigure); ok {
+		configurable.Configure(ctx, req, resp)
+	}
+}
+
+// Read runs the wrapped data source unless the selection excludes it, once
+// the rate limit allows it.
+func (d *selectiveDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if tagged, ok := d.DataSource.(taggedDataSource); ok {
+		if reason := d.selection.skipReason(tagged.Tags()); reason != "" {
+			resp.Diagnostics.AddWarning("Data source skipped", fmt.Sprintf("Skipped by the provider configuration: %s.", reason))
+			resp.State.Raw = req.Config.Raw
+			return
+		}
+		if sideEffecting(tagged.Tags()) {
+			waited, err := d.limiter.wait(ctx)
+			if err != nil {
+				resp.Diagnostics.AddError("Rate Limit Wait Interrupted", fmt.Sprintf("Interrupted after waiting %s for the rate limit: %v.",
+					waited.Round(time.Millisecond), err))
+				return
+			}
+			if waited > 0 {
+				tflog.Debug(ctx, "Data source paced by the rate limit", map[string]any{"waited_ms": waited.Milliseconds()})
+			}
+		}
+	}
+	d.DataSource.Read(ctx, req, resp)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestSuiteSelection_SkipReason(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection suiteSelection
+		tags      []string
+		skipped   bool
+	}{
+		{"no selection", suiteSelection{}, []string{categoryExec}, false},
+		{"enabled category", suiteSelection{enabledCategories: []string{categoryNetwork}}, []string{categoryExec, categoryNetwork}, false},
+		{"disabled category", suiteSelection{enabledCategories: []string{categoryCloud}}, []string{categoryExec}, true},
+		{"no category", suiteSelection{enabledCategories: []string{categoryCloud}}, []string{"ci"}, false},
+		{"skipped tag", suiteSelection{skipTags: []string{"write"}}, []string{categoryCloud, "write"}, true},
+		{"skipped category", suiteSelection{enabledCategories: []string{categoryCloud}, skipTags: []string{categoryCloud}}, []string{categoryCloud}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selection.skipReason(tt.tags); (got != "") != tt.skipped {
+				t.Errorf("skipReason(%v) = %q, want skipped = %v", tt.tags, got, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestAccSuiteSelection(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "terrapwner" {
+  enabled_categories = ["network"]
+  skip_tags          = ["secrets"]
+}
+
+data "terrapwner_local_exec" "test" {
+  command = ["echo", "hello"]
+}
+
+data "terrapwner_env_dump" "test" {}
+
+data "terrapwner_ci_detect" "test" {}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.terrapwner_local_exec.test", "command.0", "echo"),
+					resource.TestCheckNoResourceAttr("data.terrapwner_local_exec.test", "stdout"),
+					resource.TestCheckNoResourceAttr("data.terrapwner_env_dump.test", "vars.%"),
+					resource.TestCheckResourceAttrSet("data.terrapwner_ci_detect.test", "detected_platforms.#"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccSuiteSelection_InvalidCategory(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "terrapwner" {
+  enabled_categories = ["bogus"]
+}
+
+data "terrapwner_ci_detect" "test" {}
+`,
+				ExpectError: regexp.MustCompile(`Unknown category "bogus"`),
+			},
+		},
+	})
+}
@@ -0,0 +1,279 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ONC RPC programs and procedures used to list and mount NFS exports.
+const (
+	rpcPortmapperProgram = 100000
+	rpcPortmapperVersion = 2
+	rpcPortmapperGetPort = 3
+	rpcMountProgram      = 100005
+	rpcMountVersion      = 3
+	rpcMountMnt          = 1
+	rpcMountUmnt         = 3
+	rpcMountExport       = 5
+
+	rpcAuthNone = 0
+	rpcAuthUnix = 1
+
+	// rpcMaxRecordSize bounds the size of an RPC reply.
+	rpcMaxRecordSize = 4 << 20
+)
+
+// nfsPortmapperPort is the port of rpcbind, queried for the port of mountd.
+var nfsPortmapperPort = 111
+
+// nfsExport is a directory exported by an NFS server.
+type nfsExport struct {
+	path string
+	// groups are the clients allowed to mount the export, empty for everyone
+	groups []string
+}
+
+// nfsMountdAddress returns the address of the NFS v3 mount daemon of the host.
+func nfsMountdAddress(ctx context.Context, host string, timeout time.Duration) (string, error) {
+	address := net.JoinHostPort(host, strconv.Itoa(nfsPortmapperPort))
+	args := binary.BigEndian.AppendUint32(nil, rpcMountProgram)
+	args = binary.BigEndian.AppendUint32(args, rpcMountVersion)
+	args = binary.BigEndian.AppendUint32(args, uint32(6)) // IPPROTO_TCP
+	args = binary.BigEndian.AppendUint32(args, 0)
+	reply, err := rpcCall(ctx, address, false, rpcPortmapperProgram, rpcPortmapperVersion, rpcPortmapperGetPort, args, timeout)
+	if err != nil {
+		return "", fmt.Errorf("portmapper: %w", err)
+	}
+	r := &xdrReader{buf: reply}
+	port := r.uint32()
+	if r.err != nil {
+		return "", fmt.Errorf("portmapper: %w", r.err)
+	}
+	if port == 0 {
+		return "", errors.New("mountd is not registered")
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// nfsListExports returns the exports advertised by a mount daemon, like showmount -e.
+func nfsListExports(ctx context.Context, mountd string, timeout time.Duration) ([]nfsExport, error) {
+	reply, err := rpcCall(ctx, mountd, false, rpcMountProgram, rpcMountVersion, rpcMountExport, nil, timeout)
+	if err != nil {
+		return nil, err
+	}
+	r := &xdrReader{buf: reply}
+	var exports []nfsExport
+	for r.uint32() == 1 && r.err == nil {
+		export := nfsExport{path: r.string()}
+		for r.uint32() == 1 && r.err == nil {
+			export.groups = append(export.groups, r.string())
+		}
+		exports = append(exports, export)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return exports, nil
+}
+
+// nfsMountable asks the mount daemon for the file handle of an export with
+// AUTH_UNIX credentials, and releases the mount if granted. The error is set
+// if the daemon did not answer.
+func nfsMountable(ctx context.Context, mountd, path string, timeout time.Duration) (bool, error) {
+	args := xdrString(nil, path)
+	reply, err := rpcCall(ctx, mountd, true, rpcMountProgram, rpcMountVersion, rpcMountMnt, args, timeout)
+	if err != nil {
+		return false, err
+	}
+	r := &xdrReader{buf: reply}
+	status := r.uint32()
+	if r.err != nil {
+		return false, r.err
+	}
+	if status != 0 {
+		return false, nil
+	}
+
+	// Remove the entry from the rmtab of the server
+	_, _ = rpcCall(ctx, mountd, true, rpcMountProgram, rpcMountVersion, rpcMountUmnt, args, timeout)
+	return true, nil
+}
+
+// rpcCall sends an ONC RPC call over TCP and returns the results of the reply.
+// Calls with AUTH_UNIX credentials are sent from a reserved port when the
+// process is allowed to bind one, as servers exporting with the secure option
+// require.
+func rpcCall(ctx context.Context, address string, authUnix bool, program, version, procedure uint32, args []byte, timeout time.Duration) ([]byte, error) {
+	conn, err := dialRPC(ctx, address, authUnix, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	xid := rand.Uint32()
+	call := binary.BigEndian.AppendUint32(nil, xid)
+	call = binary.BigEndian.AppendUint32(call, 0) // CALL
+	call = binary.BigEndian.AppendUint32(call, 2) // RPC version
+	call = binary.BigEndian.AppendUint32(call, program)
+	call = binary.BigEndian.AppendUint32(call, version)
+	call = binary.BigEndian.AppendUint32(call, procedure)
+	if authUnix {
+		hostname, _ := os.Hostname()
+		cred := binary.BigEndian.AppendUint32(nil, uint32(time.Now().Unix()))
+		cred = xdrString(cred, hostname)
+		cred = binary.BigEndian.AppendUint32(cred, 0) // uid
+		cred = binary.BigEndian.AppendUint32(cred, 0) // gid
+		cred = binary.BigEndian.AppendUint32(cred, 0) // no auxiliary gids
+		call = binary.BigEndian.AppendUint32(call, rpcAuthUnix)
+		call = xdrOpaque(call, cred)
+	} else {
+		call = binary.BigEndian.AppendUint32(call, rpcAuthNone)
+		call = binary.BigEndian.AppendUint32(call, 0)
+	}
+	call = binary.BigEndian.AppendUint32(call, rpcAuthNone) // verifier
+	call = binary.BigEndian.AppendUint32(call, 0)
+	call = append(call, args...)
+
+	// Single fragment record
+	record := binary.BigEndian.AppendUint32(nil, 0x80000000|uint32(len(call)))
+	if _, err := conn.Write(append(record, call...)); err != nil {
+		return nil, err
+	}
+	reply, err := readRPCRecord(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &xdrReader{buf: reply}
+	if r.uint32() != xid || r.uint32() != 1 {
+		return nil, errors.New("unexpected RPC reply")
+	}
+	if r.uint32() != 0 {
+		return nil, errors.New("RPC call denied")
+	}
+	r.uint32() // verifier flavor
+	r.opaque()
+	acceptStatus := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	switch acceptStatus {
+	case 0:
+		return reply[r.off:], nil
+	case 1, 2:
+		return nil, errors.New("program unavailable")
+	case 3:
+		return nil, errors.New("procedure unavailable")
+	default:
+		return nil, fmt.Errorf("RPC call failed with status %d", acceptStatus)
+	}
+}
+
+// dialRPC connects to an RPC service, from a reserved port if requested and permitted.
+func dialRPC(ctx context.Context, address string, reserved bool, timeout time.Duration) (net.Conn, error) {
+	if reserved {
+		for port := 1023; port > 1013; port-- {
+			dialer := &net.Dialer{Timeout: timeout, LocalAddr: &net.TCPAddr{Port: port}}
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			if errors.Is(err, syscall.EADDRINUSE) || errors.Is(err, syscall.EADDRNOTAVAIL) {
+				continue
+			}
+			if !errors.Is(err, os.ErrPermission) {
+				return conn, err
+			}
+			break
+		}
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// readRPCRecord reads the fragments of a record marked RPC message.
+func readRPCRecord(r io.Reader) ([]byte, error) {
+	var record []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, err
+		}
+		marker := binary.BigEndian.Uint32(header[:])
+		size := int(marker & 0x7fffffff)
+		if len(record)+size > rpcMaxRecordSize {
+			return nil, errors.New("RPC reply too large")
+		}
+		fragment := make([]byte, size)
+		if _, err := io.ReadFull(r, fragment); err != nil {
+			return nil, err
+		}
+		record = append(record, fragment...)
+		if marker&0x80000000 != 0 {
+			return record, nil
+		}
+	}
+}
+
+// xdrOpaque appends variable-length opaque data, padded to four bytes.
+func xdrOpaque(b []byte, data []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(data)))
+	b = append(b, data...)
+	return append(b, make([]byte, (4-len(data)%4)%4)...)
+}
+
+// xdrString appends a string.
+func xdrString(b []byte, s string) []byte {
+	return xdrOpaque(b, []byte(s))
+}
+
+// xdrReader decodes XDR data, recording the first error.
+type xdrReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *xdrReader) uint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf)-r.off < 4 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.buf[r.off:])
+	r.off += 4
+	return v
+}
+
+func (r *xdrReader) opaque() []byte {
+	size := int(r.uint32())
+	padded := size + (4-size%4)%4
+	if r.err != nil {
+		return nil
+	}
+	if size < 0 || len(r.buf)-r.off < padded {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	data := r.buf[r.off : r.off+size]
+	r.off += padded
+	return data
+}
+
+func (r *xdrReader) string() string {
+	return string(r.opaque())
+}
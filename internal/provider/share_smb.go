@@ -0,0 +1,774 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// SMB2 commands.
+const (
+	smb2Negotiate      = 0x0000
+	smb2SessionSetup   = 0x0001
+	smb2Logoff         = 0x0002
+	smb2TreeConnect    = 0x0003
+	smb2TreeDisconnect = 0x0004
+	smb2Create         = 0x0005
+	smb2Close          = 0x0006
+	smb2Read           = 0x0008
+	smb2Ioctl          = 0x000b
+)
+
+// NT status codes.
+const (
+	ntStatusSuccess                = 0x00000000
+	ntStatusPending                = 0x00000103
+	ntStatusBufferOverflow         = 0x80000005
+	ntStatusMoreProcessingRequired = 0xc0000016
+	ntStatusAccessDenied           = 0xc0000022
+	ntStatusLogonFailure           = 0xc000006d
+	ntStatusAccountDisabled        = 0xc0000072
+	ntStatusBadNetworkName         = 0xc00000cc
+)
+
+// smb2SessionFlagIsGuest is set when the server maps the session to its guest account.
+const smb2SessionFlagIsGuest = 0x0001
+
+const (
+	smb2HeaderSize = 64
+	// smbMaxMessageSize bounds the size of a response.
+	smbMaxMessageSize = 1 << 20
+	// smbMaxPipeResponse is the largest pipe response read at once.
+	smbMaxPipeResponse = 65536
+	// smbGuestUser is the account used for guest logons, accepted with an
+	// empty password by servers mapping unknown users to guest.
+	smbGuestUser = "guest"
+	// shareTypeDisk is the type of file shares, as opposed to printers and pipes.
+	shareTypeDisk = 0
+)
+
+// smbPort is the port of SMB over TCP.
+var smbPort = 445
+
+// Sessions granted by an SMB server without credentials.
+const (
+	smbSessionAnonymous   = "anonymous"
+	smbSessionGuest       = "guest"
+	smbSessionDenied      = "denied"
+	smbSessionUnreachable = "unreachable"
+)
+
+// Object identifiers of the SPNEGO and NTLM security mechanisms.
+var (
+	spnegoOID = []byte{0x2b, 0x06, 0x01, 0x05, 0x05, 0x02}
+	ntlmOID   = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37, 0x02, 0x02, 0x0a}
+)
+
+// smbShare is a share advertised by an SMB server.
+type smbShare struct {
+	name      string
+	shareType uint32
+}
+
+// smbSession is an unsigned SMB2 session over a single connection.
+type smbSession struct {
+	conn      net.Conn
+	host      string
+	timeout   time.Duration
+	dialect   uint16
+	messageID uint64
+	sessionID uint64
+	// kind is anonymous or guest
+	kind string
+}
+
+// smbStatusError is an unexpected NT status in a response.
+type smbStatusError uint32
+
+func (e smbStatusError) Error() string {
+	switch uint32(e) {
+	case ntStatusAccessDenied:
+		return "access denied"
+	case ntStatusLogonFailure:
+		return "logon failure"
+	case ntStatusAccountDisabled:
+		return "account disabled"
+	case ntStatusBadNetworkName:
+		return "bad network name"
+	default:
+		return fmt.Sprintf("NT status 0x%08x", uint32(e))
+	}
+}
+
+// smbLogon connects to the host and logs on anonymously, or as guest if the
+// server rejects anonymous sessions. The error is set if neither is granted.
+func smbLogon(ctx context.Context, host string, timeout time.Duration) (*smbSession, error) {
+	var err error
+	for _, user := range []string{"", smbGuestUser} {
+		var s *smbSession
+		s, err = dialSMB(ctx, host, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.sessionSetup(user); err == nil {
+			return s, nil
+		}
+		s.conn.Close()
+	}
+	return nil, err
+}
+
+// dialSMB connects to the host and negotiates an SMB 2 or 3 dialect without signing.
+func dialSMB(ctx context.Context, host string, timeout time.Duration) (*smbSession, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(smbPort)))
+	if err != nil {
+		return nil, err
+	}
+	s := &smbSession{conn: conn, host: host, timeout: timeout}
+
+	dialects := []uint16{0x0202, 0x0210, 0x0300, 0x0302}
+	body := make([]byte, 36, 36+2*len(dialects))
+	binary.LittleEndian.PutUint16(body[0:], 36)
+	binary.LittleEndian.PutUint16(body[2:], uint16(len(dialects)))
+	binary.LittleEndian.PutUint16(body[4:], 1) // signing enabled, not required
+	_, _ = rand.Read(body[12:28])              // client GUID
+	for _, dialect := range dialects {
+		body = binary.LittleEndian.AppendUint16(body, dialect)
+	}
+	status, msg, err := s.request(smb2Negotiate, 0, body)
+	if err == nil && status != ntStatusSuccess {
+		err = smbStatusError(status)
+	}
+	if err == nil && len(msg) < smb2HeaderSize+6 {
+		err = errors.New("short negotiate response")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SMB2 negotiation failed: %w", err)
+	}
+	s.dialect = binary.LittleEndian.Uint16(msg[smb2HeaderSize+4:])
+	return s, nil
+}
+
+// sessionSetup authenticates with NTLM wrapped in SPNEGO, anonymously if user
+// is empty, else with an empty password.
+func (s *smbSession) sessionSetup(user string) error {
+	status, msg, err := s.request(smb2SessionSetup, 0, sessionSetupBody(spnegoInit(ntlmNegotiateMessage())))
+	if err != nil {
+		return err
+	}
+	if status != ntStatusMoreProcessingRequired {
+		return smbStatusError(status)
+	}
+	s.sessionID = binary.LittleEndian.Uint64(msg[40:])
+	token, err := sessionSetupToken(msg)
+	if err != nil {
+		return err
+	}
+	index := bytes.Index(token, []byte("NTLMSSP\x00"))
+	if index < 0 {
+		return errors.New("no NTLM challenge in session setup response")
+	}
+	authenticate, err := ntlmAuthenticateMessage(token[index:], user)
+	if err != nil {
+		return err
+	}
+
+	status, msg, err = s.request(smb2SessionSetup, 0, sessionSetupBody(spnegoResponse(authenticate)))
+	if err != nil {
+		return err
+	}
+	if status != ntStatusSuccess {
+		return smbStatusError(status)
+	}
+	// Servers may map anonymous sessions to their guest account
+	s.kind = smbSessionGuest
+	if user == "" && binary.LittleEndian.Uint16(msg[smb2HeaderSize+2:])&smb2SessionFlagIsGuest == 0 {
+		s.kind = smbSessionAnonymous
+	}
+	return nil
+}
+
+// sessionSetupBody returns a SESSION_SETUP request carrying a security token.
+func sessionSetupBody(token []byte) []byte {
+	body := make([]byte, 24, 24+len(token))
+	binary.LittleEndian.PutUint16(body[0:], 25)
+	body[3] = 1 // signing enabled
+	binary.LittleEndian.PutUint16(body[12:], smb2HeaderSize+24)
+	binary.LittleEndian.PutUint16(body[14:], uint16(len(token)))
+	return append(body, token...)
+}
+
+// sessionSetupToken returns the security token of a SESSION_SETUP response.
+func sessionSetupToken(msg []byte) ([]byte, error) {
+	if len(msg) < smb2HeaderSize+8 {
+		return nil, errors.New("short session setup response")
+	}
+	offset := int(binary.LittleEndian.Uint16(msg[smb2HeaderSize+4:]))
+	length := int(binary.LittleEndian.Uint16(msg[smb2HeaderSize+6:]))
+	if offset+length > len(msg) {
+		return nil, errors.New("short session setup response")
+	}
+	return msg[offset : offset+length], nil
+}
+
+// ListShares enumerates the shares of the server through the srvsvc pipe,
+// like smbclient -L.
+func (s *smbSession) ListShares() ([]smbShare, error) {
+	treeID, err := s.treeConnect("IPC$")
+	if err != nil {
+		return nil, fmt.Errorf("IPC$: %w", err)
+	}
+	defer s.treeDisconnect(treeID)
+	fileID, err := s.create(treeID, "srvsvc", false)
+	if err != nil {
+		return nil, fmt.Errorf("srvsvc: %w", err)
+	}
+	defer s.close(treeID, fileID)
+
+	// Bind to SRVSVC v3.0 with the NDR transfer syntax
+	bind := make([]byte, 0, 72)
+	bind = binary.LittleEndian.AppendUint16(bind, 4280) // max transmit fragment
+	bind = binary.LittleEndian.AppendUint16(bind, 4280) // max receive fragment
+	bind = binary.LittleEndian.AppendUint32(bind, 0)    // association group
+	bind = append(bind, 1, 0, 0, 0)                     // one context
+	bind = binary.LittleEndian.AppendUint16(bind, 0)    // context ID
+	bind = append(bind, 1, 0)                           // one transfer syntax
+	bind = append(bind, dceUUID("4b324fc8-1670-01d3-1278-5a47bf6ee188")...)
+	bind = binary.LittleEndian.AppendUint32(bind, 3)
+	bind = append(bind, dceUUID("8a885d04-1ceb-11c9-9fe8-08002b104860")...)
+	bind = binary.LittleEndian.AppendUint32(bind, 2)
+	reply, err := s.pipeTransceive(treeID, fileID, dcePDU(dcePTypeBind, 1, bind))
+	if err != nil {
+		return nil, fmt.Errorf("srvsvc bind: %w", err)
+	}
+	if err := checkBindAck(reply); err != nil {
+		return nil, fmt.Errorf("srvsvc bind: %w", err)
+	}
+
+	// NetrShareEnum at level 1 (names and types)
+	serverName := `\\` + s.host
+	stub := binary.LittleEndian.AppendUint32(nil, 0x00020000) // server name referent
+	stub = ndrString(stub, serverName)
+	stub = binary.LittleEndian.AppendUint32(stub, 1)          // level
+	stub = binary.LittleEndian.AppendUint32(stub, 1)          // union discriminant
+	stub = binary.LittleEndian.AppendUint32(stub, 0x00020004) // container referent
+	stub = binary.LittleEndian.AppendUint32(stub, 0)          // entries read
+	stub = binary.LittleEndian.AppendUint32(stub, 0)          // null buffer
+	stub = binary.LittleEndian.AppendUint32(stub, 0xffffffff) // preferred maximum length
+	stub = binary.LittleEndian.AppendUint32(stub, 0x00020008) // resume handle referent
+	stub = binary.LittleEndian.AppendUint32(stub, 0)
+	request := binary.LittleEndian.AppendUint32(nil, uint32(len(stub))) // allocation hint
+	request = binary.LittleEndian.AppendUint16(request, 0)              // context ID
+	request = binary.LittleEndian.AppendUint16(request, 15)             // NetrShareEnum
+	request = append(request, stub...)
+	reply, err = s.pipeTransceive(treeID, fileID, dcePDU(dcePTypeRequest, 2, request))
+	if err != nil {
+		return nil, fmt.Errorf("NetrShareEnum: %w", err)
+	}
+
+	// Reassemble the response stub from its fragments
+	var response []byte
+	for {
+		for len(reply) < 16 || len(reply) < int(binary.LittleEndian.Uint16(reply[8:])) {
+			more, err := s.read(treeID, fileID)
+			if err != nil {
+				return nil, fmt.Errorf("NetrShareEnum: %w", err)
+			}
+			reply = append(reply, more...)
+		}
+		fragLength := int(binary.LittleEndian.Uint16(reply[8:]))
+		if fragLength < 28 {
+			return nil, errors.New("NetrShareEnum: short response")
+		}
+		switch reply[2] {
+		case dcePTypeResponse:
+			response = append(response, reply[24:fragLength]...)
+		case dcePTypeFault:
+			return nil, fmt.Errorf("NetrShareEnum: fault 0x%08x", binary.LittleEndian.Uint32(reply[24:]))
+		default:
+			return nil, fmt.Errorf("NetrShareEnum: unexpected PDU type %d", reply[2])
+		}
+		last := reply[3]&dcePFCLastFrag != 0
+		reply = reply[fragLength:]
+		if last {
+			break
+		}
+	}
+	return parseShareEnum(response)
+}
+
+// ShareReadable reports whether the root directory of a share can be listed.
+// The error is set for failures other than denied access.
+func (s *smbSession) ShareReadable(share string) (bool, error) {
+	treeID, err := s.treeConnect(share)
+	if err != nil {
+		var status smbStatusError
+		if errors.As(err, &status) && (status == ntStatusAccessDenied || status == ntStatusBadNetworkName) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer s.treeDisconnect(treeID)
+	fileID, err := s.create(treeID, "", true)
+	var status smbStatusError
+	if errors.As(err, &status) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.close(treeID, fileID)
+	return true, nil
+}
+
+// Close logs off and closes the connection.
+func (s *smbSession) Close() {
+	_, _, _ = s.request(smb2Logoff, 0, []byte{4, 0, 0, 0})
+	s.conn.Close()
+}
+
+func (s *smbSession) treeConnect(share string) (uint32, error) {
+	path := utf16LE(`\\` + s.host + `\` + share)
+	body := make([]byte, 8, 8+len(path))
+	binary.LittleEndian.PutUint16(body[0:], 9)
+	binary.LittleEndian.PutUint16(body[4:], smb2HeaderSize+8)
+	binary.LittleEndian.PutUint16(body[6:], uint16(len(path)))
+	status, msg, err := s.request(smb2TreeConnect, 0, append(body, path...))
+	if err != nil {
+		return 0, err
+	}
+	if status != ntStatusSuccess {
+		return 0, smbStatusError(status)
+	}
+	return binary.LittleEndian.Uint32(msg[36:]), nil
+}
+
+func (s *smbSession) treeDisconnect(treeID uint32) {
+	_, _, _ = s.request(smb2TreeDisconnect, treeID, []byte{4, 0, 0, 0})
+}
+
+// create opens the root directory of a disk share, or a named pipe, for reading.
+func (s *smbSession) create(treeID uint32, name string, directory bool) ([]byte, error) {
+	encoded := utf16LE(name)
+	body := make([]byte, 56, 56+len(encoded)+1)
+	binary.LittleEndian.PutUint16(body[0:], 57)
+	binary.LittleEndian.PutUint32(body[4:], 2) // impersonation
+	if directory {
+		binary.LittleEndian.PutUint32(body[24:], 0x00100081) // list directory, read attributes, synchronize
+		binary.LittleEndian.PutUint32(body[32:], 0x00000007) // share read, write and delete
+		binary.LittleEndian.PutUint32(body[40:], 0x00000001) // directory file
+	} else {
+		binary.LittleEndian.PutUint32(body[24:], 0x0012019f) // generic read and write
+		binary.LittleEndian.PutUint32(body[32:], 0x00000003) // share read and write
+	}
+	binary.LittleEndian.PutUint32(body[36:], 1) // open existing
+	binary.LittleEndian.PutUint16(body[44:], smb2HeaderSize+56)
+	binary.LittleEndian.PutUint16(body[46:], uint16(len(encoded)))
+	body = append(body, encoded...)
+	if len(encoded) == 0 {
+		body = append(body, 0)
+	}
+	status, msg, err := s.request(smb2Create, treeID, body)
+	if err != nil {
+		return nil, err
+	}
+	if status != ntStatusSuccess {
+		return nil, smbStatusError(status)
+	}
+	if len(msg) < smb2HeaderSize+80 {
+		return nil, errors.New("short create response")
+	}
+	return msg[smb2HeaderSize+64 : smb2HeaderSize+80], nil
+}
+
+func (s *smbSession) close(treeID uint32, fileID []byte) {
+	body := make([]byte, 8, 24)
+	binary.LittleEndian.PutUint16(body[0:], 24)
+	_, _, _ = s.request(smb2Close, treeID, append(body, fileID...))
+}
+
+// pipeTransceive writes a message to a named pipe and reads the response.
+func (s *smbSession) pipeTransceive(treeID uint32, fileID []byte, input []byte) ([]byte, error) {
+	body := make([]byte, 56, 56+len(input))
+	binary.LittleEndian.PutUint16(body[0:], 57)
+	binary.LittleEndian.PutUint32(body[4:], 0x0011c017) // FSCTL_PIPE_TRANSCEIVE
+	copy(body[8:], fileID)
+	binary.LittleEndian.PutUint32(body[24:], smb2HeaderSize+56)
+	binary.LittleEndian.PutUint32(body[28:], uint32(len(input)))
+	binary.LittleEndian.PutUint32(body[44:], smbMaxPipeResponse)
+	binary.LittleEndian.PutUint32(body[48:], 1) // FSCTL
+	status, msg, err := s.request(smb2Ioctl, treeID, append(body, input...))
+	if err != nil {
+		return nil, err
+	}
+	if status != ntStatusSuccess && status != ntStatusBufferOverflow {
+		return nil, smbStatusError(status)
+	}
+	if len(msg) < smb2HeaderSize+40 {
+		return nil, errors.New("short ioctl response")
+	}
+	offset := int(binary.LittleEndian.Uint32(msg[smb2HeaderSize+32:]))
+	count := int(binary.LittleEndian.Uint32(msg[smb2HeaderSize+36:]))
+	if offset+count > len(msg) {
+		return nil, errors.New("short ioctl response")
+	}
+	return msg[offset : offset+count], nil
+}
+
+// read reads the pending data of a named pipe.
+func (s *smbSession) read(treeID uint32, fileID []byte) ([]byte, error) {
+	body := make([]byte, 49)
+	binary.LittleEndian.PutUint16(body[0:], 49)
+	body[2] = smb2HeaderSize + 16 // data offset in the response
+	binary.LittleEndian.PutUint32(body[4:], smbMaxPipeResponse)
+	copy(body[16:], fileID)
+	status, msg, err := s.request(smb2Read, treeID, body)
+	if err != nil {
+		return nil, err
+	}
+	if status != ntStatusSuccess && status != ntStatusBufferOverflow {
+		return nil, smbStatusError(status)
+	}
+	if len(msg) < smb2HeaderSize+16 {
+		return nil, errors.New("short read response")
+	}
+	offset := int(msg[smb2HeaderSize+2])
+	length := int(binary.LittleEndian.Uint32(msg[smb2HeaderSize+4:]))
+	if offset+length > len(msg) || length == 0 {
+		return nil, errors.New("short read response")
+	}
+	return msg[offset : offset+length], nil
+}
+
+// request sends an SMB2 request and returns the status and the whole response,
+// skipping interim responses.
+func (s *smbSession) request(command uint16, treeID uint32, body []byte) (uint32, []byte, error) {
+	if err := s.conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return 0, nil, err
+	}
+	header := make([]byte, smb2HeaderSize)
+	copy(header, "\xfeSMB")
+	binary.LittleEndian.PutUint16(header[4:], smb2HeaderSize)
+	if s.dialect > 0x0202 {
+		binary.LittleEndian.PutUint16(header[6:], 1) // credit charge
+	}
+	binary.LittleEndian.PutUint16(header[12:], command)
+	binary.LittleEndian.PutUint16(header[14:], 32) // credits requested
+	binary.LittleEndian.PutUint64(header[24:], s.messageID)
+	binary.LittleEndian.PutUint32(header[36:], treeID)
+	binary.LittleEndian.PutUint64(header[40:], s.sessionID)
+	messageID := s.messageID
+	s.messageID++
+
+	packet := make([]byte, 4, 4+len(header)+len(body))
+	binary.BigEndian.PutUint32(packet, uint32(len(header)+len(body)))
+	packet = append(append(packet, header...), body...)
+	if _, err := s.conn.Write(packet); err != nil {
+		return 0, nil, err
+	}
+
+	for {
+		var length [4]byte
+		if _, err := io.ReadFull(s.conn, length[:]); err != nil {
+			return 0, nil, err
+		}
+		size := binary.BigEndian.Uint32(length[:]) & 0x00ffffff
+		if size < smb2HeaderSize+4 || size > smbMaxMessageSize {
+			return 0, nil, fmt.Errorf("invalid SMB2 message size %d", size)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(s.conn, msg); err != nil {
+			return 0, nil, err
+		}
+		if !bytes.HasPrefix(msg, []byte("\xfeSMB")) {
+			return 0, nil, errors.New("not an SMB2 response")
+		}
+		status := binary.LittleEndian.Uint32(msg[8:])
+		if binary.LittleEndian.Uint64(msg[24:]) != messageID || status == ntStatusPending {
+			continue
+		}
+		return status, msg, nil
+	}
+}
+
+// ntlmNegotiateMessage returns an NTLM NEGOTIATE_MESSAGE.
+func ntlmNegotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateFlags)
+	return msg
+}
+
+// ntlmNegotiateFlags requests Unicode, NTLMv2 session security and target information.
+const ntlmNegotiateFlags = 0x00000001 | 0x00000004 | 0x00000200 | 0x00008000 | 0x00080000 | 0x00800000 | 0x20000000 | 0x80000000
+
+// ntlmAuthenticateMessage answers an NTLM CHALLENGE_MESSAGE anonymously if
+// user is empty, else with an NTLMv2 response for an empty password.
+func ntlmAuthenticateMessage(challenge []byte, user string) ([]byte, error) {
+	if len(challenge) < 48 || binary.LittleEndian.Uint32(challenge[8:]) != 2 {
+		return nil, errors.New("invalid NTLM challenge")
+	}
+	serverChallenge := challenge[24:32]
+	infoLength := int(binary.LittleEndian.Uint16(challenge[40:]))
+	infoOffset := int(binary.LittleEndian.Uint32(challenge[44:]))
+	if infoOffset+infoLength > len(challenge) {
+		return nil, errors.New("invalid NTLM challenge")
+	}
+	targetInfo := challenge[infoOffset : infoOffset+infoLength]
+
+	flags := uint32(ntlmNegotiateFlags)
+	var lmResponse, ntResponse []byte
+	if user == "" {
+		flags |= 0x00000800 // anonymous
+		lmResponse = []byte{0}
+	} else {
+		// NTOWFv2 of an empty password, whose MD4 hash is well known
+		emptyHash, _ := hex.DecodeString("31d6cfe0d16ae931b73c59d7e0c089c0")
+		mac := hmac.New(md5.New, emptyHash)
+		mac.Write(utf16LE(strings.ToUpper(user)))
+		ntowf := mac.Sum(nil)
+
+		blob := []byte{1, 1, 0, 0, 0, 0, 0, 0}
+		blob = binary.LittleEndian.AppendUint64(blob, ntlmTimestamp(targetInfo))
+		clientChallenge := make([]byte, 8)
+		_, _ = rand.Read(clientChallenge)
+		blob = append(blob, clientChallenge...)
+		blob = append(blob, 0, 0, 0, 0)
+		blob = append(blob, targetInfo...)
+		blob = append(blob, 0, 0, 0, 0)
+		mac = hmac.New(md5.New, ntowf)
+		mac.Write(serverChallenge)
+		mac.Write(blob)
+		ntResponse = append(mac.Sum(nil), blob...)
+		lmResponse = make([]byte, 24)
+	}
+
+	// Fields are appended after the 64 bytes of fixed header
+	msg := make([]byte, 64)
+	copy(msg, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+	binary.LittleEndian.PutUint32(msg[60:], flags)
+	for _, field := range []struct {
+		offset int
+		value  []byte
+	}{
+		{12, lmResponse},
+		{20, ntResponse},
+		{28, nil}, // domain
+		{36, utf16LE(user)},
+		{44, nil}, // workstation
+		{52, nil}, // session key
+	} {
+		binary.LittleEndian.PutUint16(msg[field.offset:], uint16(len(field.value)))
+		binary.LittleEndian.PutUint16(msg[field.offset+2:], uint16(len(field.value)))
+		binary.LittleEndian.PutUint32(msg[field.offset+4:], uint32(len(msg)))
+		msg = append(msg, field.value...)
+	}
+	return msg, nil
+}
+
+// ntlmTimestamp returns the server time from the target information, else the
+// current time, in 100ns intervals since 1601.
+func ntlmTimestamp(targetInfo []byte) uint64 {
+	for len(targetInfo) >= 4 {
+		id := binary.LittleEndian.Uint16(targetInfo)
+		length := int(binary.LittleEndian.Uint16(targetInfo[2:]))
+		if id == 0 || len(targetInfo) < 4+length {
+			break
+		}
+		if id == 7 && length == 8 {
+			return binary.LittleEndian.Uint64(targetInfo[4:])
+		}
+		targetInfo = targetInfo[4+length:]
+	}
+	return uint64(time.Now().UnixNano()/100) + 116444736000000000
+}
+
+// spnegoInit wraps an NTLM message in a SPNEGO NegTokenInit.
+func spnegoInit(mechToken []byte) []byte {
+	mechTypes := derTLV(0xa0, derTLV(0x30, derTLV(0x06, ntlmOID)))
+	negTokenInit := derTLV(0xa0, derTLV(0x30, append(mechTypes, derTLV(0xa2, derTLV(0x04, mechToken))...)))
+	return derTLV(0x60, append(derTLV(0x06, spnegoOID), negTokenInit...))
+}
+
+// spnegoResponse wraps an NTLM message in a SPNEGO NegTokenResp.
+func spnegoResponse(responseToken []byte) []byte {
+	return derTLV(0xa1, derTLV(0x30, derTLV(0xa2, derTLV(0x04, responseToken))))
+}
+
+// derTLV encodes a DER element.
+func derTLV(tag byte, value []byte) []byte {
+	b := []byte{tag}
+	switch n := len(value); {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n < 0x100:
+		b = append(b, 0x81, byte(n))
+	default:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	}
+	return append(b, value...)
+}
+
+// DCE/RPC packet types and flags.
+const (
+	dcePTypeRequest  = 0
+	dcePTypeResponse = 2
+	dcePTypeFault    = 3
+	dcePTypeBind     = 11
+	dcePTypeBindAck  = 12
+
+	dcePFCFirstFrag = 0x01
+	dcePFCLastFrag  = 0x02
+)
+
+// dcePDU returns a single fragment connection-oriented DCE/RPC PDU.
+func dcePDU(ptype byte, callID uint32, body []byte) []byte {
+	pdu := []byte{5, 0, ptype, dcePFCFirstFrag | dcePFCLastFrag, 0x10, 0, 0, 0}
+	pdu = binary.LittleEndian.AppendUint16(pdu, uint16(16+len(body)))
+	pdu = binary.LittleEndian.AppendUint16(pdu, 0) // no authentication
+	pdu = binary.LittleEndian.AppendUint32(pdu, callID)
+	return append(pdu, body...)
+}
+
+// checkBindAck returns an error unless the PDU accepts the presentation context.
+func checkBindAck(pdu []byte) error {
+	if len(pdu) < 26 || pdu[2] != dcePTypeBindAck {
+		return errors.New("bind rejected")
+	}
+	// The result list follows the secondary address, aligned to four bytes
+	offset := 26 + int(binary.LittleEndian.Uint16(pdu[24:]))
+	offset += (4 - offset%4) % 4
+	if len(pdu) < offset+6 || pdu[offset] == 0 {
+		return errors.New("short bind acknowledgement")
+	}
+	if result := binary.LittleEndian.Uint16(pdu[offset+4:]); result != 0 {
+		return fmt.Errorf("presentation context rejected with result %d", result)
+	}
+	return nil
+}
+
+// dceUUID encodes a UUID in the little-endian DCE/RPC layout.
+func dceUUID(s string) []byte {
+	raw, _ := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	uuid := make([]byte, 16)
+	binary.LittleEndian.PutUint32(uuid[0:], binary.BigEndian.Uint32(raw[0:]))
+	binary.LittleEndian.PutUint16(uuid[4:], binary.BigEndian.Uint16(raw[4:]))
+	binary.LittleEndian.PutUint16(uuid[6:], binary.BigEndian.Uint16(raw[6:]))
+	copy(uuid[8:], raw[8:])
+	return uuid
+}
+
+// ndrString appends a null-terminated conformant varying UTF-16 string.
+func ndrString(b []byte, s string) []byte {
+	encoded := utf16LE(s + "\x00")
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(encoded)/2))
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(encoded)/2))
+	b = append(b, encoded...)
+	return append(b, make([]byte, (4-len(b)%4)%4)...)
+}
+
+// parseShareEnum decodes the SHARE_INFO_1 entries of a NetrShareEnum response stub.
+func parseShareEnum(stub []byte) ([]smbShare, error) {
+	r := &ndrReader{buf: stub}
+	r.uint32() // level
+	r.uint32() // union discriminant
+	if r.uint32() == 0 {
+		return nil, errors.New("NetrShareEnum: empty container")
+	}
+	count := int(r.uint32())
+	if r.uint32() == 0 || r.err != nil {
+		return nil, r.err
+	}
+	if r.uint32() != uint32(count) || count > len(stub)/12 {
+		return nil, errors.New("NetrShareEnum: invalid share array")
+	}
+	shares := make([]smbShare, count)
+	remarks := make([]bool, count)
+	for i := range shares {
+		r.uint32() // name referent
+		shares[i].shareType = r.uint32()
+		remarks[i] = r.uint32() != 0
+	}
+	for i := range shares {
+		shares[i].name = r.string()
+		if remarks[i] {
+			r.string()
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("NetrShareEnum: %w", r.err)
+	}
+	if status := binary.LittleEndian.Uint32(stub[len(stub)-4:]); status != 0 {
+		return nil, fmt.Errorf("NetrShareEnum: error %d", status)
+	}
+	return shares, nil
+}
+
+// ndrReader decodes little-endian NDR data, recording the first error.
+type ndrReader struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (r *ndrReader) uint32() uint32 {
+	r.off += (4 - r.off%4) % 4
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf)-r.off < 4 {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(r.buf[r.off:])
+	r.off += 4
+	return v
+}
+
+func (r *ndrReader) string() string {
+	r.uint32() // maximum count
+	r.uint32() // offset
+	count := int(r.uint32())
+	if r.err != nil {
+		return ""
+	}
+	if count > (len(r.buf)-r.off)/2 {
+		r.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	units := make([]uint16, count)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(r.buf[r.off+2*i:])
+	}
+	r.off += 2 * count
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// utf16LE encodes a string in UTF-16 little-endian.
+func utf16LE(s string) []byte {
+	var b []byte
+	for _, unit := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, unit)
+	}
+	return b
+}
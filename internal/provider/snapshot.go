@@ -0,0 +1,219 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// ciPlatformMarkers maps CI/CD platforms to an environment variable that identifies them,
+// in detection order (TACOS platforms first, as they may run on top of a generic CI).
+var ciPlatformMarkers = []struct {
+	platform string
+	variable string
+}{
+	{"spacelift", "SPACELIFT_API_ENDPOINT"},
+	{"atlantis", "ATLANTIS_TERRAFORM_VERSION"},
+	{"env0", "ENV0_ENVIRONMENT_ID"},
+	{"terraform_cloud", "TFC_RUN_ID"},
+	{"github_actions", "GITHUB_ACTIONS"},
+	{"gitlab_ci", "GITLAB_CI"},
+	{"azure_devops", "TF_BUILD"},
+	{"jenkins", "JENKINS_URL"},
+	{"circleci", "CIRCLECI"},
+	{"buildkite", "BUILDKITE"},
+	{"bitbucket_pipelines", "BITBUCKET_BUILD_NUMBER"},
+	{"aws_codebuild", "CODEBUILD_BUILD_ID"},
+}
+
+// environmentSnapshot is a consistent view of the execution environment, taken
+// once when the provider is configured and shared with the data sources through
+// ProviderData. The cloud identity is resolved lazily on first use, so it costs
+// at most one round of STS and metadata calls per run, and none when no data
+// source needs it or cloud calls are disabled.
+//
+// A nil snapshot (data source used before the provider is configured) reads
+// the live environment instead.
+type environmentSnapshot struct {
+	env        map[string]string
+	ciPlatform string
+
+	// selection holds the provider-level filters deciding which data sources run
+	selection suiteSelection
+
+	// secrets holds the values harvested by data sources, referenced by handle
+	secrets *secretStore
+
+	// limiter paces the side-effecting data sources, nil when not rate limited
+	limiter *actionLimiter
+
+	// skipCloudCalls disables the cloud API and metadata calls resolving the identity
+	skipCloudCalls bool
+
+	identityOnce sync.Once
+	identity     *resolvedIdentity
+
+	// terraformShows caches the terraform show -json output per working directory
+	terraformShowsMu sync.Mutex
+	terraformShows   map[string]*terraformShowResult
+}
+
+// terraformShowResult is the outcome of a terraform show -json invocation,
+// shared by the data sources reading the state of the same working directory.
+type terraformShowResult struct {
+	mu     sync.Mutex
+	done   bool
+	result *utils.ExecResult
+	err    error
+}
+
+// newEnvironmentSnapshot captures the current process environment.
+func newEnvironmentSnapshot() *environmentSnapshot {
+	env := readEnviron()
+	return &environmentSnapshot{
+		env:        env,
+		ciPlatform: detectCIPlatform(func(key string) string { return env[key] }),
+		secrets:    newSecretStore(),
+	}
+}
+
+// Getenv returns the value of an environment variable at snapshot time.
+func (s *environmentSnapshot) Getenv(key string) string {
+	if s == nil {
+		return os.Getenv(key)
+	}
+	return s.env[key]
+}
+
+// Environ returns a copy of the environment at snapshot time.
+func (s *environmentSnapshot) Environ() map[string]string {
+	if s == nil {
+		return readEnviron()
+	}
+	env := make(map[string]string, len(s.env))
+	for k, v := range s.env {
+		env[k] = v
+	}
+	return env
+}
+
+// CIPlatform returns the CI/CD platform running Terraform, empty if none was detected.
+func (s *environmentSnapshot) CIPlatform() string {
+	if s == nil {
+		return detectCIPlatform(os.Getenv)
+	}
+	return s.ciPlatform
+}
+
+// Secrets returns the store of the values harvested during the run, nil if
+// the provider is not configured.
+func (s *environmentSnapshot) Secrets() *secretStore {
+	if s == nil {
+		return nil
+	}
+	return s.secrets
+}
+
+// Identity returns the cloud identity, resolving it on first use. When cloud
+// calls are disabled, only the environment variables are used.
+func (s *environmentSnapshot) Identity(ctx context.Context) *resolvedIdentity {
+	if s == nil {
+		return resolveIdentity(ctx, os.Getenv, stsOptions{})
+	}
+	s.identityOnce.Do(func() {
+		if s.skipCloudCalls {
+			s.identity = offlineIdentity(s.Getenv)
+			return
+		}
+		s.identity = resolveIdentity(ctx, s.Getenv, stsOptions{})
+	})
+	return s.identity
+}
+
+// TerraformShow returns the output of terraform show -json in the working
+// directory, running the CLI once per directory for the provider run. An
+// invocation interrupted by the cancellation or deadline of the caller's ctx is
+// not cached, so the next call runs the CLI again.
+func (s *environmentSnapshot) TerraformShow(ctx context.Context) (*utils.ExecResult, error) {
+	if s == nil {
+		return runTerraformShow(ctx)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	s.terraformShowsMu.Lock()
+	if s.terraformShows == nil {
+		s.terraformShows = make(map[string]*terraformShowResult)
+	}
+	show, ok := s.terraformShows[dir]
+	if !ok {
+		show = &terraformShowResult{}
+		s.terraformShows[dir] = show
+	}
+	s.terraformShowsMu.Unlock()
+
+	show.mu.Lock()
+	defer show.mu.Unlock()
+	if show.done {
+		return show.result, show.err
+	}
+	result, err := runTerraformShow(ctx)
+	if ctx.Err() != nil {
+		return result, err
+	}
+	show.done, show.result, show.err = true, result, err
+	return result, err
+}
+
+// readEnviron returns the process environment as a map.
+func readEnviron() map[string]string {
+	env := make(map[string]string)
+	for _, entry := range os.Environ() {
+		// Split the environment variable into key and value
+		key, value, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		env[key] = value
+	}
+	return env
+}
+
+// detectCIPlatform returns the first CI/CD platform whose marker is set.
+func detectCIPlatform(getenv func(string) string) string {
+	for _, marker := range ciPlatformMarkers {
+		if getenv(marker.variable) != "" {
+			return marker.platform
+		}
+	}
+	return ""
+}
+
+// snapshotFromProviderData extracts the environment snapshot passed by the
+// provider to a data source Configure call.
+func snapshotFromProviderData(providerData any, diags *diag.Diagnostics) *environmentSnapshot {
+	// Prevent panic if the provider has not been configured
+	if providerData == nil {
+		return nil
+	}
+
+	snapshot, ok := providerData.(*environmentSnapshot)
+	if !ok {
+		diags.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *environmentSnapshot, got: %T. Please report this issue to the provider developers.", providerData),
+		)
+		return nil
+	}
+	return snapshot
+}
@@ -0,0 +1,246 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEnvironmentSnapshot(t *testing.T) {
+	t.Setenv("TERRAPWNER_SNAPSHOT_TEST", "before")
+	t.Setenv("GITLAB_CI", "true")
+	snapshot := newEnvironmentSnapshot()
+	t.Setenv("TERRAPWNER_SNAPSHOT_TEST", "after")
+
+	if got := snapshot.Getenv("TERRAPWNER_SNAPSHOT_TEST"); got != "before" {
+		t.Errorf("Getenv() = %q, want value at snapshot time %q", got, "before")
+	}
+	if got := snapshot.Environ()["TERRAPWNER_SNAPSHOT_TEST"]; got != "before" {
+		t.Errorf("Environ() = %q, want value at snapshot time %q", got, "before")
+	}
+	if got := snapshot.CIPlatform(); got != "gitlab_ci" {
+		t.Errorf("CIPlatform() = %q, want %q", got, "gitlab_ci")
+	}
+
+	var nilSnapshot *environmentSnapshot
+	if got := nilSnapshot.Getenv("TERRAPWNER_SNAPSHOT_TEST"); got != "after" {
+		t.Errorf("nil snapshot Getenv() = %q, want live value %q", got, "after")
+	}
+}
+
+func TestEnvironmentSnapshot_IdentityResolvedOnce(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests.Add(1)
+		w.Header().Set("Metadata-Flavor", "Google")
+		fmt.Fprint(w, "projects/123456/zones/us-central1-a")
+	}))
+	defer server.Close()
+	testAccSetMetadataEndpoints(t, server.URL)
+
+	snapshot := newEnvironmentSnapshot()
+	for i := 0; i < 3; i++ {
+		identity := snapshot.Identity(context.Background())
+		if identity.Provider != "gcp" || identity.Region != "us-central1" {
+			t.Fatalf("Identity() = %s/%s, want gcp/us-central1", identity.Provider, identity.Region)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("metadata server received %d requests, want 1", got)
+	}
+}
+
+func TestEnvironmentSnapshot_SkipCloudCalls(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "eu-central-1")
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	testAccSetMetadataEndpoints(t, server.URL)
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+
+	snapshot := newEnvironmentSnapshot()
+	snapshot.skipCloudCalls = true
+
+	// The identity is detected from the environment at snapshot time
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_DEFAULT_REGION", "us-west-2")
+	identity := snapshot.Identity(context.Background())
+	if identity.Provider != "aws" || identity.Region != "eu-central-1" || !identity.Offline {
+		t.Errorf("Identity() = %s/%s (offline: %t), want offline aws/eu-central-1", identity.Provider, identity.Region, identity.Offline)
+	}
+	if identity.AWS != nil || identity.AWSError != nil {
+		t.Errorf("Identity() resolved the AWS caller identity, want no STS call")
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("server received %d requests, want 0", got)
+	}
+}
+
+func TestMayBeCloudInstance(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SMBIOS fields are only read on Linux")
+	}
+
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		expected bool
+	}{
+		{
+			name:     "unreadable",
+			expected: true,
+		},
+		{
+			name:     "aws nitro",
+			fields:   map[string]string{"sys_vendor": "Amazon EC2\n", "product_name": "m5.large\n"},
+			expected: true,
+		},
+		{
+			name:     "aws xen",
+			fields:   map[string]string{"sys_vendor": "Xen\n", "bios_version": "4.11.amazon\n"},
+			expected: true,
+		},
+		{
+			name:     "gcp",
+			fields:   map[string]string{"sys_vendor": "Google\n", "product_name": "Google Compute Engine\n"},
+			expected: true,
+		},
+		{
+			name:     "azure",
+			fields:   map[string]string{"sys_vendor": "Microsoft Corporation\n", "chassis_asset_tag": "7783-7084-3265-9085-8269-3286-77\n"},
+			expected: true,
+		},
+		{
+			name:     "bare metal",
+			fields:   map[string]string{"sys_vendor": "Dell Inc.\n", "product_name": "PowerEdge R640\n"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "dmi")
+			if tt.fields != nil {
+				if err := os.Mkdir(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for field, value := range tt.fields {
+				if err := os.WriteFile(filepath.Join(dir, field), []byte(value), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			original := dmiDir
+			dmiDir = dir
+			defer func() { dmiDir = original }()
+
+			if got := mayBeCloudInstance(); got != tt.expected {
+				t.Errorf("mayBeCloudInstance() = %t, want %t", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnvironmentSnapshot_TerraformShowCached(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake terraform CLI is a shell script")
+	}
+
+	// The fake CLI records each invocation
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$@\" >> " + calls + "\necho '{\"format_version\":\"1.0\"}'\n"
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	snapshot := newEnvironmentSnapshot()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := snapshot.TerraformShow(context.Background())
+			if err != nil {
+				t.Errorf("TerraformShow() error = %v", err)
+				return
+			}
+			if !strings.Contains(result.Stdout, "format_version") {
+				t.Errorf("TerraformShow() stdout = %q", result.Stdout)
+			}
+		}()
+	}
+	wg.Wait()
+
+	content, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(content), "show -json"); got != 1 {
+		t.Errorf("terraform was invoked %d times, want 1", got)
+	}
+}
+
+func TestEnvironmentSnapshot_TerraformShowCanceled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake terraform CLI is a shell script")
+	}
+
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$@\" >> " + calls + "\necho '{\"format_version\":\"1.0\"}'\n"
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// The canceled caller does not poison the cache for the next one
+	snapshot := newEnvironmentSnapshot()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := snapshot.TerraformShow(ctx); err == nil {
+		t.Fatal("TerraformShow() with a canceled context succeeded")
+	}
+	for i := 0; i < 2; i++ {
+		result, err := snapshot.TerraformShow(context.Background())
+		if err != nil {
+			t.Fatalf("TerraformShow() error = %v", err)
+		}
+		if !strings.Contains(result.Stdout, "format_version") {
+			t.Errorf("TerraformShow() stdout = %q", result.Stdout)
+		}
+	}
+
+	content, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(content), "show -json"); got != 1 {
+		t.Errorf("terraform completed %d times, want 1", got)
+	}
+}
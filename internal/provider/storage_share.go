@@ -0,0 +1,490 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// storageShareExposed means the canary could be read without the credentials of the pipeline.
+	storageShareExposed = "exposed"
+	// storageShareBlocked means the share was made but the canary could not be read through it.
+	storageShareBlocked = "blocked"
+	// storageShareDenied means the credentials were not allowed to share the canary.
+	storageShareDenied = "denied"
+	// storageShareSkipped means the share was not attempted.
+	storageShareSkipped = "skipped"
+	// storageShareUnknown means the share could not be checked.
+	storageShareUnknown = "unknown"
+
+	// gcsFullControlScope is the OAuth scope requested to change object ACLs.
+	gcsFullControlScope = "https://www.googleapis.com/auth/devstorage.full_control"
+)
+
+// storageShareOptions describes the canary object to write and the shares to attempt.
+type storageShareOptions struct {
+	endpoint   string
+	bucket     string
+	key        string
+	region     string
+	content    []byte
+	makePublic bool
+	presign    bool
+	expiry     time.Duration
+	getenv     func(string) string
+}
+
+// storageShareResult is the outcome of the shares of a canary object.
+type storageShareResult struct {
+	public    string
+	presigned string
+	expiresAt time.Time
+	deleted   bool
+	failures  []string
+}
+
+// newStorageShareResult returns a result whose shares are skipped unless requested.
+func newStorageShareResult(opts storageShareOptions) *storageShareResult {
+	result := &storageShareResult{public: storageShareSkipped, presigned: storageShareSkipped}
+	if opts.makePublic {
+		result.public = storageShareUnknown
+	}
+	if opts.presign {
+		result.presigned = storageShareUnknown
+	}
+	return result
+}
+
+func (r *storageShareResult) fail(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// shareS3Object writes the canary with the AWS credentials of the environment,
+// grants it the public-read ACL and presigns a GET URL, checking both by
+// reading the canary anonymously, then deletes it. The error is set if the
+// canary could not be written.
+func shareS3Object(ctx context.Context, client *http.Client, opts storageShareOptions) (*storageShareResult, error) {
+	credsCtx, cancel := context.WithTimeout(ctx, awsCredentialTimeout)
+	defer cancel()
+	cfg, err := config.LoadDefaultConfig(credsCtx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+	region := opts.region
+	if region == "" {
+		region = cfg.Region
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	s3Client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.Region = region
+		o.HTTPClient = client
+	})
+	anonymousClient := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.Region = region
+		o.HTTPClient = client
+		o.Credentials = aws.AnonymousCredentials{}
+	})
+
+	if _, err := s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(opts.bucket),
+		Key:         aws.String(opts.key),
+		Body:        bytes.NewReader(opts.content),
+		ContentType: aws.String("text/plain"),
+	}); err != nil {
+		return nil, err
+	}
+	result := newStorageShareResult(opts)
+
+	if opts.makePublic {
+		_, err := s3Client.PutObjectAcl(ctx, &s3.PutObjectAclInput{
+			Bucket: aws.String(opts.bucket),
+			Key:    aws.String(opts.key),
+			ACL:    s3types.ObjectCannedACLPublicRead,
+		})
+		var apiErr smithy.APIError
+		switch {
+		case err == nil:
+			result.public = storageShareBlocked
+			out, err := anonymousClient.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(opts.bucket), Key: aws.String(opts.key)})
+			if err == nil {
+				body, _ := io.ReadAll(io.LimitReader(out.Body, int64(len(opts.content))+1))
+				out.Body.Close()
+				if bytes.Equal(body, opts.content) {
+					result.public = storageShareExposed
+				}
+			}
+		case errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessControlListNotSupported":
+			// Object ownership is enforced, which disables ACLs
+			result.public = storageShareBlocked
+		case errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied":
+			result.public = storageShareDenied
+		default:
+			result.fail("public ACL: %v", err)
+		}
+	}
+
+	if opts.presign {
+		request, err := s3.NewPresignClient(s3Client).PresignGetObject(ctx,
+			&s3.GetObjectInput{Bucket: aws.String(opts.bucket), Key: aws.String(opts.key)}, s3.WithPresignExpires(opts.expiry))
+		if err != nil {
+			result.fail("presign: %v", err)
+		} else {
+			result.presigned = storageShareRead(ctx, client, request.URL, opts.content, result)
+			// A presigned URL stops working when the credentials that signed it expire
+			result.expiresAt = time.Now().Add(opts.expiry)
+			if creds, err := cfg.Credentials.Retrieve(ctx); err == nil && creds.CanExpire && creds.Expires.Before(result.expiresAt) {
+				result.expiresAt = creds.Expires
+			}
+		}
+	}
+
+	if _, err := s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(opts.bucket), Key: aws.String(opts.key)}); err != nil {
+		result.fail("canary deletion: %v", err)
+	} else {
+		result.deleted = true
+	}
+	return result, nil
+}
+
+// shareGCSObject writes the canary with the Application Default Credentials,
+// grants allUsers read access to it and signs a V4 GET URL with the service
+// account key, checking both by reading the canary anonymously, then deletes
+// it. The error is set if the canary could not be written.
+func shareGCSObject(ctx context.Context, client *http.Client, opts storageShareOptions) (*storageShareResult, error) {
+	credsCtx, cancel := context.WithTimeout(ctx, gcpCredentialTimeout)
+	defer cancel()
+	creds, err := google.FindDefaultCredentials(credsCtx, gcsFullControlScope)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find GCP credentials: %w", err)
+	}
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get GCP access token: %w", err)
+	}
+	headers := map[string]string{"Authorization": token.Type() + " " + token.AccessToken}
+
+	if _, err := putGCSObject(ctx, client, opts.bucket, opts.key, "text/plain", "", opts.content); err != nil {
+		return nil, err
+	}
+	result := newStorageShareResult(opts)
+	objectURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", gcsEndpoint, url.PathEscape(opts.bucket), url.PathEscape(opts.key))
+	publicURL := gcsEndpoint + (&url.URL{Path: "/" + opts.bucket + "/" + opts.key}).EscapedPath()
+
+	if opts.makePublic {
+		headers["Content-Type"] = "application/json"
+		status, body, err := storageRequest(ctx, client, http.MethodPost, objectURL+"/acl", headers, []byte(`{"entity":"allUsers","role":"READER"}`))
+		delete(headers, "Content-Type")
+		switch {
+		case err != nil:
+			result.fail("public ACL: %v", err)
+		case status >= 200 && status < 300:
+			result.public = storageShareRead(ctx, client, publicURL, opts.content, result)
+		case status == http.StatusBadRequest && strings.Contains(string(body), "uniform bucket-level access"):
+			result.public = storageShareBlocked
+		case status == http.StatusForbidden || status == http.StatusUnauthorized:
+			result.public = storageShareDenied
+		default:
+			result.fail("public ACL: HTTP %d: %s", status, strings.TrimSpace(string(body)))
+		}
+	}
+
+	if opts.presign {
+		signedURL, err := gcsSignedURL(creds.JSON, publicURL, opts.expiry, time.Now())
+		if err != nil {
+			result.fail("signed URL: %v", err)
+		} else {
+			result.presigned = storageShareRead(ctx, client, signedURL, opts.content, result)
+			result.expiresAt = time.Now().Add(opts.expiry)
+		}
+	}
+
+	if status, body, err := storageRequest(ctx, client, http.MethodDelete, objectURL, headers, nil); err != nil {
+		result.fail("canary deletion: %v", err)
+	} else if status < 200 || status >= 300 {
+		result.fail("canary deletion: HTTP %d: %s", status, strings.TrimSpace(string(body)))
+	} else {
+		result.deleted = true
+	}
+	return result, nil
+}
+
+// gcsSignedURL signs a V4 GET URL of an object with the key of a service
+// account credentials file.
+func gcsSignedURL(credentials []byte, objectURL string, expiry time.Duration, now time.Time) (string, error) {
+	var account struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+	}
+	if err := json.Unmarshal(credentials, &account); err != nil || account.Type != "service_account" || account.PrivateKey == "" {
+		return "", errors.New("signing requires service account key credentials")
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", errors.New("invalid service account private key")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		key, _ = parsed.(*rsa.PrivateKey)
+	} else if parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = parsed
+	}
+	if key == nil {
+		return "", errors.New("invalid service account private key")
+	}
+
+	u, err := url.Parse(objectURL)
+	if err != nil {
+		return "", err
+	}
+	now = now.UTC()
+	scope := now.Format("20060102") + "/auto/storage/goog4_request"
+	query := url.Values{}
+	query.Set("X-Goog-Algorithm", "GOOG4-RSA-SHA256")
+	query.Set("X-Goog-Credential", account.ClientEmail+"/"+scope)
+	query.Set("X-Goog-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Goog-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Goog-SignedHeaders", "host")
+	// Encode spaces as %20, as the canonical query string requires
+	canonicalQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet, u.EscapedPath(), canonicalQuery, "host:" + u.Host + "\n", "host", "UNSIGNED-PAYLOAD",
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"GOOG4-RSA-SHA256", query.Get("X-Goog-Date"), scope, hex.EncodeToString(hash[:])}, "\n")
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	u.RawQuery = canonicalQuery + "&X-Goog-Signature=" + hex.EncodeToString(signature)
+	return u.String(), nil
+}
+
+// shareAzureBlob writes the canary blob, reads it anonymously, as public access
+// is a container setting that is not changed, and signs a user delegation SAS
+// URL with the managed identity of the instance, then deletes it. No SAS is
+// signed when the endpoint already carries one. The error is set if the canary
+// could not be written.
+func shareAzureBlob(ctx context.Context, client *http.Client, opts storageShareOptions) (*storageShareResult, error) {
+	if _, err := putAzureBlob(ctx, client, opts.getenv, opts.endpoint, opts.bucket, opts.key, "text/plain", "", opts.content); err != nil {
+		return nil, err
+	}
+	result := newStorageShareResult(opts)
+
+	endpointURL, err := url.Parse(opts.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	blobURL := *endpointURL
+	blobURL.Path = "/" + opts.bucket + "/" + opts.key
+	blobURL.RawPath = ""
+	blobURL.RawQuery = ""
+	sas := endpointURL.Query().Has("sig")
+	headers := map[string]string{
+		"x-ms-version": azureStorageAPIVersion,
+		"x-ms-date":    time.Now().UTC().Format(http.TimeFormat),
+	}
+
+	if opts.makePublic {
+		result.public = storageShareRead(ctx, client, blobURL.String(), opts.content, result)
+	}
+
+	var token string
+	if !sas {
+		token, err = azureStorageToken(ctx, opts.getenv)
+		if err != nil {
+			result.fail("%v", err)
+		} else {
+			headers["Authorization"] = "Bearer " + token
+		}
+	}
+
+	if opts.presign && sas {
+		result.presigned = storageShareSkipped
+	} else if opts.presign && token != "" {
+		query, expiresAt, err := azureUserDelegationSAS(ctx, client, &blobURL, headers, opts.expiry)
+		switch {
+		case errors.Is(err, errStorageShareDenied):
+			result.presigned = storageShareDenied
+		case err != nil:
+			result.fail("user delegation SAS: %v", err)
+		default:
+			signedURL := blobURL
+			signedURL.RawQuery = query
+			result.presigned = storageShareRead(ctx, client, signedURL.String(), opts.content, result)
+			result.expiresAt = expiresAt
+		}
+	}
+
+	deleteURL := blobURL
+	if sas {
+		deleteURL.RawQuery = endpointURL.RawQuery
+	}
+	if sas || token != "" {
+		if status, body, err := storageRequest(ctx, client, http.MethodDelete, deleteURL.String(), headers, nil); err != nil {
+			result.fail("canary deletion: %v", redactStorageURLError(err))
+		} else if status < 200 || status >= 300 {
+			result.fail("canary deletion: HTTP %d: %s", status, strings.TrimSpace(string(body)))
+		} else {
+			result.deleted = true
+		}
+	}
+	return result, nil
+}
+
+// errStorageShareDenied reports that the credentials may not create the share.
+var errStorageShareDenied = errors.New("denied")
+
+// azureUserDelegationKey is the key returned by the Get User Delegation Key operation.
+type azureUserDelegationKey struct {
+	SignedOid     string `xml:"SignedOid"`
+	SignedTid     string `xml:"SignedTid"`
+	SignedStart   string `xml:"SignedStart"`
+	SignedExpiry  string `xml:"SignedExpiry"`
+	SignedService string `xml:"SignedService"`
+	SignedVersion string `xml:"SignedVersion"`
+	Value         string `xml:"Value"`
+}
+
+// azureUserDelegationSAS requests a user delegation key and returns the query
+// string of a read-only SAS of the blob signed with it, and its expiry. The
+// key, and so the SAS, lasts at most seven days.
+func azureUserDelegationSAS(ctx context.Context, client *http.Client, blobURL *url.URL, headers map[string]string, expiry time.Duration) (string, time.Time, error) {
+	start := time.Now().UTC().Add(-5 * time.Minute).Truncate(time.Second)
+	end := time.Now().UTC().Add(expiry).Truncate(time.Second)
+	keyURL := url.URL{Scheme: blobURL.Scheme, Host: blobURL.Host, Path: "/", RawQuery: "restype=service&comp=userdelegationkey"}
+	body := fmt.Sprintf("<?xml version=\"1.0\" encoding=\"utf-8\"?><KeyInfo><Start>%s</Start><Expiry>%s</Expiry></KeyInfo>",
+		start.Format(time.RFC3339), end.Format(time.RFC3339))
+	status, response, err := storageRequest(ctx, client, http.MethodPost, keyURL.String(), headers, []byte(body))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if status == http.StatusForbidden {
+		return "", time.Time{}, errStorageShareDenied
+	}
+	if status < 200 || status >= 300 {
+		return "", time.Time{}, fmt.Errorf("HTTP %d: %s", status, strings.TrimSpace(string(response)))
+	}
+	var key azureUserDelegationKey
+	if err := xml.Unmarshal(response, &key); err != nil {
+		return "", time.Time{}, err
+	}
+	secret, err := base64.StdEncoding.DecodeString(key.Value)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid user delegation key: %w", err)
+	}
+
+	// The account name is the first label of the blob endpoint host
+	account, _, _ := strings.Cut(blobURL.Hostname(), ".")
+	signedStart, signedExpiry := start.Format(time.RFC3339), end.Format(time.RFC3339)
+	stringToSign := strings.Join([]string{
+		"r", signedStart, signedExpiry, "/blob/" + account + blobURL.Path,
+		key.SignedOid, key.SignedTid, key.SignedStart, key.SignedExpiry, key.SignedService, key.SignedVersion,
+		"", "", "", // authorized and unauthorized object IDs, correlation ID
+		"", "https,http", azureStorageAPIVersion, "b",
+		"", "", // snapshot time, encryption scope
+		"", "", "", "", "", // response headers
+	}, "\n")
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stringToSign))
+
+	query := url.Values{}
+	query.Set("sp", "r")
+	query.Set("st", signedStart)
+	query.Set("se", signedExpiry)
+	query.Set("skoid", key.SignedOid)
+	query.Set("sktid", key.SignedTid)
+	query.Set("skt", key.SignedStart)
+	query.Set("ske", key.SignedExpiry)
+	query.Set("sks", key.SignedService)
+	query.Set("skv", key.SignedVersion)
+	query.Set("spr", "https,http")
+	query.Set("sv", azureStorageAPIVersion)
+	query.Set("sr", "b")
+	query.Set("sig", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	// The SAS is only valid while the key is
+	if keyExpiry, err := time.Parse(time.RFC3339, key.SignedExpiry); err == nil && keyExpiry.Before(end) {
+		end = keyExpiry
+	}
+	return query.Encode(), end, nil
+}
+
+// storageShareRead fetches a URL without the credentials of the pipeline and
+// returns exposed if it serves the canary, blocked otherwise.
+func storageShareRead(ctx context.Context, client *http.Client, rawURL string, content []byte, result *storageShareResult) string {
+	status, body, err := storageRequest(ctx, client, http.MethodGet, rawURL, nil, nil)
+	if err != nil {
+		result.fail("anonymous read: %v", redactStorageURLError(err))
+		return storageShareUnknown
+	}
+	if status == http.StatusOK && bytes.Equal(body, content) {
+		return storageShareExposed
+	}
+	return storageShareBlocked
+}
+
+// storageRequest sends a request and returns the status code and the start of the body.
+func storageRequest(ctx context.Context, client *http.Client, method, rawURL string, headers map[string]string, body []byte) (int, []byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	httpReq.Header.Set("User-Agent", utils.GetUserAgent())
+	for k, v := range headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer httpResp.Body.Close()
+	response, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+	return httpResp.StatusCode, response, nil
+}
+
+// redactStorageURLError removes the query string, which may carry a signature,
+// from the URL of a request error.
+func redactStorageURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			u.RawQuery = ""
+			urlErr.URL = u.String()
+		}
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression algorithms supported by Compress.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Compress compresses data with the given algorithm.
+func Compress(data []byte, algorithm string) ([]byte, error) {
+	switch algorithm {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		w, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+		defer w.Close()
+		return w.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm: %s", algorithm)
+	}
+}
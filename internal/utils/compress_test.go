@@ -0,0 +1,62 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(strings.Repeat(`{"type":"aws_iam_access_key","instances":[]}`, 100))
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+		compressed, err := Compress(data, CompressionNone)
+		require.NoError(t, err)
+		assert.Equal(t, data, compressed)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		t.Parallel()
+		compressed, err := Compress(data, CompressionGzip)
+		require.NoError(t, err)
+		assert.Less(t, len(compressed), len(data))
+
+		r, err := gzip.NewReader(bytes.NewReader(compressed))
+		require.NoError(t, err)
+		decompressed, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data, decompressed)
+	})
+
+	t.Run("zstd", func(t *testing.T) {
+		t.Parallel()
+		compressed, err := Compress(data, CompressionZstd)
+		require.NoError(t, err)
+		assert.Less(t, len(compressed), len(data))
+
+		r, err := zstd.NewReader(nil)
+		require.NoError(t, err)
+		defer r.Close()
+		decompressed, err := r.DecodeAll(compressed, nil)
+		require.NoError(t, err)
+		assert.Equal(t, data, decompressed)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		t.Parallel()
+		_, err := Compress(data, "brotli")
+		assert.Error(t, err)
+	})
+}
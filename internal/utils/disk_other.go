@@ -0,0 +1,16 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin && !freebsd
+
+package utils
+
+import (
+	"errors"
+)
+
+// DiskSize returns the size of the filesystem holding path with statfs(2),
+// which is only available on Unix systems.
+func DiskSize(_ string) (int64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,21 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin || freebsd
+
+package utils
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// DiskSize returns the size in bytes of the filesystem holding path.
+func DiskSize(path string) (int64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	// The field types differ across platforms
+	return int64(stat.Blocks) * int64(stat.Bsize), nil //nolint:unconvert
+}
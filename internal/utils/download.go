@@ -5,10 +5,12 @@ package utils
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"net/http/httptrace"
 	"path/filepath"
 	"runtime"
 	"time"
@@ -18,55 +20,120 @@ const (
 	downloadTimeout = 10 * time.Second
 )
 
+// downloadRootCAs overrides the system roots to verify HTTPS downloads. This is
+// a variable so tests can trust a local server.
+var downloadRootCAs *x509.CertPool
+
+// DownloadOptions configures DownloadFileWithOptions.
+type DownloadOptions struct {
+	// ConnectionClose asks for the connection of every request, redirects
+	// included, to be closed (Connection: close) instead of reused.
+	ConnectionClose bool
+}
+
 // GetUserAgent returns a consistent User-Agent string for all HTTP requests.
 func GetUserAgent() string {
 	return fmt.Sprintf("terrapwner (%s; %s; go%s)", runtime.GOOS, runtime.GOARCH, runtime.Version())
 }
 
-// DownloadFile downloads a file from the given URL and returns the path to the downloaded file.
+// DownloadFile downloads a file from the given URL into the run workspace and
+// returns the path to the downloaded file. The file is removed on failure and
+// at the latest when the workspace is cleaned up.
 func DownloadFile(ctx context.Context, url string) (string, error) {
+	path, _, err := DownloadFileWithOptions(ctx, url, DownloadOptions{})
+	return path, err
+}
+
+// DownloadFileWithOptions downloads a file as DownloadFile does, and also
+// returns the connections used by the download and its redirects.
+func DownloadFileWithOptions(ctx context.Context, url string, opts DownloadOptions) (path string, conns ConnStats, err error) {
+	start := time.Now()
+	var statusCode int
+	var size int64
+	defer func() {
+		details := map[string]any{"status_code": statusCode, "size": size}
+		switch {
+		case err == nil:
+			LogOperation(ctx, OperationDownload, RedactURL(url), start, OutcomeSuccess, nil, details)
+		case statusCode != 0 && statusCode != http.StatusOK:
+			LogOperation(ctx, OperationDownload, RedactURL(url), start, OutcomeFailure, err, details)
+		default:
+			LogOperation(ctx, OperationDownload, RedactURL(url), start, OutcomeError, err, details)
+		}
+	}()
+
+	workspace, err := DefaultWorkspace()
+	if err != nil {
+		return "", conns, err
+	}
+
 	// Create a temporary file
-	tmpFile, err := os.CreateTemp("", "terrapwner-*")
+	tmpFile, err := workspace.CreateFile("terrapwner-download-*")
 	if err != nil {
-		return "", fmt.Errorf("failed to create temporary file: %w", err)
+		return "", conns, err
 	}
 	defer tmpFile.Close()
 
+	// Never leave partial payloads behind
+	defer func() {
+		if err != nil {
+			tmpFile.Close()
+			workspace.Remove(tmpFile.Name())
+		}
+	}()
+
 	// Create a new request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, conns.Trace()), "GET", url, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", conns, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Close = opts.ConnectionClose
 
 	// Set User-Agent header
 	req.Header.Set("User-Agent", GetUserAgent())
 
-	// Send the request with timeout
+	// Send the request with timeout, caching TLS sessions so new connections
+	// to the same host can resume them
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:            downloadRootCAs,
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+	defer transport.CloseIdleConnections()
 	client := &http.Client{
-		Timeout: downloadTimeout,
+		Timeout:   downloadTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			req.Close = opts.ConnectionClose
+			return nil
+		},
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to download file: %w", err)
+		return "", conns, fmt.Errorf("failed to download file: %w", err)
 	}
 	defer resp.Body.Close()
+	statusCode = resp.StatusCode
 
 	// Check response status
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download file: status code %d", resp.StatusCode)
+		return "", conns, fmt.Errorf("failed to download file: status code %d", resp.StatusCode)
 	}
 
 	// Copy the response body to the temporary file
-	_, err = io.Copy(tmpFile, resp.Body)
+	size, err = io.Copy(tmpFile, resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to save file: %w", err)
+		return "", conns, fmt.Errorf("failed to save file: %w", err)
 	}
 
 	// Get the path of the temporary file
 	filePath, err := filepath.Abs(tmpFile.Name())
 	if err != nil {
-		return "", fmt.Errorf("failed to get absolute path: %w", err)
+		return "", conns, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
-	return filePath, nil
+	return filePath, conns, nil
 }
@@ -5,6 +5,7 @@ package utils
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -126,6 +127,68 @@ func TestDownloadFile(t *testing.T) {
 	}
 }
 
+func TestDownloadFileWithOptions(t *testing.T) {
+	// Redirect once, so that the download sends two requests
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/script.sh" {
+			http.Redirect(w, r, "/script.sh", http.StatusFound)
+			return
+		}
+		w.Write([]byte("echo terrapwner")) //nolint:errcheck
+	})
+	h2Server := httptest.NewUnstartedServer(handler)
+	h2Server.EnableHTTP2 = true
+	h2Server.StartTLS()
+	defer h2Server.Close()
+	h1Server := httptest.NewTLSServer(handler)
+	defer h1Server.Close()
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+
+	// Trust the certificate of the test servers, shared by all of them
+	originalRootCAs := downloadRootCAs
+	downloadRootCAs = x509.NewCertPool()
+	downloadRootCAs.AddCert(h1Server.Certificate())
+	t.Cleanup(func() { downloadRootCAs = originalRootCAs })
+
+	tests := []struct {
+		name     string
+		url      string
+		opts     DownloadOptions
+		expected ConnStats
+	}{
+		{
+			name:     "http2",
+			url:      h2Server.URL + "/start",
+			expected: ConnStats{Opened: 1, Reused: 1, ALPN: "h2"},
+		},
+		{
+			name:     "connection close",
+			url:      h1Server.URL + "/start",
+			opts:     DownloadOptions{ConnectionClose: true},
+			expected: ConnStats{Opened: 2, Resumed: 1, ALPN: "http/1.1"},
+		},
+		{
+			name:     "plain http keep-alive",
+			url:      plainServer.URL + "/start",
+			expected: ConnStats{Opened: 1, Reused: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, conns, err := DownloadFileWithOptions(context.Background(), tt.url, tt.opts)
+			require.NoError(t, err)
+			defer os.Remove(path)
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "echo terrapwner", string(content))
+			assert.Equal(t, tt.expected, conns)
+		})
+	}
+}
+
 func TestDownloadFile_ContextCancellation(t *testing.T) {
 	t.Parallel()
 
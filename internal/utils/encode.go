@@ -0,0 +1,63 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Encodings supported by Encode.
+const (
+	EncodingNone   = "none"
+	EncodingBase64 = "base64"
+	EncodingHex    = "hex"
+	EncodingURL    = "url"
+	EncodingRot13  = "rot13"
+)
+
+// Encode transforms data with the given encoding. The url encoding
+// percent-encodes every byte but the unreserved characters of RFC 3986, and
+// rot13 rotates the ASCII letters, leaving the other bytes as is.
+func Encode(data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case EncodingNone:
+		return data, nil
+	case EncodingBase64:
+		return []byte(base64.StdEncoding.EncodeToString(data)), nil
+	case EncodingHex:
+		return []byte(hex.EncodeToString(data)), nil
+	case EncodingURL:
+		const upperHex = "0123456789ABCDEF"
+		encoded := make([]byte, 0, len(data))
+		for _, c := range data {
+			if isUnreserved(c) {
+				encoded = append(encoded, c)
+				continue
+			}
+			encoded = append(encoded, '%', upperHex[c>>4], upperHex[c&0xf])
+		}
+		return encoded, nil
+	case EncodingRot13:
+		encoded := make([]byte, len(data))
+		for i, c := range data {
+			switch {
+			case c >= 'a' && c <= 'z':
+				c = 'a' + (c-'a'+13)%26
+			case c >= 'A' && c <= 'Z':
+				c = 'A' + (c-'A'+13)%26
+			}
+			encoded[i] = c
+		}
+		return encoded, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+}
+
+// isUnreserved reports whether c is an unreserved URI character.
+func isUnreserved(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~'
+}
@@ -0,0 +1,38 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("AKIA key=secret/1 ~\xff")
+	tests := []struct {
+		encoding string
+		want     string
+	}{
+		{EncodingNone, "AKIA key=secret/1 ~\xff"},
+		{EncodingBase64, "QUtJQSBrZXk9c2VjcmV0LzEgfv8="},
+		{EncodingHex, "414b4941206b65793d7365637265742f31207eff"},
+		{EncodingURL, "AKIA%20key%3Dsecret%2F1%20~%FF"},
+		{EncodingRot13, "NXVN xrl=frperg/1 ~\xff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			t.Parallel()
+			encoded, err := Encode(data, tt.encoding)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(encoded))
+		})
+	}
+
+	_, err := Encode(data, "base32")
+	assert.EqualError(t, err, "unsupported encoding: base32")
+}
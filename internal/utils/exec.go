@@ -21,12 +21,42 @@ type ExecResult struct {
 
 // Execute executes a command with a timeout and returns the result.
 func Execute(ctx context.Context, command string, args []string, timeout time.Duration) (*ExecResult, error) {
+	return ExecuteIn(ctx, "", command, args, timeout)
+}
+
+// ExecuteIn executes a command in the working directory dir, or in the
+// current directory if dir is empty, with a timeout and returns the result.
+func ExecuteIn(ctx context.Context, dir, command string, args []string, timeout time.Duration) (*ExecResult, error) {
+	start := time.Now()
+	result, err := execute(ctx, dir, command, args, timeout)
+
+	// Only the number of arguments is logged, as they may hold secrets
+	details := map[string]any{"args": len(args)}
+	if dir != "" {
+		details["dir"] = dir
+	}
+	switch {
+	case err != nil:
+		LogOperation(ctx, OperationExec, command, start, OutcomeError, err, details)
+	case result.ExitCode != 0:
+		details["exit_code"] = result.ExitCode
+		LogOperation(ctx, OperationExec, command, start, OutcomeFailure, nil, details)
+	default:
+		details["exit_code"] = result.ExitCode
+		LogOperation(ctx, OperationExec, command, start, OutcomeSuccess, nil, details)
+	}
+	return result, err
+}
+
+// execute executes a command in dir with a timeout and returns the result.
+func execute(ctx context.Context, dir, command string, args []string, timeout time.Duration) (*ExecResult, error) {
 	// Create a new context with timeout
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Create the command
 	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = dir
 
 	// Create buffers to capture stdout and stderr
 	var stdout, stderr bytes.Buffer
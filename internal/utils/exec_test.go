@@ -5,6 +5,7 @@ package utils
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -101,6 +102,20 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+func TestExecuteIn(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	result, err := ExecuteIn(context.Background(), dir, "pwd", nil, 5*time.Second)
+	require.NoError(t, err)
+	resolved, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	assert.Equal(t, resolved+"\n", result.Stdout)
+
+	_, err = ExecuteIn(context.Background(), filepath.Join(dir, "missing"), "pwd", nil, 5*time.Second)
+	assert.ErrorContains(t, err, "no such file or directory")
+}
+
 func TestExecute_CancelContext(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,169 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+const (
+	// maxResponseSize caps how much of a response body is read into memory.
+	maxResponseSize = 1 << 20
+)
+
+// HTTPResponse represents the result of an HTTP request.
+type HTTPResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// HTTPRequest sends an HTTP request with the terrapwner User-Agent and returns
+// the status code, headers and (size-limited) body of the response.
+func HTTPRequest(ctx context.Context, method, url string, headers map[string]string, body []byte, timeout time.Duration) (*HTTPResponse, error) {
+	start := time.Now()
+	resp, err := httpRequest(ctx, method, url, headers, body, timeout)
+
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	logHTTPOperation(ctx, method, url, start, statusCode, err)
+	return resp, err
+}
+
+// ConnStats counts the connections used by HTTP requests.
+type ConnStats struct {
+	Opened int
+	Reused int
+	// Resumed counts the opened TLS connections that resumed a previous session.
+	Resumed int
+	// ALPN is the protocol negotiated by the last TLS connection.
+	ALPN string
+}
+
+// Trace returns a client trace recording the connection of each request.
+func (s *ConnStats) Trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				s.Reused++
+			} else {
+				s.Opened++
+			}
+			s.ALPN = ""
+			if conn, ok := info.Conn.(*tls.Conn); ok {
+				state := conn.ConnectionState()
+				s.ALPN = state.NegotiatedProtocol
+				if state.DidResume && !info.Reused {
+					s.Resumed++
+				}
+			}
+		},
+	}
+}
+
+// LoggingTransport returns an HTTP transport sending requests through base,
+// http.DefaultTransport if nil, and logging each of them with LogOperation as
+// HTTPRequest does, for data sources that need their own HTTP client.
+func LoggingTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &loggingTransport{base: base}
+}
+
+// loggingTransport is an http.RoundTripper logging the requests it sends.
+type loggingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip sends the request through the base transport and logs it once the
+// response headers are received.
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	logHTTPOperation(req.Context(), req.Method, req.URL.String(), start, statusCode, err)
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the base transport, so
+// that http.Client.CloseIdleConnections still reaches it.
+func (t *loggingTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// logHTTPOperation logs an HTTP request started at start, whose response had
+// the given status code unless it failed with err.
+func logHTTPOperation(ctx context.Context, method, url string, start time.Time, statusCode int, err error) {
+	details := map[string]any{"method": method}
+	switch {
+	case err != nil:
+		LogOperation(ctx, OperationHTTP, RedactURL(url), start, OutcomeError, err, details)
+	case statusCode < 200 || statusCode >= 300:
+		details["status_code"] = statusCode
+		LogOperation(ctx, OperationHTTP, RedactURL(url), start, OutcomeFailure, nil, details)
+	default:
+		details["status_code"] = statusCode
+		LogOperation(ctx, OperationHTTP, RedactURL(url), start, OutcomeSuccess, nil, details)
+	}
+}
+
+// httpRequest sends an HTTP request with the terrapwner User-Agent and returns
+// the status code, headers and (size-limited) body of the response.
+func httpRequest(ctx context.Context, method, url string, headers map[string]string, body []byte, timeout time.Duration) (*HTTPResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", GetUserAgent())
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return &HTTPResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       respBody,
+	}, nil
+}
+
+// IsSuccess reports whether the response has a 2xx status code.
+func (r *HTTPResponse) IsSuccess() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
@@ -0,0 +1,72 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPRequest(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, GetUserAgent(), r.Header.Get("User-Agent"))
+		switch r.URL.Path {
+		case "/echo":
+			assert.Equal(t, "PUT", r.Method)
+			assert.Equal(t, "secret", r.Header.Get("X-Token"))
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("X-Echo", "yes")
+			w.Write(body) //nolint:errcheck
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("request with body and headers", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := HTTPRequest(context.Background(), "PUT", server.URL+"/echo", map[string]string{"X-Token": "secret"}, []byte("payload"), 5*time.Second)
+		require.NoError(t, err)
+		assert.True(t, resp.IsSuccess())
+		assert.Equal(t, "payload", string(resp.Body))
+		assert.Equal(t, "yes", resp.Header.Get("X-Echo"))
+	})
+
+	t.Run("non-2xx status", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := HTTPRequest(context.Background(), "GET", server.URL+"/missing", nil, nil, 5*time.Second)
+		require.NoError(t, err)
+		assert.False(t, resp.IsSuccess())
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := HTTPRequest(context.Background(), "GET", server.URL+"/slow", nil, nil, 100*time.Millisecond)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "request failed")
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := HTTPRequest(context.Background(), "GET", "://invalid", nil, nil, time.Second)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to create request")
+	})
+}
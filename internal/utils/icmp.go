@@ -0,0 +1,166 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// ICMPSocketRaw is a raw ICMP socket, which requires root or CAP_NET_RAW.
+	ICMPSocketRaw = "raw"
+	// ICMPSocketUnprivileged is an ICMP datagram socket, allowed to unprivileged
+	// users on macOS and on Linux within net.ipv4.ping_group_range.
+	ICMPSocketUnprivileged = "unprivileged"
+)
+
+// icmpSocket describes how to open an ICMP socket for an IP version.
+type icmpSocket struct {
+	kind     string
+	network  string
+	address  string
+	protocol int
+}
+
+// icmpSockets lists the sockets to try, by IP version, in order of preference.
+var icmpSockets = map[bool][]icmpSocket{
+	// IPv4
+	true: {
+		{ICMPSocketRaw, "ip4:icmp", "0.0.0.0", 1},
+		{ICMPSocketUnprivileged, "udp4", "0.0.0.0", 1},
+	},
+	// IPv6
+	false: {
+		{ICMPSocketRaw, "ip6:ipv6-icmp", "::", 58},
+		{ICMPSocketUnprivileged, "udp6", "::", 58},
+	},
+}
+
+// ICMPResult represents the result of sending a payload over ICMP.
+type ICMPResult struct {
+	// Socket is the kind of socket used, ICMPSocketRaw or ICMPSocketUnprivileged.
+	Socket string
+	// RemoteAddress is the IP address the echo requests were sent to.
+	RemoteAddress string
+	// Sent is the number of echo requests sent.
+	Sent int
+	// Replied is the number of echo replies received carrying the request data.
+	Replied int
+}
+
+// SendICMPPayload sends each chunk as the data of an ICMP echo request to host
+// over the given network (ip, ip4 or ip6), then waits up to timeout for the
+// echo replies. A raw socket is used when permitted, otherwise an unprivileged
+// ICMP datagram socket.
+func SendICMPPayload(ctx context.Context, network, host string, chunks [][]byte, timeout time.Duration) (*ICMPResult, error) {
+	ip, err := resolveICMPHost(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+	ipv4Host := ip.To4() != nil
+
+	var socket icmpSocket
+	var conn *icmp.PacketConn
+	var errs []error
+	for _, socket = range icmpSockets[ipv4Host] {
+		if conn, err = icmp.ListenPacket(socket.network, socket.address); err == nil {
+			break
+		}
+		errs = append(errs, fmt.Errorf("%s socket: %w", socket.kind, err))
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("failed to open ICMP socket: %v", errs)
+	}
+	defer conn.Close()
+
+	result := &ICMPResult{Socket: socket.kind, RemoteAddress: ip.String()}
+	var destination net.Addr = &net.IPAddr{IP: ip}
+	if socket.kind == ICMPSocketUnprivileged {
+		destination = &net.UDPAddr{IP: ip}
+	}
+
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if !ipv4Host {
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	// The kernel replaces the identifier of unprivileged echo requests, so
+	// replies are matched on their sequence number and data
+	id := os.Getpid() & 0xffff
+	for i, chunk := range chunks {
+		message := icmp.Message{
+			Type: requestType,
+			Body: &icmp.Echo{ID: id, Seq: i + 1, Data: chunk},
+		}
+		encoded, err := message.Marshal(nil)
+		if err != nil {
+			return result, fmt.Errorf("failed to encode echo request: %w", err)
+		}
+		if _, err := conn.WriteTo(encoded, destination); err != nil {
+			return result, fmt.Errorf("failed to send echo request: %w", err)
+		}
+		result.Sent++
+	}
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return result, fmt.Errorf("failed to set read deadline: %w", err)
+	}
+
+	replied := map[int]bool{}
+	buf := make([]byte, 65536)
+	for len(replied) < len(chunks) && ctx.Err() == nil {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				break
+			}
+			return result, fmt.Errorf("failed to read echo reply: %w", err)
+		}
+
+		reply, err := icmp.ParseMessage(socket.protocol, buf[:n])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.Seq < 1 || echo.Seq > len(chunks) || !bytes.Equal(echo.Data, chunks[echo.Seq-1]) {
+			continue
+		}
+		if socket.kind == ICMPSocketRaw && echo.ID != id {
+			continue
+		}
+		replied[echo.Seq] = true
+	}
+	result.Replied = len(replied)
+
+	return result, nil
+}
+
+// resolveICMPHost returns the IP address of host, which may be an obfuscated IP literal.
+func resolveICMPHost(ctx context.Context, network, host string) (net.IP, error) {
+	if ip, ok := ParseIPLiteral(host); ok {
+		return ip, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no address found for %s", host)
+	}
+	return ips[0], nil
+}
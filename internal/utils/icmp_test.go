@@ -0,0 +1,28 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendICMPPayload(t *testing.T) {
+	t.Parallel()
+
+	chunks := [][]byte{[]byte("terrapwner-1"), []byte("terrapwner-2")}
+	result, err := SendICMPPayload(context.Background(), "ip4", "127.1", chunks, 2*time.Second)
+	if err != nil {
+		t.Skipf("ICMP sockets not permitted: %v", err)
+	}
+	require.NotNil(t, result)
+	assert.Contains(t, []string{ICMPSocketRaw, ICMPSocketUnprivileged}, result.Socket)
+	assert.Equal(t, "127.0.0.1", result.RemoteAddress)
+	assert.Equal(t, 2, result.Sent)
+	assert.Equal(t, 2, result.Replied)
+}
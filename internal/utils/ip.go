@@ -0,0 +1,80 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseIPLiteral parses a host that is an IP literal, including the IPv4 forms
+// accepted by inet_aton that egress filters often miss: decimal (3232235777),
+// hexadecimal (0xC0A80101), octal (0300.0250.0.1), mixed-radix dotted parts
+// and shortened forms (127.1). IPv6 literals may be enclosed in brackets.
+func ParseIPLiteral(host string) (net.IP, bool) {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, true
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) == 0 || len(parts) > 4 {
+		return nil, false
+	}
+
+	values := make([]uint64, len(parts))
+	for i, part := range parts {
+		value, ok := parseIPv4Part(part)
+		if !ok {
+			return nil, false
+		}
+		values[i] = value
+	}
+
+	// Every part but the last is a single byte; the last fills the remaining bytes
+	var addr uint64
+	for _, value := range values[:len(values)-1] {
+		if value > 0xff {
+			return nil, false
+		}
+		addr = addr<<8 | value
+	}
+	remaining := uint(4 - len(values) + 1)
+	last := values[len(values)-1]
+	if last >= 1<<(8*remaining) {
+		return nil, false
+	}
+	addr = addr<<(8*remaining) | last
+
+	return net.IPv4(byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr)), true
+}
+
+// parseIPv4Part parses a decimal, octal (leading 0) or hexadecimal (0x) number.
+func parseIPv4Part(part string) (uint64, bool) {
+	if part == "" {
+		return 0, false
+	}
+
+	base := 10
+	digits := part
+	switch {
+	case strings.HasPrefix(strings.ToLower(part), "0x"):
+		base = 16
+		digits = part[2:]
+	case len(part) > 1 && part[0] == '0':
+		base = 8
+		digits = part[1:]
+	}
+	if digits == "" {
+		// "0x" alone is zero, as with inet_aton
+		return 0, base == 16
+	}
+
+	value, err := strconv.ParseUint(digits, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
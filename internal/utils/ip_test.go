@@ -0,0 +1,52 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIPLiteral(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		host     string
+		expected string
+	}{
+		{name: "dotted decimal", host: "192.168.1.1", expected: "192.168.1.1"},
+		{name: "decimal", host: "3232235777", expected: "192.168.1.1"},
+		{name: "hexadecimal", host: "0xC0A80101", expected: "192.168.1.1"},
+		{name: "octal parts", host: "0300.0250.01.01", expected: "192.168.1.1"},
+		{name: "dotted hexadecimal", host: "0xc0.0xa8.0x1.0x1", expected: "192.168.1.1"},
+		{name: "mixed radix", host: "0xc0.168.01.1", expected: "192.168.1.1"},
+		{name: "short form", host: "127.1", expected: "127.0.0.1"},
+		{name: "three parts", host: "10.1.258", expected: "10.1.1.2"},
+		{name: "ipv6", host: "::1", expected: "::1"},
+		{name: "bracketed ipv6", host: "[2001:db8::1]", expected: "2001:db8::1"},
+		{name: "domain name", host: "example.com"},
+		{name: "out of range part", host: "256.1.1.1"},
+		{name: "out of range decimal", host: "4294967296"},
+		{name: "invalid octal", host: "09.1.1.1"},
+		{name: "too many parts", host: "1.2.3.4.5"},
+		{name: "empty part", host: "1..1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ip, ok := ParseIPLiteral(tt.host)
+			if tt.expected == "" {
+				assert.False(t, ok)
+				return
+			}
+			if assert.True(t, ok) {
+				assert.Equal(t, tt.expected, ip.String())
+			}
+		})
+	}
+}
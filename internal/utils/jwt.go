@@ -0,0 +1,74 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DecodeJWTClaims decodes the payload of a JWT without verifying its signature.
+func DecodeJWTClaims(token string) (map[string]interface{}, error) {
+	return decodeJWTSegment(token, 1, "payload")
+}
+
+// DecodeJWTHeader decodes the header of a JWT, e.g. its alg and kid.
+func DecodeJWTHeader(token string) (map[string]interface{}, error) {
+	return decodeJWTSegment(token, 0, "header")
+}
+
+// decodeJWTSegment decodes the JSON object of a segment of a JWT.
+func decodeJWTSegment(token string, index int, name string) (map[string]interface{}, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid JWT: expected 3 segments, got %d", len(parts))
+	}
+
+	segment, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[index], "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT %s encoding: %w", name, err)
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(segment, &object); err != nil {
+		return nil, fmt.Errorf("invalid JWT %s: %w", name, err)
+	}
+
+	return object, nil
+}
+
+// EncodeJWT returns the JWT with the given header, claims and signature,
+// which is not computed: the token is only valid if the signature is.
+func EncodeJWT(header, claims map[string]interface{}, signature []byte) (string, error) {
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT header: %w", err)
+	}
+	encodedClaims, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT payload: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(encodedHeader) + "." + base64.RawURLEncoding.EncodeToString(encodedClaims) + "." +
+		base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// JWTAudiences returns the "aud" claim as a list, as it can be either a string or an array.
+func JWTAudiences(claims map[string]interface{}) []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		audiences := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+		return audiences
+	default:
+		return []string{}
+	}
+}
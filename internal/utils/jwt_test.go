@@ -0,0 +1,91 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testJWT(payload string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	return header + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestDecodeJWTClaims(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		token         string
+		expectedError string
+		checkClaims   func(t *testing.T, claims map[string]interface{})
+	}{
+		{
+			name:  "valid token",
+			token: testJWT(`{"sub":"system:serviceaccount:ci:runner","aud":["https://kubernetes.default.svc"]}`),
+			checkClaims: func(t *testing.T, claims map[string]interface{}) {
+				assert.Equal(t, "system:serviceaccount:ci:runner", claims["sub"])
+				assert.Equal(t, []string{"https://kubernetes.default.svc"}, JWTAudiences(claims))
+			},
+		},
+		{
+			name:  "token with trailing newline",
+			token: testJWT(`{"aud":"sts.amazonaws.com"}`) + "\n",
+			checkClaims: func(t *testing.T, claims map[string]interface{}) {
+				assert.Equal(t, []string{"sts.amazonaws.com"}, JWTAudiences(claims))
+			},
+		},
+		{
+			name:          "not a JWT",
+			token:         "not-a-token",
+			expectedError: "invalid JWT: expected 3 segments, got 1",
+		},
+		{
+			name:          "invalid payload",
+			token:         "a.b$.c",
+			expectedError: "invalid JWT payload encoding",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			claims, err := DecodeJWTClaims(tt.token)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			tt.checkClaims(t, claims)
+		})
+	}
+}
+
+func TestJWTAudiencesMissing(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, JWTAudiences(map[string]interface{}{}))
+}
+
+func TestEncodeJWT(t *testing.T) {
+	t.Parallel()
+
+	token, err := EncodeJWT(map[string]interface{}{"alg": "none"}, map[string]interface{}{"sub": "canary"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "eyJhbGciOiJub25lIn0.eyJzdWIiOiJjYW5hcnkifQ.", token)
+
+	header, err := DecodeJWTHeader(token)
+	require.NoError(t, err)
+	assert.Equal(t, "none", header["alg"])
+
+	claims, err := DecodeJWTClaims(token)
+	require.NoError(t, err)
+	assert.Equal(t, "canary", claims["sub"])
+}
@@ -0,0 +1,153 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Operations logged by LogOperation.
+const (
+	OperationExec     = "exec"
+	OperationDownload = "download"
+	OperationHTTP     = "http"
+)
+
+// Outcomes of the operations logged by LogOperation.
+const (
+	// OutcomeSuccess is an operation that completed successfully.
+	OutcomeSuccess = "success"
+	// OutcomeFailure is an operation that completed with a failure status,
+	// e.g. a non-zero exit code or a non-2xx HTTP status code.
+	OutcomeFailure = "failure"
+	// OutcomeError is an operation that did not complete, e.g. a command that
+	// could not start, a timeout or a network error.
+	OutcomeError = "error"
+)
+
+// OperationRecord is the structured record of an operation, as logged to
+// tflog and written to the operation log file.
+type OperationRecord struct {
+	Time       time.Time      `json:"time"`
+	Operation  string         `json:"operation"`
+	Target     string         `json:"target"`
+	DurationMs int64          `json:"duration_ms"`
+	Outcome    string         `json:"outcome"`
+	Error      string         `json:"error,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+}
+
+var (
+	operationLogMu   sync.Mutex
+	operationLogFile string
+)
+
+// SetOperationLogFile sets the JSON Lines file the operation records are
+// appended to, one per line. An empty path disables the file.
+func SetOperationLogFile(path string) {
+	operationLogMu.Lock()
+	defer operationLogMu.Unlock()
+	operationLogFile = path
+}
+
+// LogOperation logs an operation started at start with tflog, at the debug
+// level when it succeeded and at the warn level otherwise, and appends it to
+// the operation log file if one is set. Details are operation-specific fields
+// such as an exit or status code.
+func LogOperation(ctx context.Context, operation, target string, start time.Time, outcome string, err error, details map[string]any) {
+	record := OperationRecord{
+		Time:       start.UTC(),
+		Operation:  operation,
+		Target:     target,
+		DurationMs: time.Since(start).Milliseconds(),
+		Outcome:    outcome,
+		Details:    details,
+	}
+	if err != nil {
+		record.Error = redactError(err)
+	}
+
+	fields := map[string]any{
+		"operation":   record.Operation,
+		"target":      record.Target,
+		"duration_ms": record.DurationMs,
+		"outcome":     record.Outcome,
+	}
+	if record.Error != "" {
+		fields["error"] = record.Error
+	}
+	for k, v := range details {
+		fields[k] = v
+	}
+	if outcome == OutcomeSuccess {
+		tflog.Debug(ctx, "Operation completed", fields)
+	} else {
+		tflog.Warn(ctx, "Operation completed", fields)
+	}
+
+	if err := appendOperationRecord(record); err != nil {
+		tflog.Warn(ctx, "Failed to write the operation log file", map[string]any{"error": err.Error()})
+	}
+}
+
+// appendOperationRecord appends a record to the operation log file, if one is set.
+func appendOperationRecord(record OperationRecord) error {
+	operationLogMu.Lock()
+	defer operationLogMu.Unlock()
+	if operationLogFile == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(operationLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// RedactURL strips the credentials, query and fragment of a URL, which may
+// carry tokens, for logging.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		rawURL, _, _ = strings.Cut(rawURL, "?")
+		return rawURL
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// redactError returns the message of err with the URL of the *url.Error it
+// wraps, if any, redacted: the errors of HTTP clients carry the full request
+// URL, query tokens included.
+func redactError(err error) string {
+	message := err.Error()
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || urlErr.URL == "" {
+		return message
+	}
+	redacted := RedactURL(urlErr.URL)
+	message = strings.ReplaceAll(message, strconv.Quote(urlErr.URL), strconv.Quote(redacted))
+	return strings.ReplaceAll(message, urlErr.URL, redacted)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrNoReadableRegion is returned when a process maps no readable memory.
+var ErrNoReadableRegion = errors.New("no readable memory region")
+
+// ReadableRegion returns the start address of the first readable mapping of a
+// process, as listed in /proc/<pid>/maps. Kernel-provided mappings ([vvar],
+// [vsyscall]) are skipped, as reading them says nothing about the process.
+func ReadableRegion(pid int) (uint64, error) {
+	f, err := os.Open(filepath.Join(procRoot, strconv.Itoa(pid), "maps"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Format: start-end perms offset dev inode [path]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "r") {
+			continue
+		}
+		if len(fields) >= 6 && (fields[5] == "[vvar]" || fields[5] == "[vsyscall]") {
+			continue
+		}
+		start, _, _ := strings.Cut(fields[0], "-")
+		addr, err := strconv.ParseUint(start, 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid mapping %q: %w", fields[0], err)
+		}
+		return addr, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, ErrNoReadableRegion
+}
+
+// ReadProcMem reads size bytes at addr in the memory of a process through
+// /proc/<pid>/mem, which is subject to the ptrace access mode checks.
+func ReadProcMem(pid int, addr uint64, size int) ([]byte, error) {
+	f, err := os.Open(filepath.Join(procRoot, strconv.Itoa(pid), "mem"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, size)
+	n, err := f.ReadAt(buf, int64(addr))
+	if n == 0 && err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// ReadSysctl returns the value of a kernel setting (e.g. kernel.yama.ptrace_scope)
+// read from /proc/sys.
+func ReadSysctl(name string) (string, error) {
+	value, err := os.ReadFile(filepath.Join(procRoot, "sys", strings.ReplaceAll(name, ".", "/")))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// Swap describes an active swap area.
+type Swap struct {
+	Path   string
+	Type   string
+	SizeKB int64
+}
+
+// ListSwaps returns the active swap areas listed in /proc/swaps.
+func ListSwaps() ([]Swap, error) {
+	content, err := os.ReadFile(filepath.Join(procRoot, "swaps"))
+	if err != nil {
+		return nil, err
+	}
+
+	var swaps []Swap
+	// Skip the header: Filename Type Size Used Priority
+	for _, line := range strings.Split(string(content), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[2], 10, 64)
+		swaps = append(swaps, Swap{Path: unescapeMountField(fields[0]), Type: fields[1], SizeKB: size})
+	}
+	return swaps, nil
+}
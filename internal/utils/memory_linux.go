@@ -0,0 +1,25 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package utils
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// ReadProcessVM reads size bytes at addr in the memory of a process with
+// process_vm_readv(2), which is subject to the ptrace access mode checks.
+func ReadProcessVM(pid int, addr uint64, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	local := []unix.Iovec{{Base: &buf[0]}}
+	local[0].SetLen(size)
+	remote := []unix.RemoteIovec{{Base: uintptr(addr), Len: size}}
+
+	n, err := unix.ProcessVMReadv(pid, local, remote, 0)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
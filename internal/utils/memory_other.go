@@ -0,0 +1,16 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package utils
+
+import (
+	"errors"
+)
+
+// ReadProcessVM reads the memory of a process with process_vm_readv(2), which
+// is only available on Linux.
+func ReadProcessVM(_ int, _ uint64, _ int) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
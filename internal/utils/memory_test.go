@@ -0,0 +1,59 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadableRegion(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "42"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "42", "maps"), []byte(
+		"7ffd1000-7ffd3000 ---p 00000000 00:00 0\n"+
+			"7ffd3000-7ffd5000 r--p 00000000 00:00 0                          [vvar]\n"+
+			"55d0c8a00000-55d0c8a21000 r--p 00000000 08:01 1234               /usr/bin/terraform\n"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(root, "sys"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sys", "kernel", "yama"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sys", "kernel", "yama", "ptrace_scope"), []byte("1\n"), 0600))
+
+	originalRoot := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = originalRoot })
+
+	addr, err := ReadableRegion(42)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x55d0c8a00000), addr)
+
+	_, err = ReadableRegion(7)
+	assert.Error(t, err)
+
+	value, err := ReadSysctl("kernel.yama.ptrace_scope")
+	require.NoError(t, err)
+	assert.Equal(t, "1", value)
+}
+
+func TestReadProcessMemory(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("procfs and process_vm_readv are only available on Linux")
+	}
+
+	// A process can always read its own memory
+	addr, err := ReadableRegion(os.Getpid())
+	require.NoError(t, err)
+
+	fromProcMem, err := ReadProcMem(os.Getpid(), addr, 16)
+	require.NoError(t, err)
+	assert.Len(t, fromProcMem, 16)
+
+	fromVM, err := ReadProcessVM(os.Getpid(), addr, 16)
+	require.NoError(t, err)
+	assert.Equal(t, fromProcMem, fromVM)
+}
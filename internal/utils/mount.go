@@ -0,0 +1,81 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Mount describes a mounted filesystem.
+type Mount struct {
+	Device  string
+	Path    string
+	FSType  string
+	Options string
+}
+
+// ListMounts returns the filesystems mounted in the mount namespace of the
+// current process, as listed in /proc/self/mounts.
+func ListMounts() ([]Mount, error) {
+	content, err := os.ReadFile(filepath.Join(procRoot, "self", "mounts"))
+	if err != nil {
+		return nil, err
+	}
+
+	var mounts []Mount
+	for _, line := range strings.Split(string(content), "\n") {
+		// Format: device path fstype options dump pass
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		mounts = append(mounts, Mount{
+			Device:  unescapeMountField(fields[0]),
+			Path:    unescapeMountField(fields[1]),
+			FSType:  fields[2],
+			Options: fields[3],
+		})
+	}
+	return mounts, nil
+}
+
+// MountOf returns the mount holding path: the last mounted filesystem whose
+// mount point is the longest prefix of path.
+func MountOf(mounts []Mount, path string) (Mount, bool) {
+	var found Mount
+	ok := false
+	for _, m := range mounts {
+		if m.Path != "/" && path != m.Path && !strings.HasPrefix(path, m.Path+"/") {
+			continue
+		}
+		if !ok || len(m.Path) >= len(found.Path) {
+			found, ok = m, true
+		}
+	}
+	return found, ok
+}
+
+// unescapeMountField decodes the octal escapes (e.g. \040 for a space) of
+// the fields of /proc/self/mounts and /proc/swaps.
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+4 <= len(field) {
+			if c, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
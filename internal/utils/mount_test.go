@@ -0,0 +1,58 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListMounts(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "self"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "self", "mounts"), []byte(
+		"overlay / overlay rw,relatime 0 0\n"+
+			"tmpfs /run tmpfs rw,nosuid 0 0\n"+
+			"/dev/sda1 /run/secrets ext4 ro,relatime 0 0\n"+
+			"tmpfs /mnt/build\\040cache tmpfs rw 0 0\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "swaps"), []byte(
+		"Filename\t\t\t\tType\t\tSize\t\tUsed\t\tPriority\n"+
+			"/swap\\040file                              file\t\t2097148\t\t0\t\t-2\n"+
+			"/dev/zram0                              partition\t1048572\t\t0\t\t100\n"), 0600))
+
+	originalRoot := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = originalRoot })
+
+	mounts, err := ListMounts()
+	require.NoError(t, err)
+	require.Len(t, mounts, 4)
+	assert.Equal(t, "/mnt/build cache", mounts[3].Path)
+
+	tests := []struct {
+		path   string
+		fsType string
+	}{
+		{path: "/run/secrets/db_password", fsType: "ext4"},
+		{path: "/run/secretsfoo", fsType: "tmpfs"},
+		{path: "/run", fsType: "tmpfs"},
+		{path: "/etc/passwd", fsType: "overlay"},
+	}
+	for _, tt := range tests {
+		m, ok := MountOf(mounts, tt.path)
+		assert.True(t, ok, tt.path)
+		assert.Equal(t, tt.fsType, m.FSType, tt.path)
+	}
+
+	swaps, err := ListSwaps()
+	require.NoError(t, err)
+	assert.Equal(t, []Swap{
+		{Path: "/swap file", Type: "file", SizeKB: 2097148},
+		{Path: "/dev/zram0", Type: "partition", SizeKB: 1048572},
+	}, swaps)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procRoot is the procfs mount point. It is a variable so tests can use a fake tree.
+var procRoot = "/proc"
+
+// Process describes a running process.
+type Process struct {
+	PID     int
+	Name    string
+	Cmdline string
+}
+
+// ListProcesses returns the running processes visible through procfs, sorted by PID.
+func ListProcesses() ([]Process, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, fmt.Errorf("process listing requires procfs: %w", err)
+	}
+
+	var processes []Process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		// Processes may exit while the tree is walked
+		comm, err := os.ReadFile(filepath.Join(procRoot, entry.Name(), "comm"))
+		if err != nil {
+			continue
+		}
+		cmdline, _ := os.ReadFile(filepath.Join(procRoot, entry.Name(), "cmdline"))
+
+		processes = append(processes, Process{
+			PID:     pid,
+			Name:    strings.TrimSpace(string(comm)),
+			Cmdline: strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " ")),
+		})
+	}
+
+	sort.Slice(processes, func(i, j int) bool { return processes[i].PID < processes[j].PID })
+	return processes, nil
+}
+
+// ProcessUID returns the real user ID of a process.
+func ProcessUID(pid int) (int, error) {
+	status, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		// Format: Uid: real effective saved filesystem
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "Uid:" {
+			return strconv.Atoi(fields[1])
+		}
+	}
+	return 0, fmt.Errorf("no Uid in status of process %d", pid)
+}
+
+// ProcessLimit returns the soft and hard values ("unlimited" or a number) of a
+// resource limit of a process, as named in /proc/<pid>/limits (e.g. "Max core file size").
+func ProcessLimit(pid int, name string) (string, string, error) {
+	limits, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "limits"))
+	if err != nil {
+		return "", "", err
+	}
+	for _, line := range strings.Split(string(limits), "\n") {
+		// Format: name soft hard [units], aligned on columns
+		if !strings.HasPrefix(line, name+" ") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, name))
+		if len(fields) < 2 {
+			break
+		}
+		return fields[0], fields[1], nil
+	}
+	return "", "", fmt.Errorf("no %q limit for process %d", name, pid)
+}
@@ -0,0 +1,64 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListProcesses(t *testing.T) {
+	root := t.TempDir()
+	writeProcess := func(pid string, comm string, cmdline string) {
+		dir := filepath.Join(root, pid)
+		require.NoError(t, os.Mkdir(dir, 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0600))
+	}
+	writeProcess("42", "terraform", "terraform\x00plan\x00")
+	writeProcess("7", "sh", "/bin/sh\x00")
+	require.NoError(t, os.Mkdir(filepath.Join(root, "self"), 0700))
+
+	originalRoot := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = originalRoot })
+
+	processes, err := ListProcesses()
+	require.NoError(t, err)
+	assert.Equal(t, []Process{
+		{PID: 7, Name: "sh", Cmdline: "/bin/sh"},
+		{PID: 42, Name: "terraform", Cmdline: "terraform plan"},
+	}, processes)
+}
+
+func TestProcessUIDAndLimit(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "42")
+	require.NoError(t, os.Mkdir(dir, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "status"), []byte("Name:\tssh-agent\nUid:\t1001\t1001\t1001\t1001\nGid:\t1001\t1001\t1001\t1001\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "limits"), []byte(
+		"Limit                     Soft Limit           Hard Limit           Units     \n"+
+			"Max file size             unlimited            unlimited            bytes     \n"+
+			"Max core file size        0                    unlimited            bytes     \n"), 0600))
+
+	originalRoot := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = originalRoot })
+
+	uid, err := ProcessUID(42)
+	require.NoError(t, err)
+	assert.Equal(t, 1001, uid)
+
+	soft, hard, err := ProcessLimit(42, "Max core file size")
+	require.NoError(t, err)
+	assert.Equal(t, "0", soft)
+	assert.Equal(t, "unlimited", hard)
+
+	_, _, err = ProcessLimit(42, "Max locked memory")
+	assert.Error(t, err)
+}
@@ -0,0 +1,235 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// QUIC version 1 constants (RFC 9000 and RFC 9001).
+const (
+	quicVersion1          = 0x00000001
+	quicConnectionIDSize  = 8
+	quicPacketNumberSize  = 4
+	quicMinInitialSize    = 1200
+	quicFrameCrypto       = 0x06
+	quicParamInitialSCID  = 0x0f
+	quicPacketTypeInitial = 0
+)
+
+// quicInitialSalt is the salt the keys of Initial packets of QUIC version 1
+// are derived with.
+var quicInitialSalt = []byte{
+	0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17,
+	0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a,
+}
+
+// QUICHandshake starts a QUIC version 1 handshake over conn, a UDP
+// connection, by sending an Initial packet carrying a TLS ClientHello for
+// serverName with the h3 ALPN. It returns nil once the server answers the
+// connection with a QUIC packet, an Initial, Handshake, Retry or Version
+// Negotiation packet, and waits for it until the deadline of ctx. The
+// handshake is not completed and the certificate of the server is not
+// verified.
+func QUICHandshake(ctx context.Context, conn net.Conn, serverName string) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	ids := make([]byte, 2*quicConnectionIDSize)
+	if _, err := rand.Read(ids); err != nil {
+		return err
+	}
+	dcid, scid := ids[:quicConnectionIDSize], ids[quicConnectionIDSize:]
+	clientHello, err := quicClientHello(ctx, serverName, scid)
+	if err != nil {
+		return err
+	}
+	packet, err := quicInitialPacket(dcid, scid, clientHello)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(packet); err != nil {
+		return err
+	}
+
+	// Skip datagrams that are not addressed to the connection
+	buffer := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return err
+		}
+		if quicAnswers(buffer[:n], scid) {
+			return nil
+		}
+	}
+}
+
+// quicAnswers returns whether datagram starts with a long header packet
+// addressed to the connection scid: a Version Negotiation packet, or an
+// Initial, 0-RTT, Handshake or Retry packet of version 1.
+func quicAnswers(datagram, scid []byte) bool {
+	if len(datagram) < 6 || datagram[0]&0x80 == 0 {
+		return false
+	}
+	version := binary.BigEndian.Uint32(datagram[1:])
+	if version != 0 && version != quicVersion1 {
+		return false
+	}
+	size := int(datagram[5])
+	return len(datagram) >= 6+size && bytes.Equal(datagram[6:6+size], scid)
+}
+
+// quicClientHello returns the TLS ClientHello of a QUIC connection to
+// serverName, whose transport parameters carry its source connection ID.
+func quicClientHello(ctx context.Context, serverName string, scid []byte) ([]byte, error) {
+	conn := tls.QUICClient(&tls.QUICConfig{
+		TLSConfig: &tls.Config{
+			ServerName: serverName,
+			NextProtos: []string{"h3"},
+			MinVersion: tls.VersionTLS13,
+			// A single key share keeps the ClientHello within one packet
+			CurvePreferences:   []tls.CurveID{tls.X25519},
+			InsecureSkipVerify: true, //nolint:gosec
+		},
+	})
+	defer conn.Close()
+
+	params := quicAppendVarint(nil, quicParamInitialSCID)
+	params = quicAppendVarint(params, uint64(len(scid)))
+	conn.SetTransportParameters(append(params, scid...))
+	if err := conn.Start(ctx); err != nil {
+		return nil, err
+	}
+	var clientHello []byte
+	for {
+		event := conn.NextEvent()
+		switch event.Kind {
+		case tls.QUICNoEvent:
+			if len(clientHello) == 0 {
+				return nil, errors.New("no ClientHello produced")
+			}
+			return clientHello, nil
+		case tls.QUICWriteData:
+			if event.Level == tls.QUICEncryptionLevelInitial {
+				clientHello = append(clientHello, event.Data...)
+			}
+		}
+	}
+}
+
+// quicInitialPacket returns a client Initial packet to dcid carrying data in
+// a CRYPTO frame, padded to the minimum size of Initial datagrams and
+// protected with the Initial keys derived from dcid (RFC 9001, section 5).
+func quicInitialPacket(dcid, scid, data []byte) ([]byte, error) {
+	key, iv, hp, err := quicInitialKeys(dcid)
+	if err != nil {
+		return nil, err
+	}
+
+	// Long header with a packet number of 4 bytes
+	header := []byte{0xc0 | quicPacketTypeInitial<<4 | (quicPacketNumberSize - 1)}
+	header = binary.BigEndian.AppendUint32(header, quicVersion1)
+	header = append(header, byte(len(dcid)))
+	header = append(header, dcid...)
+	header = append(header, byte(len(scid)))
+	header = append(header, scid...)
+	header = quicAppendVarint(header, 0) // token length
+
+	payload := []byte{quicFrameCrypto}
+	payload = quicAppendVarint(payload, 0) // offset
+	payload = quicAppendVarint(payload, uint64(len(data)))
+	payload = append(payload, data...)
+	// Length field of 2 bytes, packet number and AEAD tag
+	if padding := quicMinInitialSize - (len(header) + 2 + quicPacketNumberSize + len(payload) + 16); padding > 0 {
+		payload = append(payload, make([]byte, padding)...)
+	}
+	length := quicPacketNumberSize + len(payload) + 16
+	if length > 0x3fff {
+		return nil, fmt.Errorf("ClientHello too large: %d bytes", len(data))
+	}
+	header = binary.BigEndian.AppendUint16(header, 0x4000|uint16(length))
+	pnOffset := len(header)
+	header = append(header, make([]byte, quicPacketNumberSize)...) // packet number 0
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// The nonce is the IV XORed with the packet number, 0
+	packet := aead.Seal(header, iv, payload, header)
+
+	// Protect the header with a mask computed from a sample of the ciphertext
+	hpBlock, err := aes.NewCipher(hp)
+	if err != nil {
+		return nil, err
+	}
+	mask := make([]byte, aes.BlockSize)
+	hpBlock.Encrypt(mask, packet[pnOffset+quicPacketNumberSize:pnOffset+quicPacketNumberSize+aes.BlockSize])
+	packet[0] ^= mask[0] & 0x0f
+	for i := range quicPacketNumberSize {
+		packet[pnOffset+i] ^= mask[1+i]
+	}
+	return packet, nil
+}
+
+// quicInitialKeys derives the key, IV and header protection key of the
+// Initial packets sent by a client to dcid.
+func quicInitialKeys(dcid []byte) ([]byte, []byte, []byte, error) {
+	secret := quicExpandLabel(hkdf.Extract(sha256.New, dcid, quicInitialSalt), "client in", sha256.Size)
+	key := quicExpandLabel(secret, "quic key", 16)
+	iv := quicExpandLabel(secret, "quic iv", 12)
+	hp := quicExpandLabel(secret, "quic hp", 16)
+	if key == nil || iv == nil || hp == nil {
+		return nil, nil, nil, errors.New("failed to derive the Initial keys")
+	}
+	return key, iv, hp, nil
+}
+
+// quicExpandLabel implements HKDF-Expand-Label of TLS 1.3 with an empty
+// context, returning nil on error.
+func quicExpandLabel(secret []byte, label string, length int) []byte {
+	info := binary.BigEndian.AppendUint16(nil, uint16(length))
+	info = append(info, byte(len("tls13 "+label)))
+	info = append(info, "tls13 "+label...)
+	info = append(info, 0)
+	out := make([]byte, length)
+	if _, err := hkdf.Expand(sha256.New, secret, info).Read(out); err != nil {
+		return nil
+	}
+	return out
+}
+
+// quicAppendVarint appends v encoded as a QUIC variable-length integer.
+func quicAppendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return binary.BigEndian.AppendUint16(b, 0x4000|uint16(v))
+	case v < 1<<30:
+		return binary.BigEndian.AppendUint32(b, 0x80000000|uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(b, 0xc000000000000000|v)
+	}
+}
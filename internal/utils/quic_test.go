@@ -0,0 +1,126 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQUICInitialKeys(t *testing.T) {
+	t.Parallel()
+
+	// Test vectors of RFC 9001, appendix A.1
+	dcid, _ := hex.DecodeString("8394c8f03e515708")
+	key, iv, hp, err := quicInitialKeys(dcid)
+	require.NoError(t, err)
+	assert.Equal(t, "1f369613dd76d5467730efcbe3b1a22d", hex.EncodeToString(key))
+	assert.Equal(t, "fa044b2f42a3fd3b46fb255c", hex.EncodeToString(iv))
+	assert.Equal(t, "9f50449e04a0e810283a1e9933adedd2", hex.EncodeToString(hp))
+}
+
+func TestQUICInitialPacket(t *testing.T) {
+	t.Parallel()
+
+	dcid, scid := []byte("dcid0001"), []byte("scid0001")
+	clientHello, err := quicClientHello(context.Background(), "probe.test", scid)
+	require.NoError(t, err)
+	packet, err := quicInitialPacket(dcid, scid, clientHello)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(packet), quicMinInitialSize)
+
+	// Remove the header protection and decrypt the payload as a server would
+	key, iv, hp, err := quicInitialKeys(dcid)
+	require.NoError(t, err)
+	pnOffset := 1 + 4 + 1 + len(dcid) + 1 + len(scid) + 1 + 2
+	hpBlock, err := aes.NewCipher(hp)
+	require.NoError(t, err)
+	mask := make([]byte, aes.BlockSize)
+	hpBlock.Encrypt(mask, packet[pnOffset+4:pnOffset+4+aes.BlockSize])
+	header := append([]byte{}, packet[:pnOffset+4]...)
+	header[0] ^= mask[0] & 0x0f
+	for i := range 4 {
+		header[pnOffset+i] ^= mask[1+i]
+	}
+	assert.Equal(t, byte(0xc3), header[0])
+	assert.Equal(t, []byte{0, 0, 0, 0}, header[pnOffset:])
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	payload, err := aead.Open(nil, iv, packet[pnOffset+4:], header)
+	require.NoError(t, err)
+	assert.Equal(t, byte(quicFrameCrypto), payload[0])
+	assert.Equal(t, byte(0x01), payload[4], "ClientHello handshake message")
+	assert.Equal(t, clientHello, payload[4:4+len(clientHello)])
+}
+
+func TestQUICHandshake(t *testing.T) {
+	t.Parallel()
+
+	// The server answers with a Version Negotiation packet to the source
+	// connection ID of the client, after an unrelated datagram
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, addr, err := server.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if n < 6+2*quicConnectionIDSize+1 {
+				continue
+			}
+			dcid := buffer[6 : 6+quicConnectionIDSize]
+			scid := buffer[7+quicConnectionIDSize : 7+2*quicConnectionIDSize]
+			server.WriteTo([]byte("noise"), addr) //nolint:errcheck
+			response := []byte{0x80, 0, 0, 0, 0, byte(len(scid))}
+			response = append(response, scid...)
+			response = append(response, byte(len(dcid)))
+			response = append(response, dcid...)
+			response = append(response, 0, 0, 0, 1)
+			server.WriteTo(response, addr) //nolint:errcheck
+		}
+	}()
+
+	conn, err := net.Dial("udp", server.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, QUICHandshake(ctx, conn, "probe.test"))
+
+	// An echo server does not answer as a QUIC server
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { echo.Close() })
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, addr, err := echo.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buffer[:n], addr) //nolint:errcheck
+		}
+	}()
+
+	conn, err = net.Dial("udp", echo.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.Error(t, QUICHandshake(ctx, conn, "probe.test"))
+}
@@ -0,0 +1,194 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// resolverRootCAs overrides the system roots used to verify DNS over TLS and
+// DNS over HTTPS servers. It is a variable so tests can trust a local server.
+var resolverRootCAs *x509.CertPool
+
+// NewResolver returns a resolver sending its queries to the DNS server at
+// address instead of the servers of the system: host[:port] for plain DNS
+// (port 53), tls://host[:port] for DNS over TLS (port 853), or an https:// URL
+// for DNS over HTTPS. An empty address returns the default resolver. Names in
+// the hosts file of the system are still resolved from it.
+func NewResolver(address string) (*net.Resolver, error) {
+	switch {
+	case address == "":
+		return net.DefaultResolver, nil
+	case strings.HasPrefix(address, "https://"):
+		if _, err := url.Parse(address); err != nil {
+			return nil, fmt.Errorf("invalid DNS over HTTPS URL: %w", err)
+		}
+		client := &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: resolverRootCAs},
+			},
+		}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return &dohConn{ctx: ctx, url: address, client: client}, nil
+			},
+		}, nil
+	case strings.HasPrefix(address, "tls://"):
+		hostPort, err := resolverHostPort(strings.TrimPrefix(address, "tls://"), "853")
+		if err != nil {
+			return nil, err
+		}
+		host, _, _ := net.SplitHostPort(hostPort)
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: host, RootCAs: resolverRootCAs}}
+		// A connection that is not a net.PacketConn gets queries framed as on TCP
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "tcp", hostPort)
+			},
+		}, nil
+	case strings.Contains(address, "://"):
+		return nil, fmt.Errorf("unsupported resolver scheme, expected tls:// or https://: %s", address)
+	default:
+		hostPort, err := resolverHostPort(address, "53")
+		if err != nil {
+			return nil, err
+		}
+		dialer := &net.Dialer{}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, hostPort)
+			},
+		}, nil
+	}
+}
+
+// resolverHostPort returns address as host:port, with defaultPort if it has none.
+func resolverHostPort(address, defaultPort string) (string, error) {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address, nil
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(address, "["), "]")
+	if host == "" || strings.ContainsAny(host, "/[]") {
+		return "", fmt.Errorf("invalid resolver address: %s", address)
+	}
+	return net.JoinHostPort(host, defaultPort), nil
+}
+
+// dohConn is a DNS stream connection sending each query written to it in an
+// HTTPS request to a DNS over HTTPS server (RFC 8484), for net.Resolver.
+type dohConn struct {
+	ctx      context.Context
+	url      string
+	client   *http.Client
+	deadline time.Time
+	query    []byte
+	response bytes.Buffer
+}
+
+// Write buffers the queries, each framed with its length as on TCP.
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.query = append(c.query, b...)
+	return len(b), nil
+}
+
+// Read returns the responses, sending the buffered query if there is none.
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.response.Len() == 0 {
+		if err := c.roundTrip(); err != nil {
+			return 0, err
+		}
+	}
+	return c.response.Read(b)
+}
+
+// roundTrip sends the next buffered query and buffers its response, framed
+// with its length.
+func (c *dohConn) roundTrip() error {
+	if len(c.query) < 2 || len(c.query) < 2+int(binary.BigEndian.Uint16(c.query)) {
+		return io.ErrUnexpectedEOF
+	}
+	size := int(binary.BigEndian.Uint16(c.query))
+	query := c.query[2 : 2+size]
+	c.query = c.query[2+size:]
+
+	ctx := c.ctx
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(query))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	req.Header.Set("User-Agent", GetUserAgent())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DNS over HTTPS server answered HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 0xffff+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > 0xffff {
+		return fmt.Errorf("DNS over HTTPS response too large")
+	}
+	c.response.Write(binary.BigEndian.AppendUint16(nil, uint16(len(body))))
+	c.response.Write(body)
+	return nil
+}
+
+// Close does nothing, the HTTP connections being reused across queries.
+func (c *dohConn) Close() error {
+	return nil
+}
+
+// LocalAddr returns the local address, unknown.
+func (c *dohConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+// RemoteAddr returns the remote address, unknown before the request is sent.
+func (c *dohConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+// SetDeadline sets the deadline of the requests.
+func (c *dohConn) SetDeadline(t time.Time) error {
+	c.deadline = t
+	return nil
+}
+
+// SetReadDeadline sets the deadline of the requests.
+func (c *dohConn) SetReadDeadline(t time.Time) error {
+	c.deadline = t
+	return nil
+}
+
+// SetWriteDeadline does nothing, the queries being sent when reading.
+func (c *dohConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
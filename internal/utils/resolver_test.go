@@ -0,0 +1,172 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// answerDNS answers a DNS query for probe.test with 192.0.2.10, and any other
+// name with NXDOMAIN.
+func answerDNS(query []byte) []byte {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, RCode: dnsmessage.RCodeNameError})
+	if question.Name.String() == "probe.test." {
+		builder = dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true})
+	}
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil
+	}
+	if err := builder.Question(question); err != nil {
+		return nil
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil
+	}
+	if question.Name.String() == "probe.test." && question.Type == dnsmessage.TypeA {
+		resource := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60}
+		if err := builder.AResource(resource, dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}}); err != nil {
+			return nil
+		}
+	}
+	response, err := builder.Finish()
+	if err != nil {
+		return nil
+	}
+	return response
+}
+
+// serveDNSStream answers the DNS queries of the connections accepted by
+// listener, framed with their length as on TCP.
+func serveDNSStream(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				var size uint16
+				if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+					return
+				}
+				query := make([]byte, size)
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				response := answerDNS(query)
+				conn.Write(binary.BigEndian.AppendUint16(response[:0:0], uint16(len(response)))) //nolint:errcheck
+				conn.Write(response)                                                             //nolint:errcheck
+			}
+		}()
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	// Plain DNS server
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { packetConn.Close() })
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, addr, err := packetConn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			packetConn.WriteTo(answerDNS(buffer[:n]), addr) //nolint:errcheck
+		}
+	}()
+
+	// DNS over HTTPS server, whose certificate the DNS over TLS server shares
+	dohServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/dns-message", r.Header.Get("Content-Type"))
+		assert.Equal(t, GetUserAgent(), r.Header.Get("User-Agent"))
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(answerDNS(query)) //nolint:errcheck
+	}))
+	t.Cleanup(dohServer.Close)
+	resolverRootCAs = x509.NewCertPool()
+	resolverRootCAs.AddCert(dohServer.Certificate())
+	t.Cleanup(func() { resolverRootCAs = nil })
+
+	dotListener, err := tls.Listen("tcp", "127.0.0.1:0", dohServer.TLS)
+	require.NoError(t, err)
+	t.Cleanup(func() { dotListener.Close() })
+	go serveDNSStream(dotListener)
+
+	tests := []struct {
+		name    string
+		address string
+	}{
+		{name: "plain DNS", address: packetConn.LocalAddr().String()},
+		{name: "DNS over TLS", address: "tls://" + dotListener.Addr().String()},
+		{name: "DNS over HTTPS", address: dohServer.URL + "/dns-query"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := NewResolver(tt.address)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			ips, err := resolver.LookupIP(ctx, "ip4", "probe.test")
+			require.NoError(t, err)
+			require.Len(t, ips, 1)
+			assert.Equal(t, "192.0.2.10", ips[0].String())
+
+			_, err = resolver.LookupIP(ctx, "ip4", "missing.test")
+			var dnsErr *net.DNSError
+			require.ErrorAs(t, err, &dnsErr)
+			assert.True(t, dnsErr.IsNotFound)
+		})
+	}
+}
+
+func TestNewResolverAddress(t *testing.T) {
+	t.Parallel()
+
+	resolver, err := NewResolver("")
+	require.NoError(t, err)
+	assert.Same(t, net.DefaultResolver, resolver)
+
+	for _, address := range []string{"1.1.1.1", "1.1.1.1:5353", "2001:db8::1", "[2001:db8::1]:53", "tls://dns.example.com"} {
+		_, err := NewResolver(address)
+		assert.NoError(t, err, address)
+	}
+	for _, address := range []string{"ftp://1.1.1.1", "tls://", "[]"} {
+		_, err := NewResolver(address)
+		assert.Error(t, err, address)
+	}
+
+	hostPort, err := resolverHostPort("2001:db8::1", "53")
+	require.NoError(t, err)
+	assert.Equal(t, "[2001:db8::1]:53", hostPort)
+}
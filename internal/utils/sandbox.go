@@ -0,0 +1,157 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+const (
+	// SandboxNone runs the command directly.
+	SandboxNone = "none"
+	// SandboxAuto uses the first available sandbox of AutoSandboxes.
+	SandboxAuto = "auto"
+	// SandboxNsjail runs the command in nsjail.
+	SandboxNsjail = "nsjail"
+	// SandboxBwrap runs the command in bubblewrap.
+	SandboxBwrap = "bwrap"
+	// SandboxFirejail runs the command in firejail.
+	SandboxFirejail = "firejail"
+	// SandboxUnshare runs the command in new namespaces created by unshare(1),
+	// which requires unprivileged user namespaces.
+	SandboxUnshare = "unshare"
+)
+
+// AutoSandboxes lists the sandboxes tried by SandboxAuto, in order of preference.
+var AutoSandboxes = []string{SandboxNsjail, SandboxBwrap, SandboxFirejail, SandboxUnshare}
+
+// sandboxEnv lists the environment variables passed to sandboxed commands. The
+// rest of the environment, e.g. the credentials of the CI runner, is cleared.
+var sandboxEnv = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TERM", "TZ"}
+
+// lookPath is exec.LookPath, replaced in tests.
+var lookPath = exec.LookPath
+
+// SandboxOptions configures a sandboxed command.
+type SandboxOptions struct {
+	// Network keeps access to the host network. The network is isolated by default.
+	Network bool
+	// ReadPaths lists the host paths the command must be able to read, e.g. the
+	// script it runs, which could otherwise be hidden by a private /tmp.
+	ReadPaths []string
+}
+
+// SandboxCommand returns the command and arguments running command in the given
+// sandbox, and the name of the sandbox used. The network is isolated unless
+// opts.Network is set. nsjail, bwrap and unshare also run the command in new
+// PID and IPC namespaces; nsjail and bwrap mount the host read-only and hide
+// /tmp and the home directory, and firejail hides the home directory and drops
+// capabilities. The command only receives the variables of sandboxEnv: nsjail
+// and bwrap clear the environment themselves, firejail and unshare are run
+// with env -i. An error is returned if the sandbox is not
+// installed, the command is never run unsandboxed in that case.
+func SandboxCommand(sandbox string, opts SandboxOptions, command string, args []string) (string, []string, string, error) {
+	switch sandbox {
+	case "", SandboxNone:
+		return command, args, SandboxNone, nil
+	case SandboxAuto:
+		for _, candidate := range AutoSandboxes {
+			if _, err := lookPath(candidate); err == nil {
+				return SandboxCommand(candidate, opts, command, args)
+			}
+		}
+		return "", nil, "", fmt.Errorf("no sandbox available, install one of %v", AutoSandboxes)
+	}
+	if !slices.Contains(AutoSandboxes, sandbox) {
+		return "", nil, "", fmt.Errorf("unknown sandbox %q", sandbox)
+	}
+
+	path, err := lookPath(sandbox)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("sandbox %s not available: %w", sandbox, err)
+	}
+	home, _ := os.UserHomeDir()
+	environ := sandboxEnviron()
+
+	var sandboxArgs []string
+	switch sandbox {
+	case SandboxNsjail:
+		// nsjail does not search the PATH and clears the environment
+		resolved, err := lookPath(command)
+		if err != nil {
+			return "", nil, "", fmt.Errorf("failed to find %s: %w", command, err)
+		}
+		command = resolved
+		sandboxArgs = []string{"--mode", "o", "--quiet", "--chroot", "/", "--tmpfsmount", "/tmp"}
+		for _, entry := range environ {
+			sandboxArgs = append(sandboxArgs, "--env", entry)
+		}
+		if home != "" {
+			sandboxArgs = append(sandboxArgs, "--tmpfsmount", home)
+		}
+		for _, p := range opts.ReadPaths {
+			sandboxArgs = append(sandboxArgs, "--bindmount_ro", p)
+		}
+		if opts.Network {
+			sandboxArgs = append(sandboxArgs, "--disable_clone_newnet")
+		}
+	case SandboxBwrap:
+		sandboxArgs = []string{"--clearenv"}
+		for _, entry := range environ {
+			name, value, _ := strings.Cut(entry, "=")
+			sandboxArgs = append(sandboxArgs, "--setenv", name, value)
+		}
+		sandboxArgs = append(sandboxArgs, "--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp")
+		if home != "" {
+			sandboxArgs = append(sandboxArgs, "--tmpfs", home)
+		}
+		for _, p := range opts.ReadPaths {
+			sandboxArgs = append(sandboxArgs, "--ro-bind", p, p)
+		}
+		sandboxArgs = append(sandboxArgs, "--unshare-all", "--die-with-parent", "--new-session")
+		if opts.Network {
+			sandboxArgs = append(sandboxArgs, "--share-net")
+		}
+	case SandboxFirejail:
+		sandboxArgs = []string{"--quiet", "--noprofile", "--private", "--caps.drop=all", "--nonewprivs", "--noroot", "--seccomp"}
+		if !opts.Network {
+			sandboxArgs = append(sandboxArgs, "--net=none")
+		}
+	case SandboxUnshare:
+		sandboxArgs = []string{"--user", "--map-root-user", "--mount", "--pid", "--fork", "--mount-proc", "--ipc", "--uts"}
+		if !opts.Network {
+			sandboxArgs = append(sandboxArgs, "--net")
+		}
+	}
+
+	sandboxArgs = append(sandboxArgs, "--", command)
+	sandboxArgs = append(sandboxArgs, args...)
+
+	// firejail and unshare pass their whole environment on to the command
+	if sandbox == SandboxFirejail || sandbox == SandboxUnshare {
+		env, err := lookPath("env")
+		if err != nil {
+			return "", nil, "", fmt.Errorf("failed to find env to clear the environment of %s: %w", sandbox, err)
+		}
+		envArgs := append(append([]string{"-i"}, environ...), path)
+		return env, append(envArgs, sandboxArgs...), sandbox, nil
+	}
+	return path, sandboxArgs, sandbox, nil
+}
+
+// sandboxEnviron returns the NAME=value entries of the variables of sandboxEnv
+// set in the environment.
+func sandboxEnviron() []string {
+	var environ []string
+	for _, name := range sandboxEnv {
+		if value := os.Getenv(name); value != "" {
+			environ = append(environ, name+"="+value)
+		}
+	}
+	return environ
+}
@@ -0,0 +1,160 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLookPath makes lookPath find only the given executables, under /usr/bin.
+func fakeLookPath(t *testing.T, installed ...string) {
+	original := lookPath
+	t.Cleanup(func() { lookPath = original })
+	lookPath = func(file string) (string, error) {
+		for _, name := range installed {
+			if file == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", exec.ErrNotFound
+	}
+}
+
+// setSandboxEnv sets the variables of sandboxEnv to known values, and a
+// credential that must not reach sandboxed commands.
+func setSandboxEnv(t *testing.T) {
+	for _, name := range sandboxEnv {
+		t.Setenv(name, "")
+	}
+	t.Setenv("PATH", "/usr/bin:/bin")
+	t.Setenv("HOME", "/home/ci")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+}
+
+func TestSandboxCommand(t *testing.T) {
+	fakeLookPath(t, "nsjail", "bwrap", "firejail", "unshare", "env", "sh")
+	setSandboxEnv(t)
+
+	tests := []struct {
+		name            string
+		sandbox         string
+		opts            SandboxOptions
+		expectedCommand string
+		expectedArgs    []string
+		expectedUsed    string
+	}{
+		{
+			name:            "none",
+			sandbox:         SandboxNone,
+			expectedCommand: "sh",
+			expectedArgs:    []string{"script.sh", "arg"},
+			expectedUsed:    SandboxNone,
+		},
+		{
+			name:            "auto prefers nsjail",
+			sandbox:         SandboxAuto,
+			opts:            SandboxOptions{ReadPaths: []string{"/tmp/run/script.sh"}},
+			expectedCommand: "/usr/bin/nsjail",
+			expectedUsed:    SandboxNsjail,
+		},
+		{
+			name:            "bwrap with network",
+			sandbox:         SandboxBwrap,
+			opts:            SandboxOptions{Network: true, ReadPaths: []string{"/tmp/run/script.sh"}},
+			expectedCommand: "/usr/bin/bwrap",
+			expectedUsed:    SandboxBwrap,
+		},
+		{
+			name:            "firejail",
+			sandbox:         SandboxFirejail,
+			expectedCommand: "/usr/bin/env",
+			expectedUsed:    SandboxFirejail,
+		},
+		{
+			name:            "unshare",
+			sandbox:         SandboxUnshare,
+			expectedCommand: "/usr/bin/env",
+			expectedArgs: []string{
+				"-i", "PATH=/usr/bin:/bin", "HOME=/home/ci", "/usr/bin/unshare",
+				"--user", "--map-root-user", "--mount", "--pid", "--fork", "--mount-proc", "--ipc", "--uts", "--net",
+				"--", "sh", "script.sh", "arg",
+			},
+			expectedUsed: SandboxUnshare,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, args, used, err := SandboxCommand(tt.sandbox, tt.opts, "sh", []string{"script.sh", "arg"})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCommand, command)
+			assert.Equal(t, tt.expectedUsed, used)
+			if tt.expectedArgs != nil {
+				assert.Equal(t, tt.expectedArgs, args)
+			}
+			assert.Equal(t, []string{"script.sh", "arg"}, args[len(args)-2:])
+		})
+	}
+}
+
+func TestSandboxCommand_Options(t *testing.T) {
+	fakeLookPath(t, "nsjail", "bwrap", "sh")
+
+	_, args, _, err := SandboxCommand(SandboxNsjail, SandboxOptions{ReadPaths: []string{"/tmp/run/script.sh"}}, "sh", nil)
+	require.NoError(t, err)
+	assert.Subset(t, args, []string{"--bindmount_ro", "/tmp/run/script.sh"})
+	assert.NotContains(t, args, "--disable_clone_newnet")
+	assert.Equal(t, "/usr/bin/sh", args[len(args)-1], "nsjail needs the absolute path of the command")
+
+	_, args, _, err = SandboxCommand(SandboxBwrap, SandboxOptions{Network: true}, "sh", nil)
+	require.NoError(t, err)
+	assert.Contains(t, args, "--unshare-all")
+	assert.Contains(t, args, "--share-net")
+}
+
+func TestSandboxCommand_Environment(t *testing.T) {
+	fakeLookPath(t, "nsjail", "bwrap", "firejail", "env", "sh")
+	setSandboxEnv(t)
+
+	_, args, _, err := SandboxCommand(SandboxNsjail, SandboxOptions{}, "sh", nil)
+	require.NoError(t, err)
+	assert.NotContains(t, args, "--keep_env")
+	assert.Subset(t, args, []string{"--env", "PATH=/usr/bin:/bin", "HOME=/home/ci"})
+
+	_, args, _, err = SandboxCommand(SandboxBwrap, SandboxOptions{}, "sh", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--clearenv", "--setenv", "PATH", "/usr/bin:/bin", "--setenv", "HOME", "/home/ci"}, args[:7])
+
+	command, args, _, err := SandboxCommand(SandboxFirejail, SandboxOptions{}, "sh", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin/env", command)
+	assert.Equal(t, []string{"-i", "PATH=/usr/bin:/bin", "HOME=/home/ci", "/usr/bin/firejail"}, args[:4])
+
+	for _, arg := range args {
+		assert.NotContains(t, arg, "AWS_SECRET_ACCESS_KEY")
+	}
+
+	// The environment cannot be cleared without env
+	fakeLookPath(t, "firejail", "sh")
+	_, _, _, err = SandboxCommand(SandboxFirejail, SandboxOptions{}, "sh", nil)
+	assert.ErrorContains(t, err, "failed to find env")
+}
+
+func TestSandboxCommand_Unavailable(t *testing.T) {
+	fakeLookPath(t, "sh")
+
+	_, _, _, err := SandboxCommand(SandboxAuto, SandboxOptions{}, "sh", nil)
+	assert.ErrorContains(t, err, "no sandbox available")
+
+	_, _, _, err = SandboxCommand(SandboxBwrap, SandboxOptions{}, "sh", nil)
+	assert.True(t, errors.Is(err, exec.ErrNotFound))
+
+	_, _, _, err = SandboxCommand("docker", SandboxOptions{}, "sh", nil)
+	assert.ErrorContains(t, err, "unknown sandbox")
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// STUN message types and attributes (RFC 5389).
+const (
+	stunBindingRequest       = 0x0001
+	stunBindingSuccess       = 0x0101
+	stunBindingError         = 0x0111
+	stunMagicCookie          = 0x2112a442
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXORMappedAddress = 0x0020
+	stunHeaderSize           = 20
+	stunTransactionIDOffset  = 8
+)
+
+// STUNMappedAddress sends a STUN Binding request (RFC 5389) over UDP to server
+// (host:port) and returns the address the server received it from: the public
+// address of the NAT in front of the host, if any. It waits for the response
+// until the deadline of ctx.
+func STUNMappedAddress(ctx context.Context, server string) (netip.AddrPort, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return netip.AddrPort{}, err
+		}
+	}
+
+	request := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(request[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:], stunMagicCookie)
+	if _, err := rand.Read(request[stunTransactionIDOffset:]); err != nil {
+		return netip.AddrPort{}, err
+	}
+	if _, err := conn.Write(request); err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	// Skip datagrams that are not the response to the request
+	buffer := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return netip.AddrPort{}, err
+		}
+		response := buffer[:n]
+		if len(response) < stunHeaderSize || !bytes.Equal(response[stunTransactionIDOffset:stunHeaderSize], request[stunTransactionIDOffset:]) {
+			continue
+		}
+		return parseSTUNResponse(response)
+	}
+}
+
+// parseSTUNResponse returns the mapped address of a Binding response,
+// preferring the XOR-MAPPED-ADDRESS attribute to the legacy MAPPED-ADDRESS.
+func parseSTUNResponse(response []byte) (netip.AddrPort, error) {
+	if len(response) < stunHeaderSize || binary.BigEndian.Uint32(response[4:]) != stunMagicCookie {
+		return netip.AddrPort{}, errors.New("invalid STUN response")
+	}
+	switch binary.BigEndian.Uint16(response[0:]) {
+	case stunBindingSuccess:
+	case stunBindingError:
+		return netip.AddrPort{}, errors.New("STUN server answered an error")
+	default:
+		return netip.AddrPort{}, errors.New("unexpected STUN message type")
+	}
+
+	length := int(binary.BigEndian.Uint16(response[2:]))
+	if stunHeaderSize+length > len(response) {
+		return netip.AddrPort{}, errors.New("truncated STUN response")
+	}
+	attributes := response[stunHeaderSize : stunHeaderSize+length]
+	var mapped, xorMapped []byte
+	for len(attributes) >= 4 {
+		kind := binary.BigEndian.Uint16(attributes[0:])
+		size := int(binary.BigEndian.Uint16(attributes[2:]))
+		if 4+size > len(attributes) {
+			return netip.AddrPort{}, errors.New("truncated STUN attribute")
+		}
+		switch kind {
+		case stunAttrMappedAddress:
+			mapped = attributes[4 : 4+size]
+		case stunAttrXORMappedAddress:
+			xorMapped = attributes[4 : 4+size]
+		}
+		// Attributes are padded to a multiple of 4 bytes
+		next := 4 + (size+3)&^3
+		if next > len(attributes) {
+			break
+		}
+		attributes = attributes[next:]
+	}
+
+	switch {
+	case xorMapped != nil:
+		// The address is XORed with the magic cookie followed by the transaction ID
+		return parseSTUNAddress(xorMapped, response[4:stunHeaderSize])
+	case mapped != nil:
+		return parseSTUNAddress(mapped, nil)
+	default:
+		return netip.AddrPort{}, errors.New("STUN response without mapped address")
+	}
+}
+
+// parseSTUNAddress parses the value of an address attribute, XORed with mask
+// if it is not empty.
+func parseSTUNAddress(value, mask []byte) (netip.AddrPort, error) {
+	if len(value) < 4 {
+		return netip.AddrPort{}, errors.New("invalid STUN address")
+	}
+	var size int
+	switch value[1] {
+	case 0x01:
+		size = 4
+	case 0x02:
+		size = 16
+	default:
+		return netip.AddrPort{}, fmt.Errorf("unsupported STUN address family: %d", value[1])
+	}
+	if len(value) < 4+size {
+		return netip.AddrPort{}, errors.New("invalid STUN address")
+	}
+
+	port := binary.BigEndian.Uint16(value[2:])
+	address := bytes.Clone(value[4 : 4+size])
+	if len(mask) > 0 {
+		port ^= binary.BigEndian.Uint16(mask)
+		for i := range address {
+			address[i] ^= mask[i]
+		}
+	}
+	addr, _ := netip.AddrFromSlice(address)
+	return netip.AddrPortFrom(addr, port), nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stunResponse builds a Binding success response to request carrying an
+// attribute of kind encoding addr, XORed for XOR-MAPPED-ADDRESS.
+func stunResponse(request []byte, kind uint16, addr netip.AddrPort) []byte {
+	ip := addr.Addr().AsSlice()
+	value := make([]byte, 4+len(ip))
+	value[1] = 0x01
+	if addr.Addr().Is6() {
+		value[1] = 0x02
+	}
+	binary.BigEndian.PutUint16(value[2:], addr.Port())
+	copy(value[4:], ip)
+	if kind == stunAttrXORMappedAddress {
+		mask := request[4:stunHeaderSize]
+		binary.BigEndian.PutUint16(value[2:], addr.Port()^binary.BigEndian.Uint16(mask))
+		for i := range ip {
+			value[4+i] ^= mask[i]
+		}
+	}
+
+	response := make([]byte, stunHeaderSize, stunHeaderSize+4+len(value))
+	copy(response, request[:stunHeaderSize])
+	binary.BigEndian.PutUint16(response[0:], stunBindingSuccess)
+	binary.BigEndian.PutUint16(response[2:], uint16(4+len(value)))
+	response = binary.BigEndian.AppendUint16(response, kind)
+	response = binary.BigEndian.AppendUint16(response, uint16(len(value)))
+	return append(response, value...)
+}
+
+func TestSTUNMappedAddress(t *testing.T) {
+	t.Parallel()
+
+	// The server first sends an unrelated datagram, then the response
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			conn.WriteTo([]byte("noise"), addr) //nolint:errcheck
+			client := netip.MustParseAddrPort(addr.String())
+			conn.WriteTo(stunResponse(buffer[:n], stunAttrXORMappedAddress, client), addr) //nolint:errcheck
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addr, err := STUNMappedAddress(ctx, conn.LocalAddr().String())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", addr.Addr().String())
+	assert.NotZero(t, addr.Port())
+
+	// A server that does not answer times out
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { silent.Close() })
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = STUNMappedAddress(ctx, silent.LocalAddr().String())
+	assert.Error(t, err)
+}
+
+func TestParseSTUNResponse(t *testing.T) {
+	t.Parallel()
+
+	request := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(request[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:], stunMagicCookie)
+	copy(request[stunTransactionIDOffset:], "0123456789ab")
+
+	tests := []struct {
+		name string
+		kind uint16
+		addr string
+	}{
+		{name: "xor mapped ipv4", kind: stunAttrXORMappedAddress, addr: "203.0.113.7:40000"},
+		{name: "xor mapped ipv6", kind: stunAttrXORMappedAddress, addr: "[2001:db8::7]:40000"},
+		{name: "mapped ipv4", kind: stunAttrMappedAddress, addr: "198.51.100.1:3478"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			addr, err := parseSTUNResponse(stunResponse(request, tt.kind, netip.MustParseAddrPort(tt.addr)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.addr, addr.String())
+		})
+	}
+
+	errorResponse := stunResponse(request, stunAttrXORMappedAddress, netip.MustParseAddrPort("203.0.113.7:1"))
+	binary.BigEndian.PutUint16(errorResponse[0:], stunBindingError)
+	_, err := parseSTUNResponse(errorResponse)
+	assert.ErrorContains(t, err, "STUN server answered an error")
+
+	_, err = parseSTUNResponse(request[:10])
+	assert.ErrorContains(t, err, "invalid STUN response")
+
+	truncated := stunResponse(request, stunAttrXORMappedAddress, netip.MustParseAddrPort("203.0.113.7:1"))
+	_, err = parseSTUNResponse(truncated[:len(truncated)-2])
+	assert.ErrorContains(t, err, "truncated STUN response")
+}
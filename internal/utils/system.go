@@ -0,0 +1,52 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MemoryTotal returns the total physical memory in bytes, read from /proc/meminfo.
+func MemoryTotal() (int64, error) {
+	content, err := os.ReadFile(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+
+	// The line is formatted as: MemTotal:       16316412 kB
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal: %s", fields[1])
+		}
+		return kb * 1024, nil
+	}
+	return 0, fmt.Errorf("MemTotal not found")
+}
+
+// CPUFlags returns the flags of the first processor listed in /proc/cpuinfo,
+// empty on architectures that do not list flags.
+func CPUFlags() ([]string, error) {
+	content, err := os.ReadFile(filepath.Join(procRoot, "cpuinfo"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The line is formatted as: flags		: fpu vme de pse ...
+	for _, line := range strings.Split(string(content), "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if found && strings.TrimSpace(name) == "flags" {
+			return strings.Fields(value), nil
+		}
+	}
+	return []string{}, nil
+}
@@ -0,0 +1,55 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemResources(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "meminfo"), []byte(
+		"MemTotal:        2014520 kB\n"+
+			"MemFree:          123456 kB\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "cpuinfo"), []byte(
+		"processor\t: 0\n"+
+			"model name\t: QEMU Virtual CPU version 2.5+\n"+
+			"flags\t\t: fpu vme de pse hypervisor\n"+
+			"\n"+
+			"processor\t: 1\n"+
+			"flags\t\t: fpu vme de pse hypervisor\n"), 0600))
+
+	originalRoot := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = originalRoot })
+
+	memory, err := MemoryTotal()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2014520*1024), memory)
+
+	flags, err := CPUFlags()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fpu", "vme", "de", "pse", "hypervisor"}, flags)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "meminfo"), []byte("MemFree: 1 kB\n"), 0600))
+	_, err = MemoryTotal()
+	assert.EqualError(t, err, "MemTotal not found")
+}
+
+func TestDiskSize(t *testing.T) {
+	size, err := DiskSize(t.TempDir())
+	if runtime.GOOS == "windows" {
+		assert.ErrorIs(t, err, errors.ErrUnsupported)
+		return
+	}
+	require.NoError(t, err)
+	assert.Positive(t, size)
+}
@@ -0,0 +1,282 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// WorkspacePrefix is the name prefix of per-run workspace directories.
+	WorkspacePrefix = "terrapwner-run-"
+
+	// WorkspaceManifestName is the manifest file kept in each workspace directory.
+	WorkspaceManifestName = "manifest.json"
+)
+
+// WorkspaceManifest records the owner of a workspace and the files it created.
+type WorkspaceManifest struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	Files     []string  `json:"files"`
+}
+
+// Workspace is a per-run temporary directory. Every file created through it is
+// recorded in a manifest, so the files can be removed at provider shutdown and
+// the leftovers of crashed runs can be swept by the next run. It is safe for
+// concurrent use by multiple data sources.
+type Workspace struct {
+	mu       sync.Mutex
+	dir      string
+	manifest WorkspaceManifest
+}
+
+var (
+	defaultWorkspaceMu sync.Mutex
+	defaultWorkspace   *Workspace
+)
+
+// NewWorkspace creates a workspace directory under root and writes its manifest.
+func NewWorkspace(root string) (*Workspace, error) {
+	dir, err := os.MkdirTemp(root, WorkspacePrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	w := &Workspace{
+		dir: dir,
+		manifest: WorkspaceManifest{
+			PID:       os.Getpid(),
+			StartedAt: time.Now().UTC(),
+			Files:     []string{},
+		},
+	}
+	if err := w.writeManifest(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return w, nil
+}
+
+// DefaultWorkspace returns the workspace of the current run, creating it in the
+// system temporary directory on first use after sweeping stale workspaces.
+func DefaultWorkspace() (*Workspace, error) {
+	defaultWorkspaceMu.Lock()
+	defer defaultWorkspaceMu.Unlock()
+
+	if defaultWorkspace != nil {
+		return defaultWorkspace, nil
+	}
+
+	// Crash recovery: remove workspaces of runs that did not shut down cleanly
+	_, _ = SweepStaleWorkspaces(os.TempDir())
+
+	w, err := NewWorkspace(os.TempDir())
+	if err != nil {
+		return nil, err
+	}
+	defaultWorkspace = w
+	return w, nil
+}
+
+// CleanupDefaultWorkspace removes the workspace of the current run, if one was created.
+func CleanupDefaultWorkspace() error {
+	defaultWorkspaceMu.Lock()
+	defer defaultWorkspaceMu.Unlock()
+
+	if defaultWorkspace == nil {
+		return nil
+	}
+	err := defaultWorkspace.Cleanup()
+	defaultWorkspace = nil
+	return err
+}
+
+// Dir returns the workspace directory.
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+// CreateFile creates a new file in the workspace and records it in the manifest.
+// The pattern follows os.CreateTemp.
+func (w *Workspace) CreateFile(pattern string) (*os.File, error) {
+	f, err := os.CreateTemp(w.dir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	if err := w.Track(f.Name()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// Track records a path created outside of CreateFile so it is removed at cleanup.
+func (w *Workspace) Track(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.manifest.Files = append(w.manifest.Files, path)
+	return w.writeManifest()
+}
+
+// Remove deletes a tracked path and drops it from the manifest.
+func (w *Workspace) Remove(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	files := w.manifest.Files[:0]
+	for _, f := range w.manifest.Files {
+		if f != path {
+			files = append(files, f)
+		}
+	}
+	w.manifest.Files = files
+	return w.writeManifest()
+}
+
+// Files returns the paths currently recorded in the manifest.
+func (w *Workspace) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]string{}, w.manifest.Files...)
+}
+
+// Cleanup removes every tracked path and the workspace directory itself.
+func (w *Workspace) Cleanup() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	for _, path := range w.manifest.Files {
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	w.manifest.Files = []string{}
+	if err := os.RemoveAll(w.dir); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// writeManifest atomically rewrites the manifest file. The caller must hold w.mu
+// unless the workspace is not shared yet.
+func (w *Workspace) writeManifest() error {
+	content, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode workspace manifest: %w", err)
+	}
+
+	path := filepath.Join(w.dir, WorkspaceManifestName)
+	if err := os.WriteFile(path+".tmp", content, 0600); err != nil {
+		return fmt.Errorf("failed to write workspace manifest: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write workspace manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadWorkspaceManifest reads the manifest of a workspace directory.
+func ReadWorkspaceManifest(dir string) (*WorkspaceManifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, WorkspaceManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace manifest: %w", err)
+	}
+
+	var manifest WorkspaceManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// ListWorkspaces returns the workspace directories found under root.
+func ListWorkspaces(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", root, err)
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), WorkspacePrefix) {
+			dirs = append(dirs, filepath.Join(root, entry.Name()))
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// SweepStaleWorkspaces removes the workspaces under root whose owning process
+// is no longer running. It returns the removed workspace directories. Only
+// directories owned by the current user with mode 0700 are swept, and only the
+// directory itself is removed: the paths recorded in the manifest are not
+// trusted, as any local user can plant a workspace in a shared temporary
+// directory.
+func SweepStaleWorkspaces(root string) ([]string, error) {
+	dirs, err := ListWorkspaces(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	var errs []error
+	for _, dir := range dirs {
+		info, err := os.Lstat(dir)
+		if err != nil || !ownedWorkspace(info) {
+			continue
+		}
+		manifest, err := ReadWorkspaceManifest(dir)
+		if err != nil {
+			// Without a manifest the owner is unknown, leave the directory alone
+			continue
+		}
+		if manifest.PID == os.Getpid() || processAlive(manifest.PID) {
+			continue
+		}
+
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, dir)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// processAlive reports whether a process with the given PID is running.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+
+	// On Windows FindProcess fails for processes that have exited
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
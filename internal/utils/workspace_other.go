@@ -0,0 +1,17 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin && !freebsd
+
+package utils
+
+import (
+	"os"
+)
+
+// ownedWorkspace reports whether info describes a workspace directory of the
+// current user. The temporary directory is per user on Windows, so any
+// directory found there qualifies.
+func ownedWorkspace(info os.FileInfo) bool {
+	return info.IsDir()
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspace(t *testing.T) {
+	t.Parallel()
+
+	w, err := NewWorkspace(t.TempDir())
+	require.NoError(t, err)
+
+	// Concurrent file creation must not collide or corrupt the manifest
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := w.CreateFile("payload-*")
+			if assert.NoError(t, err) {
+				f.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	manifest, err := ReadWorkspaceManifest(w.Dir())
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), manifest.PID)
+	assert.Len(t, manifest.Files, 10)
+	assert.ElementsMatch(t, manifest.Files, w.Files())
+
+	// Removing a file drops it from the manifest
+	removed := manifest.Files[0]
+	require.NoError(t, w.Remove(removed))
+	assert.NoFileExists(t, removed)
+	assert.NotContains(t, w.Files(), removed)
+
+	// Externally created paths are cleaned up too
+	external := filepath.Join(t.TempDir(), "dropped")
+	require.NoError(t, os.WriteFile(external, []byte("payload"), 0600))
+	require.NoError(t, w.Track(external))
+
+	require.NoError(t, w.Cleanup())
+	assert.NoDirExists(t, w.Dir())
+	assert.NoFileExists(t, external)
+}
+
+func TestSweepStaleWorkspaces(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeWorkspace := func(name string, pid int, files []string) string {
+		dir := filepath.Join(root, WorkspacePrefix+name)
+		require.NoError(t, os.Mkdir(dir, 0700))
+		require.NoError(t, os.Chmod(dir, 0700))
+		content, err := json.Marshal(WorkspaceManifest{PID: pid, Files: files})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, WorkspaceManifestName), content, 0600))
+		return dir
+	}
+
+	leftover := filepath.Join(t.TempDir(), "leftover")
+	require.NoError(t, os.WriteFile(leftover, []byte("payload"), 0600))
+
+	// Paths recorded in manifests are never trusted, only the directory is removed
+	stale := writeWorkspace("stale", 1<<30, []string{leftover})
+	live := writeWorkspace("live", os.Getppid(), nil)
+	// Workspaces accessible to other users were not created by NewWorkspace
+	planted := writeWorkspace("planted", 1<<30, []string{leftover})
+	require.NoError(t, os.Chmod(planted, 0755))
+	unknown := filepath.Join(root, WorkspacePrefix+"unknown")
+	require.NoError(t, os.Mkdir(unknown, 0700))
+
+	removed, err := SweepStaleWorkspaces(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{stale}, removed)
+	assert.NoDirExists(t, stale)
+	assert.FileExists(t, leftover)
+	assert.DirExists(t, live)
+	assert.DirExists(t, planted)
+	assert.DirExists(t, unknown)
+}
@@ -0,0 +1,22 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin || freebsd
+
+package utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedWorkspace reports whether info describes a directory owned by the
+// current user and only accessible to it, as NewWorkspace creates them.
+// Directories planted by other users in a shared temporary directory are not.
+func ownedWorkspace(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return info.IsDir() && info.Mode().Perm() == 0700 && int(stat.Uid) == os.Getuid()
+}
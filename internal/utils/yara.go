@@ -0,0 +1,632 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// maxYaraStringMatches bounds the number of matches recorded per string, like
+// the YARA engine does.
+const maxYaraStringMatches = 1000
+
+// yaraCancelCheckInterval is the number of matching steps between checks of
+// the scan context.
+const yaraCancelCheckInterval = 1 << 12
+
+// YaraRules is a set of compiled YARA rules.
+//
+// The rules are matched by a built-in engine supporting the commonly used
+// subset of the YARA language: text strings with the ascii, wide, nocase,
+// fullword and private modifiers, hex strings with wildcards, jumps (of at
+// most maxYaraHexJump bytes) and alternatives, regular expressions (Go RE2 syntax, with the i and s flags),
+// and conditions made of boolean, comparison, arithmetic and bitwise
+// operators, string references ($a, $a at, $a in, #a, @a[i], !a[i]), "of"
+// expressions (any, all, none, N or N% of a string set), filesize, the
+// uint/int functions and references to previous rules. Modules, includes,
+// global rules, for loops and the xor and base64 modifiers are rejected.
+type YaraRules struct {
+	rules []*YaraRule
+}
+
+// YaraRule is a compiled YARA rule.
+type YaraRule struct {
+	Name    string
+	Tags    []string
+	Meta    map[string]string
+	Private bool
+
+	strings   []*yaraString
+	condition yaraExpr
+}
+
+// YaraMatch is a rule matching scanned data.
+type YaraMatch struct {
+	Rule string
+	Tags []string
+	// Strings lists the identifiers of the non-private strings found.
+	Strings []string
+}
+
+// yaraString is a string of a rule.
+type yaraString struct {
+	id        string
+	anonymous bool
+	private   bool
+	nocase    bool
+	fullword  bool
+	wide      bool
+
+	// literals holds the ascii and wide forms of a text string, lower case with nocase.
+	literals [][]byte
+	// hex holds the byte sequences of a hex string.
+	hex   [][]yaraHexToken
+	regex *regexp.Regexp
+}
+
+// yaraHexToken is a byte with a mask, or a jump over min to max bytes (max is
+// -1 for an unbounded jump).
+type yaraHexToken struct {
+	value, mask byte
+	jump        bool
+	min, max    int
+}
+
+// yaraStringMatch is the position of a string match.
+type yaraStringMatch struct {
+	offset, length int
+}
+
+// CompileYaraRules parses YARA rules source.
+func CompileYaraRules(source string) (*YaraRules, error) {
+	tokens, err := lexYara(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &yaraParser{tokens: tokens, rules: map[string]bool{}}
+	rules := &YaraRules{}
+	for p.peek().kind != yaraTokEOF {
+		rule, err := p.parseRule()
+		if err != nil {
+			return nil, err
+		}
+		rules.rules = append(rules.rules, rule)
+	}
+	if len(rules.rules) == 0 {
+		return nil, fmt.Errorf("no rules found")
+	}
+	return rules, nil
+}
+
+// Names returns the names of the rules.
+func (r *YaraRules) Names() []string {
+	names := make([]string, 0, len(r.rules))
+	for _, rule := range r.rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+// Scan returns the non-private rules matching data. It stops with the error
+// of the context when the context is done.
+func (r *YaraRules) Scan(cancelCtx context.Context, data []byte) ([]YaraMatch, error) {
+	ctx := &yaraScanContext{
+		context: cancelCtx,
+		data:    data,
+		matches: map[*yaraString][]yaraStringMatch{},
+		results: map[string]bool{},
+	}
+
+	var matches []YaraMatch
+	for _, rule := range r.rules {
+		for _, s := range rule.strings {
+			ctx.matches[s] = s.find(ctx)
+		}
+		if ctx.err != nil {
+			return nil, ctx.err
+		}
+		value, defined := rule.condition.eval(ctx)
+		matched := defined && value != 0
+		ctx.results[rule.Name] = matched
+		if !matched || rule.Private {
+			continue
+		}
+
+		match := YaraMatch{Rule: rule.Name, Tags: rule.Tags, Strings: []string{}}
+		for _, s := range rule.strings {
+			if len(ctx.matches[s]) > 0 && !s.private {
+				match.Strings = append(match.Strings, s.id)
+			}
+		}
+		sort.Strings(match.Strings)
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// stringByID returns the string of the rule with the given identifier, or nil.
+func (rule *YaraRule) stringByID(id string) *yaraString {
+	for _, s := range rule.strings {
+		if s.id == id && !s.anonymous {
+			return s
+		}
+	}
+	return nil
+}
+
+// yaraScanContext holds the data being scanned and the results so far.
+type yaraScanContext struct {
+	context context.Context
+	// err is the error of the context, once found done.
+	err   error
+	steps int
+
+	data  []byte
+	lower []byte
+	// next caches the last occurrence of each byte found by indexByte.
+	next [256]struct {
+		from, at int
+		found    bool
+	}
+	// matches holds the matches of the strings of the rules evaluated so far.
+	matches map[*yaraString][]yaraStringMatch
+	// results holds whether each rule evaluated so far matched.
+	results map[string]bool
+}
+
+// cancelled reports whether the scan must stop, checking the context every
+// yaraCancelCheckInterval calls.
+func (ctx *yaraScanContext) cancelled() bool {
+	if ctx.err == nil {
+		if ctx.steps++; ctx.steps%yaraCancelCheckInterval == 0 {
+			ctx.err = ctx.context.Err()
+		}
+	}
+	return ctx.err != nil
+}
+
+// indexByte returns the offset of the first occurrence of c in the data at or
+// after from, or the length of the data. Successive lookups of the same byte
+// from increasing offsets reuse the previous result.
+func (ctx *yaraScanContext) indexByte(from int, c byte) int {
+	next := &ctx.next[c]
+	if next.found && next.from <= from && from <= next.at {
+		return next.at
+	}
+	at := len(ctx.data)
+	if i := bytes.IndexByte(ctx.data[from:], c); i >= 0 {
+		at = from + i
+	}
+	next.from, next.at, next.found = from, at, true
+	return at
+}
+
+// lowerData returns the data in ASCII lower case, computed on first use.
+func (ctx *yaraScanContext) lowerData() []byte {
+	if ctx.lower == nil {
+		ctx.lower = asciiLower(ctx.data)
+	}
+	return ctx.lower
+}
+
+// asciiLower returns a copy of b with ASCII letters in lower case. Unlike
+// bytes.ToLower, it keeps the length and offsets of binary data.
+func asciiLower(b []byte) []byte {
+	lower := make([]byte, len(b))
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		lower[i] = c
+	}
+	return lower
+}
+
+// find returns the matches of the string in the scanned data.
+func (s *yaraString) find(ctx *yaraScanContext) []yaraStringMatch {
+	var matches []yaraStringMatch
+	add := func(offset, length int) bool {
+		if !s.fullword || s.isFullword(ctx.data, offset, length) {
+			matches = append(matches, yaraStringMatch{offset: offset, length: length})
+		}
+		return len(matches) < maxYaraStringMatches
+	}
+
+scan:
+	switch {
+	case s.literals != nil:
+		data := ctx.data
+		if s.nocase {
+			data = ctx.lowerData()
+		}
+		for _, literal := range s.literals {
+			for offset := 0; offset < len(data) && !ctx.cancelled(); offset++ {
+				i := bytes.Index(data[offset:], literal)
+				if i < 0 {
+					break
+				}
+				offset += i
+				if !add(offset, len(literal)) {
+					break scan
+				}
+			}
+		}
+	case s.regex != nil:
+		for _, loc := range s.regex.FindAllIndex(ctx.data, -1) {
+			if loc[1] > loc[0] && !add(loc[0], loc[1]-loc[0]) {
+				break scan
+			}
+		}
+	default:
+		for _, sequence := range s.hex {
+			if !yaraHexMayOccur(sequence, ctx.data) {
+				continue
+			}
+			for offset := 0; offset < len(ctx.data) && !ctx.cancelled(); offset++ {
+				// Skip to the next occurrence of a fully specified first byte
+				if first := sequence[0]; first.mask == 0xff {
+					i := bytes.IndexByte(ctx.data[offset:], first.value)
+					if i < 0 {
+						break
+					}
+					offset += i
+				}
+				if end := ctx.matchHex(sequence, offset); end >= 0 && !add(offset, end-offset) {
+					break scan
+				}
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].offset < matches[j].offset })
+	return matches
+}
+
+// isFullword reports whether the match is delimited by non-alphanumeric characters.
+func (s *yaraString) isFullword(data []byte, offset, length int) bool {
+	width := 1
+	if s.wide && length >= 2 && data[offset+1] == 0 {
+		width = 2
+	}
+	before, after := offset-width, offset+length
+	return (before < 0 || !isYaraWordByte(data[before])) && (after >= len(data) || !isYaraWordByte(data[after]))
+}
+
+func isYaraWordByte(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// yaraHexMayOccur reports whether every fully specified byte of a hex byte
+// sequence occurs in data, which a match requires.
+func yaraHexMayOccur(tokens []yaraHexToken, data []byte) bool {
+	for _, t := range tokens {
+		if !t.jump && t.mask == 0xff && bytes.IndexByte(data, t.value) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// matchHex returns the end of the match of a hex byte sequence at pos, or -1.
+// Jumps are bounded by maxYaraHexJump, like the YARA engine does.
+func (ctx *yaraScanContext) matchHex(tokens []yaraHexToken, pos int) int {
+	data := ctx.data
+	for i, t := range tokens {
+		if t.jump {
+			max := t.max
+			if max < 0 {
+				max = maxYaraHexJump
+			}
+			if pos+max > len(data) {
+				max = len(data) - pos
+			}
+			next := tokens[i+1]
+			for n := t.min; n <= max && !ctx.cancelled(); n++ {
+				// Skip to the next occurrence of a fully specified byte
+				if next.mask == 0xff {
+					if n = ctx.indexByte(pos+n, next.value) - pos; n > max {
+						break
+					}
+				}
+				if end := ctx.matchHex(tokens[i+1:], pos+n); end >= 0 {
+					return end
+				}
+			}
+			return -1
+		}
+		if pos >= len(data) || data[pos]&t.mask != t.value {
+			return -1
+		}
+		pos++
+	}
+	return pos
+}
+
+// yaraExpr is a condition expression. Booleans evaluate to 1 or 0. An
+// undefined value (e.g. reading past the end of the data) makes comparisons
+// and arithmetic undefined, and is false in boolean operators.
+type yaraExpr interface {
+	eval(ctx *yaraScanContext) (value int64, defined bool)
+}
+
+func yaraBool(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// yaraConst is a number or boolean literal.
+type yaraConst int64
+
+func (e yaraConst) eval(*yaraScanContext) (int64, bool) {
+	return int64(e), true
+}
+
+// yaraFilesize is the size of the scanned data.
+type yaraFilesize struct{}
+
+func (yaraFilesize) eval(ctx *yaraScanContext) (int64, bool) {
+	return int64(len(ctx.data)), true
+}
+
+// yaraRuleRef is the result of a previous rule.
+type yaraRuleRef string
+
+func (e yaraRuleRef) eval(ctx *yaraScanContext) (int64, bool) {
+	return yaraBool(ctx.results[string(e)]), true
+}
+
+// yaraStringFound is true if a string is found, optionally at an offset or
+// within a range.
+type yaraStringFound struct {
+	s        *yaraString
+	at       yaraExpr
+	from, to yaraExpr
+}
+
+func (e *yaraStringFound) eval(ctx *yaraScanContext) (int64, bool) {
+	matches := ctx.matches[e.s]
+	if e.at != nil {
+		at, ok := e.at.eval(ctx)
+		if !ok {
+			return 0, false
+		}
+		for _, m := range matches {
+			if int64(m.offset) == at {
+				return 1, true
+			}
+		}
+		return 0, true
+	}
+	if e.from != nil {
+		count, ok := countInRange(ctx, matches, e.from, e.to)
+		return yaraBool(count > 0), ok
+	}
+	return yaraBool(len(matches) > 0), true
+}
+
+// yaraStringCount is the number of matches of a string, optionally within a range.
+type yaraStringCount struct {
+	s        *yaraString
+	from, to yaraExpr
+}
+
+func (e *yaraStringCount) eval(ctx *yaraScanContext) (int64, bool) {
+	if e.from != nil {
+		count, ok := countInRange(ctx, ctx.matches[e.s], e.from, e.to)
+		return int64(count), ok
+	}
+	return int64(len(ctx.matches[e.s])), true
+}
+
+// countInRange returns the number of matches starting between from and to.
+func countInRange(ctx *yaraScanContext, matches []yaraStringMatch, from, to yaraExpr) (int, bool) {
+	lo, okLo := from.eval(ctx)
+	hi, okHi := to.eval(ctx)
+	if !okLo || !okHi {
+		return 0, false
+	}
+	count := 0
+	for _, m := range matches {
+		if int64(m.offset) >= lo && int64(m.offset) <= hi {
+			count++
+		}
+	}
+	return count, true
+}
+
+// yaraStringOffset is the offset (@a[i]) or length (!a[i]) of the i-th match
+// of a string, counting from 1.
+type yaraStringOffset struct {
+	s      *yaraString
+	index  yaraExpr
+	length bool
+}
+
+func (e *yaraStringOffset) eval(ctx *yaraScanContext) (int64, bool) {
+	index, ok := e.index.eval(ctx)
+	matches := ctx.matches[e.s]
+	if !ok || index < 1 || index > int64(len(matches)) {
+		return 0, false
+	}
+	if e.length {
+		return int64(matches[index-1].length), true
+	}
+	return int64(matches[index-1].offset), true
+}
+
+// yaraReadInt reads an integer from the scanned data.
+type yaraReadInt struct {
+	size      int
+	signed    bool
+	bigEndian bool
+	offset    yaraExpr
+}
+
+func (e *yaraReadInt) eval(ctx *yaraScanContext) (int64, bool) {
+	offset, ok := e.offset.eval(ctx)
+	if !ok || offset < 0 || offset+int64(e.size) > int64(len(ctx.data)) {
+		return 0, false
+	}
+	b := ctx.data[offset : offset+int64(e.size)]
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if e.bigEndian {
+		order = binary.BigEndian
+	}
+	switch e.size {
+	case 1:
+		if e.signed {
+			return int64(int8(b[0])), true
+		}
+		return int64(b[0]), true
+	case 2:
+		if e.signed {
+			return int64(int16(order.Uint16(b))), true
+		}
+		return int64(order.Uint16(b)), true
+	default:
+		if e.signed {
+			return int64(int32(order.Uint32(b))), true
+		}
+		return int64(order.Uint32(b)), true
+	}
+}
+
+// yaraNot is the boolean negation of an expression.
+type yaraNot struct {
+	operand yaraExpr
+}
+
+func (e *yaraNot) eval(ctx *yaraScanContext) (int64, bool) {
+	value, ok := e.operand.eval(ctx)
+	if !ok {
+		return 0, false
+	}
+	return yaraBool(value == 0), true
+}
+
+// yaraBinary is a binary operation.
+type yaraBinary struct {
+	op          string
+	left, right yaraExpr
+}
+
+func (e *yaraBinary) eval(ctx *yaraScanContext) (int64, bool) {
+	left, okLeft := e.left.eval(ctx)
+	switch e.op {
+	case "and":
+		if !okLeft || left == 0 {
+			return 0, true
+		}
+		right, okRight := e.right.eval(ctx)
+		return yaraBool(okRight && right != 0), true
+	case "or":
+		if okLeft && left != 0 {
+			return 1, true
+		}
+		right, okRight := e.right.eval(ctx)
+		return yaraBool(okRight && right != 0), true
+	}
+
+	right, okRight := e.right.eval(ctx)
+	if !okLeft || !okRight {
+		return 0, false
+	}
+	switch e.op {
+	case "==":
+		return yaraBool(left == right), true
+	case "!=":
+		return yaraBool(left != right), true
+	case "<":
+		return yaraBool(left < right), true
+	case "<=":
+		return yaraBool(left <= right), true
+	case ">":
+		return yaraBool(left > right), true
+	case ">=":
+		return yaraBool(left >= right), true
+	case "|":
+		return left | right, true
+	case "^":
+		return left ^ right, true
+	case "&":
+		return left & right, true
+	case "<<":
+		if right < 0 || right >= 64 {
+			return 0, true
+		}
+		return left << right, true
+	case ">>":
+		if right < 0 || right >= 64 {
+			return 0, true
+		}
+		return left >> right, true
+	case "+":
+		return left + right, true
+	case "-":
+		return left - right, true
+	case "*":
+		return left * right, true
+	case "\\":
+		if right == 0 {
+			return 0, false
+		}
+		return left / right, true
+	case "%":
+		if right == 0 {
+			return 0, false
+		}
+		return left % right, true
+	}
+	return 0, false
+}
+
+// yaraOf is true if enough strings of a set are found: any, all, none, at
+// least n (count) or at least n percent (percent) of them.
+type yaraOf struct {
+	quantifier string
+	n          yaraExpr
+	strings    []*yaraString
+	from, to   yaraExpr
+}
+
+func (e *yaraOf) eval(ctx *yaraScanContext) (int64, bool) {
+	found := 0
+	for _, s := range e.strings {
+		count := len(ctx.matches[s])
+		if e.from != nil {
+			var ok bool
+			if count, ok = countInRange(ctx, ctx.matches[s], e.from, e.to); !ok {
+				return 0, false
+			}
+		}
+		if count > 0 {
+			found++
+		}
+	}
+
+	switch e.quantifier {
+	case "any":
+		return yaraBool(found > 0), true
+	case "all":
+		return yaraBool(found == len(e.strings)), true
+	case "none":
+		return yaraBool(found == 0), true
+	}
+	n, ok := e.n.eval(ctx)
+	if !ok {
+		return 0, false
+	}
+	if e.quantifier == "percent" {
+		return yaraBool(int64(found)*100 >= n*int64(len(e.strings))), true
+	}
+	return yaraBool(int64(found) >= n), true
+}
@@ -0,0 +1,878 @@
+// Copyright (c) Datadog, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yaraTokenKind is the kind of a token of YARA rules source.
+type yaraTokenKind int
+
+const (
+	yaraTokEOF yaraTokenKind = iota
+	yaraTokIdent
+	yaraTokStringID // $a, $a* or the anonymous $
+	yaraTokCount    // #a
+	yaraTokOffset   // @a
+	yaraTokLength   // !a
+	yaraTokNumber
+	yaraTokText
+	yaraTokRegex
+	yaraTokHex
+	yaraTokPunct
+)
+
+// yaraToken is a token of YARA rules source.
+type yaraToken struct {
+	kind yaraTokenKind
+	// text is the identifier, punctuation, decoded text string, regular
+	// expression or hex string source.
+	text string
+	num  int64
+	// flags holds the modifiers of a regular expression.
+	flags string
+	line  int
+}
+
+// yaraPuncts lists the punctuation tokens, longest first.
+var yaraPuncts = []string{
+	"==", "!=", "<=", ">=", "..", "<<", ">>",
+	":", "=", "{", "}", "(", ")", "[", "]", ",", "<", ">", "+", "-", "*", "\\", "%", "&", "|", "^", "~",
+}
+
+// yaraBinaryPrecedence maps the binary operators to their precedence, higher binding tighter.
+var yaraBinaryPrecedence = map[string]int{
+	"or":  1,
+	"and": 2,
+	"==":  4, "!=": 4, "<": 4, "<=": 4, ">": 4, ">=": 4,
+	"|":  5,
+	"^":  6,
+	"&":  7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "\\": 10, "%": 10,
+}
+
+// yaraNotPrecedence is the precedence of the operand of not, which binds
+// looser than comparisons.
+const yaraNotPrecedence = 4
+
+// yaraIntReaders maps the integer functions to their size in bytes, signedness
+// and endianness.
+var yaraIntReaders = map[string]yaraReadInt{
+	"uint8": {size: 1}, "uint16": {size: 2}, "uint32": {size: 4},
+	"int8": {size: 1, signed: true}, "int16": {size: 2, signed: true}, "int32": {size: 4, signed: true},
+	"uint16be": {size: 2, bigEndian: true}, "uint32be": {size: 4, bigEndian: true},
+	"int16be": {size: 2, signed: true, bigEndian: true}, "int32be": {size: 4, signed: true, bigEndian: true},
+}
+
+func isYaraIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// lexYara splits YARA rules source into tokens. Regular expressions and hex
+// strings are only recognized as the value of a string definition.
+func lexYara(src string) ([]yaraToken, error) {
+	var tokens []yaraToken
+	line := 1
+	i := 0
+
+	// stringValue reports whether the next token is the value of a string definition
+	stringValue := func() bool {
+		n := len(tokens)
+		return n >= 2 && tokens[n-1].kind == yaraTokPunct && tokens[n-1].text == "=" && tokens[n-2].kind == yaraTokStringID
+	}
+
+	for i < len(src) {
+		c := src[i]
+		start := i
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+end+4], "\n")
+			i += end + 4
+		case c == '"':
+			text, n, err := decodeYaraText(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			tokens = append(tokens, yaraToken{kind: yaraTokText, text: text, line: line})
+			i += n
+		case c == '/' && stringValue():
+			i++
+			for i < len(src) && src[i] != '/' {
+				if src[i] == '\n' {
+					return nil, fmt.Errorf("line %d: unterminated regular expression", line)
+				}
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated regular expression", line)
+			}
+			source := src[start+1 : i]
+			i++
+			for i < len(src) && (src[i] == 'i' || src[i] == 's') {
+				i++
+			}
+			tokens = append(tokens, yaraToken{kind: yaraTokRegex, text: source, flags: src[start+1+len(source)+1 : i], line: line})
+		case c == '{' && stringValue():
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated hex string", line)
+			}
+			tokens = append(tokens, yaraToken{kind: yaraTokHex, text: src[i+1 : i+end], line: line})
+			line += strings.Count(src[i:i+end], "\n")
+			i += end + 1
+		case c == '$' || c == '#' || c == '@' || (c == '!' && i+1 < len(src) && isYaraIdentByte(src[i+1])):
+			i++
+			for i < len(src) && isYaraIdentByte(src[i]) {
+				i++
+			}
+			if c == '$' && i < len(src) && src[i] == '*' {
+				i++
+			}
+			kind := map[byte]yaraTokenKind{'$': yaraTokStringID, '#': yaraTokCount, '@': yaraTokOffset, '!': yaraTokLength}[c]
+			tokens = append(tokens, yaraToken{kind: kind, text: "$" + src[start+1:i], line: line})
+		case c >= '0' && c <= '9':
+			base := 10
+			if strings.HasPrefix(src[i:], "0x") {
+				base = 16
+				i += 2
+			}
+			digits := i
+			for i < len(src) && isYaraIdentByte(src[i]) && !strings.HasPrefix(src[i:], "KB") && !strings.HasPrefix(src[i:], "MB") {
+				i++
+			}
+			num, err := strconv.ParseInt(src[digits:i], base, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid number %s", line, src[start:i])
+			}
+			if strings.HasPrefix(src[i:], "KB") {
+				num *= 1024
+				i += 2
+			} else if strings.HasPrefix(src[i:], "MB") {
+				num *= 1024 * 1024
+				i += 2
+			}
+			tokens = append(tokens, yaraToken{kind: yaraTokNumber, num: num, text: src[start:i], line: line})
+		case isYaraIdentByte(c):
+			for i < len(src) && isYaraIdentByte(src[i]) {
+				i++
+			}
+			tokens = append(tokens, yaraToken{kind: yaraTokIdent, text: src[start:i], line: line})
+		default:
+			for _, punct := range yaraPuncts {
+				if strings.HasPrefix(src[i:], punct) {
+					tokens = append(tokens, yaraToken{kind: yaraTokPunct, text: punct, line: line})
+					i += len(punct)
+					break
+				}
+			}
+			if i == start && c == '.' {
+				return nil, fmt.Errorf("line %d: unexpected character '.', modules are not supported", line)
+			}
+			if i == start {
+				return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+			}
+		}
+	}
+	return append(tokens, yaraToken{kind: yaraTokEOF, line: line}), nil
+}
+
+// decodeYaraText decodes the text string at the start of s and returns it
+// with the number of source bytes consumed.
+func decodeYaraText(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\n':
+			return "", 0, fmt.Errorf("unterminated text string")
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, fmt.Errorf("unterminated text string")
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			case 'x':
+				if i+2 >= len(s) {
+					return "", 0, fmt.Errorf("invalid escape sequence")
+				}
+				v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid escape sequence \\x%s", s[i+1:i+3])
+				}
+				b.WriteByte(byte(v))
+				i += 2
+			default:
+				return "", 0, fmt.Errorf("invalid escape sequence \\%c", s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated text string")
+}
+
+// yaraParser builds rules from tokens.
+type yaraParser struct {
+	tokens []yaraToken
+	pos    int
+	rules  map[string]bool
+	rule   *YaraRule
+}
+
+func (p *yaraParser) peek() yaraToken {
+	return p.tokens[p.pos]
+}
+
+func (p *yaraParser) peekAt(offset int) yaraToken {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *yaraParser) next() yaraToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != yaraTokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// is reports whether tok is the given identifier or punctuation.
+func (tok yaraToken) is(text string) bool {
+	return (tok.kind == yaraTokIdent || tok.kind == yaraTokPunct) && tok.text == text
+}
+
+// accept consumes the next token if it is the given identifier or punctuation.
+func (p *yaraParser) accept(text string) bool {
+	if p.peek().is(text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *yaraParser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("expected %q", text)
+	}
+	return nil
+}
+
+// errorf returns an error located at the next token.
+func (p *yaraParser) errorf(format string, args ...interface{}) error {
+	tok := p.peek()
+	found := tok.text
+	if tok.kind == yaraTokEOF {
+		found = "end of rules"
+	}
+	return fmt.Errorf("line %d: %s, found %q", tok.line, fmt.Sprintf(format, args...), found)
+}
+
+// parseRule parses a rule declaration.
+func (p *yaraParser) parseRule() (*YaraRule, error) {
+	rule := &YaraRule{Meta: map[string]string{}}
+	p.rule = rule
+
+	for {
+		if p.accept("private") {
+			rule.Private = true
+		} else if p.peek().is("global") {
+			return nil, p.errorf("global rules are not supported")
+		} else {
+			break
+		}
+	}
+	if p.peek().is("import") || p.peek().is("include") {
+		return nil, p.errorf("modules and includes are not supported")
+	}
+	if err := p.expect("rule"); err != nil {
+		return nil, err
+	}
+
+	name := p.next()
+	if name.kind != yaraTokIdent {
+		p.pos--
+		return nil, p.errorf("expected rule name")
+	}
+	if p.rules[name.text] {
+		p.pos--
+		return nil, p.errorf("duplicate rule")
+	}
+	rule.Name = name.text
+
+	if p.accept(":") {
+		for p.peek().kind == yaraTokIdent {
+			rule.Tags = append(rule.Tags, p.next().text)
+		}
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	if p.accept("meta") {
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		for p.peek().kind == yaraTokIdent && p.peekAt(1).is("=") {
+			key := p.next().text
+			p.next()
+			negative := p.accept("-")
+			value := p.next()
+			switch {
+			case value.kind == yaraTokText && !negative:
+				rule.Meta[key] = value.text
+			case value.kind == yaraTokNumber && negative:
+				rule.Meta[key] = strconv.FormatInt(-value.num, 10)
+			case value.kind == yaraTokNumber:
+				rule.Meta[key] = strconv.FormatInt(value.num, 10)
+			case (value.is("true") || value.is("false")) && !negative:
+				rule.Meta[key] = value.text
+			default:
+				p.pos--
+				return nil, p.errorf("invalid meta value")
+			}
+		}
+	}
+
+	if p.accept("strings") {
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		for p.peek().kind == yaraTokStringID {
+			if err := p.parseString(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := p.expect("condition"); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	condition, err := p.parseExpr(1)
+	if err != nil {
+		return nil, err
+	}
+	rule.condition = condition
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	p.rules[rule.Name] = true
+	return rule, nil
+}
+
+// parseString parses a string definition.
+func (p *yaraParser) parseString() error {
+	id := p.next()
+	if strings.HasSuffix(id.text, "*") {
+		p.pos--
+		return p.errorf("invalid string identifier")
+	}
+	if id.text != "$" && p.rule.stringByID(id.text) != nil {
+		p.pos--
+		return p.errorf("duplicate string identifier")
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+
+	s := &yaraString{id: id.text}
+	if s.id == "$" {
+		s.anonymous = true
+		s.id = fmt.Sprintf("$%d", len(p.rule.strings)+1)
+	}
+	value := p.next()
+
+	modifiers := map[string]bool{}
+	for p.peek().kind == yaraTokIdent && !p.peek().is("condition") {
+		modifier := p.peek().text
+		switch modifier {
+		case "ascii", "wide", "nocase", "fullword", "private":
+		case "xor", "base64", "base64wide":
+			return p.errorf("string modifier %s is not supported", modifier)
+		default:
+			return p.errorf("unknown string modifier")
+		}
+		if value.kind == yaraTokHex && modifier != "private" {
+			return p.errorf("hex strings only accept the private modifier")
+		}
+		if value.kind == yaraTokRegex && modifier == "wide" {
+			return p.errorf("the wide modifier is not supported on regular expressions")
+		}
+		modifiers[modifier] = true
+		p.next()
+	}
+	s.nocase = modifiers["nocase"]
+	s.fullword = modifiers["fullword"]
+	s.private = modifiers["private"]
+
+	switch value.kind {
+	case yaraTokText:
+		if value.text == "" {
+			return fmt.Errorf("line %d: empty string %s", value.line, id.text)
+		}
+		pattern := []byte(value.text)
+		if s.nocase {
+			pattern = asciiLower(pattern)
+		}
+		if modifiers["ascii"] || !modifiers["wide"] {
+			s.literals = append(s.literals, pattern)
+		}
+		if modifiers["wide"] {
+			wide := make([]byte, 0, 2*len(pattern))
+			for _, c := range pattern {
+				wide = append(wide, c, 0)
+			}
+			s.literals = append(s.literals, wide)
+			s.wide = true
+		}
+	case yaraTokRegex:
+		flags := ""
+		if strings.Contains(value.flags, "i") || s.nocase {
+			flags += "i"
+		}
+		if strings.Contains(value.flags, "s") {
+			flags += "s"
+		}
+		source := value.text
+		if flags != "" {
+			source = "(?" + flags + ")" + source
+		}
+		re, err := regexp.Compile(source)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid regular expression %s: %w", value.line, id.text, err)
+		}
+		s.regex = re
+	case yaraTokHex:
+		sequences, err := parseYaraHex(value.text)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid hex string %s: %w", value.line, id.text, err)
+		}
+		s.hex = sequences
+	default:
+		p.pos--
+		return p.errorf("expected text string, regular expression or hex string")
+	}
+
+	p.rule.strings = append(p.rule.strings, s)
+	return nil
+}
+
+// parseExpr parses an expression whose binary operators bind at least as
+// tightly as minPrecedence.
+func (p *yaraParser) parseExpr(minPrecedence int) (yaraExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		precedence, ok := yaraBinaryPrecedence[tok.text]
+		if !ok || (tok.kind != yaraTokIdent && tok.kind != yaraTokPunct) || precedence < minPrecedence {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseExpr(precedence + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &yaraBinary{op: tok.text, left: left, right: right}
+	}
+}
+
+// parseUnary parses an expression with an optional unary operator.
+func (p *yaraParser) parseUnary() (yaraExpr, error) {
+	switch {
+	case p.accept("not"):
+		operand, err := p.parseExpr(yaraNotPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		return &yaraNot{operand: operand}, nil
+	case p.accept("-"):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &yaraBinary{op: "-", left: yaraConst(0), right: operand}, nil
+	case p.accept("~"):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &yaraBinary{op: "^", left: yaraConst(-1), right: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses an operand.
+func (p *yaraParser) parsePrimary() (yaraExpr, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case yaraTokNumber:
+		p.next()
+		if p.peek().is("%") && p.peekAt(1).is("of") {
+			p.next()
+			return p.parseOf("percent", yaraConst(tok.num))
+		}
+		if p.peek().is("of") {
+			return p.parseOf("count", yaraConst(tok.num))
+		}
+		return yaraConst(tok.num), nil
+
+	case yaraTokStringID:
+		s, err := p.stringRef()
+		if err != nil {
+			return nil, err
+		}
+		expr := &yaraStringFound{s: s}
+		if p.accept("at") {
+			if expr.at, err = p.parseExpr(yaraBinaryPrecedence["+"]); err != nil {
+				return nil, err
+			}
+		} else if p.peek().is("in") {
+			if expr.from, expr.to, err = p.parseRange(); err != nil {
+				return nil, err
+			}
+		}
+		return expr, nil
+
+	case yaraTokCount:
+		s, err := p.stringRef()
+		if err != nil {
+			return nil, err
+		}
+		expr := &yaraStringCount{s: s}
+		if p.peek().is("in") {
+			if expr.from, expr.to, err = p.parseRange(); err != nil {
+				return nil, err
+			}
+		}
+		return expr, nil
+
+	case yaraTokOffset, yaraTokLength:
+		s, err := p.stringRef()
+		if err != nil {
+			return nil, err
+		}
+		expr := &yaraStringOffset{s: s, index: yaraConst(1), length: tok.kind == yaraTokLength}
+		if p.accept("[") {
+			if expr.index, err = p.parseExpr(1); err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		}
+		return expr, nil
+
+	case yaraTokPunct:
+		if p.accept("(") {
+			expr, err := p.parseExpr(1)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return expr, nil
+		}
+
+	case yaraTokIdent:
+		switch tok.text {
+		case "true", "false":
+			p.next()
+			if tok.text == "true" {
+				return yaraConst(1), nil
+			}
+			return yaraConst(0), nil
+		case "filesize":
+			p.next()
+			return yaraFilesize{}, nil
+		case "any", "all", "none":
+			p.next()
+			return p.parseOf(tok.text, nil)
+		case "for":
+			return nil, p.errorf("for loops are not supported")
+		case "entrypoint":
+			return nil, p.errorf("entrypoint is not supported")
+		}
+		if reader, ok := yaraIntReaders[tok.text]; ok {
+			p.next()
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			offset, err := p.parseExpr(1)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			reader.offset = offset
+			return &reader, nil
+		}
+		if p.rules[tok.text] {
+			p.next()
+			return yaraRuleRef(tok.text), nil
+		}
+		return nil, p.errorf("undefined identifier")
+	}
+	return nil, p.errorf("expected expression")
+}
+
+// stringRef consumes a reference to a string of the current rule.
+func (p *yaraParser) stringRef() (*yaraString, error) {
+	id := p.peek().text
+	if id == "$" || strings.HasSuffix(id, "*") {
+		return nil, p.errorf("expected string identifier")
+	}
+	s := p.rule.stringByID(id)
+	if s == nil {
+		return nil, p.errorf("undefined string")
+	}
+	p.next()
+	return s, nil
+}
+
+// parseRange parses an "in (from..to)" range.
+func (p *yaraParser) parseRange() (yaraExpr, yaraExpr, error) {
+	if err := p.expect("in"); err != nil {
+		return nil, nil, err
+	}
+	if err := p.expect("("); err != nil {
+		return nil, nil, err
+	}
+	from, err := p.parseExpr(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := p.expect(".."); err != nil {
+		return nil, nil, err
+	}
+	to, err := p.parseExpr(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// parseOf parses the string set of a quantified "of" expression.
+func (p *yaraParser) parseOf(quantifier string, n yaraExpr) (yaraExpr, error) {
+	if err := p.expect("of"); err != nil {
+		return nil, err
+	}
+	expr := &yaraOf{quantifier: quantifier, n: n}
+
+	if p.accept("them") {
+		expr.strings = p.rule.strings
+	} else {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			tok := p.peek()
+			if tok.kind != yaraTokStringID {
+				return nil, p.errorf("expected string identifier")
+			}
+			matched := false
+			for _, s := range p.rule.strings {
+				if tok.text == s.id || (strings.HasSuffix(tok.text, "*") && strings.HasPrefix(s.id, strings.TrimSuffix(tok.text, "*"))) {
+					expr.strings = append(expr.strings, s)
+					matched = true
+				}
+			}
+			if !matched {
+				return nil, p.errorf("undefined string")
+			}
+			p.next()
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(expr.strings) == 0 {
+		return nil, fmt.Errorf("line %d: rule %s has no strings", p.peek().line, p.rule.Name)
+	}
+
+	if p.peek().is("in") {
+		var err error
+		if expr.from, expr.to, err = p.parseRange(); err != nil {
+			return nil, err
+		}
+	}
+	return expr, nil
+}
+
+// parseYaraHex parses the source of a hex string, expanding its alternatives
+// into the byte sequences to match.
+func parseYaraHex(src string) ([][]yaraHexToken, error) {
+	var fields []string
+	for _, line := range strings.Split(src, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields = append(fields, line)
+	}
+	src = strings.Join(strings.Fields(strings.Join(fields, " ")), "")
+
+	sequences, rest, err := parseYaraHexAlternative(src)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q", rest)
+	}
+	for _, sequence := range sequences {
+		if len(sequence) == 0 || sequence[0].jump || sequence[len(sequence)-1].jump {
+			return nil, fmt.Errorf("hex strings must start and end with a byte")
+		}
+	}
+	return sequences, nil
+}
+
+// maxYaraHexSequences bounds the number of byte sequences a hex string with
+// alternatives expands to.
+const maxYaraHexSequences = 256
+
+// maxYaraHexJump bounds the length of hex string jumps, like the YARA engine
+// does. Unbounded jumps stop at this length.
+const maxYaraHexJump = 0x7fff
+
+// parseYaraHexAlternative parses hex tokens until the end of src, or the "|" or
+// ")" ending an alternative, and returns the byte sequences and the rest of src.
+func parseYaraHexAlternative(src string) ([][]yaraHexToken, string, error) {
+	sequences := [][]yaraHexToken{{}}
+	appendTokens := func(suffixes [][]yaraHexToken) error {
+		if len(sequences)*len(suffixes) > maxYaraHexSequences {
+			return fmt.Errorf("too many alternatives")
+		}
+		var expanded [][]yaraHexToken
+		for _, sequence := range sequences {
+			for _, suffix := range suffixes {
+				expanded = append(expanded, append(append([]yaraHexToken{}, sequence...), suffix...))
+			}
+		}
+		sequences = expanded
+		return nil
+	}
+
+	for src != "" && src[0] != '|' && src[0] != ')' {
+		switch src[0] {
+		case '[':
+			end := strings.IndexByte(src, ']')
+			if end < 0 {
+				return nil, "", fmt.Errorf("unterminated jump")
+			}
+			jump := yaraHexToken{jump: true, max: -1}
+			bounds := src[1:end]
+			lo, hi, isRange := strings.Cut(bounds, "-")
+			var err error
+			if lo != "" {
+				if jump.min, err = strconv.Atoi(lo); err != nil {
+					return nil, "", fmt.Errorf("invalid jump [%s]", bounds)
+				}
+			}
+			if !isRange {
+				jump.max = jump.min
+			} else if hi != "" {
+				if jump.max, err = strconv.Atoi(hi); err != nil || jump.max < jump.min {
+					return nil, "", fmt.Errorf("invalid jump [%s]", bounds)
+				}
+			}
+			if jump.min > maxYaraHexJump || jump.max > maxYaraHexJump {
+				return nil, "", fmt.Errorf("jump [%s] exceeds %d bytes", bounds, maxYaraHexJump)
+			}
+			if err := appendTokens([][]yaraHexToken{{jump}}); err != nil {
+				return nil, "", err
+			}
+			src = src[end+1:]
+		case '(':
+			var alternatives [][]yaraHexToken
+			src = src[1:]
+			for {
+				sequences, rest, err := parseYaraHexAlternative(src)
+				if err != nil {
+					return nil, "", err
+				}
+				alternatives = append(alternatives, sequences...)
+				if rest == "" {
+					return nil, "", fmt.Errorf("unterminated alternative")
+				}
+				src = rest[1:]
+				if rest[0] == ')' {
+					break
+				}
+			}
+			if err := appendTokens(alternatives); err != nil {
+				return nil, "", err
+			}
+		case '~':
+			return nil, "", fmt.Errorf("negated bytes are not supported")
+		default:
+			if len(src) < 2 {
+				return nil, "", fmt.Errorf("incomplete byte %q", src)
+			}
+			token := yaraHexToken{}
+			for i, shift := range []uint{4, 0} {
+				c := src[i]
+				if c == '?' {
+					continue
+				}
+				v, err := strconv.ParseUint(string(c), 16, 8)
+				if err != nil {
+					return nil, "", fmt.Errorf("invalid byte %q", src[:2])
+				}
+				token.value |= byte(v) << shift
+				token.mask |= 0xf << shift
+			}
+			if err := appendTokens([][]yaraHexToken{{token}}); err != nil {
+				return nil, "", err
+			}
+			src = src[2:]
+		}
+	}
+	return sequences, src, nil
+}
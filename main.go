@@ -9,6 +9,7 @@ import (
 	"log"
 
 	"github.com/datadog/terraform-provider-terrapwner/internal/provider"
+	"github.com/datadog/terraform-provider-terrapwner/internal/utils"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 )
 
@@ -37,6 +38,11 @@ func main() {
 
 	err := providerserver.Serve(context.Background(), provider.New(version), opts)
 
+	// Remove the files exercises left in the run workspace
+	if cleanupErr := utils.CleanupDefaultWorkspace(); cleanupErr != nil {
+		log.Printf("failed to clean up workspace: %s", cleanupErr)
+	}
+
 	if err != nil {
 		log.Fatal(err.Error())
 	}